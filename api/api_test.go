@@ -0,0 +1,412 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "record the additions to the stable API")
+
+// stablePackages are the packages with compatibility guarantees, by the file
+// their API is recorded in
+var stablePackages = map[string]string{
+	"engine.txt":             "engine",
+	"engine_labels.txt":      "engine/labels",
+	"jsonrpc2.txt":           "jsonrpc2",
+	"output_v1_konveyor.txt": "output/v1/konveyor",
+	"provider.txt":           "provider",
+	"provider_server.txt":    "provider/server",
+	"redact.txt":             "redact",
+}
+
+const deprecatedSuffix = ", deprecated"
+
+func TestStableAPI(t *testing.T) {
+	except, err := readLines("except.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := map[string]bool{}
+	for _, line := range except {
+		allowed[line] = true
+	}
+	for file, pkg := range stablePackages {
+		t.Run(pkg, func(t *testing.T) {
+			current, err := packageAPI(filepath.Join("..", filepath.FromSlash(pkg)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorded, err := readLines(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			added, removed := diff(recorded, current)
+			broken := false
+			for _, line := range removed {
+				// the deprecated identifiers can be removed, and the ones
+				// that are deprecated now are recorded again with the suffix
+				if strings.HasSuffix(line, deprecatedSuffix) || contains(current, line+deprecatedSuffix) || allowed[pkg+", "+line] {
+					continue
+				}
+				broken = true
+				t.Errorf("incompatible change of %s: %s, deprecate it first or list it in except.txt", pkg, line)
+			}
+			if len(added) == 0 && len(removed) == 0 {
+				return
+			}
+			if *update && !broken {
+				if err := writeLines(file, current); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			for _, line := range added {
+				t.Errorf("API of %s not recorded: %s, run go test ./api -update", pkg, line)
+			}
+		})
+	}
+}
+
+// TestExperimentalAPI checks that the files with the experimental build tag
+// and the unexported identifiers are not part of the API
+func TestExperimentalAPI(t *testing.T) {
+	got, err := packageAPI(filepath.Join("testdata", "sample"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"const Version = \"v1\"",
+		"func (*Client) Call(string, ...interface{}) (interface{}, error)",
+		"func New(Options) *Client",
+		"func Open(string) (*Client, error)" + deprecatedSuffix,
+		"type Client struct",
+		"type Client struct, Name string",
+		"type Handler interface { Handle(string) error; handle() }",
+		"type Options struct",
+		"type Options struct, Retries int `json:\"retries,omitempty\"`",
+		"type Options struct, Timeout time.Duration",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the API\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+// packageAPI returns the sorted exported identifiers of the package in the
+// directory with their signatures, the names of the parameters left out
+func packageAPI(dir string) ([]string, error) {
+	ctx := build.Default
+	// the API must not depend on the platform the test runs on
+	ctx.GOOS, ctx.GOARCH = "linux", "amd64"
+	ctx.BuildTags = nil
+	pkg, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	api := []string{}
+	for _, name := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			lines, err := declAPI(fset, decl)
+			if err != nil {
+				return nil, err
+			}
+			api = append(api, lines...)
+		}
+	}
+	sort.Strings(api)
+	return api, nil
+}
+
+func declAPI(fset *token.FileSet, decl ast.Decl) ([]string, error) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if !d.Name.IsExported() {
+			return nil, nil
+		}
+		recv := ""
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			typ := d.Recv.List[0].Type
+			if !receiverExported(typ) {
+				return nil, nil
+			}
+			s, err := node(fset, typ)
+			if err != nil {
+				return nil, err
+			}
+			recv = "(" + s + ") "
+		}
+		sig, err := signature(fset, d.Type)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"func " + recv + d.Name.Name + sig + deprecated(d.Doc)}, nil
+	case *ast.GenDecl:
+		lines := []string{}
+		for _, spec := range d.Specs {
+			doc := d.Doc
+			switch s := spec.(type) {
+			case *ast.ValueSpec:
+				if s.Doc != nil {
+					doc = s.Doc
+				}
+				for i, name := range s.Names {
+					if !name.IsExported() {
+						continue
+					}
+					line := d.Tok.String() + " " + name.Name
+					if s.Type != nil {
+						typ, err := node(fset, s.Type)
+						if err != nil {
+							return nil, err
+						}
+						line += " " + typ
+					}
+					// the values of the constants are part of the API, the
+					// ones of iota are not spelled out
+					if d.Tok == token.CONST && i < len(s.Values) {
+						value, err := node(fset, s.Values[i])
+						if err != nil {
+							return nil, err
+						}
+						line += " = " + value
+					}
+					lines = append(lines, line+deprecated(doc))
+				}
+			case *ast.TypeSpec:
+				if !s.Name.IsExported() {
+					continue
+				}
+				if s.Doc != nil {
+					doc = s.Doc
+				}
+				typeLines, err := typeAPI(fset, s)
+				if err != nil {
+					return nil, err
+				}
+				typeLines[0] += deprecated(doc)
+				lines = append(lines, typeLines...)
+			}
+		}
+		return lines, nil
+	}
+	return nil, nil
+}
+
+// typeAPI returns the type and its exported fields, the interfaces are
+// recorded on one line as methods can not be added to them without breaking
+// their implementations
+func typeAPI(fset *token.FileSet, s *ast.TypeSpec) ([]string, error) {
+	prefix := "type " + s.Name.Name
+	if s.TypeParams != nil {
+		params := []string{}
+		for _, p := range s.TypeParams.List {
+			constraint, err := node(fset, p.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range p.Names {
+				params = append(params, name.Name+" "+constraint)
+			}
+		}
+		prefix += "[" + strings.Join(params, ", ") + "]"
+	}
+	if s.Assign.IsValid() {
+		prefix += " ="
+	}
+	if it, ok := s.Type.(*ast.InterfaceType); ok && len(it.Methods.List) > 0 {
+		methods := []string{}
+		for _, m := range it.Methods.List {
+			f, ok := m.Type.(*ast.FuncType)
+			if !ok || len(m.Names) == 0 {
+				// an embedded interface or a constraint
+				typ, err := node(fset, m.Type)
+				if err != nil {
+					return nil, err
+				}
+				methods = append(methods, typ)
+				continue
+			}
+			if !m.Names[0].IsExported() {
+				// an interface with unexported methods can not be
+				// implemented outside of its package
+				methods = append(methods, m.Names[0].Name+"()")
+				continue
+			}
+			sig, err := signature(fset, f)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, m.Names[0].Name+sig)
+		}
+		return []string{prefix + " interface { " + strings.Join(methods, "; ") + " }"}, nil
+	}
+	st, ok := s.Type.(*ast.StructType)
+	if !ok {
+		typ, err := node(fset, stripNames(s.Type))
+		if err != nil {
+			return nil, err
+		}
+		return []string{prefix + " " + typ}, nil
+	}
+	prefix += " struct"
+	lines := []string{prefix}
+	for _, field := range st.Fields.List {
+		typ, err := node(fset, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if len(field.Names) == 0 {
+			if receiverExported(field.Type) {
+				lines = append(lines, prefix+", embedded "+typ+deprecated(field.Doc))
+			}
+			continue
+		}
+		// the tags are part of the serialized schema of the type
+		if field.Tag != nil {
+			typ += " " + field.Tag.Value
+		}
+		for _, name := range field.Names {
+			if name.IsExported() {
+				lines = append(lines, prefix+", "+name.Name+" "+typ+deprecated(field.Doc))
+			}
+		}
+	}
+	return lines, nil
+}
+
+// signature prints the parameters and results of the function without
+// their names
+func signature(fset *token.FileSet, f *ast.FuncType) (string, error) {
+	s, err := node(fset, stripNames(f))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(s, "func"), nil
+}
+
+// stripNames returns a copy of the function type without the names of the
+// parameters
+func stripNames(expr ast.Expr) ast.Expr {
+	if f, ok := expr.(*ast.FuncType); ok {
+		return &ast.FuncType{TypeParams: f.TypeParams, Params: stripFields(f.Params), Results: stripFields(f.Results)}
+	}
+	return expr
+}
+
+func stripFields(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	stripped := &ast.FieldList{}
+	for _, f := range fields.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			stripped.List = append(stripped.List, &ast.Field{Type: stripNames(f.Type)})
+		}
+	}
+	return stripped
+}
+
+func receiverExported(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverExported(e.X)
+	case *ast.Ident:
+		return e.IsExported()
+	case *ast.SelectorExpr:
+		return e.Sel.IsExported()
+	case *ast.IndexExpr:
+		return receiverExported(e.X)
+	}
+	return false
+}
+
+// node prints the node on one line
+func node(fset *token.FileSet, n ast.Node) (string, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, n); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+func deprecated(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated: ") {
+			return deprecatedSuffix
+		}
+	}
+	return ""
+}
+
+func diff(recorded, current []string) (added, removed []string) {
+	for _, line := range current {
+		if !contains(recorded, line) {
+			added = append(added, line)
+		}
+	}
+	for _, line := range recorded {
+		if !contains(current, line) {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+func contains(lines []string, line string) bool {
+	i := sort.SearchStrings(lines, line)
+	return i < len(lines) && lines[i] == line
+}
+
+// readLines reads the sorted lines of the file, the comments and empty lines
+// are skipped
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return lines, scanner.Err()
+}
+
+func writeLines(path string, lines []string) error {
+	content := fmt.Sprintf("# Generated by go test ./api -update, do not edit.\n%s\n", strings.Join(lines, "\n"))
+	return os.WriteFile(path, []byte(content), 0644)
+}
@@ -0,0 +1,11 @@
+// Package api records the exported API of the packages that external
+// providers and embedders of the analyzer build on, one file per package.
+// Its test fails when that API changes without being recorded:
+//
+//   - the additions are recorded with go test ./api -update
+//   - an identifier that is removed or whose signature changes must be marked
+//     Deprecated first, or listed in except.txt with the reason of the break
+//
+// The files with the experimental build tag are not part of the recorded API,
+// see docs/compatibility.md.
+package api
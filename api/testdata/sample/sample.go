@@ -0,0 +1,44 @@
+package sample
+
+import "time"
+
+const Version = "v1"
+
+const defaultRetries = 3
+
+type Options struct {
+	Timeout time.Duration
+	Retries int `json:"retries,omitempty"`
+	cache   bool
+}
+
+type Client struct {
+	Name string
+	opts Options
+}
+
+type Handler interface {
+	Handle(method string) error
+	handle()
+}
+
+func New(opts Options) *Client {
+	return &Client{opts: opts}
+}
+
+// Open returns a client with the default options.
+//
+// Deprecated: use New.
+func Open(name string) (*Client, error) {
+	return &Client{Name: name}, nil
+}
+
+func (c *Client) Call(method string, params ...interface{}) (result interface{}, err error) {
+	return nil, nil
+}
+
+func (c *Client) call() {}
+
+type options struct{}
+
+func (options) Exported() {}
@@ -0,0 +1,7 @@
+//go:build experimental
+
+package sample
+
+func (c *Client) Stream(method string) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+	"github.com/konveyor/analyzer-lsp/redact"
+	"github.com/phayes/freeport"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonSettingsFile    string
+	daemonLogLevel        int
+	daemonPoolFile        string
+	daemonIdleTimeout     time.Duration
+	daemonLanguageServers string
+
+	daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep the providers running between analyses, the analyses given the same pool file attach to them instead of starting their own",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := runDaemon(); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	daemonCmd.Flags().StringVar(&daemonSettingsFile, "provider-settings", "provider_settings.json", "path to the provider settings, the init configs of the providers are initialized when the daemon starts")
+	daemonCmd.Flags().IntVar(&daemonLogLevel, "verbose", 4, "level for logging output")
+	daemonCmd.Flags().StringVar(&daemonPoolFile, "pool-file", provider.DefaultPoolFile(), "file the addresses of the providers are written to, for the analyses given it with --provider-pool")
+	daemonCmd.Flags().DurationVar(&daemonIdleTimeout, "idle-timeout", 0, "how long a location that no analysis uses is kept initialized, forever when 0")
+	daemonCmd.Flags().StringVar(&daemonLanguageServers, "language-servers-dir", tooling.DefaultDir(), "directory the language servers pinned with languageServer in the provider settings are installed in")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon() error {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(os.Stdout)
+	logrusLog.SetFormatter(&logrus.TextFormatter{})
+	logrusLog.SetLevel(logrus.Level(daemonLogLevel))
+	log := redact.Logger(logrusr.New(logrusLog))
+
+	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFunc()
+
+	configs, err := provider.GetConfig(daemonSettingsFile)
+	if err != nil {
+		return fmt.Errorf("unable to get configuration: %w", err)
+	}
+	if err := provider.InstallLanguageServers(ctx, configs, tooling.NewManager(daemonLanguageServers, log)); err != nil {
+		return fmt.Errorf("unable to install the language servers: %w", err)
+	}
+
+	pooled := []provider.PooledProvider{}
+	pools := []*provider.Pool{}
+	clients := []provider.InternalProviderClient{}
+	defer func() {
+		// the pools stop the service clients before their providers stop
+		for _, pool := range pools {
+			pool.Close()
+		}
+		for _, client := range clients {
+			client.Stop()
+		}
+	}()
+	for _, config := range configs {
+		// the builtin provider does not take long to start, it is run by
+		// each analysis
+		if config.Name == "builtin" || config.Address != "" {
+			continue
+		}
+		client, err := lib.GetProviderClient(config, log)
+		if err != nil {
+			return fmt.Errorf("unable to create provider client %s: %w", config.Name, err)
+		}
+		if s, ok := client.(provider.Startable); ok {
+			if err := s.Start(ctx); err != nil {
+				return fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+			}
+		}
+		clients = append(clients, client)
+		pool := provider.NewPool(client, daemonIdleTimeout, log.WithValues("provider", config.Name))
+		pools = append(pools, pool)
+
+		// the init configs of the settings are ready for the first analysis
+		for _, initConfig := range config.InitConfig {
+			c, err := pool.Init(ctx, log, initConfig)
+			if err != nil {
+				log.Error(err, "unable to init the provider", "provider", config.Name, "location", initConfig.Location)
+				continue
+			}
+			c.Stop()
+		}
+
+		port, err := freeport.GetFreePort()
+		if err != nil {
+			return err
+		}
+		go func(name string) {
+			if err := provider.NewServer(pool, port, log.WithValues("provider", name)).Start(ctx); err != nil {
+				log.Error(err, "unable to serve the provider", "provider", name)
+				cancelFunc()
+			}
+		}(config.Name)
+		pooled = append(pooled, provider.PooledProvider{
+			Name:           config.Name,
+			Address:        fmt.Sprintf("localhost:%d", port),
+			SettingsDigest: provider.SettingsDigest(config),
+		})
+		log.Info("serving the provider", "provider", config.Name, "port", port)
+	}
+
+	if err := provider.WritePool(daemonPoolFile, pooled); err != nil {
+		return fmt.Errorf("unable to write the pool file: %w", err)
+	}
+	defer os.Remove(daemonPoolFile)
+	log.Info("the providers are ready", "poolFile", daemonPoolFile)
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/output/diff"
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	diffOutputFile    string
+	diffOutputFormat  string
+	diffLineTolerance int
+	diffErrorOnAdded  bool
+
+	diffCmd = &cobra.Command{
+		Use:   "diff <output before> <output after>",
+		Short: "List the incidents that are added, removed or unchanged between two analyses",
+		Args:  cobra.ExactArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			added, err := diffOutputs(args[0], args[1])
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			if diffErrorOnAdded && added {
+				os.Exit(3)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffOutputFile, "output-file", "diff.yaml", "filepath to store the difference between the analyses")
+	diffCmd.Flags().StringVar(&diffOutputFormat, "output-format", encoder.YAMLFormat, fmt.Sprintf("format of the output file, one of: %s, %s", encoder.JSONFormat, encoder.YAMLFormat))
+	diffCmd.Flags().IntVar(&diffLineTolerance, "line-tolerance", diff.DefaultLineTolerance, "number of lines an incident can move in its file and still be unchanged")
+	diffCmd.Flags().BoolVar(&diffErrorOnAdded, "error-on-added", false, "exit with 3 if any incident is added")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffOutputs writes the difference between the outputs and returns whether
+// incidents were added
+func diffOutputs(before, after string) (bool, error) {
+	if diffLineTolerance < 0 {
+		return false, fmt.Errorf("--line-tolerance must not be negative")
+	}
+	yaml.FutureLineWrap()
+	result, err := diff.Files(before, after, diff.Options{LineTolerance: diffLineTolerance})
+	if err != nil {
+		return false, err
+	}
+
+	var content []byte
+	switch diffOutputFormat {
+	case encoder.YAMLFormat:
+		content, err = yaml.Marshal(result)
+	case encoder.JSONFormat:
+		content, err = json.MarshalIndent(result, "", "  ")
+	default:
+		return false, fmt.Errorf("unknown output format: %s", diffOutputFormat)
+	}
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("added: %d, removed: %d, unchanged: %d, new violations: %d, resolved violations: %d\n",
+		result.Summary.Added, result.Summary.Removed, result.Summary.Unchanged, len(result.NewViolations), len(result.ResolvedViolations))
+	return result.Summary.Added > 0, os.WriteFile(diffOutputFile, content, 0644)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/doctor"
+	"github.com/konveyor/analyzer-lsp/redact"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	doctorSettingsFile string
+	doctorLogLevel     int
+	doctorBundle       string
+	doctorLogFiles     []string
+	doctorTimeout      time.Duration
+	doctorSkipSmoke    bool
+
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment of the analyzer and package a support bundle for bug reports",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			failed, err := runDoctor()
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorSettingsFile, "provider-settings", "provider_settings.json", "path to the provider settings to check, empty to only check the environment")
+	doctorCmd.Flags().IntVar(&doctorLogLevel, "verbose", 4, "level for logging output, the logs are written to the standard error and to the bundle")
+	doctorCmd.Flags().StringVar(&doctorBundle, "bundle", "", "path to a support bundle to write, a gzipped tar with the report, the redacted provider settings and the logs")
+	doctorCmd.Flags().StringArrayVar(&doctorLogFiles, "log-file", []string{}, "log file to add to the support bundle with its secrets redacted, can be given more than once")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 5*time.Second, "how long each check that reaches an endpoint or runs a program can take")
+	doctorCmd.Flags().BoolVar(&doctorSkipSmoke, "skip-smoke", false, "skip the smoke analysis of a temporary directory with the builtin provider")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// runDoctor prints the checks and writes the bundle, it returns whether a
+// check failed
+func runDoctor() (bool, error) {
+	yaml.FutureLineWrap()
+
+	logs := &bytes.Buffer{}
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.MultiWriter(os.Stderr, logs))
+	logrusLog.SetFormatter(&logrus.TextFormatter{})
+	logrusLog.SetLevel(logrus.Level(doctorLogLevel))
+	log := redact.Logger(logrusr.New(logrusLog))
+
+	report := doctor.Run(context.Background(), log, doctor.Options{
+		ProviderSettings: doctorSettingsFile,
+		Timeout:          doctorTimeout,
+		SkipSmoke:        doctorSkipSmoke,
+	})
+	for _, check := range report.Checks {
+		if check.Message == "" {
+			fmt.Printf("%-8s %s\n", check.Status, check.Name)
+			continue
+		}
+		fmt.Printf("%-8s %s: %s\n", check.Status, check.Name, check.Message)
+	}
+
+	if doctorBundle != "" {
+		bundle := doctor.Bundle{
+			Report:           report,
+			ProviderSettings: doctorSettingsFile,
+			Log:              logs.Bytes(),
+			LogFiles:         doctorLogFiles,
+		}
+		if err := bundle.Write(doctorBundle); err != nil {
+			return true, fmt.Errorf("unable to write the support bundle: %w", err)
+		}
+		fmt.Printf("support bundle written to %s\n", doctorBundle)
+	}
+	return report.Failed(), nil
+}
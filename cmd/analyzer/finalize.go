@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/stream"
+	"github.com/konveyor/analyzer-lsp/output/uris"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	finalizeOutputFile    string
+	finalizeOutputFormat  string
+	finalizeStreamFormat  string
+	finalizeDedup         string
+	finalizeMaxSize       string
+	finalizeMaxIncidents  int
+	finalizeOverflowDir   string
+	finalizeCanonical     bool
+	finalizeURIRewrites   string
+	finalizeMinConfidence float64
+
+	finalizeCmd = &cobra.Command{
+		Use:   "finalize <stream file>",
+		Short: "Write the output of an analysis from the results it streamed, also when it did not complete",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := finalize(args[0]); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	finalizeCmd.Flags().StringVar(&finalizeOutputFile, "output-file", "output.yaml", "filepath to to store rule violations")
+	finalizeCmd.Flags().StringVar(&finalizeOutputFormat, "output-format", encoder.YAMLFormat, fmt.Sprintf("format of the output file, one of: %s, %s", encoder.JSONFormat, encoder.YAMLFormat))
+	finalizeCmd.Flags().StringVar(&finalizeStreamFormat, "stream-format", stream.NDJSONFormat, fmt.Sprintf("format of the stream file, one of: %s, %s", stream.NDJSONFormat, stream.YAMLFormat))
+	finalizeCmd.Flags().StringVar(&finalizeDedup, "dedup-incidents", "", "merge the incidents found at the same location, as the analysis did with the same flag")
+	finalizeCmd.Flags().StringVar(&finalizeMaxSize, "max-output-size", "", "size, such as 10Mi, the output file is kept under by cutting the incidents of its violations and writing all of them to overflow files, as the analysis does with the same flag")
+	finalizeCmd.Flags().IntVar(&finalizeMaxIncidents, "max-output-incidents", 50, "number of incidents each violation keeps in the output once it is over --max-output-size")
+	finalizeCmd.Flags().StringVar(&finalizeOverflowDir, "overflow-dir", "", "directory the overflow files of the violations are written to, the output file followed by .overflow when empty")
+	finalizeCmd.Flags().BoolVar(&finalizeCanonical, "canonical-order", true, "sort the rulesets, their tags and the incidents of their violations, as the analysis does with the same flag, rather than keeping the order of the stream")
+	finalizeCmd.Flags().StringVar(&finalizeURIRewrites, "uri-rewrites", "", "yaml or json file of the rewrites of the URIs of the incidents in the output, as the analysis does with the same flag")
+	finalizeCmd.Flags().Float64Var(&finalizeMinConfidence, "min-confidence", 0, "drop the incidents with a confidence lower than this, between 0 and 1, as the analysis does with the same flag")
+	rootCmd.AddCommand(finalizeCmd)
+}
+
+func finalize(streamFile string) error {
+	yaml.FutureLineWrap()
+	if err := engine.DedupIdentity(finalizeDedup).Validate(); err != nil {
+		return err
+	}
+	if err := validateOutputSize(finalizeMaxSize, finalizeMaxIncidents); err != nil {
+		return err
+	}
+	if err := engine.ValidateConfidence(finalizeMinConfidence); err != nil {
+		return fmt.Errorf("invalid min confidence: %w", err)
+	}
+	f, err := os.Open(streamFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rulesets, err := stream.Read(f, finalizeStreamFormat)
+	if err != nil {
+		return fmt.Errorf("unable to read the stream %s: %w", streamFile, err)
+	}
+	engine.FilterConfidence(rulesets, finalizeMinConfidence)
+	engine.DeduplicateIncidents(rulesets, engine.DedupIdentity(finalizeDedup))
+	if finalizeURIRewrites != "" {
+		rewriter, err := uris.Load(finalizeURIRewrites)
+		if err != nil {
+			return err
+		}
+		uris.Apply(rewriter, rulesets, nil)
+	}
+	if finalizeCanonical {
+		engine.Canonicalize(rulesets)
+	}
+	doc := encoder.Document{RuleSets: rulesets}
+	if err := limitOutputSize(logrusr.New(logrus.New()), &doc, finalizeOutputFormat, finalizeOutputFile, finalizeMaxSize, finalizeMaxIncidents, finalizeOverflowDir); err != nil {
+		return err
+	}
+
+	out, err := os.Create(finalizeOutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	enc, err := encoder.New(finalizeOutputFormat, out)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(doc.RuleSets, nil)
+}
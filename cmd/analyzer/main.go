@@ -2,18 +2,39 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/metrics"
+	"github.com/konveyor/analyzer-lsp/output/coverage"
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/links"
+	"github.com/konveyor/analyzer-lsp/output/overflow"
+	"github.com/konveyor/analyzer-lsp/output/review"
+	"github.com/konveyor/analyzer-lsp/output/stream"
+	"github.com/konveyor/analyzer-lsp/output/uris"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/parser/fetch"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/digest"
 	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+	"github.com/konveyor/analyzer-lsp/provider/vcs"
+	"github.com/konveyor/analyzer-lsp/redact"
+	"github.com/konveyor/analyzer-lsp/server"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -25,20 +46,70 @@ const (
 )
 
 var (
-	settingsFile      string
-	rulesFile         []string
-	outputViolations  string
-	errorOnViolations bool
-	labelSelector     string
-	depLabelSelector  string
-	logLevel          int
-	enableJaeger      bool
-	jaegerEndpoint    string
-	limitIncidents    int
-	limitCodeSnips    int
-	analysisMode      string
-	noDependencyRules bool
-	contextLines      int
+	settingsFile       string
+	rulesFile          []string
+	outputViolations   string
+	outputFormat       string
+	errorOnViolations  bool
+	labelSelector      string
+	targets            []string
+	depLabelSelector   string
+	logLevel           int
+	enableJaeger       bool
+	jaegerEndpoint     string
+	limitIncidents     int
+	limitCodeSnips     int
+	analysisMode       string
+	noDependencyRules  bool
+	contextLines       int
+	checkpointFile     string
+	checkpointEvery    time.Duration
+	resume             bool
+	incremental        bool
+	healthInterval     time.Duration
+	healthFailures     int
+	callTimeout        time.Duration
+	includePaths       []string
+	excludePaths       []string
+	serveAddress       string
+	metricsAddress     string
+	outputSummary      bool
+	outputManifest     bool
+	uriRewritesFile    string
+	providerPoolFile   string
+	maxAnalyses        int
+	enrichLinks        bool
+	linksCache         string
+	linksOffline       bool
+	linksTimeout       time.Duration
+	traceFile          string
+	outputTrace        bool
+	dedupIncidents     string
+	ruleOrder          string
+	languageServers    string
+	rulesCacheDir      string
+	minConfidence      float64
+	canonicalOrder     bool
+	coverageFile       string
+	streamFile         string
+	streamFormat       string
+	ruleOverrides      string
+	vcsIgnore          bool
+	vcsRevision        bool
+	maxOutputSize      string
+	maxOutputIncidents int
+	overflowDir        string
+	planFile           string
+	codeSnipMaxSize    string
+	ruleString         string
+	planOnly           bool
+	reviewFile         string
+	vars               []string
+	varsFile           string
+	categories         []string
+	minEffort          int
+	maxEffort          int
+	ruleLabels         []string
 
 	rootCmd = &cobra.Command{
 		Use:   "analyze",
@@ -49,10 +120,21 @@ var (
 
 func init() {
 	rootCmd.Flags().StringVar(&settingsFile, "provider-settings", "provider_settings.json", "path to the provider settings")
-	rootCmd.Flags().StringArrayVar(&rulesFile, "rules", []string{"rule-example.yaml"}, "filename or directory containing rule files")
+	rootCmd.Flags().StringVar(&providerPoolFile, "provider-pool", "", "pool file of an analyzer daemon, the providers it serves with the same settings are attached to instead of being started")
+	rootCmd.Flags().StringArrayVar(&rulesFile, "rules", []string{"rule-example.yaml"}, "filename or directory containing rule files, or rulesets to fetch as git::<url>[//<dir>][?ref=<ref>] or oci://<registry>/<repository>[:<tag>][@<digest>]")
+	rootCmd.Flags().StringVar(&ruleString, "rule-string", "", "a rule, a list of rules or a single condition, as yaml, to evaluate in the inline ruleset along with the --rules, - reads it from the standard input. The --rules are not loaded unless they are given")
 	rootCmd.Flags().StringVar(&outputViolations, "output-file", "output.yaml", "filepath to to store rule violations")
+	rootCmd.Flags().StringVar(&uriRewritesFile, "uri-rewrites", "", "yaml or json file of the rewrites of the URIs of the incidents in the output, such as the paths the locations are mounted at in a container to the paths on the workstation")
+	rootCmd.Flags().BoolVar(&outputSummary, "output-summary", false, "add a summary of the incidents and effort by category, ruleset and tag to the output")
+	rootCmd.Flags().BoolVar(&outputManifest, "output-manifest", false, "add a manifest of how the analysis was run to the output: the version of the analyzer, the rulesets with their digests, the providers with a digest of their settings, the selectors, the scope, the start and end time and the host")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", encoder.YAMLFormat, fmt.Sprintf("format of the output file, one of: %s", strings.Join(encoder.Formats(), ", ")))
 	rootCmd.Flags().BoolVar(&errorOnViolations, "error-on-violation", false, "exit with 3 if any violation are found will also print violations to console")
 	rootCmd.Flags().StringVar(&labelSelector, "label-selector", "", "an expression to select rules based on labels")
+	rootCmd.Flags().StringArrayVar(&targets, "target", []string{}, "migration target, such as eap8, to only evaluate the rules labeled konveyor.io/target=<target> of, can be given multiple times. The violations list the targets that selected their rule, which is evaluated once, and the summary counts the effort by target")
+	rootCmd.Flags().StringArrayVar(&categories, "category", []string{}, "category, mandatory, optional or potential, of the rules to evaluate, can be given multiple times. The other rules are skipped before they are evaluated")
+	rootCmd.Flags().IntVar(&minEffort, "min-effort", -1, "only evaluate the rules with at least this effort, the rules without an effort have an effort of 0, no minimum when negative")
+	rootCmd.Flags().IntVar(&maxEffort, "max-effort", -1, "only evaluate the rules with at most this effort, no maximum when negative")
+	rootCmd.Flags().StringArrayVar(&ruleLabels, "rule-label", []string{}, "label, such as konveyor.io/source=java-ee, the rules to evaluate have, can be given multiple times, the rules need all of them. A label without a value matches any value")
 	rootCmd.Flags().StringVar(&depLabelSelector, "dep-label-selector", "", "an expression to select dependencies based on labels. This will filter out the violations from these dependencies as well these dependencies when matching dependency conditions")
 	rootCmd.Flags().IntVar(&logLevel, "verbose", 9, "level for logging output")
 	rootCmd.Flags().BoolVar(&enableJaeger, "enable-jaeger", false, "enable tracer exports to jaeger endpoint")
@@ -62,12 +144,51 @@ func init() {
 	rootCmd.Flags().StringVar(&analysisMode, "analysis-mode", "", "select one of full or source-only to tell the providers what to analyize. This can be given on a per provider setting, but this flag will override")
 	rootCmd.Flags().BoolVar(&noDependencyRules, "no-dependency-rules", false, "Disable dependency analysis rules")
 	rootCmd.Flags().IntVar(&contextLines, "context-lines", 10, "When violation occurs, A part of source code is added to the output, So this flag configures the number of source code lines to be printed to the output.")
+	rootCmd.Flags().StringVar(&codeSnipMaxSize, "code-snip-max-size", "", "size, such as 4Ki, the code snippet of each incident is cut to, keeping the lines closest to the incident, no limit when empty")
+	rootCmd.Flags().StringVar(&checkpointFile, "checkpoint-file", "", "file to periodically save the evaluated rules and provider queries to, so that an analysis can be resumed")
+	rootCmd.Flags().DurationVar(&checkpointEvery, "checkpoint-interval", time.Minute, "how often the checkpoint file is saved")
+	rootCmd.Flags().BoolVar(&resume, "resume", false, "resume the analysis from the checkpoint file, skipping the rules that are already evaluated")
+	rootCmd.Flags().BoolVar(&incremental, "incremental", false, "save a digest of the analyzed files with the checkpoint file and, when it exists, resume from it evaluating its rules again only on the files that changed since")
+	rootCmd.Flags().DurationVar(&healthInterval, "provider-health-interval", 0, "how often the providers are checked to be responding, 0 disables the health checks")
+	rootCmd.Flags().StringArrayVar(&includePaths, "include-path", []string{}, "glob of the paths to analyze, can be given multiple times, all the paths are analyzed when not set")
+	rootCmd.Flags().StringArrayVar(&excludePaths, "exclude-path", []string{}, "glob of the paths not to analyze, such as vendor or node_modules, can be given multiple times")
+	rootCmd.Flags().IntVar(&healthFailures, "provider-health-failures", 3, "number of consecutive failed health checks after which a provider is restarted, or the analysis fails when it can not be restarted")
+	rootCmd.Flags().DurationVar(&callTimeout, "provider-call-timeout", 0, "timeout of each condition query sent to the providers, for the capabilities that have no callTimeouts in the provider settings, 0 disables it")
+	rootCmd.Flags().StringVar(&serveAddress, "serve", "", "address to serve the HTTP API on instead of running a single analysis, such as :8080 on localhost or 0.0.0.0:8080 on all the interfaces. The analyses use the providers of --provider-settings and the rules of --rules on the server")
+	rootCmd.Flags().IntVar(&maxAnalyses, "max-concurrent-analyses", 2, "number of analyses the HTTP API runs at the same time, the other ones wait")
+	rootCmd.Flags().BoolVar(&enrichLinks, "enrich-links", false, "fetch the pages the rule links point to and add their title and an excerpt to the links of the violations")
+	rootCmd.Flags().StringVar(&linksCache, "links-cache", "", "file to snapshot the fetched link pages to, so that they are not fetched again")
+	rootCmd.Flags().BoolVar(&linksOffline, "links-offline", false, "only enrich the links from the links cache, without fetching any page")
+	rootCmd.Flags().DurationVar(&linksTimeout, "links-timeout", 10*time.Second, "timeout to fetch each link page")
+	rootCmd.Flags().StringVar(&traceFile, "trace-file", "", "file to write how the conditions of each rule were evaluated to, as json when it ends with .json, as yaml otherwise")
+	rootCmd.Flags().BoolVar(&outputTrace, "output-trace", false, "add how the conditions of each rule were evaluated to the output, under debug")
+	rootCmd.Flags().StringVar(&dedupIncidents, "dedup-incidents", "", fmt.Sprintf("merge the incidents found at the same location by rules of different rulesets, that have the same %s, or the same %s", engine.DedupByRuleID, engine.DedupByMessage))
+	rootCmd.Flags().StringVar(&ruleOrder, "rule-order", string(engine.RuleOrderFile), fmt.Sprintf("order the rules are evaluated in, one of: %s, %s, %s", engine.RuleOrderFile, engine.RuleOrderCost, engine.RuleOrderMandatoryFirst))
+	rootCmd.Flags().Float64Var(&minConfidence, "min-confidence", 0, "drop the incidents that the providers found by a heuristic with a confidence lower than this, between 0 and 1, the precise incidents are always kept")
+	rootCmd.Flags().BoolVar(&canonicalOrder, "canonical-order", true, "sort the rulesets, their tags and the incidents of their violations by URI, line and message so that the output of the same analysis does not change between runs, disable it to keep the order the results were found in, as the --stream-file has them")
+	rootCmd.Flags().StringVar(&coverageFile, "coverage-file", "", "file to write the bill of analysis to, every file in the locations of the providers with the providers that examined it or why it was skipped, as json when it ends with .json, as yaml otherwise")
+	rootCmd.Flags().StringVar(&streamFile, "stream-file", "", "file to append the result of each rule to as soon as it is evaluated, so that the results are not lost when the analysis stops, see the finalize command")
+	rootCmd.Flags().StringVar(&streamFormat, "stream-format", stream.NDJSONFormat, fmt.Sprintf("format of the stream file, one of: %s, %s", stream.NDJSONFormat, stream.YAMLFormat))
+	rootCmd.Flags().StringVar(&ruleOverrides, "rule-overrides", "", "yaml file with a list of overrides of the category, effort or labels of rules by ruleID, applied before the rules are selected")
+	rootCmd.Flags().BoolVar(&vcsIgnore, "vcs-ignore", false, "exclude the files that the version control system, git, svn or hg, ignores in the locations of the providers, and its metadata directory")
+	rootCmd.Flags().BoolVar(&vcsRevision, "vcs-revision", false, "add the revision of the version control working copies the locations of the providers are in to the output")
+	rootCmd.Flags().StringVar(&maxOutputSize, "max-output-size", "", "size, such as 10Mi, the output file is kept under by cutting the incidents of its violations to --max-output-incidents and writing all of them to overflow files, no limit when empty")
+	rootCmd.Flags().IntVar(&maxOutputIncidents, "max-output-incidents", 50, "number of incidents each violation keeps in the output once it is over --max-output-size")
+	rootCmd.Flags().StringVar(&overflowDir, "overflow-dir", "", "directory the overflow files of the violations are written to, the output file followed by .overflow when empty")
+	rootCmd.Flags().StringVar(&metricsAddress, "metrics-address", "", "address to serve the Prometheus metrics of the rules, the provider queries and the language server connections on /metrics, such as :9090, not served when empty")
+	rootCmd.Flags().StringVar(&languageServers, "language-servers-dir", tooling.DefaultDir(), "directory the language servers pinned with languageServer in the provider settings are installed in")
+	rootCmd.Flags().StringVar(&planFile, "emit-plan", "", "file to write the plan of the analysis to before the rules are evaluated: the rules that are selected or skipped and why, in the order they are evaluated in, with the providers they query and their estimated cost, as json when it ends with .json, as yaml otherwise")
+	rootCmd.Flags().BoolVar(&planOnly, "plan-only", false, "exit once the plan is written to the --emit-plan file, without evaluating the rules")
+	rootCmd.Flags().StringArrayVar(&vars, "var", []string{}, "name=value of a variable the {{.vars.<name>}} of the condition parameters are replaced with, can be given multiple times")
+	rootCmd.Flags().StringVar(&varsFile, "vars-file", "", "yaml file of the names of the variables of the condition parameters to their values, the --var values override them")
+	rootCmd.Flags().StringVar(&reviewFile, "review-file", "", "yaml file of the review states, unreviewed, accepted or rejected, of the incidents of the potential violations. It is read to set the review state of the incidents and written back with the incidents found for the first time as unreviewed")
+	rootCmd.Flags().StringVar(&rulesCacheDir, "rules-cache-dir", fetch.DefaultDir(), "directory the rulesets given to --rules as git or oci references are fetched to")
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		println(err.Error())
-	} else if rootCmd.Flags().Changed("help") {
+	} else if rootCmd.Flags().Changed("help") || trackCmd.Flags().Changed("help") || replCmd.Flags().Changed("help") || rulesDiffCmd.Flags().Changed("help") || schemaCmd.Flags().Changed("help") || finalizeCmd.Flags().Changed("help") || daemonCmd.Flags().Changed("help") || doctorCmd.Flags().Changed("help") || diffCmd.Flags().Changed("help") {
 		return
 	}
 
@@ -86,11 +207,38 @@ func main() {
 	// need to do research on mapping in logrusr to level here TODO
 	logrusLog.SetLevel(logrus.Level(logLevel))
 
-	log := logrusr.New(logrusLog)
+	log := redact.Logger(logrusr.New(logrusLog))
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
 
+	if metricsAddress != "" {
+		go serveMetrics(log, metricsAddress)
+	}
+
+	if serveAddress != "" {
+		// the providers of the analyses are the ones of the settings of the
+		// server, the clients only give the locations they analyze
+		configs, err := provider.GetConfig(settingsFile)
+		if err != nil {
+			log.Error(err, "unable to get configuration")
+			os.Exit(1)
+		}
+		settings := server.Settings{ProviderConfig: configs}
+		if rootCmd.Flags().Changed("rules") {
+			settings.Rules = rulesFile
+		}
+		address := listenAddress(serveAddress)
+		log.Info("serving the analysis API", "address", address)
+		s := server.NewServer(ctx, log, maxAnalyses).WithSettings(settings)
+		if metricsAddress != "" {
+			s = s.WithMetrics(metrics.Default)
+		}
+		err = http.ListenAndServe(address, s)
+		log.Error(err, "unable to serve the analysis API", "address", address)
+		os.Exit(1)
+	}
+
 	selectors := []engine.RuleSelector{}
 	if labelSelector != "" {
 		selector, err := labels.NewLabelSelector[*engine.RuleMeta](labelSelector)
@@ -131,19 +279,157 @@ func main() {
 		log.Error(err, "unable to get configuration")
 		os.Exit(1)
 	}
+	err = provider.InstallLanguageServers(ctx, configs, tooling.NewManager(languageServers, log))
+	if err != nil {
+		log.Error(err, "unable to install the language servers")
+		os.Exit(1)
+	}
+	rulePaths, err := fetch.NewFetcher(rulesCacheDir, log).Resolve(ctx, rulesFile)
+	if err != nil {
+		log.Error(err, "unable to fetch the rulesets")
+		os.Exit(1)
+	}
+
+	revisions := []konveyor.Revision{}
+	if vcsIgnore || vcsRevision {
+		revisions = applyWorkingCopies(ctx, log, configs)
+	}
+
+	engineOptions := []engine.Option{
+		engine.WithIncidentLimit(limitIncidents),
+		engine.WithCodeSnipLimit(limitCodeSnips),
+		engine.WithContextLines(contextLines),
+		engine.WithTrace(traceFile != "" || outputTrace),
+		engine.WithDeduplication(engine.DedupIdentity(dedupIncidents)),
+		engine.WithRuleOrder(engine.RuleOrder(ruleOrder)),
+		engine.WithMinConfidence(minConfidence),
+		engine.WithCanonicalOrder(canonicalOrder),
+		engine.WithLocation(provider.BuiltinLocation(configs)),
+	}
+	if codeSnipMaxSize != "" {
+		size, _ := overflow.ParseSize(codeSnipMaxSize)
+		engineOptions = append(engineOptions, engine.WithCodeSnipMaxSize(int(size)))
+	}
+	if ruleOverrides != "" {
+		overrides, err := engine.LoadRuleOverrides(ruleOverrides)
+		if err != nil {
+			log.Error(err, "unable to load the rule overrides", "file", ruleOverrides)
+			os.Exit(1)
+		}
+		engineOptions = append(engineOptions, engine.WithRuleOverrides(overrides))
+	}
+	ruleVars, err := parser.LoadVars(varsFile, vars)
+	if err != nil {
+		log.Error(err, "unable to load the variables of the rules")
+		os.Exit(1)
+	}
+	var reviews *review.File
+	if reviewFile != "" {
+		reviews, err = review.Load(reviewFile)
+		if err != nil {
+			log.Error(err, "unable to load the review file", "file", reviewFile)
+			os.Exit(1)
+		}
+	}
+	var rewriter uris.Rewriter
+	if uriRewritesFile != "" {
+		rewriter, err = uris.Load(uriRewritesFile)
+		if err != nil {
+			log.Error(err, "unable to load the uri rewrites", "file", uriRewritesFile)
+			os.Exit(1)
+		}
+	}
+	if len(targets) > 0 {
+		engineTargets := []engine.Target{}
+		for _, name := range targets {
+			selector, err := labels.NewTargetSelector[*engine.RuleMeta](name)
+			if err != nil {
+				log.Error(err, "invalid target", "target", name)
+				os.Exit(1)
+			}
+			engineTargets = append(engineTargets, engine.Target{Name: name, Selector: selector})
+		}
+		engineOptions = append(engineOptions, engine.WithTargets(engineTargets...))
+	}
+	if scope := getScope(); scope != nil {
+		engineOptions = append(engineOptions, engine.WithScope(scope))
+	}
+	if filter := getRuleFilter(); filter != nil {
+		engineOptions = append(engineOptions, engine.WithRuleFilter(*filter))
+	}
+	if metricsAddress != "" {
+		engineOptions = append(engineOptions, engine.WithRuleMiddleware(metrics.RuleMiddleware(metrics.Default)))
+	}
+	var queryCache *provider.QueryCache
+	if checkpointFile != "" {
+		queryCache = provider.NewQueryCache()
+		engineOptions = append(engineOptions,
+			engine.WithCheckpoint(checkpointFile, checkpointEvery),
+			engine.WithResume(resume),
+			engine.WithQueryCache(queryCache),
+		)
+		if metricsAddress != "" {
+			metrics.RegisterQueryCache(metrics.Default, queryCache)
+		}
+	}
+	if incremental {
+		workspace, err := digest.NewWorkspace(configLocations(configs))
+		if err != nil {
+			log.Error(err, "unable to compute the digest of the analyzed files")
+			os.Exit(1)
+		}
+		_, err = os.Stat(checkpointFile)
+		engineOptions = append(engineOptions,
+			engine.WithWorkspace(workspace),
+			engine.WithResume(resume || err == nil),
+		)
+	}
+
+	var streamWriter *stream.Writer
+	if streamFile != "" {
+		streamWriter, err = stream.NewWriter(streamFile, streamFormat)
+		if err != nil {
+			log.Error(err, "unable to create the stream file", "file", streamFile)
+			os.Exit(1)
+		}
+		engineOptions = append(engineOptions, engine.WithResultWriter(streamWriter))
+	}
+
+	// the health monitor and the warnings need the providers before they are wrapped
+	monitoredProviders := map[string]provider.InternalProviderClient{}
+	engineOptions = append(engineOptions, engine.WithWarningReporter(provider.NewWarningReporter(monitoredProviders)))
+	var healthMonitor *provider.HealthMonitor
+	if healthInterval > 0 {
+		healthMonitor = provider.NewHealthMonitor(monitoredProviders, healthInterval, healthFailures, func(name string, err error) {
+			log.Error(err, "provider is not responding, stopping the analysis", "provider", name)
+			cancelFunc()
+		}, log)
+		engineOptions = append(engineOptions, engine.WithHealthReporter(healthMonitor))
+	}
 
 	//start up the rule eng
 	eng := engine.CreateRuleEngine(ctx,
 		10,
 		log,
-		engine.WithIncidentLimit(limitIncidents),
-		engine.WithCodeSnipLimit(limitCodeSnips),
-		engine.WithContextLines(contextLines),
+		engineOptions...,
 	)
 
 	providers := map[string]provider.InternalProviderClient{}
 
+	var pooled []provider.PooledProvider
+	if providerPoolFile != "" {
+		pooled, err = provider.ReadPool(providerPoolFile)
+		if err != nil {
+			// the daemon is not running, the providers are started
+			log.Error(err, "unable to read the provider pool, starting the providers", "file", providerPoolFile)
+		}
+	}
+
 	for _, config := range configs {
+		if attached, ok := provider.AttachToPool(config, pooled); ok {
+			log.Info("attaching to the pooled provider", "provider", config.Name, "address", attached.Address)
+			config = attached
+		}
 		config.ContextLines = contextLines
 		// IF analsyis mode is set from the CLI, then we will override this for each init config
 		if analysisMode != "" {
@@ -159,13 +445,34 @@ func main() {
 			log.Error(err, "unable to create provider client")
 			os.Exit(1)
 		}
-		providers[config.Name] = prov
 		if s, ok := prov.(provider.Startable); ok {
 			if err := s.Start(ctx); err != nil {
 				log.Error(err, "unable to create provider client")
 				os.Exit(1)
 			}
 		}
+		monitoredProviders[config.Name] = prov
+		if timeouts := getCallTimeouts(config); timeouts != nil {
+			prov = provider.WithCallTimeouts(config.Name, prov, *timeouts)
+		}
+		// every attempt has its own timeout
+		if config.Retry != nil {
+			prov = provider.WithRetry(config.Name, prov, *config.Retry, log)
+		}
+		// the queries that time out are recorded as errors, the time they
+		// wait for the rate limit is not recorded
+		if metricsAddress != "" {
+			prov = metrics.WithProviderMetrics(config.Name, prov, metrics.Default)
+		}
+		// the waiting queries do not count in their timeout and the cached
+		// ones are not limited
+		if config.RateLimit != nil {
+			prov = provider.WithRateLimit(prov, *config.RateLimit)
+		}
+		if queryCache != nil {
+			prov = provider.WithQueryCache(config.Name, prov, queryCache)
+		}
+		providers[config.Name] = prov
 	}
 
 	parser := parser.RuleParser{
@@ -173,10 +480,11 @@ func main() {
 		Log:                  log.WithName("parser"),
 		NoDependencyRules:    noDependencyRules,
 		DepLabelSelector:     dependencyLabelSelector,
+		Vars:                 ruleVars,
 	}
 	ruleSets := []engine.RuleSet{}
 	needProviders := map[string]provider.InternalProviderClient{}
-	for _, f := range rulesFile {
+	for _, f := range rulePaths {
 		internRuleSet, internNeedProviders, err := parser.LoadRules(f)
 		if err != nil {
 			log.WithValues("fileName", f).Error(err, "unable to parse all the rules for ruleset")
@@ -186,17 +494,72 @@ func main() {
 			needProviders[k] = v
 		}
 	}
-	// Now that we have all the providers, we need to start them.
-	for name, provider := range needProviders {
-		err := provider.ProviderInit(ctx)
+	if ruleString != "" {
+		inlineRuleSet, inlineNeedProviders, err := parseInlineRules(parser, ruleString, os.Stdin)
 		if err != nil {
-			log.Error(err, "unable to init the providers", "provider", name)
+			log.Error(err, "unable to parse the inline rules")
 			os.Exit(1)
 		}
+		ruleSets = append(ruleSets, inlineRuleSet)
+		for k, v := range inlineNeedProviders {
+			needProviders[k] = v
+		}
+	}
+	if planFile != "" {
+		if err := writeYAMLOrJSON(planFile, eng.Plan(ruleSets, selectors...)); err != nil {
+			log.Error(err, "unable to write the plan file", "file", planFile)
+			os.Exit(1)
+		}
+		if planOnly {
+			for _, provider := range providers {
+				provider.Stop()
+			}
+			eng.Stop()
+			return
+		}
+	}
+	// Now that we have all the providers, we need to start them, with the
+	// providers they depend on.
+	dependsOn := provider.DependsOn(configs)
+	needProviders = provider.WithDependencies(needProviders, providers, dependsOn)
+	manifest := engine.NewManifest(ruleSets)
+	if err := provider.InitProviders(ctx, needProviders, dependsOn); err != nil {
+		log.Error(err, "unable to init the providers")
+		os.Exit(1)
+	}
+
+	if healthMonitor != nil {
+		healthMonitor.Start(ctx)
 	}
 
 	rulesets := eng.RunRules(ctx, ruleSets, selectors...)
+	providersFailed := false
+	for _, h := range eng.Health() {
+		if h.Failed {
+			providersFailed = true
+			log.Error(fmt.Errorf("%s", h.LastError), "provider failed during the analysis, results are incomplete", "provider", h.Name)
+		}
+	}
+	warnings := eng.Warnings()
+	for _, w := range warnings {
+		log.Info("provider warning", "provider", w.Provider, "message", w.Message, "count", w.Count)
+	}
+	traces := eng.Traces()
 	eng.Stop()
+	reporters := map[string]provider.InternalProviderClient{}
+	for name := range needProviders {
+		reporters[name] = monitoredProviders[name]
+	}
+	for name, stats := range provider.FullStats(ctx, log, reporters) {
+		if metricsAddress != "" {
+			metrics.RecordProviderStats(metrics.Default, name, stats)
+		}
+	}
+	if streamWriter != nil {
+		if err := streamWriter.Close(); err != nil {
+			log.Error(err, "unable to write the stream file", "file", streamFile)
+		}
+	}
 
 	for _, provider := range needProviders {
 		provider.Stop()
@@ -205,19 +568,135 @@ func main() {
 	sort.SliceStable(rulesets, func(i, j int) bool {
 		return rulesets[i].Name < rulesets[j].Name
 	})
+	if rewriter != nil {
+		uris.Apply(rewriter, rulesets, nil)
+	}
+
+	if reviews != nil {
+		counts := reviews.Apply(rulesets)
+		log.Info("potential incidents reviewed", "unreviewed", counts.Unreviewed, "accepted", counts.Accepted, "rejected", counts.Rejected)
+		if err := reviews.Save(reviewFile); err != nil {
+			log.Error(err, "unable to write the review file", "file", reviewFile)
+		}
+	}
+
+	if enrichLinks {
+		enricher, err := links.NewEnricher(linksCache, linksOffline, linksTimeout)
+		if err != nil {
+			log.Error(err, "unable to load the links cache", "file", linksCache)
+			os.Exit(1)
+		}
+		for _, err := range enricher.Enrich(ctx, rulesets) {
+			log.V(5).Info("link not enriched", "error", err.Error())
+		}
+		if err := enricher.Save(); err != nil {
+			log.Error(err, "unable to save the links cache", "file", linksCache)
+		}
+	}
+
+	doc := encoder.Document{
+		RuleSets:  rulesets,
+		Warnings:  warnings,
+		Revisions: revisions,
+	}
+	if outputSummary {
+		summary := engine.Summarize(rulesets)
+		doc.Summary = &summary
+	}
+	if outputManifest {
+		manifest.EndTime = time.Now().UTC()
+		manifest.Providers = providerManifests(configs, needProviders)
+		manifest.LabelSelector = labelSelector
+		manifest.DepLabelSelector = depLabelSelector
+		manifest.Targets = targets
+		if scope := getScope(); scope != nil {
+			manifest.Scope = &konveyor.PlanScope{Include: scope.Include, Exclude: scope.Exclude}
+		}
+		doc.Manifest = &manifest
+	}
+	if outputTrace {
+		doc.Debug = &konveyor.Debug{Trace: traces}
+	}
+	if traceFile != "" {
+		if err := writeTrace(traceFile, traces); err != nil {
+			log.Error(err, "unable to write the trace file", "file", traceFile)
+		}
+	}
+	if coverageFile != "" {
+		report, err := coverage.Build(configs, monitoredProviders, ruleSets, rulesets, getScope())
+		if err == nil {
+			err = writeYAMLOrJSON(coverageFile, report)
+		}
+		if err != nil {
+			log.Error(err, "unable to write the coverage file", "file", coverageFile)
+		}
+	}
 
 	// Write results out to CLI
-	b, _ := yaml.Marshal(rulesets)
 	if errorOnViolations && len(rulesets) != 0 {
-		fmt.Printf("%s", string(b))
+		enc, err := encoder.New(outputFormat, os.Stdout)
+		if err != nil {
+			log.Error(err, "unable to create output encoder", "format", outputFormat)
+			os.Exit(1)
+		}
+		if err := encoder.EncodeDocument(enc, doc); err != nil {
+			log.Error(err, "error writing the output")
+			os.Exit(1)
+		}
 		os.Exit(EXIT_ON_ERROR_CODE)
 	}
 
-	err = os.WriteFile(outputViolations, b, 0644)
+	if outputFormat != encoder.ConsoleFormat {
+		if err := limitOutputSize(log, &doc, outputFormat, outputViolations, maxOutputSize, maxOutputIncidents, overflowDir); err != nil {
+			log.Error(err, "unable to limit the size of the output", "file", outputViolations)
+			os.Exit(1)
+		}
+	}
+
+	// the console output is printed unless an output file is given
+	f := os.Stdout
+	if outputFormat != encoder.ConsoleFormat || rootCmd.Flags().Changed("output-file") {
+		f, err = os.Create(outputViolations)
+		if err != nil {
+			log.Error(err, "error writing output file", "file", outputViolations)
+			os.Exit(1) // Treat the error as a fatal error
+		}
+		defer f.Close()
+	}
+	enc, err := encoder.New(outputFormat, f)
+	if err != nil {
+		log.Error(err, "unable to create output encoder", "format", outputFormat)
+		os.Exit(1)
+	}
+	err = encoder.EncodeDocument(enc, doc)
 	if err != nil {
 		log.Error(err, "error writing output file", "file", outputViolations)
 		os.Exit(1) // Treat the error as a fatal error
-	}	
+	}
+	if providersFailed {
+		os.Exit(1)
+	}
+}
+
+// inlineRuleSetName is the ruleset of the rules given with --rule-string
+const inlineRuleSetName = "inline"
+
+// parseInlineRules parses the rules given with --rule-string in the inline
+// ruleset, reading them from in when it is -
+func parseInlineRules(p parser.RuleParser, rules string, in io.Reader) (engine.RuleSet, map[string]provider.InternalProviderClient, error) {
+	content := []byte(rules)
+	if rules == "-" {
+		var err error
+		content, err = io.ReadAll(in)
+		if err != nil {
+			return engine.RuleSet{}, nil, fmt.Errorf("unable to read the rules from the standard input: %w", err)
+		}
+	}
+	inlineRules, needProviders, err := p.ParseInline(content)
+	if err != nil {
+		return engine.RuleSet{}, nil, err
+	}
+	return engine.RuleSet{Name: inlineRuleSetName, Rules: inlineRules, Digest: parser.Digest(content)}, needProviders, nil
 }
 
 func validateFlags() error {
@@ -225,13 +704,73 @@ func validateFlags() error {
 	if err != nil {
 		return fmt.Errorf("unable to find provider settings file")
 	}
+	if serveAddress != "" {
+		if maxAnalyses < 1 {
+			return fmt.Errorf("max concurrent analyses must be at least 1")
+		}
+		return nil
+	}
 
+	if ruleString != "" && !rootCmd.Flags().Changed("rules") {
+		// the default rules are only loaded when no rule is given inline
+		rulesFile = nil
+	}
 	for _, f := range rulesFile {
+		if fetch.IsRemote(f) {
+			if _, err := fetch.ParseReference(f); err != nil {
+				return err
+			}
+			continue
+		}
 		_, err = os.Stat(f)
 		if err != nil {
 			return fmt.Errorf("unable to find rule path or file")
 		}
 	}
+	if _, err := encoder.New(outputFormat, nil); err != nil {
+		return err
+	}
+	if err := getScope().Validate(); err != nil {
+		return err
+	}
+	if filter := getRuleFilter(); filter != nil {
+		if err := filter.Validate(); err != nil {
+			return fmt.Errorf("invalid rule filter: %w", err)
+		}
+	}
+	if healthInterval > 0 && healthFailures < 1 {
+		return fmt.Errorf("provider health failures must be at least 1")
+	}
+	if err := engine.RuleOrder(ruleOrder).Validate(); err != nil {
+		return err
+	}
+	if err := engine.DedupIdentity(dedupIncidents).Validate(); err != nil {
+		return err
+	}
+	if err := engine.ValidateConfidence(minConfidence); err != nil {
+		return fmt.Errorf("invalid min confidence: %w", err)
+	}
+	if err := stream.ValidateFormat(streamFormat); err != nil {
+		return err
+	}
+	if err := validateOutputSize(maxOutputSize, maxOutputIncidents); err != nil {
+		return err
+	}
+	if _, err := overflow.ParseSize(codeSnipMaxSize); err != nil {
+		return fmt.Errorf("invalid code snip max size: %w", err)
+	}
+	if callTimeout < 0 {
+		return fmt.Errorf("provider call timeout must not be negative")
+	}
+	if resume && checkpointFile == "" {
+		return fmt.Errorf("a checkpoint file is required to resume an analysis")
+	}
+	if incremental && checkpointFile == "" {
+		return fmt.Errorf("a checkpoint file is required for an incremental analysis")
+	}
+	if planOnly && planFile == "" {
+		return fmt.Errorf("a plan file is required to only plan the analysis")
+	}
 	m := provider.AnalysisMode(strings.ToLower(analysisMode))
 	if analysisMode != "" && !(m == provider.FullAnalysisMode || m == provider.SourceOnlyAnalysisMode) {
 		return fmt.Errorf("must select one of %s or %s for analysis mode", provider.FullAnalysisMode, provider.SourceOnlyAnalysisMode)
@@ -239,3 +778,183 @@ func validateFlags() error {
 
 	return nil
 }
+
+func getScope() *engine.Scope {
+	if len(includePaths) == 0 && len(excludePaths) == 0 {
+		return nil
+	}
+	return &engine.Scope{
+		Include: includePaths,
+		Exclude: excludePaths,
+	}
+}
+
+// getRuleFilter returns the filter of the --category, --min-effort,
+// --max-effort and --rule-label flags, nil when none is given
+func getRuleFilter() *engine.RuleFilter {
+	if len(categories) == 0 && minEffort < 0 && maxEffort < 0 && len(ruleLabels) == 0 {
+		return nil
+	}
+	filter := &engine.RuleFilter{Labels: ruleLabels}
+	for _, c := range categories {
+		filter.Categories = append(filter.Categories, konveyor.Category(c))
+	}
+	if minEffort >= 0 {
+		filter.MinEffort = &minEffort
+	}
+	if maxEffort >= 0 {
+		filter.MaxEffort = &maxEffort
+	}
+	return filter
+}
+
+// applyWorkingCopies finds the version control working copies the locations
+// of the providers are in, adds the files they ignore to the excluded paths
+// with --vcs-ignore and returns their revisions with --vcs-revision
+func applyWorkingCopies(ctx context.Context, log logr.Logger, configs []provider.Config) []konveyor.Revision {
+	revisions := []konveyor.Revision{}
+	seen := map[string]bool{}
+	excluded := map[string]bool{}
+	for _, config := range configs {
+		for _, ic := range config.InitConfig {
+			for _, location := range ic.Roots() {
+				wc, err := vcs.Detect(location)
+				if err != nil || wc == nil {
+					continue
+				}
+				if vcsIgnore {
+					globs, err := wc.ExcludeGlobs(ctx, location)
+					if err != nil {
+						log.Error(err, "unable to get the files ignored by the version control system", "location", location, "system", wc.System.Name())
+					}
+					for _, g := range globs {
+						if !excluded[g] {
+							excluded[g] = true
+							excludePaths = append(excludePaths, g)
+						}
+					}
+				}
+				if !vcsRevision || seen[wc.Root] {
+					continue
+				}
+				seen[wc.Root] = true
+				revision, err := wc.Revision(ctx)
+				if err != nil {
+					log.Error(err, "unable to get the revision of the working copy", "root", wc.Root, "system", wc.System.Name())
+					continue
+				}
+				revisions = append(revisions, revision)
+			}
+		}
+	}
+	return revisions
+}
+
+// configLocations returns the locations of the init configs of the providers
+func configLocations(configs []provider.Config) []string {
+	locations := []string{}
+	for _, config := range configs {
+		for _, ic := range config.InitConfig {
+			locations = append(locations, ic.Roots()...)
+		}
+	}
+	return locations
+}
+
+// getCallTimeouts returns the call timeouts of the provider settings, with
+// --provider-call-timeout as their default, or nil when there are none
+func getCallTimeouts(config provider.Config) *provider.CallTimeouts {
+	timeouts := provider.CallTimeouts{}
+	if config.CallTimeouts != nil {
+		timeouts = *config.CallTimeouts
+	}
+	if timeouts.Default == "" && callTimeout > 0 {
+		timeouts.Default = callTimeout.String()
+	}
+	if timeouts.Default == "" && len(timeouts.Capabilities) == 0 {
+		return nil
+	}
+	return &timeouts
+}
+
+// writeTrace writes the traces as json when the file has a .json extension,
+// as yaml otherwise.
+// validateOutputSize checks the size limit of the output and the incidents
+// the violations keep under it
+// listenAddress returns the address the API listens on, localhost when the
+// address has no host, so that the API is only reachable from other hosts
+// when it is asked for, such as with 0.0.0.0:8080
+func listenAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// serveMetrics serves the metrics of the analyzer on /metrics of the address
+// until the analyzer exits
+func serveMetrics(log logr.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	log.Info("serving the metrics", "address", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Error(err, "unable to serve the metrics", "address", address)
+	}
+}
+
+func validateOutputSize(maxSize string, maxIncidents int) error {
+	if _, err := overflow.ParseSize(maxSize); err != nil {
+		return fmt.Errorf("invalid max output size: %w", err)
+	}
+	if maxIncidents < 0 {
+		return fmt.Errorf("max output incidents must not be negative")
+	}
+	return nil
+}
+
+// limitOutputSize keeps the output under its size limit, the incidents that
+// are cut are written to the overflow files next to the output file
+func limitOutputSize(log logr.Logger, doc *encoder.Document, format, outputFile, maxSize string, maxIncidents int, dir string) error {
+	limit, err := overflow.ParseSize(maxSize)
+	if err != nil || limit == 0 {
+		return err
+	}
+	if dir == "" {
+		dir = outputFile + ".overflow"
+	}
+	size, err := overflow.Limit(doc, overflow.Options{
+		MaxSize:      limit,
+		MaxIncidents: maxIncidents,
+		Dir:          dir,
+		BaseDir:      filepath.Dir(outputFile),
+		Format:       format,
+	})
+	if err != nil {
+		return err
+	}
+	if size > limit {
+		log.Info("the output is over its size limit once the incidents of its violations are cut", "size", size, "limit", limit)
+	}
+	return nil
+}
+
+func writeTrace(file string, traces []konveyor.RuleTrace) error {
+	return writeYAMLOrJSON(file, konveyor.Debug{Trace: traces})
+}
+
+// writeYAMLOrJSON writes the value as json when the file ends with .json, as
+// yaml otherwise
+func writeYAMLOrJSON(file string, v interface{}) error {
+	var content []byte
+	var err error
+	if strings.HasSuffix(strings.ToLower(file), ".json") {
+		content, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		content, err = yaml.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0644)
+}
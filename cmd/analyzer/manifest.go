@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// providerManifests returns the providers of the configs the analysis
+// needs, with a digest of their settings
+func providerManifests(configs []provider.Config, needed map[string]provider.InternalProviderClient) []konveyor.ManifestProvider {
+	providers := []konveyor.ManifestProvider{}
+	for _, config := range configs {
+		if _, ok := needed[config.Name]; !ok {
+			continue
+		}
+		p := konveyor.ManifestProvider{Name: config.Name}
+		if config.LanguageServer != nil {
+			p.LanguageServer = config.LanguageServer.Name
+			if config.LanguageServer.Version != "" {
+				p.LanguageServer += "@" + config.LanguageServer.Version
+			}
+		}
+		// the maps of the init configs are marshalled with their keys
+		// sorted, the same settings always have the same digest
+		content, err := json.Marshal(config)
+		if err == nil {
+			sum := sha256.Sum256(content)
+			p.SettingsDigest = "sha256:" + hex.EncodeToString(sum[:])
+		}
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
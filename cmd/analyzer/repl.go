@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+	"github.com/konveyor/analyzer-lsp/redact"
+	"github.com/konveyor/analyzer-lsp/repl"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	replSettingsFile    string
+	replLogLevel        int
+	replLimitIncidents  int
+	replLimitCodeSnips  int
+	replContextLines    int
+	replLanguageServers string
+
+	replCmd = &cobra.Command{
+		Use:   "repl",
+		Short: "Evaluate the rules and conditions typed on the standard input, keeping the providers running between them",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := runREPL(); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	replCmd.Flags().StringVar(&replSettingsFile, "provider-settings", "provider_settings.json", "path to the provider settings")
+	replCmd.Flags().IntVar(&replLogLevel, "verbose", 2, "level for logging output, the logs are written to the standard error")
+	replCmd.Flags().IntVar(&replLimitIncidents, "limit-incidents", 1500, "Set this to the limit incidents that a given rule can give, zero means no limit")
+	replCmd.Flags().IntVar(&replLimitCodeSnips, "limit-code-snips", 20, "limit the number code snippets that are retrieved for a file while evaluating a rule, 0 means no limit")
+	replCmd.Flags().IntVar(&replContextLines, "context-lines", 10, "number of source code lines around the incidents")
+	replCmd.Flags().StringVar(&replLanguageServers, "language-servers-dir", tooling.DefaultDir(), "directory the language servers pinned with languageServer in the provider settings are installed in")
+	rootCmd.AddCommand(replCmd)
+}
+
+func runREPL() error {
+	yaml.FutureLineWrap()
+
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(os.Stderr)
+	logrusLog.SetFormatter(&logrus.TextFormatter{})
+	logrusLog.SetLevel(logrus.Level(replLogLevel))
+	log := redact.Logger(logrusr.New(logrusLog))
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	configs, err := provider.GetConfig(replSettingsFile)
+	if err != nil {
+		return fmt.Errorf("unable to get configuration: %w", err)
+	}
+	if err := provider.InstallLanguageServers(ctx, configs, tooling.NewManager(replLanguageServers, log)); err != nil {
+		return fmt.Errorf("unable to install the language servers: %w", err)
+	}
+	for i := range configs {
+		configs[i].ContextLines = replContextLines
+	}
+
+	fmt.Fprintln(os.Stderr, "starting the providers...")
+	s, err := repl.NewSession(ctx, log, configs, os.Stdout,
+		engine.WithIncidentLimit(replLimitIncidents),
+		engine.WithCodeSnipLimit(replLimitCodeSnips),
+		engine.WithContextLines(replContextLines),
+		engine.WithLocation(provider.BuiltinLocation(configs)),
+	)
+	if err != nil {
+		return err
+	}
+	defer s.Stop()
+	return s.Run(ctx, os.Stdin)
+}
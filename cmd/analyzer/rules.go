@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	rulesDiffOutputFile   string
+	rulesDiffOutputFormat string
+
+	rulesCmd = &cobra.Command{
+		Use:   "rules",
+		Short: "Inspect rules without running an analysis",
+		Run: func(c *cobra.Command, args []string) {
+			c.Help()
+			os.Exit(0)
+		},
+	}
+
+	rulesDiffCmd = &cobra.Command{
+		Use:   "diff <previous rules> <current rules>",
+		Short: "List the rules that are added, removed or changed between two versions of the rules",
+		Args:  cobra.ExactArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			if err := diffRules(args[0], args[1]); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	rulesDiffCmd.Flags().StringVar(&rulesDiffOutputFile, "output-file", "", "filepath to store the diff, it is printed when not set")
+	rulesDiffCmd.Flags().StringVar(&rulesDiffOutputFormat, "output-format", encoder.YAMLFormat, fmt.Sprintf("format of the diff, one of: %s, %s", encoder.JSONFormat, encoder.YAMLFormat))
+	rulesCmd.AddCommand(rulesDiffCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+func diffRules(previousRules, currentRules string) error {
+	yaml.FutureLineWrap()
+	diff, err := parser.DiffRules(previousRules, currentRules)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	switch rulesDiffOutputFormat {
+	case encoder.YAMLFormat:
+		content, err = yaml.Marshal(diff)
+	case encoder.JSONFormat:
+		content, err = json.MarshalIndent(diff, "", "  ")
+		content = append(content, '\n')
+	default:
+		return fmt.Errorf("unknown output format: %s", rulesDiffOutputFormat)
+	}
+	if err != nil {
+		return err
+	}
+	if rulesDiffOutputFile == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	fmt.Printf("added: %d, removed: %d, changed: %d\n", diff.Added, diff.Removed, diff.Changed)
+	return os.WriteFile(rulesDiffOutputFile, content, 0644)
+}
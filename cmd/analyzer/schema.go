@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "provider-schema [provider]",
+	Short: "Print the json schema of the init configs of a provider, or of all the providers that publish one",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		if err := printSchema(args); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func printSchema(args []string) error {
+	var schema interface{}
+	if len(args) == 1 {
+		schema = provider.InitConfigSchema(args[0])
+	} else {
+		schemas := map[string]*openapi3.Schema{}
+		for _, name := range provider.ConfigSchemaNames() {
+			schemas[name] = provider.InitConfigSchema(name)
+		}
+		schema = schemas
+	}
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/tracking"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	trackOutputFile   string
+	trackOutputFormat string
+
+	trackCmd = &cobra.Command{
+		Use:   "track <previous output> <current output>",
+		Short: "Label the incidents of two analyses as new, persisting or resolved",
+		Args:  cobra.ExactArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			if err := track(args[0], args[1]); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		},
+	}
+)
+
+func init() {
+	trackCmd.Flags().StringVar(&trackOutputFile, "output-file", "tracking.yaml", "filepath to store the tracked incidents")
+	trackCmd.Flags().StringVar(&trackOutputFormat, "output-format", encoder.YAMLFormat, fmt.Sprintf("format of the output file, one of: %s, %s", encoder.JSONFormat, encoder.YAMLFormat))
+	rootCmd.AddCommand(trackCmd)
+}
+
+func track(previousFile, currentFile string) error {
+	yaml.FutureLineWrap()
+	previous, err := readOutput(previousFile)
+	if err != nil {
+		return err
+	}
+	current, err := readOutput(currentFile)
+	if err != nil {
+		return err
+	}
+	report := tracking.Track(previous, current)
+
+	var content []byte
+	switch trackOutputFormat {
+	case encoder.YAMLFormat:
+		content, err = yaml.Marshal(report)
+	case encoder.JSONFormat:
+		content, err = json.MarshalIndent(report, "", "  ")
+	default:
+		return fmt.Errorf("unknown output format: %s", trackOutputFormat)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("new: %d, persisting: %d, resolved: %d\n", report.New, report.Persisting, report.Resolved)
+	return os.WriteFile(trackOutputFile, content, 0644)
+}
+
+func readOutput(file string) ([]konveyor.RuleSet, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	rulesets, err := encoder.DecodeRuleSets(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the analysis output %s: %w", file, err)
+	}
+	return rulesets, nil
+}
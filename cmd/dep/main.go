@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"sort"
 
 	"github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/uris"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+	"github.com/konveyor/analyzer-lsp/redact"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -21,6 +24,9 @@ var (
 	treeOutput       bool
 	outputFile       string
 	depLabelSelector string
+	languageServers  string
+	licenses         bool
+	uriRewritesFile  string
 
 	rootCmd = &cobra.Command{
 		Use:   "analyze",
@@ -34,6 +40,9 @@ func init() {
 	rootCmd.Flags().BoolVar(&treeOutput, "tree", false, "output dependencies as a tree")
 	rootCmd.Flags().StringVar(&outputFile, "output-file", "output.yaml", "path to output file")
 	rootCmd.Flags().StringVar(&depLabelSelector, "dep-label-selector", "", "an expression to select dependencies based on labels provided by the provider")
+	rootCmd.Flags().StringVar(&languageServers, "language-servers-dir", tooling.DefaultDir(), "directory the language servers pinned with languageServer in the provider settings are installed in")
+	rootCmd.Flags().BoolVar(&licenses, "licenses", false, "detect the licenses of the dependencies, which is slower as the poms, jars or modules of every dependency are read")
+	rootCmd.Flags().StringVar(&uriRewritesFile, "uri-rewrites", "", "yaml or json file of the rewrites of the URIs of the files of the dependencies in the output, as the analysis does with the same flag")
 }
 
 func main() {
@@ -44,7 +53,7 @@ func main() {
 	logrusLog := logrus.New()
 	logrusLog.SetOutput(os.Stdout)
 	logrusLog.SetFormatter(&logrus.TextFormatter{})
-	log := logrusr.New(logrusLog)
+	log := redact.Logger(logrusr.New(logrusLog))
 
 	err := validateFlags()
 	if err != nil {
@@ -65,6 +74,7 @@ func main() {
 	defer cancelFunc()
 
 	providers := map[string]provider.Client{}
+	started := map[string]provider.InternalProviderClient{}
 
 	// Get the configs
 	configs, err := provider.GetConfig(providerSettings)
@@ -72,8 +82,27 @@ func main() {
 		log.Error(err, "unable to get configuration")
 		os.Exit(1)
 	}
+	err = provider.InstallLanguageServers(ctx, configs, tooling.NewManager(languageServers, log))
+	if err != nil {
+		log.Error(err, "unable to install the language servers")
+		os.Exit(1)
+	}
 
 	for _, config := range configs {
+		// the providers only detect the licenses when they are asked to
+		if licenses {
+			inits := []provider.InitConfig{}
+			for _, i := range config.InitConfig {
+				specific := map[string]interface{}{}
+				for k, v := range i.ProviderSpecificConfig {
+					specific[k] = v
+				}
+				specific[provider.DependencyLicensesConfigKey] = true
+				i.ProviderSpecificConfig = specific
+				inits = append(inits, i)
+			}
+			config.InitConfig = inits
+		}
 		prov, err := lib.GetProviderClient(config, log)
 		if err != nil {
 			log.Error(err, "unable to create provider client")
@@ -85,13 +114,21 @@ func main() {
 				os.Exit(1)
 			}
 		}
-		err = prov.ProviderInit(ctx)
+		providers[config.Name] = prov
+		started[config.Name] = prov
+	}
+	if err := provider.InitProviders(ctx, started, provider.DependsOn(configs)); err != nil {
+		log.Error(err, "unable to init the providers")
+		os.Exit(1)
+	}
+
+	rewriter := uris.Chain()
+	if uriRewritesFile != "" {
+		rewriter, err = uris.Load(uriRewritesFile)
 		if err != nil {
-			log.Error(err, "unable to init the providers", "provider", config.Name)
+			log.Error(err, "unable to load the uri rewrites", "file", uriRewritesFile)
 			os.Exit(1)
 		}
-		providers[config.Name] = prov
-
 	}
 
 	var depsFlat []konveyor.DepsFlatItem
@@ -110,7 +147,7 @@ func main() {
 			}
 			for u, ds := range deps {
 				depsTree = append(depsTree, konveyor.DepsTreeItem{
-					FileURI:      string(u),
+					FileURI:      string(rewriter.Rewrite(u)),
 					Provider:     name,
 					Dependencies: ds,
 				})
@@ -132,7 +169,7 @@ func main() {
 				}
 				depsFlat = append(depsFlat, konveyor.DepsFlatItem{
 					Provider:     name,
-					FileURI:      string(u),
+					FileURI:      string(rewriter.Rewrite(u)),
 					Dependencies: newDeps,
 				})
 			}
@@ -151,20 +188,14 @@ func main() {
 
 	var b []byte
 	if treeOutput {
+		engine.CanonicalizeDependencyTree(depsTree)
 		b, err = yaml.Marshal(depsTree)
 		if err != nil {
 			log.Error(err, "failed to marshal dependency data as yaml")
 			os.Exit(1)
 		}
 	} else {
-		// Sort depsFlat
-		sort.SliceStable(depsFlat, func(i, j int) bool {
-			if depsFlat[i].Provider == depsFlat[j].Provider {
-				return depsFlat[i].FileURI < depsFlat[j].FileURI
-			} else {
-				return depsFlat[i].Provider < depsFlat[j].Provider
-			}
-		})
+		engine.CanonicalizeDependencies(depsFlat)
 
 		b, err = yaml.Marshal(depsFlat)
 		if err != nil {
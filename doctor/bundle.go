@@ -0,0 +1,113 @@
+package doctor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/redact"
+	"gopkg.in/yaml.v2"
+)
+
+// Bundle is the content of a support bundle
+type Bundle struct {
+	Report Report
+	// ProviderSettings is the path to the provider settings, they are added
+	// with their secrets redacted
+	ProviderSettings string
+	// Log is the log of the checks
+	Log []byte
+	// LogFiles are added to the logs directory with their secrets redacted
+	LogFiles []string
+}
+
+// Write writes the bundle as a gzipped tar to the file. The files that can
+// not be read are listed in the errors.txt of the bundle instead.
+func (b Bundle) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	errs := []string{}
+	report, err := yaml.Marshal(b.Report)
+	if err != nil {
+		return err
+	}
+	if err := addFile(tw, "report.yaml", report); err != nil {
+		return err
+	}
+	if b.ProviderSettings != "" {
+		content, err := RedactSettings(b.ProviderSettings)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("provider settings %s: %v", b.ProviderSettings, err))
+		} else if err := addFile(tw, "provider_settings.yaml", content); err != nil {
+			return err
+		}
+	}
+	if err := addFile(tw, "doctor.log", redact.Bytes(b.Log)); err != nil {
+		return err
+	}
+	for i, logFile := range b.LogFiles {
+		content, err := os.ReadFile(logFile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("log file %s: %v", logFile, err))
+			continue
+		}
+		name := fmt.Sprintf("logs/%d-%s", i, filepath.Base(logFile))
+		if err := addFile(tw, name, redact.Bytes(content)); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		content := []byte{}
+		for _, e := range errs {
+			content = append(content, e+"\n"...)
+		}
+		if err := addFile(tw, "errors.txt", content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addFile(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+// RedactSettings reads the provider settings and replaces the values of the
+// keys that look like secrets, such as the passwords and tokens of the
+// registries, and the passwords of the URLs, such as the ones of the proxies
+func RedactSettings(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var settings interface{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		// settings that can not be parsed are still useful to the report
+		return redact.Bytes(content), nil
+	}
+	return yaml.Marshal(redact.Value(settings))
+}
@@ -0,0 +1,336 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// minJavaVersion is the oldest java the java language server runs on
+const minJavaVersion = 17
+
+var javaVersionRegex = regexp.MustCompile(`version "(1\.)?(\d+)`)
+
+// Check is the result of a check of the environment
+type Check struct {
+	Name    string `yaml:"name" json:"name"`
+	Status  Status `yaml:"status" json:"status"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// Report is the result of the checks, it is the report of the support bundle
+type Report struct {
+	Time      time.Time `yaml:"time" json:"time"`
+	OS        string    `yaml:"os" json:"os"`
+	Arch      string    `yaml:"arch" json:"arch"`
+	GoVersion string    `yaml:"goVersion" json:"goVersion"`
+	CPUs      int       `yaml:"cpus" json:"cpus"`
+	Checks    []Check   `yaml:"checks" json:"checks"`
+}
+
+// Failed returns whether a check failed
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configure the checks
+type Options struct {
+	// ProviderSettings is the path to the provider settings to check, only
+	// the environment and the smoke analysis are checked when it is empty
+	ProviderSettings string
+	// Timeout bounds each check that dials an endpoint or runs a program
+	Timeout time.Duration
+	// SkipSmoke skips the smoke analysis
+	SkipSmoke bool
+}
+
+// Run checks the environment the analyzer runs in: the provider settings, the
+// binaries of the providers and of their language servers, the JDK of the
+// java provider, the memory and the limits of the process and whether the
+// endpoints of the settings can be reached. A tiny analysis of a temporary
+// directory with the builtin provider checks the engine itself.
+func Run(ctx context.Context, log logr.Logger, opts Options) Report {
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	report := Report{
+		Time:      time.Now().UTC(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		CPUs:      runtime.NumCPU(),
+	}
+	if opts.ProviderSettings != "" {
+		configs, err := provider.GetConfig(opts.ProviderSettings)
+		if err != nil {
+			report.Checks = append(report.Checks, Check{
+				Name:    "provider settings",
+				Status:  StatusFailed,
+				Message: fmt.Sprintf("unable to load %s: %v", opts.ProviderSettings, err),
+			})
+		} else {
+			report.Checks = append(report.Checks, Check{
+				Name:    "provider settings",
+				Status:  StatusOK,
+				Message: fmt.Sprintf("%d providers in %s", len(configs), opts.ProviderSettings),
+			})
+			report.Checks = append(report.Checks, ProviderChecks(configs)...)
+			report.Checks = append(report.Checks, JavaChecks(ctx, configs, opts.Timeout)...)
+			report.Checks = append(report.Checks, EndpointChecks(ctx, configs, opts.Timeout)...)
+		}
+	}
+	report.Checks = append(report.Checks, systemChecks()...)
+	if opts.SkipSmoke {
+		report.Checks = append(report.Checks, Check{Name: "smoke analysis", Status: StatusSkipped})
+	} else {
+		report.Checks = append(report.Checks, SmokeCheck(ctx, log.WithName("smoke")))
+	}
+	return report
+}
+
+// ProviderChecks checks that the binaries of the providers, their language
+// servers and their locations exist
+func ProviderChecks(configs []provider.Config) []Check {
+	checks := []Check{}
+	servers := map[string]bool{}
+	for _, config := range configs {
+		// the java and builtin providers run in the analyzer, their binary
+		// is not used
+		if config.BinaryPath != "" && config.Name != "java" && config.Name != "builtin" {
+			checks = append(checks, executableCheck(fmt.Sprintf("provider %s binary", config.Name), config.BinaryPath))
+		}
+		for i, ic := range config.InitConfig {
+			if path, ok := ic.ProviderSpecificConfig[provider.LspServerPathConfigKey].(string); ok && path != "" && !servers[path] {
+				servers[path] = true
+				checks = append(checks, executableCheck(fmt.Sprintf("provider %s language server", config.Name), path))
+			}
+			for _, root := range ic.Roots() {
+				name := fmt.Sprintf("provider %s location %d", config.Name, i)
+				if _, err := os.Stat(root); err != nil {
+					checks = append(checks, Check{Name: name, Status: StatusWarning, Message: err.Error()})
+				} else {
+					checks = append(checks, Check{Name: name, Status: StatusOK, Message: root})
+				}
+			}
+		}
+	}
+	return checks
+}
+
+func executableCheck(name, path string) Check {
+	found, err := exec.LookPath(path)
+	if err != nil {
+		return Check{Name: name, Status: StatusFailed, Message: err.Error()}
+	}
+	return Check{Name: name, Status: StatusOK, Message: found}
+}
+
+// JavaChecks checks that the JDK the java provider needs is installed and
+// recent enough for the java language server
+func JavaChecks(ctx context.Context, configs []provider.Config, timeout time.Duration) []Check {
+	needed := false
+	for _, config := range configs {
+		if config.Name == "java" {
+			needed = true
+		}
+	}
+	if !needed {
+		return nil
+	}
+	java := "java"
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		java = filepath.Join(home, "bin", "java")
+	}
+	path, err := exec.LookPath(java)
+	if err != nil {
+		return []Check{{Name: "jdk", Status: StatusFailed, Message: err.Error()}}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "-version").CombinedOutput()
+	if err != nil {
+		return []Check{{Name: "jdk", Status: StatusFailed, Message: fmt.Sprintf("%s -version: %v", path, err)}}
+	}
+	return []Check{javaVersionCheck(path, output)}
+}
+
+// javaVersionCheck checks the output of java -version, the versions before 9
+// are reported as 1.<version>. The version can follow other lines, such as
+// the JAVA_TOOL_OPTIONS the JVM picked up.
+func javaVersionCheck(path string, output []byte) Check {
+	var match []string
+	line := ""
+	for _, l := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if match = javaVersionRegex.FindStringSubmatch(l); match != nil {
+			line = strings.TrimSpace(l)
+			break
+		}
+	}
+	if match == nil {
+		return Check{Name: "jdk", Status: StatusWarning, Message: fmt.Sprintf("unable to read the version of %s: %s", path, strings.TrimSpace(string(output)))}
+	}
+	version, _ := strconv.Atoi(match[2])
+	if version < minJavaVersion {
+		return Check{Name: "jdk", Status: StatusFailed, Message: fmt.Sprintf("%s is java %d, the java provider needs java %d or later", path, version, minJavaVersion)}
+	}
+	return Check{Name: "jdk", Status: StatusOK, Message: fmt.Sprintf("%s: %s", path, line)}
+}
+
+// EndpointChecks checks that the addresses of the providers, their proxies
+// and the registries of their dependencies can be dialed
+func EndpointChecks(ctx context.Context, configs []provider.Config, timeout time.Duration) []Check {
+	checks := []Check{}
+	seen := map[string]bool{}
+	add := func(name, address string) {
+		if address == "" || seen[address] {
+			return
+		}
+		seen[address] = true
+		checks = append(checks, dialCheck(ctx, name, address, timeout))
+	}
+	for _, config := range configs {
+		if config.Address != "" && config.BinaryPath == "" {
+			add(fmt.Sprintf("provider %s address", config.Name), config.Address)
+		}
+		for _, ic := range config.InitConfig {
+			if ic.Proxy != nil {
+				add(fmt.Sprintf("provider %s http proxy", config.Name), urlAddress(ic.Proxy.HTTPProxy))
+				add(fmt.Sprintf("provider %s https proxy", config.Name), urlAddress(ic.Proxy.HTTPSProxy))
+			}
+			registries, err := provider.GetRegistries(ic.ProviderSpecificConfig)
+			if err != nil || registries == nil {
+				continue
+			}
+			for kind, registry := range map[string]*provider.Registry{"npm": registries.Npm, "pypi": registries.PyPI, "go": registries.Go} {
+				if registry != nil {
+					add(fmt.Sprintf("provider %s %s registry", config.Name, kind), urlAddress(registry.URL))
+				}
+			}
+		}
+	}
+	return checks
+}
+
+func dialCheck(ctx context.Context, name, address string, timeout time.Duration) Check {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return Check{Name: name, Status: StatusFailed, Message: fmt.Sprintf("unable to reach %s: %v", address, err)}
+	}
+	conn.Close()
+	return Check{Name: name, Status: StatusOK, Message: address}
+}
+
+// urlAddress returns the host and port a URL is dialed on, empty when it is
+// not a URL
+func urlAddress(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// smokeRule is the rule of the smoke analysis, it matches once in the file
+// the analysis creates
+const smokeRule = `
+- ruleID: doctor-smoke
+  message: "found {{matchingText}}"
+  when:
+    builtin.filecontent:
+      pattern: konveyor-doctor
+`
+
+// SmokeCheck analyzes a temporary directory with the builtin provider and
+// checks that the rule of the analysis matches its file
+func SmokeCheck(ctx context.Context, log logr.Logger) Check {
+	start := time.Now()
+	incidents, err := smokeAnalysis(ctx, log)
+	if err != nil {
+		return Check{Name: "smoke analysis", Status: StatusFailed, Message: err.Error()}
+	}
+	if incidents != 1 {
+		return Check{Name: "smoke analysis", Status: StatusFailed, Message: fmt.Sprintf("expected 1 incident, got %d", incidents)}
+	}
+	return Check{Name: "smoke analysis", Status: StatusOK, Message: fmt.Sprintf("1 incident in %s", time.Since(start).Round(time.Millisecond))}
+}
+
+func smokeAnalysis(ctx context.Context, log logr.Logger) (int, error) {
+	dir, err := os.MkdirTemp("", "konveyor-doctor")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "smoke.txt"), []byte("konveyor-doctor\n"), 0644); err != nil {
+		return 0, err
+	}
+	config := provider.Config{
+		Name:       "builtin",
+		InitConfig: []provider.InitConfig{{Location: dir}},
+	}
+	prov, err := lib.GetProviderClient(config, log)
+	if err != nil {
+		return 0, err
+	}
+	defer prov.Stop()
+	if err := prov.ProviderInit(ctx); err != nil {
+		return 0, fmt.Errorf("unable to init the builtin provider: %w", err)
+	}
+	ruleParser := parser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{"builtin": prov},
+		Log:                  log.WithName("parser"),
+	}
+	rules, _, err := ruleParser.ParseRules([]byte(smokeRule))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the rule: %w", err)
+	}
+	eng := engine.CreateRuleEngine(ctx, 1, log)
+	defer eng.Stop()
+	incidents := 0
+	for _, rs := range eng.RunRules(ctx, []engine.RuleSet{{Name: "doctor", Rules: rules}}) {
+		for _, v := range rs.Violations {
+			incidents += len(v.Incidents)
+		}
+		for ruleID, reason := range rs.Errors {
+			return 0, fmt.Errorf("rule %s: %s", ruleID, reason)
+		}
+	}
+	return incidents, nil
+}
@@ -0,0 +1,70 @@
+package doctor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// minMemory is the memory available under which the language servers
+	// are likely to be slow or killed
+	minMemory = 2 << 30
+	// minOpenFiles is the limit of open files under which the language
+	// servers fail to watch and index large projects
+	minOpenFiles = 4096
+)
+
+// systemChecks checks the memory available and the limit of open files
+func systemChecks() []Check {
+	return []Check{memoryCheck("/proc/meminfo"), openFilesCheck()}
+}
+
+func memoryCheck(meminfo string) Check {
+	f, err := os.Open(meminfo)
+	if err != nil {
+		return Check{Name: "memory", Status: StatusWarning, Message: err.Error()}
+	}
+	defer f.Close()
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+		}
+	}
+	total, available := values["MemTotal"], values["MemAvailable"]
+	if total == 0 {
+		return Check{Name: "memory", Status: StatusWarning, Message: fmt.Sprintf("unable to read the memory from %s", meminfo)}
+	}
+	message := fmt.Sprintf("%s available of %s", formatBytes(available), formatBytes(total))
+	if available < minMemory {
+		return Check{Name: "memory", Status: StatusWarning, Message: message + fmt.Sprintf(", the language servers may need %s", formatBytes(minMemory))}
+	}
+	return Check{Name: "memory", Status: StatusOK, Message: message}
+}
+
+func openFilesCheck() Check {
+	limit := unix.Rlimit{}
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &limit); err != nil {
+		return Check{Name: "open files limit", Status: StatusWarning, Message: err.Error()}
+	}
+	message := fmt.Sprintf("soft %d, hard %d", limit.Cur, limit.Max)
+	if limit.Cur < minOpenFiles {
+		return Check{Name: "open files limit", Status: StatusWarning, Message: message + fmt.Sprintf(", large projects may need %d, raise it with ulimit -n", minOpenFiles)}
+	}
+	return Check{Name: "open files limit", Status: StatusOK, Message: message}
+}
+
+func formatBytes(b uint64) string {
+	return fmt.Sprintf("%.1fGiB", float64(b)/(1<<30))
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package doctor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// systemChecks only reads the memory and the limits of the process on linux
+func systemChecks() []Check {
+	message := fmt.Sprintf("not supported on %s", runtime.GOOS)
+	return []Check{
+		{Name: "memory", Status: StatusSkipped, Message: message},
+		{Name: "open files limit", Status: StatusSkipped, Message: message},
+	}
+}
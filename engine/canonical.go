@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// WithCanonicalOrder sorts the rulesets the engine returns, see Canonicalize,
+// and the incidents of each condition before they are limited, so that the
+// same analysis gives the same output whatever order the rules and the
+// providers answered in. It is set by default, the order the results were
+// found in is kept otherwise.
+func WithCanonicalOrder(canonical bool) Option {
+	return func(engine *ruleEngine) {
+		engine.canonicalOrder = canonical
+	}
+}
+
+// Canonicalize sorts the rulesets by name, their tags, unmatched and skipped
+// rules, and the incidents of their violations by URI, line and message.
+// The incidents without a line come first in their file.
+func Canonicalize(ruleSets []konveyor.RuleSet) {
+	sort.SliceStable(ruleSets, func(i, j int) bool {
+		return ruleSets[i].Name < ruleSets[j].Name
+	})
+	for i := range ruleSets {
+		rs := &ruleSets[i]
+		sort.Strings(rs.Tags)
+		sort.SliceStable(rs.StructuredTags, func(i, j int) bool {
+			if rs.StructuredTags[i].Category != rs.StructuredTags[j].Category {
+				return rs.StructuredTags[i].Category < rs.StructuredTags[j].Category
+			}
+			return rs.StructuredTags[i].Value < rs.StructuredTags[j].Value
+		})
+		sort.Strings(rs.Unmatched)
+		sort.Strings(rs.Skipped)
+		for _, v := range rs.Violations {
+			sortIncidents(v.Incidents)
+		}
+	}
+}
+
+func sortIncidents(incidents []konveyor.Incident) {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		a, b := incidents[i], incidents[j]
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		if lineA, lineB := lineOrNone(a.LineNumber), lineOrNone(b.LineNumber); lineA != lineB {
+			return lineA < lineB
+		}
+		if a.Message != b.Message {
+			return a.Message < b.Message
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+}
+
+// sortConditionIncidents sorts the incidents of a condition by URI and line,
+// the ones without a line first
+func sortConditionIncidents(incidents []IncidentContext) {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		if incidents[i].FileURI != incidents[j].FileURI {
+			return incidents[i].FileURI < incidents[j].FileURI
+		}
+		return lineOrNone(incidents[i].LineNumber) < lineOrNone(incidents[j].LineNumber)
+	})
+}
+
+func lineOrNone(line *int) int {
+	if line == nil {
+		return -1
+	}
+	return *line
+}
+
+// CanonicalizeDependencies sorts the dependencies by provider and file, and
+// the dependencies of each file by name, version and resolved identifier
+func CanonicalizeDependencies(deps []konveyor.DepsFlatItem) {
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Provider != deps[j].Provider {
+			return deps[i].Provider < deps[j].Provider
+		}
+		return deps[i].FileURI < deps[j].FileURI
+	})
+	for _, item := range deps {
+		sort.SliceStable(item.Dependencies, func(i, j int) bool {
+			return lessDep(item.Dependencies[i], item.Dependencies[j])
+		})
+	}
+}
+
+// CanonicalizeDependencyTree sorts the dependency trees by provider and file,
+// and the dependencies at every level of the trees as CanonicalizeDependencies
+// does
+func CanonicalizeDependencyTree(deps []konveyor.DepsTreeItem) {
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Provider != deps[j].Provider {
+			return deps[i].Provider < deps[j].Provider
+		}
+		return deps[i].FileURI < deps[j].FileURI
+	})
+	for _, item := range deps {
+		sortDepDAG(item.Dependencies)
+	}
+}
+
+func sortDepDAG(items []konveyor.DepDAGItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return lessDep(&items[i].Dep, &items[j].Dep)
+	})
+	for _, item := range items {
+		sortDepDAG(item.AddedDeps)
+	}
+}
+
+func lessDep(a, b *konveyor.Dep) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if a.Version != b.Version {
+		return a.Version < b.Version
+	}
+	return a.ResolvedIdentifier < b.ResolvedIdentifier
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestCanonicalize(t *testing.T) {
+	line := func(i int) *int { return &i }
+	ruleSets := []konveyor.RuleSet{
+		{
+			Name:           "quarkus",
+			Tags:           []string{"Java", "Backend=Quarkus"},
+			StructuredTags: []konveyor.Tag{{Value: "Java"}, {Category: "Backend", Value: "Quarkus"}},
+			Violations: map[string]konveyor.Violation{
+				"javax-import": {Incidents: []konveyor.Incident{
+					{URI: "file:///b.java", LineNumber: line(1), Message: "replace javax"},
+					{URI: "file:///a.java", LineNumber: line(10), Message: "replace javax"},
+					{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax.ws"},
+					{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax.persistence"},
+					{URI: "file:///a.java", Message: "uses javax"},
+				}},
+			},
+			Unmatched: []string{"rule-2", "rule-1"},
+			Skipped:   []string{"rule-4", "rule-3"},
+		},
+		{Name: "eap"},
+	}
+	want := []konveyor.RuleSet{
+		{Name: "eap"},
+		{
+			Name:           "quarkus",
+			Tags:           []string{"Backend=Quarkus", "Java"},
+			StructuredTags: []konveyor.Tag{{Value: "Java"}, {Category: "Backend", Value: "Quarkus"}},
+			Violations: map[string]konveyor.Violation{
+				"javax-import": {Incidents: []konveyor.Incident{
+					{URI: "file:///a.java", Message: "uses javax"},
+					{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax.persistence"},
+					{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax.ws"},
+					{URI: "file:///a.java", LineNumber: line(10), Message: "replace javax"},
+					{URI: "file:///b.java", LineNumber: line(1), Message: "replace javax"},
+				}},
+			},
+			Unmatched: []string{"rule-1", "rule-2"},
+			Skipped:   []string{"rule-3", "rule-4"},
+		},
+	}
+	Canonicalize(ruleSets)
+	if !reflect.DeepEqual(ruleSets, want) {
+		t.Errorf("Canonicalize() = %#v, want %#v", ruleSets, want)
+	}
+}
+
+func TestCanonicalizeDependencies(t *testing.T) {
+	deps := []konveyor.DepsFlatItem{
+		{Provider: "java", FileURI: "file:///b/pom.xml", Dependencies: []*konveyor.Dep{
+			{Name: "junit", Version: "4.11"},
+			{Name: "commons-io", Version: "2.6", ResolvedIdentifier: "b"},
+			{Name: "commons-io", Version: "2.6", ResolvedIdentifier: "a"},
+			{Name: "commons-io", Version: "2.11"},
+		}},
+		{Provider: "java", FileURI: "file:///a/pom.xml"},
+		{Provider: "go", FileURI: "file:///go.mod"},
+	}
+	want := []konveyor.DepsFlatItem{
+		{Provider: "go", FileURI: "file:///go.mod"},
+		{Provider: "java", FileURI: "file:///a/pom.xml"},
+		{Provider: "java", FileURI: "file:///b/pom.xml", Dependencies: []*konveyor.Dep{
+			{Name: "commons-io", Version: "2.11"},
+			{Name: "commons-io", Version: "2.6", ResolvedIdentifier: "a"},
+			{Name: "commons-io", Version: "2.6", ResolvedIdentifier: "b"},
+			{Name: "junit", Version: "4.11"},
+		}},
+	}
+	CanonicalizeDependencies(deps)
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("CanonicalizeDependencies() = %#v, want %#v", deps, want)
+	}
+
+	tree := []konveyor.DepsTreeItem{{Provider: "java", Dependencies: []konveyor.DepDAGItem{
+		{Dep: konveyor.Dep{Name: "b"}, AddedDeps: []konveyor.DepDAGItem{{Dep: konveyor.Dep{Name: "d"}}, {Dep: konveyor.Dep{Name: "c"}}}},
+		{Dep: konveyor.Dep{Name: "a"}},
+	}}}
+	wantTree := []konveyor.DepsTreeItem{{Provider: "java", Dependencies: []konveyor.DepDAGItem{
+		{Dep: konveyor.Dep{Name: "a"}},
+		{Dep: konveyor.Dep{Name: "b"}, AddedDeps: []konveyor.DepDAGItem{{Dep: konveyor.Dep{Name: "c"}}, {Dep: konveyor.Dep{Name: "d"}}}},
+	}}}
+	CanonicalizeDependencyTree(tree)
+	if !reflect.DeepEqual(tree, wantTree) {
+		t.Errorf("CanonicalizeDependencyTree() = %#v, want %#v", tree, wantTree)
+	}
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// QueryCache is a cache of provider queries that is saved along with a
+// checkpoint, so that a resumed analysis does not query the providers again.
+type QueryCache interface {
+	Snapshot() ([]byte, error)
+	Restore([]byte) error
+}
+
+// checkpoint is the state of a partially completed analysis.
+type checkpoint struct {
+	RuleSets map[string]konveyor.RuleSet `json:"ruleSets"`
+	Tags     map[string]interface{}      `json:"tags"`
+	// TaggingRules and Rules are the IDs of the evaluated rules by ruleset,
+	// a rule can be in both when it creates tags and a message.
+	TaggingRules map[string][]string `json:"taggingRules"`
+	Rules        map[string][]string `json:"rules"`
+	QueryCache   json.RawMessage     `json:"queryCache,omitempty"`
+	Workspace    json.RawMessage     `json:"workspace,omitempty"`
+
+	// changes are the files that changed since the checkpoint was saved, kept
+	// are the results of its rules in the other files
+	changes WorkspaceChanges
+	kept    map[string]map[string]konveyor.Violation
+}
+
+func newCheckpoint() *checkpoint {
+	return &checkpoint{
+		RuleSets:     map[string]konveyor.RuleSet{},
+		Tags:         map[string]interface{}{},
+		TaggingRules: map[string][]string{},
+		Rules:        map[string][]string{},
+	}
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := newCheckpoint()
+	err = json.Unmarshal(content, c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read checkpoint %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *checkpoint) evaluated(rules map[string][]string, ruleSetName, ruleID string) bool {
+	for _, id := range rules[ruleSetName] {
+		if id == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkpointer keeps track of the evaluated rules and periodically writes
+// them to disk.
+type checkpointer struct {
+	path      string
+	interval  time.Duration
+	cache     QueryCache
+	workspace Workspace
+
+	mutex     sync.Mutex
+	state     *checkpoint
+	lastWrite time.Time
+}
+
+func (c *checkpointer) markTaggingRule(ruleSetName, ruleID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.state.TaggingRules[ruleSetName] = append(c.state.TaggingRules[ruleSetName], ruleID)
+}
+
+func (c *checkpointer) markRule(ruleSetName, ruleID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.state.Rules[ruleSetName] = append(c.state.Rules[ruleSetName], ruleID)
+}
+
+// save writes the checkpoint when the interval has passed since the last
+// write, or always when forced.
+func (c *checkpointer) save(force bool, tags map[string]interface{}, ruleSets map[string]*konveyor.RuleSet) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !force && time.Since(c.lastWrite) < c.interval {
+		return nil
+	}
+	c.lastWrite = time.Now()
+
+	c.state.Tags = tags
+	c.state.RuleSets = map[string]konveyor.RuleSet{}
+	for name, rs := range ruleSets {
+		if rs != nil {
+			c.state.RuleSets[name] = *rs
+		}
+	}
+	c.state.QueryCache = nil
+	if c.cache != nil {
+		snapshot, err := c.cache.Snapshot()
+		if err != nil {
+			return err
+		}
+		c.state.QueryCache = snapshot
+	}
+	if c.workspace != nil && c.state.Workspace == nil {
+		snapshot, err := c.workspace.Snapshot()
+		if err != nil {
+			return err
+		}
+		c.state.Workspace = snapshot
+	}
+	content, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash while writing does not
+	// lose the previous checkpoint.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// resume restores the results of the checkpoint in the rulesets and the query
+// cache, it returns the checkpoint to find the rules that can be skipped.
+func (c *checkpointer) resume(mapRuleSets map[string]*konveyor.RuleSet) (*checkpoint, error) {
+	state, err := loadCheckpoint(c.path)
+	if err != nil {
+		return nil, err
+	}
+	if c.workspace != nil && len(state.Workspace) > 0 {
+		state.changes, err = c.workspace.Changes(state.Workspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the queries of the changed files have to be sent again
+	if c.cache != nil && len(state.QueryCache) > 0 && state.changes == nil {
+		err = c.cache.Restore(state.QueryCache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, saved := range state.RuleSets {
+		rs, ok := mapRuleSets[name]
+		if !ok {
+			continue
+		}
+		rs.Tags = append(rs.Tags, saved.Tags...)
+		rs.StructuredTags = append(rs.StructuredTags, saved.StructuredTags...)
+		if state.changes != nil {
+			saved.Name = name
+			state.keep(saved)
+			continue
+		}
+		for id, v := range saved.Violations {
+			rs.Violations[id] = v
+		}
+		for id, e := range saved.Errors {
+			rs.Errors[id] = e
+		}
+		rs.Unmatched = append(rs.Unmatched, saved.Unmatched...)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.state.Tags = state.Tags
+	if state.changes != nil {
+		// the rules are marked again once they are evaluated on the changed
+		// files
+		return state, nil
+	}
+	for name, ids := range state.TaggingRules {
+		c.state.TaggingRules[name] = append(c.state.TaggingRules[name], ids...)
+	}
+	for name, ids := range state.Rules {
+		c.state.Rules[name] = append(c.state.Rules[name], ids...)
+	}
+	return state, nil
+}
@@ -2,7 +2,9 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -21,18 +23,47 @@ type ConditionResponse struct {
 	// keys here, will be used in the message.
 	Incidents       []IncidentContext      `yaml:"incidents"`
 	TemplateContext map[string]interface{} `yaml:",inline"`
+	// Sampled is the number of incidents the samples of the conditions left
+	// out of Incidents
+	Sampled int `yaml:"sampled,omitempty"`
 }
 
 type ConditionContext struct {
 	Tags     map[string]interface{}   `yaml:"tags"`
 	Template map[string]ChainTemplate `yaml:"template"`
+	Scope    *Scope                   `yaml:"scope,omitempty"`
+
+	// Location is the analyzed directory the relative filepaths of the
+	// conditions are in
+	Location string `yaml:"location,omitempty"`
+}
+
+// FileURI returns the URI of a filepath of a condition, the relative ones are
+// in the Location. It is false when the filepath is relative and there is no
+// Location.
+func (c ConditionContext) FileURI(path string) (uri.URI, bool) {
+	switch {
+	case strings.Contains(path, "://"):
+		return uri.URI(path), true
+	case filepath.IsAbs(path):
+		return uri.File(path), true
+	case c.Location != "" && path != "":
+		return uri.File(filepath.Join(c.Location, path)), true
+	}
+	return "", false
 }
 
 type ConditionEntry struct {
-	From                   string
-	As                     string
-	Ignorable              bool
-	Not                    bool
+	From      string
+	As        string
+	Ignorable bool
+	Not       bool
+	// ReportAbsence when set on a negated condition, will create an incident
+	// for the scope that was searched when nothing was found in it.
+	ReportAbsence bool
+	// Sample when set is the number of incidents of the condition that are
+	// reported, the other ones are only counted
+	Sample                 int
 	ProviderSpecificConfig Conditional
 }
 
@@ -43,6 +74,9 @@ type IncidentContext struct {
 	Variables    map[string]interface{} `yaml:"variables"`
 	Links        []konveyor.Link        `yaml:"externalLink"`
 	CodeLocation *Location              `yaml:"location,omitempty"`
+	// Confidence is set when the provider found the incident with a
+	// heuristic, between 0 and 1
+	Confidence *float64 `yaml:"confidence,omitempty"`
 }
 
 type Location struct {
@@ -74,6 +108,12 @@ type Conditional interface {
 	Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error)
 }
 
+// Scoped is implemented by conditionals that can tell which files or
+// locations they were asked to search.
+type Scoped interface {
+	Scope(condCtx ConditionContext) []uri.URI
+}
+
 type CodeSnip interface {
 	GetCodeSnip(uri.URI, Location) (string, error)
 }
@@ -84,6 +124,21 @@ type RuleSet struct {
 	Labels      []string `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
 	Rules       []Rule   `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Digest is the digest of the files the ruleset was loaded from, it is
+	// set by the parser
+	Digest string `json:"-" yaml:"-"`
+	// Extends are the rulesets whose rules the ruleset has as well, the
+	// parser adds their rules to Rules
+	Extends []RuleSetBase `json:"extends,omitempty" yaml:"extends,omitempty"`
+}
+
+// RuleSetBase is a ruleset that a ruleset extends
+type RuleSetBase struct {
+	// Path is the directory of the ruleset, relative to the directory of the
+	// ruleset that extends it
+	Path string `json:"path" yaml:"path"`
+	// Disable are the IDs of the rules of the ruleset that are left out
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
 }
 
 type Rule struct {
@@ -92,6 +147,11 @@ type Rule struct {
 	When            Conditional      `yaml:"when,omitempty" json:"when,omitempty"`
 	Snipper         CodeSnip         `yaml:"-" json:"-"`
 	CustomVariables []CustomVariable `yaml:"customVariables,omitempty" json:"customVariables,omitempty"`
+	// Threshold is the number of incidents the rule needs to be reported,
+	// it is reported whenever it matches when nil
+	Threshold *Threshold `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+	// AppliedOverride is set by the engine when the rule is overridden
+	AppliedOverride *konveyor.AppliedOverride `yaml:"-" json:"-"`
 }
 
 type RuleMeta struct {
@@ -121,6 +181,14 @@ type CustomVariable struct {
 type Perform struct {
 	Message Message  `yaml:",inline"`
 	Tag     []string `yaml:"tag,omitempty"`
+	// StructuredTags are the tags of the tag action that are given as a
+	// category and a value, the value can have any character
+	StructuredTags []konveyor.Tag `yaml:"structuredTags,omitempty"`
+}
+
+// IsTagging returns whether the rule has a tag action
+func (p *Perform) IsTagging() bool {
+	return p.Tag != nil || p.StructuredTags != nil
 }
 
 type Message struct {
@@ -129,9 +197,14 @@ type Message struct {
 }
 
 func (p *Perform) Validate() error {
-	if p.Message.Text == nil && p.Tag == nil {
+	if p.Message.Text == nil && !p.IsTagging() {
 		return fmt.Errorf("either message or tag must be set")
 	}
+	for _, tag := range p.StructuredTags {
+		if tag.Value == "" {
+			return fmt.Errorf("the value of a tag must be set")
+		}
+	}
 	return nil
 }
 
@@ -159,27 +232,30 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 			// TODO: determine if this is the right thing, I am assume the full rule should fail here
 			return ConditionResponse{}, fmt.Errorf("unable to find context value: %v", c.From)
 		}
-		response, err := c.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
+		entryCtx, trace := startCondition(ctx, c)
+		response, err := c.ProviderSpecificConfig.Evaluate(entryCtx, log, condCtx)
 		if err != nil {
+			trace.end(false, 0, err)
 			return ConditionResponse{}, err
 		}
 		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
+			condCtx.Template[c.As] = newChainTemplate(response)
 		}
 
 		matched := response.Matched
 		if c.Not {
 			matched = !matched
+			response.Incidents = c.absenceIncidents(response, condCtx)
 		}
+		trace.end(matched, len(response.Incidents), nil)
 		if !matched {
 			fullResponse.Matched = false
 		}
 
 		if !c.Ignorable {
+			response = c.sample(response)
 			fullResponse.Incidents = append(fullResponse.Incidents, response.Incidents...)
+			fullResponse.Sampled += response.Sampled
 		}
 
 		for k, v := range response.TemplateContext {
@@ -208,6 +284,9 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 		Incidents:       []IncidentContext{},
 		TemplateContext: map[string]interface{}{},
 	}
+	// a query that timed out does not stop the other conditions from being
+	// evaluated, it only fails the condition when none of them matched
+	var timeoutErr error
 	conditions := sortConditionEntries(o.Conditions)
 	for _, c := range conditions {
 		if _, ok := condCtx.Template[c.From]; !ok && c.From != "" {
@@ -216,28 +295,37 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 			return ConditionResponse{}, fmt.Errorf("unable to find context value: %v", c.From)
 		}
 
-		response, err := c.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
+		entryCtx, trace := startCondition(ctx, c)
+		response, err := c.ProviderSpecificConfig.Evaluate(entryCtx, log, condCtx)
+		if err != nil && isCallTimeout(ctx, err) {
+			trace.end(false, 0, err)
+			log.V(3).Info("condition timed out, evaluating the other conditions", "error", err.Error())
+			timeoutErr = err
+			continue
+		}
 		if err != nil {
+			trace.end(false, 0, err)
 			return ConditionResponse{}, err
 		}
 
 		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
+			condCtx.Template[c.As] = newChainTemplate(response)
 		}
 
 		matched := response.Matched
 		if c.Not {
 			matched = !matched
+			response.Incidents = c.absenceIncidents(response, condCtx)
 		}
+		trace.end(matched, len(response.Incidents), nil)
 		if matched {
 			fullResponse.Matched = true
 		}
 
 		if !c.Ignorable {
+			response = c.sample(response)
 			fullResponse.Incidents = append(fullResponse.Incidents, response.Incidents...)
+			fullResponse.Sampled += response.Sampled
 		}
 
 		for k, v := range response.TemplateContext {
@@ -245,9 +333,18 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 		}
 
 	}
+	if timeoutErr != nil && !fullResponse.Matched {
+		return ConditionResponse{}, timeoutErr
+	}
 	return fullResponse, nil
 }
 
+// isCallTimeout is whether the error is a query to a provider that timed out,
+// rather than the analysis that was canceled
+func isCallTimeout(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded)
+}
+
 func (ce ConditionEntry) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
 	response, err := ce.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
 	if err != nil {
@@ -257,10 +354,83 @@ func (ce ConditionEntry) Evaluate(ctx context.Context, log logr.Logger, condCtx
 	matched := response.Matched
 	if ce.Not {
 		matched = !matched
+		response.Incidents = ce.absenceIncidents(response, condCtx)
 	}
 
 	response.Matched = matched
-	return response, nil
+	return ce.sample(response), nil
+}
+
+// sample keeps at most Sample incidents of the response, taken from as many
+// files as it can so that they show the places the condition matched rather
+// than the first file. The incidents keep their order.
+func (ce ConditionEntry) sample(response ConditionResponse) ConditionResponse {
+	if ce.Sample <= 0 || len(response.Incidents) <= ce.Sample {
+		return response
+	}
+	files := []uri.URI{}
+	byFile := map[uri.URI][]int{}
+	for i, incident := range response.Incidents {
+		if _, ok := byFile[incident.FileURI]; !ok {
+			files = append(files, incident.FileURI)
+		}
+		byFile[incident.FileURI] = append(byFile[incident.FileURI], i)
+	}
+	// one incident of each file at a time, in the order the files were found
+	selected := make([]bool, len(response.Incidents))
+	for count, round := 0, 0; count < ce.Sample; round++ {
+		for _, f := range files {
+			if round < len(byFile[f]) && count < ce.Sample {
+				selected[byFile[f][round]] = true
+				count++
+			}
+		}
+	}
+	incidents := make([]IncidentContext, 0, ce.Sample)
+	for i, incident := range response.Incidents {
+		if selected[i] {
+			incidents = append(incidents, incident)
+		}
+	}
+	response.Sampled += len(response.Incidents) - len(incidents)
+	response.Incidents = incidents
+	return response
+}
+
+// absenceIncidents returns the incidents for a negated condition. When the
+// condition did not match and absence is reported, a synthetic incident is
+// created for each location in the searched scope, as there is nothing else
+// to point to. There are none when the files that were searched are not
+// known, or when they are relative and there is no analyzed location.
+func (ce ConditionEntry) absenceIncidents(response ConditionResponse, condCtx ConditionContext) []IncidentContext {
+	if !ce.ReportAbsence || response.Matched {
+		return response.Incidents
+	}
+	scope := []uri.URI{}
+	if t, ok := condCtx.Template[ce.From]; ok && ce.From != "" {
+		for _, f := range t.Filepaths {
+			if u, ok := condCtx.FileURI(f); ok {
+				scope = append(scope, u)
+			}
+		}
+	} else if s, ok := ce.ProviderSpecificConfig.(Scoped); ok {
+		for _, u := range s.Scope(condCtx) {
+			if u != "" {
+				scope = append(scope, u)
+			}
+		}
+	}
+	incidents := []IncidentContext{}
+	for _, u := range scope {
+		incidents = append(incidents, IncidentContext{
+			FileURI: u,
+			Variables: map[string]interface{}{
+				"absent": true,
+				"scope":  string(u),
+			},
+		})
+	}
+	return incidents
 }
 
 func incidentsToFilepaths(incident []IncidentContext) []string {
@@ -301,4 +471,40 @@ func gatherChain(start ConditionEntry, entries []ConditionEntry) []ConditionEntr
 type ChainTemplate struct {
 	Filepaths []string               `yaml:"filepaths"`
 	Extras    map[string]interface{} `yaml:"extras"`
+	// Variables are the distinct values of each variable of the incidents,
+	// such as the matched symbol names or the regex captures, in the order
+	// they were found
+	Variables map[string][]interface{} `yaml:"variables,omitempty"`
+}
+
+// newChainTemplate returns the template of a condition with `as` for the
+// conditions chained to it
+func newChainTemplate(response ConditionResponse) ChainTemplate {
+	t := ChainTemplate{
+		Filepaths: incidentsToFilepaths(response.Incidents),
+		Extras:    response.TemplateContext,
+	}
+	seen := map[string]map[interface{}]bool{}
+	for _, ic := range response.Incidents {
+		for k, v := range ic.Variables {
+			switch v.(type) {
+			case string, bool, int, int32, int64, float32, float64:
+			default:
+				// only the values that can be templated in a query
+				continue
+			}
+			if seen[k] == nil {
+				seen[k] = map[interface{}]bool{}
+			}
+			if seen[k][v] {
+				continue
+			}
+			seen[k][v] = true
+			if t.Variables == nil {
+				t.Variables = map[string][]interface{}{}
+			}
+			t.Variables[k] = append(t.Variables[k], v)
+		}
+	}
+	return t
 }
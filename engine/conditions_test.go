@@ -1,8 +1,13 @@
 package engine
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
 )
 
 func Test_sortConditionEntries(t *testing.T) {
@@ -248,3 +253,308 @@ func Test_sortConditionEntries(t *testing.T) {
 		})
 	}
 }
+
+type testScopedConditional struct {
+	matched bool
+	scope   []uri.URI
+}
+
+func (t testScopedConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{Matched: t.matched}, nil
+}
+
+func (t testScopedConditional) Scope(condCtx ConditionContext) []uri.URI {
+	return t.scope
+}
+
+func TestConditionEntryReportAbsence(t *testing.T) {
+	tests := []struct {
+		title             string
+		entry             ConditionEntry
+		condCtx           ConditionContext
+		expectedMatched   bool
+		expectedIncidents []uri.URI
+	}{
+		{
+			title: "absence is not reported by default",
+			entry: ConditionEntry{
+				Not:                    true,
+				ProviderSpecificConfig: testScopedConditional{scope: []uri.URI{"file:///pom.xml"}},
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{},
+		},
+		{
+			title: "absence is reported for the scope of the condition",
+			entry: ConditionEntry{
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{scope: []uri.URI{"file:///pom.xml"}},
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{"file:///pom.xml"},
+		},
+		{
+			title: "absence is reported for the chained filepaths",
+			entry: ConditionEntry{
+				From:                   "poms",
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{scope: []uri.URI{"file:///pom.xml"}},
+			},
+			condCtx: ConditionContext{
+				Template: map[string]ChainTemplate{
+					"poms": {Filepaths: []string{"/a/pom.xml", "/b/pom.xml"}},
+				},
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{"file:///a/pom.xml", "file:///b/pom.xml"},
+		},
+		{
+			title: "absence is reported for the relative filepaths in the location",
+			entry: ConditionEntry{
+				From:                   "poms",
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{},
+			},
+			condCtx: ConditionContext{
+				Template: map[string]ChainTemplate{
+					"poms": {Filepaths: []string{"a/pom.xml", "file:///b/pom.xml"}},
+				},
+				Location: "/app",
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{"file:///app/a/pom.xml", "file:///b/pom.xml"},
+		},
+		{
+			title: "absence is not reported for the relative filepaths without a location",
+			entry: ConditionEntry{
+				From:                   "poms",
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{},
+			},
+			condCtx: ConditionContext{
+				Template: map[string]ChainTemplate{
+					"poms": {Filepaths: []string{"a/pom.xml", "/b/pom.xml"}},
+				},
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{"file:///b/pom.xml"},
+		},
+		{
+			title: "absence is not reported when the scope is unknown",
+			entry: ConditionEntry{
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{scope: []uri.URI{""}},
+			},
+			expectedMatched:   true,
+			expectedIncidents: []uri.URI{},
+		},
+		{
+			title: "absence is not reported when the condition matched",
+			entry: ConditionEntry{
+				Not:                    true,
+				ReportAbsence:          true,
+				ProviderSpecificConfig: testScopedConditional{matched: true, scope: []uri.URI{"file:///pom.xml"}},
+			},
+			expectedMatched:   false,
+			expectedIncidents: []uri.URI{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			resp, err := tt.entry.Evaluate(context.TODO(), logr.Discard(), tt.condCtx)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if resp.Matched != tt.expectedMatched {
+				t.Errorf("expected matched %v got %v", tt.expectedMatched, resp.Matched)
+			}
+			got := []uri.URI{}
+			for _, i := range resp.Incidents {
+				got = append(got, i.FileURI)
+				if i.Variables["absent"] != true {
+					t.Errorf("expected absence incident, got %v", i.Variables)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expectedIncidents) {
+				t.Errorf("expected incidents %v got %v", tt.expectedIncidents, got)
+			}
+		})
+	}
+}
+
+type testErrorConditional struct {
+	err error
+}
+
+func (t testErrorConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{}, t.err
+}
+
+func TestOrConditionCallTimeout(t *testing.T) {
+	timeoutErr := fmt.Errorf("query timed out: %w", context.DeadlineExceeded)
+	tests := []struct {
+		title           string
+		conditions      []ConditionEntry
+		expectedMatched bool
+		expectedErr     error
+	}{
+		{
+			title: "the other conditions are evaluated after a timeout",
+			conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testErrorConditional{err: timeoutErr}},
+				{ProviderSpecificConfig: testScopedConditional{matched: true}},
+			},
+			expectedMatched: true,
+		},
+		{
+			title: "a timeout fails the condition when nothing matched",
+			conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testErrorConditional{err: timeoutErr}},
+				{ProviderSpecificConfig: testScopedConditional{}},
+			},
+			expectedErr: timeoutErr,
+		},
+		{
+			title: "other errors fail the condition",
+			conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testErrorConditional{err: fmt.Errorf("failed")}},
+				{ProviderSpecificConfig: testScopedConditional{matched: true}},
+			},
+			expectedErr: fmt.Errorf("failed"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			resp, err := OrCondition{Conditions: tt.conditions}.Evaluate(context.TODO(), logr.Discard(), ConditionContext{})
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if resp.Matched != tt.expectedMatched {
+				t.Errorf("expected matched %v got %v", tt.expectedMatched, resp.Matched)
+			}
+		})
+	}
+}
+
+// testTemplateConditional records the templates of the chained conditions
+type testTemplateConditional struct {
+	templates map[string]ChainTemplate
+}
+
+func (t testTemplateConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	for k, v := range condCtx.Template {
+		t.templates[k] = v
+	}
+	return ConditionResponse{Matched: true}, nil
+}
+
+func TestChainTemplateVariables(t *testing.T) {
+	templates := map[string]ChainTemplate{}
+	condition := AndCondition{Conditions: []ConditionEntry{
+		{
+			From:                   "beans",
+			ProviderSpecificConfig: testTemplateConditional{templates: templates},
+		},
+		{
+			As: "beans",
+			ProviderSpecificConfig: testIncidentsConditional{incidents: []IncidentContext{
+				{FileURI: "file:///app/beans.xml", Variables: map[string]interface{}{"class": "com.example.Foo", "line": 3}},
+				{FileURI: "file:///app/beans.xml", Variables: map[string]interface{}{"class": "com.example.Bar", "line": 3}},
+				{FileURI: "file:///app/other.xml", Variables: map[string]interface{}{"class": "com.example.Foo", "nested": map[string]interface{}{"a": "b"}}},
+			}},
+		},
+	}}
+	_, err := condition.Evaluate(context.Background(), logr.Discard(), ConditionContext{Template: map[string]ChainTemplate{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ChainTemplate{
+		Filepaths: []string{"/app/beans.xml", "/app/beans.xml", "/app/other.xml"},
+		Variables: map[string][]interface{}{
+			"class": {"com.example.Foo", "com.example.Bar"},
+			"line":  {3},
+		},
+	}
+	if !reflect.DeepEqual(templates["beans"], want) {
+		t.Errorf("expected template %+v, got %+v", want, templates["beans"])
+	}
+}
+
+// incidentsAt returns an incident for each of the uris
+func incidentsAt(uris ...uri.URI) testIncidentsConditional {
+	incidents := []IncidentContext{}
+	for _, u := range uris {
+		incidents = append(incidents, IncidentContext{FileURI: u})
+	}
+	return testIncidentsConditional{incidents: incidents}
+}
+
+func TestConditionSample(t *testing.T) {
+	all := []uri.URI{"file:///a", "file:///a", "file:///a", "file:///b", "file:///c", "file:///c"}
+	matches := incidentsAt(all...)
+	tests := []struct {
+		title             string
+		condition         Conditional
+		expectedIncidents []uri.URI
+		expectedSampled   int
+	}{
+		{
+			title:             "the sample is taken from each file",
+			condition:         ConditionEntry{Sample: 4, ProviderSpecificConfig: matches},
+			expectedIncidents: []uri.URI{"file:///a", "file:///a", "file:///b", "file:///c"},
+			expectedSampled:   2,
+		},
+		{
+			title:             "a condition with fewer incidents than its sample",
+			condition:         ConditionEntry{Sample: 10, ProviderSpecificConfig: matches},
+			expectedIncidents: all,
+		},
+		{
+			title: "the samples of the conditions of an or",
+			condition: OrCondition{Conditions: []ConditionEntry{
+				{Sample: 1, ProviderSpecificConfig: matches},
+				{Sample: 2, ProviderSpecificConfig: incidentsAt("file:///d")},
+			}},
+			expectedIncidents: []uri.URI{"file:///a", "file:///d"},
+			expectedSampled:   5,
+		},
+		{
+			title: "the sample of a nested and",
+			condition: ConditionEntry{Sample: 2, ProviderSpecificConfig: AndCondition{Conditions: []ConditionEntry{
+				{Sample: 3, ProviderSpecificConfig: matches},
+				{ProviderSpecificConfig: incidentsAt("file:///d")},
+			}}},
+			expectedIncidents: []uri.URI{"file:///a", "file:///b"},
+			expectedSampled:   5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			resp, err := tt.condition.Evaluate(context.TODO(), logr.Discard(), ConditionContext{Template: map[string]ChainTemplate{}})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := []uri.URI{}
+			for _, i := range resp.Incidents {
+				got = append(got, i.FileURI)
+			}
+			if !reflect.DeepEqual(got, tt.expectedIncidents) {
+				t.Errorf("expected incidents %v got %v", tt.expectedIncidents, got)
+			}
+			if resp.Sampled != tt.expectedSampled {
+				t.Errorf("expected %d sampled incidents got %d", tt.expectedSampled, resp.Sampled)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// WithMinConfidence drops the incidents that the providers found with a
+// confidence lower than the given one, the incidents without a confidence are
+// always kept. A rule that has no incidents left is not matched.
+func WithMinConfidence(confidence float64) Option {
+	return func(engine *ruleEngine) {
+		engine.minConfidence = confidence
+	}
+}
+
+// ValidateConfidence checks that a confidence is between 0 and 1
+func ValidateConfidence(confidence float64) error {
+	if confidence < 0 || confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", confidence)
+	}
+	return nil
+}
+
+func (r *ruleEngine) filterConfidence(incidents []IncidentContext) []IncidentContext {
+	if r.minConfidence <= 0 {
+		return incidents
+	}
+	filtered := []IncidentContext{}
+	for _, incident := range incidents {
+		if incident.Confidence == nil || *incident.Confidence >= r.minConfidence {
+			filtered = append(filtered, incident)
+		}
+	}
+	return filtered
+}
+
+// FilterConfidence drops the incidents of the output with a confidence lower
+// than the given one, as WithMinConfidence does during the analysis, such as
+// to write a stricter report from the output of an analysis. The violations
+// left without incidents are removed.
+func FilterConfidence(ruleSets []konveyor.RuleSet, confidence float64) {
+	if confidence <= 0 {
+		return
+	}
+	for _, rs := range ruleSets {
+		for id, v := range rs.Violations {
+			incidents := []konveyor.Incident{}
+			for _, inc := range v.Incidents {
+				if inc.Confidence == nil || *inc.Confidence >= confidence {
+					incidents = append(incidents, inc)
+				}
+			}
+			if len(incidents) == 0 {
+				delete(rs.Violations, id)
+				continue
+			}
+			v.Incidents = incidents
+			rs.Violations[id] = v
+		}
+	}
+}
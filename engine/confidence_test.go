@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testIncidentsConditional struct {
+	incidents []IncidentContext
+}
+
+func (t testIncidentsConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{Matched: true, Incidents: t.incidents}, nil
+}
+
+func TestRuleEngineMinConfidence(t *testing.T) {
+	confidence := func(c float64) *float64 { return &c }
+	message := "found"
+	rule := func(id string, incidents ...IncidentContext) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testIncidentsConditional{incidents: incidents},
+		}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			rule("precise", IncidentContext{FileURI: "file:///a.java"}),
+			rule("mixed",
+				IncidentContext{FileURI: "file:///a.java", Confidence: confidence(0.5)},
+				IncidentContext{FileURI: "file:///b.java", Confidence: confidence(0.7)},
+			),
+			rule("text-search", IncidentContext{FileURI: "file:///a.java", Confidence: confidence(0.5)}),
+		},
+	}}
+	tests := []struct {
+		name          string
+		minConfidence float64
+		wantIncidents map[string][]string
+		wantUnmatched []string
+	}{
+		{
+			name: "no minimum",
+			wantIncidents: map[string][]string{
+				"precise":     {"file:///a.java"},
+				"mixed":       {"file:///a.java", "file:///b.java"},
+				"text-search": {"file:///a.java"},
+			},
+			wantUnmatched: []string{},
+		},
+		{
+			name:          "minimum",
+			minConfidence: 0.6,
+			wantIncidents: map[string][]string{
+				"precise": {"file:///a.java"},
+				"mixed":   {"file:///b.java"},
+			},
+			wantUnmatched: []string{"text-search"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithMinConfidence(tt.minConfidence))
+			defer ruleEngine.Stop()
+			results := ruleEngine.RunRules(context.Background(), ruleSets)
+			if len(results) != 1 {
+				t.Fatalf("expected one ruleset, got %+v", results)
+			}
+			gotIncidents := map[string][]string{}
+			for id, v := range results[0].Violations {
+				for _, i := range v.Incidents {
+					gotIncidents[id] = append(gotIncidents[id], string(i.URI))
+				}
+			}
+			if !reflect.DeepEqual(gotIncidents, tt.wantIncidents) {
+				t.Errorf("expected incidents %v, got %v", tt.wantIncidents, gotIncidents)
+			}
+			if !reflect.DeepEqual(results[0].Unmatched, tt.wantUnmatched) {
+				t.Errorf("expected unmatched rules %v, got %v", tt.wantUnmatched, results[0].Unmatched)
+			}
+			if v, ok := results[0].Violations["mixed"]; ok {
+				for _, i := range v.Incidents {
+					if i.Confidence == nil {
+						t.Errorf("expected the confidence of %s to be in the output", i.URI)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfidence(t *testing.T) {
+	for _, c := range []float64{0, 0.5, 1} {
+		if err := ValidateConfidence(c); err != nil {
+			t.Errorf("unexpected error for %v: %v", c, err)
+		}
+	}
+	for _, c := range []float64{-0.1, 1.5} {
+		if err := ValidateConfidence(c); err == nil {
+			t.Errorf("expected an error for %v", c)
+		}
+	}
+}
+
+func TestFilterConfidence(t *testing.T) {
+	confidence := func(c float64) *float64 { return &c }
+	ruleSets := []konveyor.RuleSet{
+		{
+			Name: "generic",
+			Violations: map[string]konveyor.Violation{
+				"text-search": {Incidents: []konveyor.Incident{
+					{URI: "file:///a.py", Confidence: confidence(0.5)},
+				}},
+				"mixed": {Incidents: []konveyor.Incident{
+					{URI: "file:///a.py", Confidence: confidence(0.5)},
+					{URI: "file:///b.py", Confidence: confidence(0.8)},
+					{URI: "file:///c.py"},
+				}},
+			},
+		},
+	}
+	FilterConfidence(ruleSets, 0.7)
+	if _, ok := ruleSets[0].Violations["text-search"]; ok {
+		t.Errorf("expected the violation without incidents left to be removed")
+	}
+	got := []string{}
+	for _, inc := range ruleSets[0].Violations["mixed"].Incidents {
+		got = append(got, string(inc.URI))
+	}
+	if want := []string{"file:///b.py", "file:///c.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the incidents %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// DedupIdentity is what makes two incidents at the same location duplicates
+type DedupIdentity string
+
+const (
+	// DedupByRuleID deduplicates the incidents of the rules that have the same
+	// rule ID in different rulesets
+	DedupByRuleID DedupIdentity = "ruleID"
+	// DedupByMessage deduplicates the incidents that have the same message,
+	// whatever rule they come from
+	DedupByMessage DedupIdentity = "message"
+)
+
+func (d DedupIdentity) Validate() error {
+	switch d {
+	case "", DedupByRuleID, DedupByMessage:
+		return nil
+	}
+	return fmt.Errorf("unknown deduplication identity %q, one of: %s, %s", d, DedupByRuleID, DedupByMessage)
+}
+
+// WithDeduplication merges the incidents found at the same location by rules
+// of overlapping rulesets, see DeduplicateIncidents
+func WithDeduplication(identity DedupIdentity) Option {
+	return func(engine *ruleEngine) {
+		engine.dedup = identity
+	}
+}
+
+// DeduplicateIncidents keeps one incident of the incidents found at the same
+// URI and line that have the same identity. The incident of the first
+// ruleset by name, and of its first rule by ID, is kept and lists all the
+// rules that found it in MergedFrom. The violations left without incidents
+// are removed.
+func DeduplicateIncidents(ruleSets []konveyor.RuleSet, identity DedupIdentity) {
+	if identity == "" {
+		return
+	}
+	type location struct {
+		identity string
+		uri      string
+		line     int
+	}
+	type kept struct {
+		ruleSet, rule string
+		index         int
+	}
+
+	order := make([]int, len(ruleSets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ruleSets[order[a]].Name < ruleSets[order[b]].Name
+	})
+
+	seen := map[location]kept{}
+	merged := map[kept][]string{}
+	for _, i := range order {
+		rs := ruleSets[i]
+		ruleIDs := []string{}
+		for id := range rs.Violations {
+			ruleIDs = append(ruleIDs, id)
+		}
+		sort.Strings(ruleIDs)
+		for _, id := range ruleIDs {
+			v := rs.Violations[id]
+			incidents := []konveyor.Incident{}
+			for _, inc := range v.Incidents {
+				loc := location{uri: string(inc.URI)}
+				if inc.LineNumber != nil {
+					loc.line = *inc.LineNumber
+				}
+				switch identity {
+				case DedupByRuleID:
+					loc.identity = id
+				case DedupByMessage:
+					loc.identity = inc.Message
+				}
+				k, ok := seen[loc]
+				if !ok {
+					seen[loc] = kept{ruleSet: rs.Name, rule: id, index: len(incidents)}
+					incidents = append(incidents, inc)
+					continue
+				}
+				merged[k] = append(merged[k], fmt.Sprintf("%s/%s", rs.Name, id))
+			}
+			v.Incidents = incidents
+			if len(incidents) == 0 {
+				delete(rs.Violations, id)
+				continue
+			}
+			rs.Violations[id] = v
+		}
+	}
+
+	byName := map[string]konveyor.RuleSet{}
+	for _, rs := range ruleSets {
+		byName[rs.Name] = rs
+	}
+	for k, from := range merged {
+		v := byName[k.ruleSet].Violations[k.rule]
+		v.Incidents[k.index].MergedFrom = append([]string{fmt.Sprintf("%s/%s", k.ruleSet, k.rule)}, from...)
+	}
+}
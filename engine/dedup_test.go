@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestDeduplicateIncidents(t *testing.T) {
+	line := func(i int) *int { return &i }
+	ruleSets := func() []konveyor.RuleSet {
+		return []konveyor.RuleSet{
+			{
+				Name: "quarkus",
+				Violations: map[string]konveyor.Violation{
+					"javax-import": {Incidents: []konveyor.Incident{
+						{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"},
+						{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax"},
+					}},
+					"other": {Incidents: []konveyor.Incident{
+						{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"},
+					}},
+				},
+			},
+			{
+				Name: "eap",
+				Violations: map[string]konveyor.Violation{
+					"javax-import": {Incidents: []konveyor.Incident{
+						{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"},
+					}},
+				},
+			},
+		}
+	}
+	type incidents map[string]map[string][]konveyor.Incident
+	tests := []struct {
+		name     string
+		identity DedupIdentity
+		want     incidents
+	}{
+		{
+			name: "disabled",
+			want: incidents{
+				"quarkus": {
+					"javax-import": {
+						{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"},
+						{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax"},
+					},
+					"other": {{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"}},
+				},
+				"eap": {
+					"javax-import": {{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"}},
+				},
+			},
+		},
+		{
+			name:     "by rule ID",
+			identity: DedupByRuleID,
+			want: incidents{
+				"quarkus": {
+					"javax-import": {{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax"}},
+					"other":        {{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax"}},
+				},
+				"eap": {
+					"javax-import": {{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax", MergedFrom: []string{"eap/javax-import", "quarkus/javax-import"}}},
+				},
+			},
+		},
+		{
+			name:     "by message",
+			identity: DedupByMessage,
+			want: incidents{
+				"quarkus": {
+					"javax-import": {{URI: "file:///a.java", LineNumber: line(2), Message: "replace javax"}},
+				},
+				"eap": {
+					"javax-import": {{URI: "file:///a.java", LineNumber: line(1), Message: "replace javax", MergedFrom: []string{"eap/javax-import", "quarkus/javax-import", "quarkus/other"}}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := ruleSets()
+			DeduplicateIncidents(rs, tt.identity)
+			got := incidents{}
+			for _, r := range rs {
+				got[r.Name] = map[string][]konveyor.Incident{}
+				for id, v := range r.Violations {
+					got[r.Name][id] = v.Incidents
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected incidents %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
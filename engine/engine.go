@@ -6,30 +6,70 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.lsp.dev/uri"
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/cbroglie/mustache"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/tracking"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/redact"
 	"github.com/konveyor/analyzer-lsp/tracing"
 )
 
 type RuleEngine interface {
 	RunRules(context context.Context, rules []RuleSet, selectors ...RuleSelector) []konveyor.RuleSet
+	// Health returns the health of the providers the rules are evaluated with
+	Health() []HealthStatus
+	// Warnings returns the non fatal problems the providers found
+	Warnings() []konveyor.Warning
+	// Traces returns how the rules were evaluated, when they were traced
+	Traces() []konveyor.RuleTrace
+	// Plan returns what RunRules would evaluate, without evaluating it
+	Plan(rules []RuleSet, selectors ...RuleSelector) konveyor.Plan
 	Stop()
 }
 
+// HealthStatus is the health of a provider used to evaluate the rules
+type HealthStatus struct {
+	Name                string
+	Healthy             bool
+	Failed              bool
+	ConsecutiveFailures int
+	Restarts            int
+	LastError           string
+	LastCheck           time.Time
+}
+
+// HealthReporter reports the health of the providers
+type HealthReporter interface {
+	Health() []HealthStatus
+}
+
+// WarningReporter reports the non fatal problems found while evaluating the
+// rules, such as files that failed to parse, so that they end up in the output.
+type WarningReporter interface {
+	Warnings() []konveyor.Warning
+}
+
 type ruleMessage struct {
 	rule        Rule
 	ruleSetName string
 	ctx         ConditionContext
 	returnChan  chan response
+	trace       bool
+	// runCtx is the context of the RunRules call the rule is evaluated for,
+	// the queries of the rule are canceled along with it
+	runCtx context.Context
+	// pause holds the rule while the run is paused
+	pause *Pause
 }
 
 type response struct {
@@ -37,6 +77,7 @@ type response struct {
 	Err               error             `yaml:"err"`
 	Rule              Rule              `yaml:"rule"`
 	RuleSetName       string
+	Trace             *konveyor.RuleTrace
 }
 
 type ruleEngine struct {
@@ -47,9 +88,47 @@ type ruleEngine struct {
 
 	wg *sync.WaitGroup
 
-	incidentLimit int
-	codeSnipLimit int
-	contextLines  int
+	incidentLimit   int
+	codeSnipLimit   int
+	contextLines    int
+	codeSnipMaxSize int
+
+	checkpointPath     string
+	checkpointInterval time.Duration
+	resume             bool
+	queryCache         QueryCache
+	workspace          Workspace
+
+	healthReporter HealthReporter
+
+	warningReporters []WarningReporter
+
+	scope    *Scope
+	location string
+
+	trace       bool
+	tracesMutex sync.Mutex
+	traces      []konveyor.RuleTrace
+
+	middlewares []RuleMiddleware
+
+	dedup DedupIdentity
+
+	ruleOrder RuleOrder
+
+	minConfidence float64
+
+	resultWriter ResultWriter
+
+	overrides []RuleOverride
+
+	pause *Pause
+
+	targets []Target
+
+	ruleFilter RuleFilter
+
+	canonicalOrder bool
 }
 
 type Option func(engine *ruleEngine)
@@ -72,6 +151,76 @@ func WithCodeSnipLimit(i int) Option {
 	}
 }
 
+// WithCodeSnipMaxSize caps the size in bytes of the code snip of each
+// incident, the lines furthest from the incident are cut first. 0 means no
+// limit.
+func WithCodeSnipMaxSize(size int) Option {
+	return func(engine *ruleEngine) {
+		engine.codeSnipMaxSize = size
+	}
+}
+
+// WithCheckpoint periodically saves the evaluated rules to the given path
+func WithCheckpoint(path string, interval time.Duration) Option {
+	return func(engine *ruleEngine) {
+		engine.checkpointPath = path
+		engine.checkpointInterval = interval
+	}
+}
+
+// WithResume skips the rules that are already evaluated in the checkpoint
+func WithResume(resume bool) Option {
+	return func(engine *ruleEngine) {
+		engine.resume = resume
+	}
+}
+
+// WithQueryCache saves and restores the cache along with the checkpoint
+func WithQueryCache(cache QueryCache) Option {
+	return func(engine *ruleEngine) {
+		engine.queryCache = cache
+	}
+}
+
+// WithHealthReporter exposes the health of the providers through the engine
+func WithHealthReporter(h HealthReporter) Option {
+	return func(engine *ruleEngine) {
+		engine.healthReporter = h
+	}
+}
+
+// WithWarningReporter adds the warnings of the reporter to the warnings of
+// the engine, it can be given multiple times.
+func WithWarningReporter(w WarningReporter) Option {
+	return func(engine *ruleEngine) {
+		engine.warningReporters = append(engine.warningReporters, w)
+	}
+}
+
+// WithScope limits the files that the rules are evaluated on
+func WithScope(scope *Scope) Option {
+	return func(engine *ruleEngine) {
+		engine.scope = scope
+	}
+}
+
+// WithLocation sets the analyzed directory that the relative filepaths of the
+// conditions are in
+func WithLocation(location string) Option {
+	return func(engine *ruleEngine) {
+		engine.location = location
+	}
+}
+
+// WithTrace records how the conditions of each rule are evaluated: the query
+// sent to the providers, the number of incidents, the duration and the
+// outcome of each condition.
+func WithTrace(trace bool) Option {
+	return func(engine *ruleEngine) {
+		engine.trace = trace
+	}
+}
+
 func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options ...Option) RuleEngine {
 	// Only allow for 10 rules to be waiting in the buffer at once.
 	// Adding more workers will increase the number of rules running at once.
@@ -91,6 +240,7 @@ func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options
 		cancelFunc:     cancelFunc,
 		logger:         log,
 		wg:             wg,
+		canonicalOrder: true,
 	}
 	for _, o := range options {
 		o(r)
@@ -98,6 +248,51 @@ func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options
 	return r
 }
 
+func (r *ruleEngine) Health() []HealthStatus {
+	if r.healthReporter == nil {
+		return []HealthStatus{}
+	}
+	return r.healthReporter.Health()
+}
+
+// Warnings returns the warnings of all the reporters sorted by provider
+func (r *ruleEngine) Warnings() []konveyor.Warning {
+	warnings := []konveyor.Warning{}
+	for _, w := range r.warningReporters {
+		warnings = append(warnings, w.Warnings()...)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Provider != warnings[j].Provider {
+			return warnings[i].Provider < warnings[j].Provider
+		}
+		return warnings[i].Message < warnings[j].Message
+	})
+	return warnings
+}
+
+// Traces returns the traces of the evaluated rules sorted by ruleset and rule
+func (r *ruleEngine) Traces() []konveyor.RuleTrace {
+	r.tracesMutex.Lock()
+	defer r.tracesMutex.Unlock()
+	traces := append([]konveyor.RuleTrace{}, r.traces...)
+	sort.SliceStable(traces, func(i, j int) bool {
+		if traces[i].RuleSet != traces[j].RuleSet {
+			return traces[i].RuleSet < traces[j].RuleSet
+		}
+		return traces[i].Rule < traces[j].Rule
+	})
+	return traces
+}
+
+func (r *ruleEngine) addTrace(trace *konveyor.RuleTrace) {
+	if trace == nil {
+		return
+	}
+	r.tracesMutex.Lock()
+	defer r.tracesMutex.Unlock()
+	r.traces = append(r.traces, *trace)
+}
+
 func (r *ruleEngine) Stop() {
 	r.cancelFunc()
 	r.logger.V(5).Info("rule engine stopping")
@@ -108,16 +303,34 @@ func processRuleWorker(ctx context.Context, ruleMessages chan ruleMessage, logge
 	for {
 		select {
 		case m := <-ruleMessages:
+			if m.runCtx != nil && m.runCtx.Err() != nil {
+				// the rules left in the buffer of a canceled run
+				logger.V(5).Info("skipping rule of canceled run", "ruleset", m.ruleSetName, "rule", m.rule.RuleID)
+				continue
+			}
 			logger.V(5).Info("taking rule", "ruleset", m.ruleSetName, "rule", m.rule.RuleID)
 			m.ctx.Template = make(map[string]ChainTemplate)
-			bo, err := processRule(ctx, m.rule, m.ctx, logger)
+			ruleCtx, cancel := withEngineCancel(m.runCtx, ctx)
+			if err := m.pause.acquire(ruleCtx); err != nil {
+				// the run was canceled or the engine stopped while paused
+				cancel()
+				continue
+			}
+			bo, trace, err := processTracedRule(ruleCtx, m.rule, m.ruleSetName, m.ctx, logger, m.trace)
+			m.pause.release()
 			logger.V(5).Info("finished rule", "found", len(bo.Incidents), "error", err, "rule", m.rule.RuleID)
-			m.returnChan <- response{
+			// nobody waits for the response of a canceled run
+			select {
+			case m.returnChan <- response{
 				ConditionResponse: bo,
 				Err:               err,
 				Rule:              m.rule,
 				RuleSetName:       m.ruleSetName,
+				Trace:             trace,
+			}:
+			case <-ruleCtx.Done():
 			}
+			cancel()
 		case <-ctx.Done():
 			logger.V(5).Info("stopping rule worker")
 			wg.Done()
@@ -126,6 +339,24 @@ func processRuleWorker(ctx context.Context, ruleMessages chan ruleMessage, logge
 	}
 }
 
+// withEngineCancel returns the context a rule is evaluated with, the one of its
+// run, which is also canceled when the engine is stopped, so that the queries
+// sent to the providers are canceled in both cases
+func withEngineCancel(runCtx, engineCtx context.Context) (context.Context, context.CancelFunc) {
+	if runCtx == nil {
+		runCtx = engineCtx
+	}
+	ctx, cancel := context.WithCancel(runCtx)
+	go func() {
+		select {
+		case <-engineCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 func (r *ruleEngine) createRuleSet(ruleSet RuleSet) *konveyor.RuleSet {
 	rs := &konveyor.RuleSet{
 		Name:        ruleSet.Name,
@@ -148,7 +379,55 @@ func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors
 
 	taggingRules, otherRules, mapRuleSets := r.filterRules(ruleSets, selectors...)
 
-	ruleContext := r.runTaggingRules(ctx, taggingRules, mapRuleSets)
+	var cp *checkpointer
+	var resumed *checkpoint
+	if r.checkpointPath != "" {
+		cp = &checkpointer{
+			path:      r.checkpointPath,
+			interval:  r.checkpointInterval,
+			cache:     r.queryCache,
+			workspace: r.workspace,
+			state:     newCheckpoint(),
+			lastWrite: time.Now(),
+		}
+		if r.resume {
+			var err error
+			resumed, err = cp.resume(mapRuleSets)
+			if err != nil {
+				r.logger.Error(err, "unable to resume from checkpoint, running all the rules", "checkpoint", r.checkpointPath)
+			} else if resumed.changes != nil {
+				r.logger.Info("resuming from checkpoint, evaluating its rules again on the changed files", "checkpoint", r.checkpointPath, "changed", resumed.changes.Paths())
+			} else {
+				r.logger.Info("resuming from checkpoint", "checkpoint", r.checkpointPath)
+			}
+		}
+	}
+
+	ruleContext := r.runTaggingRules(ctx, taggingRules, mapRuleSets, cp, resumed)
+	if cp != nil {
+		if err := cp.save(true, ruleContext.Tags, mapRuleSets); err != nil {
+			r.logger.Error(err, "unable to save checkpoint", "checkpoint", r.checkpointPath)
+		}
+	}
+
+	// the middlewares are called before the rules are dispatched, so that
+	// the skipped and failed rules are recorded before the responses are
+	// handled
+	dispatchRules := []ruleMessage{}
+	for _, rule := range otherRules {
+		if resumed != nil && resumed.evaluated(resumed.Rules, rule.ruleSetName, rule.rule.RuleID) && resumed.changes == nil {
+			r.logger.V(5).Info("rule already evaluated in checkpoint, skipping", "ruleID", rule.rule.RuleID)
+			continue
+		}
+		eval := r.beforeRule(ctx, rule.ruleSetName, rule.rule, ruleContext)
+		if !recordBeforeRule(eval, mapRuleSets) {
+			r.logger.V(5).Info("rule skipped by a middleware", "ruleID", rule.rule.RuleID, "error", eval.Err)
+			continue
+		}
+		rule.rule = eval.Rule
+		dispatchRules = append(dispatchRules, rule)
+	}
+	r.writeRuleSets(mapRuleSets)
 
 	// Need a better name for this thing
 	ret := make(chan response)
@@ -159,26 +438,37 @@ func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors
 	var failedRules int32
 
 	wg := &sync.WaitGroup{}
+	// dispatched and handled count the rules, so that the ones a canceled
+	// run leaves are done once it stops handling them
+	dispatched, handled := 0, 0
+	handlerDone := make(chan struct{})
 	// Handle returns
 	go func() {
+		defer close(handlerDone)
 		for {
 			select {
 			case response := <-ret:
 				func() {
 					r.logger.Info("rule returned", "rule", response.Rule.RuleID)
 					defer wg.Done()
+					handled++
+					r.addTrace(response.Trace)
+					response.Rule, response.ConditionResponse, response.Err = r.afterRule(ctx, response.RuleSetName, response.Rule, ruleContext, response.ConditionResponse, response.Err)
+					response.ConditionResponse.Incidents = r.filterConfidence(response.ConditionResponse.Incidents)
+					response.ConditionResponse = applyThreshold(response.Rule.Threshold, response.ConditionResponse)
 					if response.Err != nil {
 						atomic.AddInt32(&failedRules, 1)
 						r.logger.Error(response.Err, "failed to evaluate rule", "ruleID", response.Rule.RuleID)
 
 						if rs, ok := mapRuleSets[response.RuleSetName]; ok {
-							rs.Errors[response.Rule.RuleID] = response.Err.Error()
+							rs.Errors[response.Rule.RuleID] = redact.String(response.Err.Error())
 						}
 					} else if response.ConditionResponse.Matched && len(response.ConditionResponse.Incidents) > 0 {
 						violation, err := r.createViolation(ctx, response.ConditionResponse, response.Rule)
 						if err != nil {
 							r.logger.Error(err, "unable to create violation from response")
 						}
+						tracking.SetFingerprints(response.RuleSetName, response.Rule.RuleID, violation.Incidents)
 						atomic.AddInt32(&matchedRules, 1)
 
 						rs, ok := mapRuleSets[response.RuleSetName]
@@ -198,6 +488,18 @@ func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors
 					atomic.AddInt32(&totalRules, 1)
 					r.logger.V(5).Info("rule response received", "total", totalRules, "failed", failedRules, "matched", matchedRules, "unmatched", unmatchedRules)
 
+					if resumed != nil {
+						resumed.mergeKept(mapRuleSets[response.RuleSetName], response.Rule.RuleID)
+					}
+					r.writeRuleResult(mapRuleSets[response.RuleSetName], response.Rule.RuleID)
+
+					if cp != nil {
+						cp.markRule(response.RuleSetName, response.Rule.RuleID)
+						if err := cp.save(false, ruleContext.Tags, mapRuleSets); err != nil {
+							r.logger.Error(err, "unable to save checkpoint", "checkpoint", r.checkpointPath)
+						}
+					}
+
 				}()
 			case <-ctx.Done():
 				// At this point we should just return the function, we may want to close the wait group too.
@@ -206,26 +508,61 @@ func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors
 		}
 	}()
 
-	for _, rule := range otherRules {
-		wg.Add(1)
-		rule.returnChan = ret
-		rule.ctx = ruleContext
-		r.ruleProcessing <- rule
+dispatch:
+	for i, batch := range orderRules(dispatchRules, r.ruleOrder) {
+		if i > 0 {
+			// the rules of the previous batch have to be done first
+			select {
+			case <-waitDone(wg):
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		for _, rule := range batch {
+			wg.Add(1)
+			dispatched++
+			rule.returnChan = ret
+			rule.ctx = ruleContext
+			if resumed != nil && resumed.reevaluated(resumed.Rules, rule.ruleSetName, rule.rule.RuleID) {
+				rule.ctx = resumed.changedContext(ruleContext)
+			}
+			rule.trace = r.trace
+			rule.runCtx = ctx
+			rule.pause = r.pause
+			select {
+			case r.ruleProcessing <- rule:
+			case <-ctx.Done():
+				// the canceled run does not schedule the rules left
+				wg.Done()
+				dispatched--
+				break dispatch
+			}
+		}
 	}
-	r.logger.V(5).Info("All rules added buffer, waiting for engine to complete", "size", len(otherRules))
-
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		wg.Wait()
-	}()
+	r.logger.V(5).Info("All rules added buffer, waiting for engine to complete", "size", len(dispatchRules))
 
 	// Wait for all the rules to process
 	select {
-	case <-done:
+	case <-waitDone(wg):
 		r.logger.V(2).Info("done processing all the rules")
+		if cp != nil {
+			if err := cp.save(true, ruleContext.Tags, mapRuleSets); err != nil {
+				r.logger.Error(err, "unable to save checkpoint", "checkpoint", r.checkpointPath)
+			}
+		}
 	case <-ctx.Done():
 		r.logger.V(1).Info("processing of rules was canceled")
+		// the workers drop the rules of a canceled run, they are done
+		// here so that the goroutines waiting for them return
+		<-handlerDone
+		for ; handled < dispatched; handled++ {
+			wg.Done()
+		}
+	}
+	for _, h := range r.Health() {
+		if !h.Healthy {
+			r.logger.Info("provider was not healthy while evaluating the rules", "provider", h.Name, "failed", h.Failed, "error", h.LastError)
+		}
 	}
 	responses := []konveyor.RuleSet{}
 	for _, ruleSet := range mapRuleSets {
@@ -233,11 +570,25 @@ func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors
 			responses = append(responses, *ruleSet)
 		}
 	}
+	DeduplicateIncidents(responses, r.dedup)
+	if r.canonicalOrder {
+		Canonicalize(responses)
+	}
 	// Cannel running go-routine
 	cancelFunc()
 	return responses
 }
 
+// waitDone returns a channel that is closed once the wait group is done
+func waitDone(wg *sync.WaitGroup) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+	return done
+}
+
 // filterRules splits rules into tagging and other rules
 func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector) ([]ruleMessage, []ruleMessage, map[string]*konveyor.RuleSet) {
 	// filter rules that generate tags, they run first
@@ -245,19 +596,28 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 	mapRuleSets := map[string]*konveyor.RuleSet{}
 	// all rules except meta
 	otherRules := []ruleMessage{}
+	usedOverrides := map[int]bool{}
 	for _, ruleSet := range ruleSets {
 		mapRuleSets[ruleSet.Name] = r.createRuleSet(ruleSet)
 		for _, rule := range ruleSet.Rules {
 			// labels on ruleset apply to all rules in it
 			rule.Labels = append(rule.Labels, ruleSet.Labels...)
-			// skip rule when doesn't match any selector
-			if !matchesAllSelectors(rule.RuleMeta, selectors...) {
+			rule = r.applyOverrides(ruleSet.Name, rule, usedOverrides)
+			// skip rule when the filter does not select it, without querying
+			// the providers for it
+			if reason := r.ruleFilter.skipReason(rule.RuleMeta); reason != "" {
+				mapRuleSets[ruleSet.Name].Skipped = append(mapRuleSets[ruleSet.Name].Skipped, rule.RuleID)
+				r.logger.V(5).Info("the rule filter does not select the rule, skipping", "ruleID", rule.RuleID, "reason", reason)
+				continue
+			}
+			// skip rule when doesn't match any selector, or any of the targets
+			if !matchesAllSelectors(rule.RuleMeta, selectors...) || (len(r.targets) > 0 && len(r.ruleTargets(rule.RuleMeta)) == 0) {
 				mapRuleSets[ruleSet.Name].Skipped = append(mapRuleSets[ruleSet.Name].Skipped, rule.RuleID)
 				r.logger.V(5).Info("one or more selectors did not match for rule, skipping", "ruleID", rule.RuleID)
 				continue
 			}
 
-			if rule.Perform.Tag == nil {
+			if !rule.Perform.IsTagging() {
 				otherRules = append(otherRules, ruleMessage{
 					rule:        rule,
 					ruleSetName: ruleSet.Name,
@@ -271,6 +631,7 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 				// split message part into a new rule
 				if rule.Perform.Message.Text != nil {
 					rule.Perform.Tag = nil
+					rule.Perform.StructuredTags = nil
 					otherRules = append(
 						otherRules,
 						ruleMessage{
@@ -282,25 +643,74 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 			}
 		}
 	}
+	for i, o := range r.overrides {
+		if !usedOverrides[i] {
+			r.logger.Info("rule override does not match any rule", "ruleID", o.RuleID, "ruleSet", o.RuleSet)
+		}
+	}
 	return taggingRules, otherRules, mapRuleSets
 }
 
 // runTaggingRules filters and runs info rules synchronously
 // returns list of non-info rules, a context to pass to them
-func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessage, mapRuleSets map[string]*konveyor.RuleSet) ConditionContext {
+// when resuming, the tags of the checkpoint are restored and the rules already
+// evaluated in it are skipped, cp records the newly evaluated ones
+func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessage, mapRuleSets map[string]*konveyor.RuleSet, cp *checkpointer, resumed *checkpoint) ConditionContext {
 	context := ConditionContext{
 		Tags:     make(map[string]interface{}),
 		Template: make(map[string]ChainTemplate),
+		Scope:    r.scope,
+		Location: r.location,
 	}
 	// track unique tags per ruleset
 	rulesetTagsCache := map[string]map[string]bool{}
+	rulesetStructuredTagsCache := map[string]map[konveyor.Tag]bool{}
+	if resumed != nil {
+		for tag, v := range resumed.Tags {
+			context.Tags[tag] = v
+		}
+		for name, rs := range mapRuleSets {
+			rulesetTagsCache[name] = map[string]bool{}
+			for _, tag := range rs.Tags {
+				rulesetTagsCache[name][tag] = true
+			}
+			rulesetStructuredTagsCache[name] = map[konveyor.Tag]bool{}
+			for _, tag := range rs.StructuredTags {
+				rulesetStructuredTagsCache[name][tag] = true
+			}
+		}
+	}
 	for _, ruleMessage := range infoRules {
 		rule := ruleMessage.rule
-		response, err := processRule(ctx, rule, context, r.logger)
+		ruleContext := context
+		if resumed != nil && resumed.reevaluated(resumed.TaggingRules, ruleMessage.ruleSetName, rule.RuleID) {
+			ruleContext = resumed.changedContext(context)
+		} else if resumed != nil && resumed.evaluated(resumed.TaggingRules, ruleMessage.ruleSetName, rule.RuleID) {
+			r.logger.V(5).Info("tagging rule already evaluated in checkpoint, skipping", "ruleID", rule.RuleID)
+			continue
+		}
+		eval := r.beforeRule(ctx, ruleMessage.ruleSetName, rule, ruleContext)
+		if !recordBeforeRule(eval, mapRuleSets) {
+			r.logger.V(5).Info("tagging rule skipped by a middleware", "ruleID", rule.RuleID, "error", eval.Err)
+			continue
+		}
+		rule = eval.Rule
+		if err := r.pause.acquire(ctx); err != nil {
+			// the run was canceled while paused
+			break
+		}
+		if cp != nil {
+			cp.markTaggingRule(ruleMessage.ruleSetName, rule.RuleID)
+		}
+		response, trace, err := processTracedRule(ctx, rule, ruleMessage.ruleSetName, ruleContext, r.logger, r.trace)
+		r.pause.release()
+		r.addTrace(trace)
+		rule, response, err = r.afterRule(ctx, ruleMessage.ruleSetName, rule, ruleContext, response, err)
+		response = applyThreshold(rule.Threshold, response)
 		if err != nil {
 			r.logger.Error(err, "failed to evaluate rule", "ruleID", rule.RuleID)
 			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
-				rs.Errors[rule.RuleID] = err.Error()
+				rs.Errors[rule.RuleID] = redact.String(err.Error())
 			}
 		} else if response.Matched {
 			r.logger.V(5).Info("info rule was matched", "ruleID", rule.RuleID)
@@ -309,14 +719,7 @@ func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessag
 				if strings.Contains(tagString, "{{") && strings.Contains(tagString, "}}") {
 					for _, incident := range response.Incidents {
 						// If this is the case then we neeed to use the reponse variables to get the tag
-						variables := make(map[string]interface{})
-						for key, value := range incident.Variables {
-							variables[key] = value
-						}
-						if incident.LineNumber != nil {
-							variables["lineNumber"] = *incident.LineNumber
-						}
-						templateString, err := r.createPerformString(tagString, variables)
+						templateString, err := r.createPerformString(tagString, incidentTemplateVariables(incident))
 						if err != nil {
 							r.logger.Error(err, "unable to create tag string")
 							continue
@@ -326,15 +729,24 @@ func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessag
 				} else {
 					tags[tagString] = true
 				}
-				for t := range tags {
-					tags, err := parseTagsFromPerformString(t)
-					if err != nil {
-						r.logger.Error(err, "unable to create tags", "ruleID", rule.RuleID)
-						continue
-					}
-					for _, tag := range tags {
-						context.Tags[tag] = true
-					}
+			}
+			structured := map[konveyor.Tag]bool{}
+			for t := range tags {
+				category, values, err := parseTagString(t)
+				if err != nil {
+					r.logger.Error(err, "unable to create tags", "ruleID", rule.RuleID)
+					continue
+				}
+				for _, value := range values {
+					context.Tags[value] = true
+					structured[konveyor.Tag{Category: category, Value: value}] = true
+				}
+			}
+			for _, tag := range rule.Perform.StructuredTags {
+				for _, rendered := range r.renderStructuredTag(tag, response.Incidents) {
+					context.Tags[rendered.Value] = true
+					tags[rendered.String()] = true
+					structured[rendered] = true
 				}
 			}
 			rs, ok := mapRuleSets[ruleMessage.ruleSetName]
@@ -350,6 +762,15 @@ func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessag
 						rs.Tags = append(rs.Tags, tag)
 					}
 				}
+				if _, ok := rulesetStructuredTagsCache[rs.Name]; !ok {
+					rulesetStructuredTagsCache[rs.Name] = make(map[konveyor.Tag]bool)
+				}
+				for _, tag := range sortedTags(structured) {
+					if !rulesetStructuredTagsCache[rs.Name][tag] {
+						rulesetStructuredTagsCache[rs.Name][tag] = true
+						rs.StructuredTags = append(rs.StructuredTags, tag)
+					}
+				}
 				mapRuleSets[ruleMessage.ruleSetName] = rs
 			}
 		} else {
@@ -362,20 +783,106 @@ func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessag
 	return context
 }
 
+var tagStringPattern = regexp.MustCompile(`^(?:([\w- \(\)]+)=){0,1}([\w- \(\)]+(?:, *[\w- \(\),]+)*),?$`)
+
 func parseTagsFromPerformString(tagString string) ([]string, error) {
+	_, tags, err := parseTagString(tagString)
+	return tags, err
+}
+
+// parseTagString returns the category of a Category=tag1,tag2 tag string,
+// empty when it has none, and its tags
+func parseTagString(tagString string) (string, []string, error) {
 	tags := []string{}
-	pattern := regexp.MustCompile(`^(?:[\w- \(\)]+=){0,1}([\w- \(\)]+(?:, *[\w- \(\),]+)*),?$`)
-	if !pattern.MatchString(tagString) {
-		return nil, fmt.Errorf("unexpected tag string %s", tagString)
+	if !tagStringPattern.MatchString(tagString) {
+		return "", nil, fmt.Errorf("unexpected tag string %s", tagString)
 	}
-	for _, groups := range pattern.FindAllStringSubmatch(tagString, -1) {
-		for _, tag := range strings.Split(groups[1], ",") {
+	category := ""
+	for _, groups := range tagStringPattern.FindAllStringSubmatch(tagString, -1) {
+		category = strings.Trim(groups[1], " ")
+		for _, tag := range strings.Split(groups[2], ",") {
 			if tag != "" {
 				tags = append(tags, strings.Trim(tag, " "))
 			}
 		}
 	}
-	return tags, nil
+	return category, tags, nil
+}
+
+// renderStructuredTag returns the tag with the variables of each incident when
+// its category or value is a template
+func (r *ruleEngine) renderStructuredTag(tag konveyor.Tag, incidents []IncidentContext) []konveyor.Tag {
+	if !strings.Contains(tag.Category+tag.Value, "{{") {
+		return []konveyor.Tag{tag}
+	}
+	rendered := []konveyor.Tag{}
+	for _, incident := range incidents {
+		variables := incidentTemplateVariables(incident)
+		category, err := r.createPerformString(tag.Category, variables)
+		if err != nil {
+			r.logger.Error(err, "unable to create tag category")
+			continue
+		}
+		value, err := r.createPerformString(tag.Value, variables)
+		if err != nil {
+			r.logger.Error(err, "unable to create tag value")
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		rendered = append(rendered, konveyor.Tag{Category: category, Value: value})
+	}
+	return rendered
+}
+
+// incidentTemplateVariables are the variables the templates of the actions
+// are rendered with for an incident
+func incidentTemplateVariables(incident IncidentContext) map[string]interface{} {
+	variables := make(map[string]interface{})
+	for key, value := range incident.Variables {
+		variables[key] = value
+	}
+	if incident.LineNumber != nil {
+		variables["lineNumber"] = *incident.LineNumber
+	}
+	return variables
+}
+
+// sortedTags returns the tags sorted by category and value
+func sortedTags(tags map[konveyor.Tag]bool) []konveyor.Tag {
+	sorted := make([]konveyor.Tag, 0, len(tags))
+	for tag := range tags {
+		sorted = append(sorted, tag)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Category != sorted[j].Category {
+			return sorted[i].Category < sorted[j].Category
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	return sorted
+}
+
+// processTracedRule evaluates the rule, with the trace of its conditions when
+// trace is set.
+func processTracedRule(ctx context.Context, rule Rule, ruleSetName string, ruleCtx ConditionContext, log logr.Logger, trace bool) (ConditionResponse, *konveyor.RuleTrace, error) {
+	if !trace {
+		response, err := processRule(ctx, rule, ruleCtx, log)
+		return response, nil, err
+	}
+	ctx, tracer := startTrace(ctx, rule.When)
+	response, err := processRule(ctx, rule, ruleCtx, log)
+	tracer.end(response.Matched, len(response.Incidents), err)
+	return response, &konveyor.RuleTrace{
+		RuleSet:   ruleSetName,
+		Rule:      rule.RuleID,
+		Matched:   tracer.node.Matched,
+		Incidents: tracer.node.Incidents,
+		Duration:  tracer.node.Duration,
+		Error:     tracer.node.Error,
+		Condition: tracer.node,
+	}, err
 }
 
 func processRule(ctx context.Context, rule Rule, ruleCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
@@ -392,7 +899,14 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 	incidents := []konveyor.Incident{}
 	fileCodeSnipCount := map[string]int{}
 	incidentsSet := map[string]struct{}{} // Set of incidents
-	for _, m := range conditionResponse.Incidents {
+	conditionIncidents := conditionResponse.Incidents
+	if r.canonicalOrder {
+		// the incidents past the limit are the same whatever order the
+		// providers found them in
+		conditionIncidents = append([]IncidentContext{}, conditionIncidents...)
+		sortConditionIncidents(conditionIncidents)
+	}
+	for _, m := range conditionIncidents {
 		// Exit loop, we don't care about any incidents past the filter.
 		if r.incidentLimit != 0 && len(incidents) == r.incidentLimit {
 			break
@@ -401,6 +915,7 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 			URI:        m.FileURI,
 			LineNumber: m.LineNumber,
 			Variables:  m.Variables,
+			Confidence: m.Confidence,
 		}
 		if m.LineNumber != nil {
 			lineNumber := *m.LineNumber
@@ -454,14 +969,7 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		}
 
 		if rule.Perform.Message.Text != nil {
-			variables := make(map[string]interface{})
-			for key, value := range m.Variables {
-				variables[key] = value
-			}
-			if m.LineNumber != nil {
-				variables["lineNumber"] = *m.LineNumber
-			}
-			templateString, err := r.createPerformString(*rule.Perform.Message.Text, variables)
+			templateString, err := r.createPerformString(*rule.Perform.Message.Text, incidentTemplateVariables(m))
 			if err != nil {
 				r.logger.Error(err, "unable to create template string")
 			}
@@ -484,56 +992,85 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 
 	rule.Labels = deduplicateLabels(rule.Labels)
 
+	var sample *konveyor.Sample
+	if conditionResponse.Sampled > 0 {
+		sample = &konveyor.Sample{Incidents: len(conditionResponse.Incidents) + conditionResponse.Sampled}
+	}
+
 	return konveyor.Violation{
-		Description: rule.Description,
-		Labels:      rule.Labels,
-		Category:    rule.Category,
-		Incidents:   incidents,
-		Extras:      []byte{},
-		Effort:      rule.Effort,
-		Links:       rule.Perform.Message.Links,
+		Description:     rule.Description,
+		Labels:          rule.Labels,
+		Category:        rule.Category,
+		Incidents:       incidents,
+		Extras:          []byte{},
+		Effort:          rule.Effort,
+		Links:           rule.Perform.Message.Links,
+		AppliedOverride: rule.AppliedOverride,
+		Targets:         r.violationTargets(rule.RuleMeta),
+		Sample:          sample,
 	}, nil
 }
 
 func (r *ruleEngine) getCodeLocation(ctx context.Context, m IncidentContext, rule Rule) (codeSnip string, err error) {
-	if m.CodeLocation == nil {
+	location := m.CodeLocation
+	if location == nil && m.LineNumber != nil && *m.LineNumber > 0 {
+		// the incidents without a location are snipped around their line
+		line := *m.LineNumber - 1
+		location = &Location{
+			StartPosition: Position{Line: line},
+			EndPosition:   Position{Line: line},
+		}
+	}
+	if location == nil {
 		r.logger.V(6).Info("unable to get the code snip", "URI", m.FileURI)
 		return "", nil
 	}
 
 	if strings.HasPrefix(string(m.FileURI), uri.FileScheme) {
-		//Find the file, open it in a buffer.
-		readFile, err := os.Open(m.FileURI.Filename())
-		if err != nil {
-			r.logger.V(5).Error(err, "Unable to read file")
-			return "", err
-		}
-		defer readFile.Close()
-
-		scanner := bufio.NewScanner(readFile)
-		lineNumber := 0
-		codeSnip := ""
-		paddingSize := len(strconv.Itoa(m.CodeLocation.EndPosition.Line + r.contextLines))
-		for scanner.Scan() {
-			if (lineNumber - r.contextLines) == m.CodeLocation.EndPosition.Line {
-				codeSnip = codeSnip + fmt.Sprintf("%*d  %v", paddingSize, lineNumber+1, scanner.Text())
-				break
-			}
-			if (lineNumber + r.contextLines) >= m.CodeLocation.StartPosition.Line {
-				codeSnip = codeSnip + fmt.Sprintf("%*d  %v\n", paddingSize, lineNumber+1, scanner.Text())
-			}
-			lineNumber += 1
+		codeSnip, err := r.readCodeSnip(m.FileURI.Filename(), *location)
+		if err == nil || rule.Snipper == nil {
+			return limitCodeSnip(codeSnip, location.StartPosition.Line, r.codeSnipMaxSize), err
 		}
-		return codeSnip, nil
+		// the file can be in a location only the provider can read
+		r.logger.V(5).Error(err, "Unable to read file, asking the provider")
 	}
 	if rule.Snipper != nil {
-		return rule.Snipper.GetCodeSnip(m.FileURI, *m.CodeLocation)
+		codeSnip, err := rule.Snipper.GetCodeSnip(m.FileURI, *location)
+		return limitCodeSnip(codeSnip, location.StartPosition.Line, r.codeSnipMaxSize), err
 	}
 
 	// if it is not a file ask the provider
 	return "", nil
 }
 
+// readCodeSnip returns the lines of the location in the file, with the
+// context lines around them, prefixed with their line number
+func (r *ruleEngine) readCodeSnip(path string, location Location) (string, error) {
+	//Find the file, open it in a buffer.
+	readFile, err := os.Open(path)
+	if err != nil {
+		r.logger.V(5).Error(err, "Unable to read file")
+		return "", err
+	}
+	defer readFile.Close()
+
+	scanner := bufio.NewScanner(readFile)
+	lineNumber := 0
+	codeSnip := ""
+	paddingSize := len(strconv.Itoa(location.EndPosition.Line + r.contextLines))
+	for scanner.Scan() {
+		if (lineNumber - r.contextLines) == location.EndPosition.Line {
+			codeSnip = codeSnip + fmt.Sprintf("%*d  %v", paddingSize, lineNumber+1, scanner.Text())
+			break
+		}
+		if (lineNumber + r.contextLines) >= location.StartPosition.Line {
+			codeSnip = codeSnip + fmt.Sprintf("%*d  %v\n", paddingSize, lineNumber+1, scanner.Text())
+		}
+		lineNumber += 1
+	}
+	return codeSnip, nil
+}
+
 func (r *ruleEngine) createPerformString(messageTemplate string, ctx map[string]interface{}) (string, error) {
 	return mustache.Render(messageTemplate, ctx)
 }
@@ -3,12 +3,17 @@ package engine
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/sirupsen/logrus"
 )
 
@@ -744,3 +749,306 @@ func Test_parseTagsFromPerformString(t *testing.T) {
 		})
 	}
 }
+
+func TestRuleEngineStructuredTags(t *testing.T) {
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			{
+				RuleMeta: RuleMeta{RuleID: "string-tags"},
+				Perform:  Perform{Tag: []string{"Language=Java", "Spring"}},
+				When:     testIncidentsConditional{incidents: []IncidentContext{{FileURI: "file:///pom.xml"}}},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "structured-tags"},
+				Perform: Perform{StructuredTags: []konveyor.Tag{
+					{Category: "Framework", Value: "Spring Boot {{version}}"},
+					{Value: "Java"},
+				}},
+				When: testIncidentsConditional{incidents: []IncidentContext{
+					{FileURI: "file:///a/pom.xml", Variables: map[string]interface{}{"version": "2.7"}},
+					{FileURI: "file:///b/pom.xml", Variables: map[string]interface{}{"version": "2.7"}},
+					{FileURI: "file:///c/pom.xml", Variables: map[string]interface{}{"version": "3.1"}},
+				}},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "has-tags"},
+				Perform:  Perform{Tag: []string{"Checked"}},
+				When:     testTagsConditional{tags: []string{"Spring Boot 2.7", "Java", "Spring"}},
+			},
+		},
+	}}
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard())
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets)
+	if len(results) != 1 {
+		t.Fatalf("expected one ruleset, got %d", len(results))
+	}
+	wantTags := []string{"Checked", "Framework=Spring Boot 2.7", "Framework=Spring Boot 3.1", "Java", "Language=Java", "Spring"}
+	tags := append([]string{}, results[0].Tags...)
+	sort.Strings(tags)
+	if !reflect.DeepEqual(tags, wantTags) {
+		t.Errorf("expected tags %v, got %v", wantTags, tags)
+	}
+	wantStructured := map[konveyor.Tag]bool{
+		{Category: "Language", Value: "Java"}:             true,
+		{Value: "Spring"}:                                 true,
+		{Category: "Framework", Value: "Spring Boot 2.7"}: true,
+		{Category: "Framework", Value: "Spring Boot 3.1"}: true,
+		{Value: "Java"}:                                   true,
+		{Value: "Checked"}:                                true,
+	}
+	if len(results[0].StructuredTags) != len(wantStructured) {
+		t.Errorf("expected structured tags %v, got %v", wantStructured, results[0].StructuredTags)
+	}
+	for _, tag := range results[0].StructuredTags {
+		if !wantStructured[tag] {
+			t.Errorf("unexpected structured tag %v", tag)
+		}
+	}
+}
+
+// testTagsConditional matches when the tags were generated
+type testTagsConditional struct {
+	tags []string
+}
+
+func (t testTagsConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	for _, tag := range t.tags {
+		if _, ok := condCtx.Tags[tag]; !ok {
+			return ConditionResponse{}, nil
+		}
+	}
+	return ConditionResponse{Matched: true}, nil
+}
+
+type testCountingConditional struct {
+	calls *int32
+}
+
+func (t testCountingConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	atomic.AddInt32(t.calls, 1)
+	return ConditionResponse{
+		Matched:   true,
+		Incidents: []IncidentContext{{FileURI: "file:///test.java"}},
+	}, nil
+}
+
+func (t testCountingConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEngineResume(t *testing.T) {
+	var calls int32
+	message := "found"
+	ruleSets := []RuleSet{
+		{
+			Name: "test",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "tagging"},
+					Perform:  Perform{Tag: []string{"Tagged"}},
+					When:     testCountingConditional{calls: &calls},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "matched"},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     testCountingConditional{calls: &calls},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "unmatched"},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     createTestConditional(false, nil, false),
+				},
+			},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	log := logrusr.New(logrus.New())
+
+	run := func(resume bool) konveyor.RuleSet {
+		ruleEngine := CreateRuleEngine(context.Background(), 2, log, WithCheckpoint(path, time.Hour), WithResume(resume))
+		defer ruleEngine.Stop()
+		results := ruleEngine.RunRules(context.Background(), ruleSets)
+		if len(results) != 1 {
+			t.Fatalf("expected one ruleset, got %d", len(results))
+		}
+		return results[0]
+	}
+
+	first := run(false)
+	if calls != 2 {
+		t.Fatalf("expected two evaluations, got %d", calls)
+	}
+	resumed := run(true)
+	if calls != 2 {
+		t.Errorf("expected the evaluated rules to be skipped when resuming, got %d evaluations", calls)
+	}
+	if !reflect.DeepEqual(first.Tags, resumed.Tags) {
+		t.Errorf("expected tags %v, got %v", first.Tags, resumed.Tags)
+	}
+	if !reflect.DeepEqual(first.StructuredTags, resumed.StructuredTags) {
+		t.Errorf("expected structured tags %v, got %v", first.StructuredTags, resumed.StructuredTags)
+	}
+	if !reflect.DeepEqual(first.Unmatched, resumed.Unmatched) {
+		t.Errorf("expected unmatched %v, got %v", first.Unmatched, resumed.Unmatched)
+	}
+	if _, ok := resumed.Violations["matched"]; !ok {
+		t.Errorf("expected the violation to be restored from the checkpoint")
+	}
+}
+
+// testBlockingConditional waits until its context is canceled
+type testBlockingConditional struct {
+	started  chan struct{}
+	canceled *int32
+}
+
+func (t testBlockingConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	t.started <- struct{}{}
+	<-ctx.Done()
+	atomic.AddInt32(t.canceled, 1)
+	return ConditionResponse{}, ctx.Err()
+}
+
+func (t testBlockingConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEngineCancel(t *testing.T) {
+	var canceled int32
+	started := make(chan struct{}, 10)
+	message := "found"
+	ruleSet := RuleSet{Name: "test"}
+	for i := 0; i < 5; i++ {
+		ruleSet.Rules = append(ruleSet.Rules, Rule{
+			RuleMeta: RuleMeta{RuleID: fmt.Sprintf("blocking-%d", i)},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testBlockingConditional{started: started, canceled: &canceled},
+		})
+	}
+	goroutines := runtime.NumGoroutine()
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ruleEngine.RunRules(ctx, []RuleSet{ruleSet})
+	}()
+	// the two workers evaluate a rule each
+	<-started
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not stopped by its context")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ruleEngine.Stop()
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the workers were left waiting after the run was canceled")
+	}
+	if canceled != 2 {
+		t.Errorf("expected the two rules being evaluated to be canceled, got %d", canceled)
+	}
+	if len(started) != 0 {
+		t.Errorf("expected no rule to be scheduled once the run is canceled, got %d", len(started))
+	}
+	// the goroutines waiting for the rules of the canceled run return
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the goroutines of the run to return, %d are left", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testGatedConditional waits for the gate to be opened
+type testGatedConditional struct {
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (t testGatedConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	t.started <- struct{}{}
+	<-t.gate
+	return ConditionResponse{}, nil
+}
+
+func (t testGatedConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEnginePause(t *testing.T) {
+	started := make(chan struct{}, 10)
+	gate := make(chan struct{})
+	message := "found"
+	ruleSet := RuleSet{Name: "test"}
+	for i := 0; i < 5; i++ {
+		ruleSet.Rules = append(ruleSet.Rules, Rule{
+			RuleMeta: RuleMeta{RuleID: fmt.Sprintf("gated-%d", i)},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testGatedConditional{started: started, gate: gate},
+		})
+	}
+	pause := NewPause()
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithPause(pause))
+	defer ruleEngine.Stop()
+
+	done := make(chan []konveyor.RuleSet)
+	go func() {
+		done <- ruleEngine.RunRules(context.Background(), []RuleSet{ruleSet})
+	}()
+	// the two workers evaluate a rule each when the run is paused
+	<-started
+	<-started
+	if !pause.Pause() {
+		t.Fatal("expected the run to be paused")
+	}
+	if pause.Pause() {
+		t.Error("expected the run to be paused only once")
+	}
+	idle := make(chan error)
+	go func() { idle <- pause.Idle(context.Background()) }()
+	select {
+	case err := <-idle:
+		t.Fatalf("expected the run to be busy with its two rules, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// the rules being evaluated complete
+	close(gate)
+	select {
+	case err := <-idle:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not idle once its rules were evaluated")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(started) != 0 {
+		t.Fatalf("expected no rule to be started while paused, got %d", len(started))
+	}
+
+	if !pause.Resume() {
+		t.Fatal("expected the run to be resumed")
+	}
+	select {
+	case rulesets := <-done:
+		if len(rulesets) != 1 || len(rulesets[0].Unmatched) != 5 {
+			t.Errorf("expected the 5 rules to be evaluated, got %v", rulesets)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not resumed")
+	}
+	if err := pause.Idle(context.Background()); err == nil {
+		t.Error("expected a run that is not paused not to be idle")
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// RuleFilter selects the rules to evaluate by their category, effort and
+// labels before they are scheduled, so that the providers are not queried
+// for the rules that would be dropped. The rules it does not select are
+// listed as skipped, as the ones the selectors do not select.
+type RuleFilter struct {
+	// Categories are the categories of the rules to evaluate, all of them
+	// when empty
+	Categories []konveyor.Category `yaml:"categories,omitempty" json:"categories,omitempty"`
+	// MinEffort and MaxEffort bound the effort of the rules to evaluate, the
+	// rules without an effort have an effort of 0
+	MinEffort *int `yaml:"minEffort,omitempty" json:"minEffort,omitempty"`
+	MaxEffort *int `yaml:"maxEffort,omitempty" json:"maxEffort,omitempty"`
+	// Labels are the labels the rules to evaluate all have, such as
+	// konveyor.io/source=java-ee, a label without a value matches the rules
+	// that have its key with any value
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// WithRuleFilter only evaluates the rules the filter selects, on top of the
+// selectors given to RunRules and the targets
+func WithRuleFilter(filter RuleFilter) Option {
+	return func(engine *ruleEngine) {
+		engine.ruleFilter = filter
+	}
+}
+
+func (f RuleFilter) Validate() error {
+	for _, c := range f.Categories {
+		switch c {
+		case konveyor.Mandatory, konveyor.Optional, konveyor.Potential:
+		default:
+			return fmt.Errorf("invalid category %s, it must be one of mandatory, optional or potential", c)
+		}
+	}
+	if f.MinEffort != nil && f.MaxEffort != nil && *f.MinEffort > *f.MaxEffort {
+		return fmt.Errorf("the min effort %d is greater than the max effort %d", *f.MinEffort, *f.MaxEffort)
+	}
+	for _, l := range f.Labels {
+		if key, _, _ := strings.Cut(l, "="); key == "" || strings.Count(l, "=") > 1 {
+			return fmt.Errorf("invalid label %q, it must be key=value or key", l)
+		}
+	}
+	return nil
+}
+
+// skipReason returns why the filter does not select a rule, empty when it
+// selects it
+func (f RuleFilter) skipReason(m RuleMeta) string {
+	if len(f.Categories) > 0 {
+		selected := false
+		for _, c := range f.Categories {
+			if m.Category != nil && *m.Category == c {
+				selected = true
+			}
+		}
+		if !selected {
+			category := "no category"
+			if m.Category != nil {
+				category = fmt.Sprintf("the category %s", *m.Category)
+			}
+			return fmt.Sprintf("it has %s, not one of %s", category, joinCategories(f.Categories))
+		}
+	}
+	effort := 0
+	if m.Effort != nil {
+		effort = *m.Effort
+	}
+	if f.MinEffort != nil && effort < *f.MinEffort {
+		return fmt.Sprintf("its effort %d is lower than %d", effort, *f.MinEffort)
+	}
+	if f.MaxEffort != nil && effort > *f.MaxEffort {
+		return fmt.Sprintf("its effort %d is greater than %d", effort, *f.MaxEffort)
+	}
+	for _, l := range f.Labels {
+		if !hasLabel(m.Labels, l) {
+			return fmt.Sprintf("it does not have the label %s", l)
+		}
+	}
+	return ""
+}
+
+// hasLabel returns whether the labels have a label, or have its key with any
+// value when it has no value
+func hasLabel(ruleLabels []string, label string) bool {
+	for _, l := range ruleLabels {
+		if l == label || (!strings.Contains(label, "=") && strings.HasPrefix(l, label+"=")) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinCategories(categories []konveyor.Category) string {
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, string(c))
+	}
+	return strings.Join(names, ", ")
+}
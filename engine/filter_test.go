@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestRuleEngineRuleFilter(t *testing.T) {
+	effort := func(e int) *int { return &e }
+	message := "found"
+	mutex := sync.Mutex{}
+	events := []string{}
+	rule := func(id string, category *konveyor.Category, e *int, labels ...string) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id, Category: category, Effort: e, Labels: labels},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testRecordingConditional{id: id, mutex: &mutex, events: &events},
+		}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			rule("mandatory-5", &konveyor.Mandatory, effort(5), "konveyor.io/source=java-ee"),
+			rule("mandatory-1", &konveyor.Mandatory, effort(1), "konveyor.io/source=java-ee"),
+			rule("optional-5", &konveyor.Optional, effort(5), "konveyor.io/source=java-ee"),
+			rule("mandatory-no-effort", &konveyor.Mandatory, nil, "konveyor.io/source=spring"),
+			rule("mandatory-no-label", &konveyor.Mandatory, effort(5)),
+		},
+	}}
+	tests := []struct {
+		name      string
+		filter    RuleFilter
+		evaluated []string
+	}{
+		{
+			name:      "no filter",
+			evaluated: []string{"mandatory-1", "mandatory-5", "mandatory-no-effort", "mandatory-no-label", "optional-5"},
+		},
+		{
+			name:      "category",
+			filter:    RuleFilter{Categories: []konveyor.Category{konveyor.Optional}},
+			evaluated: []string{"optional-5"},
+		},
+		{
+			name:      "effort",
+			filter:    RuleFilter{MinEffort: effort(2), MaxEffort: effort(5)},
+			evaluated: []string{"mandatory-5", "mandatory-no-label", "optional-5"},
+		},
+		{
+			name:      "no effort",
+			filter:    RuleFilter{MaxEffort: effort(0)},
+			evaluated: []string{"mandatory-no-effort"},
+		},
+		{
+			name:      "label",
+			filter:    RuleFilter{Labels: []string{"konveyor.io/source=java-ee"}},
+			evaluated: []string{"mandatory-1", "mandatory-5", "optional-5"},
+		},
+		{
+			name:      "label without a value",
+			filter:    RuleFilter{Categories: []konveyor.Category{konveyor.Mandatory}, Labels: []string{"konveyor.io/source"}},
+			evaluated: []string{"mandatory-1", "mandatory-5", "mandatory-no-effort"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = []string{}
+			ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithRuleFilter(tt.filter))
+			defer ruleEngine.Stop()
+			results := ruleEngine.RunRules(context.Background(), ruleSets)
+			if len(results) != 1 {
+				t.Fatalf("expected one ruleset, got %+v", results)
+			}
+			evaluated := []string{}
+			for _, e := range events {
+				if strings.HasPrefix(e, "start ") {
+					evaluated = append(evaluated, strings.TrimPrefix(e, "start "))
+				}
+			}
+			sort.Strings(evaluated)
+			if !reflect.DeepEqual(evaluated, tt.evaluated) {
+				t.Errorf("expected the rules %v to be evaluated, got %v", tt.evaluated, evaluated)
+			}
+			if len(results[0].Skipped)+len(evaluated) != len(ruleSets[0].Rules) {
+				t.Errorf("expected the other rules to be skipped, got %v", results[0].Skipped)
+			}
+		})
+	}
+}
+
+func TestRuleFilterValidate(t *testing.T) {
+	one, two := 1, 2
+	for _, f := range []RuleFilter{
+		{Categories: []konveyor.Category{"severe"}},
+		{MinEffort: &two, MaxEffort: &one},
+		{Labels: []string{"konveyor.io/source=java=ee"}},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", f)
+		}
+	}
+	if err := (RuleFilter{Categories: []konveyor.Category{konveyor.Mandatory}, MinEffort: &one, MaxEffort: &one}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/PaesslerAG/gval"
 	"github.com/hashicorp/go-version"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 )
 
 const (
@@ -23,8 +24,55 @@ const (
 	exprSpecialSymbols = `!|\|\||&&|\(|\)`
 	// used to split string into groups of special symbols and everything else
 	exprSplitter = `(` + exprSpecialSymbols + `|[^!` + exprSpecialSymbols + `]+)`
+	// a label key in a set based expression, can contain a glob
+	exprKey = `[^\s!&|()=,]+`
 )
 
+var (
+	// matches "key in (val1, val2)" and "key notin (val1, val2)"
+	setExprRegex = regexp.MustCompile(`(` + exprKey + `)\s+(in|notin)\s+\(([^()]*)\)`)
+	// matches "key != val"
+	notEqualExprRegex = regexp.MustCompile(`(` + exprKey + `)\s*!=\s*([^!&|()]+)`)
+)
+
+// Operator is a set based operator used in a Requirement
+type Operator string
+
+const (
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+)
+
+// Requirement is a single label requirement, similar to a Kubernetes
+// label selector requirement. Keys can contain a * glob.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// String returns the requirement as a selector expression
+func (r Requirement) String() string {
+	switch r.Operator {
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	case DoesNotExist:
+		return fmt.Sprintf("!%s", r.Key)
+	case Equals, NotEquals:
+		value := ""
+		if len(r.Values) > 0 {
+			value = r.Values[0]
+		}
+		return fmt.Sprintf("%s%s%s", r.Key, r.Operator, value)
+	default:
+		return r.Key
+	}
+}
+
 type LabelSelector[T Labeled] struct {
 	expr     string
 	language gval.Language
@@ -61,6 +109,11 @@ func (l *LabelSelector[T]) Matches(v T) (bool, error) {
 	}
 }
 
+// String returns the expression of the selector
+func (l *LabelSelector[T]) String() string {
+	return l.expr
+}
+
 func (l *LabelSelector[T]) MatchList(list []T) ([]T, error) {
 	newList := []T{}
 	for _, v := range list {
@@ -84,6 +137,8 @@ type Labeled interface {
 // it enables using string expressions to form complex label queries
 // supports "&&", "||" and "!" operators, "(" ")" for grouping, operands
 // are string labels in key=val format, keys can be subdomain prefixed
+// and contain * globs. Set based operands "key in (v1,v2)", "key notin (v1,v2)"
+// and "key!=val" are also supported.
 func NewLabelSelector[T Labeled](expr string) (*LabelSelector[T], error) {
 	language := gval.NewLanguage(
 		gval.Ident(),
@@ -113,6 +168,51 @@ func NewLabelSelector[T Labeled](expr string) (*LabelSelector[T], error) {
 	}, nil
 }
 
+// NewTargetSelector returns a selector of the rules of a migration target,
+// the ones labeled konveyor.io/target=<target>. A versioned target such as
+// eap8 also selects the rules labeled with a range that has the version, such
+// as eap7+.
+func NewTargetSelector[T Labeled](target string) (*LabelSelector[T], error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("the target has no name")
+	}
+	selector, err := NewLabelSelector[T](AsString(konveyor.TargetTechnologyLabel, target))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %s: %w", target, err)
+	}
+	return selector, nil
+}
+
+// NewLabelSelectorFromRequirements returns a new selector that matches when all
+// of the given requirements are met.
+func NewLabelSelectorFromRequirements[T Labeled](requirements ...Requirement) (*LabelSelector[T], error) {
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("at least one requirement is needed")
+	}
+	exprs := []string{}
+	for _, r := range requirements {
+		switch r.Operator {
+		case In, NotIn:
+			if len(r.Values) == 0 {
+				return nil, fmt.Errorf("operator %s requires values for key %s", r.Operator, r.Key)
+			}
+		case Equals, NotEquals:
+			if len(r.Values) != 1 {
+				return nil, fmt.Errorf("operator %s requires a single value for key %s", r.Operator, r.Key)
+			}
+		case Exists, DoesNotExist:
+			if len(r.Values) != 0 {
+				return nil, fmt.Errorf("operator %s does not take values for key %s", r.Operator, r.Key)
+			}
+		default:
+			return nil, fmt.Errorf("invalid operator %s for key %s", r.Operator, r.Key)
+		}
+		exprs = append(exprs, fmt.Sprintf("(%s)", r.String()))
+	}
+	return NewLabelSelector[T](strings.Join(exprs, " && "))
+}
+
 // ParseLabels given a list of string labels, returns them as key=[val] map
 // a list of values is needed because keys can be duplicate
 func ParseLabels(labels []string) (map[string][]string, error) {
@@ -189,7 +289,8 @@ func getLabelsFromExpression(expr string) (map[string][]string, error) {
 	labelsList := []string{}
 	for _, token := range tokenize(expr) {
 		if token == "" ||
-			regexp.MustCompile(exprSpecialSymbols).MatchString(token) {
+			regexp.MustCompile(exprSpecialSymbols).MatchString(token) ||
+			isGlob(token) || token == "true" || token == "false" {
 			continue
 		}
 		labelsList = append(labelsList, token)
@@ -208,6 +309,7 @@ func getLabelsFromExpression(expr string) (map[string][]string, error) {
 // something like "true && false" as a boolean expression depending on passed labels
 // we wouldn't need this if gval supported writing custom operands
 func getBooleanExpression(expr string, compareLabels map[string][]string) string {
+	expr = replaceSetExpressions(expr, compareLabels)
 	exprLabels, err := getLabelsFromExpression(expr)
 	if err != nil {
 		return expr
@@ -232,6 +334,8 @@ func getBooleanExpression(expr string, compareLabels map[string][]string) string
 	for _, token := range tokenize(expr) {
 		if val, ok := replaceMap[token]; ok {
 			boolExpr = fmt.Sprintf("%s %s", boolExpr, val)
+		} else if isGlob(token) {
+			boolExpr = fmt.Sprintf("%s %t", boolExpr, matchesGlobLabel(token, compareLabels))
 		} else {
 			boolExpr = fmt.Sprintf("%s %s", boolExpr, token)
 		}
@@ -240,6 +344,77 @@ func getBooleanExpression(expr string, compareLabels map[string][]string) string
 	return boolExpr
 }
 
+// replaceSetExpressions evaluates set based expressions ("in", "notin" and "!=")
+// and replaces them with their results, as the tokenizer does not understand them.
+// Like in Kubernetes, "notin" and "!=" match when the key is not present.
+func replaceSetExpressions(expr string, compareLabels map[string][]string) string {
+	expr = setExprRegex.ReplaceAllStringFunc(expr, func(s string) string {
+		match := setExprRegex.FindStringSubmatch(s)
+		key, op, values := match[1], Operator(match[2]), []string{}
+		for _, v := range strings.Split(match[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		found := false
+		for _, v := range values {
+			if matchesKeyValue(key, v, compareLabels) {
+				found = true
+				break
+			}
+		}
+		if op == NotIn {
+			found = !found
+		}
+		return fmt.Sprintf(" %t ", found)
+	})
+	return notEqualExprRegex.ReplaceAllStringFunc(expr, func(s string) string {
+		match := notEqualExprRegex.FindStringSubmatch(s)
+		return fmt.Sprintf(" %t ", !matchesKeyValue(match[1], strings.TrimSpace(match[2]), compareLabels))
+	})
+}
+
+func isGlob(token string) bool {
+	key, _, _ := strings.Cut(token, "=")
+	return strings.Contains(key, "*")
+}
+
+// keysMatching returns the values of all the labels whose keys match the given
+// key, which can contain * globs.
+func keysMatching(key string, compareLabels map[string][]string) [][]string {
+	if !strings.Contains(key, "*") {
+		if vals, ok := compareLabels[key]; ok {
+			return [][]string{vals}
+		}
+		return nil
+	}
+	keyRegex, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(key), `\*`, ".*") + "$")
+	if err != nil {
+		return nil
+	}
+	matched := [][]string{}
+	for k, vals := range compareLabels {
+		if keyRegex.MatchString(k) {
+			matched = append(matched, vals)
+		}
+	}
+	return matched
+}
+
+func matchesKeyValue(key, value string, compareLabels map[string][]string) bool {
+	for _, vals := range keysMatching(key, compareLabels) {
+		if value == "" || matchesAny(value, vals) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesGlobLabel(token string, compareLabels map[string][]string) bool {
+	key, value, _ := strings.Cut(token, "=")
+	return matchesKeyValue(strings.TrimSpace(key), strings.TrimSpace(value), compareLabels)
+}
+
 func tokenize(expr string) []string {
 	tokens := []string{}
 	for _, token := range regexp.MustCompile(exprSplitter).FindAllString(expr, -1) {
@@ -378,6 +378,67 @@ func Test_ruleSelector_Matches(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "in set operator, match",
+			expr: "konveyor.io/target in (eap7, quarkus)",
+			ruleLabels: []string{
+				"konveyor.io/target=quarkus",
+			},
+			want: true,
+		},
+		{
+			name: "in set operator with versions, no match",
+			expr: "konveyor.io/target in (eap7+, quarkus) && konveyor.io/source",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=eap6",
+			},
+			want: false,
+		},
+		{
+			name: "notin set operator, key not present",
+			expr: "konveyor.io/target notin (eap7, quarkus)",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+			},
+			want: true,
+		},
+		{
+			name: "notin set operator combined with !, no match",
+			expr: "konveyor.io/source=eap && !(konveyor.io/target notin (eap7, quarkus))",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=eap6",
+			},
+			want: false,
+		},
+		{
+			name: "not equals operator",
+			expr: "konveyor.io/target!=eap7 && konveyor.io/source",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=eap6",
+			},
+			want: true,
+		},
+		{
+			name: "key prefix glob exists",
+			expr: "konveyor.io/sou* && !konveyor.io/tar*=eap7",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=quarkus",
+			},
+			want: true,
+		},
+		{
+			name: "key prefix glob with set operator, no match",
+			expr: "konveyor.io/* in (eap8)",
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=quarkus",
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -489,3 +550,76 @@ func Test_labelValueMatches(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLabelSelectorFromRequirements(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements []Requirement
+		ruleLabels   []string
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name: "all requirements met",
+			requirements: []Requirement{
+				{Key: "konveyor.io/source", Operator: Exists},
+				{Key: "konveyor.io/target", Operator: In, Values: []string{"eap7", "quarkus"}},
+				{Key: "konveyor.io/type", Operator: DoesNotExist},
+			},
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=quarkus",
+			},
+			want: true,
+		},
+		{
+			name: "one requirement not met",
+			requirements: []Requirement{
+				{Key: "konveyor.io/source", Operator: Equals, Values: []string{"eap"}},
+				{Key: "konveyor.io/target", Operator: NotIn, Values: []string{"quarkus"}},
+			},
+			ruleLabels: []string{
+				"konveyor.io/source=eap",
+				"konveyor.io/target=quarkus",
+			},
+			want: false,
+		},
+		{
+			name: "in without values is invalid",
+			requirements: []Requirement{
+				{Key: "konveyor.io/target", Operator: In},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewLabelSelectorFromRequirements[*engine.RuleMeta](tt.requirements...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLabelSelectorFromRequirements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, _ := s.Matches(&engine.RuleMeta{Labels: tt.ruleLabels}); got != tt.want {
+				t.Errorf("LabelSelector.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTargetSelector(t *testing.T) {
+	if _, err := NewTargetSelector[*engine.RuleMeta](" "); err == nil {
+		t.Errorf("expected a target without name to fail")
+	}
+	s, err := NewTargetSelector[*engine.RuleMeta]("eap8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Matches(&engine.RuleMeta{Labels: []string{"konveyor.io/target=eap7+"}}); !got {
+		t.Errorf("expected eap8 to select the rules of eap7+")
+	}
+	if got, _ := s.Matches(&engine.RuleMeta{Labels: []string{"konveyor.io/target=quarkus"}}); got {
+		t.Errorf("expected eap8 not to select the rules of quarkus")
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const analyzerModule = "github.com/konveyor/analyzer-lsp"
+
+// NewManifest starts the manifest of an analysis of the rulesets that starts
+// now, with the version of the analyzer, the digests of the rulesets and the
+// host. The caller records the settings of the analysis the engine does not
+// know, such as the providers and the label selector, and sets the end time
+// when the analysis is done.
+func NewManifest(ruleSets []RuleSet) konveyor.Manifest {
+	manifest := konveyor.Manifest{
+		AnalyzerVersion: analyzerVersion(),
+		StartTime:       time.Now().UTC(),
+		RuleSets:        []konveyor.ManifestRuleSet{},
+		Host: konveyor.Host{
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			GoVersion: runtime.Version(),
+		},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		manifest.Host.Hostname = hostname
+	}
+	for _, rs := range ruleSets {
+		manifest.RuleSets = append(manifest.RuleSets, konveyor.ManifestRuleSet{
+			Name:   rs.Name,
+			Rules:  len(rs.Rules),
+			Digest: rs.Digest,
+		})
+	}
+	return manifest
+}
+
+// analyzerVersion returns the version of the analyzer module the binary was
+// built with, either as its main module or as a dependency of an embedder
+func analyzerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path != analyzerModule {
+		for _, dep := range info.Deps {
+			if dep.Path == analyzerModule {
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					return dep.Version + " => " + dep.Replace.Path + "@" + dep.Replace.Version
+				}
+				if dep.Replace != nil {
+					return dep.Version + " => " + dep.Replace.Path
+				}
+				return dep.Version
+			}
+		}
+		return "unknown"
+	}
+	version := info.Main.Version
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" {
+		version += " (" + revision
+		if modified {
+			version += ", modified"
+		}
+		version += ")"
+	}
+	return version
+}
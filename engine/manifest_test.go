@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestNewManifest(t *testing.T) {
+	before := time.Now().UTC()
+	manifest := NewManifest([]RuleSet{
+		{Name: "ruleset-a", Rules: []Rule{{}, {}}, Digest: "sha256:ab"},
+		{Name: "inline"},
+	})
+	if manifest.AnalyzerVersion == "" {
+		t.Errorf("expected the version of the analyzer")
+	}
+	if manifest.StartTime.Before(before) || !manifest.EndTime.IsZero() {
+		t.Errorf("expected the analysis to start now and not to be done, got %v and %v", manifest.StartTime, manifest.EndTime)
+	}
+	if len(manifest.RuleSets) != 2 || manifest.RuleSets[0].Rules != 2 || manifest.RuleSets[0].Digest != "sha256:ab" || manifest.RuleSets[1].Digest != "" {
+		t.Errorf("unexpected rulesets %+v", manifest.RuleSets)
+	}
+	if manifest.Host.OS != runtime.GOOS || manifest.Host.GoVersion != runtime.Version() {
+		t.Errorf("unexpected host %+v", manifest.Host)
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/redact"
+)
+
+// RuleMiddleware runs around the evaluation of each rule, so that embedders
+// can change how the rules are evaluated, such as to skip rules based on an
+// external policy or to add metadata to the incidents.
+type RuleMiddleware interface {
+	// BeforeRule is called before the conditions of the rule are evaluated.
+	// It can change the rule, or set Skip to not evaluate it. An error fails
+	// the rule without evaluating it.
+	BeforeRule(ctx context.Context, eval *RuleEvaluation) error
+	// AfterRule is called once the conditions of the rule are evaluated,
+	// before the result is recorded. It can change the rule, the response
+	// and the error, the violation is created from what they are after the
+	// last middleware.
+	AfterRule(ctx context.Context, eval *RuleEvaluation) error
+}
+
+// RuleEvaluation is the rule that the middlewares are called for and the
+// result of its evaluation.
+type RuleEvaluation struct {
+	RuleSetName string
+	Rule        Rule
+	// Context is the context the conditions are evaluated with, such as the
+	// tags of the tagging rules
+	Context ConditionContext
+	// Skip skips the rule when it is set by BeforeRule, the rule is reported
+	// as skipped
+	Skip bool
+	// Response and Err are the result of the evaluation, they are only set
+	// for AfterRule
+	Response ConditionResponse
+	Err      error
+}
+
+// WithRuleMiddleware adds a middleware that is called around the evaluation
+// of each rule, it can be given multiple times. BeforeRule is called in the
+// order the middlewares are given and AfterRule in the reverse order.
+func WithRuleMiddleware(m RuleMiddleware) Option {
+	return func(engine *ruleEngine) {
+		engine.middlewares = append(engine.middlewares, m)
+	}
+}
+
+// beforeRule calls the middlewares before the rule is evaluated, it stops at
+// the first one that skips the rule or fails.
+func (r *ruleEngine) beforeRule(ctx context.Context, ruleSetName string, rule Rule, ruleCtx ConditionContext) *RuleEvaluation {
+	eval := &RuleEvaluation{
+		RuleSetName: ruleSetName,
+		Rule:        rule,
+		Context:     ruleCtx,
+	}
+	for _, m := range r.middlewares {
+		if err := m.BeforeRule(ctx, eval); err != nil {
+			eval.Err = err
+			return eval
+		}
+		if eval.Skip {
+			return eval
+		}
+	}
+	return eval
+}
+
+// recordBeforeRule records the rules that the middlewares skipped or failed
+// in their ruleset, it returns whether the rule is evaluated.
+func recordBeforeRule(eval *RuleEvaluation, mapRuleSets map[string]*konveyor.RuleSet) bool {
+	if eval.Err == nil && !eval.Skip {
+		return true
+	}
+	rs, ok := mapRuleSets[eval.RuleSetName]
+	if !ok {
+		return false
+	}
+	if eval.Err != nil {
+		rs.Errors[eval.Rule.RuleID] = redact.String(eval.Err.Error())
+	} else {
+		rs.Skipped = append(rs.Skipped, eval.Rule.RuleID)
+	}
+	return false
+}
+
+// afterRule calls the middlewares with the result of the evaluation, in the
+// reverse order, and returns the result they changed.
+func (r *ruleEngine) afterRule(ctx context.Context, ruleSetName string, rule Rule, ruleCtx ConditionContext, response ConditionResponse, err error) (Rule, ConditionResponse, error) {
+	if len(r.middlewares) == 0 {
+		return rule, response, err
+	}
+	eval := &RuleEvaluation{
+		RuleSetName: ruleSetName,
+		Rule:        rule,
+		Context:     ruleCtx,
+		Response:    response,
+		Err:         err,
+	}
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		if err := r.middlewares[i].AfterRule(ctx, eval); err != nil {
+			eval.Err = err
+		}
+	}
+	return eval.Rule, eval.Response, eval.Err
+}
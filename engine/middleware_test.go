@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/redact"
+)
+
+// testPolicyMiddleware skips and denies rules by ID and adds the owner to the
+// incidents of the matched rules
+type testPolicyMiddleware struct {
+	skip  string
+	deny  string
+	drop  string
+	owner string
+}
+
+func (m testPolicyMiddleware) BeforeRule(ctx context.Context, eval *RuleEvaluation) error {
+	switch eval.Rule.RuleID {
+	case m.skip:
+		eval.Skip = true
+	case m.deny:
+		return fmt.Errorf("denied by policy")
+	}
+	return nil
+}
+
+func (m testPolicyMiddleware) AfterRule(ctx context.Context, eval *RuleEvaluation) error {
+	if eval.Rule.RuleID == m.drop {
+		eval.Response = ConditionResponse{}
+		return nil
+	}
+	for i := range eval.Response.Incidents {
+		if eval.Response.Incidents[i].Variables == nil {
+			eval.Response.Incidents[i].Variables = map[string]interface{}{}
+		}
+		eval.Response.Incidents[i].Variables["owner"] = m.owner
+	}
+	effort := 5
+	eval.Rule.Effort = &effort
+	return nil
+}
+
+// testOrderMiddleware records the order the middlewares are called in
+type testOrderMiddleware struct {
+	name  string
+	mutex *sync.Mutex
+	calls *[]string
+}
+
+func (m testOrderMiddleware) record(call string, eval *RuleEvaluation) {
+	if eval.Rule.RuleID != "matched" {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	*m.calls = append(*m.calls, call+" "+m.name)
+}
+
+func (m testOrderMiddleware) BeforeRule(ctx context.Context, eval *RuleEvaluation) error {
+	m.record("before", eval)
+	return nil
+}
+
+func (m testOrderMiddleware) AfterRule(ctx context.Context, eval *RuleEvaluation) error {
+	m.record("after", eval)
+	return nil
+}
+
+func TestRuleMiddleware(t *testing.T) {
+	var calls int32
+	message := "found"
+	rule := func(id string) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testCountingConditional{calls: &calls},
+		}
+	}
+	ruleSets := []RuleSet{
+		{
+			Name: "test",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "skipped-tagging"},
+					Perform:  Perform{Tag: []string{"Skipped"}},
+					When:     testCountingConditional{calls: &calls},
+				},
+				rule("matched"),
+				rule("skipped"),
+				rule("denied"),
+				rule("dropped"),
+			},
+		},
+	}
+
+	order := []string{}
+	mutex := &sync.Mutex{}
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(),
+		WithRuleMiddleware(testOrderMiddleware{name: "first", mutex: mutex, calls: &order}),
+		WithRuleMiddleware(testPolicyMiddleware{skip: "skipped", deny: "denied", drop: "dropped", owner: "team-a"}),
+		WithRuleMiddleware(testPolicyMiddleware{skip: "skipped-tagging"}),
+		WithRuleMiddleware(testOrderMiddleware{name: "last", mutex: mutex, calls: &order}),
+	)
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets)
+	if len(results) != 1 {
+		t.Fatalf("expected one ruleset, got %d", len(results))
+	}
+	rs := results[0]
+
+	if calls != 2 {
+		t.Errorf("expected the matched and dropped rules to be evaluated, got %d evaluations", calls)
+	}
+	sort.Strings(rs.Skipped)
+	if want := []string{"skipped", "skipped-tagging"}; !reflect.DeepEqual(rs.Skipped, want) {
+		t.Errorf("expected skipped rules %v, got %v", want, rs.Skipped)
+	}
+	if len(rs.Tags) != 0 {
+		t.Errorf("expected no tags from the skipped tagging rule, got %v", rs.Tags)
+	}
+	if rs.Errors["denied"] != "denied by policy" {
+		t.Errorf("expected the denied rule to fail, got errors %v", rs.Errors)
+	}
+	if want := []string{"dropped"}; !reflect.DeepEqual(rs.Unmatched, want) {
+		t.Errorf("expected unmatched rules %v, got %v", want, rs.Unmatched)
+	}
+	violation, ok := rs.Violations["matched"]
+	if !ok || len(rs.Violations) != 1 {
+		t.Fatalf("expected only the matched rule to be a violation, got %v", rs.Violations)
+	}
+	if violation.Effort == nil || *violation.Effort != 5 {
+		t.Errorf("expected the effort set by the middleware, got %v", violation.Effort)
+	}
+	if len(violation.Incidents) != 1 || violation.Incidents[0].Variables["owner"] != "team-a" {
+		t.Errorf("expected the incidents enriched with the owner, got %+v", violation.Incidents)
+	}
+	if want := []string{"before first", "before last", "after last", "after first"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected the middlewares to be called in order %v, got %v", want, order)
+	}
+}
+
+func TestRecordBeforeRuleRedacts(t *testing.T) {
+	redact.Register("middleware-secret")
+	rs := &konveyor.RuleSet{Errors: map[string]string{}}
+	eval := &RuleEvaluation{
+		RuleSetName: "test",
+		Rule:        Rule{RuleMeta: RuleMeta{RuleID: "denied"}},
+		Err:         fmt.Errorf("login with middleware-secret failed"),
+	}
+	if recordBeforeRule(eval, map[string]*konveyor.RuleSet{"test": rs}) {
+		t.Errorf("expected the failed rule not to be evaluated")
+	}
+	if want := "login with " + redact.Placeholder + " failed"; rs.Errors["denied"] != want {
+		t.Errorf("expected the error %q, got %q", want, rs.Errors["denied"])
+	}
+}
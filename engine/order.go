@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// RuleOrder is the order the rules are evaluated in, after the tagging rules
+type RuleOrder string
+
+const (
+	// RuleOrderFile evaluates the rules in the order of the rulesets and of
+	// the rules in their files
+	RuleOrderFile RuleOrder = "file"
+	// RuleOrderCost evaluates the rules with the lowest estimated cost first,
+	// so that the most rules are evaluated when the analysis is stopped
+	RuleOrderCost RuleOrder = "cost"
+	// RuleOrderMandatoryFirst evaluates the mandatory rules, then the
+	// potential ones and then the optional ones, each category is done
+	// before the rules of the next one start
+	RuleOrderMandatoryFirst RuleOrder = "mandatory-first"
+)
+
+func (o RuleOrder) Validate() error {
+	switch o {
+	case "", RuleOrderFile, RuleOrderCost, RuleOrderMandatoryFirst:
+		return nil
+	}
+	return fmt.Errorf("unknown rule order %q, one of: %s, %s, %s", o, RuleOrderFile, RuleOrderCost, RuleOrderMandatoryFirst)
+}
+
+// WithRuleOrder sets the order the rules are evaluated in, they are evaluated
+// in file order by default
+func WithRuleOrder(order RuleOrder) Option {
+	return func(engine *ruleEngine) {
+		engine.ruleOrder = order
+	}
+}
+
+// CostEstimator is implemented by the conditions that can estimate how costly
+// they are to evaluate, the other ones cost one query to a provider.
+type CostEstimator interface {
+	EstimatedCost() int
+}
+
+// estimateCost estimates the cost of a condition by the number of queries it
+// sends to the providers
+func estimateCost(c Conditional) int {
+	switch c := c.(type) {
+	case CostEstimator:
+		return c.EstimatedCost()
+	case AndCondition:
+		return estimateEntriesCost(c.Conditions)
+	case OrCondition:
+		return estimateEntriesCost(c.Conditions)
+	case ConditionEntry:
+		return estimateCost(c.ProviderSpecificConfig)
+	case nil:
+		return 0
+	}
+	return 1
+}
+
+func estimateEntriesCost(entries []ConditionEntry) int {
+	cost := 0
+	for _, e := range entries {
+		cost += estimateCost(e.ProviderSpecificConfig)
+	}
+	return cost
+}
+
+// categoryPriority is the rank of the rules of a category in the
+// mandatory-first order, the rules without a category are last
+func categoryPriority(rule Rule) int {
+	if rule.Category == nil {
+		return 3
+	}
+	switch *rule.Category {
+	case konveyor.Mandatory:
+		return 0
+	case konveyor.Potential:
+		return 1
+	case konveyor.Optional:
+		return 2
+	}
+	return 3
+}
+
+// orderRules returns the batches of rules to evaluate, a batch is done before
+// the rules of the next one are dispatched
+func orderRules(rules []ruleMessage, order RuleOrder) [][]ruleMessage {
+	switch order {
+	case RuleOrderCost:
+		ordered := make([]ruleMessage, len(rules))
+		copy(ordered, rules)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return estimateCost(ordered[i].rule.When) < estimateCost(ordered[j].rule.When)
+		})
+		return [][]ruleMessage{ordered}
+	case RuleOrderMandatoryFirst:
+		batches := make([][]ruleMessage, 4)
+		for _, rule := range rules {
+			p := categoryPriority(rule.rule)
+			batches[p] = append(batches[p], rule)
+		}
+		ordered := [][]ruleMessage{}
+		for _, batch := range batches {
+			if len(batch) > 0 {
+				ordered = append(ordered, batch)
+			}
+		}
+		return ordered
+	}
+	return [][]ruleMessage{rules}
+}
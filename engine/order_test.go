@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testCostConditional struct {
+	cost int
+}
+
+func (t testCostConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{}, nil
+}
+
+func (t testCostConditional) EstimatedCost() int {
+	return t.cost
+}
+
+func TestOrderRules(t *testing.T) {
+	category := func(c konveyor.Category) *konveyor.Category { return &c }
+	entries := func(n int) []ConditionEntry {
+		e := []ConditionEntry{}
+		for i := 0; i < n; i++ {
+			e = append(e, ConditionEntry{ProviderSpecificConfig: testScopedConditional{}})
+		}
+		return e
+	}
+	rules := []ruleMessage{
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "optional-or", Category: category(konveyor.Optional)}, When: OrCondition{Conditions: entries(3)}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "uncategorized"}, When: testScopedConditional{}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "mandatory-and", Category: category(konveyor.Mandatory)}, When: AndCondition{Conditions: entries(2)}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "potential-costly", Category: category(konveyor.Potential)}, When: testCostConditional{cost: 10}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "mandatory", Category: category(konveyor.Mandatory)}, When: testScopedConditional{}}},
+	}
+	tests := []struct {
+		order RuleOrder
+		want  [][]string
+	}{
+		{
+			order: "",
+			want:  [][]string{{"optional-or", "uncategorized", "mandatory-and", "potential-costly", "mandatory"}},
+		},
+		{
+			order: RuleOrderFile,
+			want:  [][]string{{"optional-or", "uncategorized", "mandatory-and", "potential-costly", "mandatory"}},
+		},
+		{
+			order: RuleOrderCost,
+			want:  [][]string{{"uncategorized", "mandatory", "mandatory-and", "optional-or", "potential-costly"}},
+		},
+		{
+			order: RuleOrderMandatoryFirst,
+			want:  [][]string{{"mandatory-and", "mandatory"}, {"potential-costly"}, {"optional-or"}, {"uncategorized"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			got := [][]string{}
+			for _, batch := range orderRules(rules, tt.order) {
+				ids := []string{}
+				for _, r := range batch {
+					ids = append(ids, r.rule.RuleID)
+				}
+				got = append(got, ids)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected batches %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// testRecordingConditional records when the rules start and end
+type testRecordingConditional struct {
+	id     string
+	mutex  *sync.Mutex
+	events *[]string
+}
+
+func (t testRecordingConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	t.record("start " + t.id)
+	time.Sleep(10 * time.Millisecond)
+	t.record("end " + t.id)
+	return ConditionResponse{}, nil
+}
+
+func (t testRecordingConditional) record(event string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	*t.events = append(*t.events, event)
+}
+
+func TestRuleEngineMandatoryFirst(t *testing.T) {
+	mandatory, optional := konveyor.Mandatory, konveyor.Optional
+	message := "found"
+	mutex := &sync.Mutex{}
+	events := []string{}
+	rule := func(id string, category *konveyor.Category) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id, Category: category},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testRecordingConditional{id: id, mutex: mutex, events: &events},
+		}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			rule("optional-1", &optional),
+			rule("mandatory-1", &mandatory),
+			rule("optional-2", &optional),
+			rule("mandatory-2", &mandatory),
+		},
+	}}
+
+	ruleEngine := CreateRuleEngine(context.Background(), 4, logr.Discard(), WithRuleOrder(RuleOrderMandatoryFirst))
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets)
+	if len(results) != 1 || len(results[0].Unmatched) != 4 {
+		t.Fatalf("expected the 4 rules to be evaluated, got %+v", results)
+	}
+
+	lastMandatoryEnd, firstOptionalStart := -1, len(events)
+	for i, e := range events {
+		switch e {
+		case "end mandatory-1", "end mandatory-2":
+			if i > lastMandatoryEnd {
+				lastMandatoryEnd = i
+			}
+		case "start optional-1", "start optional-2":
+			if i < firstOptionalStart {
+				firstOptionalStart = i
+			}
+		}
+	}
+	if lastMandatoryEnd > firstOptionalStart {
+		t.Errorf("expected the mandatory rules to be done before the optional ones start, got %v", events)
+	}
+}
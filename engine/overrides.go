@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// RuleOverride changes the category, effort or labels of a rule without
+// changing its ruleset, such as to downgrade an upstream rule that does not
+// apply to an organization.
+type RuleOverride struct {
+	RuleID string `yaml:"ruleID" json:"ruleID"`
+	// RuleSet limits the override to the rule of this ruleset, the rules
+	// with the ID in all the rulesets are overridden when it is empty.
+	RuleSet  string             `yaml:"ruleSet,omitempty" json:"ruleSet,omitempty"`
+	Category *konveyor.Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Effort   *int               `yaml:"effort,omitempty" json:"effort,omitempty"`
+	// Labels are added to the labels of the rule, RemoveLabels are removed
+	// from them. The label selectors see the overridden labels.
+	Labels       []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	RemoveLabels []string `yaml:"removeLabels,omitempty" json:"removeLabels,omitempty"`
+	// Reason is recorded in the output with the override
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// Validate checks that the override has a rule and changes it
+func (o RuleOverride) Validate() error {
+	if o.RuleID == "" {
+		return fmt.Errorf("override must have a ruleID")
+	}
+	if o.Category == nil && o.Effort == nil && len(o.Labels) == 0 && len(o.RemoveLabels) == 0 {
+		return fmt.Errorf("override of rule %s must change the category, the effort or the labels", o.RuleID)
+	}
+	if o.Category != nil {
+		switch *o.Category {
+		case konveyor.Mandatory, konveyor.Optional, konveyor.Potential:
+		default:
+			return fmt.Errorf("invalid category %q in the override of rule %s, must be one of: %s, %s, %s", *o.Category, o.RuleID, konveyor.Mandatory, konveyor.Optional, konveyor.Potential)
+		}
+	}
+	if o.Effort != nil && *o.Effort < 0 {
+		return fmt.Errorf("effort in the override of rule %s must not be negative", o.RuleID)
+	}
+	return nil
+}
+
+// LoadRuleOverrides reads and validates a yaml file with a list of
+// overrides
+func LoadRuleOverrides(path string) ([]RuleOverride, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	overrides := []RuleOverride{}
+	if err := yaml.UnmarshalStrict(content, &overrides); err != nil {
+		return nil, fmt.Errorf("unable to read the rule overrides %s: %w", path, err)
+	}
+	for _, o := range overrides {
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rule overrides %s: %w", path, err)
+		}
+	}
+	return overrides, nil
+}
+
+// WithRuleOverrides applies the overrides to the rules before they are
+// selected, the violations of the overridden rules record the values the
+// overrides replaced under appliedOverride. The overrides are applied in
+// order, so that a later override of a rule wins.
+func WithRuleOverrides(overrides []RuleOverride) Option {
+	return func(engine *ruleEngine) {
+		engine.overrides = overrides
+	}
+}
+
+// applyOverrides returns the rule with the overrides of the ruleset applied,
+// the indexes of the applied overrides are added to used. Only the original
+// values of the overridden fields are recorded.
+func (r *ruleEngine) applyOverrides(ruleSetName string, rule Rule, used map[int]bool) Rule {
+	var categoryRecorded, effortRecorded, labelsRecorded bool
+	for i, o := range r.overrides {
+		if o.RuleID != rule.RuleID || (o.RuleSet != "" && o.RuleSet != ruleSetName) {
+			continue
+		}
+		used[i] = true
+		if rule.AppliedOverride == nil {
+			rule.AppliedOverride = &konveyor.AppliedOverride{}
+		}
+		if o.Reason != "" {
+			rule.AppliedOverride.Reason = o.Reason
+		}
+		if o.Category != nil {
+			if !categoryRecorded {
+				rule.AppliedOverride.Category = rule.Category
+				categoryRecorded = true
+			}
+			category := *o.Category
+			rule.Category = &category
+		}
+		if o.Effort != nil {
+			if !effortRecorded {
+				rule.AppliedOverride.Effort = rule.Effort
+				effortRecorded = true
+			}
+			effort := *o.Effort
+			rule.Effort = &effort
+		}
+		if len(o.Labels) > 0 || len(o.RemoveLabels) > 0 {
+			if !labelsRecorded {
+				rule.AppliedOverride.Labels = append([]string{}, rule.Labels...)
+				labelsRecorded = true
+			}
+			rule.Labels = overrideLabels(rule.Labels, o.Labels, o.RemoveLabels)
+		}
+	}
+	return rule
+}
+
+// overrideLabels returns a new list of labels, the list of the rule can be
+// shared with other rules
+func overrideLabels(labels, add, remove []string) []string {
+	removed := map[string]bool{}
+	for _, l := range remove {
+		removed[l] = true
+	}
+	result := []string{}
+	seen := map[string]bool{}
+	for _, l := range append(append([]string{}, labels...), add...) {
+		if removed[l] || seen[l] {
+			continue
+		}
+		seen[l] = true
+		result = append(result, l)
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestRuleEngineOverrides(t *testing.T) {
+	mandatory := konveyor.Mandatory
+	optional := konveyor.Optional
+	three, one := 3, 1
+	message := "found"
+	rule := func(id string) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id, Category: &mandatory, Effort: &three, Labels: []string{"konveyor.io/target=quarkus"}},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testIncidentsConditional{incidents: []IncidentContext{{FileURI: "file:///a.java"}}},
+		}
+	}
+	ruleSets := []RuleSet{
+		{Name: "upstream", Rules: []Rule{rule("downgraded"), rule("relabeled"), rule("unchanged")}},
+		{Name: "other", Rules: []Rule{rule("downgraded")}},
+	}
+	overrides := []RuleOverride{
+		{RuleID: "downgraded", RuleSet: "upstream", Category: &optional, Reason: "not used by our apps"},
+		{RuleID: "downgraded", RuleSet: "upstream", Effort: &one},
+		{RuleID: "relabeled", Labels: []string{"konveyor.io/target=eap"}, RemoveLabels: []string{"konveyor.io/target=quarkus"}},
+		{RuleID: "missing", Effort: &one},
+	}
+	selector, err := labels.NewLabelSelector[*RuleMeta]("konveyor.io/target=quarkus || konveyor.io/target=eap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithRuleOverrides(overrides))
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets, selector)
+	violations := map[string]map[string]konveyor.Violation{}
+	for _, rs := range results {
+		violations[rs.Name] = rs.Violations
+	}
+
+	downgraded := violations["upstream"]["downgraded"]
+	if *downgraded.Category != optional || *downgraded.Effort != one {
+		t.Errorf("expected the rule to be downgraded, got category %v and effort %v", *downgraded.Category, *downgraded.Effort)
+	}
+	want := &konveyor.AppliedOverride{Reason: "not used by our apps", Category: &mandatory, Effort: &three}
+	if !reflect.DeepEqual(downgraded.AppliedOverride, want) {
+		t.Errorf("expected applied override %+v, got %+v", want, downgraded.AppliedOverride)
+	}
+
+	relabeled := violations["upstream"]["relabeled"]
+	if !reflect.DeepEqual(relabeled.Labels, []string{"konveyor.io/target=eap"}) {
+		t.Errorf("expected the labels to be overridden, got %v", relabeled.Labels)
+	}
+	want = &konveyor.AppliedOverride{Labels: []string{"konveyor.io/target=quarkus"}}
+	if !reflect.DeepEqual(relabeled.AppliedOverride, want) {
+		t.Errorf("expected applied override %+v, got %+v", want, relabeled.AppliedOverride)
+	}
+
+	for _, v := range []konveyor.Violation{violations["upstream"]["unchanged"], violations["other"]["downgraded"]} {
+		if *v.Category != mandatory || v.AppliedOverride != nil {
+			t.Errorf("expected the rule not to be overridden, got %+v", v)
+		}
+	}
+}
+
+func TestLoadRuleOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "- ruleID: rule-001\n  category: optional\n  reason: accepted risk\n- ruleID: rule-002\n  effort: 0\n",
+			want:    2,
+		},
+		{
+			name:    "no change",
+			content: "- ruleID: rule-001\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid category",
+			content: "- ruleID: rule-001\n  category: critical\n",
+			wantErr: true,
+		},
+		{
+			name:    "negative effort",
+			content: "- ruleID: rule-001\n  effort: -1\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			content: "- ruleID: rule-001\n  severity: low\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "overrides.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			overrides, err := LoadRuleOverrides(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(overrides) != tt.want {
+				t.Errorf("expected %d overrides, got %+v", tt.want, overrides)
+			}
+		})
+	}
+}
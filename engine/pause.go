@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errNotPaused = errors.New("the run is not paused")
+
+// Pause holds the rules of a run while it is paused: the rules being
+// evaluated complete, but no other rule is started until the run is resumed,
+// so that the rules already evaluated are kept.
+type Pause struct {
+	mutex  sync.Mutex
+	paused bool
+	// resumed is closed when the run is resumed
+	resumed chan struct{}
+	// active is the number of rules being evaluated, idle is closed when it
+	// drops to zero while the run is paused
+	active int
+	idle   chan struct{}
+}
+
+func NewPause() *Pause {
+	return &Pause{}
+}
+
+// WithPause holds the rules of the runs while the pause is paused
+func WithPause(p *Pause) Option {
+	return func(engine *ruleEngine) {
+		engine.pause = p
+	}
+}
+
+// Pause stops starting rules, it returns false when the run was already
+// paused
+func (p *Pause) Pause() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.paused {
+		return false
+	}
+	p.paused = true
+	p.resumed = make(chan struct{})
+	p.idle = make(chan struct{})
+	if p.active == 0 {
+		close(p.idle)
+	}
+	return true
+}
+
+// Resume starts the rules that were held, it returns false when the run was
+// not paused
+func (p *Pause) Resume() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.paused {
+		return false
+	}
+	p.paused = false
+	close(p.resumed)
+	return true
+}
+
+// Paused returns whether the run is paused
+func (p *Pause) Paused() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.paused
+}
+
+// Idle waits until the rules that were being evaluated when the run was
+// paused are done. It fails when the context is done, or when the run is
+// resumed first.
+func (p *Pause) Idle(ctx context.Context) error {
+	p.mutex.Lock()
+	if !p.paused {
+		p.mutex.Unlock()
+		return errNotPaused
+	}
+	idle, resumed := p.idle, p.resumed
+	p.mutex.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-resumed:
+		return errNotPaused
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire waits while the run is paused before a rule is evaluated, it fails
+// when the context is done first
+func (p *Pause) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	for {
+		p.mutex.Lock()
+		if !p.paused {
+			p.active++
+			p.mutex.Unlock()
+			return nil
+		}
+		resumed := p.resumed
+		p.mutex.Unlock()
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release is called once an acquired rule is evaluated
+func (p *Pause) release() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.active--
+	if p.paused && p.active == 0 {
+		select {
+		case <-p.idle:
+		default:
+			close(p.idle)
+		}
+	}
+}
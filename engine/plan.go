@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// ProviderQuery is implemented by the conditions that send a query to a
+// provider, so that the plan tells which capabilities each rule needs.
+type ProviderQuery interface {
+	ProviderCapability() (provider string, capability string)
+}
+
+// Plan resolves what RunRules would evaluate with the same rulesets and
+// selectors, without evaluating anything: the rules that are selected or
+// skipped and why, in the order they are evaluated in, with the providers
+// they query and their estimated cost.
+func (r *ruleEngine) Plan(ruleSets []RuleSet, selectors ...RuleSelector) konveyor.Plan {
+	plan := konveyor.Plan{
+		RuleOrder: string(r.ruleOrder),
+		Rules:     []konveyor.PlannedRule{},
+	}
+	if plan.RuleOrder == "" {
+		plan.RuleOrder = string(RuleOrderFile)
+	}
+	for _, t := range r.targets {
+		plan.Targets = append(plan.Targets, t.Name)
+	}
+	if r.scope != nil {
+		plan.Scope = &konveyor.PlanScope{
+			Include: r.scope.Include,
+			Exclude: r.scope.Exclude,
+		}
+	}
+
+	tagging := []konveyor.PlannedRule{}
+	others := []ruleMessage{}
+	planned := map[string]konveyor.PlannedRule{}
+	skipped := []konveyor.PlannedRule{}
+	for _, ruleSet := range ruleSets {
+		for _, rule := range ruleSet.Rules {
+			rule.Labels = append(rule.Labels, ruleSet.Labels...)
+			rule = r.applyOverrides(ruleSet.Name, rule, map[int]bool{})
+			p := r.planRule(ruleSet.Name, rule)
+			p.Selected, p.Reason = r.selectionReason(rule.RuleMeta, selectors)
+			if !p.Selected {
+				skipped = append(skipped, p)
+				continue
+			}
+			if rule.Perform.IsTagging() {
+				p.Tagging = true
+				tagging = append(tagging, p)
+				continue
+			}
+			planned[plannedKey(ruleSet.Name, rule.RuleID)] = p
+			others = append(others, ruleMessage{rule: rule, ruleSetName: ruleSet.Name})
+		}
+	}
+
+	plan.Rules = append(plan.Rules, tagging...)
+	for _, batch := range orderRules(others, r.ruleOrder) {
+		for _, m := range batch {
+			plan.Rules = append(plan.Rules, planned[plannedKey(m.ruleSetName, m.rule.RuleID)])
+		}
+	}
+	plan.Selected = len(plan.Rules)
+	plan.Skipped = len(skipped)
+	plan.Providers = countPlannedProviders(plan.Rules)
+	plan.Rules = append(plan.Rules, skipped...)
+	return plan
+}
+
+func plannedKey(ruleSetName, ruleID string) string {
+	return ruleSetName + "/" + ruleID
+}
+
+func (r *ruleEngine) planRule(ruleSetName string, rule Rule) konveyor.PlannedRule {
+	cost := estimateCost(rule.When)
+	return konveyor.PlannedRule{
+		RuleSet:         ruleSetName,
+		RuleID:          rule.RuleID,
+		Category:        rule.Category,
+		Targets:         r.violationTargets(rule.RuleMeta),
+		Queries:         conditionQueries(rule.When),
+		Cost:            cost,
+		CostTier:        costTier(cost),
+		AppliedOverride: rule.AppliedOverride,
+	}
+}
+
+// selectionReason returns whether the rule is evaluated and why, the way
+// filterRules selects it
+func (r *ruleEngine) selectionReason(m RuleMeta, selectors []RuleSelector) (bool, string) {
+	if reason := r.ruleFilter.skipReason(m); reason != "" {
+		return false, fmt.Sprintf("the rule filter does not select it, %s", reason)
+	}
+	for _, s := range selectors {
+		if !matchesAllSelectors(m, s) {
+			return false, fmt.Sprintf("the selector %s does not match", describeSelector(s))
+		}
+	}
+	if len(r.targets) > 0 {
+		targets := r.ruleTargets(m)
+		if len(targets) == 0 {
+			return false, "no target selects it"
+		}
+		return true, fmt.Sprintf("selected by the targets %s", strings.Join(targets, ", "))
+	}
+	if len(selectors) > 0 {
+		return true, "the selectors match"
+	}
+	return true, "no selector or target is given"
+}
+
+func describeSelector(s RuleSelector) string {
+	if stringer, ok := s.(fmt.Stringer); ok {
+		return fmt.Sprintf("%q", stringer.String())
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+func costTier(cost int) konveyor.CostTier {
+	switch {
+	case cost <= 1:
+		return konveyor.CostLow
+	case cost <= 5:
+		return konveyor.CostMedium
+	}
+	return konveyor.CostHigh
+}
+
+// conditionQueries returns the sorted <provider>.<capability> the conditions
+// send queries to
+func conditionQueries(c Conditional) []string {
+	queries := map[string]bool{}
+	addConditionQueries(c, queries)
+	names := []string{}
+	for q := range queries {
+		names = append(names, q)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func addConditionQueries(c Conditional, queries map[string]bool) {
+	switch c := c.(type) {
+	case AndCondition:
+		for _, e := range c.Conditions {
+			addConditionQueries(e.ProviderSpecificConfig, queries)
+		}
+	case OrCondition:
+		for _, e := range c.Conditions {
+			addConditionQueries(e.ProviderSpecificConfig, queries)
+		}
+	case ConditionEntry:
+		addConditionQueries(c.ProviderSpecificConfig, queries)
+	case ProviderQuery:
+		provider, capability := c.ProviderCapability()
+		queries[fmt.Sprintf("%s.%s", provider, capability)] = true
+	}
+}
+
+// countPlannedProviders counts the rules that query each provider, and the
+// estimated cost of their queries
+func countPlannedProviders(rules []konveyor.PlannedRule) []konveyor.PlanCount {
+	counts := map[string]*konveyor.PlanCount{}
+	for _, rule := range rules {
+		seen := map[string]bool{}
+		for _, q := range rule.Queries {
+			name := strings.SplitN(q, ".", 2)[0]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if counts[name] == nil {
+				counts[name] = &konveyor.PlanCount{Name: name}
+			}
+			counts[name].Rules++
+			counts[name].Cost += rule.Cost
+		}
+	}
+	providers := []konveyor.PlanCount{}
+	for _, c := range counts {
+		providers = append(providers, *c)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
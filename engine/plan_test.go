@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testPlannedConditional struct {
+	provider   string
+	capability string
+}
+
+func (t testPlannedConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	return ConditionResponse{}, nil
+}
+
+func (t testPlannedConditional) ProviderCapability() (string, string) {
+	return t.provider, t.capability
+}
+
+func TestRuleEnginePlan(t *testing.T) {
+	message := "found"
+	query := func(provider, capability string) ConditionEntry {
+		return ConditionEntry{ProviderSpecificConfig: testPlannedConditional{provider: provider, capability: capability}}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			{
+				RuleMeta: RuleMeta{RuleID: "costly", Labels: []string{"konveyor.io/target=quarkus"}},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     testCostConditional{cost: 8},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "java", Labels: []string{"konveyor.io/target=quarkus"}},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     AndCondition{Conditions: []ConditionEntry{query("java", "referenced"), query("builtin", "filecontent"), query("java", "dependency")}},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "tag", Labels: []string{"konveyor.io/target=quarkus"}},
+				Perform:  Perform{Tag: []string{"Java"}},
+				When:     query("builtin", "file"),
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "other", Labels: []string{"konveyor.io/target=eap8"}},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     query("java", "referenced"),
+			},
+		},
+	}}
+	selector, err := labels.NewTargetSelector[*RuleMeta]("quarkus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleEngine := CreateRuleEngine(context.Background(), 1, logr.Discard(),
+		WithTargets(Target{Name: "quarkus", Selector: selector}),
+		WithRuleOrder(RuleOrderCost),
+		WithScope(&Scope{Exclude: []string{"vendor"}}),
+	)
+	defer ruleEngine.Stop()
+
+	plan := ruleEngine.Plan(ruleSets)
+	if plan.Selected != 3 || plan.Skipped != 1 {
+		t.Errorf("expected 3 selected and 1 skipped rules, got %d and %d", plan.Selected, plan.Skipped)
+	}
+	if plan.RuleOrder != string(RuleOrderCost) || !reflect.DeepEqual(plan.Targets, []string{"quarkus"}) {
+		t.Errorf("unexpected order %s or targets %v", plan.RuleOrder, plan.Targets)
+	}
+	if plan.Scope == nil || !reflect.DeepEqual(plan.Scope.Exclude, []string{"vendor"}) {
+		t.Errorf("expected the scope of the analysis, got %v", plan.Scope)
+	}
+	// the tagging rules first, then by cost, then the skipped ones
+	ids := []string{}
+	for _, r := range plan.Rules {
+		ids = append(ids, r.RuleID)
+	}
+	if !reflect.DeepEqual(ids, []string{"tag", "java", "costly", "other"}) {
+		t.Errorf("unexpected order of the rules %v", ids)
+	}
+	java := plan.Rules[1]
+	if !java.Selected || java.Reason != "selected by the targets quarkus" {
+		t.Errorf("unexpected selection of the rule %+v", java)
+	}
+	if !reflect.DeepEqual(java.Queries, []string{"builtin.filecontent", "java.dependency", "java.referenced"}) {
+		t.Errorf("unexpected queries %v", java.Queries)
+	}
+	if java.Cost != 3 || java.CostTier != konveyor.CostMedium {
+		t.Errorf("expected a medium cost of 3, got %s %d", java.CostTier, java.Cost)
+	}
+	if plan.Rules[2].CostTier != konveyor.CostHigh || !plan.Rules[0].Tagging {
+		t.Errorf("unexpected plan of the rules %+v", plan.Rules)
+	}
+	if other := plan.Rules[3]; other.Selected || other.Reason != "no target selects it" {
+		t.Errorf("unexpected selection of the skipped rule %+v", other)
+	}
+	wantProviders := []konveyor.PlanCount{
+		{Name: "builtin", Rules: 2, Cost: 4},
+		{Name: "java", Rules: 1, Cost: 3},
+	}
+	if !reflect.DeepEqual(plan.Providers, wantProviders) {
+		t.Errorf("expected the providers %v, got %v", wantProviders, plan.Providers)
+	}
+
+	labelSelector, err := labels.NewLabelSelector[*RuleMeta]("konveyor.io/target=eap8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan = ruleEngine.Plan(ruleSets, labelSelector)
+	for _, r := range plan.Rules {
+		if r.RuleID == "java" && r.Reason != `the selector "konveyor.io/target=eap8" does not match` {
+			t.Errorf("unexpected reason %q", r.Reason)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/uri"
+)
+
+// Scope limits the files that are analyzed using globs. It is passed to the
+// providers with every condition so that they can skip the files out of scope
+// instead of filtering their results afterwards.
+//
+// A glob without a "/" matches any element of the path, such as "vendor" or
+// "*.min.js", other globs match consecutive elements of the path, such as
+// "src/test/**". A glob starting with "/" only matches from the root, such as
+// "/target". "**" matches any number of path elements, and a glob matching a
+// directory matches all the files in it.
+type Scope struct {
+	// Include are the globs of the files to analyze, all the files are
+	// analyzed when empty.
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	// Exclude are the globs of the files not to analyze, it takes precedence
+	// over Include.
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// Validate checks that all the globs are valid
+func (s *Scope) Validate() error {
+	if s == nil {
+		return nil
+	}
+	for _, g := range append(append([]string{}, s.Include...), s.Exclude...) {
+		if _, err := globToRegex(g); err != nil {
+			return fmt.Errorf("invalid scope glob %q: %w", g, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns whether the file with the given path is in the scope, the
+// globs are matched against the path relative to the root when it is in it.
+func (s *Scope) Matches(root, path string) bool {
+	if s == nil {
+		return true
+	}
+	path = relativePath(root, path)
+	if matchesAnyGlob(s.Exclude, path) {
+		return false
+	}
+	return len(s.Include) == 0 || matchesAnyGlob(s.Include, path)
+}
+
+// MatchesURI returns whether the file of the URI is in the scope, URIs that
+// are not files, such as the ones of dependencies, are always in scope.
+func (s *Scope) MatchesURI(root string, u uri.URI) bool {
+	if s == nil || !strings.HasPrefix(string(u), uri.FileScheme) {
+		return true
+	}
+	return s.Matches(root, u.Filename())
+}
+
+// ExcludesDir returns whether nothing in the directory can be in the scope,
+// which allows to skip it while walking the file system.
+func (s *Scope) ExcludesDir(root, path string) bool {
+	if s == nil {
+		return false
+	}
+	return matchesAnyGlob(s.Exclude, relativePath(root, path))
+}
+
+// relativePath makes the path relative to the root, so that the directories
+// the root is in do not match the globs.
+func relativePath(root, path string) string {
+	if root != "" {
+		absRoot, err := filepath.Abs(root)
+		if err == nil {
+			absPath, err := filepath.Abs(path)
+			if err == nil {
+				if rel, err := filepath.Rel(absRoot, absPath); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+					path = rel
+				}
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func matchesAnyGlob(globs []string, path string) bool {
+	for _, g := range globs {
+		r, err := globToRegex(g)
+		if err != nil {
+			continue
+		}
+		if r.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// globCache keeps the compiled globs as scopes are checked for every file
+var globCache = sync.Map{}
+
+// globToRegex converts a glob to a regex matching the paths as described on Scope.
+func globToRegex(glob string) (*regexp.Regexp, error) {
+	glob = filepath.ToSlash(strings.TrimSpace(glob))
+	anchored := strings.HasPrefix(glob, "/")
+	glob = strings.Trim(glob, "/")
+	if glob == "" {
+		return nil, fmt.Errorf("glob is empty")
+	}
+	key := glob
+	if anchored {
+		key = "/" + glob
+	}
+	if r, ok := globCache.Load(key); ok {
+		return r.(*regexp.Regexp), nil
+	}
+	b := strings.Builder{}
+	if anchored {
+		b.WriteString(`^`)
+	} else {
+		b.WriteString(`(^|/)`)
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches no element at all
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString(`(.*/)?`)
+				} else {
+					b.WriteString(`.*`)
+				}
+			} else {
+				b.WriteString(`[^/]*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// a glob matching a directory matches everything in it
+	b.WriteString(`(/|$)`)
+	r, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, err
+	}
+	globCache.Store(key, r)
+	return r, nil
+}
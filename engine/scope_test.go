@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestScopeMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope *Scope
+		root  string
+		path  string
+		want  bool
+	}{
+		{
+			name: "nil scope matches everything",
+			path: "/app/vendor/lib.go",
+			want: true,
+		},
+		{
+			name:  "excluded directory name",
+			scope: &Scope{Exclude: []string{"node_modules"}},
+			root:  "/app",
+			path:  "/app/web/node_modules/lib/index.js",
+			want:  false,
+		},
+		{
+			name:  "excluded name matches whole path elements only",
+			scope: &Scope{Exclude: []string{"vendor"}},
+			root:  "/app",
+			path:  "/app/vendors/lib.go",
+			want:  true,
+		},
+		{
+			name:  "directories above the root are not matched",
+			scope: &Scope{Exclude: []string{"target"}},
+			root:  "/home/target/app",
+			path:  "/home/target/app/src/Main.java",
+			want:  true,
+		},
+		{
+			name:  "anchored glob matches from the root",
+			scope: &Scope{Exclude: []string{"/target"}},
+			root:  "/app",
+			path:  "/app/target/classes/Main.class",
+			want:  false,
+		},
+		{
+			name:  "anchored glob does not match nested paths",
+			scope: &Scope{Exclude: []string{"/target"}},
+			root:  "/app",
+			path:  "/app/module/target/classes/Main.class",
+			want:  true,
+		},
+		{
+			name:  "excluded file glob",
+			scope: &Scope{Exclude: []string{"*.min.js"}},
+			root:  "/app",
+			path:  "/app/static/app.min.js",
+			want:  false,
+		},
+		{
+			name:  "excluded path glob with double star",
+			scope: &Scope{Exclude: []string{"src/test/**"}},
+			root:  "/app",
+			path:  "/app/src/test/java/MainTest.java",
+			want:  false,
+		},
+		{
+			name:  "double star matches no directories",
+			scope: &Scope{Include: []string{"src/**/*.java"}},
+			root:  "/app",
+			path:  "/app/src/Main.java",
+			want:  true,
+		},
+		{
+			name:  "not included",
+			scope: &Scope{Include: []string{"src/main/**"}},
+			root:  "/app",
+			path:  "/app/src/test/java/MainTest.java",
+			want:  false,
+		},
+		{
+			name:  "exclude takes precedence over include",
+			scope: &Scope{Include: []string{"src/**"}, Exclude: []string{"generated"}},
+			root:  "/app",
+			path:  "/app/src/generated/Stub.java",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Matches(tt.root, tt.path); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScopeMatchesURI(t *testing.T) {
+	scope := &Scope{Exclude: []string{"vendor"}}
+	if scope.MatchesURI("/app", uri.File("/app/vendor/lib.go")) {
+		t.Errorf("expected file in excluded directory not to match")
+	}
+	if !scope.MatchesURI("/app", uri.URI("jdt://contents/vendor/Lib.class")) {
+		t.Errorf("expected URIs that are not files to match")
+	}
+}
+
+func TestScopeValidate(t *testing.T) {
+	if err := (&Scope{Exclude: []string{"vendor", ""}}).Validate(); err == nil {
+		t.Errorf("expected an empty glob to be invalid")
+	}
+	if err := (&Scope{Include: []string{"src/**/*.java"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
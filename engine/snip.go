@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// limitCodeSnip cuts the code snip to maxSize bytes, keeping the lines that
+// are the closest to the line of the incident, zero-based. The line of the
+// incident is cut when it is longer than maxSize on its own.
+func limitCodeSnip(codeSnip string, line int, maxSize int) string {
+	if maxSize <= 0 || len(codeSnip) <= maxSize {
+		return codeSnip
+	}
+	lines := strings.Split(codeSnip, "\n")
+	center := 0
+	for i, l := range lines {
+		if snipLineNumber(l) == line+1 {
+			center = i
+			break
+		}
+	}
+	if len(lines[center]) >= maxSize {
+		return truncateString(lines[center], maxSize)
+	}
+	start, end := center, center+1
+	size := len(lines[center])
+	for {
+		added := false
+		if end < len(lines) && size+1+len(lines[end]) <= maxSize {
+			size += 1 + len(lines[end])
+			end++
+			added = true
+		}
+		if start > 0 && size+1+len(lines[start-1]) <= maxSize {
+			size += 1 + len(lines[start-1])
+			start--
+			added = true
+		}
+		if !added {
+			break
+		}
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// snipLineNumber returns the line number a line of a code snip starts with,
+// -1 when it has none
+func snipLineNumber(l string) int {
+	fields := strings.Fields(l)
+	if len(fields) == 0 {
+		return -1
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// truncateString cuts s to at most size bytes without splitting a character
+func truncateString(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
+)
+
+func TestLimitCodeSnip(t *testing.T) {
+	snip := " 8  package a\n 9  \n10  import b\n11  func c() {\n12  }"
+	tests := []struct {
+		name    string
+		snip    string
+		line    int
+		maxSize int
+		want    string
+	}{
+		{
+			name:    "no limit",
+			line:    9,
+			maxSize: 0,
+			want:    snip,
+		},
+		{
+			name:    "under the limit",
+			line:    9,
+			maxSize: 100,
+			want:    snip,
+		},
+		{
+			name:    "keeps the closest lines",
+			line:    9,
+			maxSize: 30,
+			want:    "10  import b\n11  func c() {",
+		},
+		{
+			name:    "cuts the line of the incident",
+			line:    10,
+			maxSize: 8,
+			want:    "11  func",
+		},
+		{
+			name:    "does not split a character",
+			snip:    "1  éé\n2  b",
+			line:    0,
+			maxSize: 6,
+			want:    "1  é",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := snip
+			if tt.snip != "" {
+				s = tt.snip
+			}
+			if got := limitCodeSnip(s, tt.line, tt.maxSize); got != tt.want {
+				t.Errorf("limitCodeSnip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type testSnipper struct{}
+
+func (testSnipper) GetCodeSnip(u uri.URI, l Location) (string, error) {
+	return "1  first\n2  second\n3  third", nil
+}
+
+func TestGetCodeLocation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\nimport b\n\nfunc c() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &ruleEngine{logger: logr.Discard(), contextLines: 1, codeSnipMaxSize: 20}
+	line := 3
+	snip, err := r.getCodeLocation(context.Background(), IncidentContext{FileURI: uri.File(path), LineNumber: &line}, Rule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2  \n3  import b\n4  "; snip != want {
+		t.Errorf("expected the lines around the line number %q, got %q", want, snip)
+	}
+
+	// the files the engine can not read are snipped by the provider
+	snip, err = r.getCodeLocation(context.Background(), IncidentContext{
+		FileURI:    uri.File(filepath.Join(dir, "missing.go")),
+		LineNumber: &line,
+	}, Rule{Snipper: testSnipper{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2  second\n3  third"; snip != want {
+		t.Errorf("expected the capped snip of the provider %q, got %q", want, snip)
+	}
+}
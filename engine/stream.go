@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// ResultWriter is given the results of the rules as soon as they are
+// evaluated. Each result is a ruleset that only has what changed: first the
+// state of every ruleset once the tagging rules are done, with the skipped
+// rules and the tags, then a ruleset with a single violation, error or
+// unmatched rule for each other rule. Merging the results in order gives the
+// rulesets RunRules returns, before the incidents are deduplicated.
+type ResultWriter interface {
+	WriteResult(ruleSet konveyor.RuleSet) error
+}
+
+// WithResultWriter streams the results of the rules to the writer while the
+// rules are evaluated, see ResultWriter
+func WithResultWriter(w ResultWriter) Option {
+	return func(engine *ruleEngine) {
+		engine.resultWriter = w
+	}
+}
+
+func (r *ruleEngine) writeResult(ruleSet konveyor.RuleSet) {
+	if r.resultWriter == nil {
+		return
+	}
+	if err := r.resultWriter.WriteResult(ruleSet); err != nil {
+		r.logger.Error(err, "unable to write the result of the rules", "ruleset", ruleSet.Name)
+	}
+}
+
+// writeRuleSets writes the current state of the rulesets
+func (r *ruleEngine) writeRuleSets(mapRuleSets map[string]*konveyor.RuleSet) {
+	if r.resultWriter == nil {
+		return
+	}
+	for _, rs := range mapRuleSets {
+		if rs != nil {
+			r.writeResult(*rs)
+		}
+	}
+}
+
+// writeRuleResult writes the result of a single rule of the ruleset
+func (r *ruleEngine) writeRuleResult(rs *konveyor.RuleSet, ruleID string) {
+	if r.resultWriter == nil || rs == nil {
+		return
+	}
+	result := konveyor.RuleSet{Name: rs.Name}
+	if v, ok := rs.Violations[ruleID]; ok {
+		result.Violations = map[string]konveyor.Violation{ruleID: v}
+	} else if e, ok := rs.Errors[ruleID]; ok {
+		result.Errors = map[string]string{ruleID: e}
+	} else {
+		result.Unmatched = []string{ruleID}
+	}
+	r.writeResult(result)
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testResultWriter struct {
+	mutex   sync.Mutex
+	results []konveyor.RuleSet
+}
+
+// WriteResult keeps a copy of the result, the engine keeps changing the maps
+// of the rulesets after they are written
+func (t *testResultWriter) WriteResult(ruleSet konveyor.RuleSet) error {
+	content, err := json.Marshal(ruleSet)
+	if err != nil {
+		return err
+	}
+	result := konveyor.RuleSet{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.results = append(t.results, result)
+	return nil
+}
+
+func TestRuleEngineResultWriter(t *testing.T) {
+	message := "found"
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			{
+				RuleMeta: RuleMeta{RuleID: "matched"},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     testIncidentsConditional{incidents: []IncidentContext{{FileURI: "file:///a.java"}}},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "unmatched"},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     testIncidentsConditional{},
+			},
+			{
+				RuleMeta: RuleMeta{RuleID: "failed"},
+				Perform:  Perform{Message: Message{Text: &message}},
+				When:     testErrorConditional{err: errors.New("failed")},
+			},
+		},
+	}}
+	w := &testResultWriter{}
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithResultWriter(w))
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets)
+
+	if len(w.results) != 4 {
+		t.Fatalf("expected the state of the ruleset and a result per rule, got %+v", w.results)
+	}
+	if w.results[0].Name != "test" || len(w.results[0].Violations) != 0 {
+		t.Errorf("expected the state of the ruleset first, got %+v", w.results[0])
+	}
+	violations, errs, unmatched := []string{}, []string{}, []string{}
+	for _, result := range w.results[1:] {
+		if result.Name != "test" || len(result.Violations)+len(result.Errors)+len(result.Unmatched) != 1 {
+			t.Errorf("expected a single rule result, got %+v", result)
+		}
+		for id, v := range result.Violations {
+			violations = append(violations, id)
+			if len(v.Incidents) != 1 || v.Incidents[0].Fingerprint != results[0].Violations[id].Incidents[0].Fingerprint {
+				t.Errorf("expected the incidents of the output %+v, got %+v", results[0].Violations[id].Incidents, v.Incidents)
+			}
+		}
+		for id := range result.Errors {
+			errs = append(errs, id)
+		}
+		unmatched = append(unmatched, result.Unmatched...)
+	}
+	sort.Strings(violations)
+	if !reflect.DeepEqual(violations, []string{"matched"}) || !reflect.DeepEqual(errs, []string{"failed"}) || !reflect.DeepEqual(unmatched, []string{"unmatched"}) {
+		t.Errorf("unexpected results, violations %v, errors %v, unmatched %v", violations, errs, unmatched)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const uncategorized = "uncategorized"
+
+// Summarize aggregates the violations of the rulesets returned by RunRules,
+// so that embedders do not have to compute the totals themselves.
+func Summarize(rulesets []konveyor.RuleSet) konveyor.Summary {
+	summary := konveyor.Summary{
+		Categories: map[string]konveyor.SummaryCount{},
+		RuleSets:   map[string]konveyor.SummaryCount{},
+		Tags:       map[string]int{},
+	}
+	for _, rs := range rulesets {
+		rsCount := konveyor.SummaryCount{}
+		for _, v := range rs.Violations {
+			incidents := len(v.Incidents)
+			effort := 0
+			if v.Effort != nil {
+				effort = *v.Effort * incidents
+			}
+			category := uncategorized
+			if v.Category != nil {
+				category = string(*v.Category)
+			}
+
+			summary.Violations++
+			summary.Incidents += incidents
+			summary.Effort += effort
+			summary.Categories[category] = addCount(summary.Categories[category], incidents, effort)
+			rsCount = addCount(rsCount, incidents, effort)
+			for _, target := range v.Targets {
+				if summary.Targets == nil {
+					summary.Targets = map[string]konveyor.SummaryCount{}
+				}
+				summary.Targets[target] = addCount(summary.Targets[target], incidents, effort)
+			}
+		}
+		if rsCount.Violations > 0 {
+			summary.RuleSets[rs.Name] = rsCount
+		}
+		for _, tag := range rs.Tags {
+			summary.Tags[tag]++
+		}
+	}
+	return summary
+}
+
+func addCount(c konveyor.SummaryCount, incidents, effort int) konveyor.SummaryCount {
+	c.Violations++
+	c.Incidents += incidents
+	c.Effort += effort
+	return c
+}
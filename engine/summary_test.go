@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestSummarize(t *testing.T) {
+	effort := func(e int) *int { return &e }
+	incidents := func(n int) []konveyor.Incident { return make([]konveyor.Incident, n) }
+
+	tests := []struct {
+		name     string
+		rulesets []konveyor.RuleSet
+		want     konveyor.Summary
+	}{
+		{
+			name: "no rulesets",
+			want: konveyor.Summary{
+				Categories: map[string]konveyor.SummaryCount{},
+				RuleSets:   map[string]konveyor.SummaryCount{},
+				Tags:       map[string]int{},
+			},
+		},
+		{
+			name: "violations are counted by category, ruleset and tag",
+			rulesets: []konveyor.RuleSet{
+				{
+					Name: "ruleset-a",
+					Tags: []string{"Java EE", "JMS"},
+					Violations: map[string]konveyor.Violation{
+						"rule-1": {Category: &konveyor.Mandatory, Effort: effort(3), Incidents: incidents(2)},
+						"rule-2": {Category: &konveyor.Optional, Effort: effort(1), Incidents: incidents(1)},
+						"rule-3": {Incidents: incidents(4)},
+					},
+					Unmatched: []string{"rule-4"},
+				},
+				{
+					Name: "ruleset-b",
+					Tags: []string{"Java EE"},
+					Violations: map[string]konveyor.Violation{
+						"rule-1": {Category: &konveyor.Mandatory, Effort: effort(5), Incidents: incidents(1)},
+					},
+				},
+				{
+					Name:      "ruleset-c",
+					Unmatched: []string{"rule-1"},
+				},
+			},
+			want: konveyor.Summary{
+				Violations: 4,
+				Incidents:  8,
+				Effort:     12,
+				Categories: map[string]konveyor.SummaryCount{
+					"mandatory":     {Violations: 2, Incidents: 3, Effort: 11},
+					"optional":      {Violations: 1, Incidents: 1, Effort: 1},
+					"uncategorized": {Violations: 1, Incidents: 4},
+				},
+				RuleSets: map[string]konveyor.SummaryCount{
+					"ruleset-a": {Violations: 3, Incidents: 7, Effort: 7},
+					"ruleset-b": {Violations: 1, Incidents: 1, Effort: 5},
+				},
+				Tags: map[string]int{"Java EE": 2, "JMS": 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.rulesets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
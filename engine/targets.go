@@ -0,0 +1,43 @@
+package engine
+
+// Target selects the rules of a migration target, the violations of the
+// rules it selects are attributed to it. The selector is usually the one of
+// labels.NewTargetSelector.
+type Target struct {
+	Name     string
+	Selector RuleSelector
+}
+
+// WithTargets only evaluates the rules that are selected by at least one of
+// the targets, on top of the selectors given to RunRules. A rule selected by
+// several targets is evaluated once, its violation lists all of them.
+func WithTargets(targets ...Target) Option {
+	return func(engine *ruleEngine) {
+		engine.targets = append(engine.targets, targets...)
+	}
+}
+
+// ruleTargets returns the names of the targets that select the rule
+func (r *ruleEngine) ruleTargets(m RuleMeta) []string {
+	names := []string{}
+	for _, t := range r.targets {
+		matched, err := t.Selector.Matches(&m)
+		if err != nil {
+			r.logger.V(5).Error(err, "unable to match the target", "target", t.Name, "ruleID", m.RuleID)
+			continue
+		}
+		if matched {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// violationTargets returns the targets of the violation of a rule, nil
+// without targets so that they are not in the output
+func (r *ruleEngine) violationTargets(m RuleMeta) []string {
+	if len(r.targets) == 0 {
+		return nil
+	}
+	return r.ruleTargets(m)
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestRuleEngineTargets(t *testing.T) {
+	message := "found"
+	rule := func(id string, labels ...string) Rule {
+		return Rule{
+			RuleMeta: RuleMeta{RuleID: id, Labels: labels},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testIncidentsConditional{incidents: []IncidentContext{{FileURI: "file:///a.java"}}},
+		}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			rule("eap", "konveyor.io/target=eap7+"),
+			rule("quarkus", "konveyor.io/target=quarkus"),
+			rule("both", "konveyor.io/target=eap8", "konveyor.io/target=quarkus"),
+			rule("other", "konveyor.io/target=openjdk17"),
+			rule("none"),
+		},
+	}}
+	tests := []struct {
+		name        string
+		targets     []string
+		wantTargets map[string][]string
+		wantSkipped []string
+	}{
+		{
+			name: "no targets",
+			wantTargets: map[string][]string{
+				"eap": nil, "quarkus": nil, "both": nil, "other": nil, "none": nil,
+			},
+			wantSkipped: nil,
+		},
+		{
+			name:    "the violations are attributed to the targets of their rule",
+			targets: []string{"eap8", "quarkus"},
+			wantTargets: map[string][]string{
+				"eap":     {"eap8"},
+				"quarkus": {"quarkus"},
+				"both":    {"eap8", "quarkus"},
+			},
+			wantSkipped: []string{"none", "other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := []Target{}
+			for _, name := range tt.targets {
+				selector, err := labels.NewTargetSelector[*RuleMeta](name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				targets = append(targets, Target{Name: name, Selector: selector})
+			}
+			ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithTargets(targets...))
+			defer ruleEngine.Stop()
+			rulesets := ruleEngine.RunRules(context.Background(), ruleSets)
+			if len(rulesets) != 1 {
+				t.Fatalf("expected one ruleset, got %d", len(rulesets))
+			}
+			got := map[string][]string{}
+			for id, v := range rulesets[0].Violations {
+				got[id] = v.Targets
+			}
+			if !reflect.DeepEqual(got, tt.wantTargets) {
+				t.Errorf("expected the targets %v, got %v", tt.wantTargets, got)
+			}
+			skipped := rulesets[0].Skipped
+			sort.Strings(skipped)
+			if len(skipped) == 0 {
+				skipped = nil
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("expected the skipped rules %v, got %v", tt.wantSkipped, skipped)
+			}
+			if len(tt.targets) == 0 {
+				return
+			}
+			summary := Summarize(rulesets)
+			want := map[string]konveyor.SummaryCount{
+				"eap8":    {Violations: 2, Incidents: 2},
+				"quarkus": {Violations: 2, Incidents: 2},
+			}
+			if !reflect.DeepEqual(summary.Targets, want) {
+				t.Errorf("expected the summary of the targets %v, got %v", want, summary.Targets)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+)
+
+// Threshold only reports a rule once it has enough incidents, such as for
+// the rules that flag an API when it is used too often
+type Threshold struct {
+	// MinIncidents is the number of incidents the rule needs to be reported
+	MinIncidents int `yaml:"minIncidents" json:"minIncidents"`
+	// PerFile counts the incidents of each file, only the incidents of the
+	// files that have MinIncidents of them are reported
+	PerFile bool `yaml:"perFile,omitempty" json:"perFile,omitempty"`
+}
+
+func (t *Threshold) Validate() error {
+	if t.MinIncidents < 1 {
+		return fmt.Errorf("the minIncidents of a threshold must be at least 1, not %d", t.MinIncidents)
+	}
+	return nil
+}
+
+// applyThreshold removes the incidents that do not reach the threshold of
+// the rule, the response does not match anymore when none is left. The
+// incidents are counted before they are limited.
+func applyThreshold(threshold *Threshold, response ConditionResponse) ConditionResponse {
+	if threshold == nil || !response.Matched {
+		return response
+	}
+	if !threshold.PerFile {
+		if len(response.Incidents) < threshold.MinIncidents {
+			response.Matched = false
+			response.Incidents = nil
+		}
+		return response
+	}
+	perFile := map[string]int{}
+	for _, incident := range response.Incidents {
+		perFile[string(incident.FileURI)]++
+	}
+	kept := []IncidentContext{}
+	for _, incident := range response.Incidents {
+		if perFile[string(incident.FileURI)] >= threshold.MinIncidents {
+			kept = append(kept, incident)
+		}
+	}
+	response.Incidents = kept
+	response.Matched = len(kept) > 0
+	return response
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRuleEngineThreshold(t *testing.T) {
+	message := "found"
+	line := func(l int) *int { return &l }
+	incidents := []IncidentContext{
+		{FileURI: "file:///a.java", LineNumber: line(1)},
+		{FileURI: "file:///a.java", LineNumber: line(2)},
+		{FileURI: "file:///b.java", LineNumber: line(1)},
+	}
+	rule := func(id string, threshold *Threshold) Rule {
+		return Rule{
+			RuleMeta:  RuleMeta{RuleID: id},
+			Perform:   Perform{Message: Message{Text: &message}},
+			When:      testIncidentsConditional{incidents: incidents},
+			Threshold: threshold,
+		}
+	}
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{
+			rule("no-threshold", nil),
+			rule("total-reached", &Threshold{MinIncidents: 3}),
+			rule("total-not-reached", &Threshold{MinIncidents: 4}),
+			rule("per-file", &Threshold{MinIncidents: 2, PerFile: true}),
+			rule("per-file-not-reached", &Threshold{MinIncidents: 3, PerFile: true}),
+		},
+	}}
+	ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard())
+	defer ruleEngine.Stop()
+	results := ruleEngine.RunRules(context.Background(), ruleSets)
+	if len(results) != 1 {
+		t.Fatalf("expected one ruleset, got %+v", results)
+	}
+	gotIncidents := map[string][]string{}
+	for id, v := range results[0].Violations {
+		for _, i := range v.Incidents {
+			gotIncidents[id] = append(gotIncidents[id], string(i.URI))
+		}
+	}
+	wantIncidents := map[string][]string{
+		"no-threshold":  {"file:///a.java", "file:///a.java", "file:///b.java"},
+		"total-reached": {"file:///a.java", "file:///a.java", "file:///b.java"},
+		"per-file":      {"file:///a.java", "file:///a.java"},
+	}
+	if !reflect.DeepEqual(gotIncidents, wantIncidents) {
+		t.Errorf("expected incidents %v, got %v", wantIncidents, gotIncidents)
+	}
+	wantUnmatched := []string{"per-file-not-reached", "total-not-reached"}
+	if !reflect.DeepEqual(results[0].Unmatched, wantUnmatched) {
+		t.Errorf("expected unmatched rules %v, got %v", wantUnmatched, results[0].Unmatched)
+	}
+}
+
+func TestThresholdValidate(t *testing.T) {
+	if err := (&Threshold{MinIncidents: 1}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (&Threshold{}).Validate(); err == nil {
+		t.Errorf("expected an error for a threshold without minIncidents")
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/redact"
+)
+
+type traceKey struct{}
+
+// conditionTracer records the evaluation of a node of the conditions of a
+// rule, it is nil when the rule is not traced.
+type conditionTracer struct {
+	node  *konveyor.ConditionTrace
+	start time.Time
+}
+
+// startTrace returns a context in which the conditions of a rule are traced
+// under the returned node.
+func startTrace(ctx context.Context, when Conditional) (context.Context, *conditionTracer) {
+	t := &conditionTracer{
+		node:  newConditionTrace(when),
+		start: time.Now(),
+	}
+	return context.WithValue(ctx, traceKey{}, t.node), t
+}
+
+// startCondition adds a node for the condition entry to the trace of the
+// context, when the rule is traced.
+func startCondition(ctx context.Context, entry ConditionEntry) (context.Context, *conditionTracer) {
+	parent, ok := ctx.Value(traceKey{}).(*konveyor.ConditionTrace)
+	if !ok {
+		return ctx, nil
+	}
+	t := &conditionTracer{
+		node:  newConditionTrace(entry),
+		start: time.Now(),
+	}
+	parent.Conditions = append(parent.Conditions, t.node)
+	return context.WithValue(ctx, traceKey{}, t.node), t
+}
+
+func newConditionTrace(c Conditional) *konveyor.ConditionTrace {
+	node := &konveyor.ConditionTrace{}
+	if entry, ok := c.(ConditionEntry); ok {
+		node.From = entry.From
+		node.As = entry.As
+		node.Not = entry.Not
+		c = entry.ProviderSpecificConfig
+	}
+	switch c.(type) {
+	case AndCondition:
+		node.Condition = "and"
+	case OrCondition:
+		node.Condition = "or"
+	default:
+		// providers name their conditions with TraceQuery
+		node.Condition = "condition"
+	}
+	return node
+}
+
+// end records the outcome of the condition
+func (t *conditionTracer) end(matched bool, incidents int, err error) {
+	if t == nil {
+		return
+	}
+	t.node.Matched = matched
+	t.node.Incidents = incidents
+	t.node.Duration = time.Since(t.start).String()
+	if err != nil {
+		t.node.Error = redact.String(err.Error())
+	}
+}
+
+// TraceQuery records the name of a condition and the query it sent to its
+// provider when the rule is traced, providers call it from their Evaluate.
+func TraceQuery(ctx context.Context, condition string, query string) {
+	node, ok := ctx.Value(traceKey{}).(*konveyor.ConditionTrace)
+	if !ok {
+		return
+	}
+	node.Condition = condition
+	node.Query = redact.String(query)
+}
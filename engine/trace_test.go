@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testQueryConditional struct {
+	query     string
+	incidents int
+	err       error
+}
+
+func (t testQueryConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	TraceQuery(ctx, "test.query", t.query)
+	response := ConditionResponse{Matched: t.incidents > 0}
+	for i := 0; i < t.incidents; i++ {
+		response.Incidents = append(response.Incidents, IncidentContext{FileURI: "file:///test.java"})
+	}
+	return response, t.err
+}
+
+func TestRuleEngineTrace(t *testing.T) {
+	message := "message"
+	ruleSets := []RuleSet{
+		{
+			Name: "ruleset",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "and-rule"},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When: AndCondition{
+						Conditions: []ConditionEntry{
+							{
+								As:                     "found",
+								ProviderSpecificConfig: testQueryConditional{query: "a", incidents: 2},
+							},
+							{
+								From: "found",
+								Not:  true,
+								ProviderSpecificConfig: OrCondition{
+									Conditions: []ConditionEntry{
+										{ProviderSpecificConfig: testQueryConditional{query: "b"}},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "failed-rule"},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     ConditionEntry{ProviderSpecificConfig: testQueryConditional{query: "c", err: fmt.Errorf("provider failed")}},
+				},
+			},
+		},
+	}
+	want := []konveyor.RuleTrace{
+		{
+			RuleSet:   "ruleset",
+			Rule:      "and-rule",
+			Matched:   true,
+			Incidents: 2,
+			Condition: &konveyor.ConditionTrace{
+				Condition: "and",
+				Matched:   true,
+				Incidents: 2,
+				Conditions: []*konveyor.ConditionTrace{
+					{Condition: "test.query", Query: "a", As: "found", Matched: true, Incidents: 2},
+					{
+						Condition: "or",
+						From:      "found",
+						Not:       true,
+						Matched:   true,
+						Conditions: []*konveyor.ConditionTrace{
+							{Condition: "test.query", Query: "b"},
+						},
+					},
+				},
+			},
+		},
+		{
+			RuleSet: "ruleset",
+			Rule:    "failed-rule",
+			Error:   "provider failed",
+			Condition: &konveyor.ConditionTrace{
+				Condition: "test.query",
+				Query:     "c",
+				Error:     "provider failed",
+			},
+		},
+	}
+
+	for _, trace := range []bool{false, true} {
+		eng := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithTrace(trace))
+		eng.RunRules(context.Background(), ruleSets)
+		got := eng.Traces()
+		eng.Stop()
+		if !trace {
+			if len(got) != 0 {
+				t.Errorf("expected no traces when not tracing, got %v", got)
+			}
+			continue
+		}
+		for i := range got {
+			if got[i].Duration == "" {
+				t.Errorf("expected a duration for %s", got[i].Rule)
+			}
+			got[i].Duration = ""
+			clearDurations(got[i].Condition)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected traces %s, got %s", formatTraces(want), formatTraces(got))
+		}
+	}
+}
+
+func clearDurations(c *konveyor.ConditionTrace) {
+	if c == nil {
+		return
+	}
+	c.Duration = ""
+	for _, child := range c.Conditions {
+		clearDurations(child)
+	}
+}
+
+func formatTraces(traces []konveyor.RuleTrace) string {
+	s := ""
+	for _, t := range traces {
+		s += fmt.Sprintf("%+v ", t)
+		var format func(c *konveyor.ConditionTrace)
+		format = func(c *konveyor.ConditionTrace) {
+			if c == nil {
+				return
+			}
+			s += fmt.Sprintf("%+v ", *c)
+			for _, child := range c.Conditions {
+				format(child)
+			}
+		}
+		format(t.Condition)
+	}
+	return s
+}
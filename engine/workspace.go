@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+// Workspace is the state of the analyzed files that is saved along with a
+// checkpoint. When the files changed since, a resumed analysis evaluates the
+// rules of the checkpoint again on the changed files only, and keeps their
+// results on the other files.
+type Workspace interface {
+	Snapshot() ([]byte, error)
+	// Changes returns what changed since the snapshot, nil when nothing did
+	Changes(snapshot []byte) (WorkspaceChanges, error)
+}
+
+// WorkspaceChanges are the files that changed since a checkpoint
+type WorkspaceChanges interface {
+	// Paths are the changed files and directories
+	Paths() []string
+	// Scope limits the conditions to the changed files, nil when all the
+	// files have to be searched again
+	Scope() *Scope
+	// Contains returns whether the file of the URI changed
+	Contains(u uri.URI) bool
+}
+
+// WithWorkspace saves the workspace along with the checkpoint, and only
+// evaluates the rules of the checkpoint again on the changed files when
+// resuming
+func WithWorkspace(w Workspace) Option {
+	return func(engine *ruleEngine) {
+		engine.workspace = w
+	}
+}
+
+// keep splits the results of the checkpoint once the workspace changed: the
+// incidents in the files that did not change are kept aside to be merged
+// with the results of the rules evaluated again, the rest is dropped.
+func (c *checkpoint) keep(saved konveyor.RuleSet) {
+	if c.kept == nil {
+		c.kept = map[string]map[string]konveyor.Violation{}
+	}
+	kept := map[string]konveyor.Violation{}
+	for id, v := range saved.Violations {
+		incidents := []konveyor.Incident{}
+		for _, i := range v.Incidents {
+			if !c.changes.Contains(i.URI) {
+				incidents = append(incidents, i)
+			}
+		}
+		if len(incidents) != 0 {
+			v.Incidents = incidents
+			kept[id] = v
+		}
+	}
+	c.kept[saved.Name] = kept
+}
+
+// reevaluated returns whether the rule was evaluated in the checkpoint and
+// has to be evaluated again on the changed files
+func (c *checkpoint) reevaluated(rules map[string][]string, ruleSetName, ruleID string) bool {
+	return c.changes != nil && c.evaluated(rules, ruleSetName, ruleID)
+}
+
+// changedContext limits the condition context to the changed files
+func (c *checkpoint) changedContext(condCtx ConditionContext) ConditionContext {
+	condCtx.Scope = narrowScope(condCtx.Scope, c.changes.Scope())
+	return condCtx
+}
+
+// narrowScope returns the scope of the files in both scopes. The globs to
+// include of both can not be combined, the changed files are then found in
+// the results instead.
+func narrowScope(scope, changed *Scope) *Scope {
+	if scope == nil {
+		return changed
+	}
+	if changed == nil {
+		return scope
+	}
+	narrowed := &Scope{
+		Include: scope.Include,
+		Exclude: append(append([]string{}, scope.Exclude...), changed.Exclude...),
+	}
+	if len(narrowed.Include) == 0 {
+		narrowed.Include = changed.Include
+	}
+	return narrowed
+}
+
+// mergeKept merges the result of a rule of the checkpoint that was evaluated
+// again, its incidents in the changed files, with its incidents in the other
+// files
+func (c *checkpoint) mergeKept(rs *konveyor.RuleSet, ruleID string) {
+	if rs == nil || !c.reevaluated(c.Rules, rs.Name, ruleID) {
+		return
+	}
+	if _, failed := rs.Errors[ruleID]; failed {
+		return
+	}
+	kept, hasKept := c.kept[rs.Name][ruleID]
+	v, matched := rs.Violations[ruleID]
+	incidents := []konveyor.Incident{}
+	if hasKept {
+		incidents = append(incidents, kept.Incidents...)
+		if !matched {
+			v = kept
+		}
+	}
+	if matched {
+		for _, i := range v.Incidents {
+			if c.changes.Contains(i.URI) {
+				incidents = append(incidents, i)
+			}
+		}
+	}
+	if len(incidents) == 0 {
+		if matched {
+			delete(rs.Violations, ruleID)
+			rs.Unmatched = append(rs.Unmatched, ruleID)
+		}
+		return
+	}
+	if !matched {
+		unmatched := []string{}
+		for _, id := range rs.Unmatched {
+			if id != ruleID {
+				unmatched = append(unmatched, id)
+			}
+		}
+		rs.Unmatched = unmatched
+	}
+	v.Incidents = incidents
+	rs.Violations[ruleID] = v
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+type testWorkspace struct {
+	version string
+	changed []uri.URI
+}
+
+func (w testWorkspace) Snapshot() ([]byte, error) {
+	return []byte(`"` + w.version + `"`), nil
+}
+
+func (w testWorkspace) Changes(snapshot []byte) (WorkspaceChanges, error) {
+	if string(snapshot) == `"`+w.version+`"` {
+		return nil, nil
+	}
+	return testChanges{changed: w.changed}, nil
+}
+
+type testChanges struct {
+	changed []uri.URI
+}
+
+func (c testChanges) Paths() []string {
+	paths := []string{}
+	for _, u := range c.changed {
+		paths = append(paths, u.Filename())
+	}
+	return paths
+}
+
+func (c testChanges) Scope() *Scope {
+	return &Scope{Include: c.Paths()}
+}
+
+func (c testChanges) Contains(u uri.URI) bool {
+	for _, changed := range c.changed {
+		if u == changed {
+			return true
+		}
+	}
+	return false
+}
+
+// testFilesConditional finds the incidents of the current files, whatever the
+// scope, and records the scopes it was evaluated with
+type testFilesConditional struct {
+	mutex     *sync.Mutex
+	incidents *[]IncidentContext
+	scopes    *[]*Scope
+}
+
+func (t testFilesConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	*t.scopes = append(*t.scopes, condCtx.Scope)
+	return ConditionResponse{Matched: len(*t.incidents) != 0, Incidents: *t.incidents}, nil
+}
+
+func TestRuleEngineIncremental(t *testing.T) {
+	message := "found"
+	mutex := &sync.Mutex{}
+	scopes := []*Scope{}
+	unchanged := IncidentContext{FileURI: "file:///app/Unchanged.java"}
+	changed := IncidentContext{FileURI: "file:///app/Changed.java"}
+	incidents := map[string]*[]IncidentContext{
+		"kept":    {unchanged, changed},
+		"gone":    {changed},
+		"new":     {},
+		"missing": {},
+	}
+	rules := []Rule{}
+	for _, id := range []string{"kept", "gone", "new", "missing"} {
+		rules = append(rules, Rule{
+			RuleMeta: RuleMeta{RuleID: id},
+			Perform:  Perform{Message: Message{Text: &message}},
+			When:     testFilesConditional{mutex: mutex, incidents: incidents[id], scopes: &scopes},
+		})
+	}
+	ruleSets := []RuleSet{{Name: "test", Rules: rules}}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	run := func(w Workspace) konveyor.RuleSet {
+		scopes = []*Scope{}
+		ruleEngine := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithCheckpoint(path, time.Hour), WithResume(true), WithWorkspace(w))
+		defer ruleEngine.Stop()
+		results := ruleEngine.RunRules(context.Background(), ruleSets)
+		if len(results) != 1 {
+			t.Fatalf("expected one ruleset, got %d", len(results))
+		}
+		return results[0]
+	}
+	uris := func(v konveyor.Violation) []string {
+		u := []string{}
+		for _, i := range v.Incidents {
+			u = append(u, string(i.URI))
+		}
+		sort.Strings(u)
+		return u
+	}
+
+	run(testWorkspace{version: "1"})
+	if len(scopes) != 4 {
+		t.Fatalf("expected all the rules to be evaluated, got %d evaluations", len(scopes))
+	}
+
+	// nothing changed
+	first := run(testWorkspace{version: "1"})
+	if len(scopes) != 0 {
+		t.Errorf("expected the rules not to be evaluated when nothing changed, got %d evaluations", len(scopes))
+	}
+	if !reflect.DeepEqual(uris(first.Violations["kept"]), []string{"file:///app/Changed.java", "file:///app/Unchanged.java"}) {
+		t.Errorf("expected the violations of the checkpoint, got %+v", first.Violations)
+	}
+
+	*incidents["kept"] = []IncidentContext{unchanged, {FileURI: "file:///app/Changed.java", LineNumber: new(int)}}
+	*incidents["gone"] = []IncidentContext{}
+	*incidents["new"] = []IncidentContext{changed}
+	second := run(testWorkspace{version: "2", changed: []uri.URI{"file:///app/Changed.java"}})
+	if len(scopes) != 4 {
+		t.Fatalf("expected all the rules to be evaluated again, got %d evaluations", len(scopes))
+	}
+	for _, s := range scopes {
+		if s == nil || !reflect.DeepEqual(s.Include, []string{"/app/Changed.java"}) {
+			t.Errorf("expected the rules to be evaluated on the changed files, got scope %+v", s)
+		}
+	}
+	if got := uris(second.Violations["kept"]); !reflect.DeepEqual(got, []string{"file:///app/Changed.java", "file:///app/Unchanged.java"}) {
+		t.Errorf("expected the incidents of the unchanged files to be kept once, got %v", got)
+	}
+	if got := uris(second.Violations["new"]); !reflect.DeepEqual(got, []string{"file:///app/Changed.java"}) {
+		t.Errorf("expected a violation in the changed file, got %v", got)
+	}
+	sort.Strings(second.Unmatched)
+	if !reflect.DeepEqual(second.Unmatched, []string{"gone", "missing"}) {
+		t.Errorf("expected the rules without incidents to be unmatched, got %v", second.Unmatched)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	composerJSON = "composer.json"
+	composerLock = "composer.lock"
+
+	// This will communicate that, the dep is downloadable and not vendored.
+	composerDownloadableDepSourceLabel = "downloadable"
+	// composerLocalDepSourceLabel is the source of the packages of path
+	// repositories, which are in the project
+	composerLocalDepSourceLabel = "local"
+)
+
+// composerManifest is the part of composer.json the dependencies are read from
+type composerManifest struct {
+	Require    map[string]string `json:"require"`
+	RequireDev map[string]string `json:"require-dev"`
+}
+
+// composerPackage is a package locked in composer.lock
+type composerPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	Source  *struct {
+		Reference string `json:"reference"`
+	} `json:"source"`
+	Dist *struct {
+		Type      string `json:"type"`
+		Reference string `json:"reference"`
+		Shasum    string `json:"shasum"`
+	} `json:"dist"`
+	License []string `json:"license"`
+}
+
+type composerLockFile struct {
+	Packages    []composerPackage `json:"packages"`
+	PackagesDev []composerPackage `json:"packages-dev"`
+}
+
+// isPlatformPackage returns whether a requirement is php, one of its
+// extensions or libraries, or composer itself, which are not dependencies
+func isPlatformPackage(name string) bool {
+	name = strings.ToLower(name)
+	return name == "php" || name == "php-64bit" || name == "hhvm" || name == "composer" ||
+		name == "composer-plugin-api" || name == "composer-runtime-api" ||
+		strings.HasPrefix(name, "ext-") || strings.HasPrefix(name, "lib-")
+}
+
+// GetDependencies returns the dependencies of the composer project of the
+// directory. The packages locked in composer.lock are the dependencies, the
+// ones that composer.json does not require are indirect. The requirements of
+// composer.json are the dependencies with their constraint as version when
+// the project is not locked. Nothing is returned without composer.json.
+func GetDependencies(dir string, licenses bool) ([]*provider.Dep, error) {
+	content, err := os.ReadFile(filepath.Join(dir, composerJSON))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := composerManifest{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", composerJSON, err)
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, composerLock))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifestDependencies(manifest), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := composerLockFile{}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", composerLock, err)
+	}
+	return lockedDependencies(manifest, lock, licenses), nil
+}
+
+// manifestDependencies returns the requirements of composer.json, sorted by
+// name
+func manifestDependencies(manifest composerManifest) []*provider.Dep {
+	deps := []*provider.Dep{}
+	for _, requirements := range []struct {
+		require map[string]string
+		dev     bool
+	}{{manifest.Require, false}, {manifest.RequireDev, true}} {
+		names := []string{}
+		for name := range requirements.require {
+			if !isPlatformPackage(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, &provider.Dep{
+				Name:    name,
+				Version: requirements.require[name],
+				Labels:  depLabels(composerDownloadableDepSourceLabel),
+				Extras:  map[string]interface{}{"dev": requirements.dev},
+			})
+		}
+	}
+	return deps
+}
+
+// lockedDependencies returns the packages of composer.lock, in the order
+// they are locked in
+func lockedDependencies(manifest composerManifest, lock composerLockFile, licenses bool) []*provider.Dep {
+	deps := []*provider.Dep{}
+	for _, packages := range []struct {
+		packages []composerPackage
+		dev      bool
+	}{{lock.Packages, false}, {lock.PackagesDev, true}} {
+		for _, p := range packages.packages {
+			_, required := manifest.Require[p.Name]
+			_, requiredDev := manifest.RequireDev[p.Name]
+			source := composerDownloadableDepSourceLabel
+			if p.Dist != nil && p.Dist.Type == "path" {
+				source = composerLocalDepSourceLabel
+			}
+			dep := &provider.Dep{
+				Name:               p.Name,
+				Version:            normalizeVersion(p.Version),
+				Type:               p.Type,
+				Indirect:           !required && !requiredDev,
+				ResolvedIdentifier: resolvedIdentifier(p),
+				Labels:             depLabels(source),
+				Extras:             map[string]interface{}{"dev": packages.dev},
+			}
+			if constraint, ok := manifest.Require[p.Name]; ok {
+				dep.Extras["constraint"] = constraint
+			} else if constraint, ok := manifest.RequireDev[p.Name]; ok {
+				dep.Extras["constraint"] = constraint
+			}
+			if licenses {
+				found := map[string]bool{}
+				for _, l := range p.License {
+					found[provider.NormalizeLicense(l)] = true
+				}
+				dep.License = provider.JoinLicenses(found)
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// normalizeVersion removes the v of the tags the versions are often locked
+// with, v5.4.1 is 5.4.1, so that they compare as the versions of the other
+// languages. The branches, such as dev-main, are kept.
+func normalizeVersion(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && version[1] >= '0' && version[1] <= '9' {
+		return version[1:]
+	}
+	return version
+}
+
+// resolvedIdentifier returns the commit of the source of a package, or the
+// checksum of its archive when it has no source
+func resolvedIdentifier(p composerPackage) string {
+	if p.Source != nil && p.Source.Reference != "" {
+		return p.Source.Reference
+	}
+	if p.Dist != nil {
+		if p.Dist.Reference != "" {
+			return p.Dist.Reference
+		}
+		return p.Dist.Shasum
+	}
+	return ""
+}
+
+func depLabels(source string) []string {
+	return []string{
+		labels.AsString(provider.DepSourceLabel, source),
+		labels.AsString(provider.DepLanguageLabel, "php"),
+	}
+}
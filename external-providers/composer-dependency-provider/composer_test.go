@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const testComposerJSON = `{
+    "name": "acme/app",
+    "require": {
+        "php": ">=8.1",
+        "ext-json": "*",
+        "symfony/http-kernel": "^6.2",
+        "acme/tools": "*"
+    },
+    "require-dev": {
+        "phpunit/phpunit": "^10.0"
+    }
+}`
+
+const testComposerLock = `{
+    "packages": [
+        {
+            "name": "acme/tools",
+            "version": "dev-main",
+            "dist": {"type": "path", "url": "../tools", "reference": "f00d"},
+            "license": ["proprietary"]
+        },
+        {
+            "name": "psr/log",
+            "version": "3.0.0",
+            "type": "library",
+            "dist": {"type": "zip", "shasum": "fe5ea303b0887d5caefd3d431c3e61ad47037001"},
+            "license": ["MIT"]
+        },
+        {
+            "name": "symfony/http-kernel",
+            "version": "v6.2.7",
+            "type": "library",
+            "source": {"type": "git", "reference": "ca0680ad1e2d678536cc20e0ae33f9e4e5d2becd"},
+            "license": ["MIT"]
+        }
+    ],
+    "packages-dev": [
+        {
+            "name": "phpunit/phpunit",
+            "version": "10.0.19",
+            "type": "library",
+            "source": {"type": "git", "reference": "20c23e85c86e5c06d63538ba464e8054f4744e62"},
+            "license": ["BSD-3-Clause"]
+        }
+    ]
+}`
+
+func labelsOf(source string) []string {
+	return []string{
+		"konveyor.io/dep-source=" + source,
+		"konveyor.io/language=php",
+	}
+}
+
+func TestGetDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		licenses bool
+		want     []*provider.Dep
+		wantErr  bool
+	}{
+		{
+			name: "a directory without composer.json has no dependencies",
+		},
+		{
+			name:  "the requirements are the dependencies without a lock",
+			files: map[string]string{composerJSON: testComposerJSON},
+			want: []*provider.Dep{
+				{Name: "acme/tools", Version: "*", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "symfony/http-kernel", Version: "^6.2", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "phpunit/phpunit", Version: "^10.0", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": true}},
+			},
+		},
+		{
+			name:     "the locked packages are the dependencies",
+			files:    map[string]string{composerJSON: testComposerJSON, composerLock: testComposerLock},
+			licenses: true,
+			want: []*provider.Dep{
+				{
+					Name:               "acme/tools",
+					Version:            "dev-main",
+					ResolvedIdentifier: "f00d",
+					Labels:             labelsOf("local"),
+					Extras:             map[string]interface{}{"dev": false, "constraint": "*"},
+					License:            provider.NormalizeLicense("proprietary"),
+				},
+				{
+					Name:               "psr/log",
+					Version:            "3.0.0",
+					Type:               "library",
+					Indirect:           true,
+					ResolvedIdentifier: "fe5ea303b0887d5caefd3d431c3e61ad47037001",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false},
+					License:            "MIT",
+				},
+				{
+					Name:               "symfony/http-kernel",
+					Version:            "6.2.7",
+					Type:               "library",
+					ResolvedIdentifier: "ca0680ad1e2d678536cc20e0ae33f9e4e5d2becd",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false, "constraint": "^6.2"},
+					License:            "MIT",
+				},
+				{
+					Name:               "phpunit/phpunit",
+					Version:            "10.0.19",
+					Type:               "library",
+					ResolvedIdentifier: "20c23e85c86e5c06d63538ba464e8054f4744e62",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": true, "constraint": "^10.0"},
+					License:            "BSD-3-Clause",
+				},
+			},
+		},
+		{
+			name:    "an invalid lock is an error",
+			files:   map[string]string{composerJSON: testComposerJSON, composerLock: "{"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := GetDependencies(dir, tt.licenses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d dependencies, want %d: %#v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("got dependency %#v, want %#v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// Prints the dependencies of the composer project of the working directory
+// for the generic provider, the ones locked in composer.lock or the ones
+// required by composer.json when it is not locked
+func main() {
+	deps, err := GetDependencies(".", os.Getenv(provider.DependencyLicensesEnv) == "true")
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(deps) == 0 {
+		return
+	}
+
+	m := map[uri.URI][]*provider.Dep{
+		uri.File(composerJSON): deps,
+	}
+	jsonStr, err := json.Marshal(m)
+	if err != nil {
+		log.Fatal(fmt.Errorf("unable to marshal dependencies"))
+		return
+	}
+
+	// Outputs the dependency list for the generic provider
+	fmt.Println(string(jsonStr))
+}
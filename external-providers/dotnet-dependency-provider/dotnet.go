@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	packagesLock          = "packages.lock.json"
+	directoryPackagesProp = "Directory.Packages.props"
+
+	// This will communicate that, the dep is downloadable and not vendored.
+	dotnetDownloadableDepSourceLabel = "downloadable"
+	// dotnetLocalDepSourceLabel is the source of the projects the project
+	// references, which are in the repository
+	dotnetLocalDepSourceLabel = "local"
+)
+
+// projectExtensions are the extensions of the MSBuild projects of the C#, F#
+// and Visual Basic applications
+var projectExtensions = map[string]bool{".csproj": true, ".fsproj": true, ".vbproj": true}
+
+// msbuildProject is the part of an MSBuild project, or of
+// Directory.Packages.props, the dependencies are read from
+type msbuildProject struct {
+	ItemGroups []struct {
+		PackageReferences []packageReference `xml:"PackageReference"`
+		PackageVersions   []packageReference `xml:"PackageVersion"`
+		ProjectReferences []struct {
+			Include string `xml:"Include,attr"`
+		} `xml:"ProjectReference"`
+	} `xml:"ItemGroup"`
+}
+
+// packageReference is a PackageReference item of a project, or a
+// PackageVersion item of Directory.Packages.props. The version is an
+// attribute or a child element.
+type packageReference struct {
+	Include         string `xml:"Include,attr"`
+	Update          string `xml:"Update,attr"`
+	Version         string `xml:"Version,attr"`
+	VersionElement  string `xml:"Version"`
+	VersionOverride string `xml:"VersionOverride,attr"`
+}
+
+func (r packageReference) name() string {
+	if r.Include != "" {
+		return r.Include
+	}
+	return r.Update
+}
+
+func (r packageReference) version() string {
+	switch {
+	case r.VersionOverride != "":
+		return r.VersionOverride
+	case r.Version != "":
+		return r.Version
+	}
+	return strings.TrimSpace(r.VersionElement)
+}
+
+// project is the packages and the projects a project references
+type project struct {
+	// packages are the versions the packages are referenced with, by name
+	packages map[string]string
+	// projects are the names of the referenced projects, the names of their
+	// file without extension, in lower case
+	projects map[string]bool
+}
+
+// ProjectFiles returns the MSBuild projects of the directory, sorted by name
+func ProjectFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && projectExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// GetDependencies returns the dependencies of the project file of the
+// directory. The packages locked in its packages.lock.json are the
+// dependencies, the transitive ones are indirect. The packages the project
+// references are the dependencies when it is not locked, with their version
+// or the one of Directory.Packages.props when the versions are managed
+// centrally.
+func GetDependencies(dir, projectFile string, licenses bool) ([]*provider.Dep, error) {
+	p, err := readProject(filepath.Join(dir, projectFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.packages) > 0 {
+		central, err := centralVersions(dir)
+		if err != nil {
+			return nil, err
+		}
+		for name, version := range p.packages {
+			if version == "" {
+				p.packages[name] = central[strings.ToLower(name)]
+			}
+		}
+	}
+
+	var deps []*provider.Dep
+	lockPath, err := lockFile(dir, projectFile)
+	if err != nil {
+		return nil, err
+	}
+	if lockPath == "" {
+		deps = projectDependencies(p)
+	} else {
+		lock, err := readLock(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		deps = lockedDependencies(p, lock)
+	}
+	if licenses {
+		addLicenses(deps, globalPackagesDir())
+	}
+	return deps, nil
+}
+
+// readProject reads the packages and the projects a project references
+func readProject(path string) (project, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return project{}, err
+	}
+	parsed := msbuildProject{}
+	if err := xml.Unmarshal(content, &parsed); err != nil {
+		return project{}, fmt.Errorf("unable to parse %s: %w", filepath.Base(path), err)
+	}
+	p := project{packages: map[string]string{}, projects: map[string]bool{}}
+	for _, group := range parsed.ItemGroups {
+		for _, ref := range group.PackageReferences {
+			if name := ref.name(); name != "" {
+				p.packages[name] = ref.version()
+			}
+		}
+		for _, ref := range group.ProjectReferences {
+			// the paths of the projects have \ separators, even on linux
+			name := filepath.Base(strings.ReplaceAll(ref.Include, `\`, "/"))
+			p.projects[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))] = true
+		}
+	}
+	return p, nil
+}
+
+// centralVersions returns the versions of the packages of the
+// Directory.Packages.props of the directory or of the closest directory above
+// it, by name in lower case, none when there is no such file
+func centralVersions(dir string) (map[string]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		content, err := os.ReadFile(filepath.Join(dir, directoryPackagesProp))
+		if err == nil {
+			parsed := msbuildProject{}
+			if err := xml.Unmarshal(content, &parsed); err != nil {
+				return nil, fmt.Errorf("unable to parse %s: %w", directoryPackagesProp, err)
+			}
+			versions := map[string]string{}
+			for _, group := range parsed.ItemGroups {
+				for _, v := range group.PackageVersions {
+					versions[strings.ToLower(v.name())] = v.version()
+				}
+			}
+			return versions, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// lockFile returns the lock file of the project, packages.<project>.lock.json
+// when the projects of the directory share it, or else packages.lock.json. It
+// is empty when the project is not locked.
+func lockFile(dir, projectFile string) (string, error) {
+	name := strings.TrimSuffix(projectFile, filepath.Ext(projectFile))
+	for _, file := range []string{"packages." + name + ".lock.json", packagesLock} {
+		path := filepath.Join(dir, file)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// projectDependencies returns the packages the project references, sorted by
+// name, with the version they are referenced with
+func projectDependencies(p project) []*provider.Dep {
+	names := []string{}
+	for name := range p.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	deps := []*provider.Dep{}
+	for _, name := range names {
+		deps = append(deps, &provider.Dep{
+			Name:    name,
+			Version: p.packages[name],
+			Labels:  depLabels(dotnetDownloadableDepSourceLabel),
+		})
+	}
+	return deps
+}
+
+func depLabels(source string) []string {
+	return []string{
+		labels.AsString(provider.DepSourceLabel, source),
+		labels.AsString(provider.DepLanguageLabel, "dotnet"),
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const testProject = `<Project Sdk="Microsoft.NET.Sdk">
+  <PropertyGroup>
+    <TargetFrameworks>net6.0;net8.0</TargetFrameworks>
+  </PropertyGroup>
+  <ItemGroup>
+    <PackageReference Include="Newtonsoft.Json" Version="13.0.1" />
+    <PackageReference Include="Serilog">
+      <Version>3.1.1</Version>
+    </PackageReference>
+    <PackageReference Include="Dapper" />
+  </ItemGroup>
+  <ItemGroup>
+    <ProjectReference Include="..\Acme.Core\Acme.Core.csproj" />
+  </ItemGroup>
+</Project>`
+
+const testDirectoryPackages = `<Project>
+  <ItemGroup>
+    <PackageVersion Include="Dapper" Version="2.1.24" />
+  </ItemGroup>
+</Project>`
+
+const testLock = `{
+  "version": 1,
+  "dependencies": {
+    "net6.0": {
+      "Newtonsoft.Json": {
+        "type": "Direct",
+        "requested": "[13.0.1, )",
+        "resolved": "13.0.1",
+        "contentHash": "ppPFpBcvxdsfUonNcvITKqLl3bqxWbDCZIzDWHzjpdAHRFfZe0Dw9HmA0+za13IdyrgJwpkDTDA9fHaxOrt20A=="
+      },
+      "Serilog": {
+        "type": "Direct",
+        "requested": "[3.1.1, )",
+        "resolved": "3.1.1",
+        "contentHash": "P6G4/4Kt9bT635bhuwdXlJ2SCqqn2nhh4gqFqQueCOr9bK/e7W9ll/IoX1Ter948cV2Z/5+5v8pAfJYUISY03A=="
+      },
+      "System.Memory": {
+        "type": "Transitive",
+        "resolved": "4.5.5",
+        "contentHash": "XIWiDvKPXaTveaB7HVganDlOCRoj03l+jrwNvcge/t8vhGYKvqV+dMv6G4SAX2NoNmN0wZfVPTAlFwZcZvVOUw=="
+      },
+      "acme.core": {
+        "type": "Project"
+      },
+      "acme.util": {
+        "type": "Project"
+      }
+    },
+    "net8.0": {
+      "Newtonsoft.Json": {
+        "type": "Direct",
+        "requested": "[13.0.1, )",
+        "resolved": "13.0.1",
+        "contentHash": "ppPFpBcvxdsfUonNcvITKqLl3bqxWbDCZIzDWHzjpdAHRFfZe0Dw9HmA0+za13IdyrgJwpkDTDA9fHaxOrt20A=="
+      },
+      "Serilog": {
+        "type": "Direct",
+        "requested": "[3.1.1, )",
+        "resolved": "3.1.1",
+        "contentHash": "P6G4/4Kt9bT635bhuwdXlJ2SCqqn2nhh4gqFqQueCOr9bK/e7W9ll/IoX1Ter948cV2Z/5+5v8pAfJYUISY03A=="
+      }
+    }
+  }
+}`
+
+const testNewtonsoftNuspec = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata minClientVersion="2.12">
+    <id>Newtonsoft.Json</id>
+    <version>13.0.1</version>
+    <license type="expression">MIT</license>
+    <licenseUrl>https://licenses.nuget.org/MIT</licenseUrl>
+  </metadata>
+</package>`
+
+const testSerilogNuspec = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata>
+    <id>Serilog</id>
+    <version>3.1.1</version>
+    <license type="file">LICENSE</license>
+  </metadata>
+</package>`
+
+func labelsOf(source string) []string {
+	return []string{
+		"konveyor.io/dep-source=" + source,
+		"konveyor.io/language=dotnet",
+	}
+}
+
+func TestGetDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		project  string
+		licenses bool
+		want     []*provider.Dep
+		wantErr  bool
+	}{
+		{
+			name: "the references are the dependencies without a lock",
+			files: map[string]string{
+				"app/Acme.App.csproj": testProject,
+				directoryPackagesProp: testDirectoryPackages,
+			},
+			project: "Acme.App.csproj",
+			want: []*provider.Dep{
+				{Name: "Dapper", Version: "2.1.24", Labels: labelsOf("downloadable")},
+				{Name: "Newtonsoft.Json", Version: "13.0.1", Labels: labelsOf("downloadable")},
+				{Name: "Serilog", Version: "3.1.1", Labels: labelsOf("downloadable")},
+			},
+		},
+		{
+			name: "the locked packages are the dependencies",
+			files: map[string]string{
+				"app/Acme.App.csproj": testProject,
+				"app/" + packagesLock: testLock,
+				"packages/newtonsoft.json/13.0.1/newtonsoft.json.nuspec": testNewtonsoftNuspec,
+				"packages/serilog/3.1.1/serilog.nuspec":                  testSerilogNuspec,
+				"packages/serilog/3.1.1/LICENSE":                         "Apache License\nVersion 2.0, January 2004",
+			},
+			project:  "Acme.App.csproj",
+			licenses: true,
+			want: []*provider.Dep{
+				{
+					Name:     "acme.core",
+					Labels:   labelsOf("local"),
+					Extras:   map[string]interface{}{"targetFrameworks": []string{"net6.0"}},
+					Indirect: false,
+				},
+				{
+					Name:     "acme.util",
+					Labels:   labelsOf("local"),
+					Extras:   map[string]interface{}{"targetFrameworks": []string{"net6.0"}},
+					Indirect: true,
+				},
+				{
+					Name:               "Newtonsoft.Json",
+					Version:            "13.0.1",
+					ResolvedIdentifier: "ppPFpBcvxdsfUonNcvITKqLl3bqxWbDCZIzDWHzjpdAHRFfZe0Dw9HmA0+za13IdyrgJwpkDTDA9fHaxOrt20A==",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"targetFrameworks": []string{"net6.0", "net8.0"}, "constraint": "[13.0.1, )"},
+					License:            "MIT",
+				},
+				{
+					Name:               "Serilog",
+					Version:            "3.1.1",
+					ResolvedIdentifier: "P6G4/4Kt9bT635bhuwdXlJ2SCqqn2nhh4gqFqQueCOr9bK/e7W9ll/IoX1Ter948cV2Z/5+5v8pAfJYUISY03A==",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"targetFrameworks": []string{"net6.0", "net8.0"}, "constraint": "[3.1.1, )"},
+					License:            "Apache-2.0",
+				},
+				{
+					Name:               "System.Memory",
+					Version:            "4.5.5",
+					Indirect:           true,
+					ResolvedIdentifier: "XIWiDvKPXaTveaB7HVganDlOCRoj03l+jrwNvcge/t8vhGYKvqV+dMv6G4SAX2NoNmN0wZfVPTAlFwZcZvVOUw==",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"targetFrameworks": []string{"net6.0"}},
+				},
+			},
+		},
+		{
+			name: "the lock file of the project is the one named after it",
+			files: map[string]string{
+				"app/Acme.App.csproj":               testProject,
+				"app/Acme.Tests.csproj":             `<Project Sdk="Microsoft.NET.Sdk" />`,
+				"app/packages.Acme.Tests.lock.json": `{"version": 1, "dependencies": {"net8.0": {"xunit": {"type": "Direct", "requested": "[2.6.2, )", "resolved": "2.6.2", "contentHash": "x"}}}}`,
+			},
+			project: "Acme.Tests.csproj",
+			want: []*provider.Dep{
+				{
+					Name:               "xunit",
+					Version:            "2.6.2",
+					ResolvedIdentifier: "x",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"targetFrameworks": []string{"net8.0"}, "constraint": "[2.6.2, )"},
+				},
+			},
+		},
+		{
+			name: "an invalid lock is an error",
+			files: map[string]string{
+				"app/Acme.App.csproj": testProject,
+				"app/" + packagesLock: "{",
+			},
+			project: "Acme.App.csproj",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				file := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("NUGET_PACKAGES", filepath.Join(dir, "packages"))
+			got, err := GetDependencies(filepath.Join(dir, "app"), tt.project, tt.licenses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d dependencies, want %d: %#v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("got dependency %#v, want %#v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProjectFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.fsproj", "a.csproj", "c.vbproj", "a.sln", "Directory.Build.props"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("<Project />"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := ProjectFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.csproj", "b.fsproj", "c.vbproj"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got the projects %v, want %v", got, want)
+	}
+}
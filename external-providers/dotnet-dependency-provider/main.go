@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// Prints the dependencies of the .NET projects of the working directory for
+// the generic provider, the ones locked in their packages.lock.json or the
+// ones they reference when they are not locked
+func main() {
+	files, err := ProjectFiles(".")
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	licenses := os.Getenv(provider.DependencyLicensesEnv) == "true"
+	m := map[uri.URI][]*provider.Dep{}
+	for _, file := range files {
+		deps, err := GetDependencies(".", file, licenses)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+		if len(deps) != 0 {
+			m[uri.File(file)] = deps
+		}
+	}
+	if len(m) == 0 {
+		return
+	}
+
+	jsonStr, err := json.Marshal(m)
+	if err != nil {
+		log.Fatal(fmt.Errorf("unable to marshal dependencies"))
+		return
+	}
+
+	// Outputs the dependency list for the generic provider
+	fmt.Println(string(jsonStr))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// nugetLock is a packages.lock.json, the packages locked for each target
+// framework of the project
+type nugetLock struct {
+	Version      int                                    `json:"version"`
+	Dependencies map[string]map[string]nugetLockedEntry `json:"dependencies"`
+}
+
+// nugetLockedEntry is a package, or a referenced project, of a target
+// framework. Its type is Direct, Transitive, CentralTransitive or Project.
+type nugetLockedEntry struct {
+	Type        string `json:"type"`
+	Requested   string `json:"requested"`
+	Resolved    string `json:"resolved"`
+	ContentHash string `json:"contentHash"`
+}
+
+func readLock(path string) (nugetLock, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nugetLock{}, err
+	}
+	lock := nugetLock{}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nugetLock{}, fmt.Errorf("unable to parse %s: %w", filepath.Base(path), err)
+	}
+	return lock, nil
+}
+
+// lockedDependencies returns the packages and the projects of the lock file,
+// sorted by name. A version of a package that is locked for several target
+// frameworks is returned once, with all of them in the targetFrameworks
+// extra. The projects are indirect unless the project references them.
+func lockedDependencies(p project, lock nugetLock) []*provider.Dep {
+	frameworks := []string{}
+	for framework := range lock.Dependencies {
+		frameworks = append(frameworks, framework)
+	}
+	sort.Strings(frameworks)
+	byKey := map[string]*provider.Dep{}
+	deps := []*provider.Dep{}
+	for _, framework := range frameworks {
+		for name, entry := range lock.Dependencies[framework] {
+			key := strings.ToLower(name) + "@" + entry.Resolved
+			if dep, ok := byKey[key]; ok {
+				dep.Extras["targetFrameworks"] = append(dep.Extras["targetFrameworks"].([]string), framework)
+				continue
+			}
+			dep := &provider.Dep{
+				Name:               name,
+				Version:            entry.Resolved,
+				Indirect:           entry.Type != "Direct",
+				ResolvedIdentifier: entry.ContentHash,
+				Labels:             depLabels(dotnetDownloadableDepSourceLabel),
+				Extras:             map[string]interface{}{"targetFrameworks": []string{framework}},
+			}
+			if entry.Type == "Project" {
+				dep.Indirect = !p.projects[strings.ToLower(name)]
+				dep.Labels = depLabels(dotnetLocalDepSourceLabel)
+			}
+			if entry.Requested != "" {
+				dep.Extras["constraint"] = entry.Requested
+			}
+			byKey[key] = dep
+			deps = append(deps, dep)
+		}
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if !strings.EqualFold(deps[i].Name, deps[j].Name) {
+			return strings.ToLower(deps[i].Name) < strings.ToLower(deps[j].Name)
+		}
+		return deps[i].Version < deps[j].Version
+	})
+	return deps
+}
+
+// globalPackagesDir returns the directory the packages are restored to,
+// NUGET_PACKAGES or the .nuget/packages directory of the home of the user
+func globalPackagesDir() string {
+	if dir := os.Getenv("NUGET_PACKAGES"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".nuget", "packages")
+}
+
+// nuspec is the part of the manifest of a package its license is read from
+type nuspec struct {
+	Metadata struct {
+		License struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"license"`
+		LicenseURL string `xml:"licenseUrl"`
+	} `xml:"metadata"`
+}
+
+// addLicenses sets the license of the packages from their nuspec in the global
+// packages directory, the license expression, the license file or else the
+// license URL. The packages that are not restored and the projects are left
+// without a license.
+func addLicenses(deps []*provider.Dep, packagesDir string) {
+	if packagesDir == "" {
+		return
+	}
+	for _, d := range deps {
+		if d.Version == "" {
+			continue
+		}
+		d.License = packageLicense(filepath.Join(packagesDir, strings.ToLower(d.Name), strings.ToLower(d.Version)), strings.ToLower(d.Name))
+	}
+}
+
+// packageLicense returns the license of the restored package of the
+// directory, empty when it is not restored or has none
+func packageLicense(dir, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name+".nuspec"))
+	if err != nil {
+		return ""
+	}
+	spec := nuspec{}
+	if err := xml.Unmarshal(content, &spec); err != nil {
+		return ""
+	}
+	license := strings.TrimSpace(spec.Metadata.License.Value)
+	switch {
+	case license != "" && spec.Metadata.License.Type == "file":
+		text, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(license)))
+		if err != nil {
+			return ""
+		}
+		return provider.ClassifyLicenseText(string(text))
+	case license != "":
+		// the expressions are SPDX already, such as MIT OR Apache-2.0
+		return license
+	case spec.Metadata.LicenseURL != "":
+		return provider.NormalizeLicense(spec.Metadata.LicenseURL)
+	}
+	return ""
+}
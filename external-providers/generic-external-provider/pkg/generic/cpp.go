@@ -0,0 +1,407 @@
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// COMPILE_COMMANDS_DIR_CONFIG_KEY is the directory of the compile_commands.json
+// of the location, which clangd is started with and the included headers are
+// resolved with. It defaults to the location, or to its build directory, when
+// they have one.
+const COMPILE_COMMANDS_DIR_CONFIG_KEY = "compileCommandsDir"
+
+const compileCommandsFile = "compile_commands.json"
+
+// cppExtensions are the extensions of the c and c++ files the included
+// condition reads
+var cppExtensions = map[string]bool{
+	".c": true, ".cc": true, ".cpp": true, ".cxx": true, ".c++": true,
+	".h": true, ".hh": true, ".hpp": true, ".hxx": true, ".h++": true,
+	".ipp": true, ".inl": true, ".tcc": true, ".m": true, ".mm": true,
+}
+
+// includeRegex matches the include directives, the header is between <> for
+// the system headers and between quotes for the local ones
+var includeRegex = regexp.MustCompile(`^\s*#\s*(?:include|include_next|import)\s*([<"])([^>"]+)[>"]`)
+
+// includedCondition matches the include directives of the c and c++ files, as
+// in #include <openssl/ssl.h>
+type includedCondition struct {
+	// Header is a glob of the header as it is written in the directive, *
+	// matches any string but /, ** any string, as in boost/**
+	Header string `yaml:"header"`
+	// Kind is system to only match the headers included with <>, or local
+	// to only match the ones included with quotes
+	Kind string `yaml:"kind"`
+}
+
+// compileCommand is an entry of compile_commands.json
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Command   string   `json:"command"`
+	Arguments []string `json:"arguments"`
+}
+
+// includeDirs are the directories the headers are searched in, as the
+// compiler does, the quote ones are only searched for the local headers
+type includeDirs struct {
+	quote  []string
+	angled []string
+}
+
+// compileCommandsDir returns the directory of the compile_commands.json of the
+// config, the one set in the provider specific config, or the location or its
+// build directory when they have one. It is empty when there is none.
+func compileCommandsDir(config provider.InitConfig) string {
+	if dir, ok := config.ProviderSpecificConfig[COMPILE_COMMANDS_DIR_CONFIG_KEY].(string); ok && dir != "" {
+		return dir
+	}
+	for _, dir := range []string{config.Location, filepath.Join(config.Location, "build")} {
+		if _, err := os.Stat(filepath.Join(dir, compileCommandsFile)); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// clangdArgs adds the compile commands directory of the config to the
+// arguments of clangd, unless they already have one
+func clangdArgs(lspServerPath string, args []string, config provider.InitConfig) []string {
+	if !strings.HasPrefix(filepath.Base(lspServerPath), "clangd") {
+		return args
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--compile-commands-dir") {
+			return args
+		}
+	}
+	dir := compileCommandsDir(config)
+	if dir == "" {
+		return args
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return append(args, "--compile-commands-dir="+dir)
+}
+
+// splitCommand splits the command of a compile command in arguments, as a
+// shell does with the quotes and the backslashes
+func splitCommand(command string) []string {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range command {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// commandIncludeDirs returns the include directories of the arguments of a
+// compile command, relative to its directory
+func commandIncludeDirs(command compileCommand) includeDirs {
+	args := command.Arguments
+	if len(args) == 0 {
+		args = splitCommand(command.Command)
+	}
+	dirs := includeDirs{}
+	abs := func(dir string) string {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(command.Directory, dir)
+		}
+		return filepath.Clean(dir)
+	}
+	for i := 0; i < len(args); i++ {
+		for _, flag := range []string{"-iquote", "-isystem", "-idirafter", "-I"} {
+			if !strings.HasPrefix(args[i], flag) {
+				continue
+			}
+			dir := strings.TrimPrefix(args[i], flag)
+			if dir == "" && i+1 < len(args) {
+				i++
+				dir = args[i]
+			}
+			if dir == "" {
+				break
+			}
+			if flag == "-iquote" {
+				dirs.quote = append(dirs.quote, abs(dir))
+			} else {
+				dirs.angled = append(dirs.angled, abs(dir))
+			}
+			break
+		}
+	}
+	return dirs
+}
+
+// compileCommandsIncludeDirs returns the include directories of the files of
+// compile_commands.json by absolute path. The headers are not in the compile
+// commands, the include directories of all the files are used for them, which
+// is the "" key.
+func compileCommandsIncludeDirs(dir string) (map[string]includeDirs, error) {
+	content, err := os.ReadFile(filepath.Join(dir, compileCommandsFile))
+	if err != nil {
+		return nil, err
+	}
+	commands := []compileCommand{}
+	if err := json.Unmarshal(content, &commands); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", compileCommandsFile, err)
+	}
+	files := map[string]includeDirs{}
+	all := includeDirs{}
+	seen := map[string]bool{}
+	for _, command := range commands {
+		file := command.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(command.Directory, file)
+		}
+		dirs := commandIncludeDirs(command)
+		files[filepath.Clean(file)] = dirs
+		for _, d := range dirs.quote {
+			if !seen["q"+d] {
+				seen["q"+d] = true
+				all.quote = append(all.quote, d)
+			}
+		}
+		for _, d := range dirs.angled {
+			if !seen["a"+d] {
+				seen["a"+d] = true
+				all.angled = append(all.angled, d)
+			}
+		}
+	}
+	files[""] = all
+	return files, nil
+}
+
+// resolveHeader returns the path of the header included by a file, the local
+// headers are searched next to the file first
+func resolveHeader(file, header string, local bool, dirs includeDirs) string {
+	candidates := []string{}
+	if local {
+		candidates = append(candidates, filepath.Dir(file))
+		candidates = append(candidates, dirs.quote...)
+	}
+	candidates = append(candidates, dirs.angled...)
+	for _, dir := range candidates {
+		path := filepath.Join(dir, filepath.FromSlash(header))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// headerGlobRegex returns the regex of a header glob, * matches any string but
+// / and ** any string
+func headerGlobRegex(glob string) (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(glob)
+	expr = strings.ReplaceAll(expr, `\*\*`, "\x00")
+	expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+	expr = strings.ReplaceAll(expr, `\?`, `[^/]`)
+	expr = strings.ReplaceAll(expr, "\x00", `.*`)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// cppInclude is an include directive of a file
+type cppInclude struct {
+	header string
+	local  bool
+	// line is the 1-based line of the directive
+	line int
+}
+
+// cppIncludes returns the include directives of a file that are not in a
+// comment, the directives can follow a comment that is closed on their line
+func cppIncludes(content []byte) []cppInclude {
+	includes := []cppInclude{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	inComment := false
+	for line := 1; scanner.Scan(); line++ {
+		var text string
+		text, inComment = stripComments(scanner.Text(), inComment)
+		if match := includeRegex.FindStringSubmatch(text); match != nil {
+			includes = append(includes, cppInclude{header: match[2], local: match[1] == `"`, line: line})
+		}
+	}
+	return includes
+}
+
+// stripComments replaces the comments of a line with spaces, as the
+// preprocessor does, and returns whether a block comment is still open at its
+// end. The comment markers in the string and character literals do not start
+// comments.
+func stripComments(line string, inComment bool) (string, bool) {
+	text := strings.Builder{}
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				inComment = false
+				i++
+				text.WriteByte(' ')
+			}
+		case quote != 0:
+			text.WriteByte(c)
+			if c == '\\' && i+1 < len(line) {
+				i++
+				text.WriteByte(line[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			text.WriteByte(c)
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return text.String(), false
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			inComment = true
+			i++
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return text.String(), inComment
+}
+
+// evaluateIncluded returns an incident for each include directive of the c and
+// c++ files of the roots that includes a header matching the condition, with
+// the path of the header when it is found with the include directories of
+// compile_commands.json
+func (p *genericServiceClient) evaluateIncluded(cond includedCondition) (provider.ProviderEvaluateResponse, error) {
+	if cond.Header == "" {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("the included condition needs a header")
+	}
+	switch cond.Kind {
+	case "", "system", "local":
+	default:
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("invalid kind %s, it is system or local", cond.Kind)
+	}
+	headerRegex, err := headerGlobRegex(cond.Header)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("invalid header %s: %w", cond.Header, err)
+	}
+	dirs := map[string]includeDirs{}
+	if dir := compileCommandsDir(p.config); dir != "" {
+		dirs, err = compileCommandsIncludeDirs(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+	}
+	incidents := []provider.IncidentContext{}
+	for _, root := range p.config.WalkRoots() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !cppExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			fileDirs, ok := dirs[abs]
+			if !ok {
+				fileDirs = dirs[""]
+			}
+			for _, include := range cppIncludes(content) {
+				if !headerRegex.MatchString(include.header) {
+					continue
+				}
+				kind := "system"
+				if include.local {
+					kind = "local"
+				}
+				if cond.Kind != "" && cond.Kind != kind {
+					continue
+				}
+				lineNumber := include.line
+				incident := provider.IncidentContext{
+					FileURI:    uri.File(abs),
+					LineNumber: &lineNumber,
+					Variables: map[string]interface{}{
+						"file":   string(uri.File(abs)),
+						"header": include.header,
+						"kind":   kind,
+					},
+					CodeLocation: &provider.Location{
+						StartPosition: provider.Position{Line: float64(lineNumber - 1)},
+						EndPosition:   provider.Position{Line: float64(lineNumber - 1)},
+					},
+				}
+				if resolved := resolveHeader(abs, include.header, include.local, fileDirs); resolved != "" {
+					incident.Variables["resolved"] = resolved
+				}
+				incidents = append(incidents, incident)
+			}
+			return nil
+		})
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to walk %s: %w", root, err)
+		}
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
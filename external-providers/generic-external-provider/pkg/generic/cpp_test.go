@@ -0,0 +1,177 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{
+			name:    "plain arguments",
+			command: "cc -c  -o main.o\tmain.c",
+			want:    []string{"cc", "-c", "-o", "main.o", "main.c"},
+		},
+		{
+			name:    "double quotes",
+			command: `cc "-DNAME=\"a b\"" -I"include dir" main.c`,
+			want:    []string{"cc", `-DNAME="a b"`, "-Iinclude dir", "main.c"},
+		},
+		{
+			name:    "single quotes keep the backslashes",
+			command: `cc '-DPATH=C:\dir' main.c`,
+			want:    []string{"cc", `-DPATH=C:\dir`, "main.c"},
+		},
+		{
+			name:    "escaped spaces",
+			command: `cc -I/opt/my\ headers main.c`,
+			want:    []string{"cc", "-I/opt/my headers", "main.c"},
+		},
+		{
+			name:    "empty quoted argument",
+			command: `cc "" main.c`,
+			want:    []string{"cc", "", "main.c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitCommand(tt.command); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommandIncludeDirs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command compileCommand
+		want    includeDirs
+	}{
+		{
+			name: "joined and separate directories",
+			command: compileCommand{
+				Directory: "/src/build",
+				Command:   "c++ -Iinclude -I ../third_party -isystem /usr/local/include -iquote local -c main.cpp",
+			},
+			want: includeDirs{
+				quote:  []string{"/src/build/local"},
+				angled: []string{"/src/build/include", "/src/third_party", "/usr/local/include"},
+			},
+		},
+		{
+			name: "quoted directories",
+			command: compileCommand{
+				Directory: "/src",
+				Command:   `cc -I"my headers" "-I/opt/other headers" main.c`,
+			},
+			want: includeDirs{
+				angled: []string{"/src/my headers", "/opt/other headers"},
+			},
+		},
+		{
+			name: "arguments take precedence over the command",
+			command: compileCommand{
+				Directory: "/src",
+				Command:   "cc -Iignored main.c",
+				Arguments: []string{"cc", "-I", "include", "-idirafter", "after", "main.c"},
+			},
+			want: includeDirs{
+				angled: []string{"/src/include", "/src/after"},
+			},
+		},
+		{
+			name: "a flag without directory is ignored",
+			command: compileCommand{
+				Directory: "/src",
+				Arguments: []string{"cc", "main.c", "-I"},
+			},
+			want: includeDirs{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandIncludeDirs(tt.command); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHeaderGlobRegex(t *testing.T) {
+	tests := []struct {
+		glob    string
+		header  string
+		matches bool
+	}{
+		{glob: "openssl/ssl.h", header: "openssl/ssl.h", matches: true},
+		{glob: "openssl/*.h", header: "openssl/ssl.h", matches: true},
+		{glob: "openssl/*.h", header: "openssl/internal/ssl.h", matches: false},
+		{glob: "boost/**", header: "boost/asio/ip/tcp.hpp", matches: true},
+		{glob: "boost/**", header: "boostx/asio.hpp", matches: false},
+		{glob: "ssl?.h", header: "ssl2.h", matches: true},
+		{glob: "ssl?.h", header: "ssl/.h", matches: false},
+		{glob: "c++/vector", header: "c++/vector", matches: true},
+		{glob: "c++/vector", header: "cc/vector", matches: false},
+	}
+	for _, tt := range tests {
+		regex, err := headerGlobRegex(tt.glob)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got := regex.MatchString(tt.header); got != tt.matches {
+			t.Errorf("expected %s matching %s to be %v, got %v", tt.glob, tt.header, tt.matches, got)
+		}
+	}
+}
+
+func TestCppIncludes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []cppInclude
+	}{
+		{
+			name:    "system and local headers",
+			content: "#include <stdio.h>\n  #  include \"util.h\"\n#import <Foundation/Foundation.h>\n",
+			want: []cppInclude{
+				{header: "stdio.h", line: 1},
+				{header: "util.h", local: true, line: 2},
+				{header: "Foundation/Foundation.h", line: 3},
+			},
+		},
+		{
+			name:    "after a comment closed on its line",
+			content: "/* license */ #include <a.h>\n/* multi\n line */ #include \"b.h\"\n",
+			want: []cppInclude{
+				{header: "a.h", line: 1},
+				{header: "b.h", local: true, line: 3},
+			},
+		},
+		{
+			name:    "in comments",
+			content: "// #include <a.h>\n/*\n#include <b.h>\n*/\n/* #include <c.h> */\n#include <d.h> // <e.h>\n",
+			want: []cppInclude{
+				{header: "d.h", line: 6},
+			},
+		},
+		{
+			name:    "comment markers in strings",
+			content: "const char *s = \"/*\";\n#include <a.h>\nchar c = '\"'; /* x\n#include <b.h>\n*/\n",
+			want: []cppInclude{
+				{header: "a.h", line: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cppIncludes([]byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
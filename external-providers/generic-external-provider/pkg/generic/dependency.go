@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/konveyor/analyzer-lsp/provider"
 	"go.lsp.dev/uri"
@@ -15,23 +17,60 @@ func (g *genericServiceClient) GetDependencies(ctx context.Context) (map[uri.URI
 	if !isString {
 		return nil, fmt.Errorf("dependency provider path is not a string")
 	}
-	// Expects dependency provider to output provider.Dep structs to stdout
-	cmd := exec.Command(cmdStr)
-	cmd.Dir = g.config.Location
-	dataR, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	data := string(dataR)
-	if len(data) == 0 {
-		return nil, nil
+	args := []string{}
+	if rawArgs, ok := g.config.ProviderSpecificConfig["dependencyProviderArgs"]; ok {
+		list, isArray := rawArgs.([]interface{})
+		if !isArray {
+			return nil, fmt.Errorf("dependencyProviderArgs is not an array")
+		}
+		for _, rawArg := range list {
+			arg, ok := rawArg.(string)
+			if !ok {
+				return nil, fmt.Errorf("item of dependencyProviderArgs is not a string")
+			}
+			args = append(args, arg)
+		}
 	}
+	// The dependency provider runs in each root, so that the dependencies are
+	// attributed to the module they are declared in
 	m := map[uri.URI][]*provider.Dep{}
-	err = json.Unmarshal([]byte(data), &m)
-	if err != nil {
-		return nil, err
+	for _, root := range g.config.Roots() {
+		// Expects dependency provider to output provider.Dep structs to stdout
+		cmd := exec.Command(cmdStr, args...)
+		cmd.Dir = root
+		cmd.Env = g.env
+		if provider.DependencyLicenses(g.config) {
+			env := g.env
+			if env == nil {
+				env = os.Environ()
+			}
+			cmd.Env = append(append([]string{}, env...), provider.DependencyLicensesEnv+"=true")
+		}
+		dataR, err := cmd.Output()
+		if err != nil {
+			// the failures to reach a registry are only in the output
+			if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) != 0 {
+				return nil, fmt.Errorf("dependency provider %s failed in %s: %w: %s", cmdStr, root, err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return nil, fmt.Errorf("dependency provider %s failed in %s: %w", cmdStr, root, err)
+		}
+		data := string(dataR)
+		if len(data) == 0 {
+			continue
+		}
+		rootDeps := map[uri.URI][]*provider.Dep{}
+		err = json.Unmarshal([]byte(data), &rootDeps)
+		if err != nil {
+			return nil, err
+		}
+		for file, deps := range rootDeps {
+			m[file] = append(m[file], deps...)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
 	}
-	return m, err
+	return m, nil
 }
 
 func (p *genericServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
@@ -0,0 +1,151 @@
+package generic
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// deprecatedDirective marks a symbol as deprecated, its notice follows it
+const deprecatedDirective = "//go:deprecated"
+
+// evaluateDeprecated returns the references to the symbols of the packages of
+// the condition that are documented as deprecated in their package, with the
+// deprecation notice in the deprecation variable
+func (p *genericServiceClient) evaluateDeprecated(ctx context.Context, cond deprecatedCondition) (provider.ProviderEvaluateResponse, error) {
+	if cond.Package == "" {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("the deprecated condition needs a package")
+	}
+	symbols, err := p.GetPackageSymbols(ctx, cond.Package, cond.Symbol)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	deprecated := []protocol.WorkspaceSymbol{}
+	notices := map[string]string{}
+	for _, s := range symbols {
+		notice, ok := p.deprecation(ctx, s.Location.Value.(protocol.Location))
+		if !ok {
+			continue
+		}
+		deprecated = append(deprecated, s)
+		notices[s.ContainerName+"."+symbolName(s)] = notice
+	}
+	return p.referenceIncidents(ctx, deprecated, false, "", func(s protocol.WorkspaceSymbol) map[string]interface{} {
+		return map[string]interface{}{
+			"package":     s.ContainerName,
+			"symbol":      symbolName(s),
+			"deprecation": notices[s.ContainerName+"."+symbolName(s)],
+		}
+	})
+}
+
+// deprecation returns the deprecation notice of the symbol declared at the
+// location, from the doc comment above it in its file, or from its hover
+// when the file can not be read, such as when it is not on disk
+func (p *genericServiceClient) deprecation(ctx context.Context, location protocol.Location) (string, bool) {
+	if u, err := uri.Parse(location.URI); err == nil {
+		if content, err := os.ReadFile(u.Filename()); err == nil {
+			return docDeprecation(docComment(string(content), int(location.Range.Start.Line)))
+		}
+	}
+	if !p.capabilities.Supports("textDocument/hover") {
+		return "", false
+	}
+	hover := protocol.Hover{}
+	err := p.rpc.Call(ctx, "textDocument/hover", &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: location.URI},
+			Position:     location.Range.Start,
+		},
+	}, &hover)
+	if err != nil {
+		return "", false
+	}
+	return docDeprecation(strings.Split(hover.Contents.Value, "\n"))
+}
+
+// docComment returns the lines of the comment right above a line of a file,
+// without their comment marker except for the directives. The comment is
+// either line comments, or a block comment such as a javadoc.
+func docComment(content string, line int) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for i := 0; scanner.Scan() && i < line; i++ {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) > 0 && strings.HasSuffix(strings.TrimSpace(lines[len(lines)-1]), "*/") {
+		return blockComment(lines)
+	}
+	doc := []string{}
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(l, "//") {
+			break
+		}
+		if !strings.HasPrefix(l, "//go:") {
+			l = strings.TrimSpace(strings.TrimPrefix(l, "//"))
+		}
+		doc = append([]string{l}, doc...)
+	}
+	return doc
+}
+
+// blockComment returns the lines of the block comment that ends the lines,
+// without the comment markers and the * that start its lines
+func blockComment(lines []string) []string {
+	doc := []string{}
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		start := strings.Index(l, "/*")
+		if start >= 0 {
+			l = l[start+2:]
+		}
+		l = strings.TrimSuffix(l, "*/")
+		l = strings.TrimSpace(strings.TrimLeft(l, "*"))
+		doc = append([]string{l}, doc...)
+		if start >= 0 {
+			return doc
+		}
+	}
+	// the comment is not opened
+	return nil
+}
+
+// docDeprecation returns the notice of the Deprecated: paragraph, of the
+// @deprecated tag or of the //go:deprecated directive of a doc comment. The
+// comments that only mention a deprecation in their text are not deprecation
+// notices.
+func docDeprecation(doc []string) (string, bool) {
+	for i, l := range doc {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, deprecatedDirective) {
+			return strings.TrimSpace(strings.TrimPrefix(l, deprecatedDirective)), true
+		}
+		var notice []string
+		switch {
+		case strings.HasPrefix(l, "@deprecated"):
+			notice = []string{strings.TrimPrefix(l, "@deprecated")}
+		case strings.HasPrefix(l, "Deprecated:") && (i == 0 || strings.TrimSpace(doc[i-1]) == ""):
+			// the notice is a paragraph of its own
+			notice = []string{strings.TrimPrefix(l, "Deprecated:")}
+		default:
+			continue
+		}
+		// the notice ends at the next blank line, directive or tag
+		for _, next := range doc[i+1:] {
+			next = strings.TrimSpace(next)
+			if next == "" || strings.HasPrefix(next, "//go:") || strings.HasPrefix(next, "@") {
+				break
+			}
+			notice = append(notice, next)
+		}
+		return strings.TrimSpace(strings.Join(notice, " ")), true
+	}
+	return "", false
+}
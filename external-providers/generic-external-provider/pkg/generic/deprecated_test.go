@@ -0,0 +1,102 @@
+package generic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		want    []string
+	}{
+		{
+			name:    "line comments",
+			content: "package a\n\n// Foo does things.\n//\n//go:deprecated use Bar\nfunc Foo() {}\n",
+			line:    5,
+			want:    []string{"Foo does things.", "", "//go:deprecated use Bar"},
+		},
+		{
+			name:    "javadoc",
+			content: "class A {\n    /**\n     * Does things.\n     *\n     * @deprecated use {@link #bar()}\n     */\n    void foo() {}\n}\n",
+			line:    6,
+			want:    []string{"", "Does things.", "", "@deprecated use {@link #bar()}", ""},
+		},
+		{
+			name:    "block comment on one line",
+			content: "/** @deprecated */\nfunction foo() {}\n",
+			line:    1,
+			want:    []string{"@deprecated"},
+		},
+		{
+			name:    "no comment",
+			content: "package a\n\nvar x = 1 // not a doc comment\nfunc Foo() {}\n",
+			line:    3,
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := docComment(tt.content, tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDocDeprecation(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		want       string
+		deprecated bool
+	}{
+		{
+			name:       "go Deprecated paragraph",
+			doc:        "Foo does things.\n\nDeprecated: Use Bar instead,\nwhich also does other things.\n\nMore text.",
+			want:       "Use Bar instead, which also does other things.",
+			deprecated: true,
+		},
+		{
+			name:       "go directive",
+			doc:        "Foo does things.\n//go:deprecated use Bar",
+			want:       "use Bar",
+			deprecated: true,
+		},
+		{
+			name:       "javadoc tag",
+			doc:        "Does things.\n\n@deprecated use {@link #bar()}\n  as of 2.0\n@param x the thing",
+			want:       "use {@link #bar()} as of 2.0",
+			deprecated: true,
+		},
+		{
+			name:       "tag without notice",
+			doc:        "@deprecated",
+			want:       "",
+			deprecated: true,
+		},
+		{
+			name: "deprecated in the text",
+			doc:  "Foo replaces the deprecated Bar.\nIt is not @deprecated itself.",
+		},
+		{
+			name: "Deprecated: that does not start a paragraph",
+			doc:  "Foo lists the symbols that are\nDeprecated: in the package.",
+		},
+		{
+			name: "no comment",
+			doc:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, deprecated := docDeprecation(strings.Split(tt.doc, "\n"))
+			if got != tt.want || deprecated != tt.deprecated {
+				t.Errorf("expected %q, %v, got %q, %v", tt.want, tt.deprecated, got, deprecated)
+			}
+		})
+	}
+}
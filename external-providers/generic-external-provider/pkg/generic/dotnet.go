@@ -0,0 +1,37 @@
+package generic
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// omnisharpArgs adds the arguments OmniSharp needs to run as a language
+// server of the location to its arguments, -lsp and the --source directory of
+// its projects, unless they already have them
+func omnisharpArgs(lspServerPath string, args []string, config provider.InitConfig) []string {
+	if !strings.HasPrefix(strings.ToLower(filepath.Base(lspServerPath)), "omnisharp") {
+		return args
+	}
+	lsp, source := false, false
+	for _, arg := range args {
+		switch {
+		case arg == "-lsp" || arg == "--languageserver":
+			lsp = true
+		case arg == "-s" || strings.HasPrefix(arg, "--source"):
+			source = true
+		}
+	}
+	if !lsp {
+		args = append(args, "-lsp")
+	}
+	if !source && config.Location != "" {
+		dir := config.Location
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		args = append(args, "--source", dir)
+	}
+	return args
+}
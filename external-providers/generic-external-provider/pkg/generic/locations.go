@@ -0,0 +1,326 @@
+package generic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"go.lsp.dev/uri"
+)
+
+// The locations of the referenced conditions, the kinds of code the
+// references are matched in as with the java provider. The references match
+// any location when the condition has none.
+const (
+	locationConstructorCall = "constructor_call"
+	locationMethodCall      = "method_call"
+	locationFieldAccess     = "field_access"
+	locationImport          = "import"
+	locationImplementsType  = "implements_type"
+	locationAnnotation      = "annotation"
+	locationReturnType      = "return_type"
+	locationTypeParameter   = "type_parameter"
+)
+
+var typeSymbolKinds = []protocol.SymbolKind{protocol.Class, protocol.Interface, protocol.Struct, protocol.Enum, protocol.TypeParameter}
+
+// locationSymbolKinds are the kinds of the symbols that can be referenced at
+// a location, the symbols of other kinds are not looked up for it. All the
+// symbols can be imported.
+var locationSymbolKinds = map[string][]protocol.SymbolKind{
+	locationConstructorCall: {protocol.Class, protocol.Struct, protocol.Constructor},
+	locationMethodCall:      {protocol.Method, protocol.Function},
+	locationFieldAccess:     {protocol.Field, protocol.Property, protocol.EnumMember, protocol.Constant, protocol.Variable},
+	locationImport:          nil,
+	locationImplementsType:  typeSymbolKinds,
+	// the decorators of python and typescript are functions
+	locationAnnotation:    append([]protocol.SymbolKind{protocol.Function}, typeSymbolKinds...),
+	locationReturnType:    typeSymbolKinds,
+	locationTypeParameter: typeSymbolKinds,
+}
+
+// referenceLocation returns the location of the condition in lower case, an
+// error when it is not one the generic provider knows
+func referenceLocation(location string) (string, error) {
+	location = strings.ToLower(location)
+	if _, ok := locationSymbolKinds[location]; location != "" && !ok {
+		names := []string{}
+		for name := range locationSymbolKinds {
+			names = append(names, strings.ToUpper(name))
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("invalid location %s, it is one of %s", strings.ToUpper(location), strings.Join(names, ", "))
+	}
+	return location, nil
+}
+
+// symbolAtLocation returns whether the symbol can be referenced at the
+// location, the symbols of which the language server does not give the kind
+// can be referenced anywhere
+func symbolAtLocation(location string, s protocol.WorkspaceSymbol) bool {
+	kinds := locationSymbolKinds[location]
+	if kinds == nil || s.Kind == 0 {
+		return true
+	}
+	for _, kind := range kinds {
+		if s.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// languageSyntax is the syntax of a language the locations of the references
+// are told apart with, from the text of their line
+type languageSyntax struct {
+	// imports matches the lines that import names
+	imports *regexp.Regexp
+	// implements matches the text before the types that a type declaration
+	// implements or extends
+	implements *regexp.Regexp
+	// returnType matches the text before the return types that follow the
+	// parameters, as in python and typescript
+	returnType *regexp.Regexp
+	// typeBeforeName is whether the return types come before the name of the
+	// function, as in c and c#
+	typeBeforeName bool
+	// annotation is the text the annotations, decorators or attributes
+	// start with
+	annotation string
+	// newKeyword is whether the constructors are called with new
+	newKeyword bool
+	// constructorCall matches the text after a type that creates a value of
+	// the type without new, such as a call in python or a composite literal
+	// in go
+	constructorCall *regexp.Regexp
+	// typeArguments is the bracket the type arguments are in, 0 when the
+	// language has none
+	typeArguments byte
+}
+
+var (
+	goSyntax = languageSyntax{
+		// the lines of an import block only have the imported path and its
+		// name
+		imports:         regexp.MustCompile(`^\s*(import\b|(\w+\s+)?"[^"]*"\s*$)`),
+		returnType:      regexp.MustCompile(`^\s*func\b.*\)\s*\(?[\w\s.,*\[\]]*$`),
+		constructorCall: regexp.MustCompile(`^\s*\{`),
+		typeArguments:   '[',
+	}
+	pythonSyntax = languageSyntax{
+		imports:         regexp.MustCompile(`^\s*(import|from)\b`),
+		implements:      regexp.MustCompile(`^\s*class\s+\w+\s*\(`),
+		returnType:      regexp.MustCompile(`\)\s*->\s*[\w.\[\], ]*$`),
+		annotation:      "@",
+		constructorCall: regexp.MustCompile(`^\s*\(`),
+		typeArguments:   '[',
+	}
+	typescriptSyntax = languageSyntax{
+		imports:       regexp.MustCompile(`^\s*import\b|\bfrom\s+['"]|\brequire\s*\(`),
+		implements:    regexp.MustCompile(`\b(implements|extends)\b`),
+		returnType:    regexp.MustCompile(`\)\s*:\s*[\w.<>\[\], |]*$`),
+		annotation:    "@",
+		newKeyword:    true,
+		typeArguments: '<',
+	}
+	phpSyntax = languageSyntax{
+		imports:    regexp.MustCompile(`^\s*use\b|\b(require|include)(_once)?\b`),
+		implements: regexp.MustCompile(`\b(implements|extends)\b`),
+		returnType: regexp.MustCompile(`\)\s*:\s*\??[\w\\|]*$`),
+		annotation: "#[",
+		newKeyword: true,
+	}
+	cppSyntax = languageSyntax{
+		imports:        regexp.MustCompile(`^\s*(#\s*include|using|import)\b`),
+		implements:     regexp.MustCompile(`\b(class|struct)\s+\w+\s*(final\s*)?:[^:]`),
+		typeBeforeName: true,
+		newKeyword:     true,
+		typeArguments:  '<',
+	}
+	csharpSyntax = languageSyntax{
+		imports:        regexp.MustCompile(`^\s*(global\s+)?using\b`),
+		implements:     regexp.MustCompile(`\b(class|struct|interface|record)\s+\w+.*:`),
+		typeBeforeName: true,
+		annotation:     "[",
+		newKeyword:     true,
+		typeArguments:  '<',
+	}
+	// javaSyntax is the syntax of the languages derived from java and c
+	javaSyntax = languageSyntax{
+		imports:        regexp.MustCompile(`^\s*import\b`),
+		implements:     regexp.MustCompile(`\b(implements|extends)\b|\bclass\s+\w+.*\)?\s*:`),
+		returnType:     regexp.MustCompile(`\)\s*:\s*[\w.<>?, ]*$`),
+		typeBeforeName: true,
+		annotation:     "@",
+		newKeyword:     true,
+		typeArguments:  '<',
+	}
+)
+
+// languageSyntaxes are the syntaxes of the languages by file extension, the
+// other files have javaSyntax
+var languageSyntaxes = map[string]languageSyntax{
+	".go":    goSyntax,
+	".py":    pythonSyntax,
+	".pyi":   pythonSyntax,
+	".js":    typescriptSyntax,
+	".jsx":   typescriptSyntax,
+	".mjs":   typescriptSyntax,
+	".cjs":   typescriptSyntax,
+	".ts":    typescriptSyntax,
+	".tsx":   typescriptSyntax,
+	".php":   phpSyntax,
+	".phtml": phpSyntax,
+	".inc":   phpSyntax,
+	".c":     cppSyntax,
+	".cc":    cppSyntax,
+	".cpp":   cppSyntax,
+	".cxx":   cppSyntax,
+	".h":     cppSyntax,
+	".hh":    cppSyntax,
+	".hpp":   cppSyntax,
+	".hxx":   cppSyntax,
+	".cs":    csharpSyntax,
+}
+
+var (
+	// qualifierSuffix matches the package, namespace or type that qualifies
+	// a name, such as the javax.ejb. of javax.ejb.Stateless
+	qualifierSuffix = regexp.MustCompile(`([\w$]+\s*(\.|::|\\)\s*)+$|\\$`)
+	newSuffix       = regexp.MustCompile(`\bnew\s*$`)
+	callPrefix      = regexp.MustCompile(`^\s*(<[^()]*>)?\s*\(`)
+	accessSuffix    = regexp.MustCompile(`(\.|->|::|\?\.)\s*$`)
+	// declarationPrefix matches the text after a return type that comes
+	// before the name of a function, the name and the parameters
+	declarationPrefix = regexp.MustCompile(`^\s*(<[^()]*>)?(\[\s*\])*[\s*&]*[A-Za-z_]\w*\s*\(`)
+	assignmentSuffix  = regexp.MustCompile(`(=|\breturn|\bnew)\s*$`)
+)
+
+// referenceAt returns whether the reference of the range of the line is at
+// the location, according to the syntax of the language of the file
+func referenceAt(location, file, line string, r protocol.Range, s protocol.WorkspaceSymbol) bool {
+	syntax, ok := languageSyntaxes[strings.ToLower(filepath.Ext(file))]
+	if !ok {
+		syntax = javaSyntax
+	}
+	start := byteOffset(line, int(r.Start.Character))
+	end := len(line)
+	if r.End.Line == r.Start.Line {
+		end = byteOffset(line, int(r.End.Character))
+	}
+	if start > end {
+		return false
+	}
+	before, after := line[:start], line[end:]
+	// the text before the qualified name, new or @ for new javax.ejb.Stateless
+	// and @javax.ejb.Stateless
+	unqualified := strings.TrimRight(qualifierSuffix.ReplaceAllString(before, ""), " \t")
+	isType := s.Kind == protocol.Class || s.Kind == protocol.Struct || s.Kind == protocol.Constructor
+	constructorCall := (syntax.newKeyword && newSuffix.MatchString(unqualified)) ||
+		(syntax.constructorCall != nil && isType && syntax.constructorCall.MatchString(after))
+
+	isImport := syntax.imports != nil && syntax.imports.MatchString(line)
+
+	switch location {
+	case locationImport:
+		return isImport
+	case locationConstructorCall:
+		return constructorCall
+	case locationMethodCall:
+		return !constructorCall && !isType && callPrefix.MatchString(after)
+	case locationFieldAccess:
+		return !isImport && !callPrefix.MatchString(after) && (accessSuffix.MatchString(before) ||
+			s.Kind == protocol.Field || s.Kind == protocol.Property || s.Kind == protocol.EnumMember)
+	case locationImplementsType:
+		return syntax.implements != nil && syntax.implements.MatchString(before)
+	case locationAnnotation:
+		if syntax.annotation == "" {
+			return false
+		}
+		if strings.HasSuffix(unqualified, syntax.annotation) {
+			return true
+		}
+		// the attributes of c# are listed in the brackets, as in [Serializable, Obsolete]
+		return syntax.annotation == "[" && strings.HasPrefix(strings.TrimSpace(line), "[") && strings.HasSuffix(unqualified, ",")
+	case locationReturnType:
+		if syntax.returnType != nil && syntax.returnType.MatchString(unqualified) {
+			return true
+		}
+		return syntax.typeBeforeName && declarationPrefix.MatchString(after) && !assignmentSuffix.MatchString(unqualified)
+	case locationTypeParameter:
+		return syntax.typeArguments != 0 && inTypeArguments(before, syntax.typeArguments)
+	}
+	return true
+}
+
+// inTypeArguments returns whether the end of the text is in the brackets of
+// type arguments, the ones that follow a name as in List<String> or
+// map[string]T
+func inTypeArguments(text string, open byte) bool {
+	closing := byte('>')
+	if open == '[' {
+		closing = ']'
+	}
+	depth := 0
+	for i := len(text) - 1; i >= 0; i-- {
+		switch text[i] {
+		case closing:
+			depth++
+		case open:
+			if depth > 0 {
+				depth--
+				continue
+			}
+			return i > 0 && isNameChar(text[i-1])
+		case '(', ')', '{', '}', ';', '=':
+			return false
+		}
+	}
+	return false
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// byteOffset returns the offset in bytes of the character of the line, the
+// characters of the language server protocol are utf-16 code units
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// fileLines reads the lines of the files of the references once
+type fileLines map[string][]string
+
+// line returns the line of the file of the URI, empty when it can not be
+// read
+func (f fileLines) line(u string, line int) string {
+	lines, ok := f[u]
+	if !ok {
+		path := u
+		if parsed, err := uri.Parse(u); err == nil {
+			path = parsed.Filename()
+		}
+		content, err := os.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		f[u] = lines
+	}
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
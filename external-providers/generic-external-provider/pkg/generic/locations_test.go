@@ -0,0 +1,161 @@
+package generic
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"go.lsp.dev/uri"
+)
+
+// symbolOfKind returns a workspace symbol of the kind
+func symbolOfKind(kind protocol.SymbolKind) protocol.WorkspaceSymbol {
+	s := protocol.WorkspaceSymbol{}
+	s.Kind = kind
+	return s
+}
+
+// rangeOf returns the range of the last occurrence of the name in the line,
+// with the utf-16 characters of the language server protocol
+func rangeOf(line, name string) protocol.Range {
+	start := strings.LastIndex(line, name)
+	character := uint32(len(utf16.Encode([]rune(line[:start]))))
+	return protocol.Range{
+		Start: protocol.Position{Character: character},
+		End:   protocol.Position{Character: character + uint32(len(utf16.Encode([]rune(name))))},
+	}
+}
+
+func TestReferenceLocation(t *testing.T) {
+	if location, err := referenceLocation("CONSTRUCTOR_CALL"); err != nil || location != locationConstructorCall {
+		t.Errorf("expected the location to be %s, got %q, %v", locationConstructorCall, location, err)
+	}
+	if location, err := referenceLocation(""); err != nil || location != "" {
+		t.Errorf("expected no location, got %q, %v", location, err)
+	}
+	if _, err := referenceLocation("INHERITANCE"); err == nil || !strings.Contains(err.Error(), "TYPE_PARAMETER") {
+		t.Errorf("expected an error listing the locations, got %v", err)
+	}
+}
+
+func TestSymbolAtLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		kind     protocol.SymbolKind
+		want     bool
+	}{
+		{location: locationConstructorCall, kind: protocol.Class, want: true},
+		{location: locationConstructorCall, kind: protocol.Method, want: false},
+		{location: locationMethodCall, kind: protocol.Function, want: true},
+		{location: locationFieldAccess, kind: protocol.Class, want: false},
+		{location: locationImport, kind: protocol.Variable, want: true},
+		{location: locationAnnotation, kind: protocol.Function, want: true},
+		// the servers that give no kind
+		{location: locationReturnType, kind: 0, want: true},
+	}
+	for _, tt := range tests {
+		if got := symbolAtLocation(tt.location, symbolOfKind(tt.kind)); got != tt.want {
+			t.Errorf("expected a symbol of kind %v at %s to be %v, got %v", tt.kind, tt.location, tt.want, got)
+		}
+	}
+}
+
+func TestReferenceAt(t *testing.T) {
+	tests := []struct {
+		location string
+		file     string
+		line     string
+		name     string
+		kind     protocol.SymbolKind
+		want     bool
+	}{
+		{location: locationConstructorCall, file: "A.java", line: "  Foo f = new Foo();", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationConstructorCall, file: "A.java", line: "  Foo f = new javax.ejb.Foo();", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationConstructorCall, file: "A.java", line: "  Foo f = create();", name: "Foo", kind: protocol.Class, want: false},
+		{location: locationConstructorCall, file: "a.go", line: "  x := pkg.T{A: 1}", name: "T", kind: protocol.Struct, want: true},
+		{location: locationConstructorCall, file: "a.py", line: "  s = Session()", name: "Session", kind: protocol.Class, want: true},
+		{location: locationMethodCall, file: "a.go", line: "  x := pkg.Bar(1)", name: "Bar", kind: protocol.Function, want: true},
+		{location: locationMethodCall, file: "a.py", line: "  s = Session()", name: "Session", kind: protocol.Class, want: false},
+		{location: locationFieldAccess, file: "a.ts", line: "  x = obj.field;", name: "field", want: true},
+		{location: locationFieldAccess, file: "A.java", line: "import a.b.Foo;", name: "Foo", kind: protocol.Class, want: false},
+		{location: locationImport, file: "a.py", line: "from a.b import Foo", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationImport, file: "a.go", line: `	log "github.com/sirupsen/logrus"`, name: "logrus", want: true},
+		{location: locationImport, file: "A.java", line: "  Foo f;", name: "Foo", kind: protocol.Class, want: false},
+		{location: locationImplementsType, file: "A.java", line: "class A extends B implements Foo {", name: "Foo", kind: protocol.Interface, want: true},
+		{location: locationImplementsType, file: "a.py", line: "class A(Base):", name: "Base", kind: protocol.Class, want: true},
+		{location: locationAnnotation, file: "A.java", line: "@javax.ejb.Stateless", name: "Stateless", kind: protocol.Class, want: true},
+		{location: locationAnnotation, file: "A.cs", line: "[Serializable, Obsolete]", name: "Obsolete", kind: protocol.Class, want: true},
+		{location: locationAnnotation, file: "a.go", line: "// @Deprecated", name: "Deprecated", want: false},
+		{location: locationReturnType, file: "A.java", line: "  public Foo bar(int x) {", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationReturnType, file: "A.java", line: "  Foo f = bar(x);", name: "Foo", kind: protocol.Class, want: false},
+		{location: locationReturnType, file: "a.py", line: "def f(x) -> Foo:", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationReturnType, file: "a.go", line: "func f(x int) (*Foo, error) {", name: "Foo", kind: protocol.Struct, want: true},
+		{location: locationTypeParameter, file: "A.java", line: "  List<Foo> l;", name: "Foo", kind: protocol.Class, want: true},
+		{location: locationTypeParameter, file: "a.go", line: "  var l List[Foo]", name: "Foo", kind: protocol.Struct, want: true},
+		{location: locationTypeParameter, file: "a.go", line: "  var l [4]Foo", name: "Foo", kind: protocol.Struct, want: false},
+		{location: locationTypeParameter, file: "a.php", line: "  $a = new Foo();", name: "Foo", kind: protocol.Class, want: false},
+		// the characters of the language server protocol are utf-16 code
+		// units, the emoji is 2 of them and 4 bytes
+		{location: locationConstructorCall, file: "a.ts", line: `  const s = "😀"; new Foo()`, name: "Foo", kind: protocol.Class, want: true},
+		{location: locationMethodCall, file: "a.ts", line: `  log("é😀", bar())`, name: "bar", kind: protocol.Function, want: true},
+		{location: locationTypeParameter, file: "A.java", line: `  Map<String, Foo> m = Map.of("😀", x);`, name: "Foo", kind: protocol.Class, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location+" "+tt.line, func(t *testing.T) {
+			got := referenceAt(tt.location, tt.file, tt.line, rangeOf(tt.line, tt.name), symbolOfKind(tt.kind))
+			if got != tt.want {
+				t.Errorf("expected %s of %s to be at %s: %v, got %v", tt.name, tt.file, tt.location, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReferenceAtSeveralLines(t *testing.T) {
+	// a reference that ends on a later line has the end of its first line
+	line := "  Object o = new javax.ejb."
+	r := protocol.Range{
+		Start: protocol.Position{Line: 3, Character: uint32(strings.Index(line, "javax"))},
+		End:   protocol.Position{Line: 4, Character: 9},
+	}
+	if !referenceAt(locationConstructorCall, "A.java", line, r, symbolOfKind(protocol.Class)) {
+		t.Errorf("expected the reference spanning two lines to be a constructor call")
+	}
+	if referenceAt(locationMethodCall, "A.java", line, r, symbolOfKind(protocol.Method)) {
+		t.Errorf("expected the reference spanning two lines not to be a method call, its call is on the next line")
+	}
+	// a range that starts after its end on the same line is not a reference
+	r = protocol.Range{Start: protocol.Position{Line: 3, Character: 10}, End: protocol.Position{Line: 3, Character: 2}}
+	if referenceAt(locationConstructorCall, "A.java", line, r, symbolOfKind(protocol.Class)) {
+		t.Errorf("expected an inverted range not to match")
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	line := "aé😀b"
+	for character, want := range map[int]int{0: 0, 1: 1, 2: 3, 4: 7, 5: 8, 10: 8} {
+		if got := byteOffset(line, character); got != want {
+			t.Errorf("expected the character %d to be at the byte %d, got %d", character, want, got)
+		}
+	}
+}
+
+func TestFileLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(path, []byte("package a\r\n\nfunc f() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines := fileLines{}
+	u := string(uri.File(path))
+	// the lines of the language server protocol are 0-based
+	for line, want := range map[int]string{0: "package a", 1: "", 2: "func f() {}", 3: "", 4: "", -1: ""} {
+		if got := lines.line(u, line); got != want {
+			t.Errorf("expected the line %d to be %q, got %q", line, want, got)
+		}
+	}
+	if got := lines.line(string(uri.File(filepath.Join(t.TempDir(), "missing.go"))), 0); got != "" {
+		t.Errorf("expected no line for a missing file, got %q", got)
+	}
+}
@@ -0,0 +1,369 @@
+package generic
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// phpExtensions are the extensions of the php files the namespaceUse
+// condition reads
+var phpExtensions = map[string]bool{".php": true, ".phtml": true, ".inc": true}
+
+// phpSkippedDirs are the directories of the dependencies and of the version
+// control, the uses of the project are not in them
+var phpSkippedDirs = map[string]bool{"vendor": true, ".git": true, "node_modules": true}
+
+// namespaceUseCondition matches the use declarations that import a namespace,
+// or the classes, functions and constants under it, as in
+// use Symfony\Component\HttpFoundation\Request;
+type namespaceUseCondition struct {
+	// Namespace is the namespace or the name the imported names are, or are
+	// under, such as Symfony\Component, it is not case sensitive as php
+	// names are not
+	Namespace string `yaml:"namespace"`
+	// Kind is class, function or const to only match the imports of that
+	// kind, class also matches the imports of namespaces, interfaces and
+	// traits, all the imports match when it is empty
+	Kind string `yaml:"kind"`
+}
+
+// phpUse is a name imported by a use declaration
+type phpUse struct {
+	// Name is the fully qualified name without its leading \
+	Name string
+	// Alias is the name the import is used as in the file
+	Alias string
+	// Kind is class, function or const
+	Kind string
+	// Line is the 1-based line of the name
+	Line int
+}
+
+// evaluateNamespaceUse returns an incident for each use declaration of the php
+// files of the roots that imports a name of the namespace of the condition
+func (p *genericServiceClient) evaluateNamespaceUse(cond namespaceUseCondition) (provider.ProviderEvaluateResponse, error) {
+	namespace := strings.ToLower(strings.Trim(cond.Namespace, `\`))
+	if namespace == "" {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("the namespaceUse condition needs a namespace")
+	}
+	switch cond.Kind {
+	case "", "class", "function", "const":
+	default:
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("invalid kind %s, it is class, function or const", cond.Kind)
+	}
+	incidents := []provider.IncidentContext{}
+	for _, root := range p.config.WalkRoots() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && phpSkippedDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !phpExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			for _, use := range phpUses(content) {
+				name := strings.ToLower(use.Name)
+				if name != namespace && !strings.HasPrefix(name, namespace+`\`) {
+					continue
+				}
+				if cond.Kind != "" && use.Kind != cond.Kind {
+					continue
+				}
+				lineNumber := use.Line
+				incidents = append(incidents, provider.IncidentContext{
+					FileURI:    uri.File(abs),
+					LineNumber: &lineNumber,
+					Variables: map[string]interface{}{
+						"file":  string(uri.File(abs)),
+						"name":  use.Name,
+						"alias": use.Alias,
+						"kind":  use.Kind,
+					},
+					CodeLocation: &provider.Location{
+						StartPosition: provider.Position{Line: float64(lineNumber - 1)},
+						EndPosition:   provider.Position{Line: float64(lineNumber - 1)},
+					},
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to walk %s: %w", root, err)
+		}
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// phpToken is a name, such as Foo\Bar or use, or a punctuation character of
+// the php code of a file, the strings, comments and inline html are left out
+type phpToken struct {
+	text string
+	line int
+}
+
+func isPHPNameStart(c byte) bool {
+	return c == '_' || c == '\\' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isPHPNameChar(c byte) bool {
+	return isPHPNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// phpTokens returns the tokens of the php code of a file. The code after ?> is
+// inline html until the next open tag, ?> ends a statement as a ; does.
+func phpTokens(content []byte) []phpToken {
+	tokens := []phpToken{}
+	line := 1
+	i := 0
+	n := len(content)
+	// skip advances to the end, counting the lines
+	skip := func(end int) {
+		if end > n {
+			end = n
+		}
+		for ; i < end; i++ {
+			if content[i] == '\n' {
+				line++
+			}
+		}
+	}
+	inPHP := false
+	for i < n {
+		if !inPHP {
+			open := strings.Index(string(content[i:]), "<?")
+			if open < 0 {
+				break
+			}
+			skip(i + open + 2)
+			if i+3 <= n && strings.EqualFold(string(content[i:i+3]), "php") {
+				skip(i + 3)
+			} else if i < n && content[i] == '=' {
+				skip(i + 1)
+			}
+			inPHP = true
+			continue
+		}
+		c := content[i]
+		switch {
+		case c == '?' && i+1 < n && content[i+1] == '>':
+			tokens = append(tokens, phpToken{text: ";", line: line})
+			skip(i + 2)
+			inPHP = false
+		case c == '/' && i+1 < n && content[i+1] == '*':
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end < 0 {
+				skip(n)
+			} else {
+				skip(i + 2 + end + 2)
+			}
+		case (c == '/' && i+1 < n && content[i+1] == '/') || (c == '#' && (i+1 >= n || content[i+1] != '[')):
+			// a line comment ends at the end of the line or at ?>
+			for i < n && content[i] != '\n' && !(content[i] == '?' && i+1 < n && content[i+1] == '>') {
+				i++
+			}
+		case c == '\'' || c == '"' || c == '`':
+			skip(i + 1)
+			for i < n && content[i] != c {
+				if content[i] == '\\' {
+					skip(i + 1)
+				}
+				skip(i + 1)
+			}
+			skip(i + 1)
+		case c == '<' && strings.HasPrefix(string(content[i:]), "<<<"):
+			skip(i + 3)
+			for i < n && (content[i] == ' ' || content[i] == '\t') {
+				i++
+			}
+			start := i
+			for i < n && (content[i] == '"' || content[i] == '\'' || isPHPNameChar(content[i])) {
+				i++
+			}
+			label := strings.Trim(string(content[start:i]), `"'`)
+			if label == "" {
+				continue
+			}
+			// the body ends with the label at the start of a line, which
+			// may be indented
+			for i < n {
+				for i < n && content[i] != '\n' {
+					i++
+				}
+				skip(i + 1)
+				rest := strings.TrimLeft(string(content[i:]), " \t")
+				if strings.HasPrefix(rest, label) && (len(rest) == len(label) || !isPHPNameChar(rest[len(label)])) {
+					skip(n - len(rest) + len(label))
+					break
+				}
+			}
+		case c == '$' || (c >= '0' && c <= '9'):
+			// variables and numbers are not names
+			start := i
+			i++
+			for i < n && isPHPNameChar(content[i]) && content[i] != '\\' {
+				i++
+			}
+			tokens = append(tokens, phpToken{text: string(content[start:i]), line: line})
+		case isPHPNameStart(c):
+			start := i
+			for i < n && isPHPNameChar(content[i]) {
+				i++
+			}
+			tokens = append(tokens, phpToken{text: string(content[start:i]), line: line})
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			skip(i + 1)
+		default:
+			tokens = append(tokens, phpToken{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens
+}
+
+// phpUses returns the names imported by the use declarations of a file. The
+// declarations are the use statements of the top level of the file or of its
+// namespace blocks, the uses of traits in classes and the variables of
+// closures are not imports.
+func phpUses(content []byte) []phpUse {
+	uses := []phpUse{}
+	tokens := phpTokens(content)
+	// blocks has whether each open block is a namespace block
+	blocks := []bool{}
+	statementStart := true
+	namespace := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		topLevel := true
+		for _, isNamespace := range blocks {
+			topLevel = topLevel && isNamespace
+		}
+		switch {
+		case t.text == "{":
+			blocks = append(blocks, namespace)
+			namespace = false
+			statementStart = true
+		case t.text == "}":
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			statementStart = true
+		case t.text == ";":
+			namespace = false
+			statementStart = true
+		case statementStart && topLevel && strings.EqualFold(t.text, "namespace"):
+			namespace = true
+			statementStart = false
+		case statementStart && topLevel && strings.EqualFold(t.text, "use"):
+			end := i + 1
+			for end < len(tokens) && tokens[end].text != ";" {
+				end++
+			}
+			uses = append(uses, parseUseClause(tokens[i+1:end])...)
+			i = end
+			statementStart = true
+		default:
+			statementStart = false
+		}
+	}
+	return uses
+}
+
+// parseUseClause returns the names imported by the tokens of a use statement
+// after the use keyword, as in function Foo\bar as baz, or the group use
+// Foo\{Bar, function baz}
+func parseUseClause(tokens []phpToken) []phpUse {
+	uses := []phpUse{}
+	kind := "class"
+	i := 0
+	// kindOf returns the kind of the import before a name, and the index
+	// after it
+	kindOf := func(i int, kind string) (string, int) {
+		if i < len(tokens) {
+			switch strings.ToLower(tokens[i].text) {
+			case "function", "const":
+				return strings.ToLower(tokens[i].text), i + 1
+			}
+		}
+		return kind, i
+	}
+	// parseName returns the import of the name at the index, with its alias
+	// when it has one, and the index after it
+	parseName := func(i int, prefix, kind string) (phpUse, int) {
+		if i >= len(tokens) {
+			return phpUse{}, i
+		}
+		use := phpUse{
+			Name: strings.TrimLeft(prefix+tokens[i].text, `\`),
+			Kind: kind,
+			Line: tokens[i].line,
+		}
+		i++
+		if i+1 < len(tokens) && strings.EqualFold(tokens[i].text, "as") {
+			use.Alias = tokens[i+1].text
+			i += 2
+		} else {
+			use.Alias = use.Name[strings.LastIndex(use.Name, `\`)+1:]
+		}
+		return use, i
+	}
+	kind, i = kindOf(i, kind)
+	for i < len(tokens) {
+		if tokens[i].text == "," {
+			i++
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].text == "{" {
+			prefix := tokens[i].text
+			if !strings.HasSuffix(prefix, `\`) {
+				prefix += `\`
+			}
+			i += 2
+			for i < len(tokens) && tokens[i].text != "}" {
+				if tokens[i].text == "," {
+					i++
+					continue
+				}
+				var itemKind string
+				itemKind, i = kindOf(i, kind)
+				var use phpUse
+				use, i = parseName(i, prefix, itemKind)
+				if use.Name != "" {
+					uses = append(uses, use)
+				}
+			}
+			i++
+			continue
+		}
+		var use phpUse
+		use, i = parseName(i, "", kind)
+		if use.Name != "" {
+			uses = append(uses, use)
+		}
+	}
+	return uses
+}
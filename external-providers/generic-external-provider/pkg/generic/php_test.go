@@ -0,0 +1,84 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPHPUses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []phpUse
+	}{
+		{
+			name:    "class imports",
+			content: "<?php\nuse Symfony\\Component\\HttpFoundation\\Request;\nuse \\Foo\\Bar, Foo\\Baz;\n",
+			want: []phpUse{
+				{Name: `Symfony\Component\HttpFoundation\Request`, Alias: "Request", Kind: "class", Line: 2},
+				{Name: `Foo\Bar`, Alias: "Bar", Kind: "class", Line: 3},
+				{Name: `Foo\Baz`, Alias: "Baz", Kind: "class", Line: 3},
+			},
+		},
+		{
+			name:    "aliases",
+			content: "<?php\nuse Foo\\Bar as Qux;\nuse Foo\\Baz AS Quux, Foo\\Other;\n",
+			want: []phpUse{
+				{Name: `Foo\Bar`, Alias: "Qux", Kind: "class", Line: 2},
+				{Name: `Foo\Baz`, Alias: "Quux", Kind: "class", Line: 3},
+				{Name: `Foo\Other`, Alias: "Other", Kind: "class", Line: 3},
+			},
+		},
+		{
+			name:    "function and const imports",
+			content: "<?php\nuse function Foo\\bar;\nuse const Foo\\BAZ as QUX;\nuse FUNCTION Foo\\baz;\n",
+			want: []phpUse{
+				{Name: `Foo\bar`, Alias: "bar", Kind: "function", Line: 2},
+				{Name: `Foo\BAZ`, Alias: "QUX", Kind: "const", Line: 3},
+				{Name: `Foo\baz`, Alias: "baz", Kind: "function", Line: 4},
+			},
+		},
+		{
+			name:    "group imports",
+			content: "<?php\nuse Foo\\{Bar, Baz as Qux,\n    function bar, const BAZ};\nuse function Foo\\Helpers\\{first, last};\n",
+			want: []phpUse{
+				{Name: `Foo\Bar`, Alias: "Bar", Kind: "class", Line: 2},
+				{Name: `Foo\Baz`, Alias: "Qux", Kind: "class", Line: 2},
+				{Name: `Foo\bar`, Alias: "bar", Kind: "function", Line: 3},
+				{Name: `Foo\BAZ`, Alias: "BAZ", Kind: "const", Line: 3},
+				{Name: `Foo\Helpers\first`, Alias: "first", Kind: "function", Line: 4},
+				{Name: `Foo\Helpers\last`, Alias: "last", Kind: "function", Line: 4},
+			},
+		},
+		{
+			name: "comments and strings",
+			content: "<?php\n// use Foo\\Comment;\n# use Foo\\Hash;\n/* use Foo\\Block;\n use Foo\\Block2; */\n" +
+				"$a = 'use Foo\\Single;';\n$b = \"use Foo\\Double;\";\n$c = <<<EOT\nuse Foo\\Heredoc;\nEOT;\nuse Foo\\Real;\n",
+			want: []phpUse{
+				{Name: `Foo\Real`, Alias: "Real", Kind: "class", Line: 11},
+			},
+		},
+		{
+			name:    "inline html",
+			content: "use Foo\\Html;\n<?php use Foo\\Code ?>\nuse Foo\\Html2;\n<?= 1 ?>\n",
+			want: []phpUse{
+				{Name: `Foo\Code`, Alias: "Code", Kind: "class", Line: 2},
+			},
+		},
+		{
+			name: "traits and closures",
+			content: "<?php\nnamespace App {\n    use Foo\\Bar;\n    class A {\n        use SomeTrait;\n" +
+				"        function f() { return function () use ($x) {}; }\n    }\n}\n",
+			want: []phpUse{
+				{Name: `Foo\Bar`, Alias: "Bar", Kind: "class", Line: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := phpUses([]byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
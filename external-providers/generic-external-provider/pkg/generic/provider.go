@@ -25,24 +25,140 @@ func NewGenericProvider() *genericProvider {
 }
 
 func (p *genericProvider) Capabilities() []provider.Capability {
+	// the language servers look up the referenced symbols by name, the
+	// incidents found by searching the text have a lower confidence
+	symbolSearchConfidence := provider.SymbolSearchConfidence
 	return []provider.Capability{
 		{
-			Name:            "referenced",
+			Name:              "referenced",
+			TemplateContext:   openapi3.SchemaRef{},
+			DefaultConfidence: &symbolSearchConfidence,
+			IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+				"file":    provider.WithDescription(openapi3.NewStringSchema(), "URI of the file of the reference"),
+				"package": provider.WithDescription(openapi3.NewStringSchema(), "Package of the referenced symbol, for the conditions with a package"),
+				"symbol":  provider.WithDescription(openapi3.NewStringSchema(), "Name of the referenced symbol without its package, for the conditions with a package"),
+			}),
+		},
+		{
+			Name:            "deprecated",
+			TemplateContext: openapi3.SchemaRef{},
+			IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+				"file":        provider.WithDescription(openapi3.NewStringSchema(), "URI of the file of the reference"),
+				"package":     provider.WithDescription(openapi3.NewStringSchema(), "Package of the deprecated symbol"),
+				"symbol":      provider.WithDescription(openapi3.NewStringSchema(), "Name of the deprecated symbol without its package"),
+				"deprecation": provider.WithDescription(openapi3.NewStringSchema(), "Deprecation notice of the symbol, such as what to use instead"),
+			}),
+		},
+		{
+			Name:            "namespaceUse",
 			TemplateContext: openapi3.SchemaRef{},
+			IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+				"file":  provider.WithDescription(openapi3.NewStringSchema(), "URI of the file of the use declaration"),
+				"name":  provider.WithDescription(openapi3.NewStringSchema(), "Fully qualified name that is imported"),
+				"alias": provider.WithDescription(openapi3.NewStringSchema(), "Name the import is used as in the file"),
+				"kind":  provider.WithDescription(openapi3.NewStringSchema(), "Kind of the import, class, function or const"),
+			}),
 		},
 		{
-			Name:            "dependency",
+			Name:            "included",
 			TemplateContext: openapi3.SchemaRef{},
+			IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+				"file":     provider.WithDescription(openapi3.NewStringSchema(), "URI of the file of the include directive"),
+				"header":   provider.WithDescription(openapi3.NewStringSchema(), "Header as it is written in the include directive"),
+				"kind":     provider.WithDescription(openapi3.NewStringSchema(), "system for the headers included with <>, local for the ones included with quotes"),
+				"resolved": provider.WithDescription(openapi3.NewStringSchema(), "Path of the header found with the include directories of compile_commands.json"),
+			}),
+		},
+		{
+			Name:              "dependency",
+			TemplateContext:   openapi3.SchemaRef{},
+			IncidentVariables: provider.DependencyIncidentVariables,
 		},
 	}
 }
 
+// provider specific config keys
+const (
+	// INITIALIZATION_OPTIONS_CONFIG_KEY are the initializationOptions sent to
+	// the language server in the initialize request
+	INITIALIZATION_OPTIONS_CONFIG_KEY = "initializationOptions"
+	// CLIENT_CAPABILITIES_CONFIG_KEY are the capabilities sent to the language
+	// server in the initialize request, instead of the default ones
+	CLIENT_CAPABILITIES_CONFIG_KEY = "clientCapabilities"
+	// LSP_CHARSETS_CONFIG_KEY are the charsets the language server can
+	// declare in the Content-Type of its messages, utf-8 when not set
+	LSP_CHARSETS_CONFIG_KEY = "lspCharsets"
+)
+
+// configMap returns the map of the provider specific config under the key, it
+// is nil when the key is not set.
+func configMap(config map[string]interface{}, key string) (map[string]interface{}, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	m, ok := stringKeys(value).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a map", key)
+	}
+	return m, nil
+}
+
+// stringKeys converts the maps with interface keys that are decoded from yaml
+// to maps with string keys that can be encoded to json.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[fmt.Sprintf("%v", key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[key] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = stringKeys(item)
+		}
+		return items
+	}
+	return value
+}
+
 type genericCondition struct {
-	Referenced referenceCondition `yaml:"referenced"`
+	Referenced   referenceCondition    `yaml:"referenced"`
+	Deprecated   deprecatedCondition   `yaml:"deprecated"`
+	NamespaceUse namespaceUseCondition `yaml:"namespaceUse"`
+	Included     includedCondition     `yaml:"included"`
+}
+
+// deprecatedCondition matches the references to the deprecated symbols of
+// packages, the ones documented with a Deprecated: paragraph or a
+// //go:deprecated directive
+type deprecatedCondition struct {
+	// Package is a package path the symbols are in, ... matches any string
+	Package string `yaml:"package"`
+	// Symbol is a glob of the names of the symbols, all the deprecated
+	// symbols of the packages when it is empty
+	Symbol string `yaml:"symbol"`
 }
 
 type referenceCondition struct {
 	Pattern string `yaml:"pattern"`
+	// Package is a package path the referenced symbols are in, ... matches
+	// any string, as in github.com/aws/aws-sdk-go/...
+	Package string `yaml:"package"`
+	// Symbol is a glob of the names of the symbols of the package, without
+	// the package, all the symbols of the package when it is empty
+	Symbol string `yaml:"symbol"`
+	// Location is the kind of code the references are in, such as
+	// CONSTRUCTOR_CALL or IMPORT, any when it is empty
+	Location string `yaml:"location"`
 }
 
 func (p *genericProvider) Init(ctx context.Context, log logr.Logger, c provider.InitConfig) (provider.ServiceClient, error) {
@@ -78,7 +194,59 @@ func (p *genericProvider) Init(ctx context.Context, log logr.Logger, c provider.
 			}
 		}
 	}
+	args = clangdArgs(lspServerPath, args, c)
+	args = omnisharpArgs(lspServerPath, args, c)
+	var charsets []string
+	if lspCharsets, ok := c.ProviderSpecificConfig[LSP_CHARSETS_CONFIG_KEY]; ok {
+		rawCharsets, isArray := lspCharsets.([]interface{})
+		if !isArray {
+			cancelFunc()
+			return nil, fmt.Errorf("%s is not an array", LSP_CHARSETS_CONFIG_KEY)
+		}
+		for _, rawCharset := range rawCharsets {
+			charset, ok := rawCharset.(string)
+			if !ok {
+				cancelFunc()
+				return nil, fmt.Errorf("item of %s is not a string", LSP_CHARSETS_CONFIG_KEY)
+			}
+			charsets = append(charsets, charset)
+		}
+	}
+	initializationOptions, err := configMap(c.ProviderSpecificConfig, INITIALIZATION_OPTIONS_CONFIG_KEY)
+	if err != nil {
+		cancelFunc()
+		return nil, err
+	}
+	clientCapabilities, err := configMap(c.ProviderSpecificConfig, CLIENT_CAPABILITIES_CONFIG_KEY)
+	if err != nil {
+		cancelFunc()
+		return nil, err
+	}
+	registries, err := provider.GetRegistries(c.ProviderSpecificConfig)
+	if err != nil {
+		cancelFunc()
+		return nil, err
+	}
+	// the language server and the dependency provider resolve the
+	// dependencies from the registries
+	var env []string
+	var registriesDir string
+	if registries != nil {
+		registriesDir, err = os.MkdirTemp("", "registries")
+		if err != nil {
+			cancelFunc()
+			return nil, err
+		}
+		registriesEnv, err := registries.Environment(registriesDir)
+		if err != nil {
+			cancelFunc()
+			os.RemoveAll(registriesDir)
+			return nil, err
+		}
+		env = append(os.Environ(), registriesEnv...)
+	}
 	cmd := exec.CommandContext(ctx, lspServerPath, args...)
+	cmd.Env = env
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -96,7 +264,8 @@ func (p *genericProvider) Init(ctx context.Context, log logr.Logger, c provider.
 			return
 		}
 	}()
-	rpc := jsonrpc2.NewConn(jsonrpc2.NewHeaderStream(stdout, stdin), log)
+	rpc := jsonrpc2.NewConn(jsonrpc2.NewHeaderStreamWithOptions(stdout, stdin, jsonrpc2.HeaderOptions{Charsets: charsets}), log)
+	rpc.AddHandler(jsonrpc2.NewCancelHandler())
 
 	go func() {
 		err := rpc.Run(ctx)
@@ -109,13 +278,20 @@ func (p *genericProvider) Init(ctx context.Context, log logr.Logger, c provider.
 	}()
 
 	svcClient := genericServiceClient{
-		rpc:        rpc,
-		cancelFunc: cancelFunc,
-		cmd:        cmd,
-		config:     c,
+		rpc:                   rpc,
+		cancelFunc:            cancelFunc,
+		cmd:                   cmd,
+		config:                c,
+		initializationOptions: initializationOptions,
+		clientCapabilities:    clientCapabilities,
+		env:                   env,
+		registriesDir:         registriesDir,
 	}
 
 	// Lets Initiallize before returning
-	svcClient.initialization(ctx, log)
+	if err := svcClient.initialization(ctx, log); err != nil {
+		svcClient.Stop()
+		return nil, err
+	}
 	return &svcClient, nil
 }
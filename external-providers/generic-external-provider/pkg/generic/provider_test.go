@@ -0,0 +1,128 @@
+package generic
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		key     string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "initialization options",
+			config: `
+initializationOptions:
+  settings:
+    python:
+      analysis:
+        extraPaths: [lib, vendor]
+  1: one
+`,
+			key: INITIALIZATION_OPTIONS_CONFIG_KEY,
+			want: map[string]interface{}{
+				"settings": map[string]interface{}{
+					"python": map[string]interface{}{
+						"analysis": map[string]interface{}{
+							"extraPaths": []interface{}{"lib", "vendor"},
+						},
+					},
+				},
+				"1": "one",
+			},
+		},
+		{
+			name: "client capabilities",
+			config: `
+clientCapabilities:
+  workspace:
+    workspaceFolders: true
+  textDocument:
+    references: {dynamicRegistration: false}
+`,
+			key: CLIENT_CAPABILITIES_CONFIG_KEY,
+			want: map[string]interface{}{
+				"workspace": map[string]interface{}{"workspaceFolders": true},
+				"textDocument": map[string]interface{}{
+					"references": map[string]interface{}{"dynamicRegistration": false},
+				},
+			},
+		},
+		{
+			name:   "missing",
+			config: "lspServerPath: /usr/bin/pylsp\n",
+			key:    CLIENT_CAPABILITIES_CONFIG_KEY,
+		},
+		{
+			name:   "null",
+			config: "clientCapabilities:\n",
+			key:    CLIENT_CAPABILITIES_CONFIG_KEY,
+		},
+		{
+			name:    "not a map",
+			config:  "initializationOptions: --stdio\n",
+			key:     INITIALIZATION_OPTIONS_CONFIG_KEY,
+			wantErr: true,
+		},
+		{
+			name:    "list",
+			config:  "clientCapabilities: [workspace]\n",
+			key:     CLIENT_CAPABILITIES_CONFIG_KEY,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(tt.config), &config); err != nil {
+				t.Fatalf("unable to decode the config: %v", err)
+			}
+			got, err := configMap(config, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+			// the maps are sent to the language server as json
+			if _, err := json.Marshal(got); err != nil {
+				t.Errorf("unable to encode the map to json: %v", err)
+			}
+		})
+	}
+}
+
+func TestInitializeParams(t *testing.T) {
+	params := &protocol.InitializeParams{}
+	params.RootURI = "file:///src"
+	params.InitializationOptions = map[string]interface{}{"storagePath": "/tmp/ls"}
+	request := initializeParams{
+		InitializeParams: params,
+		Capabilities:     map[string]interface{}{"workspace": map[string]interface{}{"symbol": map[string]interface{}{}}},
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := map[string]interface{}{"workspace": map[string]interface{}{"symbol": map[string]interface{}{}}}; !reflect.DeepEqual(got["capabilities"], want) {
+		t.Errorf("expected the capabilities of the config %v, got %v", want, got["capabilities"])
+	}
+	if want := map[string]interface{}{"storagePath": "/tmp/ls"}; !reflect.DeepEqual(got["initializationOptions"], want) {
+		t.Errorf("expected the initialization options %v, got %v", want, got["initializationOptions"])
+	}
+	if got["rootUri"] != "file:///src" {
+		t.Errorf("expected the root URI of the params, got %v", got["rootUri"])
+	}
+}
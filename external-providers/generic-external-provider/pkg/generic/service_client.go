@@ -27,6 +27,23 @@ type genericServiceClient struct {
 
 	config       provider.InitConfig
 	capabilities protocol.ServerCapabilities
+
+	// initializationOptions and clientCapabilities are sent verbatim in the
+	// initialize request when they are set
+	initializationOptions map[string]interface{}
+	clientCapabilities    map[string]interface{}
+
+	// env is the environment of the commands with the registries, nil for
+	// the one of the provider, registriesDir has their credentials
+	env           []string
+	registriesDir string
+}
+
+// initializeParams replaces the typed capabilities of the initialize request
+// with the ones of the config.
+type initializeParams struct {
+	*protocol.InitializeParams
+	Capabilities map[string]interface{} `json:"capabilities"`
 }
 
 var _ provider.ServiceClient = &genericServiceClient{}
@@ -34,6 +51,9 @@ var _ provider.ServiceClient = &genericServiceClient{}
 func (p *genericServiceClient) Stop() {
 	p.cancelFunc()
 	p.cmd.Wait()
+	if p.registriesDir != "" {
+		os.RemoveAll(p.registriesDir)
+	}
 }
 
 func (p *genericServiceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
@@ -42,22 +62,68 @@ func (p *genericServiceClient) Evaluate(ctx context.Context, cap string, conditi
 	if err != nil {
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to get query info")
 	}
-	query := cond.Referenced.Pattern
-	if query == "" {
+	if cap == "deprecated" {
+		return p.evaluateDeprecated(ctx, cond.Deprecated)
+	}
+	if cap == "namespaceUse" {
+		return p.evaluateNamespaceUse(cond.NamespaceUse)
+	}
+	if cap == "included" {
+		return p.evaluateIncluded(cond.Included)
+	}
+	location, err := referenceLocation(cond.Referenced.Location)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	var symbols []protocol.WorkspaceSymbol
+	textSearch := false
+	switch {
+	case cond.Referenced.Package != "":
+		symbols, err = p.GetPackageSymbols(ctx, cond.Referenced.Package, cond.Referenced.Symbol)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+	case cond.Referenced.Symbol != "":
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("a symbol needs the package it is in")
+	case cond.Referenced.Pattern != "":
+		symbols, textSearch = p.GetAllSymbols(ctx, cond.Referenced.Pattern)
+	default:
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to get query info")
 	}
+	return p.referenceIncidents(ctx, symbols, textSearch, location, func(s protocol.WorkspaceSymbol) map[string]interface{} {
+		if cond.Referenced.Package == "" {
+			return nil
+		}
+		return map[string]interface{}{
+			"package": s.ContainerName,
+			"symbol":  symbolName(s),
+		}
+	})
+}
 
-	symbols := p.GetAllSymbols(ctx, query)
-
+// referenceIncidents returns an incident for each reference to the symbols in
+// the roots that is at the location, any location when it is empty, with the
+// variables of the symbol it references
+func (p *genericServiceClient) referenceIncidents(ctx context.Context, symbols []protocol.WorkspaceSymbol, textSearch bool, location string, variables func(protocol.WorkspaceSymbol) map[string]interface{}) (provider.ProviderEvaluateResponse, error) {
 	incidents := []provider.IncidentContext{}
 	incidentsMap := make(map[string]provider.IncidentContext) // To remove duplicates
+	lines := fileLines{}
 
 	for _, s := range symbols {
+		if err := ctx.Err(); err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		if location != "" && !symbolAtLocation(location, s) {
+			continue
+		}
 		references := p.GetAllReferences(ctx, s.Location.Value.(protocol.Location))
 		for _, ref := range references {
-			// Look for things that are in the location loaded,
+			if location != "" && !referenceAt(location, string(ref.URI), lines.line(string(ref.URI), int(ref.Range.Start.Line)), ref.Range, s) {
+				continue
+			}
+			// Look for things that are in the roots loaded,
 			// Note may need to filter out vendor at some point
-			if strings.Contains(ref.URI, p.config.Location) {
+			if p.inRoots(ref.URI) {
 
 				var referencedOutputIgnoreContains []interface{}
 				if p.config.ProviderSpecificConfig["referencedOutputIgnoreContains"] != nil {
@@ -92,6 +158,13 @@ func (p *genericServiceClient) Evaluate(ctx context.Context, cap string, conditi
 						"file": ref.URI,
 					},
 				}
+				for k, v := range variables(s) {
+					incident.Variables[k] = v
+				}
+				if textSearch {
+					confidence := provider.TextSearchConfidence
+					incident.Confidence = &confidence
+				}
 				b, _ := json.Marshal(incident)
 
 				incidentsMap[string(b)] = incident
@@ -99,6 +172,10 @@ func (p *genericServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		}
 	}
 
+	// the requests of a canceled query fail, its incidents are partial
+	if err := ctx.Err(); err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
 	for _, incident := range incidentsMap {
 		incidents = append(incidents, incident)
 	}
@@ -180,11 +257,12 @@ func parallelWalk(location string, regex *regexp.Regexp) ([]protocol.TextDocumen
 	return positions, nil
 }
 
-// Returns all symbols for the given query.
+// Returns all symbols for the given query, and whether they were found by
+// searching the text of the files for the query.
 // NOTE: Only returns definitions when server does not supoprt workspace/symbol.
 // Is is intended behavior?
 // TODO: Change protocol.WorkspaceSymbol to protocol.SymbolInformation
-func (p *genericServiceClient) GetAllSymbols(ctx context.Context, query string) []protocol.WorkspaceSymbol {
+func (p *genericServiceClient) GetAllSymbols(ctx context.Context, query string) ([]protocol.WorkspaceSymbol, bool) {
 	wsp := &protocol.WorkspaceSymbolParams{
 		Query: query,
 	}
@@ -206,7 +284,7 @@ func (p *genericServiceClient) GetAllSymbols(ctx context.Context, query string)
 
 	if regexErr != nil {
 		// Not a valid regex, can't do anything more
-		return symbols
+		return symbols, false
 	}
 
 	if p.capabilities.Supports("workspace/symbol") && len(symbols) == 0 {
@@ -248,20 +326,15 @@ func (p *genericServiceClient) GetAllSymbols(ctx context.Context, query string)
 			return nil
 		}
 
-		err := walkFiles([]string{p.config.Location})
+		err := walkFiles(p.config.WalkRoots())
 		if err != nil {
 			fmt.Printf("%s\n", err.Error())
-			return nil
+			return nil, false
 		}
 
-		// Leaving this in here until we determine whether we can use workspace
-		// folders
+		// Leaving this in here until we determine whether we can use
+		// dependency folders
 
-		// err = walkFiles(p.Config.WorkspaceFolders)
-		// if err != nil {
-		// 	fmt.Printf("%s\n", err.Error())
-		// 	return nil
-		// }
 		// err = walkFiles(p.Config.DependencyFolders)
 		// if err != nil {
 		// 	fmt.Printf("%s\n", err.Error())
@@ -288,9 +361,10 @@ func (p *genericServiceClient) GetAllSymbols(ctx context.Context, query string)
 		for _, ws := range symbolMap {
 			symbols = append(symbols, ws)
 		}
+		return symbols, true
 	}
 
-	return symbols
+	return symbols, false
 }
 
 func (p *genericServiceClient) GetAllReferences(ctx context.Context, location protocol.Location) []protocol.Location {
@@ -315,46 +389,47 @@ func (p *genericServiceClient) GetAllReferences(ctx context.Context, location pr
 	return res
 }
 
-func (p *genericServiceClient) initialization(ctx context.Context, log logr.Logger) {
+func (p *genericServiceClient) initialization(ctx context.Context, log logr.Logger) error {
 	// Get abosulte path of location.
 	abs, err := filepath.Abs(p.config.Location)
 	if err != nil {
 		log.Error(err, "unable to get path to analyize")
-		panic(1)
+		return fmt.Errorf("unable to get the path of %s: %w", p.config.Location, err)
 	}
 
 	//TODO(shawn-hurley): add ability to parse path to URI in a real supported way
 	params := &protocol.InitializeParams{}
 	params.RootURI = fmt.Sprintf("file://%v", abs)
 
-	// Leaving this in here until we determine whether we can use workspace
-	// folders
-
-	// var allWorkspaceFolders []string
-	// copy(allWorkspaceFolders, p.Config.WorkspaceFolders)
-	// allWorkspaceFolders = append(allWorkspaceFolders, p.Config.DependencyFolders...)
-
-	// for _, path := range allWorkspaceFolders {
-	// 	abs, err := filepath.Abs(path)
-	// 	if err != nil {
-	// 		log.Error(err, "unable to get path to analyize")
-	// 		panic(1)
-	// 	}
-
-	// 	params.WorkspaceFolders = append(params.WorkspaceFolders, protocol.WorkspaceFolder{
-	// 		URI:  abs,
-	// 		Name: abs,
-	// 	})
-	// }
+	// the location is the first of the workspace folders, so that the
+	// language servers supporting them index all the roots
+	for _, root := range p.config.Roots() {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			log.Error(err, "unable to get path to analyze", "root", root)
+			return fmt.Errorf("unable to get the path of the workspace folder %s: %w", root, err)
+		}
+		params.WorkspaceFolders = append(params.WorkspaceFolders, protocol.WorkspaceFolder{
+			URI:  fmt.Sprintf("file://%v", abs),
+			Name: filepath.Base(abs),
+		})
+	}
 
 	params.Capabilities = protocol.ClientCapabilities{}
 	params.ExtendedClientCapilities = map[string]interface{}{
 		"classFileContentsSupport": true,
 	}
+	if p.initializationOptions != nil {
+		params.InitializationOptions = p.initializationOptions
+	}
+	var request interface{} = params
+	if p.clientCapabilities != nil {
+		request = initializeParams{InitializeParams: params, Capabilities: p.clientCapabilities}
+	}
 
 	var result protocol.InitializeResult
 	for {
-		err := p.rpc.Call(ctx, "initialize", params, &result)
+		err := p.rpc.Call(ctx, "initialize", request, &result)
 		if err == nil {
 			break
 		}
@@ -386,4 +461,15 @@ func (p *genericServiceClient) initialization(ctx context.Context, log logr.Logg
 	}
 	fmt.Printf("provider connection initialized\n")
 	log.V(2).Info("provider connection initialized\n")
+	return nil
+}
+
+// inRoots returns whether the URI is in one of the roots of the config
+func (p *genericServiceClient) inRoots(u string) bool {
+	for _, root := range p.config.Roots() {
+		if strings.Contains(u, root) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,105 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// packagePatternRegex returns the regex of a package pattern, where ... matches
+// any string as in the patterns of the go command. A pattern that ends with
+// /... also matches the package before it, github.com/aws/aws-sdk-go/...
+// matches github.com/aws/aws-sdk-go and all the packages under it. The php
+// namespaces are separated with \, Symfony\Component\... matches
+// Symfony\Component and the namespaces under it, case insensitively, and the
+// c++ ones with ::, boost::asio::... matches boost::asio and the namespaces
+// under it.
+func packagePatternRegex(pattern string) (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(pattern)
+	for _, separator := range []string{`/`, `\\`, `::`} {
+		if strings.HasSuffix(expr, separator+`\.\.\.`) {
+			expr = strings.TrimSuffix(expr, separator+`\.\.\.`) + `(` + separator + `\.\.\.)?`
+		}
+	}
+	expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+	if strings.Contains(pattern, `\`) {
+		return regexp.Compile(`(?i)^\\?` + strings.TrimPrefix(expr, `\\`) + "$")
+	}
+	return regexp.Compile("^" + expr + "$")
+}
+
+// symbolName returns the name of a symbol without its package, the language
+// servers qualify it with the package path or name, such as gopls does
+// depending on its symbolStyle, or with the php or c++ namespace. Methods keep
+// their type, as in Client.Do.
+func symbolName(s protocol.WorkspaceSymbol) string {
+	name := s.Name
+	if s.ContainerName != "" {
+		for _, qualifier := range []string{s.ContainerName, path.Base(s.ContainerName)} {
+			for _, separator := range []string{".", `\`, "::"} {
+				if strings.HasPrefix(name, qualifier+separator) {
+					return strings.TrimPrefix(name, qualifier+separator)
+				}
+			}
+		}
+	}
+	return name
+}
+
+// symbolQuery returns the workspace/symbol query of a package pattern and
+// symbol glob: the literal start of the glob, or the package path before its
+// first wildcard when the glob starts with one, which the language servers
+// match against the qualified names of the symbols.
+func symbolQuery(packagePattern, symbol string) string {
+	literal := symbol
+	if i := strings.IndexAny(symbol, "*?[\\"); i >= 0 {
+		literal = symbol[:i]
+	}
+	if literal != "" {
+		return literal
+	}
+	if i := strings.Index(packagePattern, "..."); i >= 0 {
+		packagePattern = packagePattern[:i]
+	}
+	return strings.TrimRight(packagePattern, `/\:`)
+}
+
+// GetPackageSymbols returns the symbols of the packages that match the package
+// pattern and whose name matches the symbol glob, such as all the symbols of
+// github.com/aws/aws-sdk-go/... or the New* functions of a package. The
+// package of a symbol is its container, as gopls reports it. The glob is
+// matched with path.Match, * matches any symbol when it is empty.
+func (p *genericServiceClient) GetPackageSymbols(ctx context.Context, packagePattern, symbol string) ([]protocol.WorkspaceSymbol, error) {
+	if symbol == "" {
+		symbol = "*"
+	}
+	if _, err := path.Match(symbol, ""); err != nil {
+		return nil, fmt.Errorf("invalid symbol pattern %s: %w", symbol, err)
+	}
+	packageRegex, err := packagePatternRegex(packagePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package pattern %s: %w", packagePattern, err)
+	}
+	if !p.capabilities.Supports("workspace/symbol") {
+		return nil, fmt.Errorf("the language server does not support workspace/symbol, which package conditions need")
+	}
+	var candidates []protocol.WorkspaceSymbol
+	err = p.rpc.Call(ctx, "workspace/symbol", &protocol.WorkspaceSymbolParams{Query: symbolQuery(packagePattern, symbol)}, &candidates)
+	if err != nil {
+		return nil, err
+	}
+	symbols := []protocol.WorkspaceSymbol{}
+	for _, s := range candidates {
+		if !packageRegex.MatchString(s.ContainerName) {
+			continue
+		}
+		if match, _ := path.Match(symbol, symbolName(s)); match {
+			symbols = append(symbols, s)
+		}
+	}
+	return symbols, nil
+}
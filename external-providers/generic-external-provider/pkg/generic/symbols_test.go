@@ -0,0 +1,222 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/jsonrpc2"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+func TestPackagePatternRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		// go packages are separated with /
+		{pattern: "net/http", name: "net/http", matches: true},
+		{pattern: "net/http", name: "net/http/httptest", matches: false},
+		{pattern: "github.com/aws/aws-sdk-go/...", name: "github.com/aws/aws-sdk-go", matches: true},
+		{pattern: "github.com/aws/aws-sdk-go/...", name: "github.com/aws/aws-sdk-go/service/s3", matches: true},
+		{pattern: "github.com/aws/aws-sdk-go/...", name: "github.com/aws/aws-sdk-go-v2", matches: false},
+		{pattern: "github.com/.../v2", name: "github.com/a/b/v2", matches: true},
+		{pattern: "github.com/.../v2", name: "github.com/a/b/v3", matches: false},
+		{pattern: "gopkg.in/yaml.v2", name: "gopkg.in/yamlxv2", matches: false},
+		// php namespaces are separated with \ and case insensitive
+		{pattern: `App\Models`, name: `App\Models`, matches: true},
+		{pattern: `App\Models`, name: `app\models`, matches: true},
+		{pattern: `App\Models`, name: `App\Models\User`, matches: false},
+		{pattern: `Symfony\Component\...`, name: `Symfony\Component`, matches: true},
+		{pattern: `Symfony\Component\...`, name: `\Symfony\Component\HttpFoundation`, matches: true},
+		{pattern: `Symfony\Component\...`, name: `Symfony\ComponentX`, matches: false},
+		// c++ namespaces are separated with ::
+		{pattern: "std", name: "std", matches: true},
+		{pattern: "std", name: "std::chrono", matches: false},
+		{pattern: "boost::asio::...", name: "boost::asio", matches: true},
+		{pattern: "boost::asio::...", name: "boost::asio::ip", matches: true},
+		{pattern: "boost::asio::...", name: "boost::asiox", matches: false},
+		{pattern: "boost::...::detail", name: "boost::asio::detail", matches: true},
+	}
+	for _, tt := range tests {
+		regex, err := packagePatternRegex(tt.pattern)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got := regex.MatchString(tt.name); got != tt.matches {
+			t.Errorf("expected %s matching %s to be %v, got %v", tt.pattern, tt.name, tt.matches, got)
+		}
+	}
+}
+
+func TestSymbolName(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		want      string
+	}{
+		{name: "github.com/aws/aws-sdk-go/aws/session.New", container: "github.com/aws/aws-sdk-go/aws/session", want: "New"},
+		{name: "session.Session.Copy", container: "github.com/aws/aws-sdk-go/aws/session", want: "Session.Copy"},
+		{name: "New", container: "github.com/aws/aws-sdk-go/aws/session", want: "New"},
+		{name: `App\Models\User`, container: `App\Models`, want: "User"},
+		{name: "boost::asio::io_context", container: "boost::asio", want: "io_context"},
+		{name: "asio::io_context", container: "boost::asio", want: "asio::io_context"},
+		{name: "main", container: "", want: "main"},
+	}
+	for _, tt := range tests {
+		s := protocol.WorkspaceSymbol{}
+		s.Name = tt.name
+		s.ContainerName = tt.container
+		if got := symbolName(s); got != tt.want {
+			t.Errorf("expected the name of %s in %s to be %s, got %s", tt.name, tt.container, tt.want, got)
+		}
+	}
+}
+
+func TestSymbolQuery(t *testing.T) {
+	tests := []struct {
+		pattern string
+		symbol  string
+		want    string
+	}{
+		{pattern: "github.com/aws/aws-sdk-go/...", symbol: "New*", want: "New"},
+		{pattern: "github.com/aws/aws-sdk-go/...", symbol: "*", want: "github.com/aws/aws-sdk-go"},
+		{pattern: "github.com/.../v2", symbol: "", want: "github.com"},
+		{pattern: "net/http", symbol: "Client.Do", want: "Client.Do"},
+		{pattern: `Symfony\Component\...`, symbol: "*", want: `Symfony\Component`},
+		{pattern: `App\Models`, symbol: "User", want: "User"},
+		{pattern: "boost::asio::...", symbol: "*", want: "boost::asio"},
+		{pattern: "std", symbol: "vec[a-z]*", want: "vec"},
+	}
+	for _, tt := range tests {
+		if got := symbolQuery(tt.pattern, tt.symbol); got != tt.want {
+			t.Errorf("expected the query of %s %s to be %q, got %q", tt.pattern, tt.symbol, tt.want, got)
+		}
+	}
+}
+
+// symbolServer returns a client connected to a language server that answers
+// the workspace/symbol requests with the symbols, and sends the queries it
+// receives on the channel
+func symbolServer(ctx context.Context, symbols []protocol.WorkspaceSymbol, queries chan<- string) *genericServiceClient {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	client := jsonrpc2.NewConn(jsonrpc2.NewHeaderStream(r1, w2), logr.Discard())
+	server := jsonrpc2.NewConn(jsonrpc2.NewHeaderStream(r2, w1), logr.Discard())
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		var p protocol.WorkspaceSymbolParams
+		if err := json.Unmarshal(*params, &p); err != nil {
+			return nil, err
+		}
+		queries <- p.Query
+		return symbols, nil
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+	p := &genericServiceClient{rpc: client}
+	p.capabilities.WorkspaceSymbolProvider = &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true}
+	return p
+}
+
+func TestGetPackageSymbols(t *testing.T) {
+	symbol := func(name, container string) protocol.WorkspaceSymbol {
+		s := protocol.WorkspaceSymbol{}
+		s.Name = name
+		s.ContainerName = container
+		return s
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		symbol  string
+		symbols []protocol.WorkspaceSymbol
+		query   string
+		want    []string
+	}{
+		{
+			name:    "go packages under a path",
+			pattern: "github.com/aws/aws-sdk-go/...",
+			symbol:  "New*",
+			symbols: []protocol.WorkspaceSymbol{
+				symbol("session.New", "github.com/aws/aws-sdk-go/aws/session"),
+				symbol("session.NewSession", "github.com/aws/aws-sdk-go/aws/session"),
+				symbol("session.Must", "github.com/aws/aws-sdk-go/aws/session"),
+				symbol("config.New", "github.com/aws/aws-sdk-go-v2/config"),
+			},
+			query: "New",
+			want:  []string{"session.New", "session.NewSession"},
+		},
+		{
+			name:    "all the symbols of a go package",
+			pattern: "net/http",
+			symbols: []protocol.WorkspaceSymbol{
+				symbol("http.Client", "net/http"),
+				symbol("httptest.Server", "net/http/httptest"),
+			},
+			query: "net/http",
+			want:  []string{"http.Client"},
+		},
+		{
+			name:    "php namespace",
+			pattern: `Symfony\Component\...`,
+			symbol:  "Request",
+			symbols: []protocol.WorkspaceSymbol{
+				symbol(`Symfony\Component\HttpFoundation\Request`, `Symfony\Component\HttpFoundation`),
+				symbol(`Laminas\Http\Request`, `Laminas\Http`),
+			},
+			query: "Request",
+			want:  []string{`Symfony\Component\HttpFoundation\Request`},
+		},
+		{
+			name:    "c++ namespace",
+			pattern: "boost::asio",
+			symbol:  "*",
+			symbols: []protocol.WorkspaceSymbol{
+				symbol("io_context", "boost::asio"),
+				symbol("address", "boost::asio::ip"),
+			},
+			query: "boost::asio",
+			want:  []string{"io_context"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			queries := make(chan string, 1)
+			p := symbolServer(ctx, tt.symbols, queries)
+			symbols, err := p.GetPackageSymbols(ctx, tt.pattern, tt.symbol)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if query := <-queries; query != tt.query {
+				t.Errorf("expected the query %q, got %q", tt.query, query)
+			}
+			got := []string{}
+			for _, s := range symbols {
+				got = append(got, s.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected the symbols %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetPackageSymbolsErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	p := symbolServer(ctx, nil, make(chan string, 1))
+	if _, err := p.GetPackageSymbols(ctx, "net/http", "[a-"); err == nil {
+		t.Errorf("expected an error for an invalid symbol pattern")
+	}
+	p.capabilities.WorkspaceSymbolProvider = nil
+	if _, err := p.GetPackageSymbols(ctx, "net/http", "Client"); err == nil {
+		t.Errorf("expected an error when the language server does not support workspace/symbol")
+	}
+}
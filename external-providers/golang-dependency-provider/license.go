@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// addLicenses sets the license of the dependencies from the license files of
+// their module, the way go-licenses resolves them. The directories of the
+// modules are taken from dirs, such as the directory of the replacement of a
+// replaced module, or else from the module cache. The modules that are not
+// downloaded are left without a license.
+func addLicenses(deps []*provider.Dep, dirs map[string]string) error {
+	buf := bytes.Buffer{}
+	cmd := exec.Command("go", "env", "GOMODCACHE")
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	modCache := strings.TrimSpace(buf.String())
+	licenses := map[string]string{}
+	for _, d := range deps {
+		key := d.Name + "@" + d.Version
+		license, ok := licenses[key]
+		if !ok {
+			dir, ok := dirs[d.Name]
+			if !ok {
+				dir = moduleDir(modCache, d.Name, d.Version)
+			}
+			license = provider.LicenseFromDir(dir)
+			licenses[key] = license
+		}
+		d.License = license
+	}
+	return nil
+}
+
+// moduleDir returns the directory of a module in the module cache
+func moduleDir(modCache, path, version string) string {
+	return filepath.Join(modCache, filepath.FromSlash(escapeModulePath(path))+"@"+escapeModulePath(version))
+}
+
+// escapeModulePath escapes the upper case letters of a module path or version
+// as the module cache does, with an exclamation mark followed by the letter in
+// lower case
+func escapeModulePath(path string) string {
+	b := strings.Builder{}
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteRune('!')
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	// This will communicate that, the dep is downloadable and not vendored.
+	golangDownloadableDepSourceLabel = "downloadable"
+	// golangLocalDepSourceLabel is the source of the modules replaced by a
+	// directory
+	golangLocalDepSourceLabel = "local"
+)
+
+// module is a module of the output of go list -m -json all
+type module struct {
+	Path     string  `json:"Path"`
+	Version  string  `json:"Version"`
+	Main     bool    `json:"Main"`
+	Indirect bool    `json:"Indirect"`
+	Dir      string  `json:"Dir"`
+	Replace  *module `json:"Replace"`
+}
+
+// listModules returns the modules of the build list of the module in dir, as
+// go list -m all selects them, which uses the go.work of the module when it
+// has one and applies the replace and exclude directives
+func listModules(dir string) ([]module, error) {
+	output, err := goCommand(dir, "list", "-m", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+	return parseModules(output)
+}
+
+// moduleGraph returns the lines of go mod graph, the requirements of the
+// modules of the module in dir
+func moduleGraph(dir string) ([]string, error) {
+	output, err := goCommand(dir, "mod", "graph")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(output), "\n"), nil
+}
+
+func goCommand(dir string, args ...string) ([]byte, error) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseModules parses the output of go list -m -json, a stream of json
+// objects
+func parseModules(output []byte) ([]module, error) {
+	modules := []module{}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		m := module{}
+		err := decoder.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			return modules, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the modules: %w", err)
+		}
+		modules = append(modules, m)
+	}
+}
+
+// moduleDirs returns the directories of the modules by their path, the ones
+// of their replacement for the replaced modules
+func moduleDirs(modules []module) map[string]string {
+	dirs := map[string]string{}
+	for _, m := range modules {
+		if m.Replace != nil && m.Replace.Dir != "" {
+			dirs[m.Path] = m.Replace.Dir
+		} else if m.Dir != "" {
+			dirs[m.Path] = m.Dir
+		}
+	}
+	return dirs
+}
+
+// parseGoDepLines builds the dependencies of the main modules from the lines
+// of go mod graph, the modules they require with the modules these require.
+// The graph has every version of the modules that is required, the
+// requirements are taken from the versions that are selected in modules and
+// point to them, the modules that are not selected, such as the excluded
+// ones, are left out. The modules of the workspace are the main modules,
+// they are not dependencies.
+func parseGoDepLines(lines []string, modules []module) ([]provider.DepDAGItem, error) {
+	selected := map[string]module{}
+	for _, m := range modules {
+		selected[m.Path] = m
+	}
+	// requires are the paths of the modules the selected modules require
+	requires := map[string][]string{}
+	required := map[string]map[string]bool{}
+	roots := []string{}
+	for _, l := range lines {
+		if len(strings.TrimSpace(l)) == 0 {
+			continue
+		}
+		values := strings.Fields(l)
+		if len(values) != 2 {
+			return nil, fmt.Errorf("failed to split dependency line '%s'", l)
+		}
+		basePath, baseVersion := splitModule(values[0])
+		nestedPath, _ := splitModule(values[1])
+		base, ok := selected[basePath]
+		if !ok || (!base.Main && base.Version != baseVersion) {
+			continue
+		}
+		if _, ok := selected[nestedPath]; !ok {
+			continue
+		}
+		if required[basePath] == nil {
+			required[basePath] = map[string]bool{}
+			if base.Main {
+				roots = append(roots, basePath)
+			}
+		}
+		if required[basePath][nestedPath] {
+			continue
+		}
+		required[basePath][nestedPath] = true
+		requires[basePath] = append(requires[basePath], nestedPath)
+	}
+	if len(roots) == 0 {
+		for _, l := range lines {
+			if len(strings.TrimSpace(l)) != 0 {
+				return nil, fmt.Errorf("failed to parse dependencies %s", strings.Join(lines, ""))
+			}
+		}
+	}
+
+	deps := []provider.DepDAGItem{}
+	added := map[string]bool{}
+	for _, root := range roots {
+		for _, path := range requires[root] {
+			m := selected[path]
+			if m.Main || added[path] {
+				continue
+			}
+			added[path] = true
+			d := provider.DepDAGItem{Dep: moduleDep(m, m.Indirect)}
+			indirect := []provider.DepDAGItem{}
+			for _, nestedPath := range requires[path] {
+				nested := selected[nestedPath]
+				if nested.Main {
+					continue
+				}
+				indirect = append(indirect, provider.DepDAGItem{Dep: moduleDep(nested, true)})
+			}
+			d.AddedDeps = indirect
+			deps = append(deps, d)
+		}
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].Dep.Name < deps[j].Dep.Name
+	})
+	return deps, nil
+}
+
+// moduleDep returns the dependency of a selected module. A module replaced by
+// another module has the version of the replacement, and one replaced by a
+// directory is local, the replacement is in the replace extra.
+func moduleDep(m module, indirect bool) provider.Dep {
+	d := provider.Dep{
+		Name:     m.Path,
+		Version:  m.Version,
+		Indirect: indirect,
+		Labels:   depLabels(golangDownloadableDepSourceLabel),
+	}
+	if m.Replace == nil {
+		return d
+	}
+	if m.Replace.Version == "" {
+		d.Labels = depLabels(golangLocalDepSourceLabel)
+		d.Extras = map[string]interface{}{"replace": m.Replace.Path}
+		return d
+	}
+	d.Version = m.Replace.Version
+	d.Extras = map[string]interface{}{"replace": m.Replace.Path + "@" + m.Replace.Version}
+	return d
+}
+
+// splitModule splits a module of go mod graph, <path>@<version>, the main
+// modules do not have a version
+func splitModule(s string) (string, string) {
+	path, version, _ := strings.Cut(strings.TrimSpace(s), "@")
+	return path, version
+}
+
+func depLabels(source string) []string {
+	return []string{
+		labels.AsString(provider.DepSourceLabel, source),
+		labels.AsString(provider.DepLanguageLabel, "go"),
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// testModules is the output of go list -m -json all for a workspace of the
+// app and lib modules, where xpath is replaced by a fork, groupcache by a
+// directory and the v1.3.12 of xmlquery is excluded
+const testModules = `{
+	"Path": "example.com/app",
+	"Main": true,
+	"Dir": "/src/app"
+}
+{
+	"Path": "example.com/lib",
+	"Main": true,
+	"Dir": "/src/lib"
+}
+{
+	"Path": "github.com/antchfx/jsonquery",
+	"Version": "v1.3.0",
+	"Dir": "/go/pkg/mod/github.com/antchfx/jsonquery@v1.3.0"
+}
+{
+	"Path": "github.com/antchfx/xmlquery",
+	"Version": "v1.3.13",
+	"Indirect": true
+}
+{
+	"Path": "github.com/antchfx/xpath",
+	"Version": "v1.2.1",
+	"Indirect": true,
+	"Replace": {
+		"Path": "github.com/acme/xpath",
+		"Version": "v1.2.4",
+		"Dir": "/go/pkg/mod/github.com/acme/xpath@v1.2.4"
+	}
+}
+{
+	"Path": "github.com/golang/groupcache",
+	"Version": "v0.0.0-20200121045136-8c9f03a8e57e",
+	"Indirect": true,
+	"Replace": {
+		"Path": "../groupcache",
+		"Dir": "/src/groupcache"
+	}
+}
+{
+	"Path": "gopkg.in/yaml.v3",
+	"Version": "v3.0.1"
+}
+`
+
+const testGraph = `example.com/app example.com/lib@v0.0.0-00010101000000-000000000000
+example.com/app github.com/antchfx/jsonquery@v1.3.0
+example.com/app github.com/antchfx/xmlquery@v1.3.13
+example.com/lib gopkg.in/yaml.v3@v3.0.1
+example.com/lib github.com/antchfx/jsonquery@v1.2.0
+github.com/antchfx/jsonquery@v1.3.0 github.com/antchfx/xpath@v1.2.1
+github.com/antchfx/jsonquery@v1.3.0 github.com/golang/groupcache@v0.0.0-20200121045136-8c9f03a8e57e
+github.com/antchfx/jsonquery@v1.2.0 gopkg.in/check.v1@v1.0.0
+github.com/antchfx/xmlquery@v1.3.12 github.com/antchfx/xpath@v1.2.0
+github.com/antchfx/xmlquery@v1.3.13 github.com/antchfx/xpath@v1.2.1
+`
+
+func Test_parseGoDepLines(t *testing.T) {
+	modules, err := parseModules([]byte(testModules))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	downloadable := depLabels(golangDownloadableDepSourceLabel)
+	xpath := provider.Dep{
+		Name:     "github.com/antchfx/xpath",
+		Version:  "v1.2.4",
+		Indirect: true,
+		Labels:   downloadable,
+		Extras:   map[string]interface{}{"replace": "github.com/acme/xpath@v1.2.4"},
+	}
+	groupcache := provider.Dep{
+		Name:     "github.com/golang/groupcache",
+		Version:  "v0.0.0-20200121045136-8c9f03a8e57e",
+		Indirect: true,
+		Labels:   depLabels(golangLocalDepSourceLabel),
+		Extras:   map[string]interface{}{"replace": "../groupcache"},
+	}
+	tests := []struct {
+		name    string
+		graph   string
+		want    []provider.DepDAGItem
+		wantErr bool
+	}{
+		{
+			name:  "an empty go dep output shouldn't produce anything",
+			graph: "",
+			want:  []provider.DepDAGItem{},
+		},
+		{
+			name:    "an invalid go dep output should produce an error",
+			graph:   "invalid-dep\nexample.com/app gopkg.in/yaml.v3@v3.0.1",
+			wantErr: true,
+		},
+		{
+			name:  "the selected versions of the modules of the workspace",
+			graph: testGraph,
+			want: []provider.DepDAGItem{
+				{
+					Dep: provider.Dep{Name: "github.com/antchfx/jsonquery", Version: "v1.3.0", Labels: downloadable},
+					AddedDeps: []provider.DepDAGItem{
+						{Dep: xpath},
+						{Dep: groupcache},
+					},
+				},
+				{
+					Dep: provider.Dep{Name: "github.com/antchfx/xmlquery", Version: "v1.3.13", Indirect: true, Labels: downloadable},
+					AddedDeps: []provider.DepDAGItem{
+						{Dep: xpath},
+					},
+				},
+				{
+					Dep:       provider.Dep{Name: "gopkg.in/yaml.v3", Version: "v3.0.1", Labels: downloadable},
+					AddedDeps: []provider.DepDAGItem{},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGoDepLines(strings.Split(tt.graph, "\n"), modules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGoDepLines() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected deps %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_moduleDirs(t *testing.T) {
+	modules, err := parseModules([]byte(testModules))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dirs := moduleDirs(modules)
+	for path, want := range map[string]string{
+		"github.com/antchfx/jsonquery": "/go/pkg/mod/github.com/antchfx/jsonquery@v1.3.0",
+		"github.com/antchfx/xpath":     "/go/pkg/mod/github.com/acme/xpath@v1.2.4",
+		"github.com/golang/groupcache": "/src/groupcache",
+	} {
+		if dirs[path] != want {
+			t.Errorf("expected the directory %s of %s, got %s", want, path, dirs[path])
+		}
+	}
+	if _, ok := dirs["gopkg.in/yaml.v3"]; ok {
+		t.Errorf("expected no directory for a module that is not downloaded")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// Prints the dependencies of the node.js project of the working directory
+// for the generic provider, the ones locked in package-lock.json,
+// pnpm-lock.yaml or yarn.lock, or the ones required by package.json when it
+// is not locked
+func main() {
+	deps, err := GetDependencies(".", os.Getenv(provider.DependencyLicensesEnv) == "true")
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(deps) == 0 {
+		return
+	}
+
+	m := map[uri.URI][]*provider.Dep{
+		uri.File(packageJSON): deps,
+	}
+	jsonStr, err := json.Marshal(m)
+	if err != nil {
+		log.Fatal(fmt.Errorf("unable to marshal dependencies"))
+		return
+	}
+
+	// Outputs the dependency list for the generic provider
+	fmt.Println(string(jsonStr))
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	packageJSON     = "package.json"
+	packageLock     = "package-lock.json"
+	npmShrinkwrap   = "npm-shrinkwrap.json"
+	pnpmLock        = "pnpm-lock.yaml"
+	yarnLock        = "yarn.lock"
+	nodeModulesDir  = "node_modules"
+	nodeModulesPath = nodeModulesDir + "/"
+
+	// This will communicate that, the dep is downloadable and not vendored.
+	nodeDownloadableDepSourceLabel = "downloadable"
+	// nodeLocalDepSourceLabel is the source of the packages of the
+	// workspaces and of the directories of the project
+	nodeLocalDepSourceLabel = "local"
+)
+
+// packageManifest is the part of package.json the dependencies are read from
+type packageManifest struct {
+	Name                 string            `json:"name"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+// requirements returns the constraints of the packages the manifest
+// requires, and whether they are only required for development
+func (m packageManifest) requirements() map[string]requirement {
+	required := map[string]requirement{}
+	for name, c := range m.DevDependencies {
+		required[name] = requirement{constraint: c, dev: true}
+	}
+	for _, deps := range []map[string]string{m.PeerDependencies, m.OptionalDependencies, m.Dependencies} {
+		for name, c := range deps {
+			required[name] = requirement{constraint: c}
+		}
+	}
+	return required
+}
+
+// requirement is a package that a manifest requires
+type requirement struct {
+	constraint string
+	dev        bool
+}
+
+// lockedPackage is a package of a lock file
+type lockedPackage struct {
+	name      string
+	version   string
+	integrity string
+	license   string
+	local     bool
+	// dir is the directory the package is installed in, when the lock file
+	// records it
+	dir string
+	// dev is whether the package is only for development, when the lock
+	// file records it
+	dev bool
+	// dependencies are the keys of the locked packages it depends on
+	dependencies []string
+}
+
+// lockFile is the packages of a lock file by key, the key depends on the
+// lock file
+type lockFile struct {
+	packages map[string]*lockedPackage
+	// direct are the requirements of the manifests by the key of the package
+	// they are locked to
+	direct map[string]requirement
+	// scoped is set when the lock file records whether the packages are only
+	// for development
+	scoped bool
+}
+
+// GetDependencies returns the dependencies of the node.js project of the
+// directory. The packages locked in package-lock.json or
+// npm-shrinkwrap.json, pnpm-lock.yaml or yarn.lock, the first one found, are
+// the dependencies, the ones that package.json does not require are
+// indirect. The requirements of package.json are the dependencies with their
+// constraint as version when the project is not locked. Nothing is returned
+// without package.json.
+func GetDependencies(dir string, licenses bool) ([]*provider.Dep, error) {
+	content, err := os.ReadFile(filepath.Join(dir, packageJSON))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := packageManifest{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", packageJSON, err)
+	}
+
+	var lock *lockFile
+	for _, l := range []struct {
+		file  string
+		parse func([]byte, packageManifest) (*lockFile, error)
+	}{
+		{packageLock, parsePackageLock},
+		{npmShrinkwrap, parsePackageLock},
+		{pnpmLock, parsePnpmLock},
+		{yarnLock, parseYarnLock},
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, l.file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lock, err = l.parse(content, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", l.file, err)
+		}
+		break
+	}
+	if lock == nil {
+		return manifestDependencies(manifest), nil
+	}
+	if licenses {
+		readInstalledLicenses(dir, lock)
+	}
+	return lockedDependencies(lock, licenses), nil
+}
+
+// manifestDependencies returns the requirements of package.json, sorted by
+// name
+func manifestDependencies(manifest packageManifest) []*provider.Dep {
+	required := manifest.requirements()
+	names := []string{}
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	deps := []*provider.Dep{}
+	for _, name := range names {
+		r := required[name]
+		source := nodeDownloadableDepSourceLabel
+		if isLocalSpecifier(r.constraint) {
+			source = nodeLocalDepSourceLabel
+		}
+		deps = append(deps, &provider.Dep{
+			Name:    name,
+			Version: r.constraint,
+			Labels:  depLabels(source),
+			Extras:  map[string]interface{}{"dev": r.dev},
+		})
+	}
+	return deps
+}
+
+// lockedDependencies returns the packages of the lock file sorted by name and
+// version, a package locked at several places once. The packages that are
+// not reachable from the requirements that are not only for development
+// are for development, when the lock file does not record it.
+func lockedDependencies(lock *lockFile, licenses bool) []*provider.Dep {
+	prod := map[string]bool{}
+	if !lock.scoped {
+		keys := []string{}
+		for key, r := range lock.direct {
+			if !r.dev {
+				keys = append(keys, key)
+			}
+		}
+		for len(keys) > 0 {
+			key := keys[len(keys)-1]
+			keys = keys[:len(keys)-1]
+			p, ok := lock.packages[key]
+			if !ok || prod[key] {
+				continue
+			}
+			prod[key] = true
+			keys = append(keys, p.dependencies...)
+		}
+	}
+
+	keys := []string{}
+	for key := range lock.packages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	byID := map[string]*provider.Dep{}
+	deps := []*provider.Dep{}
+	for _, key := range keys {
+		p := lock.packages[key]
+		if p.name == "" {
+			continue
+		}
+		dev := p.dev
+		if !lock.scoped {
+			dev = !prod[key]
+		}
+		r, direct := lock.direct[key]
+		id := p.name + "@" + p.version
+		if dep, ok := byID[id]; ok {
+			// the same version locked at several places is direct, and not
+			// only for development, when one of them is
+			if direct {
+				dep.Indirect = false
+				if r.constraint != "" {
+					dep.Extras["constraint"] = r.constraint
+				}
+			}
+			if !dev {
+				dep.Extras["dev"] = false
+			}
+			continue
+		}
+		source := nodeDownloadableDepSourceLabel
+		if p.local {
+			source = nodeLocalDepSourceLabel
+		}
+		dep := &provider.Dep{
+			Name:               p.name,
+			Version:            p.version,
+			Indirect:           !direct,
+			ResolvedIdentifier: p.integrity,
+			Labels:             depLabels(source),
+			Extras:             map[string]interface{}{"dev": dev},
+		}
+		if direct && r.constraint != "" {
+			dep.Extras["constraint"] = r.constraint
+		}
+		if licenses {
+			dep.License = p.license
+		}
+		byID[id] = dep
+		deps = append(deps, dep)
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Version < deps[j].Version
+	})
+	return deps
+}
+
+// isLocalSpecifier returns whether a version specifier is a directory or a
+// workspace rather than a version of a registry
+func isLocalSpecifier(specifier string) bool {
+	for _, prefix := range []string{"file:", "link:", "workspace:", "portal:", "./", "../", "/"} {
+		if strings.HasPrefix(specifier, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readInstalledLicenses sets the licenses of the packages that the lock file
+// has none for from the package.json of the installed packages. The packages
+// that are not installed have no license.
+func readInstalledLicenses(dir string, lock *lockFile) {
+	for _, p := range lock.packages {
+		if p.license != "" || p.name == "" {
+			continue
+		}
+		paths := []string{
+			filepath.Join(dir, nodeModulesDir, filepath.FromSlash(p.name), packageJSON),
+			filepath.Join(dir, nodeModulesDir, ".pnpm", strings.ReplaceAll(p.name, "/", "+")+"@"+p.version, nodeModulesDir, filepath.FromSlash(p.name), packageJSON),
+		}
+		if p.dir != "" {
+			paths = append([]string{filepath.Join(dir, filepath.FromSlash(p.dir), packageJSON)}, paths...)
+		}
+		for _, path := range paths {
+			version, license := installedPackage(path)
+			if version == p.version {
+				p.license = license
+				break
+			}
+		}
+	}
+}
+
+// installedPackage returns the version and the license of the package.json
+// of an installed package
+func installedPackage(path string) (string, string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", ""
+	}
+	installed := struct {
+		Version  string          `json:"version"`
+		License  json.RawMessage `json:"license"`
+		Licenses json.RawMessage `json:"licenses"`
+	}{}
+	if err := json.Unmarshal(content, &installed); err != nil {
+		return "", ""
+	}
+	return installed.Version, packageLicense(installed.License, installed.Licenses)
+}
+
+// packageLicense returns the license of a package.json, an SPDX expression
+// or the type of the license objects of the older packages
+func packageLicense(license, licenses json.RawMessage) string {
+	var expression string
+	if json.Unmarshal(license, &expression) == nil && expression != "" {
+		if strings.Contains(expression, " OR ") || strings.Contains(expression, " AND ") {
+			return strings.TrimSuffix(strings.TrimPrefix(expression, "("), ")")
+		}
+		return provider.NormalizeLicense(expression)
+	}
+	typed := struct {
+		Type string `json:"type"`
+	}{}
+	if json.Unmarshal(license, &typed) == nil && typed.Type != "" {
+		return provider.NormalizeLicense(typed.Type)
+	}
+	list := []struct {
+		Type string `json:"type"`
+	}{}
+	found := map[string]bool{}
+	if json.Unmarshal(licenses, &list) == nil {
+		for _, l := range list {
+			found[provider.NormalizeLicense(l.Type)] = true
+		}
+	}
+	return provider.JoinLicenses(found)
+}
+
+func depLabels(source string) []string {
+	return []string{
+		labels.AsString(provider.DepSourceLabel, source),
+		labels.AsString(provider.DepLanguageLabel, "javascript"),
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const testPackageJSON = `{
+    "name": "app",
+    "version": "1.0.0",
+    "dependencies": {
+        "express": "^4.18.2",
+        "@acme/ui": "file:./packages/ui"
+    },
+    "devDependencies": {
+        "jest": "^29.7.0"
+    }
+}`
+
+const testPackageLock = `{
+    "name": "app",
+    "lockfileVersion": 3,
+    "requires": true,
+    "packages": {
+        "": {
+            "name": "app",
+            "version": "1.0.0",
+            "dependencies": {"express": "^4.18.2", "@acme/ui": "file:./packages/ui"},
+            "devDependencies": {"jest": "^29.7.0"}
+        },
+        "node_modules/@acme/ui": {"resolved": "packages/ui", "link": true},
+        "node_modules/express": {
+            "version": "4.18.2",
+            "resolved": "https://registry.npmjs.org/express/-/express-4.18.2.tgz",
+            "integrity": "sha512-express",
+            "license": "MIT",
+            "dependencies": {"debug": "2.6.9"}
+        },
+        "node_modules/debug": {
+            "version": "4.3.4",
+            "integrity": "sha512-debug4",
+            "dev": true,
+            "license": "MIT"
+        },
+        "node_modules/express/node_modules/debug": {
+            "version": "2.6.9",
+            "integrity": "sha512-debug2",
+            "license": "MIT"
+        },
+        "node_modules/jest": {
+            "version": "29.7.0",
+            "integrity": "sha512-jest",
+            "dev": true,
+            "license": "MIT",
+            "dependencies": {"debug": "^4.3.4"}
+        },
+        "packages/ui": {
+            "name": "@acme/ui",
+            "version": "0.1.0",
+            "dependencies": {"react": "^18.2.0"}
+        },
+        "node_modules/react": {
+            "version": "18.2.0",
+            "integrity": "sha512-react",
+            "license": "MIT"
+        }
+    }
+}`
+
+const testPackageLockV1 = `{
+    "name": "app",
+    "lockfileVersion": 1,
+    "dependencies": {
+        "express": {
+            "version": "4.18.2",
+            "integrity": "sha512-express",
+            "requires": {"debug": "2.6.9"},
+            "dependencies": {
+                "debug": {"version": "2.6.9", "integrity": "sha512-debug2"}
+            }
+        },
+        "jest": {"version": "29.7.0", "integrity": "sha512-jest", "dev": true}
+    }
+}`
+
+const testYarnLock = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@acme/ui@file:./packages/ui":
+  version "0.1.0"
+
+debug@2.6.9:
+  version "2.6.9"
+  resolved "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz"
+  integrity sha512-debug2
+
+debug@^4.3.4:
+  version "4.3.4"
+  integrity sha512-debug4
+
+express@^4.18.2:
+  version "4.18.2"
+  integrity sha512-express
+  dependencies:
+    debug "2.6.9"
+
+jest@^29.7.0:
+  version "29.7.0"
+  integrity sha512-jest
+  dependencies:
+    debug "^4.3.4"
+`
+
+const testBerryLock = `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"@acme/ui@file:./packages/ui::locator=app%40workspace%3A.":
+  version: 0.1.0
+  resolution: "@acme/ui@file:./packages/ui#./packages/ui::hash=1&locator=app%40workspace%3A."
+  languageName: node
+  linkType: hard
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+  dependencies:
+    express: ^4.18.2
+  languageName: unknown
+  linkType: soft
+
+"debug@npm:2.6.9":
+  version: 2.6.9
+  resolution: "debug@npm:2.6.9"
+  checksum: debug2
+  languageName: node
+  linkType: hard
+
+"debug@npm:^4.3.4":
+  version: 4.3.4
+  resolution: "debug@npm:4.3.4"
+  checksum: debug4
+  languageName: node
+  linkType: hard
+
+"express@npm:^4.18.2":
+  version: 4.18.2
+  resolution: "express@npm:4.18.2"
+  dependencies:
+    debug: 2.6.9
+  checksum: express
+  languageName: node
+  linkType: hard
+
+"jest@npm:^29.7.0":
+  version: 29.7.0
+  resolution: "jest@npm:29.7.0"
+  dependencies:
+    debug: ^4.3.4
+  checksum: jest
+  languageName: node
+  linkType: hard
+`
+
+const testPnpmLock = `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      '@acme/ui':
+        specifier: file:./packages/ui
+        version: link:packages/ui
+      express:
+        specifier: ^4.18.2
+        version: 4.18.2
+    devDependencies:
+      jest:
+        specifier: ^29.7.0
+        version: 29.7.0
+
+packages:
+
+  debug@2.6.9:
+    resolution: {integrity: sha512-debug2}
+
+  debug@4.3.4:
+    resolution: {integrity: sha512-debug4}
+
+  express@4.18.2:
+    resolution: {integrity: sha512-express}
+
+  jest@29.7.0:
+    resolution: {integrity: sha512-jest}
+
+snapshots:
+
+  debug@2.6.9: {}
+
+  debug@4.3.4: {}
+
+  express@4.18.2:
+    dependencies:
+      debug: 2.6.9
+
+  jest@29.7.0:
+    dependencies:
+      debug: 4.3.4
+`
+
+const testPnpmLockV6 = `lockfileVersion: '6.0'
+
+dependencies:
+  express:
+    specifier: ^4.18.2
+    version: 4.18.2
+
+devDependencies:
+  jest:
+    specifier: ^29.7.0
+    version: 29.7.0
+
+packages:
+
+  /debug@2.6.9:
+    resolution: {integrity: sha512-debug2}
+    dev: false
+
+  /express@4.18.2:
+    resolution: {integrity: sha512-express}
+    dependencies:
+      debug: 2.6.9
+    dev: false
+
+  /jest@29.7.0:
+    resolution: {integrity: sha512-jest}
+    dev: true
+`
+
+func labelsOf(source string) []string {
+	return []string{
+		"konveyor.io/dep-source=" + source,
+		"konveyor.io/language=javascript",
+	}
+}
+
+func TestGetDependencies(t *testing.T) {
+	// the dependencies of the lock files without the local package
+	locked := func(debug4 bool, integrity map[string]string) []*provider.Dep {
+		deps := []*provider.Dep{
+			{Name: "debug", Version: "2.6.9", Indirect: true, ResolvedIdentifier: integrity["debug2"], Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false}},
+		}
+		if debug4 {
+			deps = append(deps, &provider.Dep{Name: "debug", Version: "4.3.4", Indirect: true, ResolvedIdentifier: integrity["debug4"], Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": true}})
+		}
+		return append(deps,
+			&provider.Dep{Name: "express", Version: "4.18.2", ResolvedIdentifier: integrity["express"], Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false, "constraint": "^4.18.2"}},
+			&provider.Dep{Name: "jest", Version: "29.7.0", ResolvedIdentifier: integrity["jest"], Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": true, "constraint": "^29.7.0"}},
+		)
+	}
+	npmIntegrity := map[string]string{"debug2": "sha512-debug2", "debug4": "sha512-debug4", "express": "sha512-express", "jest": "sha512-jest"}
+	berryIntegrity := map[string]string{"debug2": "debug2", "debug4": "debug4", "express": "express", "jest": "jest"}
+	ui := &provider.Dep{Name: "@acme/ui", Version: "0.1.0", Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false, "constraint": "file:./packages/ui"}}
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		licenses bool
+		want     []*provider.Dep
+		wantErr  bool
+	}{
+		{
+			name: "a directory without package.json has no dependencies",
+		},
+		{
+			name:  "the requirements are the dependencies without a lock",
+			files: map[string]string{packageJSON: testPackageJSON},
+			want: []*provider.Dep{
+				{Name: "@acme/ui", Version: "file:./packages/ui", Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "express", Version: "^4.18.2", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "jest", Version: "^29.7.0", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": true}},
+			},
+		},
+		{
+			name:     "the packages of package-lock.json are the dependencies",
+			files:    map[string]string{packageJSON: testPackageJSON, packageLock: testPackageLock},
+			licenses: true,
+			want: func() []*provider.Dep {
+				deps := append([]*provider.Dep{ui}, locked(true, npmIntegrity)...)
+				deps = append(deps, &provider.Dep{Name: "react", Version: "18.2.0", ResolvedIdentifier: "sha512-react", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false, "constraint": "^18.2.0"}})
+				for _, d := range deps[1:] {
+					d.License = "MIT"
+				}
+				return deps
+			}(),
+		},
+		{
+			name:  "the packages of a package-lock.json of version 1 are the dependencies",
+			files: map[string]string{packageJSON: testPackageJSON, packageLock: testPackageLockV1},
+			want:  locked(false, npmIntegrity),
+		},
+		{
+			name:  "the packages of yarn.lock are the dependencies",
+			files: map[string]string{packageJSON: testPackageJSON, yarnLock: testYarnLock},
+			want:  append([]*provider.Dep{{Name: "@acme/ui", Version: "0.1.0", Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false, "constraint": "file:./packages/ui"}}}, locked(true, npmIntegrity)...),
+		},
+		{
+			name:  "the packages of the yarn.lock of yarn 2 are the dependencies",
+			files: map[string]string{packageJSON: testPackageJSON, yarnLock: testBerryLock},
+			want:  append([]*provider.Dep{ui}, locked(true, berryIntegrity)...),
+		},
+		{
+			name:  "the packages of pnpm-lock.yaml are the dependencies",
+			files: map[string]string{packageJSON: testPackageJSON, pnpmLock: testPnpmLock},
+			want:  locked(true, npmIntegrity),
+		},
+		{
+			name:  "the packages of the pnpm-lock.yaml of pnpm 6 are the dependencies",
+			files: map[string]string{packageJSON: testPackageJSON, pnpmLock: testPnpmLockV6},
+			want:  locked(false, npmIntegrity),
+		},
+		{
+			name: "the licenses of the installed packages",
+			files: map[string]string{
+				packageJSON:                         testPackageJSON,
+				yarnLock:                            testYarnLock,
+				"node_modules/express/package.json": `{"name": "express", "version": "4.18.2", "license": "MIT"}`,
+				"node_modules/jest/package.json":    `{"name": "jest", "version": "29.7.0", "licenses": [{"type": "BSD"}, {"type": "Apache License, Version 2.0"}]}`,
+				"node_modules/debug/package.json":   `{"name": "debug", "version": "4.3.4", "license": "(MIT OR Apache-2.0)"}`,
+			},
+			licenses: true,
+			want: func() []*provider.Dep {
+				deps := append([]*provider.Dep{{Name: "@acme/ui", Version: "0.1.0", Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false, "constraint": "file:./packages/ui"}}}, locked(true, npmIntegrity)...)
+				deps[2].License = "MIT OR Apache-2.0"
+				deps[3].License = "MIT"
+				deps[4].License = "Apache-2.0 OR BSD"
+				return deps
+			}(),
+		},
+		{
+			name:    "an invalid lock is an error",
+			files:   map[string]string{packageJSON: testPackageJSON, packageLock: "{"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := GetDependencies(dir, tt.licenses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d dependencies, want %d: %#v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("got dependency %#v, want %#v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// npmLockPackage is a package of the packages of a package-lock.json of
+// version 2 or 3, by the directory it is installed in
+type npmLockPackage struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Resolved             string            `json:"resolved"`
+	Integrity            string            `json:"integrity"`
+	Link                 bool              `json:"link"`
+	Dev                  bool              `json:"dev"`
+	License              json.RawMessage   `json:"license"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+// npmLockDependency is a dependency of a package-lock.json of version 1, with
+// the dependencies installed under it
+type npmLockDependency struct {
+	Version      string                       `json:"version"`
+	Integrity    string                       `json:"integrity"`
+	Dev          bool                         `json:"dev"`
+	Requires     map[string]string            `json:"requires"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+type npmLockFile struct {
+	Packages     map[string]npmLockPackage    `json:"packages"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+// parsePackageLock reads a package-lock.json or npm-shrinkwrap.json, the
+// packages are keyed by the directory they are installed in, such as
+// node_modules/a/node_modules/b
+func parsePackageLock(content []byte, manifest packageManifest) (*lockFile, error) {
+	npmLock := npmLockFile{}
+	if err := json.Unmarshal(content, &npmLock); err != nil {
+		return nil, err
+	}
+	lock := &lockFile{packages: map[string]*lockedPackage{}, direct: map[string]requirement{}, scoped: true}
+	if npmLock.Packages != nil {
+		parseLockPackages(npmLock.Packages, manifest, lock)
+		return lock, nil
+	}
+
+	// the version 1 has the packages installed under each one
+	requires := map[string]map[string]string{}
+	var walk func(prefix string, deps map[string]npmLockDependency)
+	walk = func(prefix string, deps map[string]npmLockDependency) {
+		for name, d := range deps {
+			key := prefix + nodeModulesPath + name
+			lock.packages[key] = &lockedPackage{
+				name:      name,
+				version:   d.Version,
+				integrity: d.Integrity,
+				local:     isLocalSpecifier(d.Version),
+				dir:       key,
+				dev:       d.Dev,
+			}
+			requires[key] = d.Requires
+			walk(key+"/", d.Dependencies)
+		}
+	}
+	walk("", npmLock.Dependencies)
+	for key, names := range requires {
+		for name := range names {
+			if dep, ok := resolveNpm(lock.packages, key, name); ok {
+				lock.packages[key].dependencies = append(lock.packages[key].dependencies, dep)
+			}
+		}
+	}
+	for name, r := range manifest.requirements() {
+		if key, ok := resolveNpm(lock.packages, "", name); ok {
+			lock.direct[key] = r
+		}
+	}
+	return lock, nil
+}
+
+// parseLockPackages reads the packages of a package-lock.json of version 2
+// or 3. The requirements of the root and of the workspaces are direct, the
+// links to the workspaces are local packages.
+func parseLockPackages(packages map[string]npmLockPackage, manifest packageManifest, lock *lockFile) {
+	exists := map[string]*lockedPackage{}
+	for key := range packages {
+		exists[key] = nil
+	}
+	for key, p := range packages {
+		if !strings.HasPrefix(key, nodeModulesPath) && !strings.Contains(key, "/"+nodeModulesPath) {
+			// the root or a workspace
+			required := packageManifest{
+				Dependencies:         p.Dependencies,
+				DevDependencies:      p.DevDependencies,
+				OptionalDependencies: p.OptionalDependencies,
+				PeerDependencies:     p.PeerDependencies,
+			}
+			if key == "" && p.Dependencies == nil && p.DevDependencies == nil {
+				required = manifest
+			}
+			for name, r := range required.requirements() {
+				if dep, ok := resolveNpm(exists, key, name); ok {
+					lock.direct[dep] = r
+				}
+			}
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = key[strings.LastIndex(key, nodeModulesPath)+len(nodeModulesPath):]
+		}
+		locked := &lockedPackage{
+			name:      name,
+			version:   p.Version,
+			integrity: p.Integrity,
+			license:   packageLicense(p.License, nil),
+			local:     strings.HasPrefix(p.Resolved, "file:"),
+			dir:       key,
+			dev:       p.Dev,
+		}
+		from := key
+		if p.Link {
+			// the package is the workspace it links to
+			target := packages[p.Resolved]
+			locked.version = target.Version
+			locked.local = true
+			locked.dir = p.Resolved
+			from = p.Resolved
+			p = target
+		}
+		for _, deps := range []map[string]string{p.Dependencies, p.OptionalDependencies, p.PeerDependencies} {
+			for dep := range deps {
+				if depKey, ok := resolveNpm(exists, from, dep); ok {
+					locked.dependencies = append(locked.dependencies, depKey)
+				}
+			}
+		}
+		lock.packages[key] = locked
+	}
+}
+
+// resolveNpm returns the key of the package a package depends on, the one
+// installed in the closest node_modules up from it
+func resolveNpm(packages map[string]*lockedPackage, from, name string) (string, bool) {
+	dir := from
+	for {
+		key := path.Join(dir, nodeModulesDir, name)
+		if _, ok := packages[key]; ok {
+			return key, true
+		}
+		if dir == "" {
+			return "", false
+		}
+		if i := strings.LastIndex(dir, "/"+nodeModulesPath); i >= 0 {
+			dir = dir[:i]
+		} else {
+			dir = ""
+		}
+	}
+}
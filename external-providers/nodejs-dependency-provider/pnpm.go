@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// pnpmVersion is the version a dependency of an importer is locked to, only
+// the version before pnpm 6 and along with its specifier since then
+type pnpmVersion struct {
+	Specifier string `yaml:"specifier"`
+	Version   string `yaml:"version"`
+}
+
+func (v *pnpmVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&v.Version); err == nil {
+		return nil
+	}
+	type plain pnpmVersion
+	return unmarshal((*plain)(v))
+}
+
+// pnpmImporter is the root project or a workspace of a pnpm-lock.yaml
+type pnpmImporter struct {
+	Specifiers           map[string]string      `yaml:"specifiers"`
+	Dependencies         map[string]pnpmVersion `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmVersion `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmVersion `yaml:"optionalDependencies"`
+}
+
+// pnpmPackage is a package, or a snapshot of a package with its
+// dependencies since pnpm 9
+type pnpmPackage struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Resolution struct {
+		Integrity string `yaml:"integrity"`
+		Directory string `yaml:"directory"`
+	} `yaml:"resolution"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+type pnpmLockFile struct {
+	LockfileVersion interface{}             `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter `yaml:"importers"`
+	pnpmImporter    `yaml:",inline"`
+	Packages        map[string]pnpmPackage `yaml:"packages"`
+	Snapshots       map[string]pnpmPackage `yaml:"snapshots"`
+}
+
+// parsePnpmLock reads a pnpm-lock.yaml of pnpm 5 to 9. The packages are
+// keyed as in the lock file, such as /lodash/4.17.21 for pnpm 5,
+// /lodash@4.17.21 for pnpm 6 and lodash@4.17.21 since pnpm 9, with the peer
+// dependencies they are resolved with if any.
+func parsePnpmLock(content []byte, manifest packageManifest) (*lockFile, error) {
+	pnpm := pnpmLockFile{}
+	if err := yaml.Unmarshal(content, &pnpm); err != nil {
+		return nil, err
+	}
+	major := strings.SplitN(strings.Trim(fmt.Sprint(pnpm.LockfileVersion), `'"`), ".", 2)[0]
+	// key returns the key of the package a dependency is locked to, or false
+	// for a link to a directory
+	key := func(name, version string) (string, bool) {
+		switch {
+		case version == "" || strings.HasPrefix(version, "link:"):
+			return "", false
+		case strings.HasPrefix(version, "/"):
+			// an alias of another package before pnpm 9
+			return version, true
+		case major == "5":
+			return "/" + name + "/" + version, true
+		case major == "6":
+			return "/" + name + "@" + version, true
+		}
+		if at, peers := strings.Index(version[1:], "@"), strings.Index(version, "("); at >= 0 && (peers < 0 || at+1 < peers) {
+			// an alias of another package
+			return version, true
+		}
+		return name + "@" + version, true
+	}
+
+	lock := &lockFile{packages: map[string]*lockedPackage{}, direct: map[string]requirement{}}
+	snapshots := pnpm.Snapshots
+	if snapshots == nil {
+		snapshots = pnpm.Packages
+	}
+	for k, s := range snapshots {
+		id := strings.TrimPrefix(k, "/")
+		if i := strings.Index(id, "("); i >= 0 {
+			id = id[:i]
+		}
+		p := pnpm.Packages[k]
+		if pnpm.Snapshots != nil {
+			p = pnpm.Packages[id]
+		}
+		name, version := pnpmPackageID(id, major)
+		if p.Name != "" {
+			name, version = p.Name, p.Version
+		}
+		locked := &lockedPackage{
+			name:      name,
+			version:   version,
+			integrity: p.Resolution.Integrity,
+			local:     p.Resolution.Directory != "",
+		}
+		for _, deps := range []map[string]string{s.Dependencies, s.OptionalDependencies} {
+			for dep, v := range deps {
+				if depKey, ok := key(dep, v); ok {
+					locked.dependencies = append(locked.dependencies, depKey)
+				}
+			}
+		}
+		lock.packages[k] = locked
+	}
+
+	importers := pnpm.Importers
+	if importers == nil {
+		importers = map[string]pnpmImporter{".": pnpm.pnpmImporter}
+	}
+	required := manifest.requirements()
+	for path, importer := range importers {
+		for _, deps := range []struct {
+			deps map[string]pnpmVersion
+			dev  bool
+		}{{importer.DevDependencies, true}, {importer.OptionalDependencies, false}, {importer.Dependencies, false}} {
+			for name, v := range deps.deps {
+				depKey, ok := key(name, v.Version)
+				if !ok {
+					continue
+				}
+				r := requirement{constraint: v.Specifier, dev: deps.dev}
+				if r.constraint == "" {
+					r.constraint = importer.Specifiers[name]
+				}
+				if path == "." {
+					if manifestRequirement, ok := required[name]; ok && r.constraint == "" {
+						r.constraint = manifestRequirement.constraint
+					}
+				}
+				if previous, ok := lock.direct[depKey]; ok && !previous.dev {
+					r.dev = false
+				}
+				lock.direct[depKey] = r
+			}
+		}
+	}
+	return lock, nil
+}
+
+// pnpmPackageID returns the name and version of the key of a package without
+// its peer dependencies, name/version before pnpm 6 and name@version since
+func pnpmPackageID(id, major string) (string, string) {
+	if major == "5" {
+		i := strings.LastIndex(id, "/")
+		if i < 0 {
+			return id, ""
+		}
+		version := id[i+1:]
+		if j := strings.Index(version, "_"); j >= 0 {
+			version = version[:j]
+		}
+		return id[:i], version
+	}
+	if i := strings.LastIndex(id, "@"); i > 0 {
+		return id[:i], id[i+1:]
+	}
+	return id, ""
+}
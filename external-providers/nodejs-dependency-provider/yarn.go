@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yarnEntry is an entry of a yarn.lock, the package that its descriptors,
+// such as lodash@^4.17.0, resolve to
+type yarnEntry struct {
+	descriptors          []string
+	Version              string            `yaml:"version"`
+	Resolution           string            `yaml:"resolution"`
+	Resolved             string            `yaml:"resolved"`
+	Integrity            string            `yaml:"integrity"`
+	Checksum             string            `yaml:"checksum"`
+	LinkType             string            `yaml:"linkType"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// parseYarnLock reads a yarn.lock of yarn 1, or of yarn 2 and later which is
+// yaml. The packages are keyed by their first descriptor, the workspaces are
+// left out.
+func parseYarnLock(content []byte, manifest packageManifest) (*lockFile, error) {
+	var entries []yarnEntry
+	var err error
+	berry := bytes.Contains(content, []byte("\n__metadata:"))
+	if berry {
+		entries, err = parseBerryLock(content)
+	} else {
+		entries, err = parseClassicYarnLock(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the descriptors of the dependencies of yarn 2 have the npm: protocol
+	// when they are locked, but not when they are required
+	descriptor := func(name, r string) string {
+		if berry && !strings.Contains(r, ":") {
+			r = "npm:" + r
+		}
+		return name + "@" + r
+	}
+	keys := map[string]string{}
+	for _, e := range entries {
+		for _, d := range e.descriptors {
+			keys[d] = e.descriptors[0]
+		}
+	}
+	lock := &lockFile{packages: map[string]*lockedPackage{}, direct: map[string]requirement{}}
+	for _, e := range entries {
+		if len(e.descriptors) == 0 || strings.Contains(e.Resolution, "@workspace:") {
+			continue
+		}
+		key := e.descriptors[0]
+		p := &lockedPackage{
+			name:      descriptorName(key),
+			version:   e.Version,
+			integrity: e.Integrity,
+			local:     e.LinkType == "soft" || isLocalSpecifier(strings.TrimPrefix(key, descriptorName(key)+"@")),
+		}
+		if e.Checksum != "" {
+			p.integrity = e.Checksum
+		}
+		for _, deps := range []map[string]string{e.Dependencies, e.OptionalDependencies} {
+			for name, r := range deps {
+				if dep, ok := keys[descriptor(name, r)]; ok {
+					p.dependencies = append(p.dependencies, dep)
+				}
+			}
+		}
+		lock.packages[key] = p
+	}
+	for name, r := range manifest.requirements() {
+		if key, ok := keys[descriptor(name, r.constraint)]; ok {
+			lock.direct[key] = r
+		}
+	}
+	return lock, nil
+}
+
+// descriptorName returns the name of a descriptor, the scope of the scoped
+// packages included
+func descriptorName(descriptor string) string {
+	if i := strings.Index(descriptor[1:], "@"); i >= 0 {
+		return descriptor[:i+1]
+	}
+	return descriptor
+}
+
+// parseBerryLock reads the entries of the yaml yarn.lock of yarn 2 and later
+func parseBerryLock(content []byte) ([]yarnEntry, error) {
+	raw := yaml.MapSlice{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	entries := []yarnEntry{}
+	for _, item := range raw {
+		key, _ := item.Key.(string)
+		if key == "__metadata" {
+			continue
+		}
+		value, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		e := yarnEntry{}
+		if err := yaml.Unmarshal(value, &e); err != nil {
+			return nil, err
+		}
+		// the descriptors of the directories are bound to the workspace that
+		// requires them, such as file:./ui::locator=app%40workspace%3A.
+		for _, d := range splitDescriptors(key) {
+			if i := strings.Index(d, "::"); i >= 0 {
+				d = d[:i]
+			}
+			e.descriptors = append(e.descriptors, d)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parseClassicYarnLock reads the entries of the yarn.lock of yarn 1, such as
+//
+//	"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+//	  version "7.12.13"
+//	  integrity sha512-...
+//	  dependencies:
+//	    "@babel/highlight" "^7.12.13"
+func parseClassicYarnLock(content []byte) ([]yarnEntry, error) {
+	entries := []yarnEntry{}
+	var e *yarnEntry
+	var section map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case indent == 0:
+			entries = append(entries, yarnEntry{descriptors: splitDescriptors(strings.TrimSuffix(trimmed, ":"))})
+			e = &entries[len(entries)-1]
+			section = nil
+		case e == nil:
+			continue
+		case indent <= 2 && strings.HasSuffix(trimmed, ":"):
+			section = map[string]string{}
+			switch strings.TrimSuffix(trimmed, ":") {
+			case "dependencies":
+				e.Dependencies = section
+			case "optionalDependencies":
+				e.OptionalDependencies = section
+			}
+		case indent <= 2:
+			section = nil
+			key, value := splitYarnField(trimmed)
+			switch key {
+			case "version":
+				e.Version = value
+			case "resolved":
+				e.Resolved = value
+			case "integrity":
+				e.Integrity = value
+			}
+		case section != nil:
+			key, value := splitYarnField(trimmed)
+			section[key] = value
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// splitYarnField returns the unquoted key and value of a field of yarn 1
+func splitYarnField(line string) (string, string) {
+	var key, value string
+	if strings.HasPrefix(line, `"`) {
+		end := strings.Index(line[1:], `"`) + 1
+		key, value = line[1:end], line[end+1:]
+	} else if i := strings.IndexAny(line, " :"); i >= 0 {
+		key, value = line[:i], line[i+1:]
+	} else {
+		key = line
+	}
+	return key, strings.Trim(strings.TrimSpace(value), `"`)
+}
+
+// splitDescriptors returns the descriptors of the key of an entry
+func splitDescriptors(key string) []string {
+	descriptors := []string{}
+	for _, d := range strings.Split(key, ",") {
+		if d = strings.Trim(strings.TrimSpace(d), `"`); d != "" {
+			descriptors = append(descriptors, d)
+		}
+	}
+	return descriptors
+}
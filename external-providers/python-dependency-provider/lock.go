@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// pipfileLockPackage is a package locked in Pipfile.lock
+type pipfileLockPackage struct {
+	Version  string   `json:"version"`
+	Hashes   []string `json:"hashes"`
+	Markers  string   `json:"markers"`
+	Git      string   `json:"git"`
+	Ref      string   `json:"ref"`
+	Path     string   `json:"path"`
+	Editable bool     `json:"editable"`
+}
+
+type pipfileLockFile struct {
+	Default map[string]pipfileLockPackage `json:"default"`
+	Develop map[string]pipfileLockPackage `json:"develop"`
+}
+
+// pipenvDependencies returns the packages of Pipfile.lock sorted by name, the
+// default ones first. The ones that the Pipfile does not require are
+// indirect, none is without a Pipfile.
+func pipenvDependencies(dir string) ([]*provider.Dep, error) {
+	content, err := os.ReadFile(filepath.Join(dir, pipfileLock))
+	if err != nil {
+		return nil, err
+	}
+	lock := pipfileLockFile{}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", pipfileLock, err)
+	}
+	manifest, err := readOptional(filepath.Join(dir, pipfile))
+	if err != nil {
+		return nil, err
+	}
+	tables := parseTOML(manifest)
+	required := map[string]string{}
+	for _, section := range []string{"packages", "dev-packages"} {
+		for _, t := range tables {
+			if t.name != section {
+				continue
+			}
+			for _, key := range t.keys {
+				required[normalizeName(key)] = constraint(t.values[key])
+			}
+		}
+	}
+
+	deps := []*provider.Dep{}
+	for _, packages := range []struct {
+		packages map[string]pipfileLockPackage
+		dev      bool
+	}{{lock.Default, false}, {lock.Develop, true}} {
+		names := []string{}
+		for name := range packages.packages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := packages.packages[name]
+			source := pythonDownloadableDepSourceLabel
+			if p.Path != "" {
+				source = pythonLocalDepSourceLabel
+			}
+			requiredConstraint, ok := required[normalizeName(name)]
+			dep := &provider.Dep{
+				Name:               normalizeName(name),
+				Version:            strings.TrimPrefix(strings.TrimPrefix(p.Version, "=="), "="),
+				Indirect:           manifest != "" && !ok,
+				ResolvedIdentifier: p.Ref,
+				Labels:             depLabels(source),
+				Extras:             map[string]interface{}{"dev": packages.dev},
+			}
+			if dep.ResolvedIdentifier == "" && len(p.Hashes) > 0 {
+				dep.ResolvedIdentifier = p.Hashes[0]
+			}
+			if requiredConstraint != "" {
+				dep.Extras["constraint"] = requiredConstraint
+			}
+			if p.Markers != "" {
+				dep.Extras["markers"] = p.Markers
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// poetryDependencies returns the packages of poetry.lock, in the order they
+// are locked in. The ones that pyproject.toml does not require are indirect,
+// the ones only required by its groups other than main are for development.
+func poetryDependencies(dir string) ([]*provider.Dep, error) {
+	content, err := os.ReadFile(filepath.Join(dir, poetryLock))
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readOptional(filepath.Join(dir, pyprojectTOML))
+	if err != nil {
+		return nil, err
+	}
+	required, requiredDev := pyprojectRequirements(parseTOML(manifest))
+
+	deps := []*provider.Dep{}
+	var dep *provider.Dep
+	for _, t := range parseTOML(string(content)) {
+		switch t.name {
+		case "package":
+			name := normalizeName(tomlString(t.values["name"]))
+			mainConstraint, isMain := required[name]
+			devConstraint, isDev := requiredDev[name]
+			category := tomlString(t.values["category"])
+			dep = &provider.Dep{
+				Name:     name,
+				Version:  tomlString(t.values["version"]),
+				Indirect: manifest != "" && !isMain && !isDev,
+				Labels:   depLabels(pythonDownloadableDepSourceLabel),
+				Extras:   map[string]interface{}{"dev": category == "dev" || (isDev && !isMain)},
+			}
+			if isMain && mainConstraint != "" {
+				dep.Extras["constraint"] = mainConstraint
+			} else if isDev && devConstraint != "" {
+				dep.Extras["constraint"] = devConstraint
+			}
+			deps = append(deps, dep)
+		case "package.source":
+			if dep == nil {
+				continue
+			}
+			switch tomlString(t.values["type"]) {
+			case "directory", "file":
+				dep.Labels = depLabels(pythonLocalDepSourceLabel)
+			case "git":
+				dep.ResolvedIdentifier = tomlString(t.values["resolved_reference"])
+			}
+		}
+	}
+	return deps, nil
+}
+
+var requirementNamePattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*(.*)$`)
+
+// pyprojectRequirements returns the constraints of the packages that the
+// pyproject.toml requires by normalized name, for the main group and the
+// other ones. Both the tool.poetry tables and the project ones of PEP 621 are
+// read.
+func pyprojectRequirements(tables []tomlTable) (map[string]string, map[string]string) {
+	required, requiredDev := map[string]string{}, map[string]string{}
+	for _, t := range tables {
+		switch {
+		case t.name == "tool.poetry.dependencies" || t.name == "tool.poetry.group.main.dependencies":
+			for _, key := range t.keys {
+				if key != "python" {
+					required[normalizeName(key)] = constraint(t.values[key])
+				}
+			}
+		case t.name == "tool.poetry.dev-dependencies" || (strings.HasPrefix(t.name, "tool.poetry.group.") && strings.HasSuffix(t.name, ".dependencies")):
+			for _, key := range t.keys {
+				requiredDev[normalizeName(key)] = constraint(t.values[key])
+			}
+		case t.name == "project":
+			for _, r := range tomlStrings(t.values["dependencies"]) {
+				if m := requirementNamePattern.FindStringSubmatch(r); m != nil {
+					required[normalizeName(m[1])] = specifier(m[3])
+				}
+			}
+		case t.name == "project.optional-dependencies":
+			for _, key := range t.keys {
+				for _, r := range tomlStrings(t.values[key]) {
+					if m := requirementNamePattern.FindStringSubmatch(r); m != nil {
+						required[normalizeName(m[1])] = specifier(m[3])
+					}
+				}
+			}
+		}
+	}
+	return required, requiredDev
+}
+
+// specifier returns the version specifier of the rest of a PEP 508
+// requirement, without its markers
+func specifier(rest string) string {
+	if i := strings.Index(rest, ";"); i >= 0 {
+		rest = rest[:i]
+	}
+	rest = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(rest), "("), ")")
+	return strings.Join(strings.Fields(rest), "")
+}
+
+var versionKeyPattern = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+
+// constraint returns the version constraint of a requirement of a Pipfile or
+// of pyproject.toml, either a string or an inline table with a version
+func constraint(raw string) string {
+	if strings.HasPrefix(raw, "{") {
+		if m := versionKeyPattern.FindStringSubmatch(raw); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	if c := tomlString(raw); c != "*" {
+		return c
+	}
+	return ""
+}
+
+// tomlTable is a table of a toml file with the raw values of its keys, the
+// arrays of tables are a table for each of their elements
+type tomlTable struct {
+	name   string
+	keys   []string
+	values map[string]string
+}
+
+// parseTOML reads the tables of a toml file in order. Only the keys of the
+// tables and their raw values are read, the values that span several lines
+// joined on one, which is enough for the lock files and the requirements of
+// the manifests. The keys before the first table are in a table without a
+// name.
+func parseTOML(content string) []tomlTable {
+	tables := []tomlTable{{values: map[string]string{}}}
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name := strings.Trim(line, "[]")
+			tables = append(tables, tomlTable{name: unquoteKey(name), values: map[string]string{}})
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''"):
+			delimiter := value[:3]
+			for !strings.Contains(value[3:], delimiter) && i+1 < len(lines) {
+				i++
+				value += "\n" + lines[i]
+			}
+		default:
+			for depth(value) > 0 && i+1 < len(lines) {
+				i++
+				value += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+			}
+		}
+		t := &tables[len(tables)-1]
+		key = unquoteKey(strings.TrimSpace(key))
+		t.keys = append(t.keys, key)
+		t.values[key] = value
+	}
+	return tables
+}
+
+// unquoteKey returns a dotted key without the quotes of its parts
+func unquoteKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// stripTOMLComment removes the comment of a line, the # in strings are kept
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// depth returns the number of arrays and inline tables that a value opens
+// and does not close, the brackets in strings are not counted
+func depth(value string) int {
+	n := 0
+	var quote rune
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			n++
+		case c == ']' || c == '}':
+			n--
+		}
+	}
+	return n
+}
+
+// tomlString returns the string of a raw value, empty when it is not one
+func tomlString(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1]
+	}
+	if s, err := strconv.Unquote(raw); err == nil && strings.HasPrefix(raw, `"`) {
+		return s
+	}
+	return ""
+}
+
+var tomlStringPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|'([^']*)'`)
+
+// tomlStrings returns the strings of a raw array value
+func tomlStrings(raw string) []string {
+	strs := []string{}
+	for _, m := range tomlStringPattern.FindAllStringSubmatch(raw, -1) {
+		if m[0][0] == '\'' {
+			strs = append(strs, m[2])
+			continue
+		}
+		strs = append(strs, tomlString(m[0]))
+	}
+	return strs
+}
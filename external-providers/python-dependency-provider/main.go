@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// Prints the dependencies of the python project of the working directory for
+// the generic provider, the ones locked in poetry.lock or Pipfile.lock, or
+// the requirements of requirements.txt, resolved with pip when --resolve is
+// set
+func main() {
+	resolve := flag.Bool("resolve", false, "resolve the requirements of requirements.txt and their transitive dependencies with pip")
+	python := flag.String("python", "python3", "python interpreter that runs pip")
+	flag.Parse()
+
+	file, deps, err := GetDependencies(".", Options{
+		Licenses: os.Getenv(provider.DependencyLicensesEnv) == "true",
+		Resolve:  *resolve,
+		Python:   *python,
+	})
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(deps) == 0 {
+		return
+	}
+
+	m := map[uri.URI][]*provider.Dep{
+		uri.File(file): deps,
+	}
+	jsonStr, err := json.Marshal(m)
+	if err != nil {
+		log.Fatal(fmt.Errorf("unable to marshal dependencies"))
+		return
+	}
+
+	// Outputs the dependency list for the generic provider
+	fmt.Println(string(jsonStr))
+}
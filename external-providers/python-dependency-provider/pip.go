@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// pipReport is the part of the installation report of pip the resolved
+// packages are read from
+type pipReport struct {
+	Install []struct {
+		Requested    bool `json:"requested"`
+		DownloadInfo struct {
+			DirInfo     *struct{} `json:"dir_info"`
+			ArchiveInfo *struct {
+				Hash string `json:"hash"`
+			} `json:"archive_info"`
+			VCSInfo *struct {
+				CommitID string `json:"commit_id"`
+			} `json:"vcs_info"`
+		} `json:"download_info"`
+		Metadata struct {
+			Name              string   `json:"name"`
+			Version           string   `json:"version"`
+			License           string   `json:"license"`
+			LicenseExpression string   `json:"license_expression"`
+			Classifier        []string `json:"classifier"`
+		} `json:"metadata"`
+	} `json:"install"`
+}
+
+// resolveRequirements resolves the requirements of requirements.txt and their
+// transitive dependencies with pip, without installing them. The packages
+// that requirements.txt does not require are indirect. pip downloads the
+// packages from the index of its configuration, such as the one of
+// PIP_INDEX_URL.
+func resolveRequirements(dir, python string, licenses bool) ([]*provider.Dep, error) {
+	report, err := os.CreateTemp("", "pip-report-*.json")
+	if err != nil {
+		return nil, err
+	}
+	report.Close()
+	defer os.Remove(report.Name())
+
+	cmd := exec.Command(python, "-m", "pip", "install", "--dry-run", "--ignore-installed", "--quiet", "--report", report.Name(), "-r", requirementsTxt)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("unable to resolve the requirements of %s with pip: %w: %s", requirementsTxt, err, strings.TrimSpace(string(output)))
+	}
+	content, err := os.ReadFile(report.Name())
+	if err != nil {
+		return nil, err
+	}
+	resolved := pipReport{}
+	if err := json.Unmarshal(content, &resolved); err != nil {
+		return nil, fmt.Errorf("unable to parse the report of pip: %w", err)
+	}
+
+	// the constraints are the ones of the requirements
+	requirements, err := parseRequirements(filepath.Join(dir, requirementsTxt), map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	constraints := map[string]string{}
+	for _, r := range requirements {
+		constraints[normalizeName(r.name)] = r.specifier
+	}
+
+	deps := []*provider.Dep{}
+	for _, p := range resolved.Install {
+		source := pythonDownloadableDepSourceLabel
+		if p.DownloadInfo.DirInfo != nil {
+			source = pythonLocalDepSourceLabel
+		}
+		dep := &provider.Dep{
+			Name:     normalizeName(p.Metadata.Name),
+			Version:  p.Metadata.Version,
+			Indirect: !p.Requested,
+			Labels:   depLabels(source),
+			Extras:   map[string]interface{}{},
+		}
+		switch {
+		case p.DownloadInfo.VCSInfo != nil:
+			dep.ResolvedIdentifier = p.DownloadInfo.VCSInfo.CommitID
+		case p.DownloadInfo.ArchiveInfo != nil:
+			dep.ResolvedIdentifier = p.DownloadInfo.ArchiveInfo.Hash
+		}
+		if c := constraints[dep.Name]; c != "" {
+			dep.Extras["constraint"] = c
+		}
+		if licenses {
+			dep.License = metadataLicense(p.Metadata.LicenseExpression, p.Metadata.License, p.Metadata.Classifier)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// metadataLicense returns the license of the metadata of a package: its
+// license expression, or the license field when it is a name rather than the
+// text of the license, or else the license classifiers
+func metadataLicense(expression, license string, classifiers []string) string {
+	if expression != "" {
+		return expression
+	}
+	license = strings.TrimSpace(license)
+	if license != "" && !strings.Contains(license, "\n") && len(license) <= 100 && !strings.EqualFold(license, "unknown") {
+		return provider.NormalizeLicense(license)
+	}
+	if spdx := provider.ClassifyLicenseText(license); spdx != "" {
+		return spdx
+	}
+	found := map[string]bool{}
+	for _, c := range classifiers {
+		if !strings.HasPrefix(c, "License ::") {
+			continue
+		}
+		parts := strings.Split(c, "::")
+		name := strings.TrimSpace(parts[len(parts)-1])
+		if name != "OSI Approved" {
+			found[provider.NormalizeLicense(name)] = true
+		}
+	}
+	return provider.JoinLicenses(found)
+}
+
+// setInstalledLicenses sets the licenses of the dependencies from the
+// metadata of the packages installed in the virtual environment of the
+// project, .venv or venv, or the one of VIRTUAL_ENV. The dependencies that
+// are not installed have no license.
+func setInstalledLicenses(dir string, deps []*provider.Dep) {
+	envs := []string{filepath.Join(dir, ".venv"), filepath.Join(dir, "venv")}
+	if env := os.Getenv("VIRTUAL_ENV"); env != "" {
+		envs = append(envs, env)
+	}
+	installed := map[string]string{}
+	for _, env := range envs {
+		for _, pattern := range []string{
+			filepath.Join(env, "lib", "python*", "site-packages", "*.dist-info", "METADATA"),
+			filepath.Join(env, "Lib", "site-packages", "*.dist-info", "METADATA"),
+		} {
+			files, _ := filepath.Glob(pattern)
+			for _, file := range files {
+				name, license := readMetadata(file)
+				if _, ok := installed[name]; name != "" && !ok {
+					installed[name] = license
+				}
+			}
+		}
+	}
+	for _, dep := range deps {
+		dep.License = installed[dep.Name]
+	}
+}
+
+// readMetadata returns the normalized name and the license of the METADATA
+// file of an installed package, the headers before its description are read
+func readMetadata(path string) (string, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	var name, expression, license string
+	classifiers := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			name = normalizeName(value)
+		case "License-Expression":
+			expression = value
+		case "License":
+			license = value
+		case "Classifier":
+			classifiers = append(classifiers, value)
+		}
+	}
+	return name, metadataLicense(expression, license, classifiers)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	requirementsTxt = "requirements.txt"
+	pipfile         = "Pipfile"
+	pipfileLock     = "Pipfile.lock"
+	pyprojectTOML   = "pyproject.toml"
+	poetryLock      = "poetry.lock"
+
+	// This will communicate that, the dep is downloadable and not vendored.
+	pythonDownloadableDepSourceLabel = "downloadable"
+	// pythonLocalDepSourceLabel is the source of the packages installed from
+	// a directory, which are in the project
+	pythonLocalDepSourceLabel = "local"
+)
+
+// Options are how the dependencies are found
+type Options struct {
+	// Licenses sets the licenses of the dependencies
+	Licenses bool
+	// Resolve resolves the requirements of requirements.txt and their
+	// transitive dependencies with pip
+	Resolve bool
+	// Python is the interpreter that runs pip
+	Python string
+}
+
+// GetDependencies returns the dependencies of the python project of the
+// directory and the file they are read from. The packages locked in
+// poetry.lock are the dependencies of a poetry project, then the ones of
+// Pipfile.lock, and then the requirements of requirements.txt, resolved with
+// pip when the options say so. Nothing is returned without any of them.
+func GetDependencies(dir string, opts Options) (string, []*provider.Dep, error) {
+	var file string
+	var deps []*provider.Dep
+	var err error
+	switch {
+	case exists(filepath.Join(dir, poetryLock)):
+		file = pyprojectTOML
+		deps, err = poetryDependencies(dir)
+	case exists(filepath.Join(dir, pipfileLock)):
+		file = pipfile
+		deps, err = pipenvDependencies(dir)
+	case exists(filepath.Join(dir, requirementsTxt)):
+		file = requirementsTxt
+		if opts.Resolve {
+			deps, err = resolveRequirements(dir, opts.Python, opts.Licenses)
+			if err != nil {
+				return "", nil, err
+			}
+			return file, deps, nil
+		}
+		deps, err = requirementsDependencies(dir)
+	default:
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if opts.Licenses {
+		setInstalledLicenses(dir, deps)
+	}
+	return file, deps, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// requirement is a requirement of a requirements file
+type requirement struct {
+	name      string
+	specifier string
+	markers   string
+	// local is set for the requirements of a directory
+	local bool
+}
+
+var (
+	requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*(.*)$`)
+	pinnedPattern      = regexp.MustCompile(`^===?\s*([^\s,*;]+)$`)
+	eggPattern         = regexp.MustCompile(`[#&]egg=([A-Za-z0-9._-]+)`)
+	separatorPattern   = regexp.MustCompile(`[-_.]+`)
+)
+
+// normalizeName returns the normalized name of a package, the names that
+// only differ by case and by their separators are the same package
+func normalizeName(name string) string {
+	return separatorPattern.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// version returns the version a requirement is pinned to, or its specifier
+// when it is not pinned
+func (r requirement) version() string {
+	if m := pinnedPattern.FindStringSubmatch(r.specifier); m != nil {
+		return m[1]
+	}
+	return r.specifier
+}
+
+// requirementsDependencies returns the requirements of requirements.txt and
+// of the files it includes, in the order they are required in
+func requirementsDependencies(dir string) ([]*provider.Dep, error) {
+	requirements, err := parseRequirements(filepath.Join(dir, requirementsTxt), map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	deps := []*provider.Dep{}
+	seen := map[string]bool{}
+	for _, r := range requirements {
+		name := normalizeName(r.name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		source := pythonDownloadableDepSourceLabel
+		if r.local {
+			source = pythonLocalDepSourceLabel
+		}
+		dep := &provider.Dep{
+			Name:    name,
+			Version: r.version(),
+			Labels:  depLabels(source),
+			Extras:  map[string]interface{}{},
+		}
+		if r.specifier != "" {
+			dep.Extras["constraint"] = r.specifier
+		}
+		if r.markers != "" {
+			dep.Extras["markers"] = r.markers
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseRequirements reads the requirements of a requirements file and of the
+// files it includes with -r. The constraints files, the options and the
+// requirements of a URL without a name are skipped.
+func parseRequirements(path string, included map[string]bool) ([]requirement, error) {
+	if included[path] {
+		return nil, nil
+	}
+	included[path] = true
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	requirements := []requirement{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	line := ""
+	for scanner.Scan() {
+		// the lines that end with a backslash go on with the next one
+		text := scanner.Text()
+		if strings.HasSuffix(text, `\`) {
+			line += strings.TrimSuffix(text, `\`)
+			continue
+		}
+		line, text = "", line+text
+		text = stripComment(text)
+		if text == "" {
+			continue
+		}
+		if include, ok := option(text, "-r", "--requirement"); ok {
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			nested, err := parseRequirements(include, included)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read the requirements included by %s: %w", path, err)
+			}
+			requirements = append(requirements, nested...)
+			continue
+		}
+		if editable, ok := option(text, "-e", "--editable"); ok {
+			if r, ok := locationRequirement(editable); ok {
+				requirements = append(requirements, r)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "-") {
+			continue
+		}
+		if r, ok := parseRequirement(text); ok {
+			requirements = append(requirements, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return requirements, nil
+}
+
+// stripComment removes the comment of a line, a # at its start or after a
+// space, so that the fragments of the URLs are kept
+func stripComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if i := strings.Index(line, " #"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "\t#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// option returns the value of a line that is the short or long option, such
+// as -r other.txt, --requirement other.txt or --requirement=other.txt
+func option(line, short, long string) (string, bool) {
+	for _, prefix := range []string{long + "=", long + " ", short + " ", short} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// parseRequirement reads a requirement such as requests[socks]>=2.28,<3 ;
+// python_version > "3.8", or a direct reference such as name @ url. The
+// hashes and other per requirement options are left out.
+func parseRequirement(line string) (requirement, bool) {
+	if i := strings.Index(line, " --"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if !requirementPattern.MatchString(line) {
+		return locationRequirement(line)
+	}
+	markers := ""
+	if i := strings.Index(line, ";"); i >= 0 {
+		markers = strings.TrimSpace(line[i+1:])
+		line = strings.TrimSpace(line[:i])
+	}
+	m := requirementPattern.FindStringSubmatch(line)
+	rest := strings.TrimSpace(m[3])
+	if strings.HasPrefix(rest, "@") {
+		location := strings.TrimSpace(strings.TrimPrefix(rest, "@"))
+		return requirement{name: m[1], markers: markers, local: isLocal(location)}, true
+	}
+	if m[2] == "" && (strings.Contains(rest, "/") || strings.Contains(rest, ":")) {
+		// a URL or path that starts like a name
+		return locationRequirement(line)
+	}
+	specifier := strings.TrimSuffix(strings.TrimPrefix(rest, "("), ")")
+	return requirement{name: m[1], specifier: strings.Join(strings.Fields(specifier), ""), markers: markers}, true
+}
+
+// locationRequirement returns the requirement of a URL or path, named by its
+// egg fragment or by the directory it is installed from
+func locationRequirement(location string) (requirement, bool) {
+	if m := eggPattern.FindStringSubmatch(location); m != nil {
+		return requirement{name: m[1], local: isLocal(location)}, true
+	}
+	if !isLocal(location) {
+		return requirement{}, false
+	}
+	path := strings.TrimPrefix(location, "file://")
+	if i := strings.IndexAny(path, "[;#"); i >= 0 {
+		path = path[:i]
+	}
+	name := filepath.Base(filepath.Clean(strings.TrimSpace(path)))
+	if name == "." || name == string(filepath.Separator) || strings.HasSuffix(name, ".whl") || strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".zip") {
+		return requirement{}, false
+	}
+	return requirement{name: name, local: true}, true
+}
+
+// isLocal returns whether a location is a path or file URL rather than a
+// remote one
+func isLocal(location string) bool {
+	return strings.HasPrefix(location, "file:") || strings.HasPrefix(location, ".") || strings.HasPrefix(location, "/") || !strings.Contains(location, "://")
+}
+
+func readOptional(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(content), err
+}
+
+func depLabels(source string) []string {
+	return []string{
+		labels.AsString(provider.DepSourceLabel, source),
+		labels.AsString(provider.DepLanguageLabel, "python"),
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const testRequirements = `# the web app
+Django>=4.2,<5 ; python_version >= "3.8"
+requests[socks]==2.31.0 \
+    --hash=sha256:58cd2187c01e70e6e26505bca751777aa9f2ee0b7f4300988b709f44e013003f
+-r requirements-extra.txt
+-c constraints.txt
+--index-url https://pypi.example.com/simple
+-e ./libs/acme-tools
+git+https://github.com/example/flask-auth.git@v1.2#egg=Flask_Auth
+https://example.com/packages/unnamed-1.0.tar.gz
+`
+
+const testRequirementsExtra = `gunicorn
+django==4.2.1
+`
+
+const testPyproject = `[tool.poetry]
+name = "app"
+
+[tool.poetry.dependencies]
+python = "^3.10"
+requests = "^2.31"
+acme-tools = {path = "libs/acme-tools", develop = true}
+"Flask" = { version = "^2.3", extras = ["async"] }
+
+[tool.poetry.group.dev.dependencies]
+pytest = "^7.4" # the tests
+`
+
+const testPoetryLock = `# This file is automatically @generated by Poetry and should not be changed by hand.
+
+[[package]]
+name = "acme-tools"
+version = "0.1.0"
+description = "The tools of acme"
+optional = false
+python-versions = "^3.10"
+files = []
+develop = true
+
+[package.source]
+type = "directory"
+url = "libs/acme-tools"
+
+[[package]]
+name = "Flask"
+version = "2.3.3"
+description = "A simple framework for building complex web applications."
+optional = false
+python-versions = ">=3.8"
+files = [
+    {file = "flask-2.3.3-py3-none-any.whl", hash = "sha256:f69fcd559dc907ed196ab9df0e48471709175e696d6e698dd4dbe940f96ce66b"},
+    {file = "flask-2.3.3.tar.gz", hash = "sha256:09c347a92aa7ff4a8e7f3206795f30d826654baf38b873d0744cd571ca609efc"},
+]
+
+[package.dependencies]
+Jinja2 = ">=3.1.2"
+
+[package.extras]
+async = ["asgiref (>=3.2)"]
+
+[[package]]
+name = "jinja2"
+version = "3.1.2"
+description = "A very fast and expressive template engine."
+optional = false
+python-versions = ">=3.7"
+files = []
+
+[[package]]
+name = "pytest"
+version = "7.4.2"
+description = "pytest: simple powerful testing with Python"
+optional = false
+python-versions = ">=3.7"
+files = []
+
+[[package]]
+name = "requests"
+version = "2.31.0"
+description = "Python HTTP for Humans."
+optional = false
+python-versions = ">=3.7"
+files = []
+
+[package.source]
+type = "git"
+url = "https://github.com/psf/requests.git"
+reference = "main"
+resolved_reference = "147c8511ddbfa5e8f71bbf5c18ede0c4ceb3bba4"
+
+[metadata]
+lock-version = "2.0"
+python-versions = "^3.10"
+content-hash = "0a1b2c"
+`
+
+const testPipfile = `[[source]]
+url = "https://pypi.org/simple"
+verify_ssl = true
+name = "pypi"
+
+[packages]
+requests = "*"
+django = {version = ">=4.2", extras = ["bcrypt"]}
+
+[dev-packages]
+pytest = "==7.4.2"
+`
+
+const testPipfileLock = `{
+    "_meta": {"hash": {"sha256": "0a1b2c"}},
+    "default": {
+        "django": {"hashes": ["sha256:7e4225ec065e0f354ccf7349a22d209de09cc1c074832be9eb84c51c1799c432"], "markers": "python_version >= '3.8'", "version": "==4.2.5"},
+        "requests": {"hashes": ["sha256:58cd2187c01e70e6e26505bca751777aa9f2ee0b7f4300988b709f44e013003f"], "version": "==2.31.0"},
+        "urllib3": {"hashes": ["sha256:c97dfde1f7bd43a71c8d2a58e369e9b2bf692d1334ea9f9cae55add7d0dd0f84"], "version": "==2.0.4"},
+        "acme-tools": {"editable": true, "path": "./libs/acme-tools"}
+    },
+    "develop": {
+        "pytest": {"hashes": ["sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002"], "version": "==7.4.2"}
+    }
+}`
+
+func labelsOf(source string) []string {
+	return []string{
+		"konveyor.io/dep-source=" + source,
+		"konveyor.io/language=python",
+	}
+}
+
+func TestGetDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		licenses bool
+		wantFile string
+		want     []*provider.Dep
+		wantErr  bool
+	}{
+		{
+			name: "a directory without requirements has no dependencies",
+		},
+		{
+			name:     "the requirements are the dependencies",
+			files:    map[string]string{requirementsTxt: testRequirements, "requirements-extra.txt": testRequirementsExtra},
+			wantFile: requirementsTxt,
+			want: []*provider.Dep{
+				{Name: "django", Version: ">=4.2,<5", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"constraint": ">=4.2,<5", "markers": `python_version >= "3.8"`}},
+				{Name: "requests", Version: "2.31.0", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"constraint": "==2.31.0"}},
+				{Name: "gunicorn", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{}},
+				{Name: "acme-tools", Labels: labelsOf("local"), Extras: map[string]interface{}{}},
+				{Name: "flask-auth", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{}},
+			},
+		},
+		{
+			name:     "the packages locked by poetry are the dependencies",
+			files:    map[string]string{pyprojectTOML: testPyproject, poetryLock: testPoetryLock, requirementsTxt: testRequirements},
+			wantFile: pyprojectTOML,
+			want: []*provider.Dep{
+				{Name: "acme-tools", Version: "0.1.0", Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "flask", Version: "2.3.3", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false, "constraint": "^2.3"}},
+				{Name: "jinja2", Version: "3.1.2", Indirect: true, Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": false}},
+				{Name: "pytest", Version: "7.4.2", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"dev": true, "constraint": "^7.4"}},
+				{
+					Name:               "requests",
+					Version:            "2.31.0",
+					ResolvedIdentifier: "147c8511ddbfa5e8f71bbf5c18ede0c4ceb3bba4",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false, "constraint": "^2.31"},
+				},
+			},
+		},
+		{
+			name:     "the packages locked by pipenv are the dependencies",
+			files:    map[string]string{pipfile: testPipfile, pipfileLock: testPipfileLock},
+			wantFile: pipfile,
+			want: []*provider.Dep{
+				{Name: "acme-tools", Indirect: true, Labels: labelsOf("local"), Extras: map[string]interface{}{"dev": false}},
+				{
+					Name:               "django",
+					Version:            "4.2.5",
+					ResolvedIdentifier: "sha256:7e4225ec065e0f354ccf7349a22d209de09cc1c074832be9eb84c51c1799c432",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false, "constraint": ">=4.2", "markers": "python_version >= '3.8'"},
+				},
+				{
+					Name:               "requests",
+					Version:            "2.31.0",
+					ResolvedIdentifier: "sha256:58cd2187c01e70e6e26505bca751777aa9f2ee0b7f4300988b709f44e013003f",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false},
+				},
+				{
+					Name:               "urllib3",
+					Version:            "2.0.4",
+					Indirect:           true,
+					ResolvedIdentifier: "sha256:c97dfde1f7bd43a71c8d2a58e369e9b2bf692d1334ea9f9cae55add7d0dd0f84",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": false},
+				},
+				{
+					Name:               "pytest",
+					Version:            "7.4.2",
+					ResolvedIdentifier: "sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002",
+					Labels:             labelsOf("downloadable"),
+					Extras:             map[string]interface{}{"dev": true, "constraint": "==7.4.2"},
+				},
+			},
+		},
+		{
+			name: "the licenses are the ones of the installed packages",
+			files: map[string]string{
+				requirementsTxt: "requests==2.31.0\nflask\nunknown-package\n",
+				".venv/lib/python3.11/site-packages/requests-2.31.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: requests\nVersion: 2.31.0\nLicense: Apache 2.0\n\nRequests\n",
+				".venv/lib/python3.11/site-packages/flask-2.3.3.dist-info/METADATA":     "Metadata-Version: 2.1\nName: Flask\nVersion: 2.3.3\nClassifier: License :: OSI Approved :: BSD License\nClassifier: Programming Language :: Python\n",
+			},
+			licenses: true,
+			wantFile: requirementsTxt,
+			want: []*provider.Dep{
+				{Name: "requests", Version: "2.31.0", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{"constraint": "==2.31.0"}, License: "Apache-2.0"},
+				{Name: "flask", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{}, License: "BSD License"},
+				{Name: "unknown-package", Labels: labelsOf("downloadable"), Extras: map[string]interface{}{}},
+			},
+		},
+		{
+			name:    "an invalid lock is an error",
+			files:   map[string]string{pipfileLock: "{"},
+			wantErr: true,
+		},
+		{
+			name:    "a missing included requirements file is an error",
+			files:   map[string]string{requirementsTxt: "-r missing.txt\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			file, got, err := GetDependencies(dir, Options{Licenses: tt.licenses})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if file != tt.wantFile {
+				t.Errorf("got the dependencies of %q, want %q", file, tt.wantFile)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d dependencies, want %d: %#v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("got dependency %#v, want %#v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   requirement
+		wantOK bool
+	}{
+		{line: "requests", want: requirement{name: "requests"}, wantOK: true},
+		{line: "requests (>= 2.8.1, == 2.8.*)", want: requirement{name: "requests", specifier: ">=2.8.1,==2.8.*"}, wantOK: true},
+		{line: "pip @ https://github.com/pypa/pip/archive/1.3.1.zip", want: requirement{name: "pip"}, wantOK: true},
+		{line: "acme @ file:///src/acme", want: requirement{name: "acme", local: true}, wantOK: true},
+		{line: "./downloads/numpy-1.9.2-cp34-none-win32.whl"},
+		{line: "libs/acme", want: requirement{name: "acme", local: true}, wantOK: true},
+		{line: "https://example.com/unnamed.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseRequirement(tt.line)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRequirement() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMetadataLicense(t *testing.T) {
+	tests := []struct {
+		name        string
+		expression  string
+		license     string
+		classifiers []string
+		want        string
+	}{
+		{name: "the expression first", expression: "MIT OR Apache-2.0", license: "MIT", want: "MIT OR Apache-2.0"},
+		{name: "a license name", license: "BSD-3-Clause", want: "BSD-3-Clause"},
+		{name: "the text of a license", license: "Copyright 2023\n\nPermission is hereby granted, free of charge, to any person", want: "MIT"},
+		{
+			name:        "the classifiers without a license",
+			license:     "UNKNOWN",
+			classifiers: []string{"License :: OSI Approved :: MIT License", "License :: OSI Approved :: Apache Software License 2.0", "Topic :: Internet"},
+			want:        "Apache-2.0 OR MIT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataLicense(tt.expression, tt.license, tt.classifiers); got != tt.want {
+				t.Errorf("metadataLicense() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -86,6 +86,10 @@ func (b *BackoffHandler) Cancel(ctx context.Context, conn *Conn, id ID, cancelle
 // response
 // Request is called near the start of processing any request.
 func (b *BackoffHandler) Request(ctx context.Context, conn *Conn, direction Direction, r *WireRequest) context.Context {
+	// only the requests that are sent are backed off
+	if direction == Receive {
+		return ctx
+	}
 	//handle Back off
 	requestKey := requestKey{
 		method: r.Method,
@@ -0,0 +1,327 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// BatchRequest is a call or a notification sent in a batch with Batch
+type BatchRequest struct {
+	Method string
+	Params interface{}
+	// Notify sends the request as a notification, it gets no response
+	Notify bool
+	// Result is decoded from the response of a call, as with Call
+	Result interface{}
+	// Err is the error of the call once the batch is done
+	Err error
+}
+
+// Batch sends the requests in a single batch and waits for the responses of
+// the calls. The responses are decoded into the requests they answer, in
+// whatever order they arrive, and the error of each call is set on it. The
+// returned error is the one of the batch itself, such as a failure to send it
+// or the cancellation of the context.
+func (c *Conn) Batch(ctx context.Context, requests []*BatchRequest) (err error) {
+	if len(requests) == 0 {
+		return fmt.Errorf("a batch needs at least one request")
+	}
+	wireRequests := make([]*WireRequest, len(requests))
+	for i, r := range requests {
+		method, params, err := c.intercept(ctx, r.Method, r.Params)
+		if err != nil {
+			return err
+		}
+		jsonParams, err := marshalToRaw(params)
+		if err != nil {
+			return fmt.Errorf("marshalling batch parameters: %v", err)
+		}
+		wireRequests[i] = &WireRequest{
+			Method: method,
+			Params: jsonParams,
+		}
+		if !r.Notify {
+			id := c.nextID()
+			wireRequests[i].ID = &id
+		}
+	}
+	data, err := json.Marshal(wireRequests)
+	if err != nil {
+		return fmt.Errorf("marshalling batch: %v", err)
+	}
+	requestCtxs := make([]context.Context, len(requests))
+	for i, r := range wireRequests {
+		requestCtxs[i] = ctx
+		for _, h := range c.handlers {
+			requestCtxs[i] = h.Request(requestCtxs[i], c, Send, r)
+		}
+	}
+	// the responses can arrive in any order, the channels are buffered so
+	// that the ones that are not waited for yet do not block the connection
+	rchans := make([]chan *WireResponse, len(requests))
+	c.pendingMu.Lock()
+	for i, r := range wireRequests {
+		if r.ID != nil {
+			rchans[i] = make(chan *WireResponse, 1)
+			c.pending[*r.ID] = rchans[i]
+		}
+	}
+	c.pendingMu.Unlock()
+	defer func() {
+		c.pendingMu.Lock()
+		for _, r := range wireRequests {
+			if r.ID != nil {
+				delete(c.pending, *r.ID)
+			}
+		}
+		c.pendingMu.Unlock()
+		for i, r := range requests {
+			requestErr := r.Err
+			if err != nil {
+				requestErr = err
+			}
+			for _, h := range c.handlers {
+				h.Done(requestCtxs[i], requestErr)
+			}
+		}
+	}()
+
+	n, err := c.stream.Write(ctx, data)
+	for _, h := range c.handlers {
+		ctx = h.Wrote(ctx, n)
+	}
+	if err != nil {
+		return err
+	}
+	for i, r := range requests {
+		if rchans[i] == nil {
+			continue
+		}
+		select {
+		case response := <-rchans[i]:
+			for _, h := range c.handlers {
+				requestCtxs[i] = h.Response(requestCtxs[i], c, Receive, response)
+			}
+			r.Err = decodeResult(response, r.Result)
+		case <-ctx.Done():
+			cancelled := false
+			for _, h := range c.handlers {
+				if h.Cancel(ctx, c, *wireRequests[i].ID, cancelled) {
+					cancelled = true
+				}
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// handleBatch handles the requests of a batch received on the connection and
+// delivers its responses to the calls waiting for them.
+func (c *Conn) handleBatch(ctx context.Context, previous chan struct{}, data []byte) chan struct{} {
+	messages, err := decodeBatch(data)
+	if err != nil {
+		for _, h := range c.handlers {
+			h.Error(ctx, fmt.Errorf("unmarshal failed: %v", err))
+		}
+		return previous
+	}
+	if len(messages) == 0 {
+		// an empty batch is answered with a single error
+		return c.handleRequests(ctx, previous, []*combined{{}}, false)
+	}
+	requests := []*combined{}
+	for _, m := range messages {
+		msg := &combined{}
+		if err := json.Unmarshal(m, msg); err != nil {
+			// it is answered as an invalid request
+			requests = append(requests, &combined{})
+			continue
+		}
+		if msg.Method == "" && msg.ID != nil {
+			c.deliverResponse(msg)
+			continue
+		}
+		if msg.Method == cancelRequestMethod {
+			c.cancelReceived(msg.Params)
+		}
+		requests = append(requests, msg)
+	}
+	if len(requests) == 0 {
+		return previous
+	}
+	return c.handleRequests(ctx, previous, requests, true)
+}
+
+// handleRequests answers the requests received on the connection once the
+// previous ones are answered, so that they are handled in the order they were
+// received without blocking the responses to the calls of the connection. The
+// returned channel is closed once they are answered. The requests of a batch
+// are handled one after the other and their responses are sent in a single
+// batch, in the same order.
+func (c *Conn) handleRequests(ctx context.Context, previous chan struct{}, requests []*combined, batch bool) chan struct{} {
+	if c.requestHandler == nil {
+		for _, r := range requests {
+			for _, h := range c.handlers {
+				h.Error(ctx, fmt.Errorf("no request handler, ignoring request %q", r.Method))
+			}
+		}
+		return previous
+	}
+	// the requests can be canceled while they wait for the previous ones
+	requestCtxs := make([]context.Context, len(requests))
+	for i, r := range requests {
+		requestCtxs[i] = c.trackReceived(ctx, r.ID)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			for _, r := range requests {
+				c.untrackReceived(r.ID)
+			}
+		}()
+		select {
+		case <-previous:
+		case <-ctx.Done():
+			return
+		}
+		responses := []*WireResponse{}
+		for i, r := range requests {
+			if response := c.handleRequest(requestCtxs[i], r); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		// notifications are not answered
+		if len(responses) == 0 {
+			return
+		}
+		var data []byte
+		var err error
+		if batch {
+			data, err = json.Marshal(responses)
+		} else {
+			data, err = json.Marshal(responses[0])
+		}
+		if err == nil {
+			var n int64
+			n, err = c.stream.Write(ctx, data)
+			for _, h := range c.handlers {
+				ctx = h.Wrote(ctx, n)
+			}
+		}
+		if err != nil {
+			for _, h := range c.handlers {
+				h.Error(ctx, fmt.Errorf("sending responses failed: %v", err))
+			}
+		}
+	}()
+	return done
+}
+
+// closedChan returns a closed channel, for the requests that do not wait for
+// the previous ones
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// handleRequest calls the request handler with a received request and returns
+// its response, or nil for a notification.
+func (c *Conn) handleRequest(ctx context.Context, msg *combined) *WireResponse {
+	if msg.Method == "" {
+		return &WireResponse{
+			Error: NewErrorf(CodeInvalidRequest, "invalid request"),
+			ID:    msg.ID,
+		}
+	}
+	request := &WireRequest{
+		Method: msg.Method,
+		Params: msg.Params,
+		ID:     msg.ID,
+	}
+	for _, h := range c.handlers {
+		ctx = h.Request(ctx, c, Receive, request)
+	}
+	result, err := c.requestHandler(ctx, msg.Method, msg.Params)
+	defer func() {
+		for _, h := range c.handlers {
+			h.Done(ctx, err)
+		}
+	}()
+	if msg.ID == nil {
+		if err != nil {
+			for _, h := range c.handlers {
+				h.Error(ctx, fmt.Errorf("notification %q failed: %v", msg.Method, err))
+			}
+		}
+		return nil
+	}
+	response := &WireResponse{ID: msg.ID}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = NewErrorf(CodeUnknownError, "%v", err)
+		}
+		response.Error = rpcErr
+	} else if response.Result, err = marshalToRaw(result); err != nil {
+		response.Error = NewErrorf(CodeInternalError, "marshalling result: %v", err)
+	}
+	for _, h := range c.handlers {
+		ctx = h.Response(ctx, c, Send, response)
+	}
+	return response
+}
+
+// cancelRequestMethod is the notification a peer sends to cancel a request it
+// sent, with the ID of the request as its params
+const cancelRequestMethod = "$/cancelRequest"
+
+// trackReceived returns the context of a request received on the connection,
+// which is canceled when the peer cancels the request
+func (c *Conn) trackReceived(ctx context.Context, id *ID) context.Context {
+	if id == nil {
+		return ctx
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.receivedMu.Lock()
+	c.received[*id] = cancel
+	c.receivedMu.Unlock()
+	return ctx
+}
+
+func (c *Conn) untrackReceived(id *ID) {
+	if id == nil {
+		return
+	}
+	c.receivedMu.Lock()
+	cancel, ok := c.received[*id]
+	delete(c.received, *id)
+	c.receivedMu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// cancelReceived cancels the context of the received request with the ID of
+// the params of a cancel request, a string or a number
+func (c *Conn) cancelReceived(params *json.RawMessage) {
+	if params == nil {
+		return
+	}
+	cancelParams := struct {
+		ID *ID `json:"id"`
+	}{}
+	if err := json.Unmarshal(*params, &cancelParams); err != nil || cancelParams.ID == nil {
+		return
+	}
+	c.receivedMu.Lock()
+	cancel, ok := c.received[*cancelParams.ID]
+	c.receivedMu.Unlock()
+	if ok {
+		cancel()
+	}
+}
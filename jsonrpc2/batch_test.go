@@ -0,0 +1,145 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// pipe connects two connections, the returned stream is the raw end of the
+// second one when it is not run.
+func pipe() (Stream, Stream) {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	return NewHeaderStream(r1, w2), NewHeaderStream(r2, w1)
+}
+
+func TestBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+
+	received := make(chan string, 10)
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		received <- method
+		switch method {
+		case "echo":
+			var s string
+			json.Unmarshal(*params, &s)
+			return s, nil
+		case "fail":
+			return nil, NewErrorf(CodeInvalidParams, "bad params")
+		case "slow":
+			time.Sleep(50 * time.Millisecond)
+			return "slow", nil
+		}
+		return nil, fmt.Errorf("unknown method %s", method)
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	var first, second, slow string
+	requests := []*BatchRequest{
+		{Method: "slow", Result: &slow},
+		{Method: "echo", Params: "a", Result: &first},
+		{Method: "log", Params: "ignored", Notify: true},
+		{Method: "fail", Params: 1},
+		{Method: "echo", Params: "b", Result: &second},
+		{Method: "other"},
+	}
+	if err := client.Batch(ctx, requests); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if slow != "slow" || first != "a" || second != "b" {
+		t.Errorf("unexpected results %q, %q and %q", slow, first, second)
+	}
+	if requests[2].Err != nil {
+		t.Errorf("unexpected error for a notification %v", requests[2].Err)
+	}
+	if err, ok := requests[3].Err.(*Error); !ok || err.Code != CodeInvalidParams {
+		t.Errorf("expected an invalid params error, got %v", requests[3].Err)
+	}
+	if err, ok := requests[5].Err.(*Error); !ok || err.Code != CodeUnknownError || !strings.Contains(err.Message, "unknown method") {
+		t.Errorf("expected an unknown error, got %v", requests[5].Err)
+	}
+
+	// the requests of a batch are handled in order
+	got := []string{}
+	for range requests {
+		got = append(got, <-received)
+	}
+	if want := []string{"slow", "echo", "log", "fail", "echo", "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the requests in order %v, got %v", want, got)
+	}
+
+	// single calls still work alongside batches
+	var single string
+	if err := client.Call(ctx, "echo", "single", &single); err != nil || single != "single" {
+		t.Errorf("unexpected call result %q: %v", single, err)
+	}
+}
+
+func TestReceiveBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	raw, serverStream := pipe()
+	server := NewConn(serverStream, logr.Discard())
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		return method, nil
+	})
+	go server.Run(ctx)
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			name:    "calls and notifications",
+			request: `[{"jsonrpc":"2.0","method":"a","id":1},{"jsonrpc":"2.0","method":"n"},{"jsonrpc":"2.0","method":"b","id":"two"}]`,
+			want:    `[{"jsonrpc":"2.0","result":"a","id":1},{"jsonrpc":"2.0","result":"b","id":"two"}]`,
+		},
+		{
+			name:    "invalid requests",
+			request: `[1,{"jsonrpc":"2.0","method":"a","id":2}]`,
+			want:    `[{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request","data":null}},{"jsonrpc":"2.0","result":"a","id":2}]`,
+		},
+		{
+			name:    "empty batch",
+			request: `[]`,
+			want:    `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request","data":null}}`,
+		},
+		{
+			// a batch of notifications is not answered, the next call is
+			name:    "notifications only",
+			request: `[{"jsonrpc":"2.0","method":"n"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := raw.Write(ctx, []byte(tt.request)); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				raw.Write(ctx, []byte(`{"jsonrpc":"2.0","method":"after","id":3}`))
+				tt.want = `{"jsonrpc":"2.0","result":"after","id":3}`
+			}
+			data, _, err := raw.Read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, data)
+			}
+		})
+	}
+}
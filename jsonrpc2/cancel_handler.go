@@ -0,0 +1,38 @@
+package jsonrpc2
+
+import (
+	"context"
+)
+
+// CancelHandler sends a $/cancelRequest notification to the peer for each
+// call whose context is canceled before it is answered, so that a language
+// server stops working on the requests of an analysis that is aborted.
+type CancelHandler struct {
+	EmptyHandler
+}
+
+var _ Handler = &CancelHandler{}
+
+func NewCancelHandler() *CancelHandler {
+	return &CancelHandler{}
+}
+
+// Cancel notifies the peer that the call is canceled, unless a handler before
+// it already did.
+func (h *CancelHandler) Cancel(ctx context.Context, conn *Conn, id ID, cancelled bool) bool {
+	if cancelled {
+		return true
+	}
+	// the context of the call is canceled, the notification is sent with
+	// the background one
+	params := struct {
+		ID *ID `json:"id"`
+	}{ID: &id}
+	if err := conn.Notify(context.Background(), cancelRequestMethod, &params); err != nil {
+		for _, handler := range conn.handlers {
+			handler.Error(ctx, err)
+		}
+		return false
+	}
+	return true
+}
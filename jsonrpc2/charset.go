@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	charsetUTF8    = "utf-8"
+	charsetUTF16   = "utf-16"
+	charsetUTF16LE = "utf-16le"
+	charsetUTF16BE = "utf-16be"
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// normalizeCharset returns the name of a charset in lower case, with utf8
+// written utf-8 as the LSP specification allows it
+func normalizeCharset(charset string) string {
+	charset = strings.ToLower(strings.Trim(strings.TrimSpace(charset), `"`))
+	switch charset {
+	case "utf8":
+		return charsetUTF8
+	case "utf16":
+		return charsetUTF16
+	}
+	return charset
+}
+
+// decodeCharset returns a message in utf-8, without a byte order mark
+func decodeCharset(charset string, data []byte) ([]byte, error) {
+	switch charset {
+	case charsetUTF8:
+		return bytes.TrimPrefix(data, utf8BOM), nil
+	case charsetUTF16LE:
+		return decodeUTF16(data, binary.LittleEndian)
+	case charsetUTF16BE:
+		return decodeUTF16(data, binary.BigEndian)
+	case charsetUTF16:
+		// the byte order mark tells the byte order, big endian without one
+		if len(data) >= 2 && data[0] == 0xff && data[1] == 0xfe {
+			return decodeUTF16(data[2:], binary.LittleEndian)
+		}
+		if len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff {
+			return decodeUTF16(data[2:], binary.BigEndian)
+		}
+		return decodeUTF16(data, binary.BigEndian)
+	}
+	return nil, fmt.Errorf("unsupported charset %s", charset)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid utf-16 message of %d bytes", len(data))
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	// a byte order mark of the declared order is dropped
+	if len(units) > 0 && units[0] == 0xfeff {
+		units = units[1:]
+	}
+	decoded := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		decoded = utf8.AppendRune(decoded, r)
+	}
+	return decoded, nil
+}
+
+func sortedCharsets(charsets map[string]bool) []string {
+	names := make([]string, 0, len(charsets))
+	for c := range charsets {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	return names
+}
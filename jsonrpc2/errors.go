@@ -0,0 +1,79 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The errors of the standard codes, they match the errors with the same code
+// with errors.Is, whatever their message:
+//
+//	if errors.Is(err, jsonrpc2.ErrMethodNotFound) {
+//		// the server does not support the request
+//	}
+var (
+	ErrParse                = NewError(CodeParseError, "parse error")
+	ErrInvalidRequest       = NewError(CodeInvalidRequest, "invalid request")
+	ErrMethodNotFound       = NewError(CodeMethodNotFound, "method not found")
+	ErrInvalidParams        = NewError(CodeInvalidParams, "invalid params")
+	ErrInternal             = NewError(CodeInternalError, "internal error")
+	ErrServerOverloaded     = NewError(CodeServerOverloaded, "server overloaded")
+	ErrUnknown              = NewError(CodeUnknownError, "unknown error")
+	ErrServerNotInitialized = NewError(CodeServerNotInitialized, "server not initialized")
+	ErrRequestCancelled     = NewError(CodeRequestCancelled, "request cancelled")
+	ErrContentModified      = NewError(CodeContentModified, "content modified")
+	ErrServerCancelled      = NewError(CodeServerCancelled, "server cancelled")
+	ErrRequestFailed        = NewError(CodeRequestFailed, "request failed")
+)
+
+// NewError returns the error of the code with the message
+func NewError(code int64, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// NewErrorWithData returns the error of the code with the data, which is
+// sent as JSON in the data of the error so that the peer can tell the
+// failures of the same code apart. The error is only nil when the data can
+// not be marshalled.
+func NewErrorWithData(code int64, data interface{}, format string, args ...interface{}) (*Error, error) {
+	raw, err := marshalToRaw(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error data: %w", err)
+	}
+	rpcErr := NewErrorf(code, format, args...)
+	rpcErr.Data = raw
+	return rpcErr, nil
+}
+
+// Is matches the errors with the same code, so that the errors received from
+// a peer can be compared to the errors of the standard codes
+func (err *Error) Is(target error) bool {
+	var rpcErr *Error
+	if !errors.As(target, &rpcErr) || err == nil || rpcErr == nil {
+		return false
+	}
+	return err.Code == rpcErr.Code
+}
+
+// DecodeData decodes the data of the error into v, it fails when the error
+// has no data
+func (err *Error) DecodeData(v interface{}) error {
+	if err == nil || err.Data == nil || string(*err.Data) == "null" {
+		return fmt.Errorf("the error has no data")
+	}
+	if err := json.Unmarshal(*err.Data, v); err != nil {
+		return fmt.Errorf("unmarshalling error data: %w", err)
+	}
+	return nil
+}
+
+// ErrorCode returns the code of the *Error that err is or wraps, false when
+// it does not wrap one
+func ErrorCode(err error) (int64, bool) {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return 0, false
+	}
+	return rpcErr.Code, true
+}
@@ -0,0 +1,64 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestErrorData(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+
+	type missingDependency struct {
+		Dependency string `json:"dependency"`
+	}
+	router := NewRouter()
+	Register(router, "resolve", func(ctx context.Context, _ struct{}) (string, error) {
+		rpcErr, err := NewErrorWithData(CodeRequestFailed, missingDependency{Dependency: "junit"}, "unable to resolve %s", "junit")
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("resolving: %w", rpcErr)
+	})
+	server.SetRouter(router)
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	err := client.Call(ctx, "resolve", nil, nil)
+	if !errors.Is(err, ErrRequestFailed) || errors.Is(err, ErrRequestCancelled) {
+		t.Fatalf("expected a request failed error, got %v", err)
+	}
+	if code, ok := ErrorCode(fmt.Errorf("calling: %w", err)); !ok || code != CodeRequestFailed {
+		t.Errorf("expected the code of the wrapped error, got %d", code)
+	}
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "unable to resolve junit" {
+		t.Fatalf("expected the message of the error, got %v", err)
+	}
+	data := missingDependency{}
+	if err := rpcErr.DecodeData(&data); err != nil || data.Dependency != "junit" {
+		t.Errorf("expected the data of the error, got %#v: %v", data, err)
+	}
+
+	err = client.Call(ctx, "unknown", nil, nil)
+	if !errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("expected a method not found error, got %v", err)
+	}
+	if err := err.(*Error).DecodeData(&data); err == nil {
+		t.Errorf("expected an error without data")
+	}
+	if _, ok := ErrorCode(errors.New("not sent")); ok {
+		t.Errorf("expected no code for an error that is not an rpc error")
+	}
+	if _, err := NewErrorWithData(CodeRequestFailed, make(chan int), "failed"); err == nil {
+		t.Errorf("expected an error for data that can not be marshalled")
+	}
+}
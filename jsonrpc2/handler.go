@@ -6,8 +6,11 @@ package jsonrpc2
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+
+	"github.com/konveyor/analyzer-lsp/redact"
 )
 
 // Handler is the interface used to hook into the message handling of an rpc
@@ -115,11 +118,11 @@ func (f FileHandler) Cancel(ctx context.Context, conn *Conn, id ID, cancelled bo
 func (f FileHandler) Request(ctx context.Context, conn *Conn, direction Direction, r *WireRequest) context.Context {
 	yaml := "jsonrpc: 2.0\n" +
 		"method: " + r.Method + "\n" +
-		"params: " + string(*r.Params) + "\n" +
-		"id: " + fmt.Sprint(r.ID.Number) + "\n"
+		"params: " + rawString(r.Params) + "\n" +
+		"id: " + idString(r.ID) + "\n"
 
 	fmt.Fprintf(f.File, "conn %p response %s:\n%s\n",
-		conn, direction.String(), yaml,
+		conn, direction.String(), redact.String(yaml),
 	)
 
 	return ctx
@@ -127,12 +130,12 @@ func (f FileHandler) Request(ctx context.Context, conn *Conn, direction Directio
 
 func (f FileHandler) Response(ctx context.Context, conn *Conn, direction Direction, r *WireResponse) context.Context {
 	yaml := "jsonrpc: 2.0\n" +
-		"result: " + string(*r.Result) + "\n" +
+		"result: " + rawString(r.Result) + "\n" +
 		"error: " + fmt.Sprint(r.Error) + "\n" +
-		"id: " + fmt.Sprint(r.ID.Number) + "\n"
+		"id: " + idString(r.ID) + "\n"
 
 	fmt.Fprintf(f.File, "conn %p response %s:\n%s\n",
-		conn, direction.String(), yaml,
+		conn, direction.String(), redact.String(yaml),
 	)
 
 	return ctx
@@ -149,3 +152,24 @@ func (f FileHandler) Wrote(ctx context.Context, bytes int64) context.Context {
 }
 
 func (f FileHandler) Error(ctx context.Context, err error) {}
+
+// rawString returns the JSON of a raw message, null when it is not set
+func rawString(m *json.RawMessage) string {
+	if m == nil {
+		return "null"
+	}
+	return string(*m)
+}
+
+// idString returns the ID as it is sent, a quoted string or a number, null
+// for a notification
+func idString(id *ID) string {
+	if id == nil {
+		return "null"
+	}
+	data, err := id.MarshalJSON()
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
@@ -0,0 +1,119 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestInterceptors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+	received := make(chan string, 10)
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		var p string
+		json.Unmarshal(*params, &p)
+		received <- method + " " + p
+		return p, nil
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	order := []string{}
+	client.AddInterceptor(func(ctx context.Context, method string, params interface{}) (string, interface{}, error) {
+		order = append(order, "first")
+		if method == "forbidden" {
+			return method, params, errors.New("not allowed")
+		}
+		return "renamed/" + method, params, nil
+	})
+	client.AddInterceptor(func(ctx context.Context, method string, params interface{}) (string, interface{}, error) {
+		order = append(order, "second")
+		return method, params.(string) + "-intercepted", nil
+	})
+
+	var result string
+	if err := client.Call(ctx, "echo", "a", &result); err != nil || result != "a-intercepted" {
+		t.Fatalf("unexpected result %q, %v", result, err)
+	}
+	if got := <-received; got != "renamed/echo a-intercepted" {
+		t.Errorf("expected the server to receive the rewritten call, got %q", got)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("expected the interceptors to run in the order they were added, got %v", order)
+	}
+	if err := client.Notify(ctx, "log", "b"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := <-received; got != "renamed/log b-intercepted" {
+		t.Errorf("expected the server to receive the rewritten notification, got %q", got)
+	}
+	requests := []*BatchRequest{{Method: "echo", Params: "c", Result: &result}}
+	if err := client.Batch(ctx, requests); err != nil || result != "c-intercepted" {
+		t.Fatalf("unexpected result %q, %v", result, err)
+	}
+	if got := <-received; got != "renamed/echo c-intercepted" {
+		t.Errorf("expected the server to receive the rewritten batch, got %q", got)
+	}
+
+	order = nil
+	for _, tt := range []struct {
+		name string
+		send func() error
+	}{
+		{"call", func() error { return client.Call(ctx, "forbidden", "d", nil) }},
+		{"notify", func() error { return client.Notify(ctx, "forbidden", "d") }},
+		{"batch", func() error {
+			return client.Batch(ctx, []*BatchRequest{{Method: "echo", Params: "d"}, {Method: "forbidden", Params: "d"}})
+		}},
+	} {
+		if err := tt.send(); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("expected the %s to fail with the error of the interceptor, got %v", tt.name, err)
+		}
+	}
+	if strings.Join(order, ",") != "first,first,first,second,first" {
+		t.Errorf("expected the interceptors after the failing one not to run, got %v", order)
+	}
+	select {
+	case got := <-received:
+		t.Errorf("expected nothing to be sent once an interceptor failed, got %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAddInterceptorConcurrently(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			client.AddInterceptor(func(ctx context.Context, method string, params interface{}) (string, interface{}, error) {
+				return method, params, nil
+			})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := client.Call(ctx, "ping", nil, nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	<-done
+}
@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
@@ -20,14 +21,40 @@ import (
 // Conn is a JSON RPC 2 client server connection.
 // Conn is bidirectional; it does not have a designated server or client end.
 type Conn struct {
-	seq       int64 // must only be accessed using atomic operations
-	handlers  []Handler
-	stream    Stream
-	pendingMu sync.Mutex // protects the pending map
-	pending   map[ID]chan *WireResponse
-	logger    logr.Logger
+	seq          int64 // must only be accessed using atomic operations
+	handlers     []Handler
+	interceptMu  sync.Mutex // protects the interceptors slice
+	interceptors []Interceptor
+	stream       Stream
+	pendingMu    sync.Mutex // protects the pending map
+	pending      map[ID]chan *WireResponse
+	logger       logr.Logger
+	// requestHandler answers the requests received on the connection
+	requestHandler RequestHandler
+	// concurrent tells the methods whose requests are handled as soon as
+	// they are received, nil when they are all handled in order
+	concurrent func(method string) bool
+	// idPrefix is set when the calls are sent with string IDs
+	idPrefix string
+	// received cancels the requests received on the connection that are not
+	// answered yet, by ID
+	receivedMu sync.Mutex
+	received   map[ID]context.CancelFunc
+	// retryPolicy sends the calls that failed with a transient error again
+	retryPolicy RetryPolicy
 }
 
+// Interceptor is called before an outgoing call or notification is sent, it
+// returns the method and params to send instead. An error stops the request
+// from being sent and is returned to the caller.
+type Interceptor func(ctx context.Context, method string, params interface{}) (string, interface{}, error)
+
+// RequestHandler answers a call or a notification received on the
+// connection, the result is sent back for calls and ignored for
+// notifications. An error that is not an *Error is sent with
+// CodeUnknownError.
+type RequestHandler func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error)
+
 // NewErrorf builds a Error struct for the supplied message and code.
 // If args is not empty, message and args will be passed to Sprintf.
 func NewErrorf(code int64, format string, args ...interface{}) *Error {
@@ -45,10 +72,27 @@ func NewConn(s Stream, log logr.Logger) *Conn {
 		stream:   s,
 		pending:  make(map[ID]chan *WireResponse),
 		logger:   log,
+		received: make(map[ID]context.CancelFunc),
 	}
 	return conn
 }
 
+// SetStringIDs sends the calls with string IDs, the prefix followed by a
+// sequence number, instead of numbers. It must be called before the first
+// call.
+func (c *Conn) SetStringIDs(prefix string) {
+	c.idPrefix = prefix
+}
+
+// nextID returns the ID of the next call
+func (c *Conn) nextID() ID {
+	n := atomic.AddInt64(&c.seq, 1)
+	if c.idPrefix != "" {
+		return NewStringID(c.idPrefix + strconv.FormatInt(n, 10))
+	}
+	return NewIntID(n)
+}
+
 // AddHandler adds a new handler to the set the connection will invoke.
 // Handlers are invoked in the reverse order of how they were added, this
 // allows the most recent addition to be the first one to attempt to handle a
@@ -58,10 +102,55 @@ func (c *Conn) AddHandler(handler Handler) {
 	c.handlers = append([]Handler{handler}, c.handlers...)
 }
 
+// SetRequestHandler sets the handler of the requests received on the
+// connection, it must be called before Run. Without one, the received
+// requests are ignored.
+func (c *Conn) SetRequestHandler(handler RequestHandler) {
+	c.requestHandler = handler
+}
+
+// SetRouter answers the requests received on the connection with the router,
+// it must be called before Run. The requests of the methods registered
+// WithConcurrency do not wait for the requests received before them.
+func (c *Conn) SetRouter(router *Router) {
+	c.requestHandler = router.Handle
+	c.concurrent = router.concurrent
+}
+
+// AddInterceptor adds an interceptor for the outgoing requests of the
+// connection. Interceptors are invoked in the order they were added, each one
+// getting the method and params returned by the previous one. It can be
+// called while requests are sent, they use the interceptors added before them.
+func (c *Conn) AddInterceptor(interceptor Interceptor) {
+	c.interceptMu.Lock()
+	defer c.interceptMu.Unlock()
+	c.interceptors = append(c.interceptors, interceptor)
+}
+
+// intercept returns the method and params returned by the interceptors, the
+// interceptors are called without the lock so that they can add others
+func (c *Conn) intercept(ctx context.Context, method string, params interface{}) (string, interface{}, error) {
+	c.interceptMu.Lock()
+	interceptors := c.interceptors
+	c.interceptMu.Unlock()
+	var err error
+	for _, i := range interceptors {
+		method, params, err = i(ctx, method, params)
+		if err != nil {
+			return method, params, fmt.Errorf("intercepting %s: %w", method, err)
+		}
+	}
+	return method, params, nil
+}
+
 // Notify is called to send a notification request over the connection.
 // It will return as soon as the notification has been sent, as no response is
 // possible.
 func (c *Conn) Notify(ctx context.Context, method string, params interface{}) (err error) {
+	method, params, err = c.intercept(ctx, method, params)
+	if err != nil {
+		return err
+	}
 	jsonParams, err := marshalToRaw(params)
 	if err != nil {
 		return fmt.Errorf("marshalling notify parameters: %v", err)
@@ -101,10 +190,29 @@ func (e *RPCUnmarshalError) Error() string {
 
 // Call sends a request over the connection and then waits for a response.
 // If the response is not an error, it will be decoded into result.
-// result must be of a type you an pass to json.Unmarshal.
-func (c *Conn) Call(ctx context.Context, method string, params, result interface{}) (err error) {
+// result must be of a type you an pass to json.Unmarshal. The calls that
+// fail with a retryable error of the retry policy are sent again.
+func (c *Conn) Call(ctx context.Context, method string, params, result interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := c.call(ctx, method, params, result)
+		if err == nil || attempt >= c.retryPolicy.MaxAttempts || !c.retryPolicy.Retryable(err) {
+			return err
+		}
+		backoff := c.retryPolicy.Backoff(attempt + 1)
+		c.logger.V(5).Info("retrying call", "method", method, "attempt", attempt+1, "backoff", backoff, "error", err.Error())
+		if sleep(ctx, backoff) != nil {
+			return err
+		}
+	}
+}
+
+func (c *Conn) call(ctx context.Context, method string, params, result interface{}) (err error) {
+	method, params, err = c.intercept(ctx, method, params)
+	if err != nil {
+		return err
+	}
 	// generate a new request identifier
-	id := ID{Number: atomic.AddInt64(&c.seq, 1)}
+	id := c.nextID()
 	jsonParams, err := marshalToRaw(params)
 	if err != nil {
 		return fmt.Errorf("marshalling call parameters: %v", err)
@@ -153,18 +261,7 @@ func (c *Conn) Call(ctx context.Context, method string, params, result interface
 		for _, h := range c.handlers {
 			ctx = h.Response(ctx, c, Receive, response)
 		}
-		// is it an error response?
-		if response.Error != nil {
-			return response.Error
-		}
-		if result == nil || response.Result == nil {
-			return nil
-		}
-
-		if err := json.Unmarshal(*response.Result, result); err != nil {
-			return &RPCUnmarshalError{string(*response.Result), err}
-		}
-		return nil
+		return decodeResult(response, result)
 	case <-ctx.Done():
 		// allow the handler to propagate the cancel
 		cancelled := false
@@ -177,6 +274,23 @@ func (c *Conn) Call(ctx context.Context, method string, params, result interface
 	}
 }
 
+// decodeResult decodes the result of a response into result, or returns the
+// error of the response.
+func decodeResult(response *WireResponse, result interface{}) error {
+	// is it an error response?
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil || response.Result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(*response.Result, result); err != nil {
+		return &RPCUnmarshalError{string(*response.Result), err}
+	}
+	return nil
+}
+
 // combined has all the fields of both Request and Response.
 // We can decode this and then work out which it is.
 type combined struct {
@@ -201,11 +315,18 @@ func (c *Conn) Run(runCtx context.Context) error {
 	close(nextRequest)
 	for {
 		// get the data for a message
-		data, _, err := c.stream.Read(runCtx)
+		data, n, err := c.stream.Read(runCtx)
 		if err != nil {
 			// the stream failed, we cannot continue
 			return err
 		}
+		for _, h := range c.handlers {
+			h.Read(runCtx, n)
+		}
+		if isBatch(data) {
+			nextRequest = c.handleBatch(runCtx, nextRequest, data)
+			continue
+		}
 		// read a combined message
 		msg := &combined{}
 		if err := json.Unmarshal(data, msg); err != nil {
@@ -218,26 +339,18 @@ func (c *Conn) Run(runCtx context.Context) error {
 		}
 		// work out which kind of message we have
 		switch {
+		case msg.Method == cancelRequestMethod:
+			// the request is canceled right away, not once the requests
+			// received before are answered
+			c.cancelReceived(msg.Params)
+			nextRequest = c.handleRequests(runCtx, nextRequest, []*combined{msg}, false)
+		case msg.Method != "" && c.concurrent != nil && c.concurrent(msg.Method):
+			// neither waits for the previous requests nor holds the next ones
+			c.handleRequests(runCtx, closedChan(), []*combined{msg}, false)
+		case msg.Method != "":
+			nextRequest = c.handleRequests(runCtx, nextRequest, []*combined{msg}, false)
 		case msg.ID != nil:
-			// we have a response, get the pending entry from the map
-			c.pendingMu.Lock()
-			rchan, ok := c.pending[*msg.ID]
-			if rchan != nil {
-				delete(c.pending, *msg.ID)
-			}
-			c.pendingMu.Unlock()
-			// and send the reply to the channel
-			response := &WireResponse{
-				Result: msg.Result,
-				Error:  msg.Error,
-				ID:     msg.ID,
-			}
-
-			// yaml-language-server sends back a request with an ID
-			if ok {
-				rchan <- response
-				close(rchan)
-			}
+			c.deliverResponse(msg)
 		default:
 			for _, h := range c.handlers {
 				h.Error(runCtx, fmt.Errorf("message not a call, notify or response, ignoring"))
@@ -246,6 +359,29 @@ func (c *Conn) Run(runCtx context.Context) error {
 	}
 }
 
+// deliverResponse sends a response to the call waiting for it
+func (c *Conn) deliverResponse(msg *combined) {
+	// get the pending entry from the map
+	c.pendingMu.Lock()
+	rchan, ok := c.pending[*msg.ID]
+	if rchan != nil {
+		delete(c.pending, *msg.ID)
+	}
+	c.pendingMu.Unlock()
+	// and send the reply to the channel
+	response := &WireResponse{
+		Result: msg.Result,
+		Error:  msg.Error,
+		ID:     msg.ID,
+	}
+
+	// yaml-language-server sends back a request with an ID
+	if ok {
+		rchan <- response
+		close(rchan)
+	}
+}
+
 func marshalToRaw(obj interface{}) (*json.RawMessage, error) {
 	data, err := json.Marshal(obj)
 	if err != nil {
@@ -0,0 +1,295 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestIDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    ID
+		wantErr bool
+	}{
+		{
+			name: "number",
+			data: `12`,
+			want: NewIntID(12),
+		},
+		{
+			name: "string",
+			data: `"abc-1"`,
+			want: NewStringID("abc-1"),
+		},
+		{
+			name: "string of digits",
+			data: `"12"`,
+			want: NewStringID("12"),
+		},
+		{
+			name:    "object",
+			data:    `{"id": 1}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := ID{}
+			err := json.Unmarshal([]byte(tt.data), &id)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if id != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, id)
+			}
+			data, err := json.Marshal(&id)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(data) != tt.data {
+				t.Errorf("expected the id to be sent as %s, got %s", tt.data, data)
+			}
+		})
+	}
+}
+
+// idHandler records the IDs of the requests a connection receives
+type idHandler struct {
+	EmptyHandler
+	ids chan *ID
+}
+
+func (h idHandler) Request(ctx context.Context, conn *Conn, direction Direction, r *WireRequest) context.Context {
+	if direction == Receive {
+		h.ids <- r.ID
+	}
+	return ctx
+}
+
+func TestStringIDs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	client.SetStringIDs("analyzer-")
+	server := NewConn(serverStream, logr.Discard())
+	handler := idHandler{ids: make(chan *ID, 10)}
+	server.AddHandler(handler)
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		return method, nil
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	var result string
+	if err := client.Call(ctx, "echo", nil, &result); err != nil || result != "echo" {
+		t.Fatalf("unexpected result %q, %v", result, err)
+	}
+	requests := []*BatchRequest{{Method: "first", Result: new(string)}, {Method: "second", Result: new(string)}}
+	if err := client.Batch(ctx, requests); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i, want := range []string{"analyzer-1", "analyzer-2", "analyzer-3"} {
+		id := <-handler.ids
+		if id == nil || !id.IsString() || id.Name != want {
+			t.Errorf("expected the id %d to be %q, got %v", i, want, id)
+		}
+	}
+}
+
+func TestCancelReceivedRequest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peer, serverStream := pipe()
+	server := NewConn(serverStream, logr.Discard())
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		if method == "wait" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	})
+	go server.Run(ctx)
+
+	for _, id := range []string{`"request-1"`, `7`} {
+		if _, err := peer.Write(ctx, []byte(`{"jsonrpc":"2.0","id":`+id+`,"method":"wait"}`)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := peer.Write(ctx, []byte(`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":`+id+`}}`)); err != nil {
+			t.Fatal(err)
+		}
+		data, _, err := peer.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := struct {
+			ID    json.RawMessage `json:"id"`
+			Error *Error          `json:"error"`
+		}{}
+		if err := json.Unmarshal(data, &response); err != nil {
+			t.Fatal(err)
+		}
+		if string(response.ID) != id || response.Error == nil || !strings.Contains(response.Error.Message, "canceled") {
+			t.Errorf("expected the request %s to be canceled, got %s", id, data)
+		}
+	}
+}
+
+func TestCancelHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	client.AddHandler(NewCancelHandler())
+	server := NewConn(serverStream, logr.Discard())
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		if method != "wait" {
+			return nil, nil
+		}
+		close(started)
+		select {
+		case <-ctx.Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+		return nil, ctx.Err()
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	callCtx, cancelCall := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- client.Call(callCtx, "wait", nil, nil)
+	}()
+	<-started
+	cancelCall()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected the call to be canceled, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the server did not receive the cancellation of the call")
+	}
+}
+
+// countingHandler sends the byte counts of the Read hook
+type countingHandler struct {
+	EmptyHandler
+	read chan int64
+}
+
+func (h countingHandler) Read(ctx context.Context, bytes int64) context.Context {
+	h.read <- bytes
+	return ctx
+}
+
+func TestReadHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peer, serverStream := pipe()
+	server := NewConn(serverStream, logr.Discard())
+	handler := countingHandler{read: make(chan int64, 1)}
+	server.AddHandler(handler)
+	go server.Run(ctx)
+
+	n, err := peer.Write(ctx, []byte(`{"jsonrpc":"2.0","method":"notify"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case read := <-handler.read:
+		if read != n {
+			t.Errorf("expected the handler to read %d bytes, got %d", n, read)
+		}
+	case <-ctx.Done():
+		t.Fatal("the handler was not called for the read message")
+	}
+}
+
+func TestFileHandler(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "rpc.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h := FileHandler{File: f}
+	id := NewStringID("a")
+	h.Request(context.TODO(), nil, Send, &WireRequest{Method: "initialized"})
+	h.Request(context.TODO(), nil, Receive, &WireRequest{Method: "workspace/configuration", ID: &id})
+	h.Response(context.TODO(), nil, Send, &WireResponse{ID: &id})
+	content, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"params: null\nid: null\n", "id: \"a\"\n"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected the log to contain %q, got %s", want, content)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	server := NewConn(serverStream, logr.Discard())
+	attempts := map[string]int{}
+	server.SetRequestHandler(func(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+		attempts[method]++
+		switch method {
+		case "modified":
+			if attempts[method] < 3 {
+				return nil, NewErrorf(CodeContentModified, "content modified")
+			}
+			return "done", nil
+		case "invalid":
+			return nil, NewErrorf(CodeInvalidParams, "invalid params")
+		}
+		return nil, NewErrorf(CodeServerCancelled, "server cancelled")
+	})
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	var result string
+	if err := client.Call(ctx, "modified", nil, &result); err != nil || result != "done" {
+		t.Errorf("expected the call to succeed once retried, got %q, %v", result, err)
+	}
+	if err := client.Call(ctx, "invalid", nil, nil); err == nil || attempts["invalid"] != 1 {
+		t.Errorf("expected the invalid call to fail without retries, got %d attempts, %v", attempts["invalid"], err)
+	}
+	err := client.Call(ctx, "cancelled", nil, nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeServerCancelled || attempts["cancelled"] != 3 {
+		t.Errorf("expected the call to fail after 3 attempts, got %d attempts, %v", attempts["cancelled"], err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{2: time.Second, 3: 2 * time.Second, 4: 4 * time.Second, 5: 5 * time.Second, 10: 5 * time.Second} {
+		if got := policy.Backoff(attempt); got != want {
+			t.Errorf("expected the backoff of the attempt %d to be %s, got %s", attempt, want, got)
+		}
+	}
+}
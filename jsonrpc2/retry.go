@@ -0,0 +1,88 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
+// DefaultRetryableCodes are the codes of the errors a server returns for a
+// request it may answer when it is sent again.
+var DefaultRetryableCodes = []int64{CodeContentModified, CodeServerCancelled, CodeServerOverloaded}
+
+// RetryPolicy sends the calls that fail with a transient error again, such
+// as the requests a language server drops because the workspace changed.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is sent, including the first
+	// one, the calls are not retried below 2
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, it doubles for every
+	// following one, 100ms by default
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts, 5s by default
+	MaxBackoff time.Duration
+	// RetryableCodes are the error codes of the calls that are retried,
+	// DefaultRetryableCodes when empty
+	RetryableCodes []int64
+}
+
+// Retryable tells whether a call that failed with the error is retried
+func (p RetryPolicy) Retryable(err error) bool {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	codes := p.RetryableCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryableCodes
+	}
+	for _, code := range codes {
+		if rpcErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the wait before the attempt, starting at 2 for the first
+// retry
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff, max := p.InitialBackoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	for i := 2; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// SetRetryPolicy retries the calls of the connection that fail with one of
+// the retryable codes of the policy. It must be called before the first call.
+func (c *Conn) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// sleep waits for the duration, it returns the error of the context when it
+// is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
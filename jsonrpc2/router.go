@@ -0,0 +1,169 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Router answers the requests received on a connection with the functions
+// registered for their method with Register and RegisterNotification, which
+// get their params decoded and their result encoded. The requests of the
+// methods that are not registered fail with CodeMethodNotFound, and the ones
+// whose params can not be decoded with CodeInvalidParams. It is set on a
+// connection with SetRouter.
+type Router struct {
+	mu     sync.RWMutex
+	routes map[string]*route
+	codes  []errorCode
+}
+
+type route struct {
+	handle func(ctx context.Context, params *json.RawMessage) (interface{}, error)
+	// concurrent requests do not wait for the requests received before them
+	concurrent bool
+	// slots limits the number of requests handled at the same time, nil when
+	// there is no limit
+	slots chan struct{}
+}
+
+// errorCode is the code the errors that wrap target are sent with
+type errorCode struct {
+	target error
+	code   int64
+}
+
+// RouteOption sets how the requests of a method are handled
+type RouteOption func(*route)
+
+// WithConcurrency handles the requests of the method as soon as they are
+// received, rather than once the requests received before them are answered,
+// at most limit of them at the same time, without a limit when it is 0 or
+// less. The requests over the limit wait for one to be answered.
+func WithConcurrency(limit int) RouteOption {
+	return func(r *route) {
+		r.concurrent = true
+		r.slots = nil
+		if limit > 0 {
+			r.slots = make(chan struct{}, limit)
+		}
+	}
+}
+
+// NewRouter returns a router without any method, the canceled requests fail
+// with CodeRequestCancelled
+func NewRouter() *Router {
+	return &Router{routes: map[string]*route{}}
+}
+
+// Register registers the function that answers the calls of a method,
+// replacing the one registered before if any. The params are decoded into
+// Params, which is left zero when the request has none, and the result is
+// encoded in the response.
+func Register[Params, Result any](r *Router, method string, fn func(context.Context, Params) (Result, error), options ...RouteOption) {
+	r.register(method, func(ctx context.Context, raw *json.RawMessage) (interface{}, error) {
+		var params Params
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return fn(ctx, params)
+	}, options)
+}
+
+// RegisterNotification registers the function that handles the notifications
+// of a method, replacing the one registered before if any. The params are
+// decoded as with Register, a call of the method gets a null result.
+func RegisterNotification[Params any](r *Router, method string, fn func(context.Context, Params) error, options ...RouteOption) {
+	r.register(method, func(ctx context.Context, raw *json.RawMessage) (interface{}, error) {
+		var params Params
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return nil, fn(ctx, params)
+	}, options)
+}
+
+func (r *Router) register(method string, handle func(context.Context, *json.RawMessage) (interface{}, error), options []RouteOption) {
+	rt := &route{handle: handle}
+	for _, o := range options {
+		o(rt)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[method] = rt
+}
+
+// MapError sends the errors of the registered functions that wrap target, as
+// errors.Is tells, with a code and their message. The mappings are checked
+// in the order they were added. The *Error returned by the functions are sent
+// as they are, the context.Canceled errors that are not mapped with
+// CodeRequestCancelled and the other errors with CodeUnknownError.
+func (r *Router) MapError(target error, code int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes = append(r.codes, errorCode{target: target, code: code})
+}
+
+// Handle answers a request with the function registered for its method, it
+// is the RequestHandler of the connections the router is set on
+func (r *Router) Handle(ctx context.Context, method string, params *json.RawMessage) (interface{}, error) {
+	r.mu.RLock()
+	rt, ok := r.routes[method]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, NewErrorf(CodeMethodNotFound, "method %q not found", method)
+	}
+	if rt.slots != nil {
+		select {
+		case rt.slots <- struct{}{}:
+			defer func() { <-rt.slots }()
+		case <-ctx.Done():
+			return nil, r.mapError(ctx.Err())
+		}
+	}
+	result, err := rt.handle(ctx, params)
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	return result, nil
+}
+
+// concurrent returns whether the requests of a method are handled as soon as
+// they are received
+func (r *Router) concurrent(method string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rt, ok := r.routes[method]
+	return ok && rt.concurrent
+}
+
+func (r *Router) mapError(err error) error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.codes {
+		if errors.Is(err, c.target) {
+			return NewErrorf(c.code, "%v", err)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return NewErrorf(CodeRequestCancelled, "%v", err)
+	}
+	return err
+}
+
+// decodeParams decodes the params of a request, the value is left as it is
+// when there are none
+func decodeParams(raw *json.RawMessage, value interface{}) error {
+	if raw == nil || len(*raw) == 0 || string(*raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(*raw, value); err != nil {
+		return NewErrorf(CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
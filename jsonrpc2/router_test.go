@@ -0,0 +1,148 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRouter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+
+	type addParams struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	errNotReady := errors.New("not ready")
+	router := NewRouter()
+	router.MapError(errNotReady, CodeServerOverloaded)
+	Register(router, "add", func(ctx context.Context, p addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+	Register(router, "status", func(ctx context.Context, _ struct{}) (string, error) {
+		return "", fmt.Errorf("checking status: %w", errNotReady)
+	})
+	Register(router, "invalid", func(ctx context.Context, _ struct{}) (string, error) {
+		return "", NewErrorf(CodeInvalidRequest, "refused")
+	})
+	logged := make(chan string, 1)
+	RegisterNotification(router, "log", func(ctx context.Context, message string) error {
+		logged <- message
+		return nil
+	})
+	server.SetRouter(router)
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	var sum int
+	if err := client.Call(ctx, "add", addParams{A: 1, B: 2}, &sum); err != nil || sum != 3 {
+		t.Errorf("expected 3, got %d: %v", sum, err)
+	}
+	if err := client.Call(ctx, "add", nil, &sum); err != nil || sum != 0 {
+		t.Errorf("expected 0 without params, got %d: %v", sum, err)
+	}
+
+	for _, tt := range []struct {
+		method string
+		params interface{}
+		code   int64
+	}{
+		{method: "unknown", code: CodeMethodNotFound},
+		{method: "add", params: "one and two", code: CodeInvalidParams},
+		{method: "status", code: CodeServerOverloaded},
+		{method: "invalid", code: CodeInvalidRequest},
+	} {
+		err := client.Call(ctx, tt.method, tt.params, nil)
+		if rpcErr, ok := err.(*Error); !ok || rpcErr.Code != tt.code {
+			t.Errorf("%s: expected an error with code %d, got %v", tt.method, tt.code, err)
+		}
+	}
+
+	if err := client.Notify(ctx, "log", "started"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	select {
+	case message := <-logged:
+		if message != "started" {
+			t.Errorf("expected the started notification, got %q", message)
+		}
+	case <-ctx.Done():
+		t.Fatal("the notification was not handled")
+	}
+}
+
+func TestRouterConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientStream, serverStream := pipe()
+	client := NewConn(clientStream, logr.Discard())
+	server := NewConn(serverStream, logr.Discard())
+
+	started, release := make(chan struct{}), make(chan struct{})
+	mu := sync.Mutex{}
+	running, maxRunning := 0, 0
+	router := NewRouter()
+	Register(router, "block", func(ctx context.Context, _ struct{}) (string, error) {
+		close(started)
+		<-release
+		return "released", nil
+	})
+	Register(router, "ping", func(ctx context.Context, _ struct{}) (string, error) {
+		return "pong", nil
+	}, WithConcurrency(0))
+	Register(router, "limited", func(ctx context.Context, _ struct{}) (int, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return 0, nil
+	}, WithConcurrency(2))
+	server.SetRouter(router)
+	go client.Run(ctx)
+	go server.Run(ctx)
+
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- client.Call(ctx, "block", nil, nil)
+	}()
+	<-started
+	// the concurrent method is answered while the block call is pending
+	var pong string
+	if err := client.Call(ctx, "ping", nil, &pong); err != nil || pong != "pong" {
+		t.Errorf("expected pong, got %q: %v", pong, err)
+	}
+	close(release)
+	if err := <-blocked; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Call(ctx, "limited", nil, nil); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 limited requests at the same time, got %d", maxRunning)
+	}
+}
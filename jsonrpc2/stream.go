@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
+	"net/textproto"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,16 +33,47 @@ type Stream interface {
 // The messages are sent with HTTP content length and MIME type headers.
 // This is the format used by LSP and others.
 func NewHeaderStream(in io.Reader, out io.Writer) Stream {
+	return NewHeaderStreamWithOptions(in, out, HeaderOptions{})
+}
+
+// HeaderOptions set the charsets the messages read from a header stream can
+// be encoded in and the Content-Type of the messages it writes
+type HeaderOptions struct {
+	// Charsets are the charsets of the Content-Type of the messages that are
+	// accepted, utf-8, which can be written utf8, utf-16, utf-16le or
+	// utf-16be. The messages in utf-16 are decoded to utf-8. utf-8 when
+	// empty, the messages without a charset are always read as utf-8.
+	Charsets []string
+	// ContentType is the Content-Type header of the messages written, such
+	// as application/vscode-jsonrpc; charset=utf-8, the messages have no
+	// Content-Type when empty. The messages are written in utf-8.
+	ContentType string
+}
+
+// NewHeaderStreamWithOptions returns a Stream like NewHeaderStream, that
+// accepts the messages in the charsets of the options
+func NewHeaderStreamWithOptions(in io.Reader, out io.Writer, options HeaderOptions) Stream {
+	charsets := map[string]bool{}
+	for _, c := range options.Charsets {
+		charsets[normalizeCharset(c)] = true
+	}
+	if len(charsets) == 0 {
+		charsets[charsetUTF8] = true
+	}
 	return &headerStream{
-		in:  bufio.NewReader(in),
-		out: out,
+		in:          bufio.NewReader(in),
+		out:         out,
+		charsets:    charsets,
+		contentType: options.ContentType,
 	}
 }
 
 type headerStream struct {
-	in    *bufio.Reader
-	outMu sync.Mutex
-	out   io.Writer
+	in          *bufio.Reader
+	outMu       sync.Mutex
+	out         io.Writer
+	charsets    map[string]bool
+	contentType string
 }
 
 func (s *headerStream) Read(ctx context.Context) ([]byte, int64, error) {
@@ -50,6 +83,8 @@ func (s *headerStream) Read(ctx context.Context) ([]byte, int64, error) {
 	default:
 	}
 	var total, length int64
+	charset := charsetUTF8
+	var contentTypeErr error
 	// read the header, stop on the first empty line
 	for {
 		line, err := s.in.ReadString('\n')
@@ -66,7 +101,8 @@ func (s *headerStream) Read(ctx context.Context) ([]byte, int64, error) {
 		if colon < 0 {
 			return nil, total, fmt.Errorf("invalid header line %q", line)
 		}
-		name, value := line[:colon], strings.TrimSpace(line[colon+1:])
+		// the header names are case insensitive
+		name, value := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(line[:colon])), strings.TrimSpace(line[colon+1:])
 		switch name {
 		case "Content-Length":
 			if length, err = strconv.ParseInt(value, 10, 32); err != nil {
@@ -75,6 +111,15 @@ func (s *headerStream) Read(ctx context.Context) ([]byte, int64, error) {
 			if length <= 0 {
 				return nil, total, fmt.Errorf("invalid Content-Length: %v", length)
 			}
+		case "Content-Type":
+			_, params, err := mime.ParseMediaType(value)
+			if err != nil {
+				contentTypeErr = fmt.Errorf("failed parsing Content-Type %q: %v", value, err)
+				continue
+			}
+			if c, ok := params["charset"]; ok {
+				charset = normalizeCharset(c)
+			}
 		default:
 			// ignoring unknown headers
 		}
@@ -87,6 +132,18 @@ func (s *headerStream) Read(ctx context.Context) ([]byte, int64, error) {
 		return nil, total, err
 	}
 	total += length
+	// the message is read before its content type is checked, so that the
+	// next message can still be read
+	if contentTypeErr != nil {
+		return nil, total, contentTypeErr
+	}
+	if !s.charsets[charset] {
+		return nil, total, fmt.Errorf("unsupported charset %s of the message, the stream accepts %s", charset, strings.Join(sortedCharsets(s.charsets), ", "))
+	}
+	data, err := decodeCharset(charset, data)
+	if err != nil {
+		return nil, total, err
+	}
 	return data, total, nil
 }
 
@@ -98,7 +155,11 @@ func (s *headerStream) Write(ctx context.Context, data []byte) (int64, error) {
 	}
 	s.outMu.Lock()
 	defer s.outMu.Unlock()
-	n, err := fmt.Fprintf(s.out, "Content-Length: %v\r\n\r\n", len(data))
+	header := fmt.Sprintf("Content-Length: %v\r\n", len(data))
+	if s.contentType != "" {
+		header += fmt.Sprintf("Content-Type: %s\r\n", s.contentType)
+	}
+	n, err := io.WriteString(s.out, header+"\r\n")
 	total := int64(n)
 	if err == nil {
 		n, err = s.out.Write(data)
@@ -0,0 +1,119 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestHeaderStreamRead(t *testing.T) {
+	message := `{"jsonrpc":"2.0","method":"log","params":"café"}`
+	utf16Message := func(bigEndian bool, bom bool) string {
+		units := utf16.Encode([]rune(message))
+		if bom {
+			units = append([]uint16{0xfeff}, units...)
+		}
+		b := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			if bigEndian {
+				b = append(b, byte(u>>8), byte(u))
+			} else {
+				b = append(b, byte(u), byte(u>>8))
+			}
+		}
+		return string(b)
+	}
+	header := func(contentType string, body string) string {
+		h := "Content-Length: " + strconv.Itoa(len(body)) + "\r\n"
+		if contentType != "" {
+			h += "Content-Type: " + contentType + "\r\n"
+		}
+		return h + "\r\n" + body
+	}
+	tests := []struct {
+		name     string
+		input    string
+		charsets []string
+		wantErr  string
+	}{
+		{
+			name:  "without a content type",
+			input: header("", message),
+		},
+		{
+			name:  "utf8",
+			input: header("application/vscode-jsonrpc; charset=utf8", message),
+		},
+		{
+			name:  "lower case headers and unknown headers",
+			input: "content-length: " + strconv.Itoa(len(message)) + "\r\nX-Server: test\r\ncontent-type: application/vscode-jsonrpc; charset=UTF-8\r\n\r\n" + message,
+		},
+		{
+			name:  "utf-8 byte order mark",
+			input: header("application/vscode-jsonrpc; charset=utf-8", "\xef\xbb\xbf"+message),
+		},
+		{
+			name:    "utf-16 not accepted",
+			input:   header("application/vscode-jsonrpc; charset=utf-16", utf16Message(true, false)),
+			wantErr: "unsupported charset utf-16 of the message, the stream accepts utf-8",
+		},
+		{
+			name:     "utf-16 without a byte order mark",
+			input:    header("application/vscode-jsonrpc; charset=utf-16", utf16Message(true, false)),
+			charsets: []string{"utf-8", "utf-16"},
+		},
+		{
+			name:     "utf-16 with a little endian byte order mark",
+			input:    header(`application/vscode-jsonrpc; charset="utf-16"`, utf16Message(false, true)),
+			charsets: []string{"utf-16"},
+		},
+		{
+			name:     "utf-16le",
+			input:    header("application/vscode-jsonrpc; charset=utf-16le", utf16Message(false, false)),
+			charsets: []string{"utf-16le"},
+		},
+		{
+			name:    "invalid content type",
+			input:   header("application/vscode-jsonrpc; charset", message),
+			wantErr: "failed parsing Content-Type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the next message is read after the one that failed
+			input := tt.input + header("", message)
+			stream := NewHeaderStreamWithOptions(strings.NewReader(input), &bytes.Buffer{}, HeaderOptions{Charsets: tt.charsets})
+			data, n, err := stream.Read(context.Background())
+			if n != int64(len(tt.input)) {
+				t.Errorf("expected %d bytes to be read, got %d", len(tt.input), n)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected the error %q, got %v", tt.wantErr, err)
+				}
+				data, _, err = stream.Read(context.Background())
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(data) != message {
+				t.Errorf("expected %q, got %q", message, data)
+			}
+		})
+	}
+}
+
+func TestHeaderStreamWrite(t *testing.T) {
+	out := &bytes.Buffer{}
+	stream := NewHeaderStreamWithOptions(strings.NewReader(""), out, HeaderOptions{ContentType: "application/vscode-jsonrpc; charset=utf-8"})
+	if _, err := stream.Write(context.Background(), []byte("{}")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := "Content-Length: 2\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{}"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
@@ -5,6 +5,7 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -32,6 +33,22 @@ const (
 	//CodeServerOverloaded is returned when a message was refused due to a
 	//server being temporarily unable to accept any new messages.
 	CodeServerOverloaded = -32000
+	// CodeServerNotInitialized is returned by a language server for the
+	// requests it received before the initialize request.
+	CodeServerNotInitialized = -32002
+
+	// CodeRequestCancelled is returned by a language server when the client
+	// canceled the request.
+	CodeRequestCancelled = -32800
+	// CodeContentModified is returned by a language server when the content
+	// of a document changed while it was working on a request for it.
+	CodeContentModified = -32801
+	// CodeServerCancelled is returned by a language server that canceled a
+	// request itself, it may answer it when it is sent again.
+	CodeServerCancelled = -32802
+	// CodeRequestFailed is returned by a language server when a request that
+	// is otherwise valid failed.
+	CodeRequestFailed = -32803
 )
 
 // WireRequest is sent to a server to represent a Call or Notify operaton.
@@ -63,6 +80,23 @@ type WireResponse struct {
 	ID *ID `json:"id,omitempty"`
 }
 
+// isBatch returns whether the data of a message is a batch, an array of
+// requests or responses.
+func isBatch(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+// decodeBatch splits a batch in its messages, which are decoded separately so
+// that an invalid one does not invalidate the others.
+func decodeBatch(data []byte) ([]json.RawMessage, error) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
 // Error represents a structured error in a Response.
 type Error struct {
 	// Code is an error code indicating the type of failure.
@@ -87,6 +121,21 @@ type ID struct {
 	Number int64
 }
 
+// NewIntID returns the ID of a request with a number identifier
+func NewIntID(n int64) ID {
+	return ID{Number: n}
+}
+
+// NewStringID returns the ID of a request with a string identifier
+func NewStringID(name string) ID {
+	return ID{Name: name}
+}
+
+// IsString returns whether the identifier of the request is a string
+func (id ID) IsString() bool {
+	return id.Name != ""
+}
+
 func (err *Error) Error() string {
 	if err == nil {
 		return ""
@@ -134,5 +183,8 @@ func (id *ID) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &id.Number); err == nil {
 		return nil
 	}
-	return json.Unmarshal(data, &id.Name)
+	if err := json.Unmarshal(data, &id.Name); err != nil {
+		return fmt.Errorf("invalid id %s, it must be a string or an integer", data)
+	}
+	return nil
 }
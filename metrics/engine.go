@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+)
+
+type ruleKey struct {
+	ruleSet string
+	ruleID  string
+}
+
+type ruleMiddleware struct {
+	registry *Registry
+
+	mutex   sync.Mutex
+	started map[ruleKey]time.Time
+}
+
+// RuleMiddleware returns a rule middleware that records how long each rule
+// takes to evaluate and how many rules matched, did not match or failed. It
+// is given before the other middlewares so that it sees the final result.
+func RuleMiddleware(registry *Registry) engine.RuleMiddleware {
+	return &ruleMiddleware{
+		registry: registry,
+		started:  map[ruleKey]time.Time{},
+	}
+}
+
+func (m *ruleMiddleware) BeforeRule(ctx context.Context, eval *engine.RuleEvaluation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.started[ruleKey{eval.RuleSetName, eval.Rule.RuleID}] = time.Now()
+	return nil
+}
+
+func (m *ruleMiddleware) AfterRule(ctx context.Context, eval *engine.RuleEvaluation) error {
+	key := ruleKey{eval.RuleSetName, eval.Rule.RuleID}
+	m.mutex.Lock()
+	started, ok := m.started[key]
+	delete(m.started, key)
+	m.mutex.Unlock()
+
+	labels := Labels{"ruleset": eval.RuleSetName, "rule": eval.Rule.RuleID}
+	if ok {
+		m.registry.Summarize("analyzer_rule_evaluation_seconds", "Time taken to evaluate the conditions of each rule.",
+			labels, time.Since(started).Seconds())
+	}
+	result := "unmatched"
+	switch {
+	case eval.Err != nil:
+		result = "error"
+	case eval.Response.Matched:
+		result = "matched"
+	}
+	m.registry.Add("analyzer_rules_evaluated_total", "Number of rules evaluated, by result.",
+		Labels{"ruleset": eval.RuleSetName, "result": result}, 1)
+	return nil
+}
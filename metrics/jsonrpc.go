@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/konveyor/analyzer-lsp/jsonrpc2"
+)
+
+// RPCHandler records the bytes read from and written to the connection of a
+// language server
+type RPCHandler struct {
+	jsonrpc2.EmptyHandler
+	registry *Registry
+	server   string
+}
+
+var _ jsonrpc2.Handler = &RPCHandler{}
+
+// NewRPCHandler returns a handler that records the bytes of the connection
+// to the language server with the given name
+func NewRPCHandler(registry *Registry, server string) *RPCHandler {
+	return &RPCHandler{registry: registry, server: server}
+}
+
+func (h *RPCHandler) Read(ctx context.Context, bytes int64) context.Context {
+	h.registry.Add("analyzer_jsonrpc_read_bytes_total", "Number of bytes read from the language servers.",
+		Labels{"server": h.server}, float64(bytes))
+	return ctx
+}
+
+func (h *RPCHandler) Wrote(ctx context.Context, bytes int64) context.Context {
+	h.registry.Add("analyzer_jsonrpc_written_bytes_total", "Number of bytes written to the language servers.",
+		Labels{"server": h.server}, float64(bytes))
+	return ctx
+}
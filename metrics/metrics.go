@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry the analyzer records its metrics in, it is served
+// with --metrics-address
+var Default = NewRegistry()
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the
+// histograms, from a cached query to a slow reference search
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Labels are the labels of a series of a metric
+type Labels map[string]string
+
+type kind string
+
+const (
+	counterKind   kind = "counter"
+	histogramKind kind = "histogram"
+	summaryKind   kind = "summary"
+)
+
+type series struct {
+	labels Labels
+	value  float64
+	// buckets, sum and count are the observations of the histograms and
+	// summaries, the buckets are not cumulative
+	buckets []uint64
+	sum     float64
+	count   uint64
+	fn      func() float64
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   kind
+	series map[string]*series
+}
+
+// Registry keeps the metrics of the analyzer and writes them in the text
+// format of Prometheus. The metrics are created the first time they are
+// recorded, a metric is either a counter, a histogram or a summary.
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		families: map[string]*family{},
+	}
+}
+
+// series returns the series of the labels of a metric, creating them when
+// they do not exist. It must be called with the mutex held.
+func (r *Registry) series(name, help string, k kind, labels Labels) *series {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: k, series: map[string]*series{}}
+		r.families[name] = f
+	}
+	if f.kind != k {
+		panic(fmt.Sprintf("metric %s is a %s, not a %s", name, f.kind, k))
+	}
+	key := labelString(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if k == histogramKind {
+			s.buckets = make([]uint64, len(DefaultBuckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Add adds a value to a counter
+func (r *Registry) Add(name, help string, labels Labels, value float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.series(name, help, counterKind, labels).value += value
+}
+
+// CounterFunc sets a counter whose value is returned by fn when the metrics
+// are written, such as a count kept by another package
+func (r *Registry) CounterFunc(name, help string, labels Labels, fn func() float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.series(name, help, counterKind, labels).fn = fn
+}
+
+// Observe records a value in a histogram with the DefaultBuckets
+func (r *Registry) Observe(name, help string, labels Labels, value float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s := r.series(name, help, histogramKind, labels)
+	for i, bound := range DefaultBuckets {
+		if value <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Summarize records a value in a summary, which only keeps the sum and the
+// count of the values. It is meant for the metrics with many series, such as
+// one per rule, where the buckets of a histogram would cost too much.
+func (r *Registry) Summarize(name, help string, labels Labels, value float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s := r.series(name, help, summaryKind, labels)
+	s.sum += value
+	s.count++
+}
+
+// AddSummary adds the sum and the count of several values to a summary, such
+// as the values another process summarized itself
+func (r *Registry) AddSummary(name, help string, labels Labels, sum float64, count uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s := r.series(name, help, summaryKind, labels)
+	s.sum += sum
+	s.count += count
+}
+
+// WriteTo writes the metrics in the text format of Prometheus, sorted by
+// name and labels
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := &strings.Builder{}
+	for _, name := range names {
+		writeFamily(b, r.families[name])
+	}
+	r.mutex.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeFamily(b *strings.Builder, f *family) {
+	if f.help != "" {
+		fmt.Fprintf(b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		switch f.kind {
+		case counterKind:
+			value := s.value
+			if s.fn != nil {
+				value = s.fn()
+			}
+			fmt.Fprintf(b, "%s%s %s\n", f.name, key, formatFloat(value))
+		case histogramKind:
+			cumulative := uint64(0)
+			for i, bound := range DefaultBuckets {
+				cumulative += s.buckets[i]
+				fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labelString(s.labels, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labelString(s.labels, "le", "+Inf"), s.count)
+			fallthrough
+		case summaryKind:
+			fmt.Fprintf(b, "%s_sum%s %s\n", f.name, key, formatFloat(s.sum))
+			fmt.Fprintf(b, "%s_count%s %d\n", f.name, key, s.count)
+		}
+	}
+}
+
+// Handler serves the metrics of the registry, such as on /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// labelString returns the labels as they are written after the name of the
+// metric, sorted by name, with the extra name and value pairs last
+func labelString(labels Labels, extra ...string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabel(labels[name])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], escapeLabel(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
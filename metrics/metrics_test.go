@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(r *Registry)
+		want   []string
+	}{
+		{
+			name: "counters are summed by labels",
+			record: func(r *Registry) {
+				r.Add("queries_total", "Number of queries.", Labels{"provider": "java", "capability": "referenced"}, 1)
+				r.Add("queries_total", "Number of queries.", Labels{"capability": "referenced", "provider": "java"}, 2)
+				r.Add("queries_total", "Number of queries.", Labels{"provider": "go", "capability": "referenced"}, 1)
+			},
+			want: []string{
+				"# HELP queries_total Number of queries.",
+				"# TYPE queries_total counter",
+				`queries_total{capability="referenced",provider="go"} 1`,
+				`queries_total{capability="referenced",provider="java"} 3`,
+			},
+		},
+		{
+			name: "label values are escaped",
+			record: func(r *Registry) {
+				r.Add("rules_total", "", Labels{"rule": "a\"b\\c\nd"}, 1)
+			},
+			want: []string{
+				"# TYPE rules_total counter",
+				`rules_total{rule="a\"b\\c\nd"} 1`,
+			},
+		},
+		{
+			name: "histograms have cumulative buckets",
+			record: func(r *Registry) {
+				r.Observe("duration_seconds", "Duration.", nil, 0.003)
+				r.Observe("duration_seconds", "Duration.", nil, 0.2)
+				r.Observe("duration_seconds", "Duration.", nil, 1000)
+			},
+			want: []string{
+				"# HELP duration_seconds Duration.",
+				"# TYPE duration_seconds histogram",
+				`duration_seconds_bucket{le="0.005"} 1`,
+				`duration_seconds_bucket{le="0.01"} 1`,
+				`duration_seconds_bucket{le="0.025"} 1`,
+				`duration_seconds_bucket{le="0.05"} 1`,
+				`duration_seconds_bucket{le="0.1"} 1`,
+				`duration_seconds_bucket{le="0.25"} 2`,
+				`duration_seconds_bucket{le="0.5"} 2`,
+				`duration_seconds_bucket{le="1"} 2`,
+				`duration_seconds_bucket{le="2.5"} 2`,
+				`duration_seconds_bucket{le="5"} 2`,
+				`duration_seconds_bucket{le="10"} 2`,
+				`duration_seconds_bucket{le="30"} 2`,
+				`duration_seconds_bucket{le="60"} 2`,
+				`duration_seconds_bucket{le="120"} 2`,
+				`duration_seconds_bucket{le="300"} 2`,
+				`duration_seconds_bucket{le="+Inf"} 3`,
+				"duration_seconds_sum 1000.203",
+				"duration_seconds_count 3",
+			},
+		},
+		{
+			name: "summaries and counter funcs",
+			record: func(r *Registry) {
+				r.Summarize("rule_seconds", "", Labels{"rule": "r1"}, 0.5)
+				r.Summarize("rule_seconds", "", Labels{"rule": "r1"}, 1.5)
+				hits := 0
+				r.CounterFunc("hits_total", "", nil, func() float64 { return float64(hits) })
+				hits = 4
+			},
+			want: []string{
+				"# TYPE hits_total counter",
+				"hits_total 4",
+				"# TYPE rule_seconds summary",
+				`rule_seconds_sum{rule="r1"} 2`,
+				`rule_seconds_count{rule="r1"} 2`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.record(r)
+			b := &strings.Builder{}
+			if _, err := r.WriteTo(b); err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Join(tt.want, "\n") + "\n"
+			if b.String() != want {
+				t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Add("queries_total", "", nil, 1)
+	ts := httptest.NewServer(r.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+	resp, err = http.Post(ts.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d for a POST, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestRuleMiddleware(t *testing.T) {
+	r := NewRegistry()
+	m := RuleMiddleware(r)
+	ctx := context.Background()
+	for _, eval := range []*engine.RuleEvaluation{
+		{RuleSetName: "rs", Rule: engine.Rule{RuleMeta: engine.RuleMeta{RuleID: "matched"}}, Response: engine.ConditionResponse{Matched: true}},
+		{RuleSetName: "rs", Rule: engine.Rule{RuleMeta: engine.RuleMeta{RuleID: "unmatched"}}},
+		{RuleSetName: "rs", Rule: engine.Rule{RuleMeta: engine.RuleMeta{RuleID: "failed"}}, Err: errors.New("failed")},
+	} {
+		if err := m.BeforeRule(ctx, eval); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.AfterRule(ctx, eval); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := &strings.Builder{}
+	r.WriteTo(b)
+	for _, want := range []string{
+		`analyzer_rules_evaluated_total{result="error",ruleset="rs"} 1`,
+		`analyzer_rules_evaluated_total{result="matched",ruleset="rs"} 1`,
+		`analyzer_rules_evaluated_total{result="unmatched",ruleset="rs"} 1`,
+		`analyzer_rule_evaluation_seconds_count{rule="matched",ruleset="rs"} 1`,
+		`analyzer_rule_evaluation_seconds_count{rule="failed",ruleset="rs"} 1`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
+
+type fakeProvider struct {
+	provider.InternalProviderClient
+	err error
+}
+
+func (p *fakeProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	return provider.ProviderEvaluateResponse{Matched: true}, p.err
+}
+
+func TestProviderMetrics(t *testing.T) {
+	r := NewRegistry()
+	cache := provider.NewQueryCache()
+	RegisterQueryCache(r, cache)
+	ok := provider.WithQueryCache("java", WithProviderMetrics("java", &fakeProvider{}, r), cache)
+	failing := WithProviderMetrics("go", &fakeProvider{err: errors.New("failed")}, r)
+	ctx := context.Background()
+	ok.Evaluate(ctx, "referenced", []byte("a"))
+	ok.Evaluate(ctx, "referenced", []byte("a"))
+	ok.Evaluate(ctx, "referenced", []byte("b"))
+	failing.Evaluate(ctx, "referenced", []byte("a"))
+
+	b := &strings.Builder{}
+	r.WriteTo(b)
+	for _, want := range []string{
+		`analyzer_provider_queries_total{capability="referenced",provider="java"} 2`,
+		`analyzer_provider_queries_total{capability="referenced",provider="go"} 1`,
+		`analyzer_provider_query_errors_total{capability="referenced",provider="go"} 1`,
+		`analyzer_provider_query_duration_seconds_count{capability="referenced",provider="java"} 2`,
+		"analyzer_query_cache_hits_total 1",
+		"analyzer_query_cache_misses_total 2",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `analyzer_provider_query_errors_total{capability="referenced",provider="java"}`) {
+		t.Errorf("unexpected errors of the java provider in:\n%s", b.String())
+	}
+}
+
+func TestRecordProviderStats(t *testing.T) {
+	r := NewRegistry()
+	RecordProviderStats(r, "java", []provider.CapabilityStats{
+		{Capability: "referenced", Evaluations: 4, Errors: 1, MeanLatency: 500 * time.Millisecond, CacheHits: 2},
+	})
+	RecordProviderStats(r, "java", []provider.CapabilityStats{
+		{Capability: "referenced", Evaluations: 1, MeanLatency: time.Second},
+	})
+
+	b := &strings.Builder{}
+	r.WriteTo(b)
+	for _, want := range []string{
+		"# TYPE analyzer_provider_evaluation_seconds summary",
+		`analyzer_provider_evaluations_total{capability="referenced",provider="java"} 5`,
+		`analyzer_provider_evaluation_errors_total{capability="referenced",provider="java"} 1`,
+		`analyzer_provider_evaluation_cache_hits_total{capability="referenced",provider="java"} 2`,
+		`analyzer_provider_evaluation_seconds_sum{capability="referenced",provider="java"} 3`,
+		`analyzer_provider_evaluation_seconds_count{capability="referenced",provider="java"} 5`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestRPCHandler(t *testing.T) {
+	r := NewRegistry()
+	h := NewRPCHandler(r, "java")
+	ctx := context.Background()
+	h.Read(ctx, 100)
+	h.Read(ctx, 20)
+	h.Wrote(ctx, 7)
+
+	b := &strings.Builder{}
+	r.WriteTo(b)
+	for _, want := range []string{
+		`analyzer_jsonrpc_read_bytes_total{server="java"} 120`,
+		`analyzer_jsonrpc_written_bytes_total{server="java"} 7`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
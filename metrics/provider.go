@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+type metricsClient struct {
+	provider.InternalProviderClient
+	name     string
+	registry *Registry
+}
+
+// WithProviderMetrics returns a client that records the number, the errors
+// and the latency of the queries sent to the provider by capability. It is
+// given inside the query cache so that only the queries the provider
+// answers are recorded.
+func WithProviderMetrics(name string, client provider.InternalProviderClient, registry *Registry) provider.InternalProviderClient {
+	return &metricsClient{
+		InternalProviderClient: client,
+		name:                   name,
+		registry:               registry,
+	}
+}
+
+func (c *metricsClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	start := time.Now()
+	resp, err := c.InternalProviderClient.Evaluate(ctx, cap, conditionInfo)
+	labels := Labels{"provider": c.name, "capability": cap}
+	c.registry.Add("analyzer_provider_queries_total", "Number of queries sent to the providers.", labels, 1)
+	if err != nil {
+		c.registry.Add("analyzer_provider_query_errors_total", "Number of queries the providers failed.", labels, 1)
+	}
+	c.registry.Observe("analyzer_provider_query_duration_seconds", "Time taken by the providers to answer the queries.",
+		labels, time.Since(start).Seconds())
+	return resp, err
+}
+
+// RegisterQueryCache records the hits and misses of the query cache, the
+// hit rate is hits / (hits + misses)
+func RegisterQueryCache(registry *Registry, cache *provider.QueryCache) {
+	registry.CounterFunc("analyzer_query_cache_hits_total", "Number of queries answered from the query cache.",
+		nil, func() float64 { return float64(cache.Hits()) })
+	registry.CounterFunc("analyzer_query_cache_misses_total", "Number of queries that were not in the query cache.",
+		nil, func() float64 { return float64(cache.Misses()) })
+}
+
+// RecordProviderStats adds the statistics a provider reported of the
+// evaluations of its capabilities, as the provider measured them. The
+// queries the analyzer sent include the time spent between the analyzer and
+// the provider, the evaluations do not.
+func RecordProviderStats(registry *Registry, name string, stats []provider.CapabilityStats) {
+	for _, s := range stats {
+		labels := Labels{"provider": name, "capability": s.Capability}
+		registry.Add("analyzer_provider_evaluations_total", "Number of conditions the providers evaluated, as reported by the providers.",
+			labels, float64(s.Evaluations))
+		registry.Add("analyzer_provider_evaluation_errors_total", "Number of conditions the providers failed to evaluate, as reported by the providers.",
+			labels, float64(s.Errors))
+		registry.Add("analyzer_provider_evaluation_cache_hits_total", "Number of conditions the providers answered from their own caches.",
+			labels, float64(s.CacheHits))
+		registry.AddSummary("analyzer_provider_evaluation_seconds", "Time taken by the providers to evaluate the conditions, as measured by the providers.",
+			labels, s.MeanLatency.Seconds()*float64(s.Evaluations), uint64(s.Evaluations))
+	}
+}
@@ -0,0 +1,258 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// sniffSize is how much of a file is read to tell whether it is binary or
+// generated
+const sniffSize = 8000
+
+// generatedRegex matches the markers that code generators write at the top of
+// the files
+var generatedRegex = regexp.MustCompile(`(?i)(code generated .* do not edit|@generated|<auto-generated|this file (is|was) (auto-?)?generated|automatically generated)`)
+
+type SkipReason string
+
+const (
+	// SkipExcluded is for the files and directories that are out of the
+	// scope of the analysis, see --include-path and --exclude-path
+	SkipExcluded SkipReason = "excluded"
+	// SkipBinary is for the binary files that no provider examines
+	SkipBinary SkipReason = "binary"
+	// SkipUnsupported is for the files that only providers that do not
+	// examine this kind of files are configured for
+	SkipUnsupported SkipReason = "unsupported"
+)
+
+// ProviderCoverage is a provider that examined a file
+type ProviderCoverage struct {
+	Name string `yaml:"name" json:"name"`
+	// Rules is the number of evaluated rules that sent queries to the
+	// provider
+	Rules int `yaml:"rules" json:"rules"`
+}
+
+// File is the coverage of a file, or of a directory that was skipped as a
+// whole
+type File struct {
+	Path      string             `yaml:"path" json:"path"`
+	Providers []ProviderCoverage `yaml:"providers,omitempty" json:"providers,omitempty"`
+	Skipped   SkipReason         `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+	// Generated is set for the files that have the marker of a code
+	// generator, they are examined like the other files.
+	Generated bool `yaml:"generated,omitempty" json:"generated,omitempty"`
+}
+
+// Summary counts the files by coverage, the skipped directories count as one
+type Summary struct {
+	Files     int                `yaml:"files" json:"files"`
+	Examined  int                `yaml:"examined" json:"examined"`
+	Generated int                `yaml:"generated" json:"generated"`
+	Skipped   map[SkipReason]int `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+}
+
+// Report is the bill of analysis: every file in the locations of the
+// providers with the providers that examined it, or why it was skipped
+type Report struct {
+	Summary Summary `yaml:"summary" json:"summary"`
+	Files   []File  `yaml:"files" json:"files"`
+}
+
+// Build walks the locations of the providers to report the coverage of an
+// analysis. The rules are the ones that were given to the engine, the
+// results tell which of them were evaluated. The clients are used to tell
+// which files the providers examine, see provider.FileExaminer.
+func Build(configs []provider.Config, clients map[string]provider.InternalProviderClient, rules []engine.RuleSet, results []konveyor.RuleSet, scope *engine.Scope) (Report, error) {
+	ruleCounts := countRulesByProvider(rules, results)
+	report := Report{
+		Summary: Summary{Skipped: map[SkipReason]int{}},
+		Files:   []File{},
+	}
+	for _, root := range walkRoots(configs) {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && scope.ExcludesDir(root, path) {
+					report.add(File{Path: path, Skipped: SkipExcluded})
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if !scope.Matches(root, path) {
+				report.add(File{Path: path, Skipped: SkipExcluded})
+				return nil
+			}
+			binary, generated, err := sniff(path)
+			if err != nil {
+				return err
+			}
+			file := File{Path: path, Generated: generated, Providers: []ProviderCoverage{}}
+			for _, config := range configs {
+				if !examines(config, clients[config.Name], path, binary) {
+					continue
+				}
+				file.Providers = append(file.Providers, ProviderCoverage{Name: config.Name, Rules: ruleCounts[config.Name]})
+			}
+			if len(file.Providers) == 0 {
+				file.Providers = nil
+				file.Skipped = SkipUnsupported
+				if binary {
+					file.Skipped = SkipBinary
+				}
+			}
+			report.add(file)
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (r *Report) add(file File) {
+	r.Files = append(r.Files, file)
+	r.Summary.Files++
+	if file.Skipped != "" {
+		r.Summary.Skipped[file.Skipped]++
+	} else {
+		r.Summary.Examined++
+	}
+	if file.Generated {
+		r.Summary.Generated++
+	}
+}
+
+// walkRoots returns the absolute roots of all the providers that are not in
+// another root, sorted so that the report does not depend on the settings order
+func walkRoots(configs []provider.Config) []string {
+	all := provider.InitConfig{}
+	for _, config := range configs {
+		for _, ic := range config.InitConfig {
+			for _, root := range ic.Roots() {
+				if abs, err := filepath.Abs(root); err == nil {
+					all.WorkspaceFolders = append(all.WorkspaceFolders, abs)
+				}
+			}
+		}
+	}
+	roots := all.WalkRoots()
+	sort.Strings(roots)
+	return roots
+}
+
+// examines returns whether the provider examines the file, the providers
+// that do not tell it examine the text files of their locations
+func examines(config provider.Config, client provider.InternalProviderClient, path string, binary bool) bool {
+	in := false
+	for _, ic := range config.InitConfig {
+		if ic.Contains(path) {
+			in = true
+			break
+		}
+	}
+	if !in {
+		return false
+	}
+	if e, ok := client.(provider.FileExaminer); ok {
+		return e.Examines(path)
+	}
+	return !binary
+}
+
+// sniff reads the beginning of a file to tell whether it is binary, it is
+// when it has a NUL byte, or generated
+func sniff(path string) (binary bool, generated bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, false, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, false, err
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true, false, nil
+	}
+	// the markers are in the comments at the top of the files
+	scanner := bufio.NewScanner(bytes.NewReader(head))
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		if generatedRegex.Match(scanner.Bytes()) {
+			return false, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+// countRulesByProvider counts the evaluated rules that have conditions on
+// each provider
+func countRulesByProvider(rules []engine.RuleSet, results []konveyor.RuleSet) map[string]int {
+	evaluated := map[string]map[string]bool{}
+	for _, rs := range results {
+		ids := map[string]bool{}
+		for id := range rs.Violations {
+			ids[id] = true
+		}
+		for id := range rs.Errors {
+			ids[id] = true
+		}
+		for _, id := range rs.Unmatched {
+			ids[id] = true
+		}
+		evaluated[rs.Name] = ids
+	}
+	counts := map[string]int{}
+	for _, rs := range rules {
+		for _, rule := range rs.Rules {
+			if !evaluated[rs.Name][rule.RuleID] {
+				continue
+			}
+			providers := map[string]bool{}
+			conditionProviders(rule.When, providers)
+			for name := range providers {
+				counts[name]++
+			}
+		}
+	}
+	return counts
+}
+
+// conditionProviders adds the providers the conditions send queries to
+func conditionProviders(c engine.Conditional, providers map[string]bool) {
+	switch c := c.(type) {
+	case engine.AndCondition:
+		for _, e := range c.Conditions {
+			conditionProviders(e.ProviderSpecificConfig, providers)
+		}
+	case engine.OrCondition:
+		for _, e := range c.Conditions {
+			conditionProviders(e.ProviderSpecificConfig, providers)
+		}
+	case engine.ConditionEntry:
+		conditionProviders(c.ProviderSpecificConfig, providers)
+	case provider.ProviderCondition:
+		providers[c.ProviderName] = true
+	case *provider.ProviderCondition:
+		providers[c.ProviderName] = true
+	}
+}
@@ -0,0 +1,124 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+type testExaminer struct {
+	provider.InternalProviderClient
+}
+
+func (t testExaminer) Examines(path string) bool {
+	return strings.HasSuffix(path, ".java") || strings.HasSuffix(path, ".jar")
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/Main.java":          "package main;\n",
+		"src/gen/Generated.java": "// Code generated by protoc-gen-java. DO NOT EDIT.\npackage gen;\n",
+		"lib/app.jar":            "PK\x03\x04\x00\x00",
+		"lib/native.so":          "\x7fELF\x00\x01",
+		"README.md":              "# app\n",
+		"vendor/dep/Dep.java":    "package dep;\n",
+		"src/Main.min.js":        "var a;\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs := []provider.Config{
+		{Name: "java", InitConfig: []provider.InitConfig{{Location: dir}}},
+		{Name: "builtin", InitConfig: []provider.InitConfig{{Location: dir}}},
+	}
+	clients := map[string]provider.InternalProviderClient{
+		"java":    testExaminer{},
+		"builtin": nil,
+	}
+	rules := []engine.RuleSet{{
+		Name: "rules",
+		Rules: []engine.Rule{
+			{RuleMeta: engine.RuleMeta{RuleID: "java-only"}, When: provider.ProviderCondition{ProviderName: "java"}},
+			{RuleMeta: engine.RuleMeta{RuleID: "both"}, When: engine.AndCondition{Conditions: []engine.ConditionEntry{
+				{ProviderSpecificConfig: provider.ProviderCondition{ProviderName: "java"}},
+				{ProviderSpecificConfig: provider.ProviderCondition{ProviderName: "builtin"}},
+			}}},
+			{RuleMeta: engine.RuleMeta{RuleID: "failed"}, When: provider.ProviderCondition{ProviderName: "builtin"}},
+			{RuleMeta: engine.RuleMeta{RuleID: "not-selected"}, When: provider.ProviderCondition{ProviderName: "java"}},
+		},
+	}}
+	results := []konveyor.RuleSet{{
+		Name:       "rules",
+		Violations: map[string]konveyor.Violation{"java-only": {}},
+		Unmatched:  []string{"both"},
+		Errors:     map[string]string{"failed": "timeout"},
+		Skipped:    []string{"not-selected"},
+	}}
+	scope := &engine.Scope{Exclude: []string{"vendor", "*.min.js"}}
+
+	report, err := Build(configs, clients, rules, results, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	java := ProviderCoverage{Name: "java", Rules: 2}
+	builtin := ProviderCoverage{Name: "builtin", Rules: 2}
+	want := []File{
+		{Path: "README.md", Providers: []ProviderCoverage{builtin}},
+		{Path: "lib/app.jar", Providers: []ProviderCoverage{java}},
+		{Path: "lib/native.so", Skipped: SkipBinary},
+		{Path: "src/Main.java", Providers: []ProviderCoverage{java, builtin}},
+		{Path: "src/Main.min.js", Skipped: SkipExcluded},
+		{Path: "src/gen/Generated.java", Providers: []ProviderCoverage{java, builtin}, Generated: true},
+		{Path: "vendor", Skipped: SkipExcluded},
+	}
+	got := []File{}
+	for _, f := range report.Files {
+		rel, err := filepath.Rel(dir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Path = filepath.ToSlash(rel)
+		got = append(got, f)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected files\n%+v\ngot\n%+v", want, got)
+	}
+	wantSummary := Summary{
+		Files:     7,
+		Examined:  4,
+		Generated: 1,
+		Skipped:   map[SkipReason]int{SkipBinary: 1, SkipExcluded: 2},
+	}
+	if !reflect.DeepEqual(report.Summary, wantSummary) {
+		t.Errorf("expected summary %+v, got %+v", wantSummary, report.Summary)
+	}
+}
+
+func TestBuildUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs := []provider.Config{{Name: "java", InitConfig: []provider.InitConfig{{Location: dir}}}}
+	clients := map[string]provider.InternalProviderClient{"java": testExaminer{}}
+	report, err := Build(configs, clients, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 || report.Files[0].Skipped != SkipUnsupported {
+		t.Errorf("expected the go file to be unsupported by java, got %+v", report.Files)
+	}
+}
@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// DefaultLineTolerance is how many lines an incident can move in its file
+// between two analyses and still be the same incident
+const DefaultLineTolerance = 3
+
+// Options configure how the incidents of two analyses are matched
+type Options struct {
+	// LineTolerance is how many lines an incident can move in its file and
+	// still match, 0 only matches the incidents on the same line
+	LineTolerance int
+}
+
+// Entry is an incident of either analysis
+type Entry struct {
+	RuleSet  string            `yaml:"ruleset" json:"ruleset"`
+	RuleID   string            `yaml:"ruleID" json:"ruleID"`
+	Incident konveyor.Incident `yaml:"incident" json:"incident"`
+	// BeforeLineNumber is the line of an unchanged incident in the analysis
+	// before, when it moved
+	BeforeLineNumber *int `yaml:"beforeLineNumber,omitempty" json:"beforeLineNumber,omitempty"`
+}
+
+// Rule is a rule of a ruleset
+type Rule struct {
+	RuleSet string `yaml:"ruleset" json:"ruleset"`
+	RuleID  string `yaml:"ruleID" json:"ruleID"`
+}
+
+// Summary counts the incidents of a diff
+type Summary struct {
+	Added     int `yaml:"added" json:"added"`
+	Removed   int `yaml:"removed" json:"removed"`
+	Unchanged int `yaml:"unchanged" json:"unchanged"`
+}
+
+// Result is the difference between the analyses before and after a change,
+// such as a remediation
+type Result struct {
+	Summary Summary `yaml:"summary" json:"summary"`
+	// NewViolations are the rules that only have incidents after
+	NewViolations []Rule `yaml:"newViolations" json:"newViolations"`
+	// ResolvedViolations are the rules that only have incidents before
+	ResolvedViolations []Rule `yaml:"resolvedViolations" json:"resolvedViolations"`
+	// Added are the incidents that are only after, the new ones
+	Added []Entry `yaml:"added" json:"added"`
+	// Removed are the incidents that are only before, the resolved ones
+	Removed []Entry `yaml:"removed" json:"removed"`
+	// Unchanged are the incidents of after that match an incident of before
+	Unchanged []Entry `yaml:"unchanged" json:"unchanged"`
+}
+
+// Load reads the rulesets of an analysis output, in either format
+func Load(path string) ([]konveyor.RuleSet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rulesets, err := encoder.DecodeRuleSets(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the analysis output %s: %w", path, err)
+	}
+	return rulesets, nil
+}
+
+// Files returns the difference between the outputs of two analyses
+func Files(before, after string, opts Options) (Result, error) {
+	beforeRuleSets, err := Load(before)
+	if err != nil {
+		return Result{}, err
+	}
+	afterRuleSets, err := Load(after)
+	if err != nil {
+		return Result{}, err
+	}
+	return Diff(beforeRuleSets, afterRuleSets, opts), nil
+}
+
+// Diff returns the difference between the rulesets of two analyses. The
+// incidents of a rule match when they are in the same file and their lines
+// are at most LineTolerance apart, the incidents with the same message and
+// the closest ones are matched first. The incidents without a line match
+// when they are in the same file and have the same message.
+func Diff(before, after []konveyor.RuleSet, opts Options) Result {
+	beforeIncidents := incidentsByRule(before)
+	afterIncidents := incidentsByRule(after)
+	rules := []Rule{}
+	for rule := range afterIncidents {
+		rules = append(rules, rule)
+	}
+	for rule := range beforeIncidents {
+		if _, ok := afterIncidents[rule]; !ok {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].RuleSet != rules[j].RuleSet {
+			return rules[i].RuleSet < rules[j].RuleSet
+		}
+		return rules[i].RuleID < rules[j].RuleID
+	})
+
+	result := Result{
+		NewViolations:      []Rule{},
+		ResolvedViolations: []Rule{},
+		Added:              []Entry{},
+		Removed:            []Entry{},
+		Unchanged:          []Entry{},
+	}
+	for _, rule := range rules {
+		b, a := beforeIncidents[rule], afterIncidents[rule]
+		switch {
+		case len(b) == 0 && len(a) > 0:
+			result.NewViolations = append(result.NewViolations, rule)
+		case len(b) > 0 && len(a) == 0:
+			result.ResolvedViolations = append(result.ResolvedViolations, rule)
+		}
+		diffRule(rule, b, a, opts, &result)
+	}
+	result.Summary = Summary{
+		Added:     len(result.Added),
+		Removed:   len(result.Removed),
+		Unchanged: len(result.Unchanged),
+	}
+	return result
+}
+
+func diffRule(rule Rule, before, after []konveyor.Incident, opts Options, result *Result) {
+	type candidate struct {
+		before, after int
+		otherMessage  bool
+		distance      int
+	}
+	candidates := []candidate{}
+	for i, b := range before {
+		for j, a := range after {
+			if distance, ok := match(b, a, opts.LineTolerance); ok {
+				candidates = append(candidates, candidate{i, j, normalize(b.Message) != normalize(a.Message), distance})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].otherMessage != candidates[j].otherMessage {
+			return !candidates[i].otherMessage
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	matchedBefore := make([]bool, len(before))
+	matchedAfter := make([]bool, len(after))
+	for _, c := range candidates {
+		if matchedBefore[c.before] || matchedAfter[c.after] {
+			continue
+		}
+		matchedBefore[c.before] = true
+		matchedAfter[c.after] = true
+		entry := Entry{RuleSet: rule.RuleSet, RuleID: rule.RuleID, Incident: after[c.after]}
+		if c.distance != 0 {
+			entry.BeforeLineNumber = before[c.before].LineNumber
+		}
+		result.Unchanged = append(result.Unchanged, entry)
+	}
+	for j, a := range after {
+		if !matchedAfter[j] {
+			result.Added = append(result.Added, Entry{RuleSet: rule.RuleSet, RuleID: rule.RuleID, Incident: a})
+		}
+	}
+	for i, b := range before {
+		if !matchedBefore[i] {
+			result.Removed = append(result.Removed, Entry{RuleSet: rule.RuleSet, RuleID: rule.RuleID, Incident: b})
+		}
+	}
+}
+
+// match returns how many lines apart two incidents of a rule are when they
+// are the same incident
+func match(before, after konveyor.Incident, tolerance int) (int, bool) {
+	if before.URI != after.URI {
+		return 0, false
+	}
+	if before.LineNumber == nil || after.LineNumber == nil {
+		return 0, before.LineNumber == nil && after.LineNumber == nil && normalize(before.Message) == normalize(after.Message)
+	}
+	distance := *before.LineNumber - *after.LineNumber
+	if distance < 0 {
+		distance = -distance
+	}
+	return distance, distance <= tolerance
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// incidentsByRule returns the incidents of each rule of the rulesets
+func incidentsByRule(rulesets []konveyor.RuleSet) map[Rule][]konveyor.Incident {
+	incidents := map[Rule][]konveyor.Incident{}
+	for _, rs := range rulesets {
+		for id, v := range rs.Violations {
+			rule := Rule{RuleSet: rs.Name, RuleID: id}
+			incidents[rule] = append(incidents[rule], v.Incidents...)
+		}
+	}
+	return incidents
+}
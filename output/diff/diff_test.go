@@ -0,0 +1,167 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+func incident(file string, line int, message string) konveyor.Incident {
+	i := konveyor.Incident{URI: uri.URI("file:///src/" + file), Message: message}
+	if line > 0 {
+		i.LineNumber = &line
+	}
+	return i
+}
+
+func rulesets(violations map[string][]konveyor.Incident) []konveyor.RuleSet {
+	rs := konveyor.RuleSet{Name: "ruleset", Violations: map[string]konveyor.Violation{}}
+	for id, incidents := range violations {
+		rs.Violations[id] = konveyor.Violation{Incidents: incidents}
+	}
+	return []konveyor.RuleSet{rs}
+}
+
+type entry struct {
+	rule string
+	file string
+	line int
+}
+
+func entries(es []Entry) []entry {
+	got := []entry{}
+	for _, e := range es {
+		line := 0
+		if e.Incident.LineNumber != nil {
+			line = *e.Incident.LineNumber
+		}
+		got = append(got, entry{e.RuleID, filepath.Base(string(e.Incident.URI)), line})
+	}
+	return got
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before        map[string][]konveyor.Incident
+		after         map[string][]konveyor.Incident
+		tolerance     int
+		wantAdded     []entry
+		wantRemoved   []entry
+		wantUnchanged []entry
+		wantNew       []Rule
+		wantResolved  []Rule
+	}{
+		{
+			name:          "same incidents",
+			before:        map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}},
+			after:         map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}},
+			wantUnchanged: []entry{{"rule-1", "A.java", 10}},
+		},
+		{
+			name:          "moved within the tolerance",
+			before:        map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}},
+			after:         map[string][]konveyor.Incident{"rule-1": {incident("A.java", 13, "a")}},
+			tolerance:     3,
+			wantUnchanged: []entry{{"rule-1", "A.java", 13}},
+		},
+		{
+			name:        "moved beyond the tolerance",
+			before:      map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}},
+			after:       map[string][]konveyor.Incident{"rule-1": {incident("A.java", 14, "a")}},
+			tolerance:   3,
+			wantAdded:   []entry{{"rule-1", "A.java", 14}},
+			wantRemoved: []entry{{"rule-1", "A.java", 10}},
+		},
+		{
+			name:   "same message matched first",
+			before: map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a"), incident("A.java", 11, "b")}},
+			after:  map[string][]konveyor.Incident{"rule-1": {incident("A.java", 12, "b")}},
+			// a is closer to line 12 than b but b has the same message
+			tolerance:     3,
+			wantUnchanged: []entry{{"rule-1", "A.java", 12}},
+			wantRemoved:   []entry{{"rule-1", "A.java", 10}},
+		},
+		{
+			name:          "incidents without lines",
+			before:        map[string][]konveyor.Incident{"rule-1": {incident("pom.xml", 0, "upgrade"), incident("pom.xml", 0, "remove")}},
+			after:         map[string][]konveyor.Incident{"rule-1": {incident("pom.xml", 0, "upgrade")}},
+			wantUnchanged: []entry{{"rule-1", "pom.xml", 0}},
+			wantRemoved:   []entry{{"rule-1", "pom.xml", 0}},
+		},
+		{
+			name:          "new and resolved violations",
+			before:        map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}, "rule-2": {incident("A.java", 20, "b")}},
+			after:         map[string][]konveyor.Incident{"rule-1": {incident("A.java", 10, "a")}, "rule-3": {incident("B.java", 5, "c")}},
+			wantAdded:     []entry{{"rule-3", "B.java", 5}},
+			wantRemoved:   []entry{{"rule-2", "A.java", 20}},
+			wantUnchanged: []entry{{"rule-1", "A.java", 10}},
+			wantNew:       []Rule{{RuleSet: "ruleset", RuleID: "rule-3"}},
+			wantResolved:  []Rule{{RuleSet: "ruleset", RuleID: "rule-2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Diff(rulesets(tt.before), rulesets(tt.after), Options{LineTolerance: tt.tolerance})
+			for name, pair := range map[string][2][]entry{
+				"added":     {tt.wantAdded, entries(result.Added)},
+				"removed":   {tt.wantRemoved, entries(result.Removed)},
+				"unchanged": {tt.wantUnchanged, entries(result.Unchanged)},
+			} {
+				want := pair[0]
+				if want == nil {
+					want = []entry{}
+				}
+				if !reflect.DeepEqual(want, pair[1]) {
+					t.Errorf("expected %s %v, got %v", name, want, pair[1])
+				}
+			}
+			if tt.wantNew == nil {
+				tt.wantNew = []Rule{}
+			}
+			if tt.wantResolved == nil {
+				tt.wantResolved = []Rule{}
+			}
+			if !reflect.DeepEqual(tt.wantNew, result.NewViolations) || !reflect.DeepEqual(tt.wantResolved, result.ResolvedViolations) {
+				t.Errorf("expected new %v and resolved %v violations, got %v and %v", tt.wantNew, tt.wantResolved, result.NewViolations, result.ResolvedViolations)
+			}
+			if result.Summary.Added != len(result.Added) || result.Summary.Removed != len(result.Removed) || result.Summary.Unchanged != len(result.Unchanged) {
+				t.Errorf("expected the summary to count the incidents, got %v", result.Summary)
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.yaml")
+	after := filepath.Join(dir, "after.json")
+	if err := os.WriteFile(before, []byte(`- name: ruleset
+  violations:
+    rule-1:
+      description: old api
+      incidents:
+      - uri: file:///src/A.java
+        message: a
+        lineNumber: 10
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(after, []byte(`{"rulesets": [{"name": "ruleset", "violations": {}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Files(before, after, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Summary.Removed != 1 || len(result.ResolvedViolations) != 1 {
+		t.Errorf("expected the incident to be removed, got %v", result)
+	}
+	if _, err := Files(before, filepath.Join(dir, "missing.yaml"), Options{}); err == nil {
+		t.Errorf("expected an error for a missing output")
+	}
+}
@@ -0,0 +1,269 @@
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+const ConsoleFormat = "console"
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// severities are the icons and colors of the categories, the incidents of the
+// violations without a category are shown as potential.
+var severities = map[konveyor.Category]struct {
+	icon  string
+	color string
+	order int
+}{
+	konveyor.Mandatory: {icon: "✖", color: colorRed, order: 0},
+	konveyor.Optional:  {icon: "▲", color: colorYellow, order: 1},
+	konveyor.Potential: {icon: "●", color: colorCyan, order: 2},
+}
+
+// consoleEncoder prints the incidents grouped by file for a person to read.
+// The colors are only used on a terminal and when NO_COLOR is not set, the
+// lines are cut at the width of the terminal.
+type consoleEncoder struct {
+	w     io.Writer
+	color bool
+	// width is the number of columns the lines are cut at, 0 does not cut them
+	width int
+}
+
+func NewConsoleEncoder(w io.Writer) OutputEncoder {
+	e := &consoleEncoder{w: w}
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		e.color = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+		e.width = terminalWidth(f)
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		e.width = columns
+	}
+	return e
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *consoleEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	return c.EncodeDocument(Document{RuleSets: rulesets, Dependencies: deps})
+}
+
+type consoleIncident struct {
+	ruleID   string
+	category konveyor.Category
+	effort   *int
+	incident konveyor.Incident
+}
+
+func (i consoleIncident) line() int {
+	if i.incident.LineNumber == nil {
+		return 0
+	}
+	return *i.incident.LineNumber
+}
+
+func (c *consoleEncoder) EncodeDocument(doc Document) error {
+	if lines := manifestLines(doc.Manifest); len(lines) > 0 {
+		c.printf("%s\n", c.paint(colorBold, "manifest"))
+		for _, line := range lines {
+			c.printf("  %s\n", c.paint(colorDim, c.cut(line, 2)))
+		}
+		c.printf("\n")
+	}
+	files := map[string][]consoleIncident{}
+	counts := map[konveyor.Category]int{}
+	total := 0
+	errors := []string{}
+	for _, rs := range doc.RuleSets {
+		for ruleID, v := range rs.Violations {
+			category := konveyor.Potential
+			if v.Category != nil {
+				category = *v.Category
+			}
+			for _, incident := range v.Incidents {
+				file := displayPath(incident.URI)
+				files[file] = append(files[file], consoleIncident{
+					ruleID:   fmt.Sprintf("%s/%s", rs.Name, ruleID),
+					category: category,
+					effort:   v.Effort,
+					incident: incident,
+				})
+				counts[category]++
+				total++
+			}
+		}
+		for ruleID, err := range rs.Errors {
+			errors = append(errors, fmt.Sprintf("%s/%s: %s", rs.Name, ruleID, err))
+		}
+	}
+
+	names := []string{}
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		incidents := files[file]
+		sort.SliceStable(incidents, func(i, j int) bool {
+			if incidents[i].line() != incidents[j].line() {
+				return incidents[i].line() < incidents[j].line()
+			}
+			if severities[incidents[i].category].order != severities[incidents[j].category].order {
+				return severities[incidents[i].category].order < severities[incidents[j].category].order
+			}
+			return incidents[i].ruleID < incidents[j].ruleID
+		})
+		c.printf("%s\n", c.paint(colorBold, c.cut(file, 0)))
+		for _, i := range incidents {
+			c.printIncident(i)
+		}
+		c.printf("\n")
+	}
+
+	if len(doc.Dependencies) > 0 {
+		c.printf("%s\n", c.paint(colorBold, "dependencies"))
+		for _, dep := range doc.Dependencies {
+			for _, d := range dep.Dependencies {
+				c.printf("  %s\n", c.cut(fmt.Sprintf("%s %s", d.Name, d.Version), 2))
+			}
+		}
+		c.printf("\n")
+	}
+	if len(doc.Warnings) > 0 {
+		c.printf("%s\n", c.paint(colorBold, "warnings"))
+		for _, w := range doc.Warnings {
+			message := w.Message
+			if w.Provider != "" {
+				message = fmt.Sprintf("%s: %s", w.Provider, message)
+			}
+			c.printf("  %s %s\n", c.paint(colorYellow, "!"), c.cut(message, 4))
+		}
+		c.printf("\n")
+	}
+	if len(errors) > 0 {
+		sort.Strings(errors)
+		c.printf("%s\n", c.paint(colorBold, "errors"))
+		for _, e := range errors {
+			c.printf("  %s %s\n", c.paint(colorRed, "!"), c.cut(e, 4))
+		}
+		c.printf("\n")
+	}
+
+	summary := fmt.Sprintf("%d incidents in %d files", total, len(files))
+	parts := []string{}
+	for _, category := range []konveyor.Category{konveyor.Mandatory, konveyor.Optional, konveyor.Potential} {
+		if counts[category] > 0 {
+			parts = append(parts, c.paint(severities[category].color, fmt.Sprintf("%d %s", counts[category], category)))
+		}
+	}
+	if len(parts) > 0 {
+		summary = fmt.Sprintf("%s (%s)", summary, strings.Join(parts, ", "))
+	}
+	return c.printf("%s\n", summary)
+}
+
+func (c *consoleEncoder) printIncident(i consoleIncident) {
+	severity := severities[i.category]
+	location := ""
+	if i.incident.LineNumber != nil {
+		location = fmt.Sprintf(":%d", *i.incident.LineNumber)
+	}
+	header := fmt.Sprintf("%s%s", i.ruleID, location)
+	if i.effort != nil {
+		header = fmt.Sprintf("%s (effort %d)", header, *i.effort)
+	}
+	c.printf("  %s %s\n", c.paint(severity.color, severity.icon), c.paint(colorBold, c.cut(header, 4)))
+	message, _, _ := strings.Cut(strings.TrimSpace(i.incident.Message), "\n")
+	if message != "" {
+		c.printf("    %s\n", c.cut(message, 4))
+	}
+	for _, line := range snipExcerpt(i.incident) {
+		c.printf("    %s\n", c.paint(colorDim, c.cut(line, 4)))
+	}
+}
+
+// snipExcerpt returns the line of the incident in its code snip with the
+// lines around it.
+func snipExcerpt(incident konveyor.Incident) []string {
+	if incident.CodeSnip == "" || incident.LineNumber == nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(incident.CodeSnip, "\n"), "\n")
+	for i, line := range lines {
+		number, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if number != strconv.Itoa(*incident.LineNumber) {
+			continue
+		}
+		start, end := i-1, i+2
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		return lines[start:end]
+	}
+	return nil
+}
+
+// displayPath shows the files relative to the working directory
+func displayPath(u uri.URI) string {
+	if !strings.HasPrefix(string(u), "file://") {
+		return string(u)
+	}
+	path := u.Filename()
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// cut shortens the text so that it fits the width after the indent
+func (c *consoleEncoder) cut(text string, indent int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	if c.width == 0 {
+		return text
+	}
+	runes := []rune(text)
+	limit := c.width - indent
+	if limit < 10 {
+		limit = 10
+	}
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+func (c *consoleEncoder) paint(color string, text string) string {
+	if !c.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (c *consoleEncoder) printf(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(c.w, format, args...)
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package encoder
+
+import "os"
+
+// terminalWidth is only known on linux, the COLUMNS variable is used
+// elsewhere.
+func terminalWidth(f *os.File) int {
+	return 0
+}
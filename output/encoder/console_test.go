@@ -0,0 +1,125 @@
+package encoder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	mandatory := konveyor.Mandatory
+	effort := 3
+	line := func(n int) *int { return &n }
+	doc := Document{
+		RuleSets: []konveyor.RuleSet{
+			{
+				Name: "ruleset",
+				Violations: map[string]konveyor.Violation{
+					"remove-api": {
+						Category: &mandatory,
+						Effort:   &effort,
+						Incidents: []konveyor.Incident{
+							{
+								URI:        "file:///src/B.java",
+								Message:    "The API was removed\nuse the new one",
+								LineNumber: line(12),
+								CodeSnip:   "10  a();\n11  b();\n12  removed();\n13  c();\n14  d();",
+							},
+							{URI: "file:///src/A.java", Message: "The API was removed", LineNumber: line(3)},
+						},
+					},
+					"consider": {
+						Incidents: []konveyor.Incident{
+							{URI: "file:///src/B.java", Message: "Consider a change that is described with a long message", LineNumber: line(2)},
+						},
+					},
+				},
+				Errors: map[string]string{"failed": "provider failed"},
+			},
+		},
+		Warnings: []konveyor.Warning{{Provider: "java", Message: "unable to get the dependency tree"}},
+	}
+
+	tests := []struct {
+		name  string
+		color bool
+		width int
+		want  string
+	}{
+		{
+			name: "plain",
+			want: `/src/A.java
+  ✖ ruleset/remove-api:3 (effort 3)
+    The API was removed
+
+/src/B.java
+  ● ruleset/consider:2
+    Consider a change that is described with a long message
+  ✖ ruleset/remove-api:12 (effort 3)
+    The API was removed
+    11  b();
+    12  removed();
+    13  c();
+
+warnings
+  ! java: unable to get the dependency tree
+
+errors
+  ! ruleset/failed: provider failed
+
+3 incidents in 2 files (2 mandatory, 1 potential)
+`,
+		},
+		{
+			name:  "narrow",
+			width: 30,
+			want: `/src/A.java
+  ✖ ruleset/remove-api:3 (eff…
+    The API was removed
+
+/src/B.java
+  ● ruleset/consider:2
+    Consider a change that is…
+  ✖ ruleset/remove-api:12 (ef…
+    The API was removed
+    11  b();
+    12  removed();
+    13  c();
+
+warnings
+  ! java: unable to get the d…
+
+errors
+  ! ruleset/failed: provider …
+
+3 incidents in 2 files (2 mandatory, 1 potential)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := &consoleEncoder{w: &buf, color: tt.color, width: tt.width}
+			if err := EncodeDocument(enc, doc); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := &consoleEncoder{w: &buf, color: true}
+	enc.Encode(doc.RuleSets, nil)
+	if !strings.Contains(buf.String(), colorRed+"✖"+colorReset) {
+		t.Errorf("expected the mandatory incidents in red, got %q", buf.String())
+	}
+
+	// the console encoder does not write colors to a file
+	if enc := NewConsoleEncoder(&buf).(*consoleEncoder); enc.color {
+		t.Errorf("expected no colors outside of a terminal")
+	}
+}
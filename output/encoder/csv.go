@@ -0,0 +1,77 @@
+package encoder
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const CSVFormat = "csv"
+
+var (
+	csvIncidentHeader   = []string{"ruleset", "rule", "category", "effort", "file", "line", "message", "labels", "confidence", "review"}
+	csvDependencyHeader = []string{"file", "provider", "name", "version", "type", "indirect", "license", "labels"}
+)
+
+// csvEncoder writes a row for each incident, grouped by ruleset, to be opened
+// in a spreadsheet. The dependencies are written instead when there are no
+// rulesets, the rest of the document is left out but the manifest, which is
+// written before the header as lines starting with #.
+type csvEncoder struct {
+	w io.Writer
+}
+
+func NewCSVEncoder(w io.Writer) OutputEncoder {
+	return &csvEncoder{w: w}
+}
+
+func (c *csvEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	return c.EncodeDocument(Document{RuleSets: rulesets, Dependencies: deps})
+}
+
+func (c *csvEncoder) EncodeDocument(doc Document) error {
+	for _, line := range manifestLines(doc.Manifest) {
+		if _, err := fmt.Fprintf(c.w, "# %s\n", line); err != nil {
+			return err
+		}
+	}
+	w := csv.NewWriter(c.w)
+	if doc.RuleSets == nil && doc.Dependencies != nil {
+		w.Write(csvDependencyHeader)
+		for _, item := range doc.Dependencies {
+			for _, d := range item.Dependencies {
+				w.Write([]string{
+					item.FileURI, item.Provider, d.Name, d.Version, d.Type,
+					strconv.FormatBool(d.Indirect), d.License, strings.Join(d.Labels, ";"),
+				})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	w.Write(csvIncidentHeader)
+	for _, rs := range reportRuleSets(doc.RuleSets) {
+		for _, i := range rs.incidents {
+			effort, line, confidence := "", "", ""
+			if i.violation.Effort != nil {
+				effort = strconv.Itoa(*i.violation.Effort)
+			}
+			if i.incident.LineNumber != nil {
+				line = strconv.Itoa(*i.incident.LineNumber)
+			}
+			if i.incident.Confidence != nil {
+				confidence = strconv.FormatFloat(*i.incident.Confidence, 'f', -1, 64)
+			}
+			w.Write([]string{
+				rs.name, i.ruleID, string(i.category()), effort, displayPath(i.incident.URI), line,
+				strings.TrimSpace(i.incident.Message), strings.Join(i.violation.Labels, ";"), confidence, string(i.incident.Review),
+			})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
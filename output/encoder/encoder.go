@@ -0,0 +1,170 @@
+package encoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	YAMLFormat = "yaml"
+	JSONFormat = "json"
+)
+
+// OutputEncoder writes the results of an analysis in a given format.
+// Either of rulesets or deps may be nil when the caller only has one of them.
+type OutputEncoder interface {
+	Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error
+}
+
+// Document is everything an analysis can output. The encoders keep the
+// existing output shape, a bare list of rulesets or dependencies, and only
+// nest them in the document when more than one part is given.
+type Document struct {
+	// Manifest is how the analysis was run, it is written before the
+	// results by all the encoders
+	Manifest     *konveyor.Manifest      `yaml:"manifest,omitempty" json:"manifest,omitempty"`
+	RuleSets     []konveyor.RuleSet      `yaml:"rulesets" json:"rulesets"`
+	Dependencies []konveyor.DepsFlatItem `yaml:"dependencies" json:"dependencies"`
+	Warnings     []konveyor.Warning      `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+	Revisions    []konveyor.Revision     `yaml:"revisions,omitempty" json:"revisions,omitempty"`
+	Summary      *konveyor.Summary       `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Debug        *konveyor.Debug         `yaml:"debug,omitempty" json:"debug,omitempty"`
+}
+
+// DocumentEncoder is implemented by the encoders that can write the warnings,
+// the summary and the debug information of the analysis along with the
+// results.
+type DocumentEncoder interface {
+	EncodeDocument(doc Document) error
+}
+
+// EncodeDocument writes the document with the encoder, only the rulesets and
+// dependencies are written by the encoders that are not DocumentEncoders.
+func EncodeDocument(enc OutputEncoder, doc Document) error {
+	if d, ok := enc.(DocumentEncoder); ok {
+		return d.EncodeDocument(doc)
+	}
+	return enc.Encode(doc.RuleSets, doc.Dependencies)
+}
+
+// Factory creates a new OutputEncoder that writes to the given writer.
+type Factory func(w io.Writer) OutputEncoder
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Factory{}
+)
+
+func init() {
+	Register(YAMLFormat, NewYAMLEncoder)
+	Register(JSONFormat, NewJSONEncoder)
+	Register(ConsoleFormat, NewConsoleEncoder)
+	Register(CSVFormat, NewCSVEncoder)
+	Register(HTMLFormat, NewHTMLEncoder)
+}
+
+// Register makes an encoder available under the given format name.
+// Registering a format that already exists replaces the previous encoder,
+// this allows embedders to override the built-in encoders.
+func Register(format string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[format] = factory
+}
+
+// New returns an encoder for the given format writing to w.
+func New(format string, w io.Writer) (OutputEncoder, error) {
+	registryMutex.RLock()
+	factory, ok := registry[format]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+	return factory(w), nil
+}
+
+// Formats returns the sorted list of registered format names.
+func Formats() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	formats := []string{}
+	for f := range registry {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// value is what is encoded for the document
+func (d Document) value() interface{} {
+	switch {
+	case d.Manifest != nil || len(d.Warnings) > 0 || len(d.Revisions) > 0 || d.Summary != nil || d.Debug != nil:
+		return d
+	case d.Dependencies == nil:
+		return d.RuleSets
+	case d.RuleSets == nil:
+		return d.Dependencies
+	default:
+		return d
+	}
+}
+
+type yamlEncoder struct {
+	w io.Writer
+}
+
+func NewYAMLEncoder(w io.Writer) OutputEncoder {
+	return &yamlEncoder{w: w}
+}
+
+func (y *yamlEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	return y.EncodeDocument(Document{RuleSets: rulesets, Dependencies: deps})
+}
+
+func (y *yamlEncoder) EncodeDocument(doc Document) error {
+	b, err := yaml.Marshal(doc.value())
+	if err != nil {
+		return err
+	}
+	_, err = y.w.Write(b)
+	return err
+}
+
+type jsonEncoder struct {
+	w io.Writer
+}
+
+func NewJSONEncoder(w io.Writer) OutputEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (j *jsonEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	return j.EncodeDocument(Document{RuleSets: rulesets, Dependencies: deps})
+}
+
+func (j *jsonEncoder) EncodeDocument(doc Document) error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc.value())
+}
+
+// DecodeRuleSets reads the rulesets of an output in either format, as a bare
+// list or in a document.
+func DecodeRuleSets(content []byte) ([]konveyor.RuleSet, error) {
+	rulesets := []konveyor.RuleSet{}
+	listErr := yaml.Unmarshal(content, &rulesets)
+	if listErr == nil {
+		return rulesets, nil
+	}
+	doc := Document{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, listErr
+	}
+	return doc.RuleSets, nil
+}
@@ -0,0 +1,169 @@
+package encoder
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testEncoder struct {
+	w io.Writer
+}
+
+func (t *testEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	for _, r := range rulesets {
+		t.w.Write([]byte(r.Name))
+	}
+	return nil
+}
+
+func TestEncoders(t *testing.T) {
+	Register("test", func(w io.Writer) OutputEncoder {
+		return &testEncoder{w: w}
+	})
+	rulesets := []konveyor.RuleSet{{Name: "ruleset-a"}}
+	deps := []konveyor.DepsFlatItem{{Provider: "java", FileURI: "file:///pom.xml"}}
+	warnings := []konveyor.Warning{{Provider: "builtin", Message: "xml files failed to parse", Count: 1, Items: []string{"pom.xml"}}}
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	manifest := &konveyor.Manifest{
+		AnalyzerVersion: "v0.4.0",
+		StartTime:       start,
+		EndTime:         start.Add(time.Minute),
+		RuleSets:        []konveyor.ManifestRuleSet{{Name: "ruleset-a", Rules: 2, Digest: "sha256:ab"}},
+		Host:            konveyor.Host{OS: "linux", Arch: "amd64", GoVersion: "go1.21.0"},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		rulesets []konveyor.RuleSet
+		deps     []konveyor.DepsFlatItem
+		warnings []konveyor.Warning
+		summary  *konveyor.Summary
+		manifest *konveyor.Manifest
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "yaml rulesets",
+			format:   YAMLFormat,
+			rulesets: rulesets,
+			want:     "- name: ruleset-a\n",
+		},
+		{
+			name:     "json rulesets and deps",
+			format:   JSONFormat,
+			rulesets: rulesets,
+			deps:     deps,
+			want:     "{\n  \"rulesets\": [\n    {\n      \"name\": \"ruleset-a\"\n    }\n  ],\n  \"dependencies\": [\n    {\n      \"fileURI\": \"file:///pom.xml\",\n      \"provider\": \"java\",\n      \"dependencies\": null\n    }\n  ]\n}\n",
+		},
+		{
+			name:     "yaml rulesets and warnings",
+			format:   YAMLFormat,
+			rulesets: rulesets,
+			warnings: warnings,
+			want:     "rulesets:\n- name: ruleset-a\ndependencies: []\nwarnings:\n- provider: builtin\n  message: xml files failed to parse\n  count: 1\n  items:\n  - pom.xml\n",
+		},
+		{
+			name:     "json rulesets and summary",
+			format:   JSONFormat,
+			rulesets: rulesets,
+			summary:  &konveyor.Summary{Violations: 1, Incidents: 2, Effort: 3},
+			want:     "{\n  \"rulesets\": [\n    {\n      \"name\": \"ruleset-a\"\n    }\n  ],\n  \"dependencies\": null,\n  \"summary\": {\n    \"violations\": 1,\n    \"incidents\": 2,\n    \"effort\": 3\n  }\n}\n",
+		},
+		{
+			name:     "yaml manifest before the rulesets",
+			format:   YAMLFormat,
+			rulesets: rulesets,
+			manifest: manifest,
+			want:     "manifest:\n  analyzerVersion: v0.4.0\n  startTime: 2024-03-01T10:00:00Z\n  endTime: 2024-03-01T10:01:00Z\n  rulesets:\n  - name: ruleset-a\n    rules: 2\n    digest: sha256:ab\n  host:\n    os: linux\n    arch: amd64\n    goVersion: go1.21.0\nrulesets:\n- name: ruleset-a\ndependencies: []\n",
+		},
+		{
+			name:     "csv manifest before the header",
+			format:   CSVFormat,
+			manifest: manifest,
+			want:     "# analyzer version: v0.4.0\n# start time: 2024-03-01T10:00:00Z\n# end time: 2024-03-01T10:01:00Z\n# ruleset: ruleset-a, 2 rules, sha256:ab\n# host: linux/amd64, go1.21.0\n" + strings.Join(csvIncidentHeader, ",") + "\n",
+		},
+		{
+			name:     "registered encoder without warnings",
+			format:   "test",
+			rulesets: rulesets,
+			warnings: warnings,
+			want:     "ruleset-a",
+		},
+		{
+			name:     "registered encoder",
+			format:   "test",
+			rulesets: rulesets,
+			want:     "ruleset-a",
+		},
+		{
+			name:    "unknown format",
+			format:  "unknown",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			enc, err := New(tt.format, b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			doc := Document{RuleSets: tt.rulesets, Dependencies: tt.deps, Warnings: tt.warnings, Summary: tt.summary, Manifest: tt.manifest}
+			if err := EncodeDocument(enc, doc); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Encode() got = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRuleSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "yaml list",
+			content: "- name: ruleset-a\n- name: ruleset-b\n",
+			want:    []string{"ruleset-a", "ruleset-b"},
+		},
+		{
+			name:    "json document",
+			content: `{"rulesets": [{"name": "ruleset-a"}], "warnings": [{"message": "warning"}]}`,
+			want:    []string{"ruleset-a"},
+		},
+		{
+			name:    "invalid",
+			content: "- name: [",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesets, err := DecodeRuleSets([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := []string{}
+			for _, rs := range rulesets {
+				got = append(got, rs.Name)
+			}
+			if !tt.wantErr && strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
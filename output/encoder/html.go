@@ -0,0 +1,222 @@
+package encoder
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const HTMLFormat = "html"
+
+// htmlEncoder writes a self-contained page, without scripts or external
+// resources, that can be opened in a browser or attached to a mail: the
+// summary tables first, then a card for each incident grouped by ruleset.
+type htmlEncoder struct {
+	w io.Writer
+}
+
+func NewHTMLEncoder(w io.Writer) OutputEncoder {
+	return &htmlEncoder{w: w}
+}
+
+func (h *htmlEncoder) Encode(rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) error {
+	return h.EncodeDocument(Document{RuleSets: rulesets, Dependencies: deps})
+}
+
+type htmlPage struct {
+	Manifest     []string
+	Categories   []konveyor.Category
+	Total        htmlCounts
+	RuleSets     []htmlRuleSet
+	Dependencies []konveyor.DepsFlatItem
+	Warnings     []konveyor.Warning
+}
+
+type htmlCounts struct {
+	ByCategory map[konveyor.Category]konveyor.SummaryCount
+	Incidents  int
+	Effort     int
+}
+
+type htmlRuleSet struct {
+	Name        string
+	Description string
+	Counts      htmlCounts
+	Rules       []htmlRule
+	Errors      []reportError
+}
+
+type htmlRule struct {
+	ID        string
+	Category  konveyor.Category
+	Violation konveyor.Violation
+	Incidents []htmlIncident
+}
+
+type htmlIncident struct {
+	File     string
+	Line     *int
+	Message  string
+	CodeSnip string
+	Review   konveyor.ReviewState
+}
+
+func (h *htmlEncoder) EncodeDocument(doc Document) error {
+	categories := []konveyor.Category{konveyor.Mandatory, konveyor.Optional, konveyor.Potential}
+	page := htmlPage{
+		Categories:   categories,
+		Total:        htmlCounts{ByCategory: map[konveyor.Category]konveyor.SummaryCount{}},
+		Dependencies: doc.Dependencies,
+		Warnings:     doc.Warnings,
+		Manifest:     manifestLines(doc.Manifest),
+	}
+	for _, rs := range reportRuleSets(doc.RuleSets) {
+		r := htmlRuleSet{
+			Name:        rs.name,
+			Description: rs.description,
+			Counts:      htmlCounts{ByCategory: map[konveyor.Category]konveyor.SummaryCount{}},
+			Errors:      rs.errors,
+		}
+		for category, count := range rs.counts {
+			r.Counts.ByCategory[category] = *count
+			r.Counts.Incidents += count.Incidents
+			r.Counts.Effort += count.Effort
+			total := page.Total.ByCategory[category]
+			total.Violations += count.Violations
+			total.Incidents += count.Incidents
+			total.Effort += count.Effort
+			page.Total.ByCategory[category] = total
+		}
+		page.Total.Incidents += r.Counts.Incidents
+		page.Total.Effort += r.Counts.Effort
+		for _, i := range rs.incidents {
+			if len(r.Rules) == 0 || r.Rules[len(r.Rules)-1].ID != i.ruleID {
+				r.Rules = append(r.Rules, htmlRule{ID: i.ruleID, Category: i.category(), Violation: i.violation})
+			}
+			rule := &r.Rules[len(r.Rules)-1]
+			rule.Incidents = append(rule.Incidents, htmlIncident{
+				File:     displayPath(i.incident.URI),
+				Line:     i.incident.LineNumber,
+				Message:  strings.TrimSpace(i.incident.Message),
+				CodeSnip: i.incident.CodeSnip,
+				Review:   i.incident.Review,
+			})
+		}
+		page.RuleSets = append(page.RuleSets, r)
+	}
+	return htmlTemplate.Execute(h.w, page)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Analysis report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+td.count { text-align: right; }
+.card { border: 1px solid #ccc; border-left-width: 6px; border-radius: 4px; margin: 0.5em 0; padding: 0.5em 1em; }
+.mandatory { border-left-color: #c9190b; }
+.optional { border-left-color: #f0ab00; }
+.potential { border-left-color: #009596; }
+.location { font-family: monospace; font-weight: bold; }
+.message { white-space: pre-wrap; }
+.note { color: #666; font-style: italic; }
+pre { background: #f5f5f5; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Analysis report</h1>
+{{- with .Manifest}}
+<details>
+<summary>Manifest</summary>
+<ul>
+{{- range .}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+</details>
+{{- end}}
+<p>{{.Total.Incidents}} incidents, effort {{.Total.Effort}}</p>
+<table>
+<tr><th>Category</th><th>Rules</th><th>Incidents</th><th>Effort</th></tr>
+{{- range $c := .Categories}}{{with index $.Total.ByCategory $c}}
+<tr><td>{{$c}}</td><td class="count">{{.Violations}}</td><td class="count">{{.Incidents}}</td><td class="count">{{.Effort}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- if .RuleSets}}
+<h2>Rulesets</h2>
+<table>
+<tr><th>Ruleset</th>{{range .Categories}}<th>{{.}}</th>{{end}}<th>Incidents</th><th>Effort</th></tr>
+{{- range $rs := .RuleSets}}
+<tr><td><a href="#{{$rs.Name}}">{{$rs.Name}}</a></td>{{range $c := $.Categories}}<td class="count">{{(index $rs.Counts.ByCategory $c).Incidents}}</td>{{end}}<td class="count">{{$rs.Counts.Incidents}}</td><td class="count">{{$rs.Counts.Effort}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .RuleSets}}
+<section id="{{.Name}}">
+<h2>{{.Name}}</h2>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- range .Rules}}
+<h3>{{.ID}}</h3>
+<p>{{.Category}}{{with .Violation.Effort}}, effort {{.}}{{end}}{{with .Violation.Description}}: {{.}}{{end}}</p>
+{{- with .Violation.Links}}
+<ul>
+{{- range .}}
+<li><a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- $category := .Category}}
+{{- range .Incidents}}
+<div class="card {{$category}}">
+<div class="location">{{.File}}{{with .Line}}:{{.}}{{end}}{{with .Review}} ({{.}}){{end}}</div>
+<div class="message">{{.Message}}</div>
+{{- with .CodeSnip}}
+<pre>{{.}}</pre>
+{{- end}}
+</div>
+{{- end}}
+{{- with .Violation.Sample}}
+<p class="note">a sample of the {{.Incidents}} incidents the rule matched</p>
+{{- end}}
+{{- with .Violation.Overflow}}
+<p class="note">{{.Incidents}} incidents in total, all of them are in {{.File}}</p>
+{{- end}}
+{{- end}}
+{{- with .Errors}}
+<h3>Errors</h3>
+<ul>
+{{- range .}}
+<li>{{.RuleID}}: {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+{{- end}}
+{{- with .Dependencies}}
+<h2>Dependencies</h2>
+<table>
+<tr><th>File</th><th>Provider</th><th>Name</th><th>Version</th><th>License</th></tr>
+{{- range $item := .}}{{range .Dependencies}}
+<tr><td>{{$item.FileURI}}</td><td>{{$item.Provider}}</td><td>{{.Name}}</td><td>{{.Version}}</td><td>{{.License}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- end}}
+{{- with .Warnings}}
+<h2>Warnings</h2>
+<ul>
+{{- range .}}
+<li>{{with .Provider}}{{.}}: {{end}}{{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
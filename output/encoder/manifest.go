@@ -0,0 +1,58 @@
+package encoder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// manifestLines returns the manifest as "key: value" lines, for the encoders
+// of the formats that have no structure to nest it in
+func manifestLines(m *konveyor.Manifest) []string {
+	if m == nil {
+		return nil
+	}
+	lines := []string{
+		fmt.Sprintf("analyzer version: %s", m.AnalyzerVersion),
+		fmt.Sprintf("start time: %s", m.StartTime.Format(time.RFC3339)),
+		fmt.Sprintf("end time: %s", m.EndTime.Format(time.RFC3339)),
+	}
+	for _, rs := range m.RuleSets {
+		line := fmt.Sprintf("ruleset: %s, %d rules", rs.Name, rs.Rules)
+		if rs.Digest != "" {
+			line = fmt.Sprintf("%s, %s", line, rs.Digest)
+		}
+		lines = append(lines, line)
+	}
+	for _, p := range m.Providers {
+		line := fmt.Sprintf("provider: %s", p.Name)
+		if p.LanguageServer != "" {
+			line = fmt.Sprintf("%s, %s", line, p.LanguageServer)
+		}
+		lines = append(lines, fmt.Sprintf("%s, settings %s", line, p.SettingsDigest))
+	}
+	if m.LabelSelector != "" {
+		lines = append(lines, fmt.Sprintf("label selector: %s", m.LabelSelector))
+	}
+	if m.DepLabelSelector != "" {
+		lines = append(lines, fmt.Sprintf("dependency label selector: %s", m.DepLabelSelector))
+	}
+	if len(m.Targets) > 0 {
+		lines = append(lines, fmt.Sprintf("targets: %s", strings.Join(m.Targets, ", ")))
+	}
+	if m.Scope != nil {
+		if len(m.Scope.Include) > 0 {
+			lines = append(lines, fmt.Sprintf("scope include: %s", strings.Join(m.Scope.Include, ", ")))
+		}
+		if len(m.Scope.Exclude) > 0 {
+			lines = append(lines, fmt.Sprintf("scope exclude: %s", strings.Join(m.Scope.Exclude, ", ")))
+		}
+	}
+	host := fmt.Sprintf("%s/%s, %s", m.Host.OS, m.Host.Arch, m.Host.GoVersion)
+	if m.Host.Hostname != "" {
+		host = fmt.Sprintf("%s, %s", m.Host.Hostname, host)
+	}
+	return append(lines, fmt.Sprintf("host: %s", host))
+}
@@ -0,0 +1,100 @@
+package encoder
+
+import (
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// reportRuleSet is a ruleset of a report with its incidents in order
+type reportRuleSet struct {
+	name        string
+	description string
+	incidents   []reportIncident
+	errors      []reportError
+	// counts are the incidents and effort of the ruleset by category
+	counts map[konveyor.Category]*konveyor.SummaryCount
+}
+
+type reportIncident struct {
+	ruleID    string
+	violation konveyor.Violation
+	incident  konveyor.Incident
+}
+
+type reportError struct {
+	RuleID  string
+	Message string
+}
+
+// category returns the category of the incident, the violations without one
+// are reported as potential
+func (i reportIncident) category() konveyor.Category {
+	if i.violation.Category == nil {
+		return konveyor.Potential
+	}
+	return *i.violation.Category
+}
+
+func (i reportIncident) line() int {
+	if i.incident.LineNumber == nil {
+		return 0
+	}
+	return *i.incident.LineNumber
+}
+
+// reportRuleSets returns the rulesets that have incidents or errors sorted by
+// name, with their incidents sorted by rule, file and line
+func reportRuleSets(rulesets []konveyor.RuleSet) []reportRuleSet {
+	report := []reportRuleSet{}
+	for _, rs := range rulesets {
+		r := reportRuleSet{
+			name:        rs.Name,
+			description: rs.Description,
+			counts:      map[konveyor.Category]*konveyor.SummaryCount{},
+		}
+		for ruleID, v := range rs.Violations {
+			if len(v.Incidents) == 0 {
+				continue
+			}
+			category := reportIncident{violation: v}.category()
+			count, ok := r.counts[category]
+			if !ok {
+				count = &konveyor.SummaryCount{}
+				r.counts[category] = count
+			}
+			count.Violations++
+			for _, incident := range v.Incidents {
+				r.incidents = append(r.incidents, reportIncident{ruleID: ruleID, violation: v, incident: incident})
+				count.Incidents++
+				if v.Effort != nil {
+					count.Effort += *v.Effort
+				}
+			}
+		}
+		for ruleID, message := range rs.Errors {
+			r.errors = append(r.errors, reportError{RuleID: ruleID, Message: message})
+		}
+		if len(r.incidents) == 0 && len(r.errors) == 0 {
+			continue
+		}
+		sort.Slice(r.incidents, func(i, j int) bool {
+			a, b := r.incidents[i], r.incidents[j]
+			if a.ruleID != b.ruleID {
+				return a.ruleID < b.ruleID
+			}
+			if a.incident.URI != b.incident.URI {
+				return a.incident.URI < b.incident.URI
+			}
+			return a.line() < b.line()
+		})
+		sort.Slice(r.errors, func(i, j int) bool {
+			return r.errors[i].RuleID < r.errors[j].RuleID
+		})
+		report = append(report, r)
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].name < report[j].name
+	})
+	return report
+}
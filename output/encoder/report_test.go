@@ -0,0 +1,103 @@
+package encoder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func reportDocument() Document {
+	mandatory := konveyor.Mandatory
+	effort := 3
+	line := func(n int) *int { return &n }
+	confidence := 0.5
+	return Document{
+		RuleSets: []konveyor.RuleSet{
+			{
+				Name: "ruleset-b",
+				Violations: map[string]konveyor.Violation{
+					"remove-api": {
+						Category: &mandatory,
+						Effort:   &effort,
+						Labels:   []string{"konveyor.io/target=quarkus", "konveyor.io/source=java-ee"},
+						Links:    []konveyor.Link{{URL: "https://example.com/api", Title: "API"}},
+						Incidents: []konveyor.Incident{
+							{URI: "file:///src/B.java", Message: "The API was removed, use \"New<T>\"", LineNumber: line(12), CodeSnip: "12  removed();"},
+							{URI: "file:///src/A.java", Message: "The API was removed", LineNumber: line(3)},
+						},
+					},
+				},
+				Errors: map[string]string{"failed": "provider failed"},
+			},
+			{
+				Name: "ruleset-a",
+				Violations: map[string]konveyor.Violation{
+					"consider": {
+						Incidents: []konveyor.Incident{
+							{URI: "file:///src/A.java", Message: "Consider a change", LineNumber: line(2), Confidence: &confidence, Review: konveyor.ReviewAccepted},
+						},
+					},
+				},
+			},
+			{Name: "ruleset-empty", Unmatched: []string{"unmatched"}},
+		},
+		Warnings: []konveyor.Warning{{Provider: "java", Message: "unable to get the dependency tree"}},
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := NewCSVEncoder(b).(DocumentEncoder).EncodeDocument(reportDocument()); err != nil {
+		t.Fatal(err)
+	}
+	want := `ruleset,rule,category,effort,file,line,message,labels,confidence,review
+ruleset-a,consider,potential,,/src/A.java,2,Consider a change,,0.5,accepted
+ruleset-b,remove-api,mandatory,3,/src/A.java,3,The API was removed,konveyor.io/target=quarkus;konveyor.io/source=java-ee,,
+ruleset-b,remove-api,mandatory,3,/src/B.java,12,"The API was removed, use ""New<T>""",konveyor.io/target=quarkus;konveyor.io/source=java-ee,,
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	b.Reset()
+	deps := []konveyor.DepsFlatItem{{FileURI: "file:///pom.xml", Provider: "java", Dependencies: []*konveyor.Dep{{Name: "junit.junit", Version: "4.13", License: "EPL-1.0"}}}}
+	if err := NewCSVEncoder(b).Encode(nil, deps); err != nil {
+		t.Fatal(err)
+	}
+	want = "file,provider,name,version,type,indirect,license,labels\nfile:///pom.xml,java,junit.junit,4.13,,false,EPL-1.0,\n"
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func TestHTMLEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := NewHTMLEncoder(b).(DocumentEncoder).EncodeDocument(reportDocument()); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, want := range []string{
+		"<p>3 incidents, effort 6</p>",
+		`<tr><td>mandatory</td><td class="count">1</td><td class="count">2</td><td class="count">6</td></tr>`,
+		`<tr><td><a href="#ruleset-a">ruleset-a</a></td><td class="count">0</td><td class="count">0</td><td class="count">1</td><td class="count">1</td><td class="count">0</td></tr>`,
+		`<div class="location">/src/A.java:2 (accepted)</div>`,
+		`<div class="message">The API was removed, use &#34;New&lt;T&gt;&#34;</div>`,
+		`<li><a href="https://example.com/api">API</a></li>`,
+		"<li>failed: provider failed</li>",
+		"<li>java: unable to get the dependency tree</li>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the page to contain %q, got\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "ruleset-empty") || strings.Contains(page, "<script") {
+		t.Errorf("expected no section for the ruleset without incidents and no script, got\n%s", page)
+	}
+	// the rulesets are in order, and the incidents by rule, file and line
+	if strings.Index(page, `<section id="ruleset-a">`) > strings.Index(page, `<section id="ruleset-b">`) ||
+		strings.Index(page, "/src/A.java:3") > strings.Index(page, "/src/B.java:12") {
+		t.Errorf("expected the rulesets and incidents in order, got\n%s", page)
+	}
+}
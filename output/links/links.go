@@ -0,0 +1,236 @@
+package links
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const (
+	// maxPageSize is how much of a page is read to find its title
+	maxPageSize = 512 * 1024
+	// maxExcerptLength is the length an excerpt is cut at
+	maxExcerptLength = 200
+	fetchWorkers     = 5
+)
+
+var (
+	titleRegex       = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaRegex        = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaNameRegex    = regexp.MustCompile(`(?is)\b(?:name|property)\s*=\s*["'](description|og:description|og:title)["']`)
+	metaContentRegex = regexp.MustCompile(`(?is)\bcontent\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	spaceRegex       = regexp.MustCompile(`\s+`)
+)
+
+// Snapshot is what is kept of a linked page
+type Snapshot struct {
+	Title   string    `json:"title,omitempty"`
+	Excerpt string    `json:"excerpt,omitempty"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// Enricher fills in the titles and excerpts of the links of the violations
+// from the linked pages. The pages are snapshotted in a cache file so that
+// later runs, and runs without network access, do not fetch them again.
+type Enricher struct {
+	cachePath string
+	offline   bool
+	client    *http.Client
+
+	mutex sync.Mutex
+	cache map[string]Snapshot
+}
+
+// NewEnricher loads the cache file when it is given and exists. When offline
+// is set, only the links in the cache are enriched.
+func NewEnricher(cachePath string, offline bool, timeout time.Duration) (*Enricher, error) {
+	e := &Enricher{
+		cachePath: cachePath,
+		offline:   offline,
+		client:    &http.Client{Timeout: timeout},
+		cache:     map[string]Snapshot{},
+	}
+	if cachePath == "" {
+		return e, nil
+	}
+	content, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &e.cache); err != nil {
+		return nil, fmt.Errorf("unable to read links cache %s: %w", cachePath, err)
+	}
+	return e, nil
+}
+
+// Enrich sets the title of the links that do not have one and the excerpt of
+// all the links. The links that can not be fetched are left as they are, it
+// returns the errors of the fetches so that they can be logged.
+func (e *Enricher) Enrich(ctx context.Context, rulesets []konveyor.RuleSet) []error {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, rs := range rulesets {
+		for _, v := range rs.Violations {
+			for _, l := range v.Links {
+				if !seen[l.URL] {
+					seen[l.URL] = true
+					urls = append(urls, l.URL)
+				}
+			}
+		}
+	}
+	errs := e.fetchAll(ctx, urls)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, rs := range rulesets {
+		for id, v := range rs.Violations {
+			for i, l := range v.Links {
+				snapshot, ok := e.cache[l.URL]
+				if !ok {
+					continue
+				}
+				if l.Title == "" {
+					v.Links[i].Title = snapshot.Title
+				}
+				v.Links[i].Excerpt = snapshot.Excerpt
+			}
+			rs.Violations[id] = v
+		}
+	}
+	return errs
+}
+
+func (e *Enricher) fetchAll(ctx context.Context, urls []string) []error {
+	if e.offline {
+		return nil
+	}
+	missing := make(chan string)
+	errs := []error{}
+	errsMutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < fetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range missing {
+				snapshot, err := e.fetch(ctx, url)
+				if err != nil {
+					errsMutex.Lock()
+					errs = append(errs, fmt.Errorf("unable to fetch link %s: %w", url, err))
+					errsMutex.Unlock()
+					continue
+				}
+				e.mutex.Lock()
+				e.cache[url] = snapshot
+				e.mutex.Unlock()
+			}
+		}()
+	}
+	for _, url := range urls {
+		e.mutex.Lock()
+		_, cached := e.cache[url]
+		e.mutex.Unlock()
+		if !cached && (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) {
+			missing <- url
+		}
+	}
+	close(missing)
+	wg.Wait()
+	return errs
+}
+
+func (e *Enricher) fetch(ctx context.Context, url string) (Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Snapshot{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := parsePage(string(page))
+	snapshot.Fetched = time.Now()
+	return snapshot, nil
+}
+
+// parsePage finds the title of the page, falling back to its og:title, and
+// an excerpt from its description.
+func parsePage(page string) Snapshot {
+	snapshot := Snapshot{}
+	if match := titleRegex.FindStringSubmatch(page); match != nil {
+		snapshot.Title = clean(match[1])
+	}
+	for _, meta := range metaRegex.FindAllString(page, -1) {
+		name := metaNameRegex.FindStringSubmatch(meta)
+		content := metaContentRegex.FindStringSubmatch(meta)
+		if name == nil || content == nil {
+			continue
+		}
+		value := clean(content[1] + content[2])
+		switch strings.ToLower(name[1]) {
+		case "og:title":
+			if snapshot.Title == "" {
+				snapshot.Title = value
+			}
+		default:
+			if snapshot.Excerpt == "" {
+				snapshot.Excerpt = value
+			}
+		}
+	}
+	if runes := []rune(snapshot.Excerpt); len(runes) > maxExcerptLength {
+		excerpt := string(runes[:maxExcerptLength])
+		if i := strings.LastIndex(excerpt, " "); i > 0 {
+			excerpt = excerpt[:i]
+		}
+		snapshot.Excerpt = excerpt + "..."
+	}
+	return snapshot
+}
+
+func clean(s string) string {
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(html.UnescapeString(s), " "))
+}
+
+// Save writes the cache file when one was given
+func (e *Enricher) Save() error {
+	if e.cachePath == "" {
+		return nil
+	}
+	e.mutex.Lock()
+	content, err := json.MarshalIndent(e.cache, "", "  ")
+	e.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(e.cachePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(e.cachePath, content, 0644)
+}
@@ -0,0 +1,158 @@
+package links
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func Test_parsePage(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		title   string
+		excerpt string
+	}{
+		{
+			name:    "title and description",
+			page:    `<html><head><title> Migrating   to &amp; from </title><meta name="description" content="How to migrate."></head></html>`,
+			title:   "Migrating to & from",
+			excerpt: "How to migrate.",
+		},
+		{
+			name:    "open graph fallback",
+			page:    `<head><meta property="og:title" content='Guide'><meta content="The guide." property="og:description"></head>`,
+			title:   "Guide",
+			excerpt: "The guide.",
+		},
+		{
+			name:  "title is preferred to open graph",
+			page:  `<title>Page</title><meta property="og:title" content="Other">`,
+			title: "Page",
+		},
+		{
+			name:    "long description",
+			page:    fmt.Sprintf(`<meta name="description" content="%s">`, strings.Repeat("word ", 50)),
+			excerpt: strings.TrimSpace(strings.Repeat("word ", 40)) + "...",
+		},
+		{
+			name: "nothing",
+			page: `<p>text</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := parsePage(tt.page)
+			if snapshot.Title != tt.title {
+				t.Errorf("expected title %q, got %q", tt.title, snapshot.Title)
+			}
+			if snapshot.Excerpt != tt.excerpt {
+				t.Errorf("expected excerpt %q, got %q", tt.excerpt, snapshot.Excerpt)
+			}
+		})
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	var fetches int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `<title>Page %s</title><meta name="description" content="About %s.">`, r.URL.Path, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	rulesets := func() []konveyor.RuleSet {
+		return []konveyor.RuleSet{
+			{
+				Name: "ruleset",
+				Violations: map[string]konveyor.Violation{
+					"rule-1": {
+						Links: []konveyor.Link{
+							{URL: ts.URL + "/a"},
+							{URL: ts.URL + "/b", Title: "Rule title"},
+							{URL: ts.URL + "/missing"},
+						},
+					},
+					"rule-2": {
+						Links: []konveyor.Link{
+							{URL: ts.URL + "/a"},
+							{URL: "file:///local"},
+						},
+					},
+				},
+			},
+		}
+	}
+	expected := map[string][]konveyor.Link{
+		"rule-1": {
+			{URL: ts.URL + "/a", Title: "Page /a", Excerpt: "About /a."},
+			{URL: ts.URL + "/b", Title: "Rule title", Excerpt: "About /b."},
+			{URL: ts.URL + "/missing"},
+		},
+		"rule-2": {
+			{URL: ts.URL + "/a", Title: "Page /a", Excerpt: "About /a."},
+			{URL: "file:///local"},
+		},
+	}
+	check := func(t *testing.T, rs []konveyor.RuleSet) {
+		for id, links := range expected {
+			got := rs[0].Violations[id].Links
+			if len(got) != len(links) {
+				t.Fatalf("expected %d links for %s, got %v", len(links), id, got)
+			}
+			for i := range links {
+				if got[i] != links[i] {
+					t.Errorf("expected link %v for %s, got %v", links[i], id, got[i])
+				}
+			}
+		}
+	}
+
+	cache := filepath.Join(t.TempDir(), "cache", "links.json")
+	e, err := NewEnricher(cache, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := rulesets()
+	errs := e.Enrich(context.Background(), rs)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "/missing") {
+		t.Errorf("expected the missing page to fail, got %v", errs)
+	}
+	check(t, rs)
+	if fetches != 3 {
+		t.Errorf("expected each page to be fetched once, got %d fetches", fetches)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache is used without fetching again, even when offline
+	ts.Close()
+	for _, offline := range []bool{false, true} {
+		e, err := NewEnricher(cache, offline, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs := rulesets()
+		errs := e.Enrich(context.Background(), rs)
+		if offline && len(errs) != 0 {
+			t.Errorf("expected no fetch when offline, got %v", errs)
+		}
+		check(t, rs)
+	}
+	if fetches != 3 {
+		t.Errorf("expected the cached pages not to be fetched, got %d fetches", fetches)
+	}
+}
@@ -0,0 +1,166 @@
+package overflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	sizeRegex     = regexp.MustCompile(`^([0-9]+)\s*([KMGT]i?)?B?$`)
+	fileNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// Options configure how the output is kept under its size limit
+type Options struct {
+	// MaxSize is the size in bytes the output is kept under, 0 disables the
+	// limit
+	MaxSize int64
+	// MaxIncidents is the number of incidents each violation keeps in the
+	// output once it is over its size
+	MaxIncidents int
+	// Dir is the directory the overflow files are written to
+	Dir string
+	// BaseDir is the directory the overflow files are referenced from, the
+	// one of the output file
+	BaseDir string
+	// Format is the format of the output, the overflow files are written as
+	// json when it is json and as yaml otherwise
+	Format string
+}
+
+// File is an overflow file, it has all the incidents of a violation
+type File struct {
+	RuleSet   string              `yaml:"ruleset" json:"ruleset"`
+	RuleID    string              `yaml:"ruleID" json:"ruleID"`
+	Incidents []konveyor.Incident `yaml:"incidents" json:"incidents"`
+}
+
+// ParseSize parses a size in bytes, such as 500K, 10Mi or 1GB
+func ParseSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	match := sizeRegex.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	value, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	base := int64(1000)
+	if strings.HasSuffix(match[2], "i") {
+		base = 1024
+	}
+	if match[2] != "" {
+		for i := 0; i <= strings.Index("KMGT", match[2][:1]); i++ {
+			value *= base
+		}
+	}
+	return value, nil
+}
+
+// Limit keeps the document under the size of the options. When it is over
+// it, the violations keep their first MaxIncidents incidents and all their
+// incidents are written to an overflow file that the violation references.
+// The summary and the rest of the violations are kept. It returns the size of
+// the document once it is limited, which is still over the limit when the
+// violations that are left are too large.
+func Limit(doc *encoder.Document, opts Options) (int64, error) {
+	size, err := Size(*doc, opts.Format)
+	if err != nil || opts.MaxSize == 0 || size <= opts.MaxSize {
+		return size, err
+	}
+	ext := ".yaml"
+	if opts.Format == encoder.JSONFormat {
+		ext = ".json"
+	}
+	for _, rs := range doc.RuleSets {
+		ids := make([]string, 0, len(rs.Violations))
+		for id := range rs.Violations {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			v := rs.Violations[id]
+			if len(v.Incidents) <= opts.MaxIncidents {
+				continue
+			}
+			path := filepath.Join(opts.Dir, fileName(rs.Name), fileName(id)+ext)
+			if err := writeFile(path, File{RuleSet: rs.Name, RuleID: id, Incidents: v.Incidents}); err != nil {
+				return size, fmt.Errorf("unable to write the overflow file of %s/%s: %w", rs.Name, id, err)
+			}
+			ref, err := filepath.Rel(opts.BaseDir, path)
+			if err != nil {
+				ref = path
+			}
+			v.Overflow = &konveyor.Overflow{
+				File:      filepath.ToSlash(ref),
+				Incidents: len(v.Incidents),
+			}
+			v.Incidents = v.Incidents[:opts.MaxIncidents]
+			rs.Violations[id] = v
+		}
+	}
+	return Size(*doc, opts.Format)
+}
+
+// Size returns the size of the document written in the format
+func Size(doc encoder.Document, format string) (int64, error) {
+	counter := &countingWriter{}
+	enc, err := encoder.New(format, counter)
+	if err != nil {
+		return 0, err
+	}
+	if err := encoder.EncodeDocument(enc, doc); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+func writeFile(path string, file File) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var content []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		content, err = json.MarshalIndent(file, "", "  ")
+	} else {
+		content, err = yaml.Marshal(file)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// fileName replaces the characters of a ruleset name or rule ID that can not
+// be in a file name, the names of only dots would point out of the directory
+func fileName(name string) string {
+	name = fileNameRegex.ReplaceAllString(name, "_")
+	if strings.Trim(name, ".") == "" {
+		name = "_" + name
+	}
+	return name
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
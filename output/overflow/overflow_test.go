@@ -0,0 +1,170 @@
+package overflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "", want: 0},
+		{size: "512", want: 512},
+		{size: "10K", want: 10000},
+		{size: "10Ki", want: 10240},
+		{size: "2MB", want: 2000000},
+		{size: "1Gi", want: 1 << 30},
+		{size: "ten", wantErr: true},
+		{size: "10X", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.size)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error %v for %q", err, tt.size)
+		}
+		if got != tt.want {
+			t.Errorf("expected %d for %q, got %d", tt.want, tt.size, got)
+		}
+	}
+}
+
+func incidents(n int) []konveyor.Incident {
+	incidents := []konveyor.Incident{}
+	for i := 0; i < n; i++ {
+		incidents = append(incidents, konveyor.Incident{
+			URI:     uri.URI(fmt.Sprintf("file:///src/File%d.java", i)),
+			Message: strings.Repeat("the old api is used ", 5),
+		})
+	}
+	return incidents
+}
+
+func document() encoder.Document {
+	return encoder.Document{
+		RuleSets: []konveyor.RuleSet{
+			{
+				Name: "ruleset/a",
+				Violations: map[string]konveyor.Violation{
+					"many-001": {Description: "many", Incidents: incidents(50)},
+					"few-001":  {Description: "few", Incidents: incidents(2)},
+				},
+			},
+			{
+				Name: "..",
+				Violations: map[string]konveyor.Violation{
+					"many-002": {Description: "many", Incidents: incidents(20)},
+				},
+			},
+		},
+	}
+}
+
+func TestLimit(t *testing.T) {
+	full, err := Size(document(), encoder.YAMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullJSON, err := Size(document(), encoder.JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		format       string
+		maxSize      int64
+		wantOverflow map[string]string
+	}{
+		{
+			name:    "under the limit",
+			format:  encoder.YAMLFormat,
+			maxSize: full,
+		},
+		{
+			name:    "no limit",
+			format:  encoder.YAMLFormat,
+			maxSize: 0,
+		},
+		{
+			name:    "over the limit",
+			format:  encoder.YAMLFormat,
+			maxSize: full / 2,
+			wantOverflow: map[string]string{
+				"many-001": "overflow/ruleset_a/many-001.yaml",
+				"many-002": "overflow/_../many-002.yaml",
+			},
+		},
+		{
+			name:    "json",
+			format:  encoder.JSONFormat,
+			maxSize: fullJSON / 2,
+			wantOverflow: map[string]string{
+				"many-001": "overflow/ruleset_a/many-001.json",
+				"many-002": "overflow/_../many-002.json",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			doc := document()
+			size, err := Limit(&doc, Options{
+				MaxSize:      tt.maxSize,
+				MaxIncidents: 5,
+				Dir:          filepath.Join(dir, "overflow"),
+				BaseDir:      dir,
+				Format:       tt.format,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(tt.wantOverflow) > 0 && size > tt.maxSize {
+				t.Errorf("expected the output to be under %d bytes, got %d", tt.maxSize, size)
+			}
+			for _, rs := range doc.RuleSets {
+				for id, v := range rs.Violations {
+					want, ok := tt.wantOverflow[id]
+					if !ok {
+						if v.Overflow != nil {
+							t.Errorf("expected %s to keep its incidents, got %v", id, v.Overflow)
+						}
+						continue
+					}
+					if v.Overflow == nil || v.Overflow.File != want {
+						t.Fatalf("expected %s to overflow to %s, got %v", id, want, v.Overflow)
+					}
+					if len(v.Incidents) != 5 {
+						t.Errorf("expected %s to keep 5 incidents, got %d", id, len(v.Incidents))
+					}
+					content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(want)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					file := File{}
+					if tt.format == encoder.JSONFormat {
+						err = json.Unmarshal(content, &file)
+					} else {
+						err = yaml.Unmarshal(content, &file)
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					if file.RuleSet != rs.Name || file.RuleID != id || len(file.Incidents) != v.Overflow.Incidents {
+						t.Errorf("expected the overflow file to have the %d incidents of %s, got %s %s %d", v.Overflow.Incidents, id, file.RuleSet, file.RuleID, len(file.Incidents))
+					}
+				}
+			}
+		})
+	}
+}
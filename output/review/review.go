@@ -0,0 +1,177 @@
+// Package review carries the decisions of the reviewers on the incidents of
+// the potential violations from one analysis to the next, so that they are
+// not triaged again every time the analysis runs.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/tracking"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// Review is the review state of an incident of a potential violation. The
+// incident is found by its fingerprint, its file, line and message are only
+// there to help the reviewer find it.
+type Review struct {
+	RuleSet     string               `yaml:"ruleset" json:"ruleset"`
+	Rule        string               `yaml:"rule" json:"rule"`
+	Fingerprint string               `yaml:"fingerprint" json:"fingerprint"`
+	URI         string               `yaml:"uri,omitempty" json:"uri,omitempty"`
+	LineNumber  *int                 `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
+	Message     string               `yaml:"message,omitempty" json:"message,omitempty"`
+	State       konveyor.ReviewState `yaml:"state" json:"state"`
+	Reviewer    string               `yaml:"reviewer,omitempty" json:"reviewer,omitempty"`
+	Comment     string               `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// File is the companion review file of the output of an analysis
+type File struct {
+	Reviews []Review `yaml:"reviews" json:"reviews"`
+}
+
+// Counts are the incidents of the potential violations by review state
+type Counts struct {
+	Unreviewed int `yaml:"unreviewed" json:"unreviewed"`
+	Accepted   int `yaml:"accepted" json:"accepted"`
+	Rejected   int `yaml:"rejected" json:"rejected"`
+}
+
+// Load reads the review file, a file that does not exist yet has no review.
+func Load(path string) (*File, error) {
+	f := &File{}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, f); err != nil {
+		return nil, fmt.Errorf("unable to read the review file %s: %w", path, err)
+	}
+	for _, r := range f.Reviews {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("invalid review in %s: %w", path, err)
+		}
+	}
+	return f, nil
+}
+
+func (r Review) validate() error {
+	if r.RuleSet == "" || r.Rule == "" || r.Fingerprint == "" {
+		return fmt.Errorf("the ruleset, rule and fingerprint of a review are required")
+	}
+	switch r.State {
+	case konveyor.ReviewUnreviewed, konveyor.ReviewAccepted, konveyor.ReviewRejected:
+		return nil
+	}
+	return fmt.Errorf("unknown review state %q of the incident %s of %s/%s, it must be one of %s, %s or %s",
+		r.State, r.Fingerprint, r.RuleSet, r.Rule, konveyor.ReviewUnreviewed, konveyor.ReviewAccepted, konveyor.ReviewRejected)
+}
+
+type reviewKey struct {
+	ruleSet     string
+	rule        string
+	fingerprint string
+}
+
+// Apply sets the review state of the incidents of the potential violations
+// from the file, and updates the file for the next analysis: the incidents
+// found for the first time are added as unreviewed, and the reviews of the
+// incidents that were not found again are dropped. The decisions on the
+// incidents of the rules that did not match at all are kept, in case the
+// rules were only left out of this analysis.
+func (f *File) Apply(rulesets []konveyor.RuleSet) Counts {
+	previous := map[reviewKey]Review{}
+	for _, r := range f.Reviews {
+		previous[reviewKey{r.RuleSet, r.Rule, r.Fingerprint}] = r
+	}
+	matched := map[[2]string]bool{}
+	counts := Counts{}
+	reviews := []Review{}
+	for _, rs := range rulesets {
+		for id, v := range rs.Violations {
+			if v.Category == nil || *v.Category != konveyor.Potential {
+				continue
+			}
+			matched[[2]string{rs.Name, id}] = true
+			// the outputs written before fingerprints were added
+			if len(v.Incidents) > 0 && v.Incidents[0].Fingerprint == "" {
+				tracking.SetFingerprints(rs.Name, id, v.Incidents)
+			}
+			for i := range v.Incidents {
+				incident := &v.Incidents[i]
+				r, ok := previous[reviewKey{rs.Name, id, incident.Fingerprint}]
+				if !ok {
+					r = Review{
+						RuleSet:     rs.Name,
+						Rule:        id,
+						Fingerprint: incident.Fingerprint,
+						State:       konveyor.ReviewUnreviewed,
+					}
+				}
+				// the incident may have moved in its file
+				r.URI = string(incident.URI)
+				r.LineNumber = incident.LineNumber
+				r.Message = incident.Message
+				incident.Review = r.State
+				reviews = append(reviews, r)
+				switch r.State {
+				case konveyor.ReviewAccepted:
+					counts.Accepted++
+				case konveyor.ReviewRejected:
+					counts.Rejected++
+				default:
+					counts.Unreviewed++
+				}
+			}
+		}
+	}
+	for _, r := range f.Reviews {
+		if r.State != konveyor.ReviewUnreviewed && !matched[[2]string{r.RuleSet, r.Rule}] {
+			reviews = append(reviews, r)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		a, b := reviews[i], reviews[j]
+		if a.RuleSet != b.RuleSet {
+			return a.RuleSet < b.RuleSet
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		return line(a) < line(b)
+	})
+	f.Reviews = reviews
+	return counts
+}
+
+func line(r Review) int {
+	if r.LineNumber == nil {
+		return -1
+	}
+	return *r.LineNumber
+}
+
+// Save writes the review file
+func (f *File) Save(path string) error {
+	content, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,100 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestApply(t *testing.T) {
+	potential := konveyor.Potential
+	mandatory := konveyor.Mandatory
+	line := 3
+	rulesets := []konveyor.RuleSet{{
+		Name: "ruleset",
+		Violations: map[string]konveyor.Violation{
+			"potential": {
+				Category: &potential,
+				Incidents: []konveyor.Incident{
+					{URI: "file:///a.java", Message: "accepted", LineNumber: &line, Fingerprint: "a"},
+					{URI: "file:///b.java", Message: "rejected", Fingerprint: "b"},
+					{URI: "file:///c.java", Message: "new", Fingerprint: "c"},
+				},
+			},
+			"mandatory": {
+				Category:  &mandatory,
+				Incidents: []konveyor.Incident{{URI: "file:///a.java", Fingerprint: "m"}},
+			},
+		},
+	}}
+	f := &File{Reviews: []Review{
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "a", State: konveyor.ReviewAccepted, Comment: "to fix"},
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "b", State: konveyor.ReviewRejected},
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "gone", State: konveyor.ReviewRejected},
+		{RuleSet: "ruleset", Rule: "skipped", Fingerprint: "s", State: konveyor.ReviewAccepted},
+		{RuleSet: "ruleset", Rule: "skipped", Fingerprint: "u", State: konveyor.ReviewUnreviewed},
+	}}
+
+	counts := f.Apply(rulesets)
+	if want := (Counts{Unreviewed: 1, Accepted: 1, Rejected: 1}); counts != want {
+		t.Errorf("expected the counts %+v, got %+v", want, counts)
+	}
+	states := map[string]konveyor.ReviewState{}
+	for _, v := range rulesets[0].Violations {
+		for _, i := range v.Incidents {
+			states[i.Fingerprint] = i.Review
+		}
+	}
+	wantStates := map[string]konveyor.ReviewState{
+		"a": konveyor.ReviewAccepted,
+		"b": konveyor.ReviewRejected,
+		"c": konveyor.ReviewUnreviewed,
+		"m": "",
+	}
+	if !reflect.DeepEqual(states, wantStates) {
+		t.Errorf("expected the review states %v, got %v", wantStates, states)
+	}
+
+	// the incidents not found again are dropped, the decisions on the rules
+	// that did not match are kept
+	wantReviews := []Review{
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "a", URI: "file:///a.java", LineNumber: &line, Message: "accepted", State: konveyor.ReviewAccepted, Comment: "to fix"},
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "b", URI: "file:///b.java", Message: "rejected", State: konveyor.ReviewRejected},
+		{RuleSet: "ruleset", Rule: "potential", Fingerprint: "c", URI: "file:///c.java", Message: "new", State: konveyor.ReviewUnreviewed},
+		{RuleSet: "ruleset", Rule: "skipped", Fingerprint: "s", State: konveyor.ReviewAccepted},
+	}
+	if !reflect.DeepEqual(f.Reviews, wantReviews) {
+		t.Errorf("expected the reviews %+v, got %+v", wantReviews, f.Reviews)
+	}
+
+	path := filepath.Join(t.TempDir(), "reviews.yaml")
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Reviews, wantReviews) {
+		t.Errorf("expected the saved reviews %+v, got %+v", wantReviews, loaded.Reviews)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Load(filepath.Join(dir, "missing.yaml"))
+	if err != nil || len(f.Reviews) != 0 {
+		t.Errorf("expected a missing file to have no review, got %v %v", f, err)
+	}
+	path := filepath.Join(dir, "reviews.yaml")
+	content := "reviews:\n- ruleset: ruleset\n  rule: rule\n  fingerprint: a\n  state: maybe\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected an unknown review state to fail")
+	}
+}
@@ -0,0 +1,244 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// NDJSONFormat writes one json result per line
+	NDJSONFormat = "ndjson"
+	// YAMLFormat writes one yaml document per result
+	YAMLFormat = "yaml"
+)
+
+// yamlSeparator starts each yaml document
+var yamlSeparator = []byte("---\n")
+
+// ValidateFormat checks that the stream format is known
+func ValidateFormat(format string) error {
+	switch format {
+	case NDJSONFormat, YAMLFormat:
+		return nil
+	default:
+		return fmt.Errorf("unknown stream format: %s, one of: %s, %s", format, NDJSONFormat, YAMLFormat)
+	}
+}
+
+// Writer appends the results of the rules to a file as they are evaluated,
+// see engine.ResultWriter. The file can be read back with Read even when the
+// analysis did not complete.
+type Writer struct {
+	mutex  sync.Mutex
+	f      *os.File
+	format string
+}
+
+// NewWriter creates the stream file, an existing file is truncated
+func NewWriter(path, format string) (*Writer, error) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{f: f, format: format}, nil
+}
+
+// WriteResult writes the result with a single write, so that a crash does
+// not leave more than the last result incomplete
+func (w *Writer) WriteResult(ruleSet konveyor.RuleSet) error {
+	content, err := encode(ruleSet, w.format)
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err = w.f.Write(content)
+	return err
+}
+
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.f.Close()
+}
+
+func encode(ruleSet konveyor.RuleSet, format string) ([]byte, error) {
+	switch format {
+	case NDJSONFormat:
+		content, err := json.Marshal(ruleSet)
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	case YAMLFormat:
+		content, err := yaml.Marshal(ruleSet)
+		if err != nil {
+			return nil, err
+		}
+		return append(append([]byte{}, yamlSeparator...), content...), nil
+	default:
+		return nil, ValidateFormat(format)
+	}
+}
+
+// Read merges the results of a stream into the rulesets sorted by name. The
+// last result is ignored when it does not end with a new line or can not be
+// decoded, it was being written when the analysis stopped.
+func Read(r io.Reader, format string) ([]konveyor.RuleSet, error) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	results, err := split(r, format)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]*konveyor.RuleSet{}
+	for i, content := range results {
+		last := i == len(results)-1
+		if last && !bytes.HasSuffix(content, []byte{'\n'}) {
+			continue
+		}
+		result := konveyor.RuleSet{}
+		switch format {
+		case NDJSONFormat:
+			err = json.Unmarshal(content, &result)
+		case YAMLFormat:
+			err = yaml.Unmarshal(content, &result)
+		}
+		if err != nil {
+			if last {
+				continue
+			}
+			return nil, fmt.Errorf("unable to read result %d of the stream: %w", i+1, err)
+		}
+		rs, ok := merged[result.Name]
+		if !ok {
+			rs = &konveyor.RuleSet{
+				Name:       result.Name,
+				Tags:       []string{},
+				Violations: map[string]konveyor.Violation{},
+				Errors:     map[string]string{},
+				Unmatched:  []string{},
+				Skipped:    []string{},
+			}
+			merged[result.Name] = rs
+		}
+		merge(rs, result)
+	}
+	ruleSets := []konveyor.RuleSet{}
+	for _, rs := range merged {
+		ruleSets = append(ruleSets, *rs)
+	}
+	sort.Slice(ruleSets, func(i, j int) bool {
+		return ruleSets[i].Name < ruleSets[j].Name
+	})
+	return ruleSets, nil
+}
+
+// split returns the encoded results of the stream
+func split(r io.Reader, format string) ([][]byte, error) {
+	if format == YAMLFormat {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		// the separator is at the start of a line, the documents can have
+		// it in the middle of a line
+		content = append([]byte{'\n'}, content...)
+		results := [][]byte{}
+		for _, doc := range bytes.Split(content, append([]byte{'\n'}, yamlSeparator...)) {
+			if len(bytes.TrimSpace(doc)) != 0 {
+				results = append(results, doc)
+			}
+		}
+		return results, nil
+	}
+	results := [][]byte{}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) != 0 {
+			results = append(results, line)
+		}
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// merge adds the result to the ruleset, a rule that is evaluated again
+// replaces its previous result
+func merge(rs *konveyor.RuleSet, result konveyor.RuleSet) {
+	if result.Description != "" {
+		rs.Description = result.Description
+	}
+	rs.Tags = appendMissing(rs.Tags, result.Tags...)
+	for _, tag := range result.StructuredTags {
+		found := false
+		for _, existing := range rs.StructuredTags {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rs.StructuredTags = append(rs.StructuredTags, tag)
+		}
+	}
+	rs.Skipped = appendMissing(rs.Skipped, result.Skipped...)
+	for id, v := range result.Violations {
+		forget(rs, id)
+		rs.Violations[id] = v
+	}
+	for id, e := range result.Errors {
+		forget(rs, id)
+		rs.Errors[id] = e
+	}
+	for _, id := range result.Unmatched {
+		forget(rs, id)
+		rs.Unmatched = append(rs.Unmatched, id)
+	}
+}
+
+// forget removes the previous result of a rule
+func forget(rs *konveyor.RuleSet, ruleID string) {
+	delete(rs.Violations, ruleID)
+	delete(rs.Errors, ruleID)
+	for i, id := range rs.Unmatched {
+		if id == ruleID {
+			rs.Unmatched = append(rs.Unmatched[:i], rs.Unmatched[i+1:]...)
+			break
+		}
+	}
+}
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestWriteRead(t *testing.T) {
+	message := "uses ---\nthe old api"
+	results := []konveyor.RuleSet{
+		{Name: "b", Description: "second", Skipped: []string{"b-skipped"}, Tags: []string{"java"},
+			StructuredTags: []konveyor.Tag{{Value: "java"}}},
+		{Name: "a", Tags: []string{"java"}},
+		{Name: "b", Violations: map[string]konveyor.Violation{"b-1": {
+			Description: "old api",
+			Incidents:   []konveyor.Incident{{URI: "file:///a.java", Message: message}},
+		}}},
+		{Name: "a", Unmatched: []string{"a-1"}},
+		{Name: "a", Errors: map[string]string{"a-2": "timeout"}},
+		{Name: "b", Tags: []string{"java", "spring"},
+			StructuredTags: []konveyor.Tag{{Value: "java"}, {Category: "Framework", Value: "Spring Boot 2.7"}}},
+	}
+	want := []konveyor.RuleSet{
+		{
+			Name:       "a",
+			Tags:       []string{"java"},
+			Violations: map[string]konveyor.Violation{},
+			Errors:     map[string]string{"a-2": "timeout"},
+			Unmatched:  []string{"a-1"},
+			Skipped:    []string{},
+		},
+		{
+			Name:           "b",
+			Description:    "second",
+			Tags:           []string{"java", "spring"},
+			StructuredTags: []konveyor.Tag{{Value: "java"}, {Category: "Framework", Value: "Spring Boot 2.7"}},
+			Violations: map[string]konveyor.Violation{"b-1": {
+				Description: "old api",
+				Incidents:   []konveyor.Incident{{URI: "file:///a.java", Message: message}},
+			}},
+			Errors:    map[string]string{},
+			Unmatched: []string{},
+			Skipped:   []string{"b-skipped"},
+		},
+	}
+	for _, format := range []string{NDJSONFormat, YAMLFormat} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stream")
+			w, err := NewWriter(path, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, result := range results {
+				if err := w.WriteResult(result); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(bytes.NewReader(content), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %+v, got %+v", want, got)
+			}
+
+			// the last result is incomplete when the analysis stopped while
+			// it was written
+			got, err = Read(bytes.NewReader(content[:len(content)-5]), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got[1].Tags) != 1 {
+				t.Errorf("expected the incomplete result to be ignored, got tags %v", got[1].Tags)
+			}
+		})
+	}
+}
+
+func TestReadReplacesResult(t *testing.T) {
+	content := `{"name":"a","errors":{"a-1":"provider restarted"}}
+{"name":"a","unmatched":["a-1"]}
+{"name":"a","violations":{"a-1":{"description":"found"}}}
+`
+	got, err := Read(bytes.NewBufferString(content), NDJSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Errors) != 0 || len(got[0].Unmatched) != 0 || len(got[0].Violations) != 1 {
+		t.Errorf("expected only the last result of the rule, got %+v", got)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	content := "{\"name\":\nnot json\n{\"name\":\"a\"}\n"
+	if _, err := Read(bytes.NewBufferString(content), NDJSONFormat); err == nil {
+		t.Errorf("expected an error for an invalid result that is not the last one")
+	}
+	if _, err := Read(bytes.NewBufferString(""), "xml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
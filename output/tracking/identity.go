@@ -0,0 +1,81 @@
+package tracking
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+var (
+	// codeSnipLineRegex splits a line of a code snip in its number and code
+	codeSnipLineRegex = regexp.MustCompile(`^\s*([0-9]+)  (.*)$`)
+	spaceRegex        = regexp.MustCompile(`\s+`)
+)
+
+// SetFingerprints sets the fingerprint of the incidents of a rule. The
+// fingerprint is the identity of an incident across analyses, it is made of
+// the ruleset, the rule, the file and the code of the incident, but not of
+// its line number, so that an incident keeps its fingerprint when lines are
+// added or removed above it. Incidents with the same code in a file are told
+// apart by their order.
+func SetFingerprints(ruleSet string, ruleID string, incidents []konveyor.Incident) {
+	order := make([]int, len(incidents))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return line(incidents[order[i]]) < line(incidents[order[j]])
+	})
+	occurrences := map[string]int{}
+	for _, i := range order {
+		key := strings.Join([]string{ruleSet, ruleID, string(incidents[i].URI), code(incidents[i])}, "\x00")
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+		occurrences[key]++
+		incidents[i].Fingerprint = hex.EncodeToString(hash[:16])
+	}
+}
+
+// SetAllFingerprints sets the fingerprints of all the incidents of the rulesets
+func SetAllFingerprints(rulesets []konveyor.RuleSet) {
+	for _, rs := range rulesets {
+		for id, v := range rs.Violations {
+			SetFingerprints(rs.Name, id, v.Incidents)
+		}
+	}
+}
+
+// code is the line of the incident in its code snip with the spaces
+// normalized, or its message when the code snip does not have it.
+func code(incident konveyor.Incident) string {
+	if incident.LineNumber != nil {
+		scanner := bufio.NewScanner(strings.NewReader(incident.CodeSnip))
+		for scanner.Scan() {
+			match := codeSnipLineRegex.FindStringSubmatch(scanner.Text())
+			if match == nil {
+				continue
+			}
+			if n, err := strconv.Atoi(match[1]); err == nil && n == *incident.LineNumber {
+				return normalize(match[2])
+			}
+		}
+	}
+	return normalize(incident.Message)
+}
+
+func normalize(s string) string {
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(s, " "))
+}
+
+func line(incident konveyor.Incident) int {
+	if incident.LineNumber == nil {
+		return -1
+	}
+	return *incident.LineNumber
+}
@@ -0,0 +1,228 @@
+package tracking
+
+import (
+	"path"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// maxRelocationDistance is how many lines an incident whose code changed can
+// move in its file and still be the same incident.
+const maxRelocationDistance = 10
+
+type Status string
+
+const (
+	StatusNew        Status = "new"
+	StatusPersisting Status = "persisting"
+	StatusResolved   Status = "resolved"
+)
+
+// TrackedIncident is an incident of either output with its status
+type TrackedIncident struct {
+	RuleSet string `yaml:"ruleset" json:"ruleset"`
+	Rule    string `yaml:"rule" json:"rule"`
+	Status  Status `yaml:"status" json:"status"`
+	// Identity is the fingerprint of the incident in the previous output, it
+	// differs from the fingerprint of a persisting incident that was relocated.
+	Identity string `yaml:"identity" json:"identity"`
+	// Incident is the current incident, or the previous one when it was resolved
+	Incident konveyor.Incident `yaml:"incident" json:"incident"`
+	// PreviousURI and PreviousLineNumber are where a relocated incident was
+	PreviousURI        string `yaml:"previousURI,omitempty" json:"previousURI,omitempty"`
+	PreviousLineNumber *int   `yaml:"previousLineNumber,omitempty" json:"previousLineNumber,omitempty"`
+}
+
+// Report is the remediation status of the incidents between two outputs
+type Report struct {
+	New        int               `yaml:"new" json:"new"`
+	Persisting int               `yaml:"persisting" json:"persisting"`
+	Resolved   int               `yaml:"resolved" json:"resolved"`
+	Incidents  []TrackedIncident `yaml:"incidents" json:"incidents"`
+}
+
+type ruleKey struct {
+	ruleSet string
+	rule    string
+}
+
+// Track labels the incidents of the current output as new or persisting, and
+// the incidents of the previous output that are not found anymore as
+// resolved. Incidents are first matched by fingerprint, the remaining ones
+// are matched when they were relocated: the same code moved in its file or to
+// a file with the same name, or changed code close to where it was.
+func Track(previous, current []konveyor.RuleSet) Report {
+	previousIncidents := incidentsByRule(previous)
+	currentIncidents := incidentsByRule(current)
+	keys := []ruleKey{}
+	for key := range currentIncidents {
+		keys = append(keys, key)
+	}
+	for key := range previousIncidents {
+		if _, ok := currentIncidents[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	report := Report{Incidents: []TrackedIncident{}}
+	for _, key := range keys {
+		report.Incidents = append(report.Incidents, trackRule(key, previousIncidents[key], currentIncidents[key])...)
+	}
+	for _, t := range report.Incidents {
+		switch t.Status {
+		case StatusNew:
+			report.New++
+		case StatusPersisting:
+			report.Persisting++
+		case StatusResolved:
+			report.Resolved++
+		}
+	}
+	sort.SliceStable(report.Incidents, func(i, j int) bool {
+		a, b := report.Incidents[i], report.Incidents[j]
+		if a.RuleSet != b.RuleSet {
+			return a.RuleSet < b.RuleSet
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Incident.URI != b.Incident.URI {
+			return a.Incident.URI < b.Incident.URI
+		}
+		return line(a.Incident) < line(b.Incident)
+	})
+	return report
+}
+
+func trackRule(key ruleKey, previous, current []konveyor.Incident) []TrackedIncident {
+	tracked := []TrackedIncident{}
+	persisting := func(p, c konveyor.Incident) {
+		t := TrackedIncident{
+			RuleSet:  key.ruleSet,
+			Rule:     key.rule,
+			Status:   StatusPersisting,
+			Identity: p.Fingerprint,
+			Incident: c,
+		}
+		if p.URI != c.URI || line(p) != line(c) {
+			t.PreviousURI = string(p.URI)
+			t.PreviousLineNumber = p.LineNumber
+		}
+		tracked = append(tracked, t)
+	}
+
+	matchedPrevious := make([]bool, len(previous))
+	matchedCurrent := make([]bool, len(current))
+	byFingerprint := map[string]int{}
+	for i, p := range previous {
+		byFingerprint[p.Fingerprint] = i
+	}
+	for j, c := range current {
+		if i, ok := byFingerprint[c.Fingerprint]; ok && !matchedPrevious[i] {
+			matchedPrevious[i] = true
+			matchedCurrent[j] = true
+			persisting(previous[i], c)
+		}
+	}
+
+	// the closest relocations are matched first
+	type candidate struct {
+		previous, current int
+		tier, distance    int
+	}
+	candidates := []candidate{}
+	for i, p := range previous {
+		if matchedPrevious[i] {
+			continue
+		}
+		for j, c := range current {
+			if matchedCurrent[j] {
+				continue
+			}
+			if tier, ok := relocationTier(p, c); ok {
+				candidates = append(candidates, candidate{i, j, tier, abs(line(p) - line(c))})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].tier != candidates[b].tier {
+			return candidates[a].tier < candidates[b].tier
+		}
+		return candidates[a].distance < candidates[b].distance
+	})
+	for _, c := range candidates {
+		if matchedPrevious[c.previous] || matchedCurrent[c.current] {
+			continue
+		}
+		matchedPrevious[c.previous] = true
+		matchedCurrent[c.current] = true
+		persisting(previous[c.previous], current[c.current])
+	}
+
+	for j, c := range current {
+		if !matchedCurrent[j] {
+			tracked = append(tracked, TrackedIncident{
+				RuleSet:  key.ruleSet,
+				Rule:     key.rule,
+				Status:   StatusNew,
+				Identity: c.Fingerprint,
+				Incident: c,
+			})
+		}
+	}
+	for i, p := range previous {
+		if !matchedPrevious[i] {
+			tracked = append(tracked, TrackedIncident{
+				RuleSet:  key.ruleSet,
+				Rule:     key.rule,
+				Status:   StatusResolved,
+				Identity: p.Fingerprint,
+				Incident: p,
+			})
+		}
+	}
+	return tracked
+}
+
+// relocationTier returns how close the incidents are when the current one can
+// be the previous one relocated, the lower the closer.
+func relocationTier(p, c konveyor.Incident) (int, bool) {
+	sameCode := code(p) == code(c)
+	switch {
+	case sameCode && p.URI == c.URI:
+		return 0, true
+	case sameCode && path.Base(string(p.URI)) == path.Base(string(c.URI)):
+		return 1, true
+	case p.URI == c.URI && abs(line(p)-line(c)) <= maxRelocationDistance:
+		return 2, true
+	}
+	return 0, false
+}
+
+// incidentsByRule returns the incidents of each rule, with their fingerprint
+// set when the output was written without them.
+func incidentsByRule(rulesets []konveyor.RuleSet) map[ruleKey][]konveyor.Incident {
+	incidents := map[ruleKey][]konveyor.Incident{}
+	for _, rs := range rulesets {
+		for id, v := range rs.Violations {
+			ruleIncidents := append([]konveyor.Incident{}, v.Incidents...)
+			for _, i := range ruleIncidents {
+				if i.Fingerprint == "" {
+					SetFingerprints(rs.Name, id, ruleIncidents)
+					break
+				}
+			}
+			key := ruleKey{ruleSet: rs.Name, rule: id}
+			incidents[key] = append(incidents[key], ruleIncidents...)
+		}
+	}
+	return incidents
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,165 @@
+package tracking
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+func incident(file string, line int, code string) konveyor.Incident {
+	return konveyor.Incident{
+		URI:        uri.URI("file:///src/" + file),
+		Message:    "use the new API",
+		LineNumber: &line,
+		CodeSnip:   fmt.Sprintf("%2d  before\n%2d  %s\n%2d  after", line-1, line, code, line+1),
+	}
+}
+
+func rulesets(incidents ...konveyor.Incident) []konveyor.RuleSet {
+	return []konveyor.RuleSet{
+		{
+			Name: "ruleset",
+			Violations: map[string]konveyor.Violation{
+				"rule": {Incidents: incidents},
+			},
+		},
+	}
+}
+
+func TestSetFingerprints(t *testing.T) {
+	fingerprint := func(incidents ...konveyor.Incident) []string {
+		SetFingerprints("ruleset", "rule", incidents)
+		fingerprints := []string{}
+		for _, i := range incidents {
+			fingerprints = append(fingerprints, i.Fingerprint)
+		}
+		return fingerprints
+	}
+	base := fingerprint(incident("A.java", 10, "import a.B;"), incident("A.java", 20, "import a.B;"))
+	if base[0] == base[1] {
+		t.Errorf("expected the same code at two lines to have different fingerprints")
+	}
+	if moved := fingerprint(incident("A.java", 30, "import a.B;"), incident("A.java", 12, "import  a.B; ")); !reflect.DeepEqual(moved, []string{base[1], base[0]}) {
+		t.Errorf("expected the fingerprints not to depend on the line numbers and spaces, got %v and %v", moved, base)
+	}
+	if other := fingerprint(incident("B.java", 10, "import a.B;")); other[0] == base[0] {
+		t.Errorf("expected the file to be part of the fingerprint")
+	}
+	noSnip := konveyor.Incident{URI: "file:///pom.xml", Message: "upgrade"}
+	if got := fingerprint(noSnip); got[0] == "" || got[0] == fingerprint(konveyor.Incident{URI: "file:///pom.xml", Message: "remove"})[0] {
+		t.Errorf("expected the message to identify incidents without code, got %v", got)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	type tracked struct {
+		status   Status
+		file     string
+		line     int
+		previous string
+	}
+	tests := []struct {
+		name     string
+		previous []konveyor.Incident
+		current  []konveyor.Incident
+		want     []tracked
+	}{
+		{
+			name:     "unchanged",
+			previous: []konveyor.Incident{incident("A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("A.java", 10, "a();")},
+			want:     []tracked{{status: StatusPersisting, file: "A.java", line: 10}},
+		},
+		{
+			name:     "lines added above",
+			previous: []konveyor.Incident{incident("A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("A.java", 25, "a();")},
+			want:     []tracked{{status: StatusPersisting, file: "A.java", line: 25, previous: "file:///src/A.java:10"}},
+		},
+		{
+			name:     "new and resolved",
+			previous: []konveyor.Incident{incident("A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("B.java", 10, "b();")},
+			want: []tracked{
+				{status: StatusResolved, file: "A.java", line: 10},
+				{status: StatusNew, file: "B.java", line: 10},
+			},
+		},
+		{
+			name:     "file moved",
+			previous: []konveyor.Incident{incident("old/A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("new/A.java", 12, "a();")},
+			want:     []tracked{{status: StatusPersisting, file: "new/A.java", line: 12, previous: "file:///src/old/A.java:10"}},
+		},
+		{
+			name:     "code changed close by",
+			previous: []konveyor.Incident{incident("A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("A.java", 13, "a(b);")},
+			want:     []tracked{{status: StatusPersisting, file: "A.java", line: 13, previous: "file:///src/A.java:10"}},
+		},
+		{
+			name:     "code changed far away",
+			previous: []konveyor.Incident{incident("A.java", 10, "a();")},
+			current:  []konveyor.Incident{incident("A.java", 50, "a(b);")},
+			want: []tracked{
+				{status: StatusResolved, file: "A.java", line: 10},
+				{status: StatusNew, file: "A.java", line: 50},
+			},
+		},
+		{
+			// identical incidents are told apart by their order, not their lines
+			name: "one of two identical incidents resolved",
+			previous: []konveyor.Incident{
+				incident("A.java", 10, "a();"),
+				incident("A.java", 20, "a();"),
+			},
+			current: []konveyor.Incident{incident("A.java", 18, "a();")},
+			want: []tracked{
+				{status: StatusPersisting, file: "A.java", line: 18, previous: "file:///src/A.java:10"},
+				{status: StatusResolved, file: "A.java", line: 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := rulesets(tt.previous...)
+			SetAllFingerprints(previous)
+			report := Track(previous, rulesets(tt.current...))
+			got := []tracked{}
+			for _, i := range report.Incidents {
+				tr := tracked{
+					status: i.Status,
+					file:   string(i.Incident.URI)[len("file:///src/"):],
+					line:   *i.Incident.LineNumber,
+				}
+				if i.PreviousURI != "" {
+					tr.previous = fmt.Sprintf("%s:%d", i.PreviousURI, *i.PreviousLineNumber)
+				}
+				if i.Status != StatusNew && i.Identity == "" {
+					t.Errorf("expected an identity for %v", tr)
+				}
+				got = append(got, tr)
+			}
+			sort.SliceStable(got, func(i, j int) bool {
+				if got[i].file != got[j].file {
+					return got[i].file < got[j].file
+				}
+				return got[i].line < got[j].line
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			counts := map[Status]int{}
+			for _, w := range tt.want {
+				counts[w.status]++
+			}
+			if report.New != counts[StatusNew] || report.Persisting != counts[StatusPersisting] || report.Resolved != counts[StatusResolved] {
+				t.Errorf("unexpected counts %d/%d/%d for %v", report.New, report.Persisting, report.Resolved, counts)
+			}
+		})
+	}
+}
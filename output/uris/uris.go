@@ -0,0 +1,221 @@
+// Package uris rewrites the URIs of the incidents of an output, so that the
+// paths of the locations analyzed in a container are meaningful to the people
+// reading the results on their workstation.
+package uris
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	archiveScheme    = "jar:"
+	archiveSeparator = "!/"
+	// jdtScheme is the scheme of the classes of the dependencies the java
+	// provider found references in, without their sources
+	jdtScheme = "konveyor-jdt://contents"
+)
+
+// Rewriter returns the URI an incident found at u is reported at
+type Rewriter interface {
+	Rewrite(u uri.URI) uri.URI
+}
+
+// RewriterFunc is a function used as a Rewriter
+type RewriterFunc func(u uri.URI) uri.URI
+
+func (f RewriterFunc) Rewrite(u uri.URI) uri.URI {
+	return f(u)
+}
+
+// Chain rewrites the URIs with each of the rewriters, in the order they are
+// given
+func Chain(rewriters ...Rewriter) Rewriter {
+	return RewriterFunc(func(u uri.URI) uri.URI {
+		for _, r := range rewriters {
+			u = r.Rewrite(u)
+		}
+		return u
+	})
+}
+
+// Prefix replaces the directory From of the file URIs with To, such as the
+// directory the sources are mounted in a container with the directory of the
+// sources on the workstation. Both are paths or URIs, the URIs of the files
+// in archives under From are rewritten as well.
+type Prefix struct {
+	From string
+	To   string
+}
+
+func (p Prefix) Rewrite(u uri.URI) uri.URI {
+	return rewriteFile(u, func(fileURI string) string {
+		from, to := toURI(p.From), toURI(p.To)
+		if rest, ok := cutDir(fileURI, from); ok {
+			return to + rest
+		}
+		return fileURI
+	})
+}
+
+// Relative makes the file URIs under the directory Root paths relative to
+// it, such as the root of the repository that was analyzed, the other URIs
+// are left as they are. The files in archives keep their archive URI, with
+// the path of the archive relative to Root.
+type Relative struct {
+	Root string
+}
+
+func (r Relative) Rewrite(u uri.URI) uri.URI {
+	return rewriteFile(u, func(fileURI string) string {
+		if rest, ok := cutDir(fileURI, toURI(r.Root)); ok && rest != "" {
+			return strings.TrimPrefix(rest, "/")
+		}
+		return fileURI
+	})
+}
+
+// Jars writes the URIs of the classes of the dependencies the java provider
+// found references in as archive URIs, jar:file:///lib.jar!/com/example/A.class,
+// like the URIs of the files in archives found by the builtin provider.
+type Jars struct{}
+
+func (Jars) Rewrite(u uri.URI) uri.URI {
+	s := string(u)
+	if !strings.HasPrefix(s, jdtScheme) {
+		return u
+	}
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return u
+	}
+	class := parsed.Query().Get("packageName")
+	if class == "" {
+		return u
+	}
+	entry := strings.ReplaceAll(strings.TrimSuffix(class, ".class"), ".", "/") + ".class"
+	return uri.URI(archiveScheme + string(uri.File(parsed.Path)) + archiveSeparator + entry)
+}
+
+// rewriteFile rewrites the file URI, or the file URI of the outer archive of
+// an archive URI
+func rewriteFile(u uri.URI, rewrite func(fileURI string) string) uri.URI {
+	s := string(u)
+	if strings.HasPrefix(s, archiveScheme) {
+		archive, entries, found := strings.Cut(strings.TrimPrefix(s, archiveScheme), archiveSeparator)
+		if !found || !strings.HasPrefix(archive, uri.FileScheme) {
+			return u
+		}
+		return uri.URI(archiveScheme + rewrite(archive) + archiveSeparator + entries)
+	}
+	if !strings.HasPrefix(s, uri.FileScheme) {
+		return u
+	}
+	return uri.URI(rewrite(s))
+}
+
+// cutDir returns what follows the directory dir in the URI, when the URI is
+// in it
+func cutDir(fileURI, dir string) (string, bool) {
+	dir = strings.TrimSuffix(dir, "/")
+	if !strings.HasPrefix(fileURI, dir) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(fileURI, dir)
+	if rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+// toURI returns the file URI of a path, and URIs as they are
+func toURI(s string) string {
+	if strings.Contains(s, "://") {
+		return s
+	}
+	return string(uri.File(path.Clean(s)))
+}
+
+// Config is a rewriter of the URIs, as they are given in a file or an
+// analysis request. Only one of its rewriters is set.
+type Config struct {
+	// Prefix and Replace are the From and To of a Prefix
+	Prefix  string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Replace string `yaml:"replace,omitempty" json:"replace,omitempty"`
+	// RelativeTo is the Root of a Relative
+	RelativeTo string `yaml:"relativeTo,omitempty" json:"relativeTo,omitempty"`
+	// Jars is set for a Jars
+	Jars bool `yaml:"jars,omitempty" json:"jars,omitempty"`
+}
+
+func (c Config) rewriter() (Rewriter, error) {
+	set := 0
+	var r Rewriter
+	if c.Prefix != "" {
+		set++
+		r = Prefix{From: c.Prefix, To: c.Replace}
+	}
+	if c.RelativeTo != "" {
+		set++
+		r = Relative{Root: c.RelativeTo}
+	}
+	if c.Jars {
+		set++
+		r = Jars{}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("a uri rewrite must have one of prefix, relativeTo or jars, it has %d of them", set)
+	}
+	if (c.Prefix == "") != (c.Replace == "") {
+		return nil, fmt.Errorf("prefix and replace must be given together, relativeTo removes a prefix")
+	}
+	return r, nil
+}
+
+// New returns the rewriter that chains the rewriters of the configs
+func New(configs []Config) (Rewriter, error) {
+	rewriters := []Rewriter{}
+	for i, c := range configs {
+		r, err := c.rewriter()
+		if err != nil {
+			return nil, fmt.Errorf("uri rewrite %d: %w", i, err)
+		}
+		rewriters = append(rewriters, r)
+	}
+	return Chain(rewriters...), nil
+}
+
+// Load reads the configs of the rewriters from a yaml or json file
+func Load(path string) (Rewriter, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configs := []Config{}
+	if err := yaml.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("unable to parse the uri rewrites of %s: %w", path, err)
+	}
+	return New(configs)
+}
+
+// Apply rewrites the URIs of the incidents of the rulesets, and the file of
+// the dependencies.
+func Apply(r Rewriter, rulesets []konveyor.RuleSet, deps []konveyor.DepsFlatItem) {
+	for _, rs := range rulesets {
+		for _, v := range rs.Violations {
+			for i := range v.Incidents {
+				v.Incidents[i].URI = r.Rewrite(v.Incidents[i].URI)
+			}
+		}
+	}
+	for i := range deps {
+		deps[i].FileURI = string(r.Rewrite(uri.URI(deps[i].FileURI)))
+	}
+}
@@ -0,0 +1,111 @@
+package uris
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+func TestRewriters(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewriter Rewriter
+		uri      string
+		want     string
+	}{
+		{
+			name:     "prefix",
+			rewriter: Prefix{From: "/opt/input/source", To: "/home/dev/app"},
+			uri:      "file:///opt/input/source/src/App.java",
+			want:     "file:///home/dev/app/src/App.java",
+		},
+		{
+			name:     "prefix with a trailing slash and uris",
+			rewriter: Prefix{From: "file:///opt/input/source/", To: "https://git.example.com/app/blob/main"},
+			uri:      "file:///opt/input/source/src/App.java",
+			want:     "https://git.example.com/app/blob/main/src/App.java",
+		},
+		{
+			name:     "prefix of another directory",
+			rewriter: Prefix{From: "/opt/input/source", To: "/home/dev/app"},
+			uri:      "file:///opt/input/sources/App.java",
+			want:     "file:///opt/input/sources/App.java",
+		},
+		{
+			name:     "prefix of an archive",
+			rewriter: Prefix{From: "/opt/input/source", To: "/home/dev/app"},
+			uri:      "jar:file:///opt/input/source/app.ear!/app.war!/WEB-INF/web.xml",
+			want:     "jar:file:///home/dev/app/app.ear!/app.war!/WEB-INF/web.xml",
+		},
+		{
+			name:     "relative",
+			rewriter: Relative{Root: "/opt/input/source"},
+			uri:      "file:///opt/input/source/src/App.java",
+			want:     "src/App.java",
+		},
+		{
+			name:     "relative outside of the root",
+			rewriter: Relative{Root: "/opt/input/source"},
+			uri:      "file:///root/.m2/repository/lib.jar",
+			want:     "file:///root/.m2/repository/lib.jar",
+		},
+		{
+			name:     "jars",
+			rewriter: Jars{},
+			uri:      "konveyor-jdt://contents/root/.m2/repository/org/lib/1.0/lib-1.0.jar?packageName=org.lib.Client.class&source-range=false",
+			want:     "jar:file:///root/.m2/repository/org/lib/1.0/lib-1.0.jar!/org/lib/Client.class",
+		},
+		{
+			name:     "chain",
+			rewriter: Chain(Jars{}, Prefix{From: "/root/.m2", To: "/home/dev/.m2"}),
+			uri:      "konveyor-jdt://contents/root/.m2/lib.jar?packageName=org.lib.Client.class",
+			want:     "jar:file:///home/dev/.m2/lib.jar!/org/lib/Client.class",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rewriter.Rewrite(uri.URI(tt.uri)); string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rewrites.yaml")
+	content := "- jars: true\n- prefix: /opt/input/source\n  replace: /home/dev/app\n- relativeTo: /home/dev\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rewriter, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rulesets := []konveyor.RuleSet{{
+		Name: "test",
+		Violations: map[string]konveyor.Violation{
+			"rule-000": {Incidents: []konveyor.Incident{{URI: "file:///opt/input/source/src/App.java"}}},
+		},
+	}}
+	deps := []konveyor.DepsFlatItem{{FileURI: "file:///opt/input/source/pom.xml"}}
+	Apply(rewriter, rulesets, deps)
+	if got := rulesets[0].Violations["rule-000"].Incidents[0].URI; got != "app/src/App.java" {
+		t.Errorf("unexpected incident uri %s", got)
+	}
+	if deps[0].FileURI != "app/pom.xml" {
+		t.Errorf("unexpected dependency uri %s", deps[0].FileURI)
+	}
+
+	for _, invalid := range []string{"- prefix: /opt\n", "- relativeTo: /opt\n  jars: true\n", "- {}\n"} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package konveyor
 
 import (
 	"encoding/json"
+	"time"
 
 	"go.lsp.dev/uri"
 )
@@ -18,6 +19,9 @@ type RuleSet struct {
 	Description string `yaml:"description,omitempty" json:"description,omitempty"`
 	// Tags list of generated tags from the rules in this ruleset.
 	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// StructuredTags are the generated tags with their categories, the
+	// Category=value tags are split in a tag for each of their values.
+	StructuredTags []Tag `yaml:"structuredTags,omitempty" json:"structuredTags,omitempty"`
 	// Violations is a map containing violations generated for the
 	// matched rules in this ruleset. Keys are rule IDs, values are
 	// their respective generated violations.
@@ -32,6 +36,24 @@ type RuleSet struct {
 	Skipped []string `yaml:"skipped,omitempty" json:"skipped,omitempty"`
 }
 
+// Tag is a tag generated by a tagging rule, such as the Spring Boot 2.7 value
+// of the Framework category
+type Tag struct {
+	// Category of the tag, empty when the tag has none
+	Category string `yaml:"category,omitempty" json:"category,omitempty"`
+	// Value of the tag
+	Value string `yaml:"value" json:"value"`
+}
+
+// String returns the tag as it is written in the tags of a ruleset,
+// Category=value or the value when it has no category
+func (t Tag) String() string {
+	if t.Category == "" {
+		return t.Value
+	}
+	return t.Category + "=" + t.Value
+}
+
 type Category string
 
 var (
@@ -71,6 +93,46 @@ type Violation struct {
 
 	// Effort defines expected story points for this incident
 	Effort *int `yaml:"effort,omitempty" json:"effort,omitempty"`
+
+	// AppliedOverride is set when the category, effort or labels of the
+	// rule were changed by an override
+	AppliedOverride *AppliedOverride `yaml:"appliedOverride,omitempty" json:"appliedOverride,omitempty"`
+
+	// Overflow is set when the incidents were cut to keep the output under
+	// its size limit, it references the file that has all of them
+	Overflow *Overflow `yaml:"overflow,omitempty" json:"overflow,omitempty"`
+
+	// Targets are the migration targets given to the analysis that selected
+	// the rule of the violation
+	Targets []string `yaml:"targets,omitempty" json:"targets,omitempty"`
+
+	// Sample is set when the conditions of the rule only reported a sample
+	// of the incidents they matched
+	Sample *Sample `yaml:"sample,omitempty" json:"sample,omitempty"`
+}
+
+// Sample is the number of incidents the conditions of a violation matched,
+// the violation only has a sample of them
+type Sample struct {
+	Incidents int `yaml:"incidents" json:"incidents"`
+}
+
+// Overflow references the file the incidents of a violation were moved to
+type Overflow struct {
+	// File is the path to the overflow file, relative to the output file
+	File string `yaml:"file" json:"file"`
+	// Incidents is the number of incidents of the violation, the output only
+	// has the first ones
+	Incidents int `yaml:"incidents" json:"incidents"`
+}
+
+// AppliedOverride has the category, effort and labels of the rule before
+// they were overridden, and the reason given with the override
+type AppliedOverride struct {
+	Reason   string    `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Category *Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Effort   *int      `yaml:"effort,omitempty" json:"effort,omitempty"`
+	Labels   []string  `yaml:"labels,omitempty" json:"labels,omitempty"`
 }
 
 // Incident defines instance of a violation
@@ -84,13 +146,238 @@ type Incident struct {
 	//Extras json.RawMessage
 	LineNumber *int                   `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
 	Variables  map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+	// Fingerprint identifies the incident across analyses, it does not
+	// change when the incident only moves in its file.
+	Fingerprint string `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+	// MergedFrom lists the rules, as <ruleset>/<ruleID>, that found the same
+	// incident when duplicated incidents are merged
+	MergedFrom []string `yaml:"mergedFrom,omitempty" json:"mergedFrom,omitempty"`
+	// Confidence is set, between 0 and 1, when the provider found the
+	// incident with a heuristic, such as a text search, rather than with a
+	// precise search. The incidents without a confidence are precise.
+	Confidence *float64 `yaml:"confidence,omitempty" json:"confidence,omitempty"`
+	// Review is set on the incidents of the potential violations when a
+	// review file is given, so that they are triaged by a human once
+	Review ReviewState `yaml:"review,omitempty" json:"review,omitempty"`
 }
 
+// ReviewState is where an incident of a potential violation is in its review
+type ReviewState string
+
+const (
+	// ReviewUnreviewed is an incident no reviewer decided on yet
+	ReviewUnreviewed ReviewState = "unreviewed"
+	// ReviewAccepted is an incident a reviewer confirmed is an issue
+	ReviewAccepted ReviewState = "accepted"
+	// ReviewRejected is an incident a reviewer decided is not an issue
+	ReviewRejected ReviewState = "rejected"
+)
+
 // Link defines an external hyperlink
 type Link struct {
 	URL string `yaml:"url" json:"url"`
 	// Title optional description
 	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	// Excerpt is a short description of the linked page, when it was fetched
+	Excerpt string `yaml:"excerpt,omitempty" json:"excerpt,omitempty"`
+}
+
+// Summary aggregates the violations of the rulesets of an analysis
+type Summary struct {
+	// Violations is the number of matched rules
+	Violations int `yaml:"violations" json:"violations"`
+	Incidents  int `yaml:"incidents" json:"incidents"`
+	// Effort is the total story points, the effort of each violation is
+	// counted for each of its incidents.
+	Effort int `yaml:"effort" json:"effort"`
+	// Categories are keyed by category, the violations without a category
+	// are under "uncategorized".
+	Categories map[string]SummaryCount `yaml:"categories,omitempty" json:"categories,omitempty"`
+	RuleSets   map[string]SummaryCount `yaml:"rulesets,omitempty" json:"rulesets,omitempty"`
+	// Tags is the number of rulesets each tag was generated in
+	Tags map[string]int `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Targets are keyed by the migration targets given to the analysis, a
+	// violation is counted for each of the targets that selected its rule
+	Targets map[string]SummaryCount `yaml:"targets,omitempty" json:"targets,omitempty"`
+}
+
+// SummaryCount is the part of a Summary for a category or ruleset
+type SummaryCount struct {
+	Violations int `yaml:"violations" json:"violations"`
+	Incidents  int `yaml:"incidents" json:"incidents"`
+	Effort     int `yaml:"effort" json:"effort"`
+}
+
+// Warning is a non fatal problem found by a provider during the analysis, the
+// results may be incomplete because of it.
+type Warning struct {
+	// Provider is the name of the provider that found the problem
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Message  string `yaml:"message" json:"message"`
+	// Count is the number of items the problem was found for, such as the
+	// number of files that failed to parse.
+	Count int `yaml:"count,omitempty" json:"count,omitempty"`
+	// Items are the files or dependencies the problem was found for
+	Items []string `yaml:"items,omitempty" json:"items,omitempty"`
+}
+
+// Revision is the version of a working copy that the analyzed locations are
+// in, as recorded by its version control system.
+type Revision struct {
+	// System is the version control system, such as git, svn or hg
+	System string `yaml:"system" json:"system"`
+	// Root is the root directory of the working copy
+	Root     string `yaml:"root" json:"root"`
+	Revision string `yaml:"revision,omitempty" json:"revision,omitempty"`
+	Branch   string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// Remote is the repository the working copy was checked out from
+	Remote string `yaml:"remote,omitempty" json:"remote,omitempty"`
+	// Modified is set when the working copy has changes that are not
+	// committed, the analysis does not match the revision then.
+	Modified bool `yaml:"modified,omitempty" json:"modified,omitempty"`
+}
+
+// Manifest records how an analysis was run, so that its output can be
+// reproduced. It is only in the output when it is requested.
+type Manifest struct {
+	// AnalyzerVersion is the version of the analyzer module, with the
+	// revision it was built from when it is known
+	AnalyzerVersion string             `yaml:"analyzerVersion" json:"analyzerVersion"`
+	StartTime       time.Time          `yaml:"startTime" json:"startTime"`
+	EndTime         time.Time          `yaml:"endTime" json:"endTime"`
+	RuleSets        []ManifestRuleSet  `yaml:"rulesets,omitempty" json:"rulesets,omitempty"`
+	Providers       []ManifestProvider `yaml:"providers,omitempty" json:"providers,omitempty"`
+	LabelSelector   string             `yaml:"labelSelector,omitempty" json:"labelSelector,omitempty"`
+	// DepLabelSelector selects the dependencies the rules are evaluated on
+	DepLabelSelector string     `yaml:"depLabelSelector,omitempty" json:"depLabelSelector,omitempty"`
+	Targets          []string   `yaml:"targets,omitempty" json:"targets,omitempty"`
+	Scope            *PlanScope `yaml:"scope,omitempty" json:"scope,omitempty"`
+	Host             Host       `yaml:"host" json:"host"`
+}
+
+// ManifestRuleSet is a ruleset an analysis was run with
+type ManifestRuleSet struct {
+	Name  string `yaml:"name" json:"name"`
+	Rules int    `yaml:"rules" json:"rules"`
+	// Digest is the sha256 of the files the ruleset was loaded from, it is
+	// empty for the rulesets that were not loaded from files
+	Digest string `yaml:"digest,omitempty" json:"digest,omitempty"`
+}
+
+// ManifestProvider is a provider an analysis was run with
+type ManifestProvider struct {
+	Name string `yaml:"name" json:"name"`
+	// LanguageServer is the name and version of the language server the
+	// provider installed, when it installed one
+	LanguageServer string `yaml:"languageServer,omitempty" json:"languageServer,omitempty"`
+	// SettingsDigest is the sha256 of the settings of the provider, the
+	// analyses with the same digest ran the provider with the same settings
+	SettingsDigest string `yaml:"settingsDigest" json:"settingsDigest"`
+}
+
+// Host is the machine an analysis was run on
+type Host struct {
+	Hostname  string `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	OS        string `yaml:"os" json:"os"`
+	Arch      string `yaml:"arch" json:"arch"`
+	GoVersion string `yaml:"goVersion" json:"goVersion"`
+}
+
+// RuleTrace is how a rule was evaluated, it is only recorded when the
+// analysis is traced.
+type RuleTrace struct {
+	RuleSet   string `yaml:"ruleset" json:"ruleset"`
+	Rule      string `yaml:"rule" json:"rule"`
+	Matched   bool   `yaml:"matched" json:"matched"`
+	Incidents int    `yaml:"incidents" json:"incidents"`
+	Duration  string `yaml:"duration" json:"duration"`
+	Error     string `yaml:"error,omitempty" json:"error,omitempty"`
+	// Condition is the trace of the when block of the rule
+	Condition *ConditionTrace `yaml:"condition,omitempty" json:"condition,omitempty"`
+}
+
+// Debug is the information to debug an analysis, it is only in the output
+// when it is requested.
+type Debug struct {
+	Trace []RuleTrace `yaml:"trace,omitempty" json:"trace,omitempty"`
+}
+
+// ConditionTrace is how a node of the conditions of a rule was evaluated
+type ConditionTrace struct {
+	// Condition is and, or, dependency or the capability of the provider
+	Condition string `yaml:"condition" json:"condition"`
+	// Query is what was sent to the provider, after templating
+	Query string `yaml:"query,omitempty" json:"query,omitempty"`
+	From  string `yaml:"from,omitempty" json:"from,omitempty"`
+	As    string `yaml:"as,omitempty" json:"as,omitempty"`
+	Not   bool   `yaml:"not,omitempty" json:"not,omitempty"`
+	// Matched is the outcome of the condition, after it was negated
+	Matched    bool              `yaml:"matched" json:"matched"`
+	Incidents  int               `yaml:"incidents" json:"incidents"`
+	Duration   string            `yaml:"duration" json:"duration"`
+	Error      string            `yaml:"error,omitempty" json:"error,omitempty"`
+	Conditions []*ConditionTrace `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+}
+
+// CostTier is the estimated cost of evaluating a rule, by the number of
+// queries it sends to the providers
+type CostTier string
+
+const (
+	// CostLow is for the rules that send a single query
+	CostLow CostTier = "low"
+	// CostMedium is for the rules that send up to five queries
+	CostMedium CostTier = "medium"
+	// CostHigh is for the rules that send more than five queries
+	CostHigh CostTier = "high"
+)
+
+// Plan is what an analysis will evaluate, resolved from the rules, the
+// selectors, the targets and the overrides before any rule is evaluated.
+type Plan struct {
+	// RuleOrder is the order the selected rules are evaluated in, they are
+	// listed in it after the tagging rules
+	RuleOrder string        `yaml:"ruleOrder,omitempty" json:"ruleOrder,omitempty"`
+	Targets   []string      `yaml:"targets,omitempty" json:"targets,omitempty"`
+	Scope     *PlanScope    `yaml:"scope,omitempty" json:"scope,omitempty"`
+	Selected  int           `yaml:"selected" json:"selected"`
+	Skipped   int           `yaml:"skipped" json:"skipped"`
+	Providers []PlanCount   `yaml:"providers,omitempty" json:"providers,omitempty"`
+	Rules     []PlannedRule `yaml:"rules" json:"rules"`
+}
+
+// PlanScope is the globs of the files the providers are asked to search
+type PlanScope struct {
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// PlanCount is the number of selected rules that send queries to a provider
+// and their estimated cost
+type PlanCount struct {
+	Name  string `yaml:"name" json:"name"`
+	Rules int    `yaml:"rules" json:"rules"`
+	Cost  int    `yaml:"cost" json:"cost"`
+}
+
+// PlannedRule is a rule of the plan, with why it is evaluated or skipped
+type PlannedRule struct {
+	RuleSet  string `yaml:"ruleset" json:"ruleset"`
+	RuleID   string `yaml:"ruleID" json:"ruleID"`
+	Selected bool   `yaml:"selected" json:"selected"`
+	Reason   string `yaml:"reason" json:"reason"`
+	// Tagging is set for the rules that are evaluated first to tag the
+	// application
+	Tagging  bool      `yaml:"tagging,omitempty" json:"tagging,omitempty"`
+	Category *Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Targets  []string  `yaml:"targets,omitempty" json:"targets,omitempty"`
+	// Queries are the <provider>.<capability> the conditions of the rule
+	// send queries to
+	Queries []string `yaml:"queries,omitempty" json:"queries,omitempty"`
+	// Cost is the estimated number of queries the rule sends
+	Cost            int              `yaml:"cost" json:"cost"`
+	CostTier        CostTier         `yaml:"costTier" json:"costTier"`
+	AppliedOverride *AppliedOverride `yaml:"appliedOverride,omitempty" json:"appliedOverride,omitempty"`
 }
 
 type Dep struct {
@@ -102,6 +389,9 @@ type Dep struct {
 	Extras             map[string]interface{} `json:"extras,omitempty" yaml:"extras,omitempty"`
 	Labels             []string               `json:"labels,omitempty" yaml:"labels,omitempty"`
 	FileURIPrefix      string                 `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// License is the license of the dependency, as an SPDX identifier when it
+	// is recognized, set when the licenses of the dependencies are detected
+	License string `json:"license,omitempty" yaml:"license,omitempty"`
 }
 
 func (d *Dep) GetLabels() []string {
@@ -0,0 +1,309 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// Condition is a condition of a rule built in Go code, it is made with the
+// capability constructors of the parser and combined with And, Or and Not.
+// An invalid condition keeps its error until the rule is built.
+type Condition struct {
+	entry     engine.ConditionEntry
+	providers map[string]provider.InternalProviderClient
+	err       error
+}
+
+// As names the result of the condition so that the conditions that come
+// after it can use it with From.
+func (c Condition) As(name string) Condition {
+	c.entry.As = name
+	return c
+}
+
+// From makes the condition search the result of the condition named name.
+func (c Condition) From(name string) Condition {
+	c.entry.From = name
+	return c
+}
+
+// Ignore makes the condition only give its result to the conditions that
+// use it, its incidents are not reported.
+func (c Condition) Ignore() Condition {
+	c.entry.Ignorable = true
+	return c
+}
+
+// ReportAbsence makes a negated condition create an incident for the scope
+// that was searched when nothing was found in it.
+func (c Condition) ReportAbsence() Condition {
+	c.entry.ReportAbsence = true
+	return c
+}
+
+// Sample makes the condition only report n of its incidents, taken from as
+// many files as it can, the violation has the number of incidents it matched.
+func (c Condition) Sample(n int) Condition {
+	if n <= 0 && c.err == nil {
+		c.err = fmt.Errorf("sample must be a positive integer, not %d", n)
+	}
+	c.entry.Sample = n
+	return c
+}
+
+// Not negates the condition.
+func Not(c Condition) Condition {
+	c.entry.Not = !c.entry.Not
+	return c
+}
+
+// And matches when all the conditions match.
+func And(conditions ...Condition) Condition {
+	entries, providers, err := combine("and", conditions)
+	if err != nil || len(entries) == 0 {
+		return Condition{err: err}
+	}
+	return Condition{
+		entry: engine.ConditionEntry{
+			ProviderSpecificConfig: engine.AndCondition{Conditions: entries},
+		},
+		providers: providers,
+	}
+}
+
+// Or matches when any of the conditions match.
+func Or(conditions ...Condition) Condition {
+	entries, providers, err := combine("or", conditions)
+	if err != nil || len(entries) == 0 {
+		return Condition{err: err}
+	}
+	return Condition{
+		entry: engine.ConditionEntry{
+			ProviderSpecificConfig: engine.OrCondition{Conditions: entries},
+		},
+		providers: providers,
+	}
+}
+
+func combine(kind string, conditions []Condition) ([]engine.ConditionEntry, map[string]provider.InternalProviderClient, error) {
+	if len(conditions) == 0 {
+		return nil, nil, fmt.Errorf("%s must have at least one condition", kind)
+	}
+	entries := []engine.ConditionEntry{}
+	providers := map[string]provider.InternalProviderClient{}
+	for _, c := range conditions {
+		if c.err != nil {
+			return nil, nil, c.err
+		}
+		if c.entry.ProviderSpecificConfig == nil {
+			// the conditions that are not evaluated are left out
+			continue
+		}
+		entries = append(entries, c.entry)
+		for k, v := range c.providers {
+			providers[k] = v
+		}
+	}
+	return entries, providers, nil
+}
+
+// Capability returns the condition that asks the capability of the provider,
+// value is what follows <provider>.<capability> in a rule file, such as
+//
+//	map[string]interface{}{"pattern": "javax.*", "location": "IMPORT"}
+//
+// The provider must be one of ProviderNameToClient and have the capability.
+func (r *RuleParser) Capability(providerName, capability string, value interface{}) Condition {
+	// the providers get the condition as it is read from a rule file
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return Condition{err: fmt.Errorf("invalid condition %s.%s: %w", providerName, capability, err)}
+	}
+	var info interface{}
+	if err := yaml.Unmarshal(content, &info); err != nil {
+		return Condition{err: fmt.Errorf("invalid condition %s.%s: %w", providerName, capability, err)}
+	}
+	condition, client, err := r.getConditionForProvider(providerName, capability, info)
+	if err != nil {
+		return Condition{err: err}
+	}
+	if condition == nil {
+		// the dependency conditions are not evaluated
+		return Condition{}
+	}
+	return Condition{
+		entry: engine.ConditionEntry{
+			ProviderSpecificConfig: condition,
+		},
+		providers: map[string]provider.InternalProviderClient{providerName: client},
+	}
+}
+
+// Referenced returns the referenced condition of the provider, location is
+// optional.
+func (r *RuleParser) Referenced(providerName, pattern, location string) Condition {
+	value := map[string]interface{}{"pattern": pattern}
+	if location != "" {
+		value["location"] = location
+	}
+	return r.Capability(providerName, "referenced", value)
+}
+
+// Dependency returns the dependency condition of the provider that matches
+// the dependency name in the versions, a maven version range or semver
+// constraints.
+func (r *RuleParser) Dependency(providerName, name, versions string) Condition {
+	return r.Capability(providerName, "dependency", map[string]interface{}{
+		"name":     name,
+		"versions": versions,
+	})
+}
+
+// FileContent returns the filecontent condition of the builtin provider,
+// filePattern is optional.
+func (r *RuleParser) FileContent(pattern, filePattern string) Condition {
+	value := map[string]interface{}{"pattern": pattern}
+	if filePattern != "" {
+		value["filePattern"] = filePattern
+	}
+	return r.Capability("builtin", "filecontent", value)
+}
+
+// RuleBuilder builds a rule in Go code, it is checked the way the parser
+// checks the rules of a rule file.
+type RuleBuilder struct {
+	parser    *RuleParser
+	rule      engine.Rule
+	condition *Condition
+}
+
+// NewRule starts the rule with the id, its capability conditions use the
+// providers of the parser.
+func (r *RuleParser) NewRule(ruleID string) *RuleBuilder {
+	return &RuleBuilder{
+		parser: r,
+		rule: engine.Rule{
+			RuleMeta: engine.RuleMeta{
+				RuleID: ruleID,
+				Labels: []string{},
+			},
+		},
+	}
+}
+
+func (b *RuleBuilder) WithDescription(description string) *RuleBuilder {
+	b.rule.Description = description
+	return b
+}
+
+func (b *RuleBuilder) WithLabels(labels ...string) *RuleBuilder {
+	b.rule.Labels = append(b.rule.Labels, labels...)
+	return b
+}
+
+func (b *RuleBuilder) WithCategory(category konveyor.Category) *RuleBuilder {
+	b.rule.Category = &category
+	return b
+}
+
+func (b *RuleBuilder) WithEffort(effort int) *RuleBuilder {
+	b.rule.Effort = &effort
+	return b
+}
+
+func (b *RuleBuilder) WithMessage(message string, links ...konveyor.Link) *RuleBuilder {
+	b.rule.Perform.Message.Text = &message
+	b.rule.Perform.Message.Links = append([]konveyor.Link{}, links...)
+	return b
+}
+
+func (b *RuleBuilder) WithTags(tags ...string) *RuleBuilder {
+	b.rule.Perform.Tag = append(b.rule.Perform.Tag, tags...)
+	return b
+}
+
+func (b *RuleBuilder) WithStructuredTags(tags ...konveyor.Tag) *RuleBuilder {
+	b.rule.Perform.StructuredTags = append(b.rule.Perform.StructuredTags, tags...)
+	return b
+}
+
+func (b *RuleBuilder) WithCustomVariables(variables ...engine.CustomVariable) *RuleBuilder {
+	b.rule.CustomVariables = append(b.rule.CustomVariables, variables...)
+	return b
+}
+
+// WithCondition sets the when of the rule.
+func (b *RuleBuilder) WithCondition(condition Condition) *RuleBuilder {
+	b.condition = &condition
+	return b
+}
+
+// Build returns the rule with the providers of its conditions, the way
+// ParseRules returns them.
+func (b *RuleBuilder) Build() (engine.Rule, map[string]provider.InternalProviderClient, error) {
+	rule := b.rule
+	if e, ok := validateRuleID(rule.RuleID); !ok || rule.RuleID == "" {
+		if rule.RuleID == "" {
+			e = "rule id must be set"
+		}
+		return engine.Rule{}, nil, fmt.Errorf("invalid rule %q: %s", rule.RuleID, e)
+	}
+	if err := rule.Perform.Validate(); err != nil {
+		return engine.Rule{}, nil, fmt.Errorf("rule %s: %w", rule.RuleID, err)
+	}
+	if rule.Perform.Message.Text != nil && rule.Category == nil {
+		rule.Category = &konveyor.Potential
+	}
+	if b.condition == nil {
+		return engine.Rule{}, nil, fmt.Errorf("rule %s: a Rule must have a single condition", rule.RuleID)
+	}
+	c := *b.condition
+	if c.err != nil {
+		return engine.Rule{}, nil, fmt.Errorf("rule %s: %w", rule.RuleID, c.err)
+	}
+	if c.entry.ProviderSpecificConfig == nil {
+		return engine.Rule{}, nil, fmt.Errorf("rule %s: the condition is not evaluated", rule.RuleID)
+	}
+	if c.entry.ReportAbsence && !c.entry.Not {
+		return engine.Rule{}, nil, fmt.Errorf("rule %s: reportAbsence can only be used with not", rule.RuleID)
+	}
+	rule.When = c.entry
+	switch config := c.entry.ProviderSpecificConfig.(type) {
+	case engine.AndCondition, engine.OrCondition:
+		// a rule file gives the and and or of the when without an entry
+		e := c.entry
+		if e.From == "" && e.As == "" && !e.Ignorable && !e.Not && e.Sample == 0 {
+			rule.When = config
+		}
+	}
+
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	snippers := []engine.CodeSnip{}
+	for _, name := range names {
+		if snip, ok := c.providers[name].(engine.CodeSnip); ok {
+			snippers = append(snippers, snip)
+		}
+	}
+	if len(snippers) == 1 {
+		rule.Snipper = snippers[0]
+	} else if len(snippers) > 1 {
+		rule.Snipper = provider.CodeSnipProvider{
+			Providers: snippers,
+		}
+	}
+
+	if err := b.parser.validateVariables(rule); err != nil {
+		return engine.Rule{}, nil, err
+	}
+	return rule, c.providers, nil
+}
@@ -0,0 +1,106 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestRuleBuilder(t *testing.T) {
+	parser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{
+				caps: []provider.Capability{{Name: "filecontent"}},
+			},
+			"java": testProvider{
+				caps: []provider.Capability{{Name: "referenced"}, {Name: "dependency"}},
+			},
+		},
+		Log: logr.Discard(),
+	}
+
+	rule, providers, err := parser.NewRule("javax-to-jakarta").
+		WithMessage("use jakarta", konveyor.Link{URL: "https://jakarta.ee", Title: "Jakarta"}).
+		WithLabels("konveyor.io/target=jakarta-ee").
+		WithEffort(3).
+		WithCondition(ruleparser.And(
+			parser.Referenced("java", "javax.*", "IMPORT").As("refs").Ignore(),
+			ruleparser.Not(parser.FileContent("jakarta", "*.xml")),
+			parser.Dependency("java", "javax.servlet.servlet-api", "[1.0,4.0)"),
+		)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 {
+		t.Errorf("expected the builtin and java providers, got %v", providers)
+	}
+	if rule.Category == nil || *rule.Category != konveyor.Potential {
+		t.Errorf("expected the potential category, got %v", rule.Category)
+	}
+	and, ok := rule.When.(engine.AndCondition)
+	if !ok {
+		t.Fatalf("expected an and condition, got %T", rule.When)
+	}
+	if len(and.Conditions) != 3 {
+		t.Fatalf("expected 3 conditions, got %d", len(and.Conditions))
+	}
+	if and.Conditions[0].As != "refs" || !and.Conditions[0].Ignorable {
+		t.Errorf("expected the referenced condition to be ignored as refs, got %+v", and.Conditions[0])
+	}
+	if !and.Conditions[1].Not {
+		t.Errorf("expected the filecontent condition to be negated")
+	}
+	c, ok := and.Conditions[0].ProviderSpecificConfig.(provider.ProviderCondition)
+	if !ok {
+		t.Fatalf("expected a provider condition, got %T", and.Conditions[0].ProviderSpecificConfig)
+	}
+	info, ok := c.ConditionInfo.(map[interface{}]interface{})
+	if !ok || info["pattern"] != "javax.*" || info["location"] != "IMPORT" {
+		t.Errorf("expected the condition info of a rule file, got %#v", c.ConditionInfo)
+	}
+	if _, ok := and.Conditions[2].ProviderSpecificConfig.(*provider.DependencyCondition); !ok {
+		t.Errorf("expected a dependency condition, got %T", and.Conditions[2].ProviderSpecificConfig)
+	}
+
+	invalid := []struct {
+		name    string
+		builder *ruleparser.RuleBuilder
+	}{
+		{
+			name:    "no condition",
+			builder: parser.NewRule("no-condition").WithMessage("message"),
+		},
+		{
+			name:    "no message or tag",
+			builder: parser.NewRule("no-action").WithCondition(parser.FileContent("a", "")),
+		},
+		{
+			name:    "unknown provider",
+			builder: parser.NewRule("unknown").WithTags("a").WithCondition(parser.Referenced("go", "a", "")),
+		},
+		{
+			name:    "missing capability",
+			builder: parser.NewRule("missing").WithTags("a").WithCondition(parser.Capability("builtin", "xml", map[string]interface{}{})),
+		},
+		{
+			name:    "invalid rule id",
+			builder: parser.NewRule("a;b").WithTags("a").WithCondition(parser.FileContent("a", "")),
+		},
+		{
+			name:    "empty or",
+			builder: parser.NewRule("empty").WithTags("a").WithCondition(ruleparser.Or()),
+		},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tt.builder.Build(); err == nil {
+				t.Errorf("expected the rule to fail")
+			}
+		})
+	}
+}
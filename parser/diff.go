@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	path "path/filepath"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RuleChange is how a rule changed between two versions of the rules
+type RuleChange string
+
+const (
+	RuleAdded   RuleChange = "added"
+	RuleRemoved RuleChange = "removed"
+	RuleChanged RuleChange = "changed"
+)
+
+// FieldDiff is a field of a rule with its previous and current values, a
+// field that was added or removed has no previous or current value
+type FieldDiff struct {
+	Field string      `yaml:"field" json:"field"`
+	Old   interface{} `yaml:"old,omitempty" json:"old,omitempty"`
+	New   interface{} `yaml:"new,omitempty" json:"new,omitempty"`
+}
+
+// RuleDiff is a rule that was added, removed or changed. A RuleDiff without a
+// rule ID is a change of the ruleset itself, such as its labels.
+type RuleDiff struct {
+	RuleSet string     `yaml:"ruleset" json:"ruleset"`
+	RuleID  string     `yaml:"ruleID,omitempty" json:"ruleID,omitempty"`
+	Change  RuleChange `yaml:"change" json:"change"`
+	// Fields are the fields of a changed rule that differ, such as when,
+	// labels or effort
+	Fields        []FieldDiff `yaml:"fields,omitempty" json:"fields,omitempty"`
+	AddedLabels   []string    `yaml:"addedLabels,omitempty" json:"addedLabels,omitempty"`
+	RemovedLabels []string    `yaml:"removedLabels,omitempty" json:"removedLabels,omitempty"`
+}
+
+// RulesDiff is what changes between two versions of the rules
+type RulesDiff struct {
+	Added   int        `yaml:"added" json:"added"`
+	Removed int        `yaml:"removed" json:"removed"`
+	Changed int        `yaml:"changed" json:"changed"`
+	Rules   []RuleDiff `yaml:"rules" json:"rules"`
+}
+
+// rawRuleSet is a ruleset as it is written, the conditions are not parsed so
+// that the rules can be compared without the providers
+type rawRuleSet struct {
+	meta  map[string]interface{}
+	rules map[string]map[string]interface{}
+}
+
+// DiffRules compares the rules of two rule files or directories, laid out as
+// they are given to the analyzer. The rules are matched by ruleset name and
+// rule ID.
+func DiffRules(oldPath, newPath string) (RulesDiff, error) {
+	oldRuleSets := map[string]*rawRuleSet{}
+	if err := readRawRuleSets(oldPath, oldRuleSets); err != nil {
+		return RulesDiff{}, err
+	}
+	newRuleSets := map[string]*rawRuleSet{}
+	if err := readRawRuleSets(newPath, newRuleSets); err != nil {
+		return RulesDiff{}, err
+	}
+	return diffRuleSets(oldRuleSets, newRuleSets), nil
+}
+
+func diffRuleSets(oldRuleSets, newRuleSets map[string]*rawRuleSet) RulesDiff {
+	diff := RulesDiff{Rules: []RuleDiff{}}
+	add := func(d RuleDiff) {
+		switch d.Change {
+		case RuleAdded:
+			diff.Added++
+		case RuleRemoved:
+			diff.Removed++
+		case RuleChanged:
+			diff.Changed++
+		}
+		diff.Rules = append(diff.Rules, d)
+	}
+
+	for name, newSet := range newRuleSets {
+		oldSet, ok := oldRuleSets[name]
+		if !ok {
+			oldSet = &rawRuleSet{rules: map[string]map[string]interface{}{}}
+		} else if d, changed := diffFields(oldSet.meta, newSet.meta, "name"); changed {
+			d.RuleSet = name
+			add(d)
+		}
+		for id, newRule := range newSet.rules {
+			oldRule, ok := oldSet.rules[id]
+			if !ok {
+				add(RuleDiff{RuleSet: name, RuleID: id, Change: RuleAdded})
+				continue
+			}
+			if d, changed := diffFields(oldRule, newRule, "ruleID"); changed {
+				d.RuleSet = name
+				d.RuleID = id
+				add(d)
+			}
+		}
+		for id := range oldSet.rules {
+			if _, ok := newSet.rules[id]; !ok {
+				add(RuleDiff{RuleSet: name, RuleID: id, Change: RuleRemoved})
+			}
+		}
+	}
+	for name, oldSet := range oldRuleSets {
+		if _, ok := newRuleSets[name]; ok {
+			continue
+		}
+		for id := range oldSet.rules {
+			add(RuleDiff{RuleSet: name, RuleID: id, Change: RuleRemoved})
+		}
+	}
+
+	sort.Slice(diff.Rules, func(i, j int) bool {
+		a, b := diff.Rules[i], diff.Rules[j]
+		if a.RuleSet != b.RuleSet {
+			return a.RuleSet < b.RuleSet
+		}
+		return a.RuleID < b.RuleID
+	})
+	return diff
+}
+
+// diffFields compares the fields of a rule or a ruleset, except the one that
+// identifies it
+func diffFields(oldFields, newFields map[string]interface{}, idField string) (RuleDiff, bool) {
+	keys := []string{}
+	for k := range oldFields {
+		keys = append(keys, k)
+	}
+	for k := range newFields {
+		if _, ok := oldFields[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	d := RuleDiff{Change: RuleChanged}
+	for _, k := range keys {
+		if k == idField {
+			continue
+		}
+		oldValue, newValue := oldFields[k], newFields[k]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		d.Fields = append(d.Fields, FieldDiff{Field: k, Old: oldValue, New: newValue})
+		if k == "labels" {
+			d.AddedLabels = missingStrings(newValue, oldValue)
+			d.RemovedLabels = missingStrings(oldValue, newValue)
+		}
+	}
+	return d, len(d.Fields) > 0
+}
+
+// missingStrings returns the strings of the list a that are not in b
+func missingStrings(a, b interface{}) []string {
+	in := map[string]bool{}
+	if l, ok := b.([]interface{}); ok {
+		for _, s := range l {
+			in[fmt.Sprint(s)] = true
+		}
+	}
+	missing := []string{}
+	if l, ok := a.([]interface{}); ok {
+		for _, s := range l {
+			if !in[fmt.Sprint(s)] {
+				missing = append(missing, fmt.Sprint(s))
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return missing
+}
+
+// readRawRuleSets reads the rulesets of a rule file or directory the same way
+// LoadRules does
+func readRawRuleSets(filepath string, ruleSets map[string]*rawRuleSet) error {
+	info, err := os.Stat(filepath)
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() {
+		meta, err := readRawRuleSetMeta(path.Dir(filepath))
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			meta = map[string]interface{}{"name": defaultRuleSet.Name}
+		}
+		return addRawRules(ruleSets, meta, []string{filepath})
+	}
+
+	files, err := os.ReadDir(filepath)
+	if err != nil {
+		return err
+	}
+	ruleFiles := []string{}
+	foundTree := false
+	for _, f := range files {
+		info, err := os.Stat(path.Join(filepath, f.Name()))
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			foundTree = true
+			if err := readRawRuleSets(path.Join(filepath, f.Name()), ruleSets); err != nil {
+				return err
+			}
+			continue
+		}
+		if info.Mode().IsRegular() && f.Name() != RULE_SET_GOLDEN_FILE_NAME {
+			ruleFiles = append(ruleFiles, path.Join(filepath, f.Name()))
+		}
+	}
+	meta, err := readRawRuleSetMeta(filepath)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		if !foundTree {
+			return fmt.Errorf("unable to find %v in %s", RULE_SET_GOLDEN_FILE_NAME, filepath)
+		}
+		return nil
+	}
+	return addRawRules(ruleSets, meta, ruleFiles)
+}
+
+// readRawRuleSetMeta reads the ruleset golden file of a directory, it returns
+// nil when there is none
+func readRawRuleSetMeta(dir string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path.Join(dir, RULE_SET_GOLDEN_FILE_NAME))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &meta); err != nil {
+		return nil, fmt.Errorf("unable to convert file: %s to yaml: %w", path.Join(dir, RULE_SET_GOLDEN_FILE_NAME), err)
+	}
+	return normalizeYAML(meta).(map[string]interface{}), nil
+}
+
+func addRawRules(ruleSets map[string]*rawRuleSet, meta map[string]interface{}, files []string) error {
+	name := fmt.Sprint(meta["name"])
+	set, ok := ruleSets[name]
+	if !ok {
+		set = &rawRuleSet{meta: meta, rules: map[string]map[string]interface{}{}}
+		ruleSets[name] = set
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rules := []map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &rules); err != nil {
+			return fmt.Errorf("unable to convert file: %s to yaml: %w", file, err)
+		}
+		for _, rule := range rules {
+			ruleID, ok := rule["ruleID"].(string)
+			if !ok || ruleID == "" {
+				return fmt.Errorf("unable to find ruleID in a rule of %s", file)
+			}
+			if _, ok := set.rules[ruleID]; ok {
+				return fmt.Errorf("duplicated rule id: %s in ruleset %s", ruleID, name)
+			}
+			set.rules[ruleID] = normalizeYAML(rule).(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+// normalizeYAML converts the maps that yaml decodes to maps with string keys,
+// so that the values can be compared and written as json
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, value := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(value)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, value := range v {
+			m[k] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			l[i] = normalizeYAML(value)
+		}
+		return l
+	default:
+		return v
+	}
+}
@@ -0,0 +1,172 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+)
+
+func writeRuleFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiffRules(t *testing.T) {
+	oldDir := writeRuleFiles(t, map[string]string{
+		"java/ruleset.yaml": "name: java\nlabels: [konveyor.io/source=java]\n",
+		"java/rules.yaml": `- ruleID: same
+  message: same
+  when:
+    java.referenced:
+      pattern: a.B
+- ruleID: condition
+  message: condition
+  when:
+    java.referenced:
+      pattern: a.C
+- ruleID: labels
+  labels: [konveyor.io/target=quarkus, konveyor.io/target=eap]
+  effort: 1
+  message: labels
+  when:
+    java.referenced:
+      pattern: a.D
+- ruleID: removed
+  message: removed
+  when:
+    java.referenced:
+      pattern: a.E
+`,
+		"go/ruleset.yaml": "name: go\n",
+		"go/rules.yaml":   "- ruleID: go-removed\n  tag: [Go]\n  when:\n    builtin.file:\n      pattern: \"*.go\"\n",
+	})
+	newDir := writeRuleFiles(t, map[string]string{
+		"java/ruleset.yaml": "name: java\nlabels: [konveyor.io/source=java, konveyor.io/source=java-ee]\n",
+		"java/rules.yaml": `- ruleID: same
+  message: same
+  when:
+    java.referenced:
+      pattern: a.B
+- ruleID: condition
+  message: condition
+  when:
+    java.referenced:
+      pattern: a.Changed
+- ruleID: labels
+  labels: [konveyor.io/target=quarkus, konveyor.io/target=cloud-readiness]
+  effort: 3
+  message: labels
+  when:
+    java.referenced:
+      pattern: a.D
+- ruleID: added
+  message: added
+  when:
+    java.referenced:
+      pattern: a.F
+`,
+	})
+
+	diff, err := ruleparser.DiffRules(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := ruleparser.RulesDiff{
+		Added:   1,
+		Removed: 2,
+		Changed: 3,
+		Rules: []ruleparser.RuleDiff{
+			{RuleSet: "go", RuleID: "go-removed", Change: ruleparser.RuleRemoved},
+			{
+				RuleSet: "java",
+				Change:  ruleparser.RuleChanged,
+				Fields: []ruleparser.FieldDiff{{
+					Field: "labels",
+					Old:   []interface{}{"konveyor.io/source=java"},
+					New:   []interface{}{"konveyor.io/source=java", "konveyor.io/source=java-ee"},
+				}},
+				AddedLabels: []string{"konveyor.io/source=java-ee"},
+			},
+			{RuleSet: "java", RuleID: "added", Change: ruleparser.RuleAdded},
+			{
+				RuleSet: "java",
+				RuleID:  "condition",
+				Change:  ruleparser.RuleChanged,
+				Fields: []ruleparser.FieldDiff{{
+					Field: "when",
+					Old:   map[string]interface{}{"java.referenced": map[string]interface{}{"pattern": "a.C"}},
+					New:   map[string]interface{}{"java.referenced": map[string]interface{}{"pattern": "a.Changed"}},
+				}},
+			},
+			{
+				RuleSet: "java",
+				RuleID:  "labels",
+				Change:  ruleparser.RuleChanged,
+				Fields: []ruleparser.FieldDiff{
+					{Field: "effort", Old: 1, New: 3},
+					{
+						Field: "labels",
+						Old:   []interface{}{"konveyor.io/target=quarkus", "konveyor.io/target=eap"},
+						New:   []interface{}{"konveyor.io/target=quarkus", "konveyor.io/target=cloud-readiness"},
+					},
+				},
+				AddedLabels:   []string{"konveyor.io/target=cloud-readiness"},
+				RemovedLabels: []string{"konveyor.io/target=eap"},
+			},
+			{RuleSet: "java", RuleID: "removed", Change: ruleparser.RuleRemoved},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected diff\n%+v\ngot\n%+v", expected, diff)
+	}
+}
+
+func TestDiffRulesErrors(t *testing.T) {
+	valid := writeRuleFiles(t, map[string]string{
+		"ruleset.yaml": "name: test\n",
+		"rules.yaml":   "- ruleID: a\n  tag: [A]\n  when:\n    builtin.file:\n      pattern: a\n",
+	})
+	testCases := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name:  "no ruleset",
+			files: map[string]string{"rules.yaml": "- ruleID: a\n"},
+		},
+		{
+			name: "duplicated rule",
+			files: map[string]string{
+				"ruleset.yaml": "name: test\n",
+				"a.yaml":       "- ruleID: a\n",
+				"b.yaml":       "- ruleID: a\n",
+			},
+		},
+		{
+			name: "rule without ID",
+			files: map[string]string{
+				"ruleset.yaml": "name: test\n",
+				"a.yaml":       "- message: a\n",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ruleparser.DiffRules(valid, writeRuleFiles(t, tc.files)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
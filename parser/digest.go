@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	path "path/filepath"
+)
+
+// Digest returns the sha256 of rules given as content, as it is recorded in
+// the manifest of an analysis
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// digestFiles returns the sha256 of the names and the contents of the files
+// of a ruleset, in the order they are given. The files that can not be read
+// are left out, the errors of the rules they have are reported when they are
+// loaded.
+func digestFiles(files ...string) string {
+	h := sha256.New()
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		h.Write([]byte(path.Base(f)))
+		h.Write([]byte{0})
+		h.Write(content)
+		h.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
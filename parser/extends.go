@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	path "path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// extend adds the rules of the rulesets the ruleset of the directory
+// extends to its rules. A rule of the ruleset replaces the rules with the
+// same ID of the rulesets it extends, in place, and the disabled rules are
+// left out. A rule that two extended rulesets have must be overridden or
+// disabled in one of them.
+func (r *RuleParser) extend(dir string, ruleSet *engine.RuleSet, clients map[string]provider.InternalProviderClient) error {
+	if len(ruleSet.Extends) == 0 {
+		return nil
+	}
+	dir, err := path.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if r.extending == nil {
+		r.extending = map[string]bool{}
+	}
+	if r.extending[dir] {
+		chain := append(append([]string{}, r.extendingOrder...), ruleSet.Name)
+		return fmt.Errorf("ruleset %s extends itself: %s", ruleSet.Name, strings.Join(chain, " -> "))
+	}
+	r.extending[dir] = true
+	r.extendingOrder = append(r.extendingOrder, ruleSet.Name)
+	defer func() {
+		delete(r.extending, dir)
+		r.extendingOrder = r.extendingOrder[:len(r.extendingOrder)-1]
+	}()
+
+	own := map[string]int{}
+	for i, rule := range ruleSet.Rules {
+		own[rule.RuleID] = i
+	}
+	inherited := []engine.Rule{}
+	// from is the ruleset each inherited rule comes from
+	from := map[string]string{}
+	overridden := map[string]bool{}
+	digests := []string{ruleSet.Digest}
+	for _, base := range ruleSet.Extends {
+		if base.Path == "" {
+			return fmt.Errorf("ruleset %s: an extended ruleset must have a path", ruleSet.Name)
+		}
+		basePath := base.Path
+		if !path.IsAbs(basePath) {
+			basePath = path.Join(dir, basePath)
+		}
+		if info, err := os.Stat(basePath); err != nil || !info.IsDir() {
+			return fmt.Errorf("ruleset %s: the extended ruleset %s is not a directory", ruleSet.Name, base.Path)
+		}
+		baseSets, baseClients, err := r.LoadRules(basePath)
+		if err != nil {
+			return fmt.Errorf("ruleset %s: unable to load the extended ruleset %s: %w", ruleSet.Name, base.Path, err)
+		}
+		if len(baseSets) != 1 {
+			return fmt.Errorf("ruleset %s: the extended ruleset %s must be a single ruleset, it has %d", ruleSet.Name, base.Path, len(baseSets))
+		}
+		baseSet := baseSets[0]
+		digests = append(digests, baseSet.Digest)
+		for k, v := range baseClients {
+			clients[k] = v
+		}
+
+		disabled := map[string]bool{}
+		for _, id := range base.Disable {
+			if _, ok := own[id]; ok {
+				return fmt.Errorf("ruleset %s: rule %s of %s is both disabled and overridden", ruleSet.Name, id, baseSet.Name)
+			}
+			disabled[id] = true
+		}
+		for _, rule := range baseSet.Rules {
+			id := rule.RuleID
+			if disabled[id] {
+				delete(disabled, id)
+				continue
+			}
+			if i, ok := own[id]; ok {
+				// the rule of the ruleset is where the first extended
+				// ruleset had it
+				if !overridden[id] {
+					overridden[id] = true
+					inherited = append(inherited, ruleSet.Rules[i])
+				}
+				continue
+			}
+			if other, ok := from[id]; ok {
+				return fmt.Errorf("ruleset %s: rule %s is in the extended rulesets %s and %s, override it or disable it in one of them", ruleSet.Name, id, other, baseSet.Name)
+			}
+			from[id] = baseSet.Name
+			// the labels of the extended ruleset still select its rules
+			rule.Labels = append(append([]string{}, rule.Labels...), baseSet.Labels...)
+			inherited = append(inherited, rule)
+		}
+		if len(disabled) > 0 {
+			ids := []string{}
+			for id := range disabled {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return fmt.Errorf("ruleset %s: the disabled rules %s are not in the extended ruleset %s", ruleSet.Name, strings.Join(ids, ", "), baseSet.Name)
+		}
+	}
+	// the rules that do not override one come after the inherited ones
+	for _, rule := range ruleSet.Rules {
+		if !overridden[rule.RuleID] {
+			inherited = append(inherited, rule)
+		}
+	}
+	ruleSet.Rules = inherited
+	ruleSet.Digest = Digest([]byte(strings.Join(digests, "\n")))
+	return nil
+}
@@ -0,0 +1,91 @@
+package parser_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoadRulesExtends(t *testing.T) {
+	type rule struct {
+		ID      string
+		Message string
+		Labels  []string
+	}
+	testCases := []struct {
+		Name         string
+		Dir          string
+		Expected     []rule
+		ErrorMessage string
+	}{
+		{
+			Name: "overrides and disables the rules of the extended rulesets",
+			Dir:  "child",
+			Expected: []rule{
+				{ID: "file-001", Message: "all the go files of the child"},
+				{ID: "file-002", Message: "all json files of the child"},
+				{ID: "file-007", Message: "all markdown files", Labels: []string{"konveyor.io/source=base"}},
+				{ID: "file-005", Message: "all xml files"},
+				{ID: "file-004", Message: "all toml files"},
+			},
+		},
+		{
+			Name:         "rule in two extended rulesets",
+			Dir:          "conflict",
+			ErrorMessage: "ruleset conflict: rule file-002 is in the extended rulesets base and other, override it or disable it in one of them",
+		},
+		{
+			Name:         "disabled rule not in the extended ruleset",
+			Dir:          "unknown-disable",
+			ErrorMessage: "ruleset unknown-disable: the disabled rules file-009 are not in the extended ruleset base",
+		},
+		{
+			Name:         "rulesets extending each other",
+			Dir:          "cycle-a",
+			ErrorMessage: "ruleset cycle-a extends itself: cycle-a -> cycle-b -> cycle-a",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ruleParser := ruleparser.RuleParser{
+				ProviderNameToClient: map[string]provider.InternalProviderClient{
+					"builtin": testProvider{
+						caps: []provider.Capability{{
+							Name: "file",
+						}},
+					},
+				},
+				Log: logrusr.New(logrus.New()),
+			}
+			ruleSets, _, err := ruleParser.LoadRules(filepath.Join("testdata", "extends", tc.Dir))
+			if tc.ErrorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ErrorMessage) {
+					t.Fatalf("expected the error %q, got %v", tc.ErrorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ruleSets) != 1 {
+				t.Fatalf("expected a single ruleset, got %d", len(ruleSets))
+			}
+			got := []rule{}
+			for _, r := range ruleSets[0].Rules {
+				got = append(got, rule{ID: r.RuleID, Message: *r.Perform.Message.Text})
+				if len(r.Labels) > 0 {
+					got[len(got)-1].Labels = r.Labels
+				}
+			}
+			if !reflect.DeepEqual(got, tc.Expected) {
+				t.Errorf("expected the rules %+v, got %+v", tc.Expected, got)
+			}
+		})
+	}
+}
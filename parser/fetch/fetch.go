@@ -0,0 +1,342 @@
+// Package fetch pulls the rulesets given as a git repository or an OCI
+// artifact into a cache directory, so that they are parsed as the rulesets
+// on the local disk and can be distributed as images are.
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	gitPrefix = "git::"
+	ociPrefix = "oci://"
+
+	// fetchedFile is written next to the rulesets once they are fetched,
+	// with the reference they were fetched from, the parser would read it
+	// as rules if it were with them
+	fetchedFile = ".fetched"
+	rulesetsDir = "rulesets"
+)
+
+// fetchMutex serializes the fetches, so that analyses running at the same
+// time do not fetch the same ruleset at once.
+var fetchMutex sync.Mutex
+
+var (
+	commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	digestRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// Reference is a ruleset that is not on the local disk, given as
+// git::<url>[//<dir>][?ref=<ref>&digest=<digest>] for a git repository, or
+// as oci://<registry>/<repository>[:<tag>][@<digest>] for an OCI artifact.
+type Reference struct {
+	// Kind is git or oci
+	Kind string `json:"kind"`
+	// URL is the url of the git repository, or the registry and the
+	// repository of the OCI artifact
+	URL string `json:"url"`
+	// Ref is the branch, tag or commit of the git repository, or the tag of
+	// the OCI artifact
+	Ref string `json:"ref,omitempty"`
+	// Dir is the directory of the rulesets in the git repository
+	Dir string `json:"dir,omitempty"`
+	// Digest is sha256:<hex>, the digest of the manifest of the OCI artifact,
+	// or the digest of the files of the rulesets of the git repository as
+	// Digest computes it
+	Digest string `json:"digest,omitempty"`
+}
+
+// IsRemote returns whether the rules are a reference to fetch rather than a
+// path on the local disk
+func IsRemote(rules string) bool {
+	return strings.HasPrefix(rules, gitPrefix) || strings.HasPrefix(rules, ociPrefix)
+}
+
+// ParseReference parses a git:: or oci:// reference
+func ParseReference(s string) (Reference, error) {
+	var r Reference
+	var err error
+	switch {
+	case strings.HasPrefix(s, gitPrefix):
+		r, err = parseGitReference(strings.TrimPrefix(s, gitPrefix))
+	case strings.HasPrefix(s, ociPrefix):
+		r, err = parseOCIReference(strings.TrimPrefix(s, ociPrefix))
+	default:
+		return r, fmt.Errorf("invalid ruleset reference %s, it starts with %s or %s", s, gitPrefix, ociPrefix)
+	}
+	if err != nil {
+		return r, fmt.Errorf("invalid ruleset reference %s: %w", s, err)
+	}
+	if r.Digest != "" && !digestRegex.MatchString(r.Digest) {
+		return r, fmt.Errorf("invalid digest %s of ruleset reference %s, it is sha256:<hex>", r.Digest, s)
+	}
+	return r, nil
+}
+
+func parseGitReference(s string) (Reference, error) {
+	r := Reference{Kind: "git"}
+	if i := strings.LastIndex(s, "?"); i >= 0 {
+		query, err := url.ParseQuery(s[i+1:])
+		if err != nil {
+			return r, err
+		}
+		for k := range query {
+			if k != "ref" && k != "digest" {
+				return r, fmt.Errorf("unknown parameter %s", k)
+			}
+		}
+		r.Ref = query.Get("ref")
+		r.Digest = query.Get("digest")
+		s = s[:i]
+	}
+	// the directory is after a // that is not the one of the scheme
+	start := 0
+	if i := strings.Index(s, "://"); i >= 0 {
+		start = i + 3
+	}
+	if i := strings.Index(s[start:], "//"); i >= 0 {
+		r.Dir = filepath.Clean(filepath.FromSlash(s[start+i+2:]))
+		s = s[:start+i]
+		if r.Dir == "." || filepath.IsAbs(r.Dir) || r.Dir == ".." || strings.HasPrefix(r.Dir, ".."+string(filepath.Separator)) {
+			return r, fmt.Errorf("invalid directory %s", r.Dir)
+		}
+	}
+	if s == "" {
+		return r, fmt.Errorf("the git repository has no url")
+	}
+	r.URL = s
+	return r, nil
+}
+
+func parseOCIReference(s string) (Reference, error) {
+	r := Reference{Kind: "oci"}
+	if i := strings.Index(s, "@"); i >= 0 {
+		r.Digest = s[i+1:]
+		s = s[:i]
+	}
+	slash := strings.Index(s, "/")
+	if slash <= 0 || slash == len(s)-1 {
+		return r, fmt.Errorf("the artifact is <registry>/<repository>")
+	}
+	// the tag is after the last : that is not the one of the port of the
+	// registry
+	if i := strings.LastIndex(s, ":"); i > slash {
+		r.Ref = s[i+1:]
+		s = s[:i]
+	}
+	if r.Ref == "" && r.Digest == "" {
+		r.Ref = "latest"
+	}
+	r.URL = s
+	return r, nil
+}
+
+// Pinned returns whether the reference always fetches the same rulesets, so
+// that the ones in the cache are used without fetching them again
+func (r Reference) Pinned() bool {
+	return r.Digest != "" || (r.Kind == "git" && commitRegex.MatchString(r.Ref))
+}
+
+// Fetcher fetches the rulesets in a cache directory, a pinned reference is
+// only fetched the first time it is used, the other ones each time.
+type Fetcher struct {
+	dir    string
+	log    logr.Logger
+	client *http.Client
+	// run runs the git commands
+	run func(ctx context.Context, dir string, name string, args ...string) (string, error)
+}
+
+// DefaultDir is the cache directory of the user for the rulesets
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "konveyor", "rulesets")
+}
+
+func NewFetcher(dir string, log logr.Logger) *Fetcher {
+	return &Fetcher{
+		dir:    dir,
+		log:    log.WithName("fetch"),
+		client: http.DefaultClient,
+		run:    run,
+	}
+}
+
+func run(ctx context.Context, dir string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	// a repository that needs credentials fails instead of waiting for them
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// Resolve returns the paths of the rules with the references fetched, the
+// paths on the local disk are returned as they are.
+func (f *Fetcher) Resolve(ctx context.Context, rules []string) ([]string, error) {
+	paths := []string{}
+	for _, r := range rules {
+		if !IsRemote(r) {
+			paths = append(paths, r)
+			continue
+		}
+		path, err := f.Fetch(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Fetch fetches the rulesets of the reference when they are not in the cache
+// yet and returns their directory.
+func (f *Fetcher) Fetch(ctx context.Context, reference string) (string, error) {
+	r, err := ParseReference(reference)
+	if err != nil {
+		return "", err
+	}
+	marker, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.Abs(f.dir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(marker)
+	dir := filepath.Join(root, r.Kind, hex.EncodeToString(sum[:16]))
+	fetchMutex.Lock()
+	defer fetchMutex.Unlock()
+	if fetched, err := os.ReadFile(filepath.Join(dir, fetchedFile)); err == nil && string(fetched) == string(marker) && r.Pinned() {
+		return filepath.Join(dir, rulesetsDir, r.Dir), nil
+	}
+
+	f.log.Info("fetching rulesets", "reference", reference, "dir", dir)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	// the rulesets are fetched next to the cache entry and moved in place
+	// once they are verified, a failed fetch keeps the previous ones
+	tmp, err := os.MkdirTemp(root, ".fetch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	fetched := filepath.Join(tmp, rulesetsDir)
+	if err := os.Mkdir(fetched, 0755); err != nil {
+		return "", err
+	}
+	switch r.Kind {
+	case "git":
+		err = f.fetchGit(ctx, r, fetched)
+	case "oci":
+		err = f.fetchOCI(ctx, r, fetched)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch rulesets %s: %w", reference, err)
+	}
+	rulesets := filepath.Join(fetched, r.Dir)
+	if info, err := os.Stat(rulesets); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("rulesets %s have no directory %s", reference, r.Dir)
+	}
+	digest, err := Digest(rulesets)
+	if err != nil {
+		return "", err
+	}
+	if r.Kind == "git" && r.Digest != "" && digest != r.Digest {
+		return "", &DigestError{Reference: reference, Expected: r.Digest, Actual: digest}
+	}
+	f.log.Info("fetched rulesets", "reference", reference, "digest", digest)
+	if err := os.WriteFile(filepath.Join(tmp, fetchedFile), marker, 0644); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, rulesetsDir, r.Dir), nil
+}
+
+// Digest returns the digest of the files of a directory, from their paths and
+// the sha256 of their content. It is the digest that pins the rulesets of a
+// git repository, the analyzer logs it when it fetches them.
+func Digest(dir string) (string, error) {
+	hash := sha256.New()
+	// the entries are walked in lexical order
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		content := sha256.New()
+		if _, err := io.Copy(content, file); err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%s\n", filepath.ToSlash(rel), hex.EncodeToString(content.Sum(nil)))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DigestError is the error of rulesets that do not have the digest of their
+// reference.
+type DigestError struct {
+	Reference string
+	Expected  string
+	Actual    string
+}
+
+func (e *DigestError) Error() string {
+	return fmt.Sprintf("digest of %s is %s, expected %s", e.Reference, e.Actual, e.Expected)
+}
+
+// IsDigestError returns whether rulesets were not fetched because they did
+// not match the digest of their reference.
+func IsDigestError(err error) bool {
+	var digestErr *DigestError
+	return errors.As(err, &digestErr)
+}
@@ -0,0 +1,286 @@
+package fetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		want      Reference
+		wantErr   bool
+	}{
+		{
+			name:      "git repository",
+			reference: "git::https://github.com/konveyor/rulesets.git",
+			want:      Reference{Kind: "git", URL: "https://github.com/konveyor/rulesets.git"},
+		},
+		{
+			name:      "git directory, ref and digest",
+			reference: "git::https://github.com/konveyor/rulesets.git//default/generated?ref=v0.3.0&digest=" + testDigest,
+			want: Reference{Kind: "git", URL: "https://github.com/konveyor/rulesets.git", Ref: "v0.3.0",
+				Dir: filepath.Join("default", "generated"), Digest: testDigest},
+		},
+		{
+			name:      "git scp-like url with a directory",
+			reference: "git::git@github.com:konveyor/rulesets.git//default",
+			want:      Reference{Kind: "git", URL: "git@github.com:konveyor/rulesets.git", Dir: "default"},
+		},
+		{
+			name:      "git directory out of the repository",
+			reference: "git::https://github.com/konveyor/rulesets.git//../other",
+			wantErr:   true,
+		},
+		{
+			name:      "git unknown parameter",
+			reference: "git::https://github.com/konveyor/rulesets.git?branch=main",
+			wantErr:   true,
+		},
+		{
+			name:      "oci tag",
+			reference: "oci://quay.io/konveyor/rulesets:v0.3.0",
+			want:      Reference{Kind: "oci", URL: "quay.io/konveyor/rulesets", Ref: "v0.3.0"},
+		},
+		{
+			name:      "oci latest tag on a registry with a port",
+			reference: "oci://localhost:5000/rulesets",
+			want:      Reference{Kind: "oci", URL: "localhost:5000/rulesets", Ref: "latest"},
+		},
+		{
+			name:      "oci digest",
+			reference: "oci://quay.io/konveyor/rulesets@" + testDigest,
+			want:      Reference{Kind: "oci", URL: "quay.io/konveyor/rulesets", Digest: testDigest},
+		},
+		{
+			name:      "oci invalid digest",
+			reference: "oci://quay.io/konveyor/rulesets@md5:abc",
+			wantErr:   true,
+		},
+		{
+			name:      "oci without a repository",
+			reference: "oci://quay.io",
+			wantErr:   true,
+		},
+		{
+			name:      "local path",
+			reference: "rules/",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReference(tt.reference)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReference() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestFetchGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md":                "rulesets",
+		"rulesets/ruleset.yaml":    "name: test",
+		"rulesets/rules/rule.yaml": "- ruleID: test-00001",
+	} {
+		if err := writeFile(filepath.Join(repo, filepath.FromSlash(name)), strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "rulesets"},
+	} {
+		if _, err := run(ctx, repo, "git", args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := run(ctx, repo, "git", "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := strings.TrimSpace(out)
+
+	f := NewFetcher(t.TempDir(), logr.Discard())
+	dir, err := f.Fetch(ctx, "git::file://"+repo+"//rulesets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"ruleset.yaml": "name: test", "rules/rule.yaml": "- ruleID: test-00001"}
+	if got := readFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("fetched %v, want %v", got, want)
+	}
+	digest, err := Digest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.Fetch(ctx, "git::file://"+repo+"//rulesets?digest="+testDigest)
+	if !IsDigestError(err) {
+		t.Errorf("expected a digest error, got %v", err)
+	}
+	pinned := "git::file://" + repo + "//rulesets?ref=" + commit + "&digest=" + digest
+	if _, err := f.Fetch(ctx, pinned); err != nil {
+		t.Fatal(err)
+	}
+	// the pinned rulesets are not fetched again
+	if err := os.RemoveAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	dir, err = f.Fetch(ctx, pinned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("cached %v, want %v", got, want)
+	}
+	if _, err := f.Fetch(ctx, "git::file://"+repo+"//rulesets"); err == nil {
+		t.Errorf("expected the rulesets that are not pinned to be fetched again")
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// testRegistry serves an artifact with a file and a directory as oras pushes
+// them, behind an anonymous token
+func testRegistry(t *testing.T, blobs map[string][]byte, manifest []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	var ts *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:konveyor/rulesets:pull" {
+			http.Error(w, "invalid scope", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"token": "anonymous"}`))
+	})
+	mux.HandleFunc("/v2/konveyor/rulesets/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+ts.URL+`/token",service="test",scope="repository:konveyor/rulesets:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v2/konveyor/rulesets/")
+		switch {
+		case strings.HasPrefix(path, "manifests/"):
+			w.Write(manifest)
+		case strings.HasPrefix(path, "blobs/") && blobs[strings.TrimPrefix(path, "blobs/")] != nil:
+			w.Write(blobs[strings.TrimPrefix(path, "blobs/")])
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ts = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetchOCI(t *testing.T) {
+	ctx := context.Background()
+	file := []byte("name: test")
+	dir := tarGz(t, map[string]string{"rules/rule.yaml": "- ruleID: test-00001"})
+	blobs := map[string][]byte{sha256Digest(file): file, sha256Digest(dir): dir}
+	layers := []ociDescriptor{
+		{
+			MediaType:   "application/vnd.oci.image.layer.v1.tar",
+			Digest:      sha256Digest(file),
+			Annotations: map[string]string{titleAnnotation: "ruleset.yaml"},
+		},
+		{
+			MediaType:   "application/vnd.oci.image.layer.v1.tar+gzip",
+			Digest:      sha256Digest(dir),
+			Annotations: map[string]string{titleAnnotation: "rules", unpackAnnotation: "true"},
+		},
+	}
+	manifest, err := json.Marshal(ociManifest{MediaType: ociManifestMediaType, Layers: layers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := testRegistry(t, blobs, manifest)
+	registry := strings.TrimPrefix(ts.URL, "http://")
+	want := map[string]string{"ruleset.yaml": "name: test", "rules/rule.yaml": "- ruleID: test-00001"}
+
+	f := NewFetcher(t.TempDir(), logr.Discard())
+	for _, reference := range []string{
+		"oci://" + registry + "/konveyor/rulesets:v1",
+		"oci://" + registry + "/konveyor/rulesets@" + sha256Digest(manifest),
+	} {
+		got, err := f.Fetch(ctx, reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files := readFiles(t, got); !reflect.DeepEqual(files, want) {
+			t.Errorf("fetched %v from %s, want %v", files, reference, want)
+		}
+	}
+
+	_, err = f.Fetch(ctx, "oci://"+registry+"/konveyor/rulesets:v1@"+testDigest)
+	if !IsDigestError(err) {
+		t.Errorf("expected a digest error, got %v", err)
+	}
+
+	// a layer that is not the one of the manifest
+	blobs[sha256Digest(file)] = []byte("name: changed")
+	_, err = f.Fetch(ctx, "oci://"+registry+"/konveyor/rulesets:v1")
+	if !IsDigestError(err) {
+		t.Errorf("expected a digest error of the layer, got %v", err)
+	}
+}
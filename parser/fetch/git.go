@@ -0,0 +1,36 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fetchGit checks the ref of the repository out in the directory, without
+// its history. A ref that is a commit must be the commit that is checked out.
+func (f *Fetcher) fetchGit(ctx context.Context, r Reference, dir string) error {
+	ref := r.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := f.run(ctx, dir, "git", "init", "--quiet"); err != nil {
+		return err
+	}
+	if _, err := f.run(ctx, dir, "git", "fetch", "--quiet", "--depth", "1", r.URL, ref); err != nil {
+		return err
+	}
+	if _, err := f.run(ctx, dir, "git", "checkout", "--quiet", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	out, err := f.run(ctx, dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if commit := strings.TrimSpace(out); commitRegex.MatchString(r.Ref) && commit != r.Ref {
+		return fmt.Errorf("ref %s checked out commit %s", r.Ref, commit)
+	}
+	// the rulesets are the files of the commit
+	return os.RemoveAll(filepath.Join(dir, ".git"))
+}
@@ -0,0 +1,283 @@
+package fetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	ociIndexMediaType       = "application/vnd.oci.image.index.v1+json"
+
+	// titleAnnotation is the name of the file of a layer, as oras pushes
+	// the files
+	titleAnnotation = "org.opencontainers.image.title"
+	// unpackAnnotation is set by oras on the layers of the directories, which
+	// are gzipped tarballs
+	unpackAnnotation = "io.deis.oras.content.unpack"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// registry is the repository of an artifact in an OCI registry, it is pulled
+// anonymously, with a bearer token when the registry asks for one
+type registry struct {
+	client *http.Client
+	base   string
+	token  string
+}
+
+// fetchOCI writes the layers of the artifact in the directory, the files
+// pushed with oras are written with their name and the gzipped tarballs are
+// extracted. The digests of the layers are verified, and the one of the
+// manifest when the reference has one.
+func (f *Fetcher) fetchOCI(ctx context.Context, r Reference, dir string) error {
+	host, repository := r.URL[:strings.Index(r.URL, "/")], r.URL[strings.Index(r.URL, "/")+1:]
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	scheme := "https"
+	if isLocalhost(host) {
+		scheme = "http"
+	}
+	reg := &registry{client: f.client, base: fmt.Sprintf("%s://%s/v2/%s", scheme, host, repository)}
+	reference := r.Ref
+	if r.Digest != "" {
+		reference = r.Digest
+	}
+	body, err := reg.get(ctx, "manifests/"+reference, ociManifestMediaType+", "+dockerManifestMediaType)
+	if err != nil {
+		return err
+	}
+	if r.Digest != "" {
+		if digest := sha256Digest(body); digest != r.Digest {
+			return &DigestError{Reference: ociPrefix + r.URL, Expected: r.Digest, Actual: digest}
+		}
+	}
+	manifest := ociManifest{}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.MediaType == ociIndexMediaType {
+		return fmt.Errorf("the artifact is an index, not a manifest")
+	}
+	for _, layer := range manifest.Layers {
+		blob, err := reg.get(ctx, "blobs/"+layer.Digest, "")
+		if err != nil {
+			return err
+		}
+		if digest := sha256Digest(blob); digest != layer.Digest {
+			return &DigestError{Reference: ociPrefix + r.URL + "@" + layer.Digest, Expected: layer.Digest, Actual: digest}
+		}
+		if err := writeLayer(layer, blob, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isLocalhost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// get returns the content of a path of the repository, asking for a token
+// the first time the registry refuses it
+func (r *registry) get(ctx context.Context, path string, accept string) ([]byte, error) {
+	resp, err := r.do(ctx, r.base+"/"+path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		resp, err = r.do(ctx, r.base+"/"+path, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get %s: %s", r.base+"/"+path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (r *registry) do(ctx context.Context, u string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.client.Do(req)
+}
+
+// authenticate gets an anonymous token from the realm of a bearer challenge
+func (r *registry) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return fmt.Errorf("unable to pull from %s, it needs credentials", r.base)
+	}
+	params := map[string]string{}
+	for _, p := range strings.Split(challenge[len("bearer "):], ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok {
+			params[strings.ToLower(k)] = strings.Trim(v, `"`)
+		}
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid authentication challenge %s", challenge)
+	}
+	query := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			query.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = query.Encode()
+	resp, err := r.do(ctx, realm.String(), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get a token from %s: %s", params["realm"], resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	if r.token == "" {
+		return fmt.Errorf("no token from %s", params["realm"])
+	}
+	return nil
+}
+
+// writeLayer writes a layer in the directory, as the file of its title or as
+// the files of its tarball
+func writeLayer(layer ociDescriptor, blob []byte, dir string) error {
+	title := layer.Annotations[titleAnnotation]
+	gzipped := strings.HasSuffix(layer.MediaType, "tar+gzip") || strings.HasSuffix(layer.MediaType, "tar.gzip")
+	switch {
+	case gzipped && (title == "" || layer.Annotations[unpackAnnotation] == "true"):
+		gz, err := gzip.NewReader(bytes.NewReader(blob))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dir)
+	case title != "":
+		path, err := target(dir, title)
+		if err != nil {
+			return err
+		}
+		return writeFile(path, bytes.NewReader(blob))
+	case strings.HasSuffix(layer.MediaType, ".tar"):
+		return extractTar(bytes.NewReader(blob), dir)
+	}
+	return fmt.Errorf("layer %s of type %s has no file name", layer.Digest, layer.MediaType)
+}
+
+// target returns where a file of a layer is written, the files outside of
+// the directory are refused.
+func target(dir string, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if path == dir || !strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file %s", name)
+	}
+	return path, nil
+}
+
+// extractTar writes the directories and the regular files of a tarball in
+// the directory, the rulesets do not need the other entries
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.Trim(header.Name, "./") == "" {
+			continue
+		}
+		path, err := target(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = writeFile(path, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -44,6 +44,14 @@ type RuleParser struct {
 	Log                  logr.Logger
 	NoDependencyRules    bool
 	DepLabelSelector     *labels.LabelSelector[*provider.Dep]
+	// Vars are the values of the {{.vars.<name>}} of the condition
+	// parameters
+	Vars map[string]string
+
+	// extending are the directories of the rulesets whose extended rulesets
+	// are being loaded, to find the rulesets that extend themselves
+	extending      map[string]bool
+	extendingOrder []string
 }
 
 func (r *RuleParser) loadRuleSet(dir string) *engine.RuleSet {
@@ -100,6 +108,10 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 			ruleSet = defaultRuleSet
 		}
 		ruleSet.Rules = rules
+		ruleSet.Digest = digestFiles(path.Join(path.Dir(filepath), RULE_SET_GOLDEN_FILE_NAME), filepath)
+		if err := r.extend(path.Dir(filepath), ruleSet, m); err != nil {
+			return nil, nil, err
+		}
 
 		return []engine.RuleSet{*ruleSet}, m, err
 	}
@@ -113,6 +125,7 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 	}
 	var ruleSet *engine.RuleSet
 	rules := []engine.Rule{}
+	ruleSetFiles := []string{}
 	foundTree := false
 	parserErr := &parserErrors{}
 	for _, f := range files {
@@ -137,6 +150,7 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 			continue
 		}
 		if info.Mode().IsRegular() {
+			ruleSetFiles = append(ruleSetFiles, path.Join(filepath, f.Name()))
 			if f.Name() == RULE_SET_GOLDEN_FILE_NAME {
 				ruleSet = r.loadRuleSet(filepath)
 				continue
@@ -159,6 +173,10 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 	}
 	if ruleSet != nil {
 		ruleSet.Rules = rules
+		ruleSet.Digest = digestFiles(ruleSetFiles...)
+		if err := r.extend(filepath, ruleSet, clientMap); err != nil {
+			return nil, nil, err
+		}
 		ruleSets = append(ruleSets, *ruleSet)
 	}
 	// Return nil if there are no captured errors
@@ -182,7 +200,65 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to convert file: %s to yaml", filepath)
 	}
+	return r.parseRules(ruleMap)
+}
+
+// ParseRules parses the rules of a rule file that is already read, such as
+// the rules pasted in the interactive mode.
+func (r *RuleParser) ParseRules(content []byte) ([]engine.Rule, map[string]provider.InternalProviderClient, error) {
+	ruleMap := []map[string]interface{}{}
+	err := yaml.Unmarshal(content, &ruleMap)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to convert rules to yaml: %w", err)
+	}
+	return r.parseRules(ruleMap)
+}
 
+// InlineConditionRuleID is the rule ID of the rule created for a condition
+// that is given on its own, see ParseInline
+const InlineConditionRuleID = "condition"
+
+// ParseInline parses a rule, a list of rules or a single condition given
+// inline, such as on the command line. A single condition is parsed as a rule
+// with the InlineConditionRuleID and a message saying that it matched.
+func (r *RuleParser) ParseInline(content []byte) ([]engine.Rule, map[string]provider.InternalProviderClient, error) {
+	var parsed interface{}
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse the input as yaml: %w", err)
+	}
+	var rules []interface{}
+	switch v := parsed.(type) {
+	case []interface{}:
+		rules = v
+	case map[interface{}]interface{}:
+		if _, ok := v["ruleID"]; ok {
+			rules = []interface{}{v}
+		} else {
+			rules = []interface{}{map[interface{}]interface{}{
+				"ruleID":  InlineConditionRuleID,
+				"message": "condition matched",
+				"when":    v,
+			}}
+		}
+	default:
+		return nil, nil, fmt.Errorf("expected a rule, a list of rules or a condition")
+	}
+	content, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedRules, providers, err := r.ParseRules(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(parsedRules) == 0 {
+		return nil, nil, fmt.Errorf("no rule to evaluate, the rules need a ruleID and a condition")
+	}
+	return parsedRules, providers, nil
+}
+
+func (r *RuleParser) parseRules(ruleMap []map[string]interface{}) ([]engine.Rule, map[string]provider.InternalProviderClient, error) {
+	var err error
 	// rules that provide metadata
 	infoRules := []engine.Rule{}
 	// all rules
@@ -249,11 +325,18 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 						return nil, nil, fmt.Errorf("tag must be a list of strings")
 					}
 					for _, tagVal := range tagList {
-						tag, ok := tagVal.(string)
-						if !ok {
-							return nil, nil, fmt.Errorf("tag value must be a string")
+						switch tag := tagVal.(type) {
+						case string:
+							perform.Tag = append(perform.Tag, tag)
+						case map[interface{}]interface{}:
+							structured, err := parseStructuredTag(tag)
+							if err != nil {
+								return nil, nil, fmt.Errorf("rule %s: %w", ruleID, err)
+							}
+							perform.StructuredTags = append(perform.StructuredTags, structured)
+						default:
+							return nil, nil, fmt.Errorf("tag value must be a string or a category and a value")
 						}
-						perform.Tag = append(perform.Tag, tag)
 					}
 				}
 			}
@@ -271,7 +354,21 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 		}
 
 		r.addRuleFields(&rule, ruleMap)
+		if thresholdRaw, ok := ruleMap["threshold"]; ok {
+			threshold, err := parseThreshold(thresholdRaw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("rule %s: %w", ruleID, err)
+			}
+			rule.Threshold = threshold
+		}
 
+		if when, ok := ruleMap["when"]; ok {
+			when, err := r.substituteVars(when)
+			if err != nil {
+				return nil, nil, fmt.Errorf("rule %s: %w", ruleID, err)
+			}
+			ruleMap["when"] = when
+		}
 		whenMap, ok := ruleMap["when"].(map[interface{}]interface{})
 		if !ok {
 			return nil, nil, fmt.Errorf("a Rule must have a single condition")
@@ -281,6 +378,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 		var as string
 		var ignorable bool
 		var not bool
+		var reportAbsence bool
 		fromRaw, ok := whenMap["from"]
 		if ok {
 			delete(whenMap, "from")
@@ -316,6 +414,14 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				return nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		reportAbsence, err = getReportAbsence(whenMap, not)
+		if err != nil {
+			return nil, nil, err
+		}
+		sample, err := getSample(whenMap)
+		if err != nil {
+			return nil, nil, err
+		}
 
 		noConditions := false
 		for k, value := range whenMap {
@@ -338,7 +444,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 					noConditions = true
 				}
 
-				rule.When = engine.OrCondition{Conditions: conditions}
+				rule.When = sampled(engine.OrCondition{Conditions: conditions}, sample)
 				snippers := []engine.CodeSnip{}
 				for k, prov := range provs {
 					if snip, ok := prov.(engine.CodeSnip); ok {
@@ -364,7 +470,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				if len(conditions) == 0 {
 					noConditions = true
 				}
-				rule.When = engine.AndCondition{Conditions: conditions}
+				rule.When = sampled(engine.AndCondition{Conditions: conditions}, sample)
 				snippers := []engine.CodeSnip{}
 				for k, prov := range provs {
 					if snip, ok := prov.(engine.CodeSnip); ok {
@@ -401,6 +507,8 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					ReportAbsence:          reportAbsence,
+					Sample:                 sample,
 				}
 				rule.When = c
 				if snipper, ok := provider.(engine.CodeSnip); ok {
@@ -413,9 +521,12 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 			r.Log.V(5).Info("skipping rule no conditions found", "rule", rule.RuleID)
 			continue
 		}
+		if err := r.validateVariables(rule); err != nil {
+			return nil, nil, err
+		}
 
 		ruleIDMap[rule.RuleID] = nil
-		if rule.Perform.Tag != nil {
+		if rule.Perform.IsTagging() {
 			infoRules = append(infoRules, rule)
 		} else {
 			rules = append(rules, rule)
@@ -427,6 +538,114 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 	return append(infoRules, rules...), providers, nil
 }
 
+// parseStructuredTag reads a tag given as a category and a value, such as
+//
+//	category: Framework
+//	value: Spring Boot 2.7
+func parseStructuredTag(tagMap map[interface{}]interface{}) (konveyor.Tag, error) {
+	tag := konveyor.Tag{}
+	for key, val := range tagMap {
+		var str string
+		switch v := val.(type) {
+		case string:
+			str = v
+		case int, float64, bool:
+			str = fmt.Sprintf("%v", v)
+		default:
+			return tag, fmt.Errorf("the %v of a tag must be a string", key)
+		}
+		switch key {
+		case "category":
+			tag.Category = str
+		case "value":
+			tag.Value = str
+		default:
+			return tag, fmt.Errorf("unknown field %v of a tag, a tag has a category and a value", key)
+		}
+	}
+	if tag.Value == "" {
+		return tag, fmt.Errorf("the value of a tag must be set")
+	}
+	if strings.ContainsAny(tag.Category, "=,") {
+		return tag, fmt.Errorf("the category %s of a tag must not have = or ,", tag.Category)
+	}
+	return tag, nil
+}
+
+// parseThreshold reads the number of incidents a rule needs to be reported,
+// such as
+//
+//	minIncidents: 50
+//	perFile: true
+func parseThreshold(raw interface{}) (*engine.Threshold, error) {
+	thresholdMap, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("threshold must be an object with minIncidents and perFile")
+	}
+	threshold := &engine.Threshold{}
+	for key, val := range thresholdMap {
+		switch key {
+		case "minIncidents":
+			minIncidents, ok := val.(int)
+			if !ok {
+				return nil, fmt.Errorf("the minIncidents of a threshold must be an integer, not %v", val)
+			}
+			threshold.MinIncidents = minIncidents
+		case "perFile":
+			perFile, ok := val.(bool)
+			if !ok {
+				return nil, fmt.Errorf("the perFile of a threshold must be a boolean, not %v", val)
+			}
+			threshold.PerFile = perFile
+		default:
+			return nil, fmt.Errorf("unknown field %v of a threshold, a threshold has minIncidents and perFile", key)
+		}
+	}
+	return threshold, threshold.Validate()
+}
+
+// getReportAbsence reads and removes the reportAbsence keyword from the condition,
+// it is only valid for negated conditions.
+func getReportAbsence(conditionMap map[interface{}]interface{}, not bool) (bool, error) {
+	reportAbsenceRaw, ok := conditionMap["reportAbsence"]
+	if !ok {
+		return false, nil
+	}
+	delete(conditionMap, "reportAbsence")
+	reportAbsence, ok := reportAbsenceRaw.(bool)
+	if !ok {
+		return false, fmt.Errorf("reportAbsence must be a boolean, not %v", reportAbsenceRaw)
+	}
+	if reportAbsence && !not {
+		return false, fmt.Errorf("reportAbsence can only be used with not")
+	}
+	return reportAbsence, nil
+}
+
+// getSample returns the number of incidents of the condition that are
+// reported, 0 when they all are
+func getSample(conditionMap map[interface{}]interface{}) (int, error) {
+	sampleRaw, ok := conditionMap["sample"]
+	if !ok {
+		return 0, nil
+	}
+	delete(conditionMap, "sample")
+	sample, ok := sampleRaw.(int)
+	if !ok || sample <= 0 {
+		return 0, fmt.Errorf("sample must be a positive integer, not %v", sampleRaw)
+	}
+	return sample, nil
+}
+
+// sampled returns the and or the or of a when, in an entry that samples its
+// incidents when it has a sample
+func sampled(c engine.Conditional, sample int) engine.Conditional {
+	if sample == 0 {
+		return c
+	}
+	return engine.ConditionEntry{ProviderSpecificConfig: c, Sample: sample}
+}
+
 func validateRuleID(ruleID string) (string, bool) {
 	if strings.Contains(ruleID, "\n") {
 		return "rule id can not contain string", false
@@ -562,6 +781,7 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 		var as string
 		var ignorable bool
 		var not bool
+		var reportAbsence bool
 		fromRaw, ok := conditionMap["from"]
 		if ok {
 			delete(conditionMap, "from")
@@ -594,6 +814,14 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 				return nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		reportAbsence, err := getReportAbsence(conditionMap, not)
+		if err != nil {
+			return nil, nil, err
+		}
+		sample, err := getSample(conditionMap)
+		if err != nil {
+			return nil, nil, err
+		}
 		for k, v := range conditionMap {
 			key, ok := k.(string)
 			if !ok {
@@ -616,10 +844,12 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					return []engine.ConditionEntry{}, nil, nil
 				}
 				ce = engine.ConditionEntry{
-					From:      from,
-					As:        as,
-					Ignorable: ignorable,
-					Not:       not,
+					From:          from,
+					As:            as,
+					Ignorable:     ignorable,
+					Not:           not,
+					ReportAbsence: reportAbsence,
+					Sample:        sample,
 					ProviderSpecificConfig: engine.AndCondition{
 						Conditions: conds,
 					},
@@ -642,10 +872,12 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					return []engine.ConditionEntry{}, nil, nil
 				}
 				ce = engine.ConditionEntry{
-					From:      from,
-					As:        as,
-					Ignorable: ignorable,
-					Not:       not,
+					From:          from,
+					As:            as,
+					Ignorable:     ignorable,
+					Not:           not,
+					ReportAbsence: reportAbsence,
+					Sample:        sample,
 					ProviderSpecificConfig: engine.OrCondition{
 						Conditions: conds,
 					},
@@ -678,6 +910,8 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					ReportAbsence:          reportAbsence,
+					Sample:                 sample,
 				}
 				providers[providerKey] = provider
 			}
@@ -724,7 +958,8 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 
 	if capability == "dependency" && !r.NoDependencyRules {
 		depCondition := provider.DependencyCondition{
-			Client: client,
+			ProviderName: langProvider,
+			Client:       client,
 		}
 
 		fullCondition, ok := value.(map[interface{}]interface{})
@@ -747,6 +982,8 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 				depCondition.Upperbound = value
 			case "lowerbound":
 				depCondition.Lowerbound = value
+			case "versions":
+				depCondition.Versions = value
 			case "nameregex":
 				depCondition.NameRegex = value
 			default:
@@ -761,8 +998,14 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 			return nil, nil, fmt.Errorf("Unable to parse dependency condition for %s (name is required)", langProvider)
 		}
 
-		if depCondition.Upperbound == "" && depCondition.Lowerbound == "" {
-			return nil, nil, fmt.Errorf("Unable to parse dependency condition for %s (one of upperbound or lowerbound is required)", langProvider)
+		if depCondition.Upperbound == "" && depCondition.Lowerbound == "" && depCondition.Versions == "" {
+			return nil, nil, fmt.Errorf("Unable to parse dependency condition for %s (one of upperbound, lowerbound or versions is required)", langProvider)
+		}
+		if depCondition.Versions != "" && (depCondition.Upperbound != "" || depCondition.Lowerbound != "") {
+			return nil, nil, fmt.Errorf("Unable to parse dependency condition for %s (versions can not be used with upperbound or lowerbound)", langProvider)
+		}
+		if err := depCondition.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("Unable to parse dependency condition for %s (%v)", langProvider, err)
 		}
 
 		return &depCondition, client, nil
@@ -778,6 +1021,7 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 	}
 
 	return provider.ProviderCondition{
+		ProviderName:     langProvider,
 		Client:           client,
 		Capability:       capability,
 		ConditionInfo:    value,
@@ -562,6 +562,113 @@ func TestLoadRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:         "structured tags",
+			testFileName: "valid-structured-tag-rule.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID: "tag-001",
+							},
+							Perform: engine.Perform{
+								Tag: []string{"Language=Java"},
+								StructuredTags: []konveyor.Tag{
+									{Category: "Framework", Value: "Spring Boot 2.7"},
+									{Value: "Spring"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "structured tag without a value",
+			testFileName: "invalid-structured-tag-rule.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "rule tag-001: the value of a tag must be set",
+		},
+		{
+			Name:         "rule with a threshold",
+			testFileName: "valid-threshold-rule.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform:   engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							Threshold: &engine.Threshold{MinIncidents: 5, PerFile: true},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+		},
+		{
+			Name:         "threshold without incidents",
+			testFileName: "invalid-threshold-rule.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "rule file-001: the minIncidents of a threshold must be at least 1, not 0",
+		},
+		{
+			Name:         "sample that is not positive",
+			testFileName: "invalid-sample-rule.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "sample must be a positive integer, not 0",
+		},
 		{
 			Name:         "multiple-rulesets",
 			testFileName: "folder-of-rulesets",
@@ -731,7 +838,8 @@ func TestLoadRules(t *testing.T) {
 				for _, rule := range ruleSet.Rules {
 					foundRule := false
 					for _, expectedRule := range expectedSet.Rules {
-						if reflect.DeepEqual(expectedRule.Perform, rule.Perform) && expectedRule.Description == rule.Description {
+						if reflect.DeepEqual(expectedRule.Perform, rule.Perform) && expectedRule.Description == rule.Description &&
+							reflect.DeepEqual(expectedRule.Threshold, rule.Threshold) {
 							if expectedRule.Category != nil && rule.Category != nil {
 								foundRule = *expectedRule.Category == *rule.Category
 							} else if expectedRule.Category != nil || rule.Category != nil {
@@ -750,3 +858,67 @@ func TestLoadRules(t *testing.T) {
 		})
 	}
 }
+
+func TestParseInline(t *testing.T) {
+	parser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{
+				caps: []provider.Capability{{Name: "filecontent"}},
+			},
+		},
+		Log: logr.Discard(),
+	}
+	tests := []struct {
+		name    string
+		input   string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "condition",
+			input:   "builtin.filecontent:\n  pattern: TODO\n",
+			wantIDs: []string{ruleparser.InlineConditionRuleID},
+		},
+		{
+			name:    "rule",
+			input:   "ruleID: todo\nmessage: found a TODO\nwhen:\n  builtin.filecontent:\n    pattern: TODO\n",
+			wantIDs: []string{"todo"},
+		},
+		{
+			name:    "rules",
+			input:   "- ruleID: a\n  tag: [a]\n  when:\n    builtin.filecontent:\n      pattern: a\n- ruleID: b\n  message: b\n  when:\n    builtin.filecontent:\n      pattern: b\n",
+			wantIDs: []string{"a", "b"},
+		},
+		{
+			name:    "not yaml",
+			input:   "builtin.filecontent: [",
+			wantErr: true,
+		},
+		{
+			name:    "scalar",
+			input:   "TODO",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, providers, err := parser.ParseInline([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseInline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			ids := []string{}
+			for _, r := range rules {
+				ids = append(ids, r.RuleID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("expected the rules %v, got %v", tt.wantIDs, ids)
+			}
+			if _, ok := providers["builtin"]; !ok {
+				t.Errorf("expected the builtin provider, got %v", providers)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"gopkg.in/yaml.v2"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// validateVariables checks that the message of the rule and the queries of
+// its chained conditions only use the variables the incidents of their
+// conditions have, so that a typo such as {{matchingtext}} fails the rule
+// when it is loaded instead of rendering empty. The variables are only known
+// when the providers of the conditions declare them in their capabilities.
+func (r *RuleParser) validateVariables(rule engine.Rule) error {
+	if rule.Perform.Message.Text != nil {
+		if names, ok := r.incidentVariables(rule.When); ok {
+			names["lineNumber"] = true
+			for _, cv := range rule.CustomVariables {
+				names[cv.Name] = true
+			}
+			for _, name := range templateNames(*rule.Perform.Message.Text) {
+				name = strings.Split(name, ".")[0]
+				if !names[name] {
+					return fmt.Errorf("rule %s: the message uses the variable %s that its incidents do not have, they have %s",
+						rule.RuleID, name, joinNames(names))
+				}
+			}
+		}
+	}
+
+	chains := map[string]map[string]bool{}
+	entries := conditionEntries(rule.When)
+	for _, entry := range entries {
+		if entry.As == "" {
+			continue
+		}
+		if names, ok := r.incidentVariables(entry.ProviderSpecificConfig); ok {
+			chains[entry.As] = names
+		}
+	}
+	for _, entry := range entries {
+		if entry.From == "" {
+			continue
+		}
+		for _, c := range providerConditions(entry.ProviderSpecificConfig) {
+			content, err := yaml.Marshal(c.ConditionInfo)
+			if err != nil {
+				continue
+			}
+			for _, name := range templateNames(string(content)) {
+				// {{<as>.variables.<name>}} and {{<as>.regex.<name>}}
+				parts := strings.Split(name, ".")
+				if len(parts) != 3 || (parts[1] != "variables" && parts[1] != "regex") {
+					continue
+				}
+				names, ok := chains[parts[0]]
+				if ok && !names[parts[2]] {
+					return fmt.Errorf("rule %s: the condition %s.%s uses the variable %s that the incidents of %s do not have, they have %s",
+						rule.RuleID, c.ProviderName, c.Capability, parts[2], parts[0], joinNames(names))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// incidentVariables returns the names of the variables the incidents of the
+// condition can have, false when a provider of its conditions does not
+// declare them
+func (r *RuleParser) incidentVariables(c engine.Conditional) (map[string]bool, bool) {
+	switch c := c.(type) {
+	case engine.ConditionEntry:
+		return r.incidentVariables(c.ProviderSpecificConfig)
+	case engine.AndCondition:
+		return r.conditionsVariables(c.Conditions)
+	case engine.OrCondition:
+		return r.conditionsVariables(c.Conditions)
+	case *provider.DependencyCondition:
+		return provider.Capability{IncidentVariables: provider.DependencyIncidentVariables}.IncidentVariableNames()
+	case provider.ProviderCondition:
+		client, ok := r.ProviderNameToClient[c.ProviderName]
+		if !ok {
+			return nil, false
+		}
+		for _, capability := range client.Capabilities() {
+			if capability.Name == c.Capability {
+				return capability.IncidentVariableNames()
+			}
+		}
+	}
+	return nil, false
+}
+
+func (r *RuleParser) conditionsVariables(conditions []engine.ConditionEntry) (map[string]bool, bool) {
+	names := map[string]bool{}
+	for _, c := range conditions {
+		variables, ok := r.incidentVariables(c)
+		if !ok {
+			return nil, false
+		}
+		for name := range variables {
+			names[name] = true
+		}
+	}
+	return names, true
+}
+
+// conditionEntries returns the entries of the condition and of its nested
+// conditions
+func conditionEntries(c engine.Conditional) []engine.ConditionEntry {
+	entries := []engine.ConditionEntry{}
+	switch c := c.(type) {
+	case engine.ConditionEntry:
+		entries = append(entries, c)
+		entries = append(entries, conditionEntries(c.ProviderSpecificConfig)...)
+	case engine.AndCondition:
+		for _, entry := range c.Conditions {
+			entries = append(entries, conditionEntries(entry)...)
+		}
+	case engine.OrCondition:
+		for _, entry := range c.Conditions {
+			entries = append(entries, conditionEntries(entry)...)
+		}
+	}
+	return entries
+}
+
+// providerConditions returns the provider conditions of the condition and of
+// its nested conditions
+func providerConditions(c engine.Conditional) []provider.ProviderCondition {
+	conditions := []provider.ProviderCondition{}
+	if p, ok := c.(provider.ProviderCondition); ok {
+		return append(conditions, p)
+	}
+	for _, entry := range conditionEntries(c) {
+		if p, ok := entry.ProviderSpecificConfig.(provider.ProviderCondition); ok {
+			conditions = append(conditions, p)
+		}
+	}
+	return conditions
+}
+
+// templateNames returns the names the template looks up in its context. The
+// names in the sections are left out, they can be the fields of the items of
+// the section. A template that can not be parsed has no names, it fails when
+// it is rendered.
+func templateNames(template string) []string {
+	if !strings.Contains(template, "{{") {
+		return nil
+	}
+	tmpl, err := mustache.ParseString(template)
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, tag := range tmpl.Tags() {
+		switch tag.Type() {
+		case mustache.Variable, mustache.Section, mustache.InvertedSection:
+			if tag.Name() != "." {
+				names = append(names, tag.Name())
+			}
+		}
+	}
+	return names
+}
+
+func joinNames(names map[string]bool) string {
+	sorted := []string{}
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
@@ -0,0 +1,157 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/getkin/kin-openapi/openapi3"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateVariables(t *testing.T) {
+	providers := map[string]provider.InternalProviderClient{
+		"builtin": testProvider{
+			caps: []provider.Capability{
+				{
+					Name: "filecontent",
+					IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+						"matchingText": openapi3.NewStringSchema(),
+					}),
+				},
+				{
+					Name:              "file",
+					IncidentVariables: provider.NewIncidentVariablesSchema(nil),
+				},
+			},
+		},
+		"java": testProvider{
+			caps: []provider.Capability{
+				{Name: "referenced"},
+				{
+					Name: "inventory",
+					IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+						"class": openapi3.NewStringSchema(),
+					}),
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		rule      string
+		wantError string
+	}{
+		{
+			name: "declared variable",
+			rule: `
+- ruleID: rule-001
+  message: "found {{matchingText}} on line {{lineNumber}}"
+  when:
+    builtin.filecontent:
+      pattern: foo`,
+		},
+		{
+			name: "typo in the message",
+			rule: `
+- ruleID: rule-001
+  message: "found {{matchingtext}}"
+  when:
+    builtin.filecontent:
+      pattern: foo`,
+			wantError: "rule rule-001: the message uses the variable matchingtext that its incidents do not have, they have lineNumber, matchingText",
+		},
+		{
+			name: "variable of one of the conditions",
+			rule: `
+- ruleID: rule-001
+  message: "found {{matchingText}}"
+  when:
+    or:
+    - builtin.filecontent:
+        pattern: foo
+    - builtin.file:
+        pattern: "*.go"`,
+		},
+		{
+			name: "condition without declared variables",
+			rule: `
+- ruleID: rule-001
+  message: "found {{anything}}"
+  when:
+    or:
+    - builtin.filecontent:
+        pattern: foo
+    - java.referenced:
+        pattern: foo`,
+		},
+		{
+			name: "custom variable",
+			rule: `
+- ruleID: rule-001
+  message: "found {{name}}"
+  customVariables:
+  - name: name
+    pattern: "(?P<name>[a-z]+)"
+    nameOfCaptureGroup: name
+  when:
+    builtin.filecontent:
+      pattern: foo`,
+		},
+		{
+			name: "names in a section",
+			rule: `
+- ruleID: rule-001
+  message: "{{#matchingText}}{{length}}{{/matchingText}}"
+  when:
+    builtin.filecontent:
+      pattern: foo`,
+		},
+		{
+			name: "chained variable",
+			rule: `
+- ruleID: rule-001
+  message: found
+  when:
+    and:
+    - java.inventory:
+        kind: bean
+      as: beans
+    - builtin.filecontent:
+        pattern: "{{beans.regex.class}}"
+      from: beans`,
+		},
+		{
+			name: "typo in a chained variable",
+			rule: `
+- ruleID: rule-001
+  message: found
+  when:
+    and:
+    - java.inventory:
+        kind: bean
+      as: beans
+    - builtin.filecontent:
+        pattern: "{{beans.regex.klass}}"
+      from: beans`,
+			wantError: "rule rule-001: the condition builtin.filecontent uses the variable klass that the incidents of beans do not have, they have class",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleParser := ruleparser.RuleParser{
+				ProviderNameToClient: providers,
+				Log:                  logrusr.New(logrus.New()),
+			}
+			_, _, err := ruleParser.ParseRules([]byte(strings.TrimSpace(tt.rule)))
+			if tt.wantError == "" && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if tt.wantError != "" && (err == nil || err.Error() != tt.wantError) {
+				t.Errorf("expected error %q, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
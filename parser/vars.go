@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// varPattern matches the {{.vars.<name>}} of the condition parameters
+var varPattern = regexp.MustCompile(`\{\{\s*\.vars\.([^\s{}]*)\s*\}\}`)
+
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// substituteVars replaces the {{.vars.<name>}} in the strings of the
+// condition with the values of the variables, so that a rule can use the
+// values of the environment it is evaluated in, such as the package prefix of
+// the internal libraries. It fails on a variable that has no value.
+func (r *RuleParser) substituteVars(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+		substituted := varPattern.ReplaceAllStringFunc(v, func(match string) string {
+			name := varPattern.FindStringSubmatch(match)[1]
+			value, ok := r.Vars[name]
+			if !ok && err == nil {
+				err = r.undefinedVar(name)
+			}
+			return value
+		})
+		return substituted, err
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			substituted, err := r.substituteVars(item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = substituted
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			substituted, err := r.substituteVars(item)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = substituted
+		}
+		return l, nil
+	}
+	return value, nil
+}
+
+func (r *RuleParser) undefinedVar(name string) error {
+	if len(r.Vars) == 0 {
+		return fmt.Errorf("the condition uses the variable %s, but no variable is defined", name)
+	}
+	names := []string{}
+	for n := range r.Vars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("the condition uses the undefined variable %s, the variables are %s", name, strings.Join(names, ", "))
+}
+
+// LoadVars returns the variables of the vars file, a yaml map of their names
+// to their values, and of the name=value pairs, which override the ones of
+// the file. The file is not read when it is empty.
+func LoadVars(file string, pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("unable to read the vars file %s: %w", file, err)
+		}
+		for name, value := range values {
+			switch value.(type) {
+			case map[interface{}]interface{}, []interface{}, nil:
+				return nil, fmt.Errorf("the variable %s of the vars file %s must be a string, a number or a boolean", name, file)
+			}
+			vars[name] = fmt.Sprint(value)
+		}
+	}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid variable %q, it must be name=value", pair)
+		}
+		vars[name] = value
+	}
+	for name := range vars {
+		if !varNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %q, it must start with a letter or _ and only have letters, digits, _ and -", name)
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,118 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/engine"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSubstituteVars(t *testing.T) {
+	providers := map[string]provider.InternalProviderClient{
+		"java": testProvider{caps: []provider.Capability{{Name: "referenced"}}},
+	}
+	tests := []struct {
+		name      string
+		vars      map[string]string
+		rule      string
+		want      interface{}
+		wantError string
+	}{
+		{
+			name: "variables in the parameters",
+			vars: map[string]string{"prefix": "com.example.internal", "location": "IMPORT"},
+			rule: `
+- ruleID: rule-001
+  message: "found {{matchingText}}"
+  when:
+    java.referenced:
+      pattern: "{{.vars.prefix}}.*"
+      location: "{{ .vars.location }}"
+      filepaths:
+      - "{{.vars.prefix}}"`,
+			want: map[interface{}]interface{}{
+				"pattern":   "com.example.internal.*",
+				"location":  "IMPORT",
+				"filepaths": []interface{}{"com.example.internal"},
+			},
+		},
+		{
+			name: "undefined variable",
+			vars: map[string]string{"prefix": "com.example"},
+			rule: `
+- ruleID: rule-001
+  message: found
+  when:
+    java.referenced:
+      pattern: "{{.vars.prefx}}.*"`,
+			wantError: "rule rule-001: the condition uses the undefined variable prefx, the variables are prefix",
+		},
+		{
+			name: "no variables",
+			rule: `
+- ruleID: rule-001
+  message: found
+  when:
+    java.referenced:
+      pattern: "{{.vars.prefix}}.*"`,
+			wantError: "rule rule-001: the condition uses the variable prefix, but no variable is defined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleParser := ruleparser.RuleParser{
+				ProviderNameToClient: providers,
+				Log:                  logrusr.New(logrus.New()),
+				Vars:                 tt.vars,
+			}
+			rules, _, err := ruleParser.ParseRules([]byte(strings.TrimSpace(tt.rule)))
+			if tt.wantError != "" {
+				if err == nil || err.Error() != tt.wantError {
+					t.Errorf("expected error %q, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			when := rules[0].When
+			if entry, ok := when.(engine.ConditionEntry); ok {
+				when = entry.ProviderSpecificConfig
+			}
+			condition, ok := when.(provider.ProviderCondition)
+			if !ok {
+				t.Fatalf("expected a provider condition, got %T", when)
+			}
+			if !reflect.DeepEqual(condition.ConditionInfo, tt.want) {
+				t.Errorf("expected the parameters %v, got %v", tt.want, condition.ConditionInfo)
+			}
+		})
+	}
+}
+
+func TestLoadVars(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(file, []byte("prefix: com.example\nversion: 2.7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := ruleparser.LoadVars(file, []string{"prefix=org.example", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"prefix": "org.example", "version": "2.7", "empty": ""}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("expected the variables %v, got %v", want, vars)
+	}
+	for _, pairs := range [][]string{{"prefix"}, {"bad name=value"}} {
+		if _, err := ruleparser.LoadVars("", pairs); err == nil {
+			t.Errorf("expected the variables %v to be invalid", pairs)
+		}
+	}
+}
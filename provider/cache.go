@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+)
+
+// QueryCache keeps the responses of the providers by the query that was
+// evaluated, it is saved with the checkpoints of the rule engine.
+type QueryCache struct {
+	// hits and misses count the queries of the caching clients, they are
+	// first to be aligned for the atomic operations
+	hits   uint64
+	misses uint64
+
+	mutex     sync.RWMutex
+	responses map[string]ProviderEvaluateResponse
+}
+
+var _ engine.QueryCache = &QueryCache{}
+
+func NewQueryCache() *QueryCache {
+	return &QueryCache{
+		responses: map[string]ProviderEvaluateResponse{},
+	}
+}
+
+func queryCacheKey(providerName, cap string, conditionInfo []byte) string {
+	sum := sha256.Sum256(conditionInfo)
+	return fmt.Sprintf("%s/%s/%s", providerName, cap, hex.EncodeToString(sum[:]))
+}
+
+func (c *QueryCache) get(key string) (ProviderEvaluateResponse, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	resp, ok := c.responses[key]
+	return resp, ok
+}
+
+func (c *QueryCache) set(key string, resp ProviderEvaluateResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.responses[key] = resp
+}
+
+// Hits returns how many queries were answered from the cache
+func (c *QueryCache) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns how many queries were sent to the providers because they
+// were not in the cache
+func (c *QueryCache) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+func (c *QueryCache) Snapshot() ([]byte, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return json.Marshal(c.responses)
+}
+
+func (c *QueryCache) Restore(content []byte) error {
+	responses := map[string]ProviderEvaluateResponse{}
+	err := json.Unmarshal(content, &responses)
+	if err != nil {
+		return fmt.Errorf("unable to restore query cache: %w", err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for k, v := range responses {
+		c.responses[k] = v
+	}
+	return nil
+}
+
+type cachingClient struct {
+	InternalProviderClient
+	name  string
+	cache *QueryCache
+}
+
+// WithQueryCache returns a client that answers the queries that are in the cache
+// without calling the provider, and adds the responses of the provider to it.
+func WithQueryCache(name string, client InternalProviderClient, cache *QueryCache) InternalProviderClient {
+	return &cachingClient{
+		InternalProviderClient: client,
+		name:                   name,
+		cache:                  cache,
+	}
+}
+
+func (c *cachingClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	key := queryCacheKey(c.name, cap, conditionInfo)
+	if resp, ok := c.cache.get(key); ok {
+		atomic.AddUint64(&c.cache.hits, 1)
+		return resp, nil
+	}
+	atomic.AddUint64(&c.cache.misses, 1)
+	resp, err := c.InternalProviderClient.Evaluate(ctx, cap, conditionInfo)
+	if err != nil {
+		return resp, err
+	}
+	c.cache.set(key, resp)
+	return resp, nil
+}
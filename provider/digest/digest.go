@@ -0,0 +1,281 @@
+// Package digest computes a Merkle hash of the directories of the analyzed
+// locations, so that the subtrees that changed since a checkpoint can be
+// found without reading the files.
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider/vcs"
+	"go.lsp.dev/uri"
+)
+
+// Digest is the hash of a directory, from the names, sizes, modes and
+// modification times of its files and from the digests of its
+// subdirectories. The content of the files is not read, a file that is only
+// touched is changed.
+type Digest struct {
+	Hash string `json:"hash"`
+	// Files is the hash of the files directly in the directory
+	Files string `json:"files"`
+	// Dirs are the digests of the subdirectories by name
+	Dirs map[string]*Digest `json:"dirs,omitempty"`
+	// File is set for the digest of a location that is a file, such as a
+	// binary that is decompiled
+	File bool `json:"file,omitempty"`
+}
+
+// Compute returns the digest of the directory, the metadata directories of
+// the version control systems are left out.
+func Compute(dir string) (*Digest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	for _, s := range vcs.Systems() {
+		skip[s.Marker()] = true
+	}
+	d := &Digest{Dirs: map[string]*Digest{}}
+	files := sha256.New()
+	// the entries are sorted by name
+	for _, e := range entries {
+		if skip[e.Name()] {
+			continue
+		}
+		if e.IsDir() {
+			sub, err := Compute(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			d.Dirs[e.Name()] = sub
+			continue
+		}
+		info, err := e.Info()
+		if os.IsNotExist(err) {
+			// removed while walking
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(files, "%s\x00%d\x00%o\x00%d\n", e.Name(), info.Size(), info.Mode(), info.ModTime().UnixNano())
+	}
+	d.Files = hex.EncodeToString(files.Sum(nil))
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", d.Files)
+	names := make([]string, 0, len(d.Dirs))
+	for name := range d.Dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%s\n", name, d.Dirs[name].Hash)
+	}
+	d.Hash = hex.EncodeToString(hash.Sum(nil))
+	if len(d.Dirs) == 0 {
+		d.Dirs = nil
+	}
+	return d, nil
+}
+
+// Changes are the paths, relative to the directory, that changed between two
+// digests of a directory
+type Changes struct {
+	// Subtrees are the directories that were added or removed
+	Subtrees []string
+	// Dirs are the directories with files that were added, removed or
+	// modified, their subdirectories can be unchanged
+	Dirs []string
+}
+
+// Compare returns what changed from the previous digest to the current one,
+// the paths are relative and use "/", "." is the directory itself.
+func Compare(previous, current *Digest) Changes {
+	changes := Changes{}
+	compare(previous, current, ".", &changes)
+	return changes
+}
+
+func compare(previous, current *Digest, rel string, changes *Changes) {
+	switch {
+	case previous == nil || current == nil:
+		changes.Subtrees = append(changes.Subtrees, rel)
+		return
+	case previous.Hash == current.Hash:
+		return
+	case previous.Files != current.Files:
+		changes.Dirs = append(changes.Dirs, rel)
+	}
+	names := []string{}
+	for name := range previous.Dirs {
+		names = append(names, name)
+	}
+	for name := range current.Dirs {
+		if _, ok := previous.Dirs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		compare(previous.Dirs[name], current.Dirs[name], join(rel, name), changes)
+	}
+}
+
+func join(rel, name string) string {
+	if rel == "." {
+		return name
+	}
+	return rel + "/" + name
+}
+
+// Workspace is the digest of the analyzed locations, it is saved with the
+// checkpoints so that an incremental analysis only evaluates the rules again
+// on the files that changed since.
+type Workspace struct {
+	// Digests are the digests of the locations by absolute path
+	Digests map[string]*Digest `json:"digests"`
+}
+
+var _ engine.Workspace = &Workspace{}
+
+// NewWorkspace computes the digests of the locations
+func NewWorkspace(locations []string) (*Workspace, error) {
+	w := &Workspace{Digests: map[string]*Digest{}}
+	for _, location := range locations {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := w.Digests[abs]; ok {
+			continue
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%o\x00%d", info.Size(), info.Mode(), info.ModTime().UnixNano())))
+			w.Digests[abs] = &Digest{Hash: hex.EncodeToString(hash[:]), File: true}
+			continue
+		}
+		d, err := Compute(abs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute the digest of %s: %w", location, err)
+		}
+		w.Digests[abs] = d
+	}
+	return w, nil
+}
+
+func (w *Workspace) Snapshot() ([]byte, error) {
+	return json.Marshal(w)
+}
+
+// Changes compares the workspace with a snapshot, the locations that are not
+// in both are changed.
+func (w *Workspace) Changes(snapshot []byte) (engine.WorkspaceChanges, error) {
+	previous := &Workspace{}
+	if err := json.Unmarshal(snapshot, previous); err != nil {
+		return nil, fmt.Errorf("unable to read the workspace digest: %w", err)
+	}
+	c := &changes{}
+	for location, d := range w.Digests {
+		p, ok := previous.Digests[location]
+		if !ok || p.File != d.File {
+			c.subtrees = append(c.subtrees, location)
+			c.includes = append(c.includes, "")
+			continue
+		}
+		if d.File {
+			if p.Hash != d.Hash {
+				c.subtrees = append(c.subtrees, location)
+				c.includes = append(c.includes, "")
+			}
+			continue
+		}
+		locationChanges := Compare(p, d)
+		for _, rel := range locationChanges.Subtrees {
+			c.subtrees = append(c.subtrees, filepath.Join(location, filepath.FromSlash(rel)))
+			c.includes = append(c.includes, include(rel, ""))
+		}
+		for _, rel := range locationChanges.Dirs {
+			c.dirs = append(c.dirs, filepath.Join(location, filepath.FromSlash(rel)))
+			c.includes = append(c.includes, include(rel, "/*"))
+		}
+	}
+	for location := range previous.Digests {
+		if _, ok := w.Digests[location]; !ok {
+			// only to drop its results
+			c.subtrees = append(c.subtrees, location)
+		}
+	}
+	if len(c.subtrees) == 0 && len(c.dirs) == 0 {
+		return nil, nil
+	}
+	sort.Strings(c.subtrees)
+	sort.Strings(c.dirs)
+	return c, nil
+}
+
+// include is the glob of a changed path, "" when it is the whole location
+func include(rel string, suffix string) string {
+	if rel == "." {
+		if suffix == "" {
+			return ""
+		}
+		return "/" + strings.TrimPrefix(suffix, "/")
+	}
+	return "/" + rel + suffix
+}
+
+type changes struct {
+	subtrees []string
+	dirs     []string
+	includes []string
+}
+
+func (c *changes) Paths() []string {
+	return append(append([]string{}, c.subtrees...), c.dirs...)
+}
+
+func (c *changes) Scope() *engine.Scope {
+	for _, i := range c.includes {
+		// a whole location changed
+		if i == "" || i == "/*" {
+			return nil
+		}
+	}
+	if len(c.includes) == 0 {
+		return nil
+	}
+	return &engine.Scope{Include: c.includes}
+}
+
+func (c *changes) Contains(u uri.URI) bool {
+	if !strings.HasPrefix(string(u), uri.FileScheme) {
+		return false
+	}
+	path := u.Filename()
+	for _, s := range c.subtrees {
+		if path == s || strings.HasPrefix(path, s+string(filepath.Separator)) {
+			return true
+		}
+	}
+	dir := filepath.Dir(path)
+	for _, d := range c.dirs {
+		if dir == d {
+			return true
+		}
+	}
+	return false
+}
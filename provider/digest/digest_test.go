@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"pom.xml":                    "<project/>",
+		"src/main/java/App.java":     "class App {}",
+		"src/main/java/lib/Lib.java": "class Lib {}",
+		"src/test/AppTest.java":      "class AppTest {}",
+		"docs/README.md":             "app",
+		".git/HEAD":                  "ref: refs/heads/main",
+	})
+	previous, err := Compute(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Compute(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(previous, again) {
+		t.Fatalf("expected the digest of an unchanged directory to be the same")
+	}
+
+	writeFiles(t, root, map[string]string{
+		"src/main/java/App.java": "class App { }",
+		"src/other/Other.java":   "class Other {}",
+		".git/HEAD":              "ref: refs/heads/other",
+	})
+	if err := os.RemoveAll(filepath.Join(root, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	current, err := Compute(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Changes{
+		Subtrees: []string{"docs", "src/other"},
+		Dirs:     []string{"src/main/java"},
+	}
+	if got := Compare(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected changes %+v, got %+v", want, got)
+	}
+}
+
+func TestWorkspaceChanges(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"pom.xml":                    "<project/>",
+		"src/main/java/App.java":     "class App {}",
+		"src/main/java/lib/Lib.java": "class Lib {}",
+	})
+	previous, err := NewWorkspace([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := previous.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := previous.Changes(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != nil {
+		t.Fatalf("expected no changes, got %v", changes.Paths())
+	}
+
+	writeFiles(t, root, map[string]string{"src/main/java/App.java": "class App { }"})
+	current, err := NewWorkspace([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err = current.Changes(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes == nil {
+		t.Fatalf("expected the changes to be found")
+	}
+	if scope := changes.Scope(); scope == nil || !reflect.DeepEqual(scope.Include, []string{"/src/main/java/*"}) {
+		t.Errorf("expected the scope of the changed directory, got %+v", scope)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "src/main/java/App.java", want: true},
+		{path: "src/main/java/lib/Lib.java"},
+		{path: "pom.xml"},
+	}
+	for _, tt := range tests {
+		if got := changes.Contains(uri.File(filepath.Join(root, tt.path))); got != tt.want {
+			t.Errorf("expected %s to be changed %v, got %v", tt.path, tt.want, got)
+		}
+	}
+
+	writeFiles(t, root, map[string]string{"pom.xml": "<project></project>"})
+	current, err = NewWorkspace([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err = current.Changes(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope := changes.Scope(); scope != nil {
+		t.Errorf("expected no scope when the files of the location changed, got %+v", scope)
+	}
+	if !changes.Contains(uri.File(filepath.Join(root, "pom.xml"))) || changes.Contains(uri.File(filepath.Join(root, "src", "main", "java", "lib", "Lib.java"))) {
+		t.Errorf("expected only the changed files to be changed")
+	}
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
+)
+
+// FileChangeType is how a watched file changed, with the values of the
+// language server protocol
+type FileChangeType int
+
+const (
+	FileCreated FileChangeType = 1
+	FileChanged FileChangeType = 2
+	FileDeleted FileChangeType = 3
+)
+
+func (t FileChangeType) String() string {
+	switch t {
+	case FileCreated:
+		return "created"
+	case FileChanged:
+		return "changed"
+	case FileDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// FileChange is a file of the analyzed locations that changed while the
+// providers were running
+type FileChange struct {
+	URI  uri.URI
+	Type FileChangeType
+}
+
+// FileWatcher is implemented by the providers that keep state about the
+// analyzed files, such as the index of a language server, so that they are
+// told when the files change instead of answering from stale state. It is
+// called between two evaluations of the rules.
+type FileWatcher interface {
+	DidChangeWatchedFiles(ctx context.Context, changes []FileChange) error
+}
+
+// NotifyFileChanges tells the providers that watch the files about the
+// changes, the providers that fail to handle them are logged and skipped.
+func NotifyFileChanges(ctx context.Context, log logr.Logger, providers map[string]InternalProviderClient, changes []FileChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for name, p := range providers {
+		w, ok := p.(FileWatcher)
+		if !ok {
+			continue
+		}
+		if err := w.DidChangeWatchedFiles(ctx, changes); err != nil {
+			log.Error(err, "unable to notify the provider of the changed files", "provider", name)
+		}
+	}
+}
@@ -15,7 +15,10 @@ import (
 	"github.com/phayes/freeport"
 	"go.lsp.dev/uri"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -26,12 +29,17 @@ type grpcProvider struct {
 	ctx    context.Context
 	conn   *grpc.ClientConn
 	config provider.Config
+	// process is the provider binary when it was started by the analyzer
+	process *provider.Process
 
 	serviceClients []provider.ServiceClient
 }
 
 var _ provider.InternalProviderClient = &grpcProvider{}
 var _ provider.Startable = &grpcProvider{}
+var _ provider.HealthChecker = &grpcProvider{}
+var _ provider.Suspendable = &grpcProvider{}
+var _ provider.StatsReporter = &grpcProvider{}
 
 func NewGRPCClient(config provider.Config, log logr.Logger) *grpcProvider {
 	log = log.WithName(config.Name)
@@ -68,13 +76,19 @@ func (g *grpcProvider) Capabilities() []provider.Capability {
 			Name: x.Name,
 			//TemplateContext: x.TemplateContext.AsMap(),
 		}
+		v.IncidentVariables, err = provider.SchemaFromStruct(x.IncidentVariables)
+		if err != nil {
+			// the variables of the incidents are not validated
+			g.log.V(5).Error(err, "invalid incident variables", "capability", x.Name)
+		}
 		c = append(c, v)
 	}
 	return c
 }
 
 func (g *grpcProvider) Init(ctx context.Context, log logr.Logger, config provider.InitConfig) (provider.ServiceClient, error) {
-	s, err := structpb.NewStruct(config.ProviderSpecificConfig)
+	providerSpecificConfig, _ := stringKeys(config.ProviderSpecificConfig).(map[string]interface{})
+	s, err := structpb.NewStruct(providerSpecificConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -82,13 +96,17 @@ func (g *grpcProvider) Init(ctx context.Context, log logr.Logger, config provide
 	c := pb.Config{
 		Location:               config.Location,
 		DependencyPath:         config.DependencyPath,
+		WorkspaceFolders:       config.WorkspaceFolders,
 		AnalysisMode:           string(config.AnalysisMode),
 		ProviderSpecificConfig: s,
-		Proxy: &pb.Proxy{
+		Proxy:                  &pb.Proxy{},
+	}
+	if config.Proxy != nil {
+		c.Proxy = &pb.Proxy{
 			HTTPProxy:  config.Proxy.HTTPProxy,
 			HTTPSProxy: config.Proxy.HTTPSProxy,
 			NoProxy:    config.Proxy.NoProxy,
-		},
+		}
 	}
 
 	r, err := g.Client.Init(ctx, &c)
@@ -107,6 +125,9 @@ func (g *grpcProvider) Init(ctx context.Context, log logr.Logger, config provide
 }
 
 func (g *grpcProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	if err := g.process.Exited(); err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
 	return provider.FullResponseFromServiceClients(ctx, g.serviceClients, cap, conditionInfo)
 }
 
@@ -118,6 +139,62 @@ func (g *grpcProvider) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]pr
 	return provider.FullDepDAGResponse(ctx, g.serviceClients)
 }
 
+// HealthCheck fails once the provider binary started by the analyzer stopped,
+// for instance because it exceeded its resource limits, or when the gRPC
+// health service of the provider reports that it is not serving. The
+// providers that do not have the health service are healthy while they run.
+func (g *grpcProvider) HealthCheck(ctx context.Context) error {
+	if err := g.process.Exited(); err != nil {
+		return err
+	}
+	if g.conn == nil {
+		return nil
+	}
+	r, err := healthpb.NewHealthClient(g.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("provider %s is %s", g.config.Name, r.Status)
+	}
+	return nil
+}
+
+// Stats returns the statistics of the evaluations of the provider, added up
+// over its service clients
+func (g *grpcProvider) Stats(ctx context.Context) ([]provider.CapabilityStats, error) {
+	stats := [][]provider.CapabilityStats{}
+	for _, c := range g.serviceClients {
+		s, ok := c.(*grpcServiceClient)
+		if !ok {
+			continue
+		}
+		clientStats, err := s.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, clientStats)
+	}
+	return provider.MergeStats(stats...), nil
+}
+
+// Suspend stops the provider binary, the providers given by address are not
+// run by the analyzer and can not be suspended
+func (g *grpcProvider) Suspend() error {
+	if g.process == nil {
+		return fmt.Errorf("provider %s was not started by the analyzer", g.config.Name)
+	}
+	return g.process.Suspend()
+}
+
+// Continue runs the provider binary again after Suspend
+func (g *grpcProvider) Continue() error {
+	return g.process.Continue()
+}
+
 func (g *grpcProvider) Stop() {
 	for _, c := range g.serviceClients {
 		c.Stop()
@@ -141,7 +218,7 @@ func (g *grpcProvider) Start(ctx context.Context) error {
 		}
 		go g.LogProviderOut(ctx, out)
 
-		err = cmd.Start()
+		g.process, err = provider.StartProcess(fmt.Sprintf("provider %s", g.config.Name), cmd, g.config.ResourceLimits)
 		if err != nil {
 			return err
 		}
@@ -157,6 +234,9 @@ func (g *grpcProvider) Start(ctx context.Context) error {
 		for {
 			select {
 			default:
+				if err := g.process.Exited(); err != nil {
+					return err
+				}
 				caps := g.Capabilities()
 				if len(caps) != 0 {
 					return nil
@@ -189,3 +269,30 @@ func (g *grpcProvider) LogProviderOut(ctx context.Context, out io.ReadCloser) {
 		g.log.V(3).Info(scan.Text())
 	}
 }
+
+// stringKeys converts the maps decoded from the yaml provider settings, which
+// have interface keys, to maps with string keys that can be sent to the
+// provider.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[fmt.Sprintf("%v", key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[key] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = stringKeys(item)
+		}
+		return items
+	}
+	return value
+}
@@ -3,10 +3,13 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/konveyor/analyzer-lsp/provider"
 	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
 	"go.lsp.dev/uri"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServiceClient struct {
@@ -42,8 +45,9 @@ func (g *grpcServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 	incs := []provider.IncidentContext{}
 	for _, i := range r.Response.IncidentContexts {
 		inc := provider.IncidentContext{
-			FileURI:   uri.URI(i.FileURI),
-			Variables: i.GetVariables().AsMap(),
+			FileURI:    uri.URI(i.FileURI),
+			Variables:  i.GetVariables().AsMap(),
+			Confidence: i.Confidence,
 		}
 		if i.LineNumber != nil {
 			lineNumber := int(*i.LineNumber)
@@ -109,6 +113,7 @@ func (g *grpcServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]
 				ResolvedIdentifier: d.ResolvedIdentifier,
 				Extras:             d.Extras.AsMap(),
 				Labels:             d.Labels,
+				License:            d.License,
 			})
 		}
 		provs[u] = deps
@@ -131,6 +136,7 @@ func recreateDAGAddedItems(items []*pb.DependencyDAGItem) []provider.DepDAGItem
 				ResolvedIdentifier: x.Key.ResolvedIdentifier,
 				Extras:             x.Key.Extras.AsMap(),
 				Labels:             x.Key.Labels,
+				License:            x.Key.License,
 			},
 			AddedDeps: recreateDAGAddedItems(x.AddedDeps),
 		})
@@ -161,6 +167,32 @@ func (g *grpcServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI
 
 }
 
+// Stats returns the statistics of the evaluations of the client, none when
+// the provider does not have the Stats method
+func (g *grpcServiceClient) Stats(ctx context.Context) ([]provider.CapabilityStats, error) {
+	r, err := g.client.Stats(ctx, &pb.ServiceRequest{Id: g.id})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.Successful {
+		return nil, fmt.Errorf(r.Error)
+	}
+	stats := []provider.CapabilityStats{}
+	for _, s := range r.Stats {
+		stats = append(stats, provider.CapabilityStats{
+			Capability:  s.Capability,
+			Evaluations: s.Evaluations,
+			Errors:      s.Errors,
+			MeanLatency: time.Duration(s.MeanLatencySeconds * float64(time.Second)),
+			CacheHits:   s.CacheHits,
+		})
+	}
+	return stats, nil
+}
+
 func (g *grpcServiceClient) Stop() {
 	g.client.Stop(context.TODO(), &pb.ServiceRequest{Id: g.id})
 }
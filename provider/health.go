@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+)
+
+// HealthChecker is implemented by the clients that can tell whether they are
+// still responding, for instance by sending a cheap request to a language server.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Restartable is implemented by the providers that can be restarted when they
+// stop responding.
+type Restartable interface {
+	Restart(ctx context.Context) error
+}
+
+// Suspendable is implemented by the providers that run processes of their
+// own, which can be suspended so that they do not use resources while an
+// analysis is paused.
+type Suspendable interface {
+	Suspend() error
+	Continue() error
+}
+
+// FullHealthCheck checks all the clients that can be checked
+func FullHealthCheck(ctx context.Context, clients []ServiceClient) error {
+	for _, c := range clients {
+		h, ok := c.(HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := h.HealthCheck(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealthMonitor periodically checks the health of the providers. A provider
+// that misses the given number of consecutive checks is restarted when it can
+// be, otherwise it is marked as failed and onFailure is called.
+type HealthMonitor struct {
+	providers   map[string]InternalProviderClient
+	interval    time.Duration
+	maxFailures int
+	onFailure   func(name string, err error)
+	log         logr.Logger
+
+	mutex  sync.RWMutex
+	status map[string]engine.HealthStatus
+}
+
+var _ engine.HealthReporter = &HealthMonitor{}
+
+// NewHealthMonitor creates a monitor for the providers, the map can be filled
+// in until the monitor is started.
+func NewHealthMonitor(providers map[string]InternalProviderClient, interval time.Duration, maxFailures int, onFailure func(name string, err error), log logr.Logger) *HealthMonitor {
+	return &HealthMonitor{
+		providers:   providers,
+		interval:    interval,
+		maxFailures: maxFailures,
+		onFailure:   onFailure,
+		log:         log.WithName("health-monitor"),
+		status:      map[string]engine.HealthStatus{},
+	}
+}
+
+// Start checks the providers every interval until the context is done.
+func (m *HealthMonitor) Start(ctx context.Context) {
+	m.mutex.Lock()
+	for name := range m.providers {
+		m.status[name] = engine.HealthStatus{Name: name, Healthy: true}
+	}
+	m.mutex.Unlock()
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.checkAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (m *HealthMonitor) checkAll(ctx context.Context) {
+	names := []string{}
+	for name := range m.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.check(ctx, name)
+	}
+}
+
+func (m *HealthMonitor) check(ctx context.Context, name string) {
+	h, ok := m.providers[name].(HealthChecker)
+	if !ok {
+		return
+	}
+	m.mutex.RLock()
+	status, ok := m.status[name]
+	m.mutex.RUnlock()
+	if !ok {
+		status = engine.HealthStatus{Name: name, Healthy: true}
+	}
+	if status.Failed {
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, m.interval)
+	err := h.HealthCheck(checkCtx)
+	cancel()
+	status.LastCheck = time.Now()
+	if err == nil {
+		status.Healthy = true
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+		m.setStatus(status)
+		return
+	}
+
+	status.Healthy = false
+	status.ConsecutiveFailures++
+	status.LastError = err.Error()
+	m.log.Info("provider health check failed", "provider", name, "failures", status.ConsecutiveFailures, "error", err)
+	// a provider that exceeded its resource limits would exceed them again
+	limitExceeded := IsResourceLimitError(err)
+	if status.ConsecutiveFailures < m.maxFailures && !limitExceeded {
+		m.setStatus(status)
+		return
+	}
+
+	if !limitExceeded {
+		err = fmt.Errorf("provider failed %d consecutive health checks: %w", status.ConsecutiveFailures, err)
+	}
+	if r, ok := m.providers[name].(Restartable); ok && !limitExceeded {
+		m.log.Info("restarting provider", "provider", name)
+		restartErr := r.Restart(ctx)
+		if restartErr == nil {
+			status.Restarts++
+			status.ConsecutiveFailures = 0
+			m.setStatus(status)
+			return
+		}
+		err = fmt.Errorf("unable to restart provider after %d failed health checks: %w", status.ConsecutiveFailures, restartErr)
+	}
+	status.Failed = true
+	status.LastError = err.Error()
+	m.setStatus(status)
+	m.log.Error(err, "provider failed", "provider", name)
+	if m.onFailure != nil {
+		m.onFailure(name, err)
+	}
+}
+
+func (m *HealthMonitor) setStatus(status engine.HealthStatus) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.status[status.Name] = status
+}
+
+// Health returns the status of all the providers sorted by name
+func (m *HealthMonitor) Health() []engine.HealthStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	statuses := []engine.HealthStatus{}
+	for _, s := range m.status {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
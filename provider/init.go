@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrDependencyFailed is wrapped by the init errors of the providers that are
+// not initialized because a provider they depend on failed to
+var ErrDependencyFailed = errors.New("a provider it depends on failed to initialize")
+
+// InitError has the errors of the providers that failed to initialize, by
+// the name of the provider
+type InitError struct {
+	Errors map[string]error
+}
+
+func (e *InitError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("unable to init the provider %s: %v", name, e.Errors[name]))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// DependsOn returns the providers each provider depends on, from the
+// dependsOn of their configs
+func DependsOn(configs []Config) map[string][]string {
+	dependsOn := map[string][]string{}
+	for _, c := range configs {
+		if len(c.DependsOn) > 0 {
+			dependsOn[c.Name] = c.DependsOn
+		}
+	}
+	return dependsOn
+}
+
+// WithDependencies returns the providers of needed and the providers of all
+// that they depend on, directly or not, so that they are initialized with
+// them
+func WithDependencies(needed map[string]InternalProviderClient, all map[string]InternalProviderClient, dependsOn map[string][]string) map[string]InternalProviderClient {
+	providers := map[string]InternalProviderClient{}
+	var add func(name string, prov InternalProviderClient)
+	add = func(name string, prov InternalProviderClient) {
+		if _, ok := providers[name]; ok {
+			return
+		}
+		providers[name] = prov
+		for _, dep := range dependsOn[name] {
+			if p, ok := all[dep]; ok {
+				add(dep, p)
+			}
+		}
+	}
+	for name, prov := range needed {
+		add(name, prov)
+	}
+	return providers
+}
+
+// InitProviders initializes the providers, each one once the providers it
+// depends on are initialized, and the ones that do not depend on each other
+// at the same time. The providers it depends on that are not in providers
+// are expected to be initialized already. The providers that depend on one
+// that fails are not initialized, the error is an *InitError with the error
+// of each provider that is not initialized.
+func InitProviders(ctx context.Context, providers map[string]InternalProviderClient, dependsOn map[string][]string) error {
+	if err := checkDependencyCycles(dependsOn); err != nil {
+		return err
+	}
+	done := map[string]chan struct{}{}
+	for name := range providers {
+		done[name] = make(chan struct{})
+	}
+	mu := sync.Mutex{}
+	errs := map[string]error{}
+	failed := func(name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		_, ok := errs[name]
+		return ok
+	}
+	wg := sync.WaitGroup{}
+	for name, prov := range providers {
+		wg.Add(1)
+		go func(name string, prov InternalProviderClient) {
+			defer wg.Done()
+			defer close(done[name])
+			err := func() error {
+				for _, dep := range dependsOn[name] {
+					depDone, ok := done[dep]
+					if !ok {
+						continue
+					}
+					select {
+					case <-depDone:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+					if failed(dep) {
+						return fmt.Errorf("%w: %s", ErrDependencyFailed, dep)
+					}
+				}
+				return prov.ProviderInit(ctx)
+			}()
+			if err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}
+		}(name, prov)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return &InitError{Errors: errs}
+	}
+	return nil
+}
+
+// validateDependsOn checks that the providers depend on providers that are
+// configured and that they do not depend on each other
+func validateDependsOn(configs []Config) error {
+	names := map[string]bool{}
+	for _, c := range configs {
+		names[c.Name] = true
+	}
+	for _, c := range configs {
+		for _, dep := range c.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("provider %s depends on the provider %s that is not configured", c.Name, dep)
+			}
+		}
+	}
+	return checkDependencyCycles(DependsOn(configs))
+}
+
+// checkDependencyCycles returns an error with the providers of a cycle when
+// providers depend on each other
+func checkDependencyCycles(dependsOn map[string][]string) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	path := []string{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("the providers depend on each other: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependsOn[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	names := make([]string, 0, len(dependsOn))
+	for name := range dependsOn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeInitProvider struct {
+	fakeClient
+	name    string
+	err     error
+	mu      *sync.Mutex
+	order   *[]string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *fakeInitProvider) ProviderInit(context.Context) error {
+	if p.started != nil {
+		close(p.started)
+	}
+	if p.release != nil {
+		<-p.release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*p.order = append(*p.order, p.name)
+	return p.err
+}
+
+func TestInitProviders(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mu := &sync.Mutex{}
+	order := []string{}
+	newProvider := func(name string, err error) *fakeInitProvider {
+		return &fakeInitProvider{name: name, err: err, mu: mu, order: &order}
+	}
+
+	// the independent providers are initialized at the same time, java and
+	// go are only released once both started, while maven waits for java
+	javaProv, goProv := newProvider("java", nil), newProvider("go", nil)
+	javaProv.started, goProv.started = make(chan struct{}), make(chan struct{})
+	javaProv.release, goProv.release = make(chan struct{}), make(chan struct{})
+	go func() {
+		<-javaProv.started
+		<-goProv.started
+		close(goProv.release)
+		close(javaProv.release)
+	}()
+	providers := map[string]InternalProviderClient{
+		"java":  javaProv,
+		"go":    goProv,
+		"maven": newProvider("maven", nil),
+	}
+	if err := InitProviders(ctx, providers, map[string][]string{"maven": {"java"}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	index := map[string]int{}
+	for i, name := range order {
+		index[name] = i
+	}
+	if len(order) != 3 || index["maven"] < index["java"] {
+		t.Errorf("expected maven to be initialized after java, got %v", order)
+	}
+
+	order = []string{}
+	errFailed := errors.New("no java home")
+	providers = map[string]InternalProviderClient{
+		"java":    newProvider("java", errFailed),
+		"maven":   newProvider("maven", nil),
+		"gradle":  newProvider("gradle", nil),
+		"builtin": newProvider("builtin", nil),
+	}
+	err := InitProviders(ctx, providers, map[string][]string{"maven": {"java"}, "gradle": {"maven"}})
+	var initErr *InitError
+	if !errors.As(err, &initErr) {
+		t.Fatalf("expected an init error, got %v", err)
+	}
+	if !errors.Is(initErr.Errors["java"], errFailed) {
+		t.Errorf("expected the error of java, got %v", initErr.Errors["java"])
+	}
+	for _, name := range []string{"maven", "gradle"} {
+		if !errors.Is(initErr.Errors[name], ErrDependencyFailed) {
+			t.Errorf("expected %s to fail with its dependency, got %v", name, initErr.Errors[name])
+		}
+	}
+	if _, ok := initErr.Errors["builtin"]; ok {
+		t.Errorf("expected builtin to be initialized, got %v", initErr.Errors["builtin"])
+	}
+	if len(order) != 2 {
+		t.Errorf("expected only java and builtin to be initialized, got %v", order)
+	}
+	want := fmt.Sprintf("unable to init the provider gradle: %v: maven; unable to init the provider java: no java home; unable to init the provider maven: %v: java", ErrDependencyFailed, ErrDependencyFailed)
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestValidateDependsOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []Config
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			configs: []Config{{Name: "java"}, {Name: "maven", DependsOn: []string{"java", "builtin"}}},
+		},
+		{
+			name:    "unknown provider",
+			configs: []Config{{Name: "maven", DependsOn: []string{"java"}}},
+			wantErr: "provider maven depends on the provider java that is not configured",
+		},
+		{
+			name:    "cycle",
+			configs: []Config{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"c"}}, {Name: "c", DependsOn: []string{"b"}}},
+			wantErr: "the providers depend on each other: b -> c -> b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PrepareConfigs(tt.configs)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("expected the error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package builtin
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+const (
+	// ARCHIVE_DEPTH_INIT_OPTION is how many levels of archives the file,
+	// filecontent, xml and json conditions look into, 1 searches the files
+	// of the archives in the location, 2 the ones of the archives they
+	// contain as well, such as the jars of the wars of an ear. 0, the
+	// default, does not open the archives.
+	ARCHIVE_DEPTH_INIT_OPTION = "archiveDepth"
+
+	// archiveScheme is the scheme of the URIs of the files in archives,
+	// jar:file:///app.ear!/app.war!/WEB-INF/web.xml
+	archiveScheme = "jar:"
+	// archiveSeparator separates the archive from its entry in the URIs
+	archiveSeparator = "!/"
+	// maxNestedArchiveSize bounds the archives inside archives, which are
+	// read in memory to be opened
+	maxNestedArchiveSize = 256 << 20
+	// binaryCheckSize is how much of a file is checked for a NUL byte to not
+	// search the binary files, as grep does
+	binaryCheckSize = 8000
+)
+
+// archivePattern matches the names of the archives that are opened
+const archivePattern = `(?i)\.(jar|war|ear|rar|sar|zip)$`
+
+var archiveRegex = regexp.MustCompile(archivePattern)
+
+// archiveDepth returns the archiveDepth of the init config, which is a
+// float when it comes from json
+func archiveDepth(config provider.InitConfig) int {
+	switch depth := config.ProviderSpecificConfig[ARCHIVE_DEPTH_INIT_OPTION].(type) {
+	case int:
+		return depth
+	case float64:
+		return int(depth)
+	}
+	return 0
+}
+
+// archiveURI returns the URI of a file in an archive, the entries are the
+// paths of the nested archives down to the file
+func archiveURI(archive string, entries []string) uri.URI {
+	return uri.URI(archiveScheme + string(uri.File(archive)) + archiveSeparator + strings.Join(entries, archiveSeparator))
+}
+
+// parseArchiveURI returns the archive on disk and the entries of the URI of
+// a file in an archive
+func parseArchiveURI(u string) (string, []string, bool) {
+	if !strings.HasPrefix(u, archiveScheme) {
+		return "", nil, false
+	}
+	parts := strings.Split(strings.TrimPrefix(u, archiveScheme), archiveSeparator)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], uri.FileScheme) {
+		return "", nil, false
+	}
+	return uri.URI(parts[0]).Filename(), parts[1:], true
+}
+
+// archiveFile is a file in an archive
+type archiveFile struct {
+	uri uri.URI
+	// name is the path of the file in its innermost archive
+	name string
+	file *zip.File
+}
+
+func (f archiveFile) read() ([]byte, error) {
+	rc, err := f.file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// walkArchive calls fn with the files of the archive, and with the ones of
+// the archives it contains until depth levels of archives are opened
+func walkArchive(archive string, r *zip.Reader, entries []string, depth int, warnings *provider.Warnings, fn func(archiveFile) error) error {
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		fileEntries := append(append([]string{}, entries...), f.Name)
+		u := archiveURI(archive, fileEntries)
+		if err := fn(archiveFile{uri: u, name: f.Name, file: f}); err != nil {
+			return err
+		}
+		if depth <= 1 || !archiveRegex.MatchString(f.Name) {
+			continue
+		}
+		if f.UncompressedSize64 > maxNestedArchiveSize {
+			warnings.Warn("nested archives too large to open", string(u))
+			continue
+		}
+		content, err := archiveFile{file: f}.read()
+		if err != nil {
+			warnings.Warn("archives failed to open", string(u))
+			continue
+		}
+		nested, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			warnings.Warn("archives failed to open", string(u))
+			continue
+		}
+		if err := walkArchive(archive, nested, fileEntries, depth-1, warnings, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkArchives calls fn with the files of the archives in the roots that are
+// in scope, down to the archiveDepth of the config
+func (p *builtinServiceClient) walkArchives(scope *engine.Scope, fn func(archiveFile) error) error {
+	depth := archiveDepth(p.config)
+	if depth <= 0 {
+		return nil
+	}
+	for _, root := range p.config.WalkRoots() {
+		archives, err := findFilesMatchingPattern(p.config, root, archivePattern, scope)
+		if err != nil {
+			return fmt.Errorf("unable to find the archives in %s: %w", root, err)
+		}
+		for _, archive := range archives {
+			if abs, err := filepath.Abs(archive); err == nil {
+				archive = abs
+			}
+			r, err := zip.OpenReader(archive)
+			if err != nil {
+				p.warnings.Warn("archives failed to open", archive)
+				continue
+			}
+			err = walkArchive(archive, &r.Reader, nil, depth, p.warnings, fn)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readArchiveURI returns the content of the file of the URI of a file in an
+// archive
+func readArchiveURI(u string) ([]byte, error) {
+	archive, entries, ok := parseArchiveURI(u)
+	if !ok {
+		return nil, fmt.Errorf("invalid archive URI %s", u)
+	}
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	reader := &r.Reader
+	for i, entry := range entries {
+		var found *zip.File
+		for _, f := range reader.File {
+			if f.Name == entry {
+				found = f
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%s is not in the archive %s", entry, u)
+		}
+		content, err := archiveFile{file: found}.read()
+		if err != nil || i == len(entries)-1 {
+			return content, err
+		}
+		reader, err = zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("invalid archive URI %s", u)
+}
+
+// isBinary returns whether the content has a NUL byte in its start
+func isBinary(content []byte) bool {
+	if len(content) > binaryCheckSize {
+		content = content[:binaryCheckSize]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// nameMatcher returns whether the name of a file matches a pattern, as a
+// regex or as a glob when the pattern is not a valid regex
+func nameMatcher(pattern string) func(name string) bool {
+	if regex, err := regexp.Compile(pattern); err == nil {
+		return regex.MatchString
+	}
+	return func(name string) bool {
+		matched, _ := filepath.Match(pattern, name)
+		return matched
+	}
+}
+
+// archiveFileMatcher returns whether a file in an archive is one of the files
+// of the filepaths of an xml or json condition, either one of their URIs, as
+// they are given by a chained condition, or one whose name matches one of
+// them. The files whose name matches the default patterns are matched when
+// there are no filepaths.
+func archiveFileMatcher(filepaths []string, defaultPatterns ...string) func(archiveFile) bool {
+	// the rendered lists are space separated
+	if len(filepaths) == 1 {
+		filepaths = strings.Split(filepaths[0], " ")
+	}
+	patterns := filepaths
+	if len(filepaths) == 0 {
+		patterns = defaultPatterns
+	}
+	uris := map[string]bool{}
+	matchers := []func(string) bool{}
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, archiveScheme) {
+			uris[pattern] = true
+		} else {
+			matchers = append(matchers, nameMatcher(pattern))
+		}
+	}
+	return func(f archiveFile) bool {
+		if uris[string(f.uri)] {
+			return true
+		}
+		for _, match := range matchers {
+			if match(path.Base(f.name)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// archiveSnip returns the lines around the location in a file in an archive
+func archiveSnip(u uri.URI, loc engine.Location, contextLines int) (string, error) {
+	content, err := readArchiveURI(string(u))
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	codeSnip := ""
+	paddingSize := len(strconv.Itoa(loc.EndPosition.Line + contextLines))
+	for scanner.Scan() {
+		if (lineNumber - contextLines) == loc.EndPosition.Line {
+			codeSnip = codeSnip + fmt.Sprintf("%*d  %v", paddingSize, lineNumber+1, scanner.Text())
+			break
+		}
+		if (lineNumber + contextLines) >= loc.StartPosition.Line {
+			codeSnip = codeSnip + fmt.Sprintf("%*d  %v\n", paddingSize, lineNumber+1, scanner.Text())
+		}
+		lineNumber += 1
+	}
+	return codeSnip, nil
+}
+
+// archiveContentIncidents returns the incidents of a filecontent condition in
+// the text files of the archives. The pattern is matched with the go regexp
+// syntax, the patterns that only grep supports are not searched in archives.
+func (p *builtinServiceClient) archiveContentIncidents(c fileContentCondition, scope *engine.Scope) ([]provider.IncidentContext, error) {
+	incidents := []provider.IncidentContext{}
+	if archiveDepth(p.config) <= 0 {
+		return incidents, nil
+	}
+	regex, err := regexp.Compile(c.Pattern)
+	if err != nil {
+		p.warnings.Warn("filecontent patterns that can not be searched in archives", c.Pattern)
+		return incidents, nil
+	}
+	err = p.walkArchives(scope, func(f archiveFile) error {
+		containsFile, err := provider.FilterFilePattern(c.FilePattern, f.name)
+		if err != nil {
+			return err
+		}
+		if !containsFile || archiveRegex.MatchString(f.name) {
+			return nil
+		}
+		content, err := f.read()
+		if err != nil {
+			p.warnings.Warn("files in archives failed to read", string(f.uri))
+			return nil
+		}
+		if isBinary(content) {
+			return nil
+		}
+		for i, line := range bytes.Split(content, []byte{'\n'}) {
+			// as grep -o, each match of a line is an incident
+			for _, match := range regex.FindAll(bytes.TrimSuffix(line, []byte{'\r'}), -1) {
+				if len(match) == 0 {
+					continue
+				}
+				lineNumber := i + 1
+				incidents = append(incidents, provider.IncidentContext{
+					FileURI:    f.uri,
+					LineNumber: &lineNumber,
+					Variables: map[string]interface{}{
+						"matchingText": string(match),
+					},
+					CodeLocation: &provider.Location{
+						StartPosition: provider.Position{Line: float64(lineNumber)},
+						EndPosition:   provider.Position{Line: float64(lineNumber)},
+					},
+				})
+			}
+		}
+		return nil
+	})
+	return incidents, err
+}
@@ -0,0 +1,196 @@
+package builtin
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeEar writes an ear with a war that has a jar in its libraries
+func writeEar(t *testing.T) string {
+	dir := t.TempDir()
+	jar := zipBytes(t, map[string][]byte{
+		"META-INF/persistence.xml": []byte(javaeePersistence),
+		"app.properties":           []byte("db.user=app\ndb.url=jdbc:oracle:thin:@db\n"),
+	})
+	war := zipBytes(t, map[string][]byte{
+		"WEB-INF/web.xml":     []byte(`<web-app><display-name>web</display-name></web-app>`),
+		"WEB-INF/lib/lib.jar": jar,
+		"config.json":         []byte(`{"datasource": "jdbc/app"}`),
+	})
+	ear := zipBytes(t, map[string][]byte{
+		"META-INF/application.xml": []byte(`<application><display-name>app</display-name></application>`),
+		"app.war":                  war,
+	})
+	if err := os.WriteFile(filepath.Join(dir, "app.ear"), ear, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestArchives(t *testing.T) {
+	dir := writeEar(t)
+	ear := filepath.Join(dir, "app.ear")
+	application := string(archiveURI(ear, []string{"META-INF/application.xml"}))
+	web := string(archiveURI(ear, []string{"app.war", "WEB-INF/web.xml"}))
+	config := string(archiveURI(ear, []string{"app.war", "config.json"}))
+	persistence := string(archiveURI(ear, []string{"app.war", "WEB-INF/lib/lib.jar", "META-INF/persistence.xml"}))
+	properties := string(archiveURI(ear, []string{"app.war", "WEB-INF/lib/lib.jar", "app.properties"}))
+
+	tests := []struct {
+		name      string
+		cap       string
+		condition map[string]interface{}
+		depth     int
+		want      []string
+		wantText  []string
+	}{
+		{
+			name:      "archives are not opened by default",
+			cap:       "file",
+			condition: map[string]interface{}{"file": map[string]interface{}{"pattern": "*.xml"}},
+			want:      []string{},
+		},
+		{
+			name:      "files of the archives of the location",
+			cap:       "file",
+			condition: map[string]interface{}{"file": map[string]interface{}{"pattern": "*.xml"}},
+			depth:     1,
+			want:      []string{application},
+		},
+		{
+			name:      "files of the nested archives",
+			cap:       "file",
+			condition: map[string]interface{}{"file": map[string]interface{}{"pattern": "*.xml"}},
+			depth:     3,
+			want:      []string{application, persistence, web},
+		},
+		{
+			name:      "content of the nested archives",
+			cap:       "filecontent",
+			condition: map[string]interface{}{"filecontent": map[string]interface{}{"pattern": "jdbc:[a-z]+"}},
+			depth:     3,
+			want:      []string{properties},
+			wantText:  []string{"jdbc:oracle"},
+		},
+		{
+			name:      "content of the files matching the file pattern",
+			cap:       "filecontent",
+			condition: map[string]interface{}{"filecontent": map[string]interface{}{"pattern": "jdbc:[a-z]+", "filePattern": `\.xml$`}},
+			depth:     3,
+			want:      []string{},
+		},
+		{
+			name:      "xml beyond the depth",
+			cap:       "xml",
+			condition: map[string]interface{}{"xml": map[string]interface{}{"xpath": "//*[local-name()='persistence-unit']/@name"}},
+			depth:     2,
+			want:      []string{},
+		},
+		{
+			name:      "xml in the nested archives",
+			cap:       "xml",
+			condition: map[string]interface{}{"xml": map[string]interface{}{"xpath": "//*[local-name()='persistence-unit']/@name"}},
+			depth:     3,
+			want:      []string{persistence},
+		},
+		{
+			name:      "xml of the files of a chained condition",
+			cap:       "xml",
+			condition: map[string]interface{}{"xml": map[string]interface{}{"xpath": "//display-name", "filepaths": []string{web}}},
+			depth:     3,
+			want:      []string{web},
+		},
+		{
+			name:      "json in the nested archives",
+			cap:       "json",
+			condition: map[string]interface{}{"json": map[string]interface{}{"xpath": "//datasource"}},
+			depth:     2,
+			want:      []string{config},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &builtinServiceClient{
+				config: provider.InitConfig{
+					Location:               dir,
+					ProviderSpecificConfig: map[string]interface{}{ARCHIVE_DEPTH_INIT_OPTION: tt.depth},
+				},
+				warnings: &provider.Warnings{},
+			}
+			conditionInfo, err := yaml.Marshal(tt.condition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Evaluate(context.Background(), tt.cap, conditionInfo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			gotText := []string{}
+			for _, incident := range resp.Incidents {
+				got = append(got, string(incident.FileURI))
+				if text, ok := incident.Variables["matchingText"].(string); ok {
+					gotText = append(gotText, text)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got incidents in %v, want %v", got, tt.want)
+			}
+			if resp.Matched != (len(tt.want) != 0) {
+				t.Errorf("got matched %v for %d incidents", resp.Matched, len(tt.want))
+			}
+			if tt.wantText != nil && !reflect.DeepEqual(gotText, tt.wantText) {
+				t.Errorf("got matching texts %v, want %v", gotText, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestArchiveCodeSnip(t *testing.T) {
+	dir := writeEar(t)
+	properties := archiveURI(filepath.Join(dir, "app.ear"), []string{"app.war", "WEB-INF/lib/lib.jar", "app.properties"})
+	p := &builtinProvider{config: provider.Config{ContextLines: 1}}
+	snip, err := p.GetCodeSnip(properties, engine.Location{
+		StartPosition: engine.Position{Line: 1},
+		EndPosition:   engine.Position{Line: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1  db.user=app\n2  db.url=jdbc:oracle:thin:@db\n"
+	if snip != want {
+		t.Errorf("got snip %q, want %q", snip, want)
+	}
+	if _, err := p.GetCodeSnip(archiveURI(filepath.Join(dir, "app.ear"), []string{"missing.xml"}), engine.Location{}); err == nil {
+		t.Errorf("expected an error for a file that is not in the archive")
+	}
+}
@@ -0,0 +1,91 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// grepBufferSize is the size of the buffer the output of grep is read
+	// in, longer lines are copied to a separate buffer that is reused as well
+	grepBufferSize = 64 * 1024
+	// maxInternedText and maxInternedTexts bound the matching texts that are
+	// shared between the matches, the matches of a pattern often have the
+	// same text
+	maxInternedText  = 256
+	maxInternedTexts = 4096
+)
+
+// parseGrepOutput reads the output of grep -o -n as it is written and calls
+// match for every line, {filepath}:{lineNumber}:{matchingText}. The lines are
+// read in reused buffers, the file names are only allocated once for all the
+// consecutive matches in a file and the short matching texts once for all the
+// matches that have them, so that a search with many matches, such as in large
+// XML or SQL dumps, does not keep the whole output in memory.
+func parseGrepOutput(r io.Reader, match func(file string, line int, text string) error) error {
+	reader := bufio.NewReaderSize(r, grepBufferSize)
+	long := []byte{}
+	file := ""
+	texts := map[string]string{}
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long[:0], line...)
+			for err == bufio.ErrBufferFull {
+				line, err = reader.ReadSlice('\n')
+				long = append(long, line...)
+			}
+			line = long
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if trimmed := bytes.TrimSuffix(line, []byte{'\n'}); len(bytes.TrimSpace(trimmed)) != 0 {
+			//TODO(fabianvf): This will not work if there is a `:` in the filename, do we care?
+			fileEnd := bytes.IndexByte(trimmed, ':')
+			lineEnd := -1
+			if fileEnd >= 0 {
+				lineEnd = bytes.IndexByte(trimmed[fileEnd+1:], ':')
+			}
+			if lineEnd < 0 {
+				return fmt.Errorf(
+					"malformed response from grep, cannot parse grep output '%s' with pattern {filepath}:{lineNumber}:{matchingText}", trimmed)
+			}
+			lineEnd += fileEnd + 1
+			// comparing with a converted slice does not allocate
+			if string(trimmed[:fileEnd]) != file {
+				file = string(trimmed[:fileEnd])
+			}
+			lineNumber, convErr := strconv.Atoi(string(trimmed[fileEnd+1 : lineEnd]))
+			if convErr != nil {
+				return fmt.Errorf("Cannot convert line number string to integer")
+			}
+			if err := match(file, lineNumber, intern(texts, trimmed[lineEnd+1:])); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// intern returns the string of the text, the same string for the texts that
+// are already in the map
+func intern(texts map[string]string, text []byte) string {
+	if len(text) > maxInternedText {
+		return string(text)
+	}
+	// looking up a converted slice does not allocate
+	if s, ok := texts[string(text)]; ok {
+		return s
+	}
+	s := string(text)
+	if len(texts) < maxInternedTexts {
+		texts[s] = s
+	}
+	return s
+}
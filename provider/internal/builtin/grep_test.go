@@ -0,0 +1,123 @@
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type grepMatch struct {
+	file string
+	line int
+	text string
+}
+
+func TestParseGrepOutput(t *testing.T) {
+	long := strings.Repeat("x", grepBufferSize*2+10)
+	tests := []struct {
+		name    string
+		output  string
+		want    []grepMatch
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			want: []grepMatch{},
+		},
+		{
+			name:   "matches",
+			output: "/app/a.xml:1:<bean\n/app/a.xml:12:<bean id=\"a:b\"\n/app/b.sql:3:DROP\n",
+			want: []grepMatch{
+				{file: "/app/a.xml", line: 1, text: "<bean"},
+				{file: "/app/a.xml", line: 12, text: "<bean id=\"a:b\""},
+				{file: "/app/b.sql", line: 3, text: "DROP"},
+			},
+		},
+		{
+			name:   "no trailing new line",
+			output: "/app/a.xml:1:<bean",
+			want:   []grepMatch{{file: "/app/a.xml", line: 1, text: "<bean"}},
+		},
+		{
+			name:   "match longer than the buffer",
+			output: "/app/dump.sql:2:" + long + "\n/app/dump.sql:3:x\n",
+			want: []grepMatch{
+				{file: "/app/dump.sql", line: 2, text: long},
+				{file: "/app/dump.sql", line: 3, text: "x"},
+			},
+		},
+		{
+			name:    "malformed",
+			output:  "/app/a.xml\n",
+			wantErr: true,
+		},
+		{
+			name:    "line number",
+			output:  "/app/a.xml:one:<bean\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []grepMatch{}
+			err := parseGrepOutput(strings.NewReader(tt.output), func(file string, line int, text string) error {
+				got = append(got, grepMatch{file: file, line: line, text: text})
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// grepOutput is the output of a search with many matches in a few large files
+func grepOutput() []byte {
+	b := &bytes.Buffer{}
+	for f := 0; f < 10; f++ {
+		for l := 1; l <= 5000; l++ {
+			fmt.Fprintf(b, "/app/db/dump-%d.sql:%d:INSERT INTO\n", f, l)
+		}
+	}
+	return b.Bytes()
+}
+
+func BenchmarkParseGrepOutput(b *testing.B) {
+	output := grepOutput()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := parseGrepOutput(bytes.NewReader(output), func(file string, line int, text string) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSplitGrepOutput parses the output like the provider did before it
+// was streamed, once all of it was read, for comparison
+func BenchmarkSplitGrepOutput(b *testing.B) {
+	output := grepOutput()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		read := append([]byte{}, output...)
+		for _, match := range strings.Split(strings.TrimSpace(string(read)), "\n") {
+			pieces := strings.SplitN(match, ":", 3)
+			if len(pieces) != 3 {
+				b.Fatal("malformed output")
+			}
+			if _, err := strconv.Atoi(pieces[1]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
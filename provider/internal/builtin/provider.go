@@ -2,20 +2,37 @@ package builtin
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
 )
 
 const TAGS_FILE_INIT_OPTION = "tagsFile"
 
+// ConfigSchema is the schema of the providerSpecificConfig of the init
+// configs of the builtin provider
+func ConfigSchema() *openapi3.Schema {
+	return provider.NewConfigSchema(map[string]*openapi3.Schema{
+		TAGS_FILE_INIT_OPTION:     provider.WithDescription(openapi3.NewStringSchema(), "Path of a yaml file with the tags of the application"),
+		ARCHIVE_DEPTH_INIT_OPTION: provider.WithDescription(openapi3.NewIntegerSchema().WithMin(0), "Levels of nested archives, jars, wars, ears and zips, whose files the file, filecontent, xml and json conditions search, 0 does not open the archives"),
+	})
+}
+
 var capabilities = []provider.Capability{
 	{
 		Name:            "filecontent",
 		TemplateContext: openapi3.SchemaRef{},
+		IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+			"matchingText": provider.WithDescription(openapi3.NewStringSchema(), "Line that matched the pattern"),
+		}),
 	},
 	{
 		Name: "file",
@@ -35,18 +52,31 @@ var capabilities = []provider.Capability{
 				},
 			},
 		},
+		IncidentVariables: provider.NewIncidentVariablesSchema(nil),
 	},
 	{
 		Name:            "xml",
 		TemplateContext: openapi3.SchemaRef{},
+		IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+			"matchingXML": provider.WithDescription(openapi3.NewStringSchema(), "XML of the node that matched the xpath"),
+			"innerText":   provider.WithDescription(openapi3.NewStringSchema(), "Text of the node"),
+			"data":        provider.WithDescription(openapi3.NewStringSchema(), "Name of the element, or text of the node"),
+		}),
 	},
 	{
 		Name:            "json",
 		TemplateContext: openapi3.SchemaRef{},
+		IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+			"matchingJSON": provider.WithDescription(openapi3.NewStringSchema(), "Text of the node that matched the xpath"),
+			"data":         provider.WithDescription(openapi3.NewStringSchema(), "Name of the element, or text of the node"),
+		}),
 	},
 	{
 		Name:            "hasTags",
 		TemplateContext: openapi3.SchemaRef{},
+		IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+			"tags": provider.WithDescription(openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()), "Tags of the condition"),
+		}),
 	},
 }
 
@@ -61,7 +91,7 @@ type builtinCondition struct {
 
 type fileContentCondition struct {
 	FilePattern string `yaml:"filePattern"`
-	Pattern     string `yaml:"pattern`
+	Pattern     string `yaml:"pattern"`
 }
 
 type fileCondition struct {
@@ -73,11 +103,14 @@ var _ provider.InternalProviderClient = &builtinProvider{}
 type xmlCondition struct {
 	XPath      string            `yaml:"xpath"`
 	Namespaces map[string]string `yaml:"namespaces"`
-	Filepaths  []string          `yaml:"filepaths"`
+	// DefaultNamespacePrefix is bound to the namespace of the document
+	// element of each file, whatever version of a schema it uses.
+	DefaultNamespacePrefix string   `yaml:"defaultNamespacePrefix"`
+	Filepaths              []string `yaml:"filepaths"`
 }
 
 type jsonCondition struct {
-	XPath     string   `yaml:'xpath'`
+	XPath     string   `yaml:"xpath"`
 	Filepaths []string `yaml:"filepaths"`
 }
 
@@ -90,6 +123,8 @@ type builtinProvider struct {
 	provider.UnimplementedDependenciesComponent
 
 	clients []provider.ServiceClient
+
+	warnings provider.Warnings
 }
 
 func NewBuiltinProvider(config provider.Config, log logr.Logger) *builtinProvider {
@@ -128,9 +163,15 @@ func (p *builtinProvider) Init(ctx context.Context, log logr.Logger, config prov
 		config:                             config,
 		tags:                               p.tags,
 		UnimplementedDependenciesComponent: provider.UnimplementedDependenciesComponent{},
+		warnings:                           &p.warnings,
 	}, nil
 }
 
+// Warnings returns the problems found while analyzing that do not stop the analysis
+func (p *builtinProvider) Warnings() []konveyor.Warning {
+	return p.warnings.Warnings()
+}
+
 func (p *builtinProvider) loadTags(config provider.InitConfig) error {
 	tagsFile, ok := config.ProviderSpecificConfig[TAGS_FILE_INIT_OPTION].(string)
 	// for now, if the tags file is invalid, lets ignore
@@ -161,6 +202,17 @@ func (p *builtinProvider) Evaluate(ctx context.Context, cap string, conditionInf
 	return provider.FullResponseFromServiceClients(ctx, p.clients, cap, conditionInfo)
 }
 
+var _ engine.CodeSnip = &builtinProvider{}
+
+// GetCodeSnip returns the lines around the incidents in the files of the
+// archives, the engine reads the other files
+func (p *builtinProvider) GetCodeSnip(u uri.URI, loc engine.Location) (string, error) {
+	if !strings.HasPrefix(string(u), archiveScheme) {
+		return "", fmt.Errorf("invalid uri %s, must be a file in an archive", u)
+	}
+	return archiveSnip(u, loc, p.config.ContextLines)
+}
+
 func (p *builtinProvider) Stop() {
 	return
 }
@@ -1,19 +1,21 @@
 package builtin
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/antchfx/jsonquery"
 	"github.com/antchfx/xmlquery"
-	"github.com/antchfx/xpath"
+	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
@@ -23,6 +25,8 @@ type builtinServiceClient struct {
 	config provider.InitConfig
 	tags   map[string]bool
 	provider.UnimplementedDependenciesComponent
+
+	warnings *provider.Warnings
 }
 
 var _ provider.ServiceClient = &builtinServiceClient{}
@@ -44,16 +48,35 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		if c.Pattern == "" {
 			return response, fmt.Errorf("could not parse provided file pattern as string: %v", conditionInfo)
 		}
-		matchingFiles, err := findFilesMatchingPattern(p.config.Location, c.Pattern)
+		matchingFiles := []string{}
+		for _, root := range p.config.WalkRoots() {
+			files, err := findFilesMatchingPattern(p.config, root, c.Pattern, cond.Scope)
+			if err != nil {
+				return response, fmt.Errorf("unable to find files using pattern `%s`: %v", c.Pattern, err)
+			}
+			matchingFiles = append(matchingFiles, files...)
+		}
+		archiveFiles := []uri.URI{}
+		matchName := nameMatcher(c.Pattern)
+		err := p.walkArchives(cond.Scope, func(f archiveFile) error {
+			if matchName(path.Base(f.name)) {
+				archiveFiles = append(archiveFiles, f.uri)
+			}
+			return nil
+		})
 		if err != nil {
-			return response, fmt.Errorf("unable to find files using pattern `%s`: %v", c.Pattern, err)
+			return response, err
 		}
 
-		if len(matchingFiles) != 0 {
+		if len(matchingFiles) != 0 || len(archiveFiles) != 0 {
 			response.Matched = true
 		}
 
-		response.TemplateContext = map[string]interface{}{"filepaths": matchingFiles}
+		filepaths := append([]string{}, matchingFiles...)
+		for _, u := range archiveFiles {
+			filepaths = append(filepaths, string(u))
+		}
+		response.TemplateContext = map[string]interface{}{"filepaths": filepaths}
 		for _, match := range matchingFiles {
 			if filepath.IsAbs(match) {
 				response.Incidents = append(response.Incidents, provider.IncidentContext{
@@ -73,130 +96,131 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			})
 
 		}
+		for _, u := range archiveFiles {
+			response.Incidents = append(response.Incidents, provider.IncidentContext{
+				FileURI: u,
+			})
+		}
 		return response, nil
 	case "filecontent":
 		c := cond.Filecontent
 		if c.Pattern == "" {
 			return response, fmt.Errorf("could not parse provided regex pattern as string: %v", conditionInfo)
 		}
-		var outputBytes []byte
-		grep := exec.Command("grep", "-o", "-n", "-R", "-P", c.Pattern, p.config.Location)
-		outputBytes, err := grep.Output()
-		if err != nil {
-			if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
-				return response, nil
+		args := []string{"-o", "-n", "-R", "-P"}
+		if cond.Scope != nil {
+			// grep can only skip directories by name, the other globs are
+			// checked on the matches
+			for _, exclude := range cond.Scope.Exclude {
+				if !strings.Contains(exclude, "/") {
+					args = append(args, fmt.Sprintf("--exclude-dir=%s", exclude))
+				}
 			}
+		}
+		args = append(args, c.Pattern)
+		args = append(args, p.config.WalkRoots()...)
+		grep := exec.CommandContext(ctx, "grep", args...)
+		stdout, err := grep.StdoutPipe()
+		if err != nil {
 			return response, fmt.Errorf("could not run grep with provided pattern %+v", err)
 		}
-
-		matches := []string{}
-		outputString := strings.TrimSpace(string(outputBytes))
-		if outputString != "" {
-			matches = append(matches, strings.Split(outputString, "\n")...)
+		if err := grep.Start(); err != nil {
+			return response, fmt.Errorf("could not run grep with provided pattern %+v", err)
 		}
-
-		for _, match := range matches {
-			//TODO(fabianvf): This will not work if there is a `:` in the filename, do we care?
-			pieces := strings.SplitN(match, ":", 3)
-			if len(pieces) != 3 {
-				//TODO(fabianvf): Just log or return?
-				//(shawn-hurley): I think the return is good personally
-				return response, fmt.Errorf(
-					"malformed response from grep, cannot parse grep output '%s' with pattern {filepath}:{lineNumber}:{matchingText}", match)
-			}
-
-			containsFile, err := provider.FilterFilePattern(c.FilePattern, pieces[0])
+		// the matches are parsed while grep searches, instead of once all
+		// its output is in memory
+		err = parseGrepOutput(stdout, func(file string, line int, text string) error {
+			containsFile, err := provider.FilterFilePattern(c.FilePattern, file)
 			if err != nil {
-				return response, err
-			}
-			if !containsFile {
-				continue
+				return err
 			}
-
-			ab, err := filepath.Abs(pieces[0])
-			if err != nil {
-				ab = pieces[0]
+			if !containsFile || !cond.Scope.Matches(p.config.RootOf(file), file) {
+				return nil
 			}
-			lineNumber, err := strconv.Atoi(pieces[1])
+			ab, err := filepath.Abs(file)
 			if err != nil {
-				return response, fmt.Errorf("Cannot convert line number string to integer")
+				ab = file
 			}
+			lineNumber := line
 			response.Incidents = append(response.Incidents, provider.IncidentContext{
 				FileURI:    uri.File(ab),
 				LineNumber: &lineNumber,
 				Variables: map[string]interface{}{
-					"matchingText": pieces[2],
+					"matchingText": text,
 				},
 				CodeLocation: &provider.Location{
 					StartPosition: provider.Position{Line: float64(lineNumber)},
 					EndPosition:   provider.Position{Line: float64(lineNumber)},
 				},
 			})
+			return nil
+		})
+		if err != nil {
+			grep.Process.Kill()
+			grep.Wait()
+			return response, err
 		}
+		if err := grep.Wait(); err != nil {
+			// grep exits with 1 when nothing matches
+			if exitError, ok := err.(*exec.ExitError); !ok || exitError.ExitCode() != 1 {
+				return response, fmt.Errorf("could not run grep with provided pattern %+v", err)
+			}
+		}
+		archiveIncidents, err := p.archiveContentIncidents(c, cond.Scope)
+		if err != nil {
+			return response, err
+		}
+		response.Incidents = append(response.Incidents, archiveIncidents...)
 		if len(response.Incidents) != 0 {
 			response.Matched = true
 		}
 		return response, nil
 	case "xml":
-		query, err := xpath.CompileWithNS(cond.XML.XPath, cond.XML.Namespaces)
-		if query == nil || err != nil {
+		xmlQuery, err := newXMLQuery(cond.XML)
+		if err != nil {
 			return response, fmt.Errorf("Could not parse provided xpath query '%s': %v", cond.XML.XPath, err)
 		}
 		//TODO(fabianvf): how should we scope the files searched here?
 		var xmlFiles []string
 		patterns := []string{"*.xml", "*.xhtml"}
-		xmlFiles, err = provider.GetFiles(p.config.Location, cond.XML.Filepaths, patterns...)
-		if err != nil {
-			return response, fmt.Errorf("Unable to find files using pattern `%s`: %v", patterns, err)
+		for _, root := range p.config.WalkRoots() {
+			files, err := provider.GetFiles(root, cond.XML.Filepaths, patterns...)
+			if err != nil {
+				return response, fmt.Errorf("Unable to find files using pattern `%s`: %v", patterns, err)
+			}
+			xmlFiles = append(xmlFiles, filterFilesInScope(p.config, files, cond.Scope)...)
 		}
 
 		for _, file := range xmlFiles {
-
 			f, err := os.Open(file)
 			if err != nil {
 				fmt.Printf("unable to open file '%s': %v\n", file, err)
 				continue
 			}
-			// TODO This should start working if/when this merges and releases: https://github.com/golang/go/pull/56848
-			var doc *xmlquery.Node
-			doc, err = xmlquery.ParseWithOptions(f, xmlquery.ParserOptions{Decoder: &xmlquery.DecoderOptions{Strict: false}})
+			ab, err := filepath.Abs(file)
 			if err != nil {
-				if err.Error() == "xml: unsupported version \"1.1\"; only version 1.0 is supported" {
-					// TODO HACK just pretend 1.1 xml documents are 1.0 for now while we wait for golang to support 1.1
-					b, err := os.ReadFile(file)
-					if err != nil {
-						fmt.Printf("unable to parse xml file '%s': %v\n", file, err)
-						continue
-					}
-					docString := strings.Replace(string(b), "<?xml version=\"1.1\"", "<?xml version = \"1.0\"", 1)
-					doc, err = xmlquery.Parse(strings.NewReader(docString))
-					if err != nil {
-						fmt.Printf("unable to parse xml file '%s': %v\n", file, err)
-						continue
-					}
-				} else {
-					fmt.Printf("unable to parse xml file '%s': %v\n", file, err)
-					continue
-				}
+				ab = file
 			}
-			list := xmlquery.QuerySelectorAll(doc, query)
-			if len(list) != 0 {
-				response.Matched = true
-				for _, node := range list {
-					ab, err := filepath.Abs(file)
-					if err != nil {
-						ab = file
-					}
-					response.Incidents = append(response.Incidents, provider.IncidentContext{
-						FileURI: uri.File(ab),
-						Variables: map[string]interface{}{
-							"matchingXML": node.OutputXML(false),
-							"innerText":   node.InnerText(),
-							"data":        node.Data,
-						},
-					})
-				}
+			err = p.queryXML(f, file, uri.File(ab), xmlQuery, &response)
+			f.Close()
+			if err != nil {
+				return response, err
+			}
+		}
+		matchFile := archiveFileMatcher(cond.XML.Filepaths, patterns...)
+		err = p.walkArchives(cond.Scope, func(f archiveFile) error {
+			if !matchFile(f) {
+				return nil
 			}
+			content, err := f.read()
+			if err != nil {
+				p.warnings.Warn("files in archives failed to read", string(f.uri))
+				return nil
+			}
+			return p.queryXML(bytes.NewReader(content), string(f.uri), f.uri, xmlQuery, &response)
+		})
+		if err != nil {
+			return response, err
 		}
 
 		return response, nil
@@ -206,43 +230,63 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			return response, fmt.Errorf("Could not parse provided xpath query as string: %v", conditionInfo)
 		}
 		pattern := "*.json"
-		jsonFiles, err := provider.GetFiles(p.config.Location, cond.JSON.Filepaths, pattern)
-		if err != nil {
-			return response, fmt.Errorf("Unable to find files using pattern `%s`: %v", pattern, err)
+		jsonFiles := []string{}
+		for _, root := range p.config.WalkRoots() {
+			files, err := provider.GetFiles(root, cond.JSON.Filepaths, pattern)
+			if err != nil {
+				return response, fmt.Errorf("Unable to find files using pattern `%s`: %v", pattern, err)
+			}
+			jsonFiles = append(jsonFiles, filterFilesInScope(p.config, files, cond.Scope)...)
 		}
 		for _, file := range jsonFiles {
 			f, err := os.Open(file)
-			doc, err := jsonquery.Parse(f)
-			list, err := jsonquery.QueryAll(doc, query)
+			if err != nil {
+				fmt.Printf("unable to open file '%s': %v\n", file, err)
+				continue
+			}
+			ab, err := filepath.Abs(file)
+			if err != nil {
+				ab = file
+			}
+			err = p.queryJSON(f, file, uri.File(ab), query, &response)
+			f.Close()
 			if err != nil {
 				return response, err
 			}
-			if len(list) != 0 {
-				response.Matched = true
-				for _, node := range list {
-					ab, err := filepath.Abs(file)
-					if err != nil {
-						ab = file
-					}
-					response.Incidents = append(response.Incidents, provider.IncidentContext{
-						FileURI: uri.File(ab),
-						Variables: map[string]interface{}{
-							"matchingJSON": node.InnerText(),
-							"data":         node.Data,
-						},
-					})
-				}
+		}
+		matchFile := archiveFileMatcher(cond.JSON.Filepaths, pattern)
+		err := p.walkArchives(cond.Scope, func(f archiveFile) error {
+			if !matchFile(f) {
+				return nil
+			}
+			content, err := f.read()
+			if err != nil {
+				p.warnings.Warn("files in archives failed to read", string(f.uri))
+				return nil
 			}
+			return p.queryJSON(bytes.NewReader(content), string(f.uri), f.uri, query, &response)
+		})
+		if err != nil {
+			return response, err
 		}
 		return response, nil
 	case "hasTags":
+		hasTag := func(tag string) bool {
+			if _, exists := cond.ProviderContext.Tags[tag]; exists {
+				return true
+			}
+			return p.tags[tag]
+		}
+		// the tags and expressions of the condition must all match
 		found := true
 		for _, tag := range cond.HasTags {
-			if _, exists := cond.ProviderContext.Tags[tag]; !exists {
-				if _, exists := p.tags[tag]; !exists {
-					found = false
-					break
-				}
+			expr, err := parseTagExpression(tag)
+			if err != nil {
+				return response, err
+			}
+			if !expr.eval(hasTag) {
+				found = false
+				break
 			}
 		}
 		if found {
@@ -258,7 +302,85 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		return response, fmt.Errorf("capability must be one of %v, not %s", capabilities, cap)
 	}
 }
-func findFilesMatchingPattern(root, pattern string) ([]string, error) {
+
+// queryXML adds the nodes of the xml document that match the query to the
+// incidents of the response, the name of the document is the one its parsing
+// errors are reported with
+func (p *builtinServiceClient) queryXML(r io.ReadSeeker, name string, fileURI uri.URI, xmlQuery *xmlQuery, response *provider.ProviderEvaluateResponse) error {
+	doc, err := parseXML(r)
+	if err != nil {
+		fmt.Printf("unable to parse xml file '%s': %v\n", name, err)
+		p.warnings.Warn("xml files failed to parse", name)
+		return nil
+	}
+	query, err := xmlQuery.compile(doc)
+	if err != nil {
+		return fmt.Errorf("Could not parse provided xpath query '%s': %v", xmlQuery.xpath, err)
+	}
+	if query == nil {
+		return nil
+	}
+	list := xmlquery.QuerySelectorAll(doc, query)
+	if len(list) != 0 {
+		response.Matched = true
+		for _, node := range list {
+			response.Incidents = append(response.Incidents, provider.IncidentContext{
+				FileURI: fileURI,
+				Variables: map[string]interface{}{
+					"matchingXML": node.OutputXML(false),
+					"innerText":   node.InnerText(),
+					"data":        node.Data,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// queryJSON adds the nodes of the json document that match the query to the
+// incidents of the response, the name of the document is the one its parsing
+// errors are reported with
+func (p *builtinServiceClient) queryJSON(r io.Reader, name string, fileURI uri.URI, query string, response *provider.ProviderEvaluateResponse) error {
+	doc, err := jsonquery.Parse(r)
+	if err != nil {
+		fmt.Printf("unable to parse json file '%s': %v\n", name, err)
+		p.warnings.Warn("json files failed to parse", name)
+		return nil
+	}
+	list, err := jsonquery.QueryAll(doc, query)
+	if err != nil {
+		return err
+	}
+	if len(list) != 0 {
+		response.Matched = true
+		for _, node := range list {
+			response.Incidents = append(response.Incidents, provider.IncidentContext{
+				FileURI: fileURI,
+				Variables: map[string]interface{}{
+					"matchingJSON": node.InnerText(),
+					"data":         node.Data,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// filterFilesInScope matches the files against the scope relative to their
+// root
+func filterFilesInScope(config provider.InitConfig, files []string, scope *engine.Scope) []string {
+	filtered := []string{}
+	for _, file := range files {
+		if scope.Matches(config.RootOf(file), file) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// findFilesMatchingPattern walks the root, the scope is matched relative to
+// the innermost root of the config the files are in
+func findFilesMatchingPattern(config provider.InitConfig, root, pattern string, scope *engine.Scope) ([]string, error) {
 	var regex *regexp.Regexp
 	// if the regex doesn't compile, we'll default to using filepath.Match on the pattern directly
 	regex, _ = regexp.Compile(pattern)
@@ -267,6 +389,12 @@ func findFilesMatchingPattern(root, pattern string) ([]string, error) {
 		if err != nil {
 			return err
 		}
+		if d.IsDir() && path != root && scope.ExcludesDir(config.RootOf(path), path) {
+			return filepath.SkipDir
+		}
+		if !scope.Matches(config.RootOf(path), path) {
+			return nil
+		}
 		var matched bool
 		if regex != nil {
 			matched = regex.MatchString(d.Name())
@@ -0,0 +1,162 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tagExpression is a tag of a hasTags condition, or a boolean expression of
+// tags such as "Spring Boot && !(Java EE)". The tags of an expression are
+// the text between the &&, ||, ! operators and the parentheses, without its
+// leading and trailing spaces.
+type tagExpression interface {
+	eval(hasTag func(string) bool) bool
+}
+
+type tagName string
+
+func (t tagName) eval(hasTag func(string) bool) bool {
+	return hasTag(string(t))
+}
+
+type tagNot struct {
+	expr tagExpression
+}
+
+func (n tagNot) eval(hasTag func(string) bool) bool {
+	return !n.expr.eval(hasTag)
+}
+
+type tagAnd []tagExpression
+
+func (a tagAnd) eval(hasTag func(string) bool) bool {
+	for _, e := range a {
+		if !e.eval(hasTag) {
+			return false
+		}
+	}
+	return true
+}
+
+type tagOr []tagExpression
+
+func (o tagOr) eval(hasTag func(string) bool) bool {
+	for _, e := range o {
+		if e.eval(hasTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTagExpression parses a tag of a hasTags condition, a tag without any
+// of the &&, || and ! operators is a tag as is, so that the tags with
+// parentheses still match themselves
+func parseTagExpression(s string) (tagExpression, error) {
+	if !strings.Contains(s, "&&") && !strings.Contains(s, "||") && !strings.Contains(s, "!") {
+		return tagName(strings.TrimSpace(s)), nil
+	}
+	p := &tagParser{input: s}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag expression %q: %w", s, err)
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("invalid tag expression %q: unexpected %q at %d", s, p.input[p.pos:], p.pos)
+	}
+	return expr, nil
+}
+
+// tagParser parses the expressions of the grammar
+//
+//	or    = and { "||" and }
+//	and   = unary { "&&" unary }
+//	unary = "!" unary | "(" or ")" | tag
+type tagParser struct {
+	input string
+	pos   int
+}
+
+func (p *tagParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *tagParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *tagParser) parseOr() (tagExpression, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := tagOr{expr}
+	for p.consume("||") {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *tagParser) parseAnd() (tagExpression, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	and := tagAnd{expr}
+	for p.consume("&&") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *tagParser) parseUnary() (tagExpression, error) {
+	if p.consume("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return tagNot{expr: expr}, nil
+	}
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing ) at %d", p.pos)
+		}
+		return expr, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("()!", rune(p.input[p.pos])) &&
+		!strings.HasPrefix(p.input[p.pos:], "&&") && !strings.HasPrefix(p.input[p.pos:], "||") {
+		p.pos++
+	}
+	tag := strings.TrimSpace(p.input[start:p.pos])
+	if tag == "" {
+		return nil, fmt.Errorf("missing a tag at %d", start)
+	}
+	return tagName(tag), nil
+}
@@ -0,0 +1,83 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_parseTagExpression(t *testing.T) {
+	tags := map[string]bool{"Spring Boot": true, "Java": true, "Servlet (3.0)": true}
+	hasTag := func(tag string) bool { return tags[tag] }
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "Java", want: true},
+		{expr: "Java EE", want: false},
+		{expr: "Servlet (3.0)", want: true},
+		{expr: "Spring Boot && !(Java EE)", want: true},
+		{expr: "Spring Boot && !Java", want: false},
+		{expr: "Java EE || Spring Boot", want: true},
+		{expr: "Java EE || Quarkus && Java", want: false},
+		{expr: "(Java EE || Spring Boot) && Java", want: true},
+		{expr: "!!Java", want: true},
+		{expr: "Java &&", wantErr: true},
+		{expr: "!(Java EE", wantErr: true},
+		{expr: "Java || Quarkus)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseTagExpression(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTagExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := expr.eval(hasTag); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_hasTagsExpressions(t *testing.T) {
+	client := &builtinServiceClient{tags: map[string]bool{"Kubernetes": true}}
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "tags of the condition context and of the tags file",
+			condition: "hasTags:\n- Spring Boot\n- Kubernetes\ntags:\n  Spring Boot: true\n",
+			want:      true,
+		},
+		{
+			name:      "all the expressions must match",
+			condition: "hasTags:\n- Spring Boot && !(Java EE)\n- Kubernetes || Openshift\ntags:\n  Spring Boot: true\n  Java EE: true\n",
+			want:      false,
+		},
+		{
+			name:      "invalid expression",
+			condition: "hasTags:\n- Spring Boot && (Java EE\n",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.Evaluate(context.Background(), "hasTags", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if response.Matched != tt.want {
+				t.Errorf("expected matched %v, got %+v", tt.want, response)
+			}
+			if tt.want && len(response.Incidents) != 1 {
+				t.Errorf("expected one incident, got %+v", response.Incidents)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+var (
+	// xpathLiteralRegex finds the string literals of a query, so that their
+	// content is not mistaken for prefixed names.
+	xpathLiteralRegex = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+	// xpathPrefixRegex finds the prefixes of the names in a query, the axes
+	// are not matched as "::" is not followed by a name.
+	xpathPrefixRegex = regexp.MustCompile(`(?:^|::|[^\w.:-])([A-Za-z_][\w.-]*):[A-Za-z_*]`)
+)
+
+// xmlDeclarationSize is how much of a document is read to find its xml
+// declaration
+const xmlDeclarationSize = 512
+
+var (
+	xml11Declaration = []byte(`<?xml version="1.1"`)
+	xml10Declaration = []byte(`<?xml version = "1.0"`)
+)
+
+// parseXML parses the document as it is read. The documents of version 1.1
+// are parsed as 1.0, as the go xml decoder only supports 1.0.
+func parseXML(r io.ReadSeeker) (*xmlquery.Node, error) {
+	// TODO This should start working if/when this merges and releases: https://github.com/golang/go/pull/56848
+	doc, err := xmlquery.ParseWithOptions(r, xmlquery.ParserOptions{Decoder: &xmlquery.DecoderOptions{Strict: false}})
+	if err == nil || err.Error() != "xml: unsupported version \"1.1\"; only version 1.0 is supported" {
+		return doc, err
+	}
+	// TODO HACK just pretend 1.1 xml documents are 1.0 for now while we wait for golang to support 1.1
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return xmlquery.Parse(xml10Reader(r))
+}
+
+// xml10Reader replaces the 1.1 version of the xml declaration at the start of
+// the document, without reading the rest of the document in memory
+func xml10Reader(r io.Reader) io.Reader {
+	reader := bufio.NewReaderSize(r, xmlDeclarationSize)
+	head, _ := reader.Peek(xmlDeclarationSize)
+	i := bytes.Index(head, xml11Declaration)
+	if i < 0 {
+		return reader
+	}
+	replaced := append(append(append([]byte{}, head[:i]...), xml10Declaration...), head[i+len(xml11Declaration):]...)
+	reader.Discard(len(head))
+	return io.MultiReader(bytes.NewReader(replaced), reader)
+}
+
+// xmlQuery is the xpath query of an xml condition. When the condition uses
+// namespaces, the prefixes it does not declare are bound to the namespaces
+// declared in each document, and the default namespace prefix to the
+// namespace of the document element, so the query is compiled for each
+// document.
+type xmlQuery struct {
+	xpath         string
+	namespaces    map[string]string
+	defaultPrefix string
+	// unbound are the prefixes of the query that the condition does not declare
+	unbound []string
+	// static is the query when it does not depend on the documents
+	static *xpath.Expr
+}
+
+func newXMLQuery(cond xmlCondition) (*xmlQuery, error) {
+	q := &xmlQuery{
+		xpath:         cond.XPath,
+		namespaces:    cond.Namespaces,
+		defaultPrefix: cond.DefaultNamespacePrefix,
+	}
+	placeholders := map[string]string{}
+	for prefix, ns := range cond.Namespaces {
+		placeholders[prefix] = ns
+	}
+	for _, prefix := range xpathPrefixes(cond.XPath) {
+		if _, ok := placeholders[prefix]; ok {
+			continue
+		}
+		placeholders[prefix] = ""
+		if prefix != q.defaultPrefix {
+			q.unbound = append(q.unbound, prefix)
+		}
+	}
+	// the query is validated even when it is compiled for each document
+	expr, err := xpath.CompileWithNS(cond.XPath, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	if q.defaultPrefix != "" || (len(cond.Namespaces) != 0 && len(q.unbound) != 0) {
+		return q, nil
+	}
+	if len(cond.Namespaces) == 0 {
+		// without namespaces, the prefixes are matched as written in the documents
+		expr, err = xpath.CompileWithNS(cond.XPath, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	q.static = expr
+	return q, nil
+}
+
+// compile returns the query for the document, or nil when the document does
+// not declare a prefix of the query, as nothing can match in it.
+func (q *xmlQuery) compile(doc *xmlquery.Node) (*xpath.Expr, error) {
+	if q.static != nil {
+		return q.static, nil
+	}
+	declared, defaultNamespace := documentNamespaces(doc)
+	namespaces := map[string]string{}
+	for prefix, ns := range q.namespaces {
+		namespaces[prefix] = ns
+	}
+	if q.defaultPrefix != "" {
+		namespaces[q.defaultPrefix] = defaultNamespace
+	}
+	for _, prefix := range q.unbound {
+		ns, ok := declared[prefix]
+		if !ok {
+			return nil, nil
+		}
+		namespaces[prefix] = ns
+	}
+	return xpath.CompileWithNS(q.xpath, namespaces)
+}
+
+// xpathPrefixes returns the sorted prefixes used by the names of the query
+func xpathPrefixes(query string) []string {
+	query = xpathLiteralRegex.ReplaceAllString(query, "''")
+	seen := map[string]bool{}
+	prefixes := []string{}
+	for _, match := range xpathPrefixRegex.FindAllStringSubmatch(query, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			prefixes = append(prefixes, match[1])
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// documentNamespaces returns the prefixes declared in the document, the first
+// declaration of a prefix wins, and the namespace of the document element when
+// it is not prefixed.
+func documentNamespaces(doc *xmlquery.Node) (map[string]string, string) {
+	declared := map[string]string{}
+	defaultNamespace := ""
+	if root := documentElement(doc); root != nil && root.Prefix == "" {
+		defaultNamespace = root.NamespaceURI
+	}
+	var walk func(n *xmlquery.Node)
+	walk = func(n *xmlquery.Node) {
+		for ; n != nil; n = n.NextSibling {
+			if n.Type != xmlquery.ElementNode {
+				continue
+			}
+			for _, attr := range n.Attr {
+				if attr.Name.Space != "xmlns" {
+					continue
+				}
+				if _, ok := declared[attr.Name.Local]; !ok {
+					declared[attr.Name.Local] = attr.Value
+				}
+			}
+			walk(n.FirstChild)
+		}
+	}
+	walk(doc.FirstChild)
+	return declared, defaultNamespace
+}
+
+func documentElement(doc *xmlquery.Node) *xmlquery.Node {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
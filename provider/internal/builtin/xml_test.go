@@ -0,0 +1,167 @@
+package builtin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+const (
+	javaeePersistence = `<?xml version="1.0"?>
+<persistence xmlns="http://xmlns.jcp.org/xml/ns/persistence" version="2.1">
+  <persistence-unit name="javaee" transaction-type="JTA"/>
+</persistence>`
+	jakartaPersistence = `<?xml version="1.0"?>
+<persistence xmlns="https://jakarta.ee/xml/ns/persistence" version="3.0">
+  <persistence-unit name="jakarta" transaction-type="JTA"/>
+</persistence>`
+	springBeans = `<?xml version="1.0"?>
+<beans xmlns="http://www.springframework.org/schema/beans"
+       xmlns:j="http://www.springframework.org/schema/jee">
+  <j:jndi-lookup id="ds" jndi-name="java:comp/env/jdbc/ds"/>
+</beans>`
+)
+
+func Test_xmlQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    xmlCondition
+		docs    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "default namespace prefix matches any version of the schema",
+			cond: xmlCondition{
+				XPath:                  "//p:persistence-unit[@transaction-type='JTA']/@name",
+				DefaultNamespacePrefix: "p",
+			},
+			docs: []string{javaeePersistence, jakartaPersistence},
+			want: []string{"javaee", "jakarta"},
+		},
+		{
+			name: "declared namespace only matches its version",
+			cond: xmlCondition{
+				XPath:      "//p:persistence-unit/@name",
+				Namespaces: map[string]string{"p": "https://jakarta.ee/xml/ns/persistence"},
+			},
+			docs: []string{javaeePersistence, jakartaPersistence},
+			want: []string{"jakarta"},
+		},
+		{
+			name: "undeclared prefix is bound from the document",
+			cond: xmlCondition{
+				XPath:      "//b:beans/j:jndi-lookup/@jndi-name",
+				Namespaces: map[string]string{"b": "http://www.springframework.org/schema/beans"},
+			},
+			docs: []string{springBeans, javaeePersistence},
+			want: []string{"java:comp/env/jdbc/ds"},
+		},
+		{
+			name: "prefixes in literals are ignored",
+			cond: xmlCondition{
+				XPath:                  "//b:beans/j:jndi-lookup[@jndi-name='java:comp/env/jdbc/ds']/@id",
+				DefaultNamespacePrefix: "b",
+			},
+			docs: []string{springBeans},
+			want: []string{"ds"},
+		},
+		{
+			name: "without namespaces prefixes are matched as written",
+			cond: xmlCondition{
+				XPath: "//j:jndi-lookup/@id",
+			},
+			docs: []string{springBeans},
+			want: []string{"ds"},
+		},
+		{
+			name: "invalid query",
+			cond: xmlCondition{
+				XPath:                  "//p:persistence-unit[",
+				DefaultNamespacePrefix: "p",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newXMLQuery(tt.cond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newXMLQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := []string{}
+			for _, d := range tt.docs {
+				doc, err := xmlquery.Parse(strings.NewReader(d))
+				if err != nil {
+					t.Fatal(err)
+				}
+				expr, err := q.compile(doc)
+				if err != nil {
+					t.Fatalf("compile() error = %v", err)
+				}
+				if expr == nil {
+					continue
+				}
+				for _, n := range xmlquery.QuerySelectorAll(doc, expr) {
+					got = append(got, n.InnerText())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_xpathPrefixes(t *testing.T) {
+	got := xpathPrefixes(`//p:unit/child::q:name[@xsi:type='a:b' and contains(text(), "c:d")]/p:*`)
+	want := []string{"p", "q", "xsi"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func Test_parseXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "version 1.0",
+			content: javaeePersistence,
+		},
+		{
+			name:    "version 1.1",
+			content: strings.Replace(javaeePersistence, `version="1.0"`, `version="1.1"`, 1),
+		},
+		{
+			name:    "version 1.1 longer than the declaration size",
+			content: strings.Replace(javaeePersistence, `version="1.0"`, `version="1.1"`, 1) + "<!--" + strings.Repeat("x", 2*xmlDeclarationSize) + "-->",
+		},
+		{
+			name:    "invalid",
+			content: "<persistence",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseXML(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if unit := xmlquery.FindOne(doc, "//persistence-unit"); unit == nil || unit.SelectAttr("name") != "javaee" {
+				t.Errorf("expected the persistence unit to be found, got %v", unit)
+			}
+		})
+	}
+}
@@ -27,6 +27,7 @@ const (
 	ProviderService_Stop_FullMethodName               = "/provider.ProviderService/Stop"
 	ProviderService_GetDependencies_FullMethodName    = "/provider.ProviderService/GetDependencies"
 	ProviderService_GetDependenciesDAG_FullMethodName = "/provider.ProviderService/GetDependenciesDAG"
+	ProviderService_Stats_FullMethodName              = "/provider.ProviderService/Stats"
 )
 
 // ProviderServiceClient is the client API for ProviderService service.
@@ -40,6 +41,7 @@ type ProviderServiceClient interface {
 	Stop(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetDependencies(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*DependencyResponse, error)
 	GetDependenciesDAG(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*DependencyDAGResponse, error)
+	Stats(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*StatsResponse, error)
 }
 
 type providerServiceClient struct {
@@ -113,6 +115,15 @@ func (c *providerServiceClient) GetDependenciesDAG(ctx context.Context, in *Serv
 	return out, nil
 }
 
+func (c *providerServiceClient) Stats(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, ProviderService_Stats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProviderServiceServer is the server API for ProviderService service.
 // All implementations must embed UnimplementedProviderServiceServer
 // for forward compatibility
@@ -124,6 +135,7 @@ type ProviderServiceServer interface {
 	Stop(context.Context, *ServiceRequest) (*emptypb.Empty, error)
 	GetDependencies(context.Context, *ServiceRequest) (*DependencyResponse, error)
 	GetDependenciesDAG(context.Context, *ServiceRequest) (*DependencyDAGResponse, error)
+	Stats(context.Context, *ServiceRequest) (*StatsResponse, error)
 	mustEmbedUnimplementedProviderServiceServer()
 }
 
@@ -152,6 +164,9 @@ func (UnimplementedProviderServiceServer) GetDependencies(context.Context, *Serv
 func (UnimplementedProviderServiceServer) GetDependenciesDAG(context.Context, *ServiceRequest) (*DependencyDAGResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDependenciesDAG not implemented")
 }
+func (UnimplementedProviderServiceServer) Stats(context.Context, *ServiceRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
 func (UnimplementedProviderServiceServer) mustEmbedUnimplementedProviderServiceServer() {}
 
 // UnsafeProviderServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -291,6 +306,24 @@ func _ProviderService_GetDependenciesDAG_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProviderService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProviderService_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServiceServer).Stats(ctx, req.(*ServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProviderService_ServiceDesc is the grpc.ServiceDesc for ProviderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -326,6 +359,10 @@ var ProviderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDependenciesDAG",
 			Handler:    _ProviderService_GetDependenciesDAG_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _ProviderService_Stats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "provider/internal/grpc/library.proto",
@@ -0,0 +1,216 @@
+package java
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	// QUERY_CHUNKING_INIT_OPTION splits the symbol queries in queries on
+	// parts of the workspace
+	QUERY_CHUNKING_INIT_OPTION = "queryChunking"
+	// QUERY_CHUNK_SIZE_INIT_OPTION is the number of package directories of
+	// a chunk with the package strategy
+	QUERY_CHUNK_SIZE_INIT_OPTION = "queryChunkSize"
+
+	defaultQueryChunkSize = 100
+)
+
+type chunkingStrategy string
+
+const (
+	chunkingNone       chunkingStrategy = "none"
+	chunkingSourceRoot chunkingStrategy = "source-root"
+	chunkingPackage    chunkingStrategy = "package"
+)
+
+// queryChunking is how the symbol queries are split
+type queryChunking struct {
+	strategy chunkingStrategy
+	size     int
+}
+
+// queryChunk is a part of the workspace a symbol query is limited to
+type queryChunk struct {
+	name  string
+	paths []string
+}
+
+// getQueryChunking reads the chunking of the init config, the chunk size is
+// a float when it comes from json
+func getQueryChunking(config provider.InitConfig) (queryChunking, error) {
+	c := queryChunking{strategy: chunkingNone, size: defaultQueryChunkSize}
+	if s, ok := config.ProviderSpecificConfig[QUERY_CHUNKING_INIT_OPTION].(string); ok && s != "" {
+		c.strategy = chunkingStrategy(s)
+	}
+	switch c.strategy {
+	case chunkingNone, chunkingSourceRoot, chunkingPackage:
+	default:
+		return c, fmt.Errorf("invalid %s %q, it must be one of %s, %s or %s", QUERY_CHUNKING_INIT_OPTION, c.strategy, chunkingNone, chunkingSourceRoot, chunkingPackage)
+	}
+	switch size := config.ProviderSpecificConfig[QUERY_CHUNK_SIZE_INIT_OPTION].(type) {
+	case int:
+		c.size = size
+	case float64:
+		c.size = int(size)
+	}
+	if c.size < 1 {
+		return c, fmt.Errorf("%s must be at least 1", QUERY_CHUNK_SIZE_INIT_OPTION)
+	}
+	return c, nil
+}
+
+// chunks returns the parts of the workspace of the roots, nil when the
+// queries are not split
+func (c queryChunking) chunks(roots []string) ([]queryChunk, error) {
+	switch c.strategy {
+	case chunkingSourceRoot:
+		chunks := []queryChunk{}
+		for _, root := range roots {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, queryChunk{name: abs, paths: []string{abs}})
+		}
+		return chunks, nil
+	case chunkingPackage:
+		dirs := []string{}
+		for _, root := range roots {
+			rootDirs, err := packageDirs(root)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, rootDirs...)
+		}
+		chunks := []queryChunk{}
+		for start := 0; start < len(dirs); start += c.size {
+			end := start + c.size
+			if end > len(dirs) {
+				end = len(dirs)
+			}
+			chunks = append(chunks, queryChunk{
+				name:  fmt.Sprintf("%s (%d packages)", dirs[start], end-start),
+				paths: dirs[start:end],
+			})
+		}
+		return chunks, nil
+	}
+	return nil, nil
+}
+
+// packageDirs returns the sorted absolute directories of the root that have
+// java files, the hidden and build output directories are left out.
+func packageDirs(root string) ([]string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	err = filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != abs && (strings.HasPrefix(d.Name(), ".") || d.Name() == "target" || d.Name() == "build") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == JavaFile {
+			found[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	for dir := range found {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// getQueryChunks returns the chunks of the workspace, they are computed once
+// and again after java files are created or deleted
+func (p *javaServiceClient) getQueryChunks() []queryChunk {
+	p.chunksMutex.Lock()
+	defer p.chunksMutex.Unlock()
+	if p.chunks != nil {
+		return p.chunks
+	}
+	chunks, err := p.chunking.chunks(p.config.WalkRoots())
+	if err != nil {
+		p.log.Error(err, "unable to split the workspace in chunks, the queries are not split")
+		return nil
+	}
+	if chunks == nil {
+		chunks = []queryChunk{}
+	}
+	p.chunks = chunks
+	return chunks
+}
+
+// resetQueryChunks drops the chunks when java files were created or deleted
+func (p *javaServiceClient) resetQueryChunks(changes []provider.FileChange) {
+	for _, c := range changes {
+		if c.Type != provider.FileChanged && filepath.Ext(c.URI.Filename()) == JavaFile {
+			p.chunksMutex.Lock()
+			p.chunks = nil
+			p.chunksMutex.Unlock()
+			return
+		}
+	}
+}
+
+// querySymbolsInChunks sends the query for every chunk of the workspace and
+// merges their symbols. The chunks whose query fails are reported as
+// warnings and their symbols are missing, it only fails when all of them do.
+func (p *javaServiceClient) querySymbolsInChunks(ctx context.Context, chunks []queryChunk, query, location string) ([]protocol.WorkspaceSymbol, error) {
+	symbols := []protocol.WorkspaceSymbol{}
+	seen := map[string]bool{}
+	failed := 0
+	var lastErr error
+	for _, chunk := range chunks {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		refs, err := p.querySymbols(ctx, query, location, chunk.paths)
+		if err != nil {
+			failed++
+			lastErr = err
+			p.log.Error(err, "symbol query failed on a chunk of the workspace", "query", query, "chunk", chunk.name)
+			p.warnings.Warn(fmt.Sprintf("the query of %s failed on a part of the workspace, its incidents there are missing", query), chunk.name)
+			continue
+		}
+		for _, s := range refs {
+			key := symbolKey(s)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			symbols = append(symbols, s)
+		}
+	}
+	if failed > 0 && failed == len(chunks) {
+		return nil, fmt.Errorf("the query of %s failed on all the %d chunks of the workspace: %w", query, failed, lastErr)
+	}
+	return symbols, nil
+}
+
+// symbolKey identifies a symbol found by several chunks, such as the ones of
+// nested roots
+func symbolKey(s protocol.WorkspaceSymbol) string {
+	location, _ := json.Marshal(s.Location)
+	return fmt.Sprintf("%s\x00%d\x00%s", s.Name, s.Kind, location)
+}
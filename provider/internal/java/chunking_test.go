@@ -0,0 +1,120 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestGetQueryChunking(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		want    queryChunking
+		wantErr bool
+	}{
+		{
+			name:   "not split by default",
+			config: map[string]interface{}{},
+			want:   queryChunking{strategy: chunkingNone, size: defaultQueryChunkSize},
+		},
+		{
+			name:   "chunk size from json",
+			config: map[string]interface{}{QUERY_CHUNKING_INIT_OPTION: "package", QUERY_CHUNK_SIZE_INIT_OPTION: float64(20)},
+			want:   queryChunking{strategy: chunkingPackage, size: 20},
+		},
+		{
+			name:    "unknown strategy",
+			config:  map[string]interface{}{QUERY_CHUNKING_INIT_OPTION: "module"},
+			wantErr: true,
+		},
+		{
+			name:    "empty chunks",
+			config:  map[string]interface{}{QUERY_CHUNKING_INIT_OPTION: "package", QUERY_CHUNK_SIZE_INIT_OPTION: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getQueryChunking(provider.InitConfig{ProviderSpecificConfig: tt.config})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getQueryChunking() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("getQueryChunking() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryChunks(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"src/main/java/com/example/App.java",
+		"src/main/java/com/example/Lib.java",
+		"src/main/java/com/example/web/Servlet.java",
+		"src/test/java/com/example/AppTest.java",
+		"src/main/resources/application.properties",
+		"target/generated-sources/com/example/Gen.java",
+		".git/App.java",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := func(rel string) string {
+		return filepath.Join(root, filepath.FromSlash(rel))
+	}
+
+	chunks, err := queryChunking{strategy: chunkingPackage, size: 2}.chunks([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []queryChunk{
+		{name: dir("src/main/java/com/example") + " (2 packages)", paths: []string{dir("src/main/java/com/example"), dir("src/main/java/com/example/web")}},
+		{name: dir("src/test/java/com/example") + " (1 packages)", paths: []string{dir("src/test/java/com/example")}},
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("expected the package chunks %v, got %v", want, chunks)
+	}
+
+	chunks, err = queryChunking{strategy: chunkingSourceRoot}.chunks([]string{root, dir("src/main")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || !reflect.DeepEqual(chunks[1].paths, []string{dir("src/main")}) {
+		t.Errorf("expected a chunk by root, got %v", chunks)
+	}
+
+	chunks, err = queryChunking{strategy: chunkingNone}.chunks([]string{root})
+	if err != nil || chunks != nil {
+		t.Errorf("expected the queries not to be split, got %v %v", chunks, err)
+	}
+}
+
+func TestSymbolKey(t *testing.T) {
+	location := func(uri string, line uint32) protocol.OrPLocation_workspace_symbol {
+		return protocol.OrPLocation_workspace_symbol{Value: protocol.Location{URI: uri, Range: protocol.Range{Start: protocol.Position{Line: line}}}}
+	}
+	symbol := func(line uint32) protocol.WorkspaceSymbol {
+		return protocol.WorkspaceSymbol{
+			BaseSymbolInformation: protocol.BaseSymbolInformation{Name: "App", Kind: protocol.Class},
+			Location:              location("file:///a/App.java", line),
+		}
+	}
+	a, same, other := symbol(1), symbol(1), symbol(2)
+	if symbolKey(a) != symbolKey(same) {
+		t.Errorf("expected the same symbol found by two chunks to have the same key")
+	}
+	if symbolKey(a) == symbolKey(other) {
+		t.Errorf("expected symbols at different locations to have different keys")
+	}
+}
@@ -0,0 +1,88 @@
+package java
+
+import "regexp"
+
+// httpSessionInventory finds state kept in the HTTP session, which needs
+// session replication or sticky sessions once the application is scaled out.
+var httpSessionInventory = inventory{
+	code: []codeQuery{
+		{kind: "session-attribute", pattern: "javax.servlet.http.HttpSession.setAttribute", location: "method_call"},
+		{kind: "session-attribute", pattern: "javax.servlet.http.HttpSession.getAttribute", location: "method_call"},
+		{kind: "session-attribute", pattern: "jakarta.servlet.http.HttpSession.setAttribute", location: "method_call"},
+		{kind: "session-attribute", pattern: "jakarta.servlet.http.HttpSession.getAttribute", location: "method_call"},
+		{kind: "session-access", pattern: "javax.servlet.http.HttpServletRequest.getSession", location: "method_call"},
+		{kind: "session-access", pattern: "jakarta.servlet.http.HttpServletRequest.getSession", location: "method_call"},
+		{kind: "session-scoped-bean", pattern: "javax.enterprise.context.SessionScoped", location: "annotation"},
+		{kind: "session-scoped-bean", pattern: "jakarta.enterprise.context.SessionScoped", location: "annotation"},
+		{kind: "session-scoped-bean", pattern: "org.springframework.web.context.annotation.SessionScope", location: "annotation"},
+		{kind: "stateful-bean", pattern: "javax.ejb.Stateful", location: "annotation"},
+		{kind: "stateful-bean", pattern: "jakarta.ejb.Stateful", location: "annotation"},
+	},
+	config: []configQuery{
+		{
+			kind:    "session-config",
+			files:   regexp.MustCompile(`^web\.xml$`),
+			pattern: regexp.MustCompile(`<(?:session-config|distributable)\b`),
+		},
+		{
+			kind:    "session-config",
+			files:   regexp.MustCompile(`^ejb-jar\.xml$`),
+			pattern: regexp.MustCompile(`<session-type>\s*Stateful\s*<`),
+		},
+		{
+			kind:    "session-config",
+			files:   regexp.MustCompile(`^application.*\.(properties|ya?ml)$`),
+			pattern: regexp.MustCompile(`^\s*(?:server\.servlet\.session|spring\.session)\.`),
+		},
+	},
+}
+
+// fileSystemWriteInventory finds writes to the local file system, which is
+// ephemeral in a container.
+var fileSystemWriteInventory = inventory{
+	code: []codeQuery{
+		{kind: "file-stream", pattern: "java.io.FileOutputStream", location: "constructor_call"},
+		{kind: "file-stream", pattern: "java.io.FileWriter", location: "constructor_call"},
+		{kind: "file-stream", pattern: "java.io.RandomAccessFile", location: "constructor_call"},
+		{kind: "file-stream", pattern: "java.io.PrintWriter", location: "constructor_call"},
+		{kind: "nio-write", pattern: "java.nio.file.Files.write*", location: "method_call"},
+		{kind: "nio-write", pattern: "java.nio.file.Files.newOutputStream", location: "method_call"},
+		{kind: "nio-write", pattern: "java.nio.file.Files.newBufferedWriter", location: "method_call"},
+		{kind: "nio-write", pattern: "java.nio.file.Files.copy", location: "method_call"},
+		{kind: "nio-write", pattern: "java.nio.file.Files.move", location: "method_call"},
+		{kind: "file-create", pattern: "java.nio.file.Files.create*", location: "method_call"},
+		{kind: "file-create", pattern: "java.io.File.createNewFile", location: "method_call"},
+		{kind: "file-create", pattern: "java.io.File.createTempFile", location: "method_call"},
+		{kind: "file-create", pattern: "java.io.File.mkdir*", location: "method_call"},
+	},
+	config: []configQuery{
+		{
+			kind:    "local-path-config",
+			files:   regexp.MustCompile(`.*\.(properties|ya?ml)$`),
+			pattern: regexp.MustCompile(`(?i)^\s*[\w.-]*(?:dir|directory|path|folder|location)\s*[=:]\s*["']?(?P<path>(?:/|[a-zA-Z]:\\|file:)[^"'\s]*)`),
+		},
+	},
+}
+
+// staticStateInventory finds mutable state kept in static fields, which is not
+// shared between replicas of the application.
+var staticStateInventory = inventory{
+	config: []configQuery{
+		{
+			kind:    "static-field",
+			files:   regexp.MustCompile(`.*\.java$`),
+			pattern: regexp.MustCompile(`^\s*(?:(?:public|protected|private)\s+)?static\s+(?:(?:volatile|transient)\s+)*(?P<type>[\w.$<>\[\], ?]+?)\s+(?P<field>\w+)\s*(?:=|;)`),
+			exclude: regexp.MustCompile(`\bfinal\b`),
+		},
+		{
+			kind:    "static-mutable-collection",
+			files:   regexp.MustCompile(`.*\.java$`),
+			pattern: regexp.MustCompile(`^\s*(?:(?:public|protected|private)\s+)?(?:static\s+final|final\s+static)\s+(?P<type>(?:java\.util\.(?:concurrent\.)?)?(?:Map|HashMap|LinkedHashMap|TreeMap|ConcurrentHashMap|ConcurrentMap|List|ArrayList|LinkedList|Set|HashSet|TreeSet|Collection|Queue|Deque)\b[^=;]*?)\s+(?P<field>\w+)\s*=\s*new\b`),
+		},
+		{
+			kind:    "singleton",
+			files:   regexp.MustCompile(`.*\.java$`),
+			pattern: regexp.MustCompile(`@(?:javax\.ejb\.|jakarta\.ejb\.)?Singleton\b`),
+		},
+	},
+}
@@ -0,0 +1,39 @@
+package java
+
+import "regexp"
+
+// concurrencyInventory finds threads and executors that are not managed by the
+// container, with a summary of each class to scale the effort of moving to
+// managed executors.
+var concurrencyInventory = inventory{
+	code: []codeQuery{
+		{kind: "thread-creation", pattern: "java.lang.Thread", location: "constructor_call"},
+		{kind: "thread-creation", pattern: "java.lang.Thread", location: "inheritance"},
+		{kind: "thread-creation", pattern: "java.lang.Thread.ofPlatform", location: "method_call"},
+		{kind: "thread-creation", pattern: "java.lang.Thread.ofVirtual", location: "method_call"},
+		{kind: "thread-creation", pattern: "java.lang.Thread.startVirtualThread", location: "method_call"},
+		{kind: "executor-creation", pattern: "java.util.concurrent.Executors.new*", location: "method_call"},
+		{kind: "executor-creation", pattern: "java.util.concurrent.ThreadPoolExecutor", location: "constructor_call"},
+		{kind: "executor-creation", pattern: "java.util.concurrent.ScheduledThreadPoolExecutor", location: "constructor_call"},
+		{kind: "executor-creation", pattern: "java.util.concurrent.ForkJoinPool", location: "constructor_call"},
+		{kind: "thread-local", pattern: "java.lang.ThreadLocal", location: "constructor_call"},
+		{kind: "thread-local", pattern: "java.lang.InheritableThreadLocal", location: "constructor_call"},
+		{kind: "thread-local", pattern: "java.lang.ThreadLocal.withInitial", location: "method_call"},
+		{kind: "managed-executor", pattern: "javax.enterprise.concurrent.ManagedExecutorService", location: "type"},
+		{kind: "managed-executor", pattern: "jakarta.enterprise.concurrent.ManagedExecutorService", location: "type"},
+		{kind: "managed-executor", pattern: "org.eclipse.microprofile.context.ManagedExecutor", location: "type"},
+	},
+	config: []configQuery{
+		{
+			kind:    "synchronization",
+			files:   regexp.MustCompile(`.*\.java$`),
+			pattern: regexp.MustCompile(`\bsynchronized\s*[(]|^\s*(?:(?:public|protected|private|static|final)\s+)*synchronized\s`),
+		},
+		{
+			kind:    "thread-pool-config",
+			files:   regexp.MustCompile(`^application.*\.(properties|ya?ml)$`),
+			pattern: regexp.MustCompile(`^\s*(?P<property>[\w.-]*(?:thread-pool|threadpool|executor|task\.execution)[\w.-]*)\s*[=:]`),
+		},
+	},
+	summaryKind: "concurrency-summary",
+}
@@ -0,0 +1,46 @@
+package java
+
+import "regexp"
+
+// datasourceInventory finds JNDI lookups, datasource definitions and
+// connection strings. JNDI names found in the configuration are correlated
+// with the code that uses them.
+var datasourceInventory = inventory{
+	code: []codeQuery{
+		{kind: "jndi-lookup", pattern: "javax.naming.InitialContext.lookup", location: "method_call"},
+		{kind: "jndi-lookup", pattern: "javax.naming.Context.lookup", location: "method_call"},
+		{kind: "datasource-annotation", pattern: "javax.annotation.Resource", location: "annotation"},
+		{kind: "datasource-annotation", pattern: "jakarta.annotation.Resource", location: "annotation"},
+		{kind: "datasource-annotation", pattern: "javax.annotation.sql.DataSourceDefinition", location: "annotation"},
+		{kind: "datasource-annotation", pattern: "jakarta.annotation.sql.DataSourceDefinition", location: "annotation"},
+		{kind: "connection-string", pattern: "java.sql.DriverManager.getConnection", location: "method_call"},
+	},
+	config: []configQuery{
+		{
+			kind:    "datasource-definition",
+			files:   regexp.MustCompile(`.*\.xml$`),
+			pattern: regexp.MustCompile(`<(?:xa-)?datasource\b[^>]*\bjndi-name="(?P<name>[^"]+)"`),
+		},
+		{
+			kind:    "datasource-definition",
+			files:   regexp.MustCompile(`^persistence\.xml$`),
+			pattern: regexp.MustCompile(`<(?:non-)?jta-data-source>\s*(?P<name>[^<\s]+)\s*<`),
+		},
+		{
+			kind:    "datasource-definition",
+			files:   regexp.MustCompile(`^(web|ejb-jar|jboss-web|jboss-ejb3)\.xml$`),
+			pattern: regexp.MustCompile(`<(?:res-ref-name|resource-env-ref-name|jndi-name|lookup-name)>\s*(?P<name>[^<\s]+)\s*<`),
+		},
+		{
+			kind:    "datasource-definition",
+			files:   regexp.MustCompile(`^application.*\.(properties|ya?ml)$`),
+			pattern: regexp.MustCompile(`(?:spring\.datasource\.)?jndi-name\s*[=:]\s*["']?(?P<name>[^"'\s]+)`),
+		},
+		{
+			kind:    "connection-string",
+			files:   regexp.MustCompile(`.*\.(properties|ya?ml|xml|java)$`),
+			pattern: regexp.MustCompile(`(?P<url>jdbc:[a-zA-Z0-9]+:[^\s"'<>]+)`),
+		},
+	},
+	correlationKind: "jndi-reference",
+}
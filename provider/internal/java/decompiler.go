@@ -0,0 +1,205 @@
+package java
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const (
+	// DECOMPILER_INIT_OPTION is the decompiler of the binary locations and
+	// of the dependencies that have no sources, one of fernflower, cfr or
+	// none
+	DECOMPILER_INIT_OPTION = "decompiler"
+	// DECOMPILER_PATH_INIT_OPTION is the jar of the decompiler
+	DECOMPILER_PATH_INIT_OPTION = "decompilerPath"
+	// DECOMPILE_CACHE_INIT_OPTION is a directory the decompiled sources of
+	// the dependencies are kept in, by the checksum of their jar, so that a
+	// jar is only decompiled once across analyses
+	DECOMPILE_CACHE_INIT_OPTION = "decompileCache"
+)
+
+type decompilerName string
+
+const (
+	fernflowerDecompiler decompilerName = "fernflower"
+	cfrDecompiler        decompilerName = "cfr"
+	noDecompiler         decompilerName = "none"
+)
+
+var defaultDecompilerJars = map[decompilerName]string{
+	fernflowerDecompiler: "/bin/fernflower.jar",
+	cfrDecompiler:        "/bin/cfr.jar",
+}
+
+// decompiler decompiles the classes and the jars that have no sources, so
+// that the references in them are found
+type decompiler struct {
+	name     decompilerName
+	jar      string
+	cacheDir string
+}
+
+// getDecompiler reads the decompiler of the init config, fernflower when it
+// is not given
+func getDecompiler(config provider.InitConfig) (decompiler, error) {
+	d := decompiler{name: fernflowerDecompiler}
+	if s, ok := config.ProviderSpecificConfig[DECOMPILER_INIT_OPTION].(string); ok && s != "" {
+		d.name = decompilerName(s)
+	}
+	switch d.name {
+	case fernflowerDecompiler, cfrDecompiler, noDecompiler:
+	default:
+		return d, fmt.Errorf("invalid %s %q, it must be one of %s, %s or %s", DECOMPILER_INIT_OPTION, d.name, fernflowerDecompiler, cfrDecompiler, noDecompiler)
+	}
+	d.jar = defaultDecompilerJars[d.name]
+	if s, ok := config.ProviderSpecificConfig[DECOMPILER_PATH_INIT_OPTION].(string); ok && s != "" {
+		d.jar = s
+	}
+	if s, ok := config.ProviderSpecificConfig[DECOMPILE_CACHE_INIT_OPTION].(string); ok {
+		d.cacheDir = s
+	}
+	return d, nil
+}
+
+func (d decompiler) enabled() bool {
+	return d.name != noDecompiler
+}
+
+// decompileFile decompiles a class to the java file output, or a jar to the
+// jar of sources output. The jars are taken from the cache when it has them.
+func (d decompiler) decompileFile(ctx context.Context, input, output string) error {
+	if !strings.HasSuffix(input, JavaArchive) || d.cacheDir == "" {
+		return d.run(ctx, input, output)
+	}
+	key, err := d.cacheKey(input)
+	if err != nil {
+		return err
+	}
+	cached := filepath.Join(d.cacheDir, key+JavaArchive)
+	if _, err := os.Stat(cached); err == nil {
+		return copyFile(cached, output)
+	}
+	if err := d.run(ctx, input, output); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.cacheDir, 0755); err != nil {
+		return err
+	}
+	// the sources are copied under another name first, so that an analysis
+	// running at the same time never reads a jar that is partly written
+	tmp := fmt.Sprintf("%s.%d.tmp", cached, os.Getpid())
+	if err := copyFile(output, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, cached)
+}
+
+// cacheKey is the checksum of the jar, the same jar decompiled by another
+// decompiler has other sources
+func (d decompiler) cacheKey(jar string) (string, error) {
+	f, err := os.Open(jar)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", d.name, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+func (d decompiler) run(ctx context.Context, input, output string) error {
+	switch d.name {
+	case fernflowerDecompiler:
+		// fernflower writes the file of the same name as the input in the
+		// directory, the java file of a class
+		return exec.CommandContext(ctx, "java", "-jar", d.jar, input, filepath.Dir(output)).Run()
+	case cfrDecompiler:
+		if !strings.HasSuffix(input, JavaArchive) {
+			// cfr prints the source of a single class
+			source, err := exec.CommandContext(ctx, "java", "-jar", d.jar, input, "--silent", "true").Output()
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(output, source, 0644)
+		}
+		// cfr writes the sources of a jar in the tree of their packages,
+		// they are put in a jar as the sources jars are
+		dir, err := os.MkdirTemp(filepath.Dir(output), "cfr-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := exec.CommandContext(ctx, "java", "-jar", d.jar, input, "--outputdir", dir, "--silent", "true").Run(); err != nil {
+			return err
+		}
+		return zipJavaFiles(dir, output)
+	}
+	return fmt.Errorf("the %s decompiler can not decompile %s", d.name, input)
+}
+
+// zipJavaFiles writes the java files of the directory in the jar, by their
+// path in the directory
+func zipJavaFiles(dir, jar string) error {
+	f, err := os.Create(jar)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, JavaFile) {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entryWriter, err := w.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		source, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		_, err = io.Copy(entryWriter, source)
+		return err
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func copyFile(srcPath, destPath string) error {
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
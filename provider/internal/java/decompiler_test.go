@@ -0,0 +1,116 @@
+package java
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestGetDecompiler(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		want    decompiler
+		wantErr bool
+	}{
+		{
+			name:   "fernflower by default",
+			config: map[string]interface{}{},
+			want:   decompiler{name: fernflowerDecompiler, jar: "/bin/fernflower.jar"},
+		},
+		{
+			name:   "cfr with a cache",
+			config: map[string]interface{}{DECOMPILER_INIT_OPTION: "cfr", DECOMPILER_PATH_INIT_OPTION: "/opt/cfr-0.152.jar", DECOMPILE_CACHE_INIT_OPTION: "/cache"},
+			want:   decompiler{name: cfrDecompiler, jar: "/opt/cfr-0.152.jar", cacheDir: "/cache"},
+		},
+		{
+			name:   "none",
+			config: map[string]interface{}{DECOMPILER_INIT_OPTION: "none"},
+			want:   decompiler{name: noDecompiler},
+		},
+		{
+			name:    "unknown decompiler",
+			config:  map[string]interface{}{DECOMPILER_INIT_OPTION: "procyon"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getDecompiler(provider.InitConfig{ProviderSpecificConfig: tt.config})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getDecompiler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("getDecompiler() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecompileFileFromCache(t *testing.T) {
+	dir := t.TempDir()
+	jar := filepath.Join(dir, "lib-1.0.jar")
+	if err := os.WriteFile(jar, []byte("classes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the decompiler jar does not exist, the sources can only come from
+	// the cache
+	d := decompiler{name: cfrDecompiler, jar: filepath.Join(dir, "missing.jar"), cacheDir: filepath.Join(dir, "cache")}
+	key, err := d.cacheKey(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(d.cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(d.cacheDir, key+JavaArchive), []byte("sources"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "decompiled", "lib-1.0.jar")
+	os.MkdirAll(filepath.Dir(output), 0755)
+	if err := d.decompileFile(context.Background(), jar, output); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if content, _ := os.ReadFile(output); string(content) != "sources" {
+		t.Errorf("expected the cached sources, got %q", content)
+	}
+	other := decompiler{name: fernflowerDecompiler, cacheDir: d.cacheDir}
+	if otherKey, _ := other.cacheKey(jar); otherKey == key {
+		t.Errorf("expected the decompilers to have their own cache keys")
+	}
+}
+
+func TestZipJavaFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"com/example/App.java", "com/example/util/Strings.java", "summary.txt"} {
+		path := filepath.Join(dir, "out", filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("class"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jar := filepath.Join(dir, "lib-sources.jar")
+	if err := zipJavaFiles(filepath.Join(dir, "out"), jar); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	names := []string{}
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	want := []string{"com/example/App.java", "com/example/util/Strings.java"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected the entries %v, got %v", want, names)
+	}
+}
@@ -30,13 +30,29 @@ const (
 
 // TODO implement this for real
 func (p *javaServiceClient) findPom() string {
+	return p.findPomIn(p.config.Location)
+}
+
+// findPoms returns the pom of each root, so that the dependencies are
+// attributed to the module they are declared in
+func (p *javaServiceClient) findPoms() []string {
+	poms := []string{}
+	for _, root := range p.config.Roots() {
+		if pom := p.findPomIn(root); pom != "" {
+			poms = append(poms, pom)
+		}
+	}
+	return poms
+}
+
+func (p *javaServiceClient) findPomIn(root string) string {
 	var depPath string
 	if p.config.DependencyPath == "" {
 		depPath = "pom.xml"
 	} else {
 		depPath = p.config.DependencyPath
 	}
-	f, err := filepath.Abs(filepath.Join(p.config.Location, depPath))
+	f, err := filepath.Abs(filepath.Join(root, depPath))
 	if err != nil {
 		return ""
 	}
@@ -58,10 +74,12 @@ func (p *javaServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]
 		ll, err = p.GetDependenciesDAG(ctx)
 		if err != nil {
 			p.log.Info("unable to get dependencies, using fallback", "error", err)
+			p.warnings.Warn("unable to get the dependency tree, only the dependencies declared in the pom are used", p.config.Location)
 			return p.GetDependenciesFallback(ctx, "")
 		}
 		if len(ll) == 0 {
 			p.log.Info("unable to get dependencies (none found), using fallback")
+			p.warnings.Warn("unable to get the dependency tree, only the dependencies declared in the pom are used", p.config.Location)
 			return p.GetDependenciesFallback(ctx, "")
 		}
 	}
@@ -98,6 +116,23 @@ func getMavenLocalRepoPath(mvnSettingsFile string) string {
 }
 
 func (p *javaServiceClient) GetDependenciesFallback(ctx context.Context, location string) (map[uri.URI][]*provider.Dep, error) {
+	if location == "" && len(p.config.WorkspaceFolders) > 0 {
+		m := map[uri.URI][]*provider.Dep{}
+		for i, pom := range p.findPoms() {
+			if _, err := os.Stat(pom); err != nil && i > 0 {
+				continue
+			}
+			rootDeps, err := p.GetDependenciesFallback(ctx, pom)
+			if err != nil {
+				return nil, err
+			}
+			for depPath := range rootDeps {
+				m[depPath] = rootDeps[depPath]
+			}
+		}
+		p.depsCache = m
+		return m, nil
+	}
 	deps := []*provider.Dep{}
 
 	path, err := filepath.Abs(p.findPom())
@@ -128,6 +163,11 @@ func (p *javaServiceClient) GetDependenciesFallback(ctx context.Context, locatio
 		}
 	}
 
+	localRepoPath := ""
+	if p.licenses {
+		localRepoPath = getMavenLocalRepoPath(p.mvnSettingsFile)
+	}
+
 	// add each dependency found
 	for _, d := range pomDeps {
 		if d.GroupID == nil || d.Version == nil || d.ArtifactID == nil {
@@ -146,6 +186,9 @@ func (p *javaServiceClient) GetDependenciesFallback(ctx context.Context, locatio
 				dep.Version = *d.Version
 			}
 		}
+		if localRepoPath != "" && dep.Version != "" {
+			dep.License = pomLicense(mavenPomPath(localRepoPath, *d.GroupID, *d.ArtifactID, dep.Version))
+		}
 		deps = append(deps, &dep)
 	}
 
@@ -175,7 +218,22 @@ func (p *javaServiceClient) GetDependenciesFallback(ctx context.Context, locatio
 func (p *javaServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
 	localRepoPath := getMavenLocalRepoPath(p.mvnSettingsFile)
 
-	path := p.findPom()
+	m := map[uri.URI][]provider.DepDAGItem{}
+	for i, path := range p.findPoms() {
+		// only the location has to be a maven project, the other workspace
+		// folders are skipped when they are not
+		if _, err := os.Stat(path); err != nil && i > 0 {
+			continue
+		}
+		if err := p.getPomDependenciesDAG(path, localRepoPath, m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// getPomDependenciesDAG adds the dependency tree of the pom to the map
+func (p *javaServiceClient) getPomDependenciesDAG(path string, localRepoPath string, m map[uri.URI][]provider.DepDAGItem) error {
 	file := uri.File(path)
 
 	moddir := filepath.Dir(path)
@@ -192,9 +250,12 @@ func (p *javaServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI
 	// get the graph output
 	cmd := exec.Command("mvn", args...)
 	cmd.Dir = moddir
+	if env := p.projectJDK.env(); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	mvnOutput, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	lines := strings.Split(string(mvnOutput), "\n")
@@ -204,12 +265,11 @@ func (p *javaServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI
 	for _, tree := range submoduleTrees {
 		submoduleDeps, err := p.parseMavenDepLines(tree, localRepoPath)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		pomDeps = append(pomDeps, submoduleDeps...)
 	}
 
-	m := map[uri.URI][]provider.DepDAGItem{}
 	m[file] = pomDeps
 
 	if len(m) == 0 {
@@ -217,7 +277,7 @@ func (p *javaServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI
 		p.discoverDepsFromJars(moddir, m)
 	}
 
-	return m, nil
+	return nil
 }
 
 // extractSubmoduleTrees creates an array of lines for each submodule tree found in the mvn dependency:tree output
@@ -262,6 +322,7 @@ func (p *javaServiceClient) discoverDepsFromJars(path string, ll map[uri.URI][]k
 		deps:        ll,
 		depToLabels: p.depToLabels,
 		m2RepoPath:  getMavenLocalRepoPath(p.mvnSettingsFile),
+		licenses:    p.licenses,
 		seen:        map[string]bool{},
 	}
 	filepath.WalkDir(path, w.walkDirForJar)
@@ -271,6 +332,7 @@ type walker struct {
 	deps        map[uri.URI][]provider.DepDAGItem
 	depToLabels map[string]*depLabelItem
 	m2RepoPath  string
+	licenses    bool
 	seen        map[string]bool
 }
 
@@ -303,6 +365,9 @@ func (w *walker) walkDirForJar(path string, info fs.DirEntry, err error) error {
 					strings.Replace(artifact.GroupId, ".", "/", -1), artifact.ArtifactId, artifact.Version)
 			}
 		}
+		if w.licenses {
+			d.License = jarLicense(path)
+		}
 
 		w.deps[uri.URI(filepath.Join(path, info.Name()))] = []provider.DepDAGItem{
 			{
@@ -340,6 +405,7 @@ func (p *javaServiceClient) parseDepString(dep, localRepoPath string) (provider.
 	if err != nil {
 		// Log the error and continue with the next dependency.
 		p.log.V(5).Error(err, "error reading SHA hash file for dependency", "dep", d.Name)
+		p.warnings.Warn("dependencies not found in the local maven repository", d.Name)
 		// Set some default or empty resolved identifier for the dependency.
 		d.ResolvedIdentifier = ""
 	} else {
@@ -348,6 +414,9 @@ func (p *javaServiceClient) parseDepString(dep, localRepoPath string) (provider.
 
 	d.Labels = addDepLabels(p.depToLabels, d.Name)
 	d.FileURIPrefix = fmt.Sprintf("file://%v", filepath.Dir(fp))
+	if p.licenses {
+		d.License = pomLicense(mavenPomPath(localRepoPath, parts[0], parts[1], d.Version))
+	}
 
 	return d, nil
 }
@@ -468,7 +537,19 @@ func (p *javaServiceClient) initExcludeDepLabels() error {
 		return nil
 	}
 	var excludePackages []string
-	if excludePackages, ok = v.([]string); !ok {
+	switch packages := v.(type) {
+	case []string:
+		excludePackages = packages
+	case []interface{}:
+		// the lists decoded from the provider settings
+		for _, p := range packages {
+			s, ok := p.(string)
+			if !ok {
+				return fmt.Errorf("%s config must be a list of packages to exclude", providerSpecificConfigExcludePackagesKey)
+			}
+			excludePackages = append(excludePackages, s)
+		}
+	default:
 		return fmt.Errorf("%s config must be a list of packages to exclude", providerSpecificConfigExcludePackagesKey)
 	}
 	return loadDepLabelItems(strings.NewReader(
@@ -0,0 +1,116 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// maxSubtypes bounds the subtypes a referenced condition is extended to, the
+// types such as java.io.Serializable have thousands of them in the JDK
+const maxSubtypes = 1000
+
+// validateSubtypes checks that the pattern of a referenced condition that
+// includes the subtypes is a type, given by its qualified name
+func validateSubtypes(pattern, location string) error {
+	switch locationToCode[strings.ToLower(location)] {
+	case 2, 11:
+		return fmt.Errorf("includeSubtypes is not supported with location %s", location)
+	}
+	if strings.ContainsAny(pattern, "*?[]()|\\") || !strings.Contains(pattern, ".") {
+		return fmt.Errorf("includeSubtypes needs the qualified name of a type, got %s", pattern)
+	}
+	return nil
+}
+
+// getSubtypes returns the qualified names of the classes that extend or
+// implement the type, directly or not, as given by the type hierarchy of the
+// language server
+func (p *javaServiceClient) getSubtypes(ctx context.Context, name string) ([]string, error) {
+	types, err := p.findTypes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	queue := []protocol.TypeHierarchyItem{}
+	for _, t := range types {
+		params := &protocol.TypeHierarchyPrepareParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{
+					URI: t.Location.URI,
+				},
+				Position: t.Location.Range.Start,
+			},
+		}
+		var items []protocol.TypeHierarchyItem
+		if err := p.rpc.Call(ctx, "textDocument/prepareTypeHierarchy", params, &items); err != nil {
+			return nil, fmt.Errorf("unable to get the type hierarchy of %s: %w", name, err)
+		}
+		queue = append(queue, items...)
+	}
+
+	seen := map[string]bool{name: true}
+	subtypes := []string{}
+	for len(queue) > 0 && len(subtypes) < maxSubtypes {
+		item := queue[0]
+		queue = queue[1:]
+		var items []protocol.TypeHierarchyItem
+		if err := p.rpc.Call(ctx, "typeHierarchy/subtypes", &protocol.TypeHierarchySubtypesParams{Item: item}, &items); err != nil {
+			return nil, fmt.Errorf("unable to get the subtypes of %s: %w", qualifiedName(item), err)
+		}
+		for _, i := range items {
+			n := qualifiedName(i)
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			subtypes = append(subtypes, n)
+			queue = append(queue, i)
+		}
+	}
+	if len(subtypes) >= maxSubtypes {
+		p.log.Info("too many subtypes, only the first ones are matched", "type", name, "subtypes", len(subtypes))
+	}
+	sort.Strings(subtypes)
+	p.log.V(5).Info("subtypes retrieved", "type", name, "subtypes", subtypes)
+	return subtypes, nil
+}
+
+// findTypes returns the declarations of the type with the qualified name,
+// in the sources or the dependencies
+func (p *javaServiceClient) findTypes(ctx context.Context, name string) ([]protocol.SymbolInformation, error) {
+	container, simpleName := name, name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		container, simpleName = name[:i], name[i+1:]
+	}
+	var symbols []protocol.SymbolInformation
+	if err := p.rpc.Call(ctx, "workspace/symbol", &protocol.WorkspaceSymbolParams{Query: simpleName}, &symbols); err != nil {
+		return nil, fmt.Errorf("unable to find type %s: %w", name, err)
+	}
+	types := []protocol.SymbolInformation{}
+	for _, s := range symbols {
+		switch s.Kind {
+		case protocol.Class, protocol.Interface, protocol.Enum:
+			if s.Name == simpleName && s.ContainerName == container {
+				types = append(types, s)
+			}
+		}
+	}
+	return types, nil
+}
+
+// qualifiedName returns the qualified name of a type of the hierarchy, of
+// which the detail is the package. The nested types are named with a $ by
+// jdtls, they are named with a . in the patterns.
+func qualifiedName(item protocol.TypeHierarchyItem) string {
+	name := strings.ReplaceAll(item.Name, "$", ".")
+	switch {
+	case item.Detail == "":
+		return name
+	case strings.HasSuffix(item.Detail, "."+name):
+		return item.Detail
+	}
+	return item.Detail + "." + name
+}
@@ -0,0 +1,86 @@
+package java
+
+import (
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+func TestValidateSubtypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		location string
+		wantErr  bool
+	}{
+		{
+			name:    "type",
+			pattern: "javax.ejb.SessionBean",
+		},
+		{
+			name:     "inheritance",
+			pattern:  "javax.ejb.SessionBean",
+			location: "INHERITANCE",
+		},
+		{
+			name:     "method call",
+			pattern:  "javax.ejb.SessionBean",
+			location: "METHOD_CALL",
+			wantErr:  true,
+		},
+		{
+			name:    "wildcard",
+			pattern: "javax.ejb.*",
+			wantErr: true,
+		},
+		{
+			name:    "simple name",
+			pattern: "SessionBean",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSubtypes(tt.pattern, tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	tests := []struct {
+		name string
+		item protocol.TypeHierarchyItem
+		want string
+	}{
+		{
+			name: "package",
+			item: protocol.TypeHierarchyItem{Name: "OrderBean", Detail: "com.example.ejb"},
+			want: "com.example.ejb.OrderBean",
+		},
+		{
+			name: "nested type",
+			item: protocol.TypeHierarchyItem{Name: "Orders$Bean", Detail: "com.example.ejb"},
+			want: "com.example.ejb.Orders.Bean",
+		},
+		{
+			name: "qualified detail",
+			item: protocol.TypeHierarchyItem{Name: "OrderBean", Detail: "com.example.ejb.OrderBean"},
+			want: "com.example.ejb.OrderBean",
+		},
+		{
+			name: "default package",
+			item: protocol.TypeHierarchyItem{Name: "OrderBean"},
+			want: "OrderBean",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := qualifiedName(tt.item); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
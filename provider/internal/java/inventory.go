@@ -0,0 +1,546 @@
+package java
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	INVENTORY_SOURCE_CODE   = "code"
+	INVENTORY_SOURCE_CONFIG = "config"
+)
+
+// inventory is a capability that is made up of a set of well known code
+// references and configuration entries for a given topic.
+type inventory struct {
+	// code are referenced queries that are run against the language server
+	code []codeQuery
+	// config are line based searches of configuration files found in the location
+	config []configQuery
+	// files are searches of the files found in the location by name
+	files []fileQuery
+	// correlationKind is the kind of the incidents created for code that
+	// refers to names found in the configuration, empty disables correlation.
+	correlationKind string
+	// summaryKind is the kind of the incidents that aggregate the incidents of
+	// each file, empty disables the summaries.
+	summaryKind string
+	// consolidatedKind is the kind of the incidents that aggregate the
+	// incidents with the same value of the consolidateBy variable, so that a
+	// rule matches once for each value, empty disables the consolidation.
+	consolidatedKind string
+	consolidateBy    string
+}
+
+type codeQuery struct {
+	kind     string
+	pattern  string
+	location string
+	// variables are added to every incident found by the query
+	variables map[string]interface{}
+}
+
+type configQuery struct {
+	kind string
+	// files is matched against the file name
+	files *regexp.Regexp
+	// pattern is matched against every line of the file, named groups are
+	// added to the incident, the "name" group is used for correlation.
+	pattern *regexp.Regexp
+	// exclude skips the lines matching it, when set
+	exclude *regexp.Regexp
+	// variables are added to every incident found by the query
+	variables map[string]interface{}
+	// enrich can add variables derived from the matched ones
+	enrich func(variables map[string]interface{})
+}
+
+type fileQuery struct {
+	kind string
+	// files is matched against the file name, named groups are added to the incident
+	files *regexp.Regexp
+}
+
+// inventoryCondition is the condition for all the inventory capabilities
+type inventoryCondition struct {
+	// Kinds limit the kinds of incidents that are returned, all are returned when empty.
+	Kinds []string `yaml:"kinds"`
+}
+
+var inventories = map[string]inventory{
+	"datasource":      datasourceInventory,
+	"httpsession":     httpSessionInventory,
+	"filesystemwrite": fileSystemWriteInventory,
+	"staticstate":     staticStateInventory,
+	"scheduler":       schedulerInventory,
+	"classloading":    classLoadingInventory,
+	"packaging":       packagingInventory,
+	"transaction":     transactionInventory,
+	"jni":             jniInventory,
+	"concurrency":     concurrencyInventory,
+	"logging":         loggingInventory,
+}
+
+func inventoryNames() []string {
+	names := []string{}
+	for name := range inventories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c inventoryCondition) wants(kind string) bool {
+	if len(c.Kinds) == 0 {
+		return true
+	}
+	for _, k := range c.Kinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func getInventoryCondition(cap string, conditionInfo []byte) (inventoryCondition, error) {
+	cond := inventoryCondition{}
+	m := map[string]interface{}{}
+	err := yaml.Unmarshal(conditionInfo, &m)
+	if err != nil {
+		return cond, fmt.Errorf("unable to get query info: %v", err)
+	}
+	if m[cap] == nil {
+		return cond, nil
+	}
+	b, err := yaml.Marshal(m[cap])
+	if err != nil {
+		return cond, fmt.Errorf("unable to get query info: %v", err)
+	}
+	err = yaml.Unmarshal(b, &cond)
+	if err != nil {
+		return cond, fmt.Errorf("unable to get query info: %v", err)
+	}
+	return cond, nil
+}
+
+func (p *javaServiceClient) evaluateInventory(ctx context.Context, cap string, inv inventory, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	cond, err := getInventoryCondition(cap, conditionInfo)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	scope, err := getScope(conditionInfo)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+
+	// the summaries aggregate all the kinds, even the ones that are not returned
+	summarize := inv.summaryKind != "" && cond.wants(inv.summaryKind)
+	consolidate := inv.consolidatedKind != "" && cond.wants(inv.consolidatedKind)
+	wants := func(kind string) bool {
+		return summarize || consolidate || cond.wants(kind)
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, q := range inv.code {
+		if !wants(q.kind) {
+			continue
+		}
+		refs, err := p.getReferencedIncidents(ctx, q.pattern, q.location, nil, scope)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		for _, inc := range refs {
+			if inc.Variables == nil {
+				inc.Variables = map[string]interface{}{}
+			}
+			for k, v := range q.variables {
+				inc.Variables[k] = v
+			}
+			inc.Variables["kind"] = q.kind
+			inc.Variables["source"] = INVENTORY_SOURCE_CODE
+			incidents = append(incidents, inc)
+		}
+	}
+
+	configQueries := []configQuery{}
+	for _, q := range inv.config {
+		if wants(q.kind) {
+			configQueries = append(configQueries, q)
+		}
+	}
+	fileQueries := []fileQuery{}
+	for _, q := range inv.files {
+		if wants(q.kind) {
+			fileQueries = append(fileQueries, q)
+		}
+	}
+
+	// the names of the config files are only correlated with the code of
+	// the same root
+	for _, root := range p.config.WalkRoots() {
+		configIncidents, err := searchConfigFiles(root, scope, configQueries)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		fileIncidents, err := searchFiles(root, scope, fileQueries)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		if inv.correlationKind != "" && cond.wants(inv.correlationKind) {
+			correlated, err := correlateConfigNames(root, scope, inv.correlationKind, configIncidents)
+			if err != nil {
+				return provider.ProviderEvaluateResponse{}, err
+			}
+			incidents = append(incidents, correlated...)
+		}
+		incidents = append(incidents, configIncidents...)
+		incidents = append(incidents, fileIncidents...)
+	}
+
+	if summarize || consolidate {
+		aggregated := []provider.IncidentContext{}
+		if summarize {
+			aggregated = append(aggregated, summarizeByFile(inv.summaryKind, incidents)...)
+		}
+		if consolidate {
+			aggregated = append(aggregated, consolidateIncidents(inv.consolidatedKind, inv.consolidateBy, incidents)...)
+		}
+		filtered := []provider.IncidentContext{}
+		for _, inc := range incidents {
+			if kind, _ := inc.Variables["kind"].(string); cond.wants(kind) {
+				filtered = append(filtered, inc)
+			}
+		}
+		incidents = append(filtered, aggregated...)
+	}
+
+	return provider.ProviderEvaluateResponse{
+		Matched:   len(incidents) > 0,
+		Incidents: incidents,
+	}, nil
+}
+
+// summarizeByFile creates an incident for each file with the number of
+// incidents of each kind found in it. Where the incidents have a "model"
+// variable, the summary has the model of the file, or "mixed" when the
+// incidents do not agree.
+func summarizeByFile(kind string, incidents []provider.IncidentContext) []provider.IncidentContext {
+	files := []uri.URI{}
+	counts := map[uri.URI]map[string]interface{}{}
+	models := map[uri.URI]string{}
+	for _, inc := range incidents {
+		if _, ok := counts[inc.FileURI]; !ok {
+			files = append(files, inc.FileURI)
+			counts[inc.FileURI] = map[string]interface{}{}
+		}
+		k, _ := inc.Variables["kind"].(string)
+		n, _ := counts[inc.FileURI][k].(int)
+		counts[inc.FileURI][k] = n + 1
+		if model, ok := inc.Variables["model"].(string); ok {
+			if current, ok := models[inc.FileURI]; ok && current != model {
+				model = "mixed"
+			}
+			models[inc.FileURI] = model
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i] < files[j] })
+
+	summaries := []provider.IncidentContext{}
+	for _, file := range files {
+		inc := provider.IncidentContext{
+			FileURI: file,
+			Variables: map[string]interface{}{
+				"kind":   kind,
+				"source": INVENTORY_SOURCE_CODE,
+				"counts": counts[file],
+			},
+		}
+		name := filepath.Base(string(file))
+		if filepath.Ext(name) == JavaFile {
+			inc.Variables["class"] = strings.TrimSuffix(name, JavaFile)
+		} else {
+			inc.Variables["source"] = INVENTORY_SOURCE_CONFIG
+		}
+		if model, ok := models[file]; ok {
+			inc.Variables["model"] = model
+		}
+		summaries = append(summaries, inc)
+	}
+	return summaries
+}
+
+// consolidateIncidents creates an incident for each value of the variable, located at
+// the first incident with the value and listing all of them in the
+// "locations" variable. The "sources" variable has the sources the value was
+// found in.
+func consolidateIncidents(kind, variable string, incidents []provider.IncidentContext) []provider.IncidentContext {
+	values := []string{}
+	grouped := map[string][]provider.IncidentContext{}
+	for _, inc := range incidents {
+		value, ok := inc.Variables[variable].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if _, ok := grouped[value]; !ok {
+			values = append(values, value)
+		}
+		grouped[value] = append(grouped[value], inc)
+	}
+	sort.Strings(values)
+
+	consolidated := []provider.IncidentContext{}
+	for _, value := range values {
+		group := grouped[value]
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].FileURI != group[j].FileURI {
+				return group[i].FileURI < group[j].FileURI
+			}
+			return lineNumber(group[i]) < lineNumber(group[j])
+		})
+		locations := []interface{}{}
+		sources := []interface{}{}
+		seenSources := map[string]bool{}
+		for _, inc := range group {
+			k, _ := inc.Variables["kind"].(string)
+			location := map[string]interface{}{
+				"file": string(inc.FileURI),
+				"kind": k,
+			}
+			if inc.LineNumber != nil {
+				location["line"] = *inc.LineNumber
+			}
+			locations = append(locations, location)
+			if source, ok := inc.Variables["source"].(string); ok && !seenSources[source] {
+				seenSources[source] = true
+				sources = append(sources, source)
+			}
+		}
+		first := group[0]
+		consolidated = append(consolidated, provider.IncidentContext{
+			FileURI:      first.FileURI,
+			LineNumber:   first.LineNumber,
+			CodeLocation: first.CodeLocation,
+			Variables: map[string]interface{}{
+				"kind":      kind,
+				variable:    value,
+				"sources":   sources,
+				"locations": locations,
+			},
+		})
+	}
+	return consolidated
+}
+
+func lineNumber(inc provider.IncidentContext) int {
+	if inc.LineNumber == nil {
+		return 0
+	}
+	return *inc.LineNumber
+}
+
+// getScope returns the scope the engine passed along with the condition
+func getScope(conditionInfo []byte) (*engine.Scope, error) {
+	cond := struct {
+		provider.ProviderContext `yaml:",inline"`
+	}{}
+	err := yaml.Unmarshal(conditionInfo, &cond)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get query info: %v", err)
+	}
+	return cond.Scope, nil
+}
+
+// walkLocation calls fn for every regular file in scope under the location,
+// skipping hidden directories and build output.
+func walkLocation(location string, scope *engine.Scope, fn func(path string) error) error {
+	return filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != location && (strings.HasPrefix(d.Name(), ".") || d.Name() == "target" || scope.ExcludesDir(location, path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !scope.Matches(location, path) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// scanLines calls fn for each line of the file with a one based line number.
+func scanLines(path string, fn func(lineNumber int, line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fn(lineNumber, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func newLineIncident(path string, lineNumber int) provider.IncidentContext {
+	ab, err := filepath.Abs(path)
+	if err != nil {
+		ab = path
+	}
+	ln := lineNumber
+	return provider.IncidentContext{
+		FileURI:    uri.File(ab),
+		LineNumber: &ln,
+		Variables:  map[string]interface{}{},
+		CodeLocation: &provider.Location{
+			StartPosition: provider.Position{Line: float64(lineNumber)},
+			EndPosition:   provider.Position{Line: float64(lineNumber)},
+		},
+	}
+}
+
+func searchConfigFiles(location string, scope *engine.Scope, queries []configQuery) ([]provider.IncidentContext, error) {
+	incidents := []provider.IncidentContext{}
+	if len(queries) == 0 {
+		return incidents, nil
+	}
+	err := walkLocation(location, scope, func(path string) error {
+		fileQueries := []configQuery{}
+		for _, q := range queries {
+			if q.files.MatchString(filepath.Base(path)) {
+				fileQueries = append(fileQueries, q)
+			}
+		}
+		if len(fileQueries) == 0 {
+			return nil
+		}
+		return scanLines(path, func(lineNumber int, line string) {
+			for _, q := range fileQueries {
+				match := q.pattern.FindStringSubmatch(line)
+				if match == nil || (q.exclude != nil && q.exclude.MatchString(line)) {
+					continue
+				}
+				inc := newLineIncident(path, lineNumber)
+				for k, v := range q.variables {
+					inc.Variables[k] = v
+				}
+				// named groups become variables of the incident
+				for i, group := range q.pattern.SubexpNames() {
+					if group != "" && match[i] != "" {
+						inc.Variables[group] = match[i]
+					}
+				}
+				if q.enrich != nil {
+					q.enrich(inc.Variables)
+				}
+				inc.Variables["kind"] = q.kind
+				inc.Variables["source"] = INVENTORY_SOURCE_CONFIG
+				inc.Variables["matchingText"] = strings.TrimSpace(match[0])
+				incidents = append(incidents, inc)
+			}
+		})
+	})
+	return incidents, err
+}
+
+// searchFiles creates an incident for every file with a name matching a query
+func searchFiles(location string, scope *engine.Scope, queries []fileQuery) ([]provider.IncidentContext, error) {
+	incidents := []provider.IncidentContext{}
+	if len(queries) == 0 {
+		return incidents, nil
+	}
+	err := walkLocation(location, scope, func(path string) error {
+		name := filepath.Base(path)
+		for _, q := range queries {
+			match := q.files.FindStringSubmatch(name)
+			if match == nil {
+				continue
+			}
+			ab, err := filepath.Abs(path)
+			if err != nil {
+				ab = path
+			}
+			inc := provider.IncidentContext{
+				FileURI: uri.File(ab),
+				Variables: map[string]interface{}{
+					"kind":   q.kind,
+					"source": INVENTORY_SOURCE_CONFIG,
+					"file":   name,
+				},
+			}
+			for i, group := range q.files.SubexpNames() {
+				if group != "" && match[i] != "" {
+					inc.Variables[group] = match[i]
+				}
+			}
+			incidents = append(incidents, inc)
+		}
+		return nil
+	})
+	return incidents, err
+}
+
+// correlateConfigNames finds java sources that refer to the names found in the
+// configuration as string literals. The code incident gets the configuration it
+// refers to, and the configuration incidents get the code that refers to them.
+func correlateConfigNames(location string, scope *engine.Scope, kind string, configIncidents []provider.IncidentContext) ([]provider.IncidentContext, error) {
+	names := map[string][]int{}
+	for idx, inc := range configIncidents {
+		if name, ok := inc.Variables["name"].(string); ok {
+			names[name] = append(names[name], idx)
+		}
+	}
+	incidents := []provider.IncidentContext{}
+	if len(names) == 0 {
+		return incidents, nil
+	}
+	sortedNames := []string{}
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	err := walkLocation(location, scope, func(path string) error {
+		if filepath.Ext(path) != JavaFile {
+			return nil
+		}
+		return scanLines(path, func(lineNumber int, line string) {
+			for _, name := range sortedNames {
+				configIdxs := names[name]
+				if !strings.Contains(line, fmt.Sprintf("\"%s\"", name)) {
+					continue
+				}
+				inc := newLineIncident(path, lineNumber)
+				configuredIn := []interface{}{}
+				for _, i := range configIdxs {
+					configuredIn = append(configuredIn, string(configIncidents[i].FileURI))
+					referencedIn, _ := configIncidents[i].Variables["referencedIn"].([]interface{})
+					configIncidents[i].Variables["referencedIn"] = append(referencedIn, string(inc.FileURI))
+				}
+				inc.Variables["kind"] = kind
+				inc.Variables["source"] = INVENTORY_SOURCE_CODE
+				inc.Variables["name"] = name
+				inc.Variables["matchingText"] = strings.TrimSpace(line)
+				inc.Variables["configuredIn"] = configuredIn
+				incidents = append(incidents, inc)
+			}
+		})
+	})
+	return incidents, err
+}
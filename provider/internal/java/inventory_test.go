@@ -0,0 +1,374 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func Test_searchConfigFilesAndCorrelate(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"src/main/resources/META-INF/persistence.xml": "<persistence-unit name=\"pu\">\n  <jta-data-source>java:jboss/datasources/ExampleDS</jta-data-source>\n</persistence-unit>\n",
+		"src/main/resources/application.properties":   "db.url=jdbc:postgresql://localhost:5432/db\n",
+		"src/main/java/com/example/Repo.java":         "class Repo {\n  Object ds = ctx.lookup(\"java:jboss/datasources/ExampleDS\");\n}\n",
+		"target/classes/META-INF/persistence.xml":     "<jta-data-source>java:jboss/datasources/Ignored</jta-data-source>\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configIncidents, err := searchConfigFiles(location, nil, datasourceInventory.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotKinds := map[string]string{}
+	for _, inc := range configIncidents {
+		gotKinds[inc.Variables["matchingText"].(string)] = inc.Variables["kind"].(string)
+	}
+	expectedKinds := map[string]string{
+		"<jta-data-source>java:jboss/datasources/ExampleDS<": "datasource-definition",
+		"jdbc:postgresql://localhost:5432/db":                "connection-string",
+	}
+	if !reflect.DeepEqual(gotKinds, expectedKinds) {
+		t.Errorf("expected config incidents %v, got %v", expectedKinds, gotKinds)
+	}
+
+	correlated, err := correlateConfigNames(location, nil, "jndi-reference", configIncidents)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(correlated) != 1 {
+		t.Fatalf("expected a single correlated incident, got %v", correlated)
+	}
+	if *correlated[0].LineNumber != 2 || correlated[0].Variables["name"] != "java:jboss/datasources/ExampleDS" {
+		t.Errorf("unexpected correlated incident %v", correlated[0])
+	}
+	for _, inc := range configIncidents {
+		if inc.Variables["name"] == "java:jboss/datasources/ExampleDS" && inc.Variables["referencedIn"] == nil {
+			t.Errorf("expected configuration to refer to the code using it")
+		}
+	}
+}
+
+func Test_staticStateInventory(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "    private static int counter = 0;", want: "static-field"},
+		{line: "    static volatile Map<String, String> cache;", want: "static-field"},
+		{line: "    private static final Logger LOG = Logger.getLogger(Foo.class);", want: ""},
+		{line: "    public static final Map<String, Object> CACHE = new HashMap<>();", want: "static-mutable-collection"},
+		{line: "    private static final List<String> NAMES = Collections.emptyList();", want: ""},
+		{line: "import static org.junit.Assert.assertEquals;", want: ""},
+		{line: "    public static void main(String[] args) {", want: ""},
+		{line: "@Singleton", want: "singleton"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := ""
+			for _, q := range staticStateInventory.config {
+				if q.pattern.MatchString(tt.line) && (q.exclude == nil || !q.exclude.MatchString(tt.line)) {
+					got = q.kind
+					break
+				}
+			}
+			if got != tt.want {
+				t.Errorf("expected kind %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_schedulerInventory(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		line string
+		want map[string]interface{}
+	}{
+		{
+			name: "ejb schedule with defaults",
+			file: "Jobs.java",
+			line: `    @Schedule(hour = "*/2", minute = "30", persistent = "false")`,
+			want: map[string]interface{}{"kind": "ejb-schedule", "timerType": "ejb", "cron": "0 30 */2 * * *", "persistent": "false"},
+		},
+		{
+			name: "spring cron",
+			file: "Jobs.java",
+			line: `    @Scheduled(cron = "0 0 1 * * MON")`,
+			want: map[string]interface{}{"kind": "spring-scheduled", "timerType": "spring", "cron": "0 0 1 * * MON"},
+		},
+		{
+			name: "spring fixed rate",
+			file: "Jobs.java",
+			line: `    @Scheduled(fixedRate = 5000)`,
+			want: map[string]interface{}{"kind": "spring-scheduled", "timerType": "spring", "trigger": "fixedRate", "interval": "5000"},
+		},
+		{
+			name: "quartz cron schedule",
+			file: "Jobs.java",
+			line: `        .withSchedule(CronScheduleBuilder.cronSchedule("0 0/5 * * * ?"))`,
+			want: map[string]interface{}{"kind": "quartz-cron", "timerType": "quartz", "cron": "0 0/5 * * * ?"},
+		},
+		{
+			name: "quartz job xml",
+			file: "quartz_data.xml",
+			line: `  <cron-expression>0 15 10 ? * MON-FRI</cron-expression>`,
+			want: map[string]interface{}{"kind": "quartz-cron", "timerType": "quartz", "cron": "0 15 10 ? * MON-FRI"},
+		},
+		{
+			name: "cron property",
+			file: "application.properties",
+			line: `jobs.cleanup.cron=0 0 3 * * *`,
+			want: map[string]interface{}{"kind": "scheduler-config", "timerType": "config", "property": "jobs.cleanup.cron", "cron": "0 0 3 * * *"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := t.TempDir()
+			if err := os.WriteFile(filepath.Join(location, tt.file), []byte(tt.line+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			incidents, err := searchConfigFiles(location, nil, schedulerInventory.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(incidents) != 1 {
+				t.Fatalf("expected one incident, got %d", len(incidents))
+			}
+			for k, v := range tt.want {
+				if got := incidents[0].Variables[k]; got != v {
+					t.Errorf("expected %s to be %q, got %q", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_packagingInventory(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"pom.xml":                              "<project>\n  <packaging>war</packaging>\n  <plugin>\n    <artifactId>maven-war-plugin</artifactId>\n    <packagingExcludes>WEB-INF/lib/*.jar</packagingExcludes>\n  </plugin>\n  <dependency>\n    <scope>provided</scope>\n  </dependency>\n</project>\n",
+		"src/main/webapp/META-INF/MANIFEST.MF": "Manifest-Version: 1.0\nClass-Path: lib/commons.jar lib/util.jar\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	incidents, err := searchConfigFiles(location, nil, packagingInventory.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[string]map[string]interface{}{}
+	for _, inc := range incidents {
+		got[inc.Variables["kind"].(string)] = inc.Variables
+	}
+	want := map[string]map[string]interface{}{
+		"packaging-type":             {"packaging": "war"},
+		"skinny-archive":             {"packaging": "war"},
+		"server-provided-dependency": {},
+		"manifest-classpath":         {"classPath": "lib/commons.jar lib/util.jar"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected kinds %v, got %v", want, got)
+	}
+	for kind, variables := range want {
+		for k, v := range variables {
+			if got[kind][k] != v {
+				t.Errorf("expected %s of %s to be %v, got %v", k, kind, v, got[kind][k])
+			}
+		}
+	}
+}
+
+func Test_summarizeByFile(t *testing.T) {
+	incident := func(file, kind, model string) provider.IncidentContext {
+		return provider.IncidentContext{
+			FileURI:   uri.URI(file),
+			Variables: map[string]interface{}{"kind": kind, "model": model},
+		}
+	}
+	incidents := []provider.IncidentContext{
+		incident("file:///src/Orders.java", "user-transaction", "programmatic"),
+		incident("file:///src/Orders.java", "user-transaction", "programmatic"),
+		incident("file:///src/Orders.java", "transactional-annotation", "declarative"),
+		incident("file:///src/Billing.java", "transaction-attribute", "declarative"),
+	}
+	got := summarizeByFile("transaction-summary", incidents)
+	want := []provider.IncidentContext{
+		{
+			FileURI: "file:///src/Billing.java",
+			Variables: map[string]interface{}{
+				"kind":   "transaction-summary",
+				"source": INVENTORY_SOURCE_CODE,
+				"class":  "Billing",
+				"model":  "declarative",
+				"counts": map[string]interface{}{"transaction-attribute": 1},
+			},
+		},
+		{
+			FileURI: "file:///src/Orders.java",
+			Variables: map[string]interface{}{
+				"kind":   "transaction-summary",
+				"source": INVENTORY_SOURCE_CODE,
+				"class":  "Orders",
+				"model":  "mixed",
+				"counts": map[string]interface{}{"user-transaction": 2, "transactional-annotation": 1},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func Test_jniInventory(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"src/main/java/com/example/Codec.java":          "class Codec {\n  static { System.loadLibrary(\"codec\"); }\n  private static native byte[] encode(byte[] data);\n  public native int version();\n  public String nativeName() { return \"native\"; }\n}\n",
+		"src/main/resources/linux-x86-64/libcodec.so.1": "",
+		"src/main/resources/win32-x86-64/codec.dll":     "",
+		"src/main/resources/application.properties":     "java.library.path=/opt/codec/lib\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configIncidents, err := searchConfigFiles(location, nil, jniInventory.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	methods := []string{}
+	for _, inc := range configIncidents {
+		if inc.Variables["kind"] == "native-method" {
+			methods = append(methods, inc.Variables["method"].(string))
+		} else if inc.Variables["path"] != "/opt/codec/lib" {
+			t.Errorf("expected the library path to be found, got %v", inc.Variables)
+		}
+	}
+	if !reflect.DeepEqual(methods, []string{"encode", "version"}) {
+		t.Errorf("expected native methods encode and version, got %v", methods)
+	}
+
+	fileIncidents, err := searchFiles(location, nil, jniInventory.files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	libraries := []string{}
+	for _, inc := range fileIncidents {
+		libraries = append(libraries, inc.Variables["file"].(string))
+		if inc.LineNumber != nil {
+			t.Errorf("expected no line number for a library file")
+		}
+	}
+	if !reflect.DeepEqual(libraries, []string{"libcodec.so.1", "codec.dll"}) {
+		t.Errorf("expected the native libraries to be found, got %v", libraries)
+	}
+}
+
+func Test_concurrencyInventory(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"src/main/java/com/example/Worker.java":     "class Worker {\n  private final Object lock = new Object();\n  public synchronized void run() {}\n  void other() {\n    synchronized (lock) {}\n  }\n  String synchronizedName() { return \"\"; }\n}\n",
+		"src/main/resources/application.properties": "spring.task.execution.pool.max-size=16\nserver.port=8080\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	incidents, err := searchConfigFiles(location, nil, concurrencyInventory.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := []int{}
+	properties := []string{}
+	for _, inc := range incidents {
+		switch inc.Variables["kind"] {
+		case "synchronization":
+			lines = append(lines, *inc.LineNumber)
+		case "thread-pool-config":
+			properties = append(properties, inc.Variables["property"].(string))
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{3, 5}) {
+		t.Errorf("expected synchronization on lines 3 and 5, got %v", lines)
+	}
+	if !reflect.DeepEqual(properties, []string{"spring.task.execution.pool.max-size"}) {
+		t.Errorf("expected the thread pool property to be found, got %v", properties)
+	}
+}
+
+func Test_loggingInventory(t *testing.T) {
+	location := t.TempDir()
+	files := map[string]string{
+		"src/main/resources/log4j.properties": "log4j.rootLogger=INFO, stdout\nlog4j.appender.stdout=org.apache.log4j.ConsoleAppender\n",
+		"src/main/resources/logback.xml":      "<configuration>\n  <root level=\"info\"/>\n</configuration>\n",
+		"pom.xml":                             "<dependency>\n  <artifactId>log4j-core</artifactId>\n</dependency>\n<dependency>\n  <artifactId>log4j</artifactId>\n</dependency>\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	incidents, err := searchConfigFiles(location, nil, loggingInventory.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	consolidated := consolidateIncidents("logging-framework", "framework", incidents)
+	frameworks := []string{}
+	locations := map[string]int{}
+	for _, inc := range consolidated {
+		if inc.Variables["kind"] != "logging-framework" {
+			t.Errorf("expected a logging-framework incident, got %v", inc.Variables["kind"])
+		}
+		framework := inc.Variables["framework"].(string)
+		frameworks = append(frameworks, framework)
+		locations[framework] = len(inc.Variables["locations"].([]interface{}))
+	}
+	if !reflect.DeepEqual(frameworks, []string{"log4j1", "log4j2", "logback"}) {
+		t.Errorf("expected one incident for each framework, got %v", frameworks)
+	}
+	if !reflect.DeepEqual(locations, map[string]int{"log4j1": 3, "log4j2": 1, "logback": 1}) {
+		t.Errorf("unexpected number of locations %v", locations)
+	}
+	// the first location of a framework is the first file in order
+	if !strings.HasSuffix(string(consolidated[0].FileURI), "pom.xml") || *consolidated[0].LineNumber != 5 {
+		t.Errorf("expected log4j1 to be located in the pom, got %s:%d", consolidated[0].FileURI, *consolidated[0].LineNumber)
+	}
+}
@@ -0,0 +1,219 @@
+package java
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vifraa/gopom"
+)
+
+// minServerJavaVersion is the java version the language server needs to run
+const minServerJavaVersion = 17
+
+// jdkSearchPaths are the globs of the directories the JDKs are usually
+// installed in.
+var jdkSearchPaths = []string{
+	"/usr/lib/jvm/*",
+	"/usr/java/*",
+	"/opt/java/*",
+	"/Library/Java/JavaVirtualMachines/*/Contents/Home",
+	"~/.sdkman/candidates/java/*",
+	"~/.jdks/*",
+}
+
+// jdk is a java installation, its zero value stands for the java of the
+// environment of the analyzer.
+type jdk struct {
+	home    string
+	version string
+	major   int
+}
+
+// readJDK reads the version of the JDK in the directory from its release file
+func readJDK(home string) (jdk, error) {
+	if _, err := os.Stat(filepath.Join(home, "bin", "java")); err != nil {
+		return jdk{}, fmt.Errorf("%s is not a JDK, it has no bin/java", home)
+	}
+	f, err := os.Open(filepath.Join(home, "release"))
+	if err != nil {
+		return jdk{}, fmt.Errorf("unable to read the version of the JDK %s: %w", home, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != "JAVA_VERSION" {
+			continue
+		}
+		version := strings.Trim(value, `"`)
+		major := javaMajor(version)
+		if major == 0 {
+			return jdk{}, fmt.Errorf("invalid version %q of the JDK %s", version, home)
+		}
+		return jdk{home: home, version: version, major: major}, nil
+	}
+	return jdk{}, fmt.Errorf("the JDK %s has no JAVA_VERSION in its release file", home)
+}
+
+// javaMajor returns the major version of a java version or target level, such
+// as 8 for 1.8.0_292 and 17 for 17.0.2, or 0 when it is not one.
+func javaMajor(version string) int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "1.")
+	end := strings.IndexFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		version = version[:end]
+	}
+	major, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
+	}
+	return major
+}
+
+func (j jdk) String() string {
+	return fmt.Sprintf("java %s at %s", j.version, j.home)
+}
+
+// env returns the environment variables that run the commands with the JDK
+func (j jdk) env() []string {
+	if j.home == "" {
+		return nil
+	}
+	return []string{
+		"JAVA_HOME=" + j.home,
+		fmt.Sprintf("PATH=%s%c%s", filepath.Join(j.home, "bin"), os.PathListSeparator, os.Getenv("PATH")),
+	}
+}
+
+// executionEnvironment is the name the language server gives the JDK
+func (j jdk) executionEnvironment() string {
+	if j.major <= 8 {
+		return fmt.Sprintf("JavaSE-1.%d", j.major)
+	}
+	return fmt.Sprintf("JavaSE-%d", j.major)
+}
+
+// discoverJDKs finds the JDK of JAVA_HOME, the one of the java on the path
+// and the ones in the usual directories.
+func discoverJDKs() []jdk {
+	candidates := []string{}
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		candidates = append(candidates, home)
+	}
+	if java, err := exec.LookPath("java"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(java); err == nil {
+			candidates = append(candidates, filepath.Dir(filepath.Dir(resolved)))
+		}
+	}
+	userHome, _ := os.UserHomeDir()
+	for _, glob := range jdkSearchPaths {
+		if strings.HasPrefix(glob, "~/") {
+			if userHome == "" {
+				continue
+			}
+			glob = filepath.Join(userHome, glob[2:])
+		}
+		matches, _ := filepath.Glob(glob)
+		candidates = append(candidates, matches...)
+	}
+	seen := map[string]bool{}
+	jdks := []jdk{}
+	for _, home := range candidates {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+		if seen[home] {
+			continue
+		}
+		seen[home] = true
+		if j, err := readJDK(home); err == nil {
+			jdks = append(jdks, j)
+		}
+	}
+	sort.SliceStable(jdks, func(i, j int) bool {
+		return jdks[i].major < jdks[j].major
+	})
+	return jdks
+}
+
+// projectTargetLevel returns the java version the project in the location is
+// compiled for, from the properties of its pom, or 0 when it is not known.
+func projectTargetLevel(location string) int {
+	pom, err := gopom.Parse(filepath.Join(location, "pom.xml"))
+	if err != nil || pom.Properties == nil {
+		return 0
+	}
+	for _, key := range []string{"maven.compiler.release", "maven.compiler.target", "maven.compiler.source", "java.version"} {
+		value := pom.Properties.Entries[key]
+		if strings.HasPrefix(value, "${") {
+			value = pom.Properties.Entries[strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")]
+		}
+		if major := javaMajor(value); major > 0 {
+			return major
+		}
+	}
+	return 0
+}
+
+// selectJDKs returns the JDK the language server runs on and the one the
+// project is compiled with. A JDK given in the config must be able to run the
+// server or compile the project, otherwise the ones that are discovered are
+// used. The returned warnings explain why the environment of the analyzer is
+// used instead.
+func selectJDKs(config map[string]interface{}, target int, discover func() []jdk) (server jdk, project jdk, warnings []string, err error) {
+	var discovered []jdk
+	if home, ok := config[JAVA_HOME_INIT_OPTION].(string); ok && home != "" {
+		server, err = readJDK(home)
+		if err != nil {
+			return jdk{}, jdk{}, nil, fmt.Errorf("invalid %s: %w", JAVA_HOME_INIT_OPTION, err)
+		}
+		if server.major < minServerJavaVersion {
+			return jdk{}, jdk{}, nil, fmt.Errorf("%s can not run the java language server, it needs java %d or later", server, minServerJavaVersion)
+		}
+	} else {
+		discovered = discover()
+		for i := len(discovered) - 1; i >= 0; i-- {
+			if discovered[i].major >= minServerJavaVersion {
+				server = discovered[i]
+				break
+			}
+		}
+		if server.home == "" {
+			warnings = append(warnings, fmt.Sprintf("no JDK with java %d or later was found to run the java language server, the java of the environment is used", minServerJavaVersion))
+		}
+	}
+
+	if home, ok := config[PROJECT_JAVA_HOME_INIT_OPTION].(string); ok && home != "" {
+		project, err = readJDK(home)
+		if err != nil {
+			return jdk{}, jdk{}, nil, fmt.Errorf("invalid %s: %w", PROJECT_JAVA_HOME_INIT_OPTION, err)
+		}
+		if project.major < target {
+			return jdk{}, jdk{}, nil, fmt.Errorf("%s can not compile the project, it targets java %d", project, target)
+		}
+		return server, project, warnings, nil
+	}
+	if target == 0 {
+		return server, server, warnings, nil
+	}
+	if discovered == nil {
+		discovered = discover()
+	}
+	// the closest JDK to the target level has the API the project is compiled against
+	for _, j := range discovered {
+		if j.major >= target {
+			return server, j, warnings, nil
+		}
+	}
+	if server.home != "" && server.major >= target {
+		return server, server, warnings, nil
+	}
+	warnings = append(warnings, fmt.Sprintf("no JDK with java %d or later was found to compile the project, the java of the environment is used", target))
+	return server, jdk{}, warnings, nil
+}
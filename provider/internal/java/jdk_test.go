@@ -0,0 +1,172 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeJDK creates a JDK directory with the version in its release file
+func fakeJDK(t *testing.T, dir string, version string) jdk {
+	home := filepath.Join(dir, "jdk-"+version)
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", "java"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "release"), []byte("IMPLEMENTOR=\"Test\"\nJAVA_VERSION=\""+version+"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	j, err := readJDK(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestJavaMajor(t *testing.T) {
+	for version, want := range map[string]int{
+		"1.8.0_292": 8,
+		"1.8":       8,
+		"11":        11,
+		"17.0.2":    17,
+		"21-ea":     21,
+		"":          0,
+		"latest":    0,
+	} {
+		if got := javaMajor(version); got != want {
+			t.Errorf("expected major %d of %q, got %d", want, version, got)
+		}
+	}
+}
+
+func TestSelectJDKs(t *testing.T) {
+	dir := t.TempDir()
+	jdk8 := fakeJDK(t, dir, "1.8.0_292")
+	jdk11 := fakeJDK(t, dir, "11.0.20")
+	jdk17 := fakeJDK(t, dir, "17.0.2")
+	jdk21 := fakeJDK(t, dir, "21.0.1")
+	notJDK := filepath.Join(dir, "empty")
+	os.MkdirAll(notJDK, 0755)
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		target      int
+		discovered  []jdk
+		wantServer  jdk
+		wantProject jdk
+		warnings    int
+		errContains string
+	}{
+		{
+			name:        "discovered",
+			target:      11,
+			discovered:  []jdk{jdk8, jdk11, jdk17, jdk21},
+			wantServer:  jdk21,
+			wantProject: jdk11,
+		},
+		{
+			name:        "unknown target level",
+			discovered:  []jdk{jdk11, jdk17},
+			wantServer:  jdk17,
+			wantProject: jdk17,
+		},
+		{
+			name:        "server on a different JDK than the project",
+			config:      map[string]interface{}{JAVA_HOME_INIT_OPTION: jdk17.home, PROJECT_JAVA_HOME_INIT_OPTION: jdk8.home},
+			target:      8,
+			wantServer:  jdk17,
+			wantProject: jdk8,
+		},
+		{
+			name:        "project JDK from the configured server",
+			config:      map[string]interface{}{JAVA_HOME_INIT_OPTION: jdk17.home},
+			target:      17,
+			discovered:  []jdk{jdk11},
+			wantServer:  jdk17,
+			wantProject: jdk17,
+		},
+		{
+			name:        "nothing discovered",
+			target:      8,
+			warnings:    2,
+			wantServer:  jdk{},
+			wantProject: jdk{},
+		},
+		{
+			name:        "server JDK too old",
+			config:      map[string]interface{}{JAVA_HOME_INIT_OPTION: jdk11.home},
+			errContains: "can not run the java language server",
+		},
+		{
+			name:        "project JDK too old",
+			config:      map[string]interface{}{PROJECT_JAVA_HOME_INIT_OPTION: jdk8.home},
+			target:      11,
+			discovered:  []jdk{jdk17},
+			errContains: "can not compile the project",
+		},
+		{
+			name:        "not a JDK",
+			config:      map[string]interface{}{JAVA_HOME_INIT_OPTION: notJDK},
+			errContains: "is not a JDK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, project, warnings, err := selectJDKs(tt.config, tt.target, func() []jdk { return tt.discovered })
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected an error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(server, tt.wantServer) || !reflect.DeepEqual(project, tt.wantProject) {
+				t.Errorf("expected %v and %v, got %v and %v", tt.wantServer, tt.wantProject, server, project)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("expected %d warnings, got %v", tt.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestProjectTargetLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string
+		want       int
+	}{
+		{
+			name:       "release",
+			properties: "<maven.compiler.release>17</maven.compiler.release>",
+			want:       17,
+		},
+		{
+			name:       "property reference",
+			properties: "<java.version>1.8</java.version><maven.compiler.target>${java.version}</maven.compiler.target>",
+			want:       8,
+		},
+		{
+			name: "no properties",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			pom := "<project><modelVersion>4.0.0</modelVersion><properties>" + tt.properties + "</properties></project>"
+			if err := os.WriteFile(filepath.Join(dir, "pom.xml"), []byte(pom), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := projectTargetLevel(dir); got != tt.want {
+				t.Errorf("expected target level %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package java
+
+import "regexp"
+
+// jniInventory finds native code used through JNI, which has to be built for
+// the target platform or blocks the migration to it.
+var jniInventory = inventory{
+	code: []codeQuery{
+		{kind: "native-library-load", pattern: "java.lang.System.loadLibrary", location: "method_call"},
+		{kind: "native-library-load", pattern: "java.lang.System.load", location: "method_call"},
+		{kind: "native-library-load", pattern: "java.lang.Runtime.loadLibrary", location: "method_call"},
+		{kind: "native-library-load", pattern: "java.lang.Runtime.load", location: "method_call"},
+		{kind: "jna-library", pattern: "com.sun.jna.Library", location: "inheritance"},
+		{kind: "jna-library", pattern: "com.sun.jna.Native.load*", location: "method_call"},
+	},
+	config: []configQuery{
+		{
+			kind:    "native-method",
+			files:   regexp.MustCompile(`.*\.java$`),
+			pattern: regexp.MustCompile(`^\s*(?:(?:public|protected|private|static|final|synchronized)\s+)*native\s+(?:(?:public|protected|private|static|final|synchronized)\s+)*[\w.<>\[\], ]+?\s+(?P<method>\w+)\s*\(`),
+		},
+		{
+			kind:    "native-library-config",
+			files:   regexp.MustCompile(`(^MANIFEST\.MF|\.properties|\.ya?ml)$`),
+			pattern: regexp.MustCompile(`(?:java\.library\.path|Bundle-NativeCode|jna\.library\.path)\s*[=:]\s*(?P<path>.*?)\s*$`),
+		},
+	},
+	files: []fileQuery{
+		{kind: "native-library", files: regexp.MustCompile(`\.(?P<extension>so(\.\d+)*|dll|dylib|jnilib|a|lib)$`)},
+	},
+}
@@ -0,0 +1,129 @@
+package java
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/vifraa/gopom"
+)
+
+// mavenPomPath returns the path of the pom of an artifact in the local maven
+// repository
+func mavenPomPath(localRepoPath, groupID, artifactID, version string) string {
+	return filepath.Join(localRepoPath, strings.Replace(groupID, ".", "/", -1), artifactID, version,
+		fmt.Sprintf("%v-%v.pom", artifactID, version))
+}
+
+// pomLicense returns the licenses a pom declares, empty when the pom can not
+// be read or declares none
+func pomLicense(path string) string {
+	pom, err := gopom.Parse(path)
+	if err != nil {
+		return ""
+	}
+	return projectLicense(pom)
+}
+
+func projectLicense(pom *gopom.Project) string {
+	if pom == nil || pom.Licenses == nil {
+		return ""
+	}
+	found := map[string]bool{}
+	for _, l := range *pom.Licenses {
+		switch {
+		case l.Name != nil && strings.TrimSpace(*l.Name) != "":
+			found[provider.NormalizeLicense(*l.Name)] = true
+		case l.URL != nil && strings.TrimSpace(*l.URL) != "":
+			found[provider.NormalizeLicense(*l.URL)] = true
+		}
+	}
+	return provider.JoinLicenses(found)
+}
+
+// jarLicense returns the license of a jar, from the Bundle-License of its
+// manifest, the licenses of the pom it embeds or its license file, in that
+// order
+func jarLicense(jarFile string) string {
+	jar, err := zip.OpenReader(jarFile)
+	if err != nil {
+		return ""
+	}
+	defer jar.Close()
+
+	var pomFile, licenseFile *zip.File
+	for _, file := range jar.File {
+		name := strings.ToUpper(file.Name)
+		if file.Name == "META-INF/MANIFEST.MF" {
+			rc, err := file.Open()
+			if err != nil {
+				continue
+			}
+			license := manifestLicense(rc)
+			rc.Close()
+			if license != "" {
+				return license
+			}
+		} else if match, _ := filepath.Match("META-INF/maven/*/*/pom.xml", file.Name); match && pomFile == nil {
+			pomFile = file
+		} else if (strings.HasPrefix(name, "META-INF/LICENSE") || strings.HasPrefix(name, "META-INF/LICENCE")) && licenseFile == nil {
+			licenseFile = file
+		}
+	}
+	if pomFile != nil {
+		if rc, err := pomFile.Open(); err == nil {
+			pom, err := gopom.ParseFromReader(rc)
+			rc.Close()
+			if license := projectLicense(pom); err == nil && license != "" {
+				return license
+			}
+		}
+	}
+	if licenseFile != nil {
+		if rc, err := licenseFile.Open(); err == nil {
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err == nil {
+				return provider.ClassifyLicenseText(string(content))
+			}
+		}
+	}
+	return ""
+}
+
+// manifestLicense returns the licenses of the Bundle-License header of a
+// manifest, which lists SPDX identifiers or license URLs separated by commas.
+// The lines of the manifest longer than 72 bytes continue on the lines that
+// start with a space.
+func manifestLicense(r io.Reader) string {
+	header := ""
+	inHeader := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if inHeader && strings.HasPrefix(line, " ") {
+			header += line[1:]
+			continue
+		}
+		if inHeader {
+			break
+		}
+		if strings.HasPrefix(line, "Bundle-License:") {
+			header = strings.TrimSpace(strings.TrimPrefix(line, "Bundle-License:"))
+			inHeader = true
+		}
+	}
+	found := map[string]bool{}
+	for _, l := range strings.Split(header, ",") {
+		// the attributes of a license, such as its link, follow a semicolon
+		l = strings.TrimSpace(strings.SplitN(l, ";", 2)[0])
+		if l != "" {
+			found[provider.NormalizeLicense(l)] = true
+		}
+	}
+	return provider.JoinLicenses(found)
+}
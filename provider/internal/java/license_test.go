@@ -0,0 +1,131 @@
+package java
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifestLicense(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{
+			name:     "spdx identifier",
+			manifest: "Manifest-Version: 1.0\r\nBundle-License: Apache-2.0\r\nBundle-Name: lib\r\n",
+			want:     "Apache-2.0",
+		},
+		{
+			name:     "continued url with attributes",
+			manifest: "Manifest-Version: 1.0\nBundle-License: https://www.eclipse.org/legal/epl-2.0;link=\"http\n s://www.eclipse.org\",MIT\nBundle-Name: lib\n",
+			want:     "EPL-2.0 OR MIT",
+		},
+		{
+			name:     "no license",
+			manifest: "Manifest-Version: 1.0\nBundle-Name: lib\n",
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manifestLicense(strings.NewReader(tt.manifest)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func writeJar(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJarLicense(t *testing.T) {
+	pom := `<project><licenses>
+  <license><name>The Apache Software License, Version 2.0</name></license>
+  <license><url>https://opensource.org/licenses/MIT</url></license>
+</licenses></project>`
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "manifest",
+			files: map[string]string{
+				"META-INF/MANIFEST.MF":                   "Bundle-License: EPL-2.0\n",
+				"META-INF/maven/org.example/lib/pom.xml": pom,
+			},
+			want: "EPL-2.0",
+		},
+		{
+			name: "embedded pom",
+			files: map[string]string{
+				"META-INF/MANIFEST.MF":                   "Manifest-Version: 1.0\n",
+				"META-INF/maven/org.example/lib/pom.xml": pom,
+			},
+			want: "Apache-2.0 OR MIT",
+		},
+		{
+			name: "license file",
+			files: map[string]string{
+				"META-INF/LICENSE.txt": "Permission is hereby granted, free of charge, to any person",
+			},
+			want: "MIT",
+		},
+		{
+			name:  "no license",
+			files: map[string]string{"org/example/Lib.class": ""},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "lib.jar")
+			writeJar(t, path, tt.files)
+			if got := jarLicense(path); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPomLicense(t *testing.T) {
+	repo := t.TempDir()
+	path := mavenPomPath(repo, "org.example", "lib", "1.0")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`<project><licenses><license><name>Eclipse Public License - v 2.0</name></license></licenses></project>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(repo, "org", "example", "lib", "1.0", "lib-1.0.pom"); path != want {
+		t.Errorf("expected the pom at %s, got %s", want, path)
+	}
+	if got := pomLicense(path); got != "EPL-2.0" {
+		t.Errorf("expected EPL-2.0, got %q", got)
+	}
+	if got := pomLicense(mavenPomPath(repo, "org.example", "missing", "1.0")); got != "" {
+		t.Errorf("expected no license for a missing pom, got %q", got)
+	}
+}
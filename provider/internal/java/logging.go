@@ -0,0 +1,84 @@
+package java
+
+import "regexp"
+
+// loggingInventory finds the logging frameworks used by the code and their
+// configuration files. Each incident has the framework in the "framework"
+// variable, and the logging-framework incidents gather all the locations of a
+// framework so that migration rules match once for each framework.
+var loggingInventory = inventory{
+	code: []codeQuery{
+		{kind: "logging-api", pattern: "org.apache.log4j*", location: "import", variables: map[string]interface{}{"framework": "log4j1"}},
+		{kind: "logging-api", pattern: "org.apache.logging.log4j*", location: "import", variables: map[string]interface{}{"framework": "log4j2"}},
+		{kind: "logging-api", pattern: "ch.qos.logback*", location: "import", variables: map[string]interface{}{"framework": "logback"}},
+		{kind: "logging-api", pattern: "java.util.logging*", location: "import", variables: map[string]interface{}{"framework": "jul"}},
+		{kind: "logging-api", pattern: "org.slf4j*", location: "import", variables: map[string]interface{}{"framework": "slf4j"}},
+		{kind: "logging-api", pattern: "org.apache.commons.logging*", location: "import", variables: map[string]interface{}{"framework": "commons-logging"}},
+		{kind: "logging-api", pattern: "org.jboss.logging*", location: "import", variables: map[string]interface{}{"framework": "jboss-logging"}},
+	},
+	config: []configQuery{
+		{
+			kind:      "logging-config",
+			files:     regexp.MustCompile(`^log4j\.(properties|xml)$`),
+			pattern:   regexp.MustCompile(`^\s*log4j\.(?:rootLogger|rootCategory|logger|appender)|<log4j:configuration\b`),
+			variables: map[string]interface{}{"framework": "log4j1"},
+		},
+		{
+			kind:      "logging-config",
+			files:     regexp.MustCompile(`^log4j2(-[\w-]+)?\.(properties|xml|json|ya?ml)$`),
+			pattern:   regexp.MustCompile(`<Configuration\b|^\s*(?:rootLogger|appenders?|status)\s*[=:.]|"[Cc]onfiguration"\s*:`),
+			variables: map[string]interface{}{"framework": "log4j2"},
+		},
+		{
+			kind:      "logging-config",
+			files:     regexp.MustCompile(`^logback(-[\w-]+)?\.xml$`),
+			pattern:   regexp.MustCompile(`<configuration\b`),
+			variables: map[string]interface{}{"framework": "logback"},
+		},
+		{
+			kind:      "logging-config",
+			files:     regexp.MustCompile(`^logging\.properties$`),
+			pattern:   regexp.MustCompile(`^\s*(?:handlers|\.level)\s*=`),
+			variables: map[string]interface{}{"framework": "jul"},
+		},
+		{
+			kind:      "logging-config",
+			files:     regexp.MustCompile(`^commons-logging\.properties$`),
+			pattern:   regexp.MustCompile(`^\s*org\.apache\.commons\.logging\.\w+\s*=`),
+			variables: map[string]interface{}{"framework": "commons-logging"},
+		},
+		{
+			kind:    "logging-dependency",
+			files:   regexp.MustCompile(`^pom\.xml$`),
+			pattern: regexp.MustCompile(`<artifactId>\s*(?P<framework>log4j|log4j-core|logback-classic|slf4j-api|commons-logging|jboss-logging)\s*</artifactId>`),
+			enrich:  normalizeLoggingFramework,
+		},
+		{
+			kind:    "logging-dependency",
+			files:   regexp.MustCompile(`^build\.gradle(\.kts)?$`),
+			pattern: regexp.MustCompile(`["'][\w.-]+:(?P<framework>log4j|log4j-core|logback-classic|slf4j-api|commons-logging|jboss-logging):`),
+			enrich:  normalizeLoggingFramework,
+		},
+	},
+	consolidatedKind: "logging-framework",
+	consolidateBy:    "framework",
+}
+
+var loggingArtifactFrameworks = map[string]string{
+	"log4j":           "log4j1",
+	"log4j-core":      "log4j2",
+	"logback-classic": "logback",
+	"slf4j-api":       "slf4j",
+	"commons-logging": "commons-logging",
+	"jboss-logging":   "jboss-logging",
+}
+
+// normalizeLoggingFramework replaces the artifact found in a build file by the
+// name of its framework, the same as for the code and configuration files.
+func normalizeLoggingFramework(variables map[string]interface{}) {
+	artifact, _ := variables["framework"].(string)
+	if framework, ok := loggingArtifactFrameworks[artifact]; ok {
+		variables["artifactId"] = artifact
+		variables["framework"] = framework
+	}
+}
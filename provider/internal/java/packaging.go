@@ -0,0 +1,100 @@
+package java
+
+import "regexp"
+
+// classLoadingInventory finds code and descriptors that change how classes are
+// loaded, which rarely behaves the same on another application server.
+var classLoadingInventory = inventory{
+	code: []codeQuery{
+		{kind: "custom-classloader", pattern: "java.lang.ClassLoader", location: "inheritance"},
+		{kind: "custom-classloader", pattern: "java.net.URLClassLoader", location: "inheritance"},
+		{kind: "classloader-creation", pattern: "java.net.URLClassLoader", location: "constructor_call"},
+		{kind: "classloader-creation", pattern: "java.net.URLClassLoader.newInstance", location: "method_call"},
+		{kind: "context-classloader", pattern: "java.lang.Thread.setContextClassLoader", location: "method_call"},
+		{kind: "context-classloader", pattern: "java.lang.Thread.getContextClassLoader", location: "method_call"},
+		{kind: "dynamic-class-loading", pattern: "java.lang.Class.forName", location: "method_call"},
+		{kind: "dynamic-class-loading", pattern: "java.lang.ClassLoader.loadClass", location: "method_call"},
+	},
+	config: []configQuery{
+		{
+			kind:    "classloading-config",
+			files:   regexp.MustCompile(`^jboss-deployment-structure\.xml$`),
+			pattern: regexp.MustCompile(`<(?:ear-subdeployments-isolated|exclusions|exclude-subsystems|module)\b(?:.*\bname="(?P<name>[^"]+)")?`),
+		},
+		{
+			kind:    "classloading-config",
+			files:   regexp.MustCompile(`^weblogic(-application)?\.xml$`),
+			pattern: regexp.MustCompile(`<(?:prefer-web-inf-classes|prefer-application-packages|prefer-application-resources|package-name)\b`),
+		},
+		{
+			kind:    "classloading-config",
+			files:   regexp.MustCompile(`^(ibm-web-ext|ibm-application-ext|deployment)\.xml$`),
+			pattern: regexp.MustCompile(`(?i)classloader|class-loader`),
+		},
+		{
+			kind:    "classloading-config",
+			files:   regexp.MustCompile(`^jboss-classloading\.xml$`),
+			pattern: regexp.MustCompile(`<classloading\b`),
+		},
+	},
+}
+
+// packagingInventory finds how the application is packaged from the build
+// files and manifests, skinny archives expect libraries from the server and
+// fat archives bundle a server or all the libraries.
+var packagingInventory = inventory{
+	config: []configQuery{
+		{
+			kind:    "manifest-classpath",
+			files:   regexp.MustCompile(`^MANIFEST\.MF$`),
+			pattern: regexp.MustCompile(`^Class-Path:\s*(?P<classPath>.+?)\s*$`),
+		},
+		{
+			kind:    "packaging-type",
+			files:   regexp.MustCompile(`^pom\.xml$`),
+			pattern: regexp.MustCompile(`<packaging>\s*(?P<packaging>war|ear|rar|ejb|jar)\s*</packaging>`),
+		},
+		{
+			kind:    "packaging-type",
+			files:   regexp.MustCompile(`^build\.gradle(\.kts)?$`),
+			pattern: regexp.MustCompile(`^\s*(?:apply\s+plugin:\s*|id\s*\(?\s*)["'](?P<packaging>war|ear)["']`),
+		},
+		{
+			kind:      "skinny-archive",
+			files:     regexp.MustCompile(`^pom\.xml$`),
+			pattern:   regexp.MustCompile(`<(?:skinnyWars|skinnyModules)>\s*true\s*<`),
+			variables: map[string]interface{}{"packaging": "ear"},
+		},
+		{
+			kind:      "skinny-archive",
+			files:     regexp.MustCompile(`^pom\.xml$`),
+			pattern:   regexp.MustCompile(`<packagingExcludes>[^<]*WEB-INF/lib`),
+			variables: map[string]interface{}{"packaging": "war"},
+		},
+		{
+			kind:    "fat-archive",
+			files:   regexp.MustCompile(`^pom\.xml$`),
+			pattern: regexp.MustCompile(`<artifactId>\s*(?P<plugin>spring-boot-maven-plugin|maven-shade-plugin|thorntail-maven-plugin|wildfly-swarm-plugin|wildfly-jar-maven-plugin|quarkus-maven-plugin)\s*</artifactId>`),
+		},
+		{
+			kind:    "fat-archive",
+			files:   regexp.MustCompile(`^pom\.xml$`),
+			pattern: regexp.MustCompile(`<descriptorRef>\s*(?P<plugin>jar-with-dependencies)\s*<`),
+		},
+		{
+			kind:    "fat-archive",
+			files:   regexp.MustCompile(`^build\.gradle(\.kts)?$`),
+			pattern: regexp.MustCompile(`["'](?P<plugin>org\.springframework\.boot|com\.github\.johnrengelman\.shadow|com\.gradleup\.shadow)["']`),
+		},
+		{
+			kind:    "server-provided-dependency",
+			files:   regexp.MustCompile(`^pom\.xml$`),
+			pattern: regexp.MustCompile(`<scope>\s*provided\s*</scope>`),
+		},
+		{
+			kind:    "server-provided-dependency",
+			files:   regexp.MustCompile(`^build\.gradle(\.kts)?$`),
+			pattern: regexp.MustCompile(`^\s*(?:providedCompile|providedRuntime|compileOnly)\b`),
+		},
+	},
+}
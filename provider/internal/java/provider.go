@@ -12,11 +12,15 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/jsonrpc2"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/metrics"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/vifraa/gopom"
 	"go.lsp.dev/uri"
@@ -36,8 +40,31 @@ const (
 	WORKSPACE_INIT_OPTION         = "workspace"
 	MVN_SETTINGS_FILE_INIT_OPTION = "mavenSettingsFile"
 	JVM_MAX_MEM_INIT_OPTION       = "jvmMaxMem"
+	// JAVA_HOME_INIT_OPTION is the JDK the language server runs on
+	JAVA_HOME_INIT_OPTION = "javaHome"
+	// PROJECT_JAVA_HOME_INIT_OPTION is the JDK the project is compiled with
+	PROJECT_JAVA_HOME_INIT_OPTION = "projectJavaHome"
 )
 
+// ConfigSchema is the schema of the providerSpecificConfig of the init
+// configs of the java provider
+func ConfigSchema() *openapi3.Schema {
+	return provider.NewConfigSchema(map[string]*openapi3.Schema{
+		provider.LspServerPathConfigKey:            provider.WithDescription(openapi3.NewStringSchema(), "Path of the language server"),
+		BUNDLES_INIT_OPTION:                        provider.WithDescription(openapi3.NewStringSchema(), "Paths of the extension bundles of the language server, separated by commas"),
+		WORKSPACE_INIT_OPTION:                      provider.WithDescription(openapi3.NewStringSchema(), "Directory the language server writes its workspace and logs to"),
+		MVN_SETTINGS_FILE_INIT_OPTION:              provider.WithDescription(openapi3.NewStringSchema(), "Path of the maven settings.xml"),
+		JVM_MAX_MEM_INIT_OPTION:                    provider.WithDescription(openapi3.NewStringSchema(), "Maximum memory of the language server JVM, passed as -Xmx"),
+		JAVA_HOME_INIT_OPTION:                      provider.WithDescription(openapi3.NewStringSchema(), "JDK the language server runs on, java 17 or later"),
+		PROJECT_JAVA_HOME_INIT_OPTION:              provider.WithDescription(openapi3.NewStringSchema(), "JDK the project is compiled with"),
+		providerSpecificConfigOpenSourceDepListKey: provider.WithDescription(openapi3.NewStringSchema(), "File of the patterns of the open source dependencies, one by line"),
+		providerSpecificConfigExcludePackagesKey:   provider.WithDescription(openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()), "Patterns of the dependency packages to exclude"),
+		provider.DependencyLicensesConfigKey:       provider.WithDescription(openapi3.NewBoolSchema(), "Detect the licenses of the dependencies from their poms and jars"),
+		QUERY_CHUNKING_INIT_OPTION:                 provider.WithDescription(openapi3.NewStringSchema().WithEnum(string(chunkingNone), string(chunkingSourceRoot), string(chunkingPackage)), "Split the symbol queries in a query by source root or by group of packages"),
+		QUERY_CHUNK_SIZE_INIT_OPTION:               provider.WithDescription(openapi3.NewIntegerSchema(), "Number of package directories of each query with the package chunking"),
+	})
+}
+
 // Rule Location to location that the bundle understands
 var locationToCode = map[string]int{
 	//Type is the default.
@@ -60,20 +87,37 @@ type javaProvider struct {
 	config provider.Config
 	Log    logr.Logger
 
-	clients []provider.ServiceClient
+	// clientsMutex protects the clients, which are replaced on restarts
+	clientsMutex sync.RWMutex
+	clients      []provider.ServiceClient
 
 	hasMaven bool
+
+	warnings provider.Warnings
 }
 
 var _ provider.InternalProviderClient = &javaProvider{}
+var _ provider.HealthChecker = &javaProvider{}
+var _ provider.Restartable = &javaProvider{}
+var _ provider.Suspendable = &javaProvider{}
+var _ engine.WarningReporter = &javaProvider{}
+var _ provider.FileExaminer = &javaProvider{}
+var _ provider.FileWatcher = &javaProvider{}
 
 type javaCondition struct {
-	Referenced referenceCondition `yaml:"referenced"`
+	Referenced               referenceCondition `yaml:"referenced"`
+	provider.ProviderContext `yaml:",inline"`
 }
 
 type referenceCondition struct {
 	Pattern  string `yaml:"pattern"`
 	Location string `yaml:"location"`
+	// Arguments are the parameter types of the overload a METHOD_CALL calls,
+	// an empty list only matches the calls to the overload without parameters
+	Arguments []string `yaml:"arguments,omitempty"`
+	// IncludeSubtypes also matches the classes that extend or implement the
+	// type of the pattern, directly or not
+	IncludeSubtypes bool `yaml:"includeSubtypes,omitempty"`
 }
 
 func NewJavaProvider(config provider.Config, log logr.Logger) *javaProvider {
@@ -90,9 +134,73 @@ func NewJavaProvider(config provider.Config, log logr.Logger) *javaProvider {
 
 func (p *javaProvider) Stop() {
 	// Ignore the error here, it stopped and we wanted it to.
-	for _, c := range p.clients {
+	for _, c := range p.getClients() {
+		c.Stop()
+	}
+}
+
+// Warnings returns the problems found while analyzing that do not stop the analysis
+func (p *javaProvider) Warnings() []konveyor.Warning {
+	return p.warnings.Warnings()
+}
+
+func (p *javaProvider) getClients() []provider.ServiceClient {
+	p.clientsMutex.RLock()
+	defer p.clientsMutex.RUnlock()
+	return p.clients
+}
+
+// HealthCheck checks that every language server still answers requests
+func (p *javaProvider) HealthCheck(ctx context.Context) error {
+	return provider.FullHealthCheck(ctx, p.getClients())
+}
+
+// Restart stops the language servers and starts new ones
+func (p *javaProvider) Restart(ctx context.Context) error {
+	p.clientsMutex.Lock()
+	clients := p.clients
+	p.clients = []provider.ServiceClient{}
+	p.clientsMutex.Unlock()
+	for _, c := range clients {
 		c.Stop()
 	}
+	return p.ProviderInit(ctx)
+}
+
+// Suspend stops the language servers
+func (p *javaProvider) Suspend() error {
+	for _, c := range p.getClients() {
+		if s, ok := c.(provider.Suspendable); ok {
+			if err := s.Suspend(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Continue runs the language servers again after Suspend
+func (p *javaProvider) Continue() error {
+	for _, c := range p.getClients() {
+		if s, ok := c.(provider.Suspendable); ok {
+			if err := s.Continue(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DidChangeWatchedFiles tells every language server about the changed files
+func (p *javaProvider) DidChangeWatchedFiles(ctx context.Context, changes []provider.FileChange) error {
+	for _, c := range p.getClients() {
+		if w, ok := c.(provider.FileWatcher); ok {
+			if err := w.DidChangeWatchedFiles(ctx, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (p *javaProvider) Capabilities() []provider.Capability {
@@ -102,17 +210,50 @@ func (p *javaProvider) Capabilities() []provider.Capability {
 			TemplateContext: openapi3.SchemaRef{},
 		},
 	}
-	if p.hasMaven {
+	for _, name := range inventoryNames() {
 		caps = append(caps, provider.Capability{
-			Name:            "dependency",
+			Name:            name,
 			TemplateContext: openapi3.SchemaRef{},
 		})
 	}
+	if p.hasMaven {
+		caps = append(caps, provider.Capability{
+			Name:              "dependency",
+			TemplateContext:   openapi3.SchemaRef{},
+			IncidentVariables: provider.DependencyIncidentVariables,
+		})
+	}
 	return caps
 }
 
+// Examines returns whether the file is a java source or binary, a build file
+// or a file that one of the inventories searches
+func (p *javaProvider) Examines(path string) bool {
+	name := filepath.Base(path)
+	switch filepath.Ext(name) {
+	case ".java", ".class", JavaArchive, WebArchive, EnterpriseArchive:
+		return true
+	}
+	if name == "pom.xml" {
+		return true
+	}
+	for _, inv := range inventories {
+		for _, q := range inv.config {
+			if q.files.MatchString(name) {
+				return true
+			}
+		}
+		for _, q := range inv.files {
+			if q.files.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (p *javaProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
-	return provider.FullResponseFromServiceClients(ctx, p.clients, cap, conditionInfo)
+	return provider.FullResponseFromServiceClients(ctx, p.getClients(), cap, conditionInfo)
 }
 
 func symbolKindToString(symbolKind protocol.SymbolKind) string {
@@ -179,7 +320,9 @@ func (p *javaProvider) ProviderInit(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		p.clientsMutex.Lock()
 		p.clients = append(p.clients, client)
+		p.clientsMutex.Unlock()
 	}
 	return nil
 }
@@ -216,6 +359,15 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		return nil, fmt.Errorf("invalid lspServerPath provided, unable to init java provider")
 	}
 
+	chunking, err := getQueryChunking(config)
+	if err != nil {
+		return nil, err
+	}
+	decompiler, err := getDecompiler(config)
+	if err != nil {
+		return nil, err
+	}
+
 	isBinary := false
 	var returnErr error
 	// each service client should have their own context
@@ -223,7 +375,7 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	extension := strings.ToLower(path.Ext(config.Location))
 	switch extension {
 	case JavaArchive, WebArchive, EnterpriseArchive:
-		depLocation, sourceLocation, err := decompileJava(ctx, log, config.Location)
+		depLocation, sourceLocation, err := decompileJava(ctx, log, decompiler, config.Location)
 		if err != nil {
 			cancelFunc()
 			return nil, err
@@ -234,12 +386,33 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		isBinary = true
 	}
 
+	// a single JDK compiles the projects of all the roots
+	targetLevel := 0
+	for _, root := range config.Roots() {
+		if level := projectTargetLevel(root); level > targetLevel {
+			targetLevel = level
+		}
+	}
+	serverJDK, projectJDK, jdkWarnings, err := selectJDKs(config.ProviderSpecificConfig, targetLevel, discoverJDKs)
+	if err != nil {
+		cancelFunc()
+		return nil, err
+	}
+	for _, warning := range jdkWarnings {
+		log.Info(warning)
+		p.warnings.Warn(warning, config.Location)
+	}
+	log.V(3).Info("selected JDKs", "server", serverJDK.home, "project", projectJDK.home)
+
 	// we attempt to decompile JARs of dependencies that don't have a sources JAR attached
 	// we need to do this for jdtls to correctly recognize source attachment for dep
-	err := resolveSourcesJars(ctx, log, config.Location, mavenSettingsFile)
-	if err != nil {
-		// TODO (pgaikwad): should we ignore this failure?
-		log.Error(err, "failed to resolve sources jar for location", "location", config.Location)
+	for _, root := range config.Roots() {
+		err = resolveSourcesJars(ctx, log, decompiler, root, mavenSettingsFile, projectJDK)
+		if err != nil {
+			// TODO (pgaikwad): should we ignore this failure?
+			log.Error(err, "failed to resolve sources jar for location", "location", root)
+			p.warnings.Warn("unable to resolve the sources of the dependencies, references into them may be missing", root)
+		}
 	}
 
 	// handle proxy settings
@@ -259,6 +432,9 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	}
 
 	cmd := exec.CommandContext(ctx, lspServerPath, args...)
+	if env := serverJDK.env(); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		cancelFunc()
@@ -270,18 +446,20 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		return nil, err
 	}
 
-	go func() {
-		err := cmd.Start()
-		if err != nil {
-			cancelFunc()
-			returnErr = err
-			log.Error(err, "unable to  start lsp command")
-			return
-		}
-	}()
+	process, err := provider.StartProcess("java language server", cmd, p.config.ResourceLimits)
+	if err != nil {
+		cancelFunc()
+		log.Error(err, "unable to  start lsp command")
+		return nil, err
+	}
 	rpc := jsonrpc2.NewConn(jsonrpc2.NewHeaderStream(stdout, stdin), log)
 
 	rpc.AddHandler(jsonrpc2.NewBackoffHandler(log))
+	if p.config.Retry != nil {
+		rpc.SetRetryPolicy(p.config.Retry.RPCPolicy())
+	}
+	rpc.AddHandler(jsonrpc2.NewCancelHandler())
+	rpc.AddHandler(metrics.NewRPCHandler(metrics.Default, "java"))
 
 	go func() {
 		err := rpc.Run(ctx)
@@ -299,16 +477,23 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		rpc:              rpc,
 		cancelFunc:       cancelFunc,
 		config:           config,
-		cmd:              cmd,
+		process:          process,
 		bundles:          bundles,
 		workspace:        workspace,
 		log:              log,
 		depToLabels:      map[string]*depLabelItem{},
 		isLocationBinary: isBinary,
 		mvnSettingsFile:  mavenSettingsFile,
+		projectJDK:       projectJDK,
+		licenses:         provider.DependencyLicenses(config),
+		warnings:         &p.warnings,
+		chunking:         chunking,
 	}
 
-	svcClient.initialization(ctx)
+	if err := svcClient.initialization(ctx); err != nil {
+		svcClient.Stop()
+		return nil, err
+	}
 	err = svcClient.depInit()
 	if err != nil {
 		return nil, err
@@ -317,16 +502,16 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 }
 
 func (p *javaProvider) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
-	return provider.FullDepsResponse(ctx, p.clients)
+	return provider.FullDepsResponse(ctx, p.getClients())
 }
 
 func (p *javaProvider) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
-	return provider.FullDepDAGResponse(ctx, p.clients)
+	return provider.FullDepDAGResponse(ctx, p.getClients())
 }
 
 // resolveSourcesJars for a given source code location, runs maven to find
 // deps that don't have sources attached and decompiles them
-func resolveSourcesJars(ctx context.Context, log logr.Logger, location, mavenSettings string) error {
+func resolveSourcesJars(ctx context.Context, log logr.Logger, d decompiler, location, mavenSettings string, projectJDK jdk) error {
 	decompileJobs := []decompileJob{}
 
 	log.V(5).Info("resolving dependency sources")
@@ -347,6 +532,9 @@ func resolveSourcesJars(ctx context.Context, log logr.Logger, location, mavenSet
 	}
 	cmd := exec.CommandContext(ctx, "mvn", args...)
 	cmd.Dir = location
+	if env := projectJDK.env(); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	mvnOutput, err := cmd.CombinedOutput()
 	if err != nil {
 		return err
@@ -360,6 +548,9 @@ func resolveSourcesJars(ctx context.Context, log logr.Logger, location, mavenSet
 
 	// remove unresolved sources if they are an actual module in the project
 	artifacts = filterExistingSubmodules(artifacts, pom)
+	if !d.enabled() {
+		return nil
+	}
 
 	m2Repo := getMavenLocalRepoPath(mavenSettings)
 	if m2Repo == "" {
@@ -379,7 +570,7 @@ func resolveSourcesJars(ctx context.Context, log logr.Logger, location, mavenSet
 				m2Repo, groupDirs, artifactDirs, artifact.Version, "decompiled", jarName),
 		})
 	}
-	err = decompile(ctx, log, alwaysDecompileFilter(true), 10, decompileJobs, "")
+	err = decompile(ctx, log, d, alwaysDecompileFilter(true), 10, decompileJobs, "")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,134 @@
+package java
+
+import (
+	"regexp"
+	"strings"
+)
+
+// the attributes of an EJB @Schedule and their defaults, in the order of
+// the fields of a cron expression
+var ejbScheduleAttributes = []struct {
+	name         string
+	defaultValue string
+}{
+	{name: "second", defaultValue: "0"},
+	{name: "minute", defaultValue: "0"},
+	{name: "hour", defaultValue: "0"},
+	{name: "dayOfMonth", defaultValue: "*"},
+	{name: "month", defaultValue: "*"},
+	{name: "dayOfWeek", defaultValue: "*"},
+}
+
+var annotationAttributeRegex = regexp.MustCompile(`(\w+)\s*=\s*"([^"]*)"`)
+
+// ejbScheduleToCron converts the attributes of an EJB @Schedule to a cron expression
+func ejbScheduleToCron(variables map[string]interface{}) {
+	attributes, ok := variables["attributes"].(string)
+	if !ok {
+		return
+	}
+	values := map[string]string{}
+	for _, m := range annotationAttributeRegex.FindAllStringSubmatch(attributes, -1) {
+		values[m[1]] = m[2]
+	}
+	fields := []string{}
+	for _, a := range ejbScheduleAttributes {
+		v, ok := values[a.name]
+		if !ok {
+			v = a.defaultValue
+		}
+		fields = append(fields, v)
+	}
+	variables["cron"] = strings.Join(fields, " ")
+	if persistent, ok := values["persistent"]; ok {
+		variables["persistent"] = persistent
+	}
+}
+
+// schedulerInventory finds scheduled jobs and timers, with their schedule where
+// it can be found, so that they can be moved to Kubernetes CronJobs.
+var schedulerInventory = inventory{
+	code: []codeQuery{
+		{
+			kind:      "ejb-timer",
+			pattern:   "javax.ejb.TimerService.create*",
+			location:  "method_call",
+			variables: map[string]interface{}{"timerType": "ejb"},
+		},
+		{
+			kind:      "ejb-timer",
+			pattern:   "jakarta.ejb.TimerService.create*",
+			location:  "method_call",
+			variables: map[string]interface{}{"timerType": "ejb"},
+		},
+		{
+			kind:      "quartz-job",
+			pattern:   "org.quartz.Scheduler.scheduleJob",
+			location:  "method_call",
+			variables: map[string]interface{}{"timerType": "quartz"},
+		},
+		{
+			kind:      "quartz-job",
+			pattern:   "org.quartz.Job",
+			location:  "implements_type",
+			variables: map[string]interface{}{"timerType": "quartz"},
+		},
+		{
+			kind:      "java-timer",
+			pattern:   "java.util.Timer.schedule*",
+			location:  "method_call",
+			variables: map[string]interface{}{"timerType": "java.util.Timer"},
+		},
+		{
+			kind:      "executor-schedule",
+			pattern:   "java.util.concurrent.ScheduledExecutorService.schedule*",
+			location:  "method_call",
+			variables: map[string]interface{}{"timerType": "executor"},
+		},
+	},
+	config: []configQuery{
+		{
+			kind:      "ejb-schedule",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:javax\.ejb\.|jakarta\.ejb\.)?Schedule\s*\((?P<attributes>[^)]*)\)`),
+			variables: map[string]interface{}{"timerType": "ejb"},
+			enrich:    ejbScheduleToCron,
+		},
+		{
+			kind:      "ejb-timeout",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:javax\.ejb\.|jakarta\.ejb\.)?Timeout\b`),
+			variables: map[string]interface{}{"timerType": "ejb"},
+		},
+		{
+			kind:      "spring-scheduled",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:org\.springframework\.scheduling\.annotation\.)?Scheduled\s*\(.*\bcron\s*=\s*"(?P<cron>[^"]+)"`),
+			variables: map[string]interface{}{"timerType": "spring"},
+		},
+		{
+			kind:      "spring-scheduled",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:org\.springframework\.scheduling\.annotation\.)?Scheduled\s*\(.*\b(?P<trigger>fixedRate|fixedDelay)(?:String)?\s*=\s*"?(?P<interval>[^",)]+)`),
+			variables: map[string]interface{}{"timerType": "spring"},
+		},
+		{
+			kind:      "quartz-cron",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`(?:cronSchedule|CronExpression|CronTrigger\w*)\s*\(\s*"(?P<cron>[^"]+)"`),
+			variables: map[string]interface{}{"timerType": "quartz"},
+		},
+		{
+			kind:      "quartz-cron",
+			files:     regexp.MustCompile(`.*\.xml$`),
+			pattern:   regexp.MustCompile(`<cron-expression>\s*(?P<cron>[^<]+?)\s*<`),
+			variables: map[string]interface{}{"timerType": "quartz"},
+		},
+		{
+			kind:      "scheduler-config",
+			files:     regexp.MustCompile(`^(quartz|application.*)\.(properties|ya?ml)$`),
+			pattern:   regexp.MustCompile(`^\s*(?P<property>[\w.-]*cron[\w.-]*)\s*[=:]\s*["']?(?P<cron>[^"'#]+?)["']?\s*$`),
+			variables: map[string]interface{}{"timerType": "config"},
+		},
+	},
+}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/jsonrpc2"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
 	"github.com/konveyor/analyzer-lsp/provider"
@@ -22,13 +23,22 @@ type javaServiceClient struct {
 	cancelFunc       context.CancelFunc
 	config           provider.InitConfig
 	log              logr.Logger
-	cmd              *exec.Cmd
+	process          *provider.Process
 	bundles          []string
 	workspace        string
 	depToLabels      map[string]*depLabelItem
 	isLocationBinary bool
 	mvnSettingsFile  string
+	projectJDK       jdk
 	depsCache        map[uri.URI][]*provider.Dep
+	// licenses is whether the licenses of the dependencies are detected
+	licenses bool
+	warnings *provider.Warnings
+	// chunking splits the symbol queries in queries on parts of the
+	// workspace, chunks are these parts once computed
+	chunking    queryChunking
+	chunksMutex sync.Mutex
+	chunks      []queryChunk
 }
 
 type depLabelItem struct {
@@ -39,6 +49,10 @@ type depLabelItem struct {
 var _ provider.ServiceClient = &javaServiceClient{}
 
 func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	if inv, ok := inventories[cap]; ok {
+		return p.evaluateInventory(ctx, cap, inv, conditionInfo)
+	}
+
 	cond := &javaCondition{}
 	err := yaml.Unmarshal(conditionInfo, &cond)
 	if err != nil {
@@ -49,18 +63,86 @@ func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("provided query pattern empty")
 	}
 
-	symbols := p.GetAllSymbols(ctx, cond.Referenced.Pattern, cond.Referenced.Location)
+	if err := validateArguments(cond.Referenced.Location, cond.Referenced.Arguments); err != nil {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("invalid arguments of pattern %s: %w", cond.Referenced.Pattern, err)
+	}
+
+	if cond.Referenced.IncludeSubtypes {
+		if err := validateSubtypes(cond.Referenced.Pattern, cond.Referenced.Location); err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+	}
+
+	if err := p.process.Exited(); err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+
+	incidents, err := p.getReferencedIncidents(ctx, cond.Referenced.Pattern, cond.Referenced.Location, cond.Referenced.Arguments, cond.Scope)
+	// push error up for easier printing.
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	if cond.Referenced.IncludeSubtypes {
+		subtypes, err := p.getSubtypes(ctx, cond.Referenced.Pattern)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		// the subtypes are matched as the type is
+		for _, subtype := range subtypes {
+			subtypeIncidents, err := p.getReferencedIncidents(ctx, subtype, cond.Referenced.Location, cond.Referenced.Arguments, cond.Scope)
+			if err != nil {
+				return provider.ProviderEvaluateResponse{}, err
+			}
+			incidents = append(incidents, subtypeIncidents...)
+		}
+	}
+	// the requests of a canceled query fail, its incidents are partial
+	if err := ctx.Err(); err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{
+			Matched: false,
+		}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// getReferencedIncidents finds the symbols matching the pattern and filters them
+// based on the given location, method arguments and scope.
+func (p *javaServiceClient) getReferencedIncidents(ctx context.Context, pattern, location string, arguments []string, scope *engine.Scope) ([]provider.IncidentContext, error) {
+	symbols := p.GetAllSymbols(ctx, pattern, location)
 	p.log.V(5).Info("Symbols retrieved", "symbols", symbols)
 
+	var err error
 	incidents := []provider.IncidentContext{}
-	switch locationToCode[strings.ToLower(cond.Referenced.Location)] {
+	switch locationToCode[strings.ToLower(location)] {
 	case 0:
 		// Filter handle for type, find all the referneces to this type.
 		incidents, err = p.filterDefault(symbols)
 	case 1, 5:
 		incidents, err = p.filterTypesInheritance(symbols)
 	case 2:
-		incidents, err = p.filterMethodSymbols(symbols)
+		if arguments == nil {
+			incidents, err = p.filterMethodSymbols(symbols)
+			break
+		}
+		matched, unresolved := p.filterMethodArguments(ctx, symbols, arguments)
+		incidents, err = p.filterMethodSymbols(matched)
+		if err != nil {
+			break
+		}
+		var unresolvedIncidents []provider.IncidentContext
+		unresolvedIncidents, err = p.filterMethodSymbols(unresolved)
+		for _, inc := range unresolvedIncidents {
+			confidence := provider.UnresolvedConfidence
+			inc.Confidence = &confidence
+			incidents = append(incidents, inc)
+		}
 	case 3:
 		incidents, err = p.filterConstructorSymbols(ctx, symbols)
 	case 4:
@@ -78,33 +160,49 @@ func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 	default:
 
 	}
-
-	// push error up for easier printing.
-	if err != nil {
-		return provider.ProviderEvaluateResponse{}, err
+	if err != nil || scope == nil {
+		return incidents, err
 	}
-
-	if len(incidents) == 0 {
-		return provider.ProviderEvaluateResponse{
-			Matched: false,
-		}, nil
+	inScope := []provider.IncidentContext{}
+	for _, inc := range incidents {
+		if scope.MatchesURI(p.config.RootOfURI(inc.FileURI), inc.FileURI) {
+			inScope = append(inScope, inc)
+		}
 	}
-	return provider.ProviderEvaluateResponse{
-		Matched:   true,
-		Incidents: incidents,
-	}, nil
+	return inScope, nil
 }
 
+// GetAllSymbols sends the query to the bundle, in a query for each chunk of
+// the workspace when the queries are split
 func (p *javaServiceClient) GetAllSymbols(ctx context.Context, query, location string) []protocol.WorkspaceSymbol {
+	var refs []protocol.WorkspaceSymbol
+	var err error
+	if chunks := p.getQueryChunks(); len(chunks) > 0 {
+		refs, err = p.querySymbolsInChunks(ctx, chunks, query, location)
+	} else {
+		refs, err = p.querySymbols(ctx, query, location, nil)
+	}
+	if err != nil {
+		p.log.Error(err, "unable to ask for tackle rule entry")
+	}
+	return refs
+}
+
+// querySymbols sends the query to the bundle, limited to the included paths
+// when they are given
+func (p *javaServiceClient) querySymbols(ctx context.Context, query, location string, includedPaths []string) ([]protocol.WorkspaceSymbol, error) {
 	// This command will run the added bundle to the language server. The command over the wire needs too look like this.
 	// in this case the project is hardcoded in the init of the Langauge Server above
 	// workspace/executeCommand '{"command": "io.konveyor.tackle.ruleEntry", "arguments": {"query":"*customresourcedefinition","project": "java"}}'
-	argumentsMap := map[string]string{
+	argumentsMap := map[string]interface{}{
 		"query":        query,
 		"project":      "java",
 		"location":     fmt.Sprintf("%v", locationToCode[strings.ToLower(location)]),
 		"analysisMode": string(p.config.AnalysisMode),
 	}
+	if len(includedPaths) > 0 {
+		argumentsMap["includedPaths"] = includedPaths
+	}
 
 	argumentsBytes, _ := json.Marshal(argumentsMap)
 	arguments := []json.RawMessage{argumentsBytes}
@@ -116,11 +214,7 @@ func (p *javaServiceClient) GetAllSymbols(ctx context.Context, query, location s
 
 	var refs []protocol.WorkspaceSymbol
 	err := p.rpc.Call(ctx, "workspace/executeCommand", wsp, &refs)
-	if err != nil {
-		p.log.Error(err, "unable to ask for tackle rule entry")
-	}
-
-	return refs
+	return refs, err
 }
 
 func (p *javaServiceClient) GetAllReferences(ctx context.Context, symbol protocol.WorkspaceSymbol) []protocol.Location {
@@ -155,24 +249,84 @@ func (p *javaServiceClient) GetAllReferences(ctx context.Context, symbol protoco
 		},
 	}
 
+	// the references of the other symbols are still found when the ones of a
+	// symbol fail
 	res := []protocol.Location{}
 	err := p.rpc.Call(ctx, "textDocument/references", params, &res)
-	if err != nil {
-		fmt.Printf("Error rpc: %v", err)
+	if err != nil && ctx.Err() == nil {
+		p.log.Error(err, "unable to find the references of a symbol", "symbol", symbol.Name)
+		p.warnings.Warn("unable to find the references of some symbols, their incidents are missing", symbol.Name)
 	}
 	return res
 }
 
+// HealthCheck sends a workspace symbol request that the language server has
+// to answer, without caring about the result.
+func (p *javaServiceClient) HealthCheck(ctx context.Context) error {
+	if err := p.process.Exited(); err != nil {
+		return err
+	}
+	var symbols []protocol.WorkspaceSymbol
+	err := p.rpc.Call(ctx, "workspace/symbol", &protocol.WorkspaceSymbolParams{Query: "__konveyor_health_check__"}, &symbols)
+	if err != nil {
+		return fmt.Errorf("java language server is not responding: %w", err)
+	}
+	return nil
+}
+
+func (p *javaServiceClient) Suspend() error {
+	return p.process.Suspend()
+}
+
+func (p *javaServiceClient) Continue() error {
+	return p.process.Continue()
+}
+
+// DidChangeWatchedFiles tells the language server to index the changed files
+// again, and drops the dependencies found before when a pom.xml changed.
+func (p *javaServiceClient) DidChangeWatchedFiles(ctx context.Context, changes []provider.FileChange) error {
+	params := &protocol.DidChangeWatchedFilesParams{}
+	for _, c := range changes {
+		params.Changes = append(params.Changes, protocol.FileEvent{
+			URI:  string(c.URI),
+			Type: protocol.FileChangeType(c.Type),
+		})
+		if filepath.Base(c.URI.Filename()) == "pom.xml" {
+			p.depsCache = nil
+		}
+	}
+	p.resetQueryChunks(changes)
+	if err := p.rpc.Notify(ctx, "workspace/didChangeWatchedFiles", params); err != nil {
+		return fmt.Errorf("unable to notify the java language server of the changed files: %w", err)
+	}
+	return nil
+}
+
 func (p *javaServiceClient) Stop() {
 	p.cancelFunc()
-	p.cmd.Wait()
+	p.process.Wait()
 }
 
-func (p *javaServiceClient) initialization(ctx context.Context) {
+func (p *javaServiceClient) initialization(ctx context.Context) error {
 	absLocation, err := filepath.Abs(p.config.Location)
 	if err != nil {
 		p.log.Error(err, "unable to get path to analyize")
-		panic(1)
+		return fmt.Errorf("unable to get the path of %s: %w", p.config.Location, err)
+	}
+	// jdtls imports the projects of all the workspace folders
+	folders := []protocol.WorkspaceFolder{}
+	folderURIs := []string{}
+	for _, root := range p.config.Roots() {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			p.log.Error(err, "unable to get path to analyize", "root", root)
+			return fmt.Errorf("unable to get the path of the workspace folder %s: %w", root, err)
+		}
+		folders = append(folders, protocol.WorkspaceFolder{
+			URI:  fmt.Sprintf("file://%v", absRoot),
+			Name: filepath.Base(absRoot),
+		})
+		folderURIs = append(folderURIs, fmt.Sprintf("file://%v", absRoot))
 	}
 
 	var absBundles []string
@@ -180,7 +334,7 @@ func (p *javaServiceClient) initialization(ctx context.Context) {
 		abs, err := filepath.Abs(bundle)
 		if err != nil {
 			p.log.Error(err, "unable to get path to bundles")
-			panic(1)
+			return fmt.Errorf("unable to get the path of the bundle %s: %w", bundle, err)
 		}
 		absBundles = append(absBundles, abs)
 
@@ -193,20 +347,31 @@ func (p *javaServiceClient) initialization(ctx context.Context) {
 	//TODO(shawn-hurley): add ability to parse path to URI in a real supported way
 	params := &protocol.InitializeParams{}
 	params.RootURI = fmt.Sprintf("file://%v", absLocation)
+	params.WorkspaceFolders = folders
 	params.Capabilities = protocol.ClientCapabilities{}
 	params.ExtendedClientCapilities = map[string]interface{}{
 		"classFileContentsSupport": true,
 	}
+	configuration := map[string]interface{}{
+		"maven": map[string]interface{}{
+			"userSettings": p.mvnSettingsFile,
+		},
+	}
+	if p.projectJDK.home != "" {
+		configuration["runtimes"] = []map[string]interface{}{
+			{
+				"name":    p.projectJDK.executionEnvironment(),
+				"path":    p.projectJDK.home,
+				"default": true,
+			},
+		}
+	}
 	params.InitializationOptions = map[string]interface{}{
 		"bundles":          absBundles,
-		"workspaceFolders": []string{fmt.Sprintf("file://%v", absLocation)},
+		"workspaceFolders": folderURIs,
 		"settings": map[string]interface{}{
 			"java": map[string]interface{}{
-				"configuration": map[string]interface{}{
-					"maven": map[string]interface{}{
-						"userSettings": p.mvnSettingsFile,
-					},
-				},
+				"configuration": configuration,
 				"maven": map[string]interface{}{
 					"downloadSources": downloadSources,
 				},
@@ -227,4 +392,5 @@ func (p *javaServiceClient) initialization(ctx context.Context) {
 		p.log.Error(err, "initialize failed")
 	}
 	p.log.V(2).Info("java connection initialized")
+	return nil
 }
@@ -0,0 +1,223 @@
+package java
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+const (
+	// ANY_ARGUMENT matches an argument of any type in the arguments of a
+	// METHOD_CALL condition
+	ANY_ARGUMENT  = "*"
+	varargsSuffix = "..."
+)
+
+// validateArguments checks the arguments of a referenced condition, they only
+// apply to method calls and only the last one can be a varargs
+func validateArguments(location string, arguments []string) error {
+	if arguments == nil {
+		return nil
+	}
+	if locationToCode[strings.ToLower(location)] != 2 {
+		return fmt.Errorf("arguments are only supported with location METHOD_CALL")
+	}
+	for i, arg := range arguments {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("argument %d is empty", i)
+		}
+		if strings.HasSuffix(arg, varargsSuffix) && i != len(arguments)-1 {
+			return fmt.Errorf("only the last argument can be a varargs, got %s", arg)
+		}
+	}
+	return nil
+}
+
+// filterMethodArguments keeps the method calls that call the overload with
+// the given parameters, the overload that is called is given by the hover of
+// the language server on the call. The calls of which the overload can not be
+// told are returned apart, they may call it.
+func (p *javaServiceClient) filterMethodArguments(ctx context.Context, symbols []protocol.WorkspaceSymbol, arguments []string) (matched []protocol.WorkspaceSymbol, unresolved []protocol.WorkspaceSymbol) {
+	for _, symbol := range symbols {
+		location, ok := symbol.Location.Value.(protocol.Location)
+		if !ok {
+			unresolved = append(unresolved, symbol)
+			continue
+		}
+		hover, err := p.getHoverText(ctx, location)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to get the signature of the method call", "symbol", symbol.Name)
+			unresolved = append(unresolved, symbol)
+			continue
+		}
+		parameters, ok := parseParameterTypes(hover)
+		if !ok {
+			p.log.V(5).Info("unable to parse the signature of the method call", "symbol", symbol.Name, "hover", hover)
+			unresolved = append(unresolved, symbol)
+			continue
+		}
+		if argumentsMatch(arguments, parameters) {
+			matched = append(matched, symbol)
+		}
+	}
+	return matched, unresolved
+}
+
+// getHoverText returns the text of the hover of the language server at the
+// start of a location
+func (p *javaServiceClient) getHoverText(ctx context.Context, location protocol.Location) (string, error) {
+	params := &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: location.URI,
+			},
+			Position: location.Range.Start,
+		},
+	}
+	// jdtls answers with any of the forms of the hover contents, which the
+	// protocol types do not all decode
+	var res struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := p.rpc.Call(ctx, "textDocument/hover", params, &res); err != nil {
+		return "", err
+	}
+	return hoverContentsText(res.Contents), nil
+}
+
+// hoverContentsText returns the text of hover contents, which are a string, a
+// marked string or markup content, or a list of strings and marked strings
+func hoverContentsText(contents json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(contents, &text); err == nil {
+		return text
+	}
+	var value struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(contents, &value); err == nil && value.Value != "" {
+		return value.Value
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(contents, &list); err != nil {
+		return ""
+	}
+	texts := []string{}
+	for _, item := range list {
+		texts = append(texts, hoverContentsText(item))
+	}
+	return strings.Join(texts, "\n")
+}
+
+// parseParameterTypes returns the types of the parameters of the method
+// signature in a hover, such as "boolean java.util.List.add(E e)"
+func parseParameterTypes(hover string) ([]string, bool) {
+	for _, line := range strings.Split(hover, "\n") {
+		start := strings.Index(line, "(")
+		if start < 0 {
+			continue
+		}
+		end := strings.LastIndex(line, ")")
+		if end < start {
+			continue
+		}
+		parameters := []string{}
+		for _, param := range splitTopLevel(line[start+1:end], ',') {
+			if param = parameterType(param); param != "" {
+				parameters = append(parameters, param)
+			}
+		}
+		return parameters, true
+	}
+	return nil, false
+}
+
+// splitTopLevel splits s on sep outside of the type arguments
+func splitTopLevel(s string, sep rune) []string {
+	parts := []string{}
+	depth, last := 0, 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// parameterType returns the type of a parameter, without its modifiers,
+// annotations and name
+func parameterType(param string) string {
+	words := []string{}
+	for _, word := range splitTopLevel(strings.TrimSpace(param), ' ') {
+		if word == "" || word == "final" || strings.HasPrefix(word, "@") {
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	// the parameters of the classes without sources can have no names
+	if len(words) > 1 {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// argumentsMatch tells whether the parameters of a method match the arguments
+// of a condition
+func argumentsMatch(arguments, parameters []string) bool {
+	if len(arguments) != len(parameters) {
+		return false
+	}
+	for i := range arguments {
+		if !typeMatches(arguments[i], parameters[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeMatches tells whether a parameter type matches the type of an argument,
+// either its simple or its qualified name. The type arguments are ignored.
+func typeMatches(argument, parameter string) bool {
+	argument = strings.TrimSpace(argument)
+	if argument == ANY_ARGUMENT {
+		return true
+	}
+	if strings.HasSuffix(argument, varargsSuffix) != strings.HasSuffix(parameter, varargsSuffix) {
+		return false
+	}
+	argument = eraseTypeArguments(strings.TrimSuffix(argument, varargsSuffix))
+	parameter = eraseTypeArguments(strings.TrimSuffix(parameter, varargsSuffix))
+	return argument == parameter || strings.HasSuffix(parameter, "."+argument)
+}
+
+// eraseTypeArguments removes the type arguments and the spaces from a type,
+// such as java.util.List<String> []
+func eraseTypeArguments(t string) string {
+	erased := strings.Builder{}
+	depth := 0
+	for _, r := range t {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>':
+			depth--
+		case depth == 0 && r != ' ':
+			erased.WriteRune(r)
+		}
+	}
+	return erased.String()
+}
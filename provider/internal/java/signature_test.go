@@ -0,0 +1,207 @@
+package java
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParameterTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		hover string
+		want  []string
+	}{
+		{
+			name:  "no parameters",
+			hover: "```java\njava.lang.String java.lang.Object.toString()\n```",
+			want:  []string{},
+		},
+		{
+			name:  "named parameters",
+			hover: "void java.util.List.add(int index, E element)",
+			want:  []string{"int", "E"},
+		},
+		{
+			name:  "generic parameters",
+			hover: "void com.example.Cache.putAll(java.util.Map<String, ? extends V> entries, final @Nonnull String[] keys)",
+			want:  []string{"java.util.Map<String, ? extends V>", "String[]"},
+		},
+		{
+			name:  "varargs",
+			hover: "java.lang.String java.lang.String.format(String format, Object... args)",
+			want:  []string{"String", "Object..."},
+		},
+		{
+			name:  "class file without names",
+			hover: "void org.example.Client.call(java.lang.String, int)\n\nCalls the (remote) service",
+			want:  []string{"java.lang.String", "int"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseParameterTypes(tt.hover)
+			if !ok {
+				t.Fatalf("unable to parse %q", tt.hover)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+	if _, ok := parseParameterTypes("java.util.List"); ok {
+		t.Errorf("expected a hover without signature not to be parsed")
+	}
+}
+
+func TestArgumentsMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		arguments  []string
+		parameters []string
+		want       bool
+	}{
+		{
+			name:       "no arguments",
+			arguments:  []string{},
+			parameters: []string{},
+			want:       true,
+		},
+		{
+			name:       "different arity",
+			arguments:  []string{"String"},
+			parameters: []string{"String", "int"},
+			want:       false,
+		},
+		{
+			name:       "simple name",
+			arguments:  []string{"String", "int"},
+			parameters: []string{"java.lang.String", "int"},
+			want:       true,
+		},
+		{
+			name:       "qualified name",
+			arguments:  []string{"java.lang.String"},
+			parameters: []string{"java.lang.String"},
+			want:       true,
+		},
+		{
+			name:       "different type",
+			arguments:  []string{"long"},
+			parameters: []string{"int"},
+			want:       false,
+		},
+		{
+			name:       "partial simple name",
+			arguments:  []string{"String"},
+			parameters: []string{"com.example.MyString"},
+			want:       false,
+		},
+		{
+			name:       "any argument",
+			arguments:  []string{"*", "int"},
+			parameters: []string{"java.util.Map<K, V>", "int"},
+			want:       true,
+		},
+		{
+			name:       "type arguments ignored",
+			arguments:  []string{"java.util.List<String>"},
+			parameters: []string{"java.util.List<java.lang.Integer>"},
+			want:       true,
+		},
+		{
+			name:       "varargs",
+			arguments:  []string{"String", "Object..."},
+			parameters: []string{"String", "java.lang.Object..."},
+			want:       true,
+		},
+		{
+			name:       "array is not varargs",
+			arguments:  []string{"Object..."},
+			parameters: []string{"Object[]"},
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := argumentsMatch(tt.arguments, tt.parameters); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		location  string
+		arguments []string
+		wantErr   bool
+	}{
+		{
+			name:     "no arguments",
+			location: "TYPE",
+		},
+		{
+			name:      "method call",
+			location:  "METHOD_CALL",
+			arguments: []string{"String", "Object..."},
+		},
+		{
+			name:      "other location",
+			location:  "CONSTRUCTOR_CALL",
+			arguments: []string{"String"},
+			wantErr:   true,
+		},
+		{
+			name:      "varargs not last",
+			location:  "METHOD_CALL",
+			arguments: []string{"Object...", "String"},
+			wantErr:   true,
+		},
+		{
+			name:      "empty argument",
+			location:  "METHOD_CALL",
+			arguments: []string{""},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArguments(tt.location, tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHoverContentsText(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "string",
+			contents: `"void a.B.c()"`,
+			want:     "void a.B.c()",
+		},
+		{
+			name:     "markup content",
+			contents: `{"kind": "markdown", "value": "void a.B.c()"}`,
+			want:     "void a.B.c()",
+		},
+		{
+			name:     "list of marked strings",
+			contents: `[{"language": "java", "value": "void a.B.c(int i)"}, "Does c"]`,
+			want:     "void a.B.c(int i)\nDoes c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hoverContentsText([]byte(tt.contents)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
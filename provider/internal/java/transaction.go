@@ -0,0 +1,62 @@
+package java
+
+import "regexp"
+
+var (
+	programmaticTransaction = map[string]interface{}{"model": "programmatic"}
+	declarativeTransaction  = map[string]interface{}{"model": "declarative"}
+)
+
+// transactionInventory finds where transactions are demarcated, either in
+// code or declaratively, with a summary of each class to estimate the effort
+// of moving to another transaction model.
+var transactionInventory = inventory{
+	code: []codeQuery{
+		{kind: "user-transaction", pattern: "javax.transaction.UserTransaction.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "user-transaction", pattern: "jakarta.transaction.UserTransaction.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "user-transaction", pattern: "javax.ejb.EJBContext.getUserTransaction", location: "method_call", variables: programmaticTransaction},
+		{kind: "user-transaction", pattern: "jakarta.ejb.EJBContext.getUserTransaction", location: "method_call", variables: programmaticTransaction},
+		{kind: "transaction-manager", pattern: "javax.transaction.TransactionManager.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "transaction-manager", pattern: "jakarta.transaction.TransactionManager.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "transaction-manager", pattern: "org.springframework.transaction.PlatformTransactionManager.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "transaction-manager", pattern: "org.springframework.transaction.support.TransactionTemplate.execute*", location: "method_call", variables: programmaticTransaction},
+		{kind: "resource-local-transaction", pattern: "javax.persistence.EntityTransaction.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "resource-local-transaction", pattern: "jakarta.persistence.EntityTransaction.*", location: "method_call", variables: programmaticTransaction},
+		{kind: "transactional-annotation", pattern: "javax.transaction.Transactional", location: "annotation", variables: declarativeTransaction},
+		{kind: "transactional-annotation", pattern: "jakarta.transaction.Transactional", location: "annotation", variables: declarativeTransaction},
+		{kind: "transactional-annotation", pattern: "org.springframework.transaction.annotation.Transactional", location: "annotation", variables: declarativeTransaction},
+	},
+	config: []configQuery{
+		{
+			kind:      "bean-managed",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:javax\.ejb\.|jakarta\.ejb\.)?TransactionManagement\s*\(\s*(?:TransactionManagementType\.)?BEAN\s*\)`),
+			variables: programmaticTransaction,
+		},
+		{
+			kind:      "transaction-attribute",
+			files:     regexp.MustCompile(`.*\.java$`),
+			pattern:   regexp.MustCompile(`@(?:javax\.ejb\.|jakarta\.ejb\.)?TransactionAttribute\s*\(\s*(?:value\s*=\s*)?(?:TransactionAttributeType\.)?(?P<attribute>\w+)\s*\)`),
+			variables: declarativeTransaction,
+		},
+		{
+			kind:      "bean-managed",
+			files:     regexp.MustCompile(`^ejb-jar\.xml$`),
+			pattern:   regexp.MustCompile(`<transaction-type>\s*Bean\s*<`),
+			variables: programmaticTransaction,
+		},
+		{
+			kind:      "transaction-descriptor",
+			files:     regexp.MustCompile(`^ejb-jar\.xml$`),
+			pattern:   regexp.MustCompile(`<trans-attribute>\s*(?P<attribute>\w+)\s*<`),
+			variables: declarativeTransaction,
+		},
+		{
+			kind:      "transaction-descriptor",
+			files:     regexp.MustCompile(`.*\.xml$`),
+			pattern:   regexp.MustCompile(`<tx:(?:annotation-driven|advice|method)\b`),
+			variables: declarativeTransaction,
+		},
+	},
+	summaryKind: "transaction-summary",
+}
@@ -12,7 +12,6 @@ import (
 	"math"
 	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -98,7 +97,10 @@ type decompileJob struct {
 // decompile decompiles files submitted via a list of decompileJob concurrently
 // if a .class file is encountered, it will be decompiled to output path right away
 // if a .jar file is encountered, it will be decompiled as a whole, then exploded to project path
-func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, workerCount int, jobs []decompileJob, projectPath string) error {
+func decompile(ctx context.Context, log logr.Logger, d decompiler, filter decompileFilter, workerCount int, jobs []decompileJob, projectPath string) error {
+	if !d.enabled() {
+		return nil
+	}
 	wg := &sync.WaitGroup{}
 	jobChan := make(chan decompileJob)
 
@@ -126,9 +128,7 @@ func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, wor
 						"failed to create directories for decompiled file", "path", outputPathDir)
 					continue
 				}
-				cmd := exec.CommandContext(
-					ctx, "java", "-jar", "/bin/fernflower.jar", job.inputPath, outputPathDir)
-				err := cmd.Run()
+				err := d.decompileFile(ctx, job.inputPath, job.outputPath)
 				if err != nil {
 					log.V(5).Error(err, "failed to decompile file", "file", job.inputPath, job.outputPath)
 				} else {
@@ -165,7 +165,10 @@ func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, wor
 // decompileJava unpacks archive at archivePath, decompiles all .class files in it
 // creates new java project and puts the java files in the tree of the project
 // returns path to exploded archive, path to java project, and an error when encountered
-func decompileJava(ctx context.Context, log logr.Logger, archivePath string) (explodedPath, projectPath string, err error) {
+func decompileJava(ctx context.Context, log logr.Logger, d decompiler, archivePath string) (explodedPath, projectPath string, err error) {
+	if !d.enabled() {
+		return "", "", fmt.Errorf("the binary %s can not be analyzed with the %s %s", archivePath, DECOMPILER_INIT_OPTION, noDecompiler)
+	}
 	ctx, span := tracing.StartNewSpan(ctx, "decompile")
 	defer span.End()
 
@@ -186,7 +189,7 @@ func decompileJava(ctx context.Context, log logr.Logger, archivePath string) (ex
 	}
 	log.V(5).Info("created java project", "path", projectPath)
 
-	err = decompile(ctx, log, decompFilter, 10, decompJobs, projectPath)
+	err = decompile(ctx, log, d, decompFilter, 10, decompJobs, projectPath)
 	if err != nil {
 		log.Error(err, "failed to decompile", "path", archivePath)
 		return "", "", err
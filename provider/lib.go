@@ -3,6 +3,7 @@ package provider
 import (
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -65,7 +66,7 @@ func GetFiles(configLocation string, filepaths []string, patterns ...string) ([]
 				xmlFiles = append(xmlFiles, files...)
 			}
 		}
-	} else if len(filepaths) == 1 {
+	} else if len(filepaths) == 1 && !isFileIn(configLocation, filepaths[0]) {
 		// Currently, rendering will render a list as a space separated paths as a single string.
 		patterns := strings.Split(filepaths[0], " ")
 		for _, pattern := range patterns {
@@ -83,9 +84,14 @@ func GetFiles(configLocation string, filepaths []string, patterns ...string) ([]
 		}
 	} else {
 		for _, pattern := range filepaths {
+			// the files of a chained condition
+			if isFileIn(configLocation, pattern) {
+				xmlFiles = append(xmlFiles, pattern)
+				continue
+			}
 			files, err := FindFilesMatchingPattern(configLocation, pattern)
 			if err != nil {
-				fmt.Errorf("Unable to find files using pattern `%s`: %v", pattern, err)
+				// the patterns that can not be searched do not match any file
 				continue
 			} else {
 				xmlFiles = append(xmlFiles, files...)
@@ -94,3 +100,21 @@ func GetFiles(configLocation string, filepaths []string, patterns ...string) ([]
 	}
 	return xmlFiles, nil
 }
+
+// isFileIn is whether the path is the absolute path of a file in the
+// location
+func isFileIn(location string, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(location, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -8,8 +8,18 @@ import (
 	"github.com/konveyor/analyzer-lsp/provider/internal/java"
 )
 
+func init() {
+	provider.RegisterConfigSchema("java", java.ConfigSchema())
+	provider.RegisterConfigSchema("builtin", builtin.ConfigSchema())
+}
+
 // We need some wrapper that can deal with out of tree providers, this will be a call, that will mock it out, but go against in tree.
+// The providers given by address, such as the ones attached to a daemon,
+// are reached over gRPC whatever their name.
 func GetProviderClient(config provider.Config, log logr.Logger) (provider.InternalProviderClient, error) {
+	if config.Address != "" {
+		return grpc.NewGRPCClient(config, log), nil
+	}
 	switch config.Name {
 	case "java":
 		return java.NewJavaProvider(config, log), nil
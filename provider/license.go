@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DependencyLicensesConfigKey is a provider specific config that tells the
+// providers to detect the licenses of the dependencies. It is off by default,
+// the detection reads the poms, manifests or license files of every dependency.
+const DependencyLicensesConfigKey = "dependencyLicenses"
+
+// DependencyLicensesEnv is set to true in the environment of the dependency
+// providers of the external providers when the licenses are detected
+const DependencyLicensesEnv = "KONVEYOR_DEPENDENCY_LICENSES"
+
+// DependencyLicenses returns whether the licenses of the dependencies are
+// detected for the init config
+func DependencyLicenses(c InitConfig) bool {
+	enabled, _ := c.ProviderSpecificConfig[DependencyLicensesConfigKey].(bool)
+	return enabled
+}
+
+// licenseNames maps the names and URLs the licenses are declared with, in
+// lower case, to their SPDX identifier. The names are matched as substrings,
+// the more specific first.
+var licenseNames = []struct {
+	pattern *regexp.Regexp
+	spdx    string
+}{
+	{regexp.MustCompile(`apache.*2(\.0)?|apache-2\.0|licenses/license-2\.0`), "Apache-2.0"},
+	{regexp.MustCompile(`\bmit\b|opensource\.org/licenses/mit`), "MIT"},
+	{regexp.MustCompile(`bsd.*3|3-clause|new bsd|revised bsd|eclipse distribution license`), "BSD-3-Clause"},
+	{regexp.MustCompile(`bsd.*2|2-clause|simplified bsd|freebsd`), "BSD-2-Clause"},
+	{regexp.MustCompile(`eclipse public license.*2|\bepl.*2`), "EPL-2.0"},
+	{regexp.MustCompile(`eclipse public license|\bepl\b`), "EPL-1.0"},
+	{regexp.MustCompile(`common development and distribution license|cddl`), "CDDL-1.0"},
+	{regexp.MustCompile(`lesser general public license.*3|\blgpl.*3`), "LGPL-3.0"},
+	{regexp.MustCompile(`lesser general public license|library general public license|\blgpl`), "LGPL-2.1"},
+	{regexp.MustCompile(`affero|\bagpl`), "AGPL-3.0"},
+	{regexp.MustCompile(`general public license.*3|\bgpl.*3`), "GPL-3.0"},
+	{regexp.MustCompile(`general public license|\bgpl`), "GPL-2.0"},
+	{regexp.MustCompile(`mozilla public license|\bmpl\b`), "MPL-2.0"},
+	{regexp.MustCompile(`isc license|\bisc\b`), "ISC"},
+	{regexp.MustCompile(`unlicense`), "Unlicense"},
+}
+
+// NormalizeLicense returns the SPDX identifier of a license declared by its
+// name or URL, such as the ones of a pom, or the name as is when it is not
+// recognized
+func NormalizeLicense(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	for _, l := range licenseNames {
+		if l.pattern.MatchString(lower) {
+			return l.spdx
+		}
+	}
+	return name
+}
+
+// licenseTexts are phrases of the text of the licenses that tell them apart,
+// the more specific first
+var licenseTexts = []struct {
+	phrase string
+	spdx   string
+}{
+	{"apache license", "Apache-2.0"},
+	{"permission is hereby granted, free of charge", "MIT"},
+	{"mozilla public license", "MPL-2.0"},
+	{"gnu affero general public license", "AGPL-3.0"},
+	{"gnu lesser general public license", "LGPL-3.0"},
+	{"gnu general public license", "GPL-3.0"},
+	{"eclipse public license", "EPL-2.0"},
+	{"neither the name of", "BSD-3-Clause"},
+	{"redistributions of source code must retain", "BSD-2-Clause"},
+	{"permission to use, copy, modify, and/or distribute", "ISC"},
+	{"this is free and unencumbered software", "Unlicense"},
+}
+
+// ClassifyLicenseText returns the SPDX identifier of the license of a license
+// file, empty when it is not recognized
+func ClassifyLicenseText(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, l := range licenseTexts {
+		if !strings.Contains(text, l.phrase) {
+			continue
+		}
+		switch l.spdx {
+		case "GPL-3.0", "LGPL-3.0":
+			if strings.Contains(text, "version 2") && !strings.Contains(text, "version 3") {
+				if l.spdx == "GPL-3.0" {
+					return "GPL-2.0"
+				}
+				return "LGPL-2.1"
+			}
+		case "EPL-2.0":
+			if strings.Contains(text, "v 1.0") || strings.Contains(text, "version 1.0") {
+				return "EPL-1.0"
+			}
+		}
+		return l.spdx
+	}
+	return ""
+}
+
+// LicenseFromDir returns the license of the license files at the top of a
+// directory, such as LICENSE, LICENSE.txt or COPYING. The licenses of several
+// files are joined with OR, empty when there is none or none is recognized.
+func LicenseFromDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	found := map[string]bool{}
+	for _, e := range entries {
+		name := strings.ToUpper(e.Name())
+		if e.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		if spdx := ClassifyLicenseText(string(content)); spdx != "" {
+			found[spdx] = true
+		}
+	}
+	return JoinLicenses(found)
+}
+
+// JoinLicenses joins the licenses of a dependency that is under several of
+// them with OR, sorted so that the output is stable
+func JoinLicenses(licenses map[string]bool) string {
+	list := []string{}
+	for l := range licenses {
+		if l != "" {
+			list = append(list, l)
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, " OR ")
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "The Apache Software License, Version 2.0", want: "Apache-2.0"},
+		{name: "https://www.apache.org/licenses/LICENSE-2.0.txt", want: "Apache-2.0"},
+		{name: "MIT License", want: "MIT"},
+		{name: "https://opensource.org/licenses/MIT", want: "MIT"},
+		{name: "Eclipse Public License - v 2.0", want: "EPL-2.0"},
+		{name: "Eclipse Public License 1.0", want: "EPL-1.0"},
+		{name: "Eclipse Distribution License - v 1.0", want: "BSD-3-Clause"},
+		{name: "GNU Lesser General Public License, Version 2.1", want: "LGPL-2.1"},
+		{name: "GPL2 w/ CPE", want: "GPL-2.0"},
+		{name: "CDDL + GPLv2 with classpath exception", want: "CDDL-1.0"},
+		{name: "https://example.com/license", want: "https://example.com/license"},
+		{name: " Proprietary ", want: "Proprietary"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeLicense(tt.name); got != tt.want {
+			t.Errorf("expected %q for %q, got %q", tt.want, tt.name, got)
+		}
+	}
+}
+
+func TestClassifyLicenseText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "mit",
+			text: "MIT License\n\nPermission is hereby granted, free of charge, to any person\nobtaining a copy",
+			want: "MIT",
+		},
+		{
+			name: "apache",
+			text: "                                 Apache License\n                           Version 2.0, January 2004",
+			want: "Apache-2.0",
+		},
+		{
+			name: "bsd 3 clause",
+			text: "Redistributions of source code must retain the above copyright notice.\nNeither the name of Google Inc. nor the names of its contributors",
+			want: "BSD-3-Clause",
+		},
+		{
+			name: "bsd 2 clause",
+			text: "Redistributions of source code must retain the above copyright notice.",
+			want: "BSD-2-Clause",
+		},
+		{
+			name: "gpl 2",
+			text: "GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991",
+			want: "GPL-2.0",
+		},
+		{
+			name: "unknown",
+			text: "All rights reserved.",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyLicenseText(tt.text); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLicenseFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"LICENSE":         "Permission is hereby granted, free of charge, to any person",
+		"LICENSE-APACHE":  "Apache License\nVersion 2.0",
+		"COPYING.unknown": "All rights reserved.",
+		"README.md":       "GNU General Public License",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := LicenseFromDir(dir); got != "Apache-2.0 OR MIT" {
+		t.Errorf("expected Apache-2.0 OR MIT, got %q", got)
+	}
+	if got := LicenseFromDir(filepath.Join(dir, "missing")); got != "" {
+		t.Errorf("expected no license for a missing directory, got %q", got)
+	}
+}
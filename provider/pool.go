@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+)
+
+// Pool keeps the service clients of a provider running once the analyses
+// that initialized them stop them, so that the next analysis of the same
+// location with the same settings reuses them instead of starting and
+// indexing again, which is most of the time of an analysis with few rules.
+// It is served by a provider server in a daemon the analyses attach to, see
+// PooledProvider.
+type Pool struct {
+	client BaseClient
+	// idleTimeout is how long a service client that no analysis uses is kept,
+	// forever when it is 0
+	idleTimeout time.Duration
+	log         logr.Logger
+
+	mutex   sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	key    string
+	client ServiceClient
+	// leases is the number of analyses using the client
+	leases int
+	idle   *time.Timer
+	// ready is closed once the client is initialized, err is set when it
+	// failed to
+	ready chan struct{}
+	err   error
+}
+
+var _ BaseClient = &Pool{}
+
+// NewPool returns the pool of the service clients of the client
+func NewPool(client BaseClient, idleTimeout time.Duration, log logr.Logger) *Pool {
+	return &Pool{
+		client:      client,
+		idleTimeout: idleTimeout,
+		log:         log.WithName("pool"),
+		clients:     map[string]*pooledClient{},
+	}
+}
+
+func (p *Pool) Capabilities() []Capability {
+	return p.client.Capabilities()
+}
+
+// Init returns the service client of the config, initializing it when the
+// pool does not have it yet. The service client it returns is not stopped by
+// its Stop, it is released for the next analysis.
+func (p *Pool) Init(ctx context.Context, log logr.Logger, config InitConfig) (ServiceClient, error) {
+	key, err := InitConfigKey(config)
+	if err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	c, ok := p.clients[key]
+	if !ok {
+		c = &pooledClient{key: key, ready: make(chan struct{})}
+		p.clients[key] = c
+	}
+	c.leases++
+	if c.idle != nil {
+		c.idle.Stop()
+		c.idle = nil
+	}
+	p.mutex.Unlock()
+
+	if ok {
+		<-c.ready
+		if c.err != nil {
+			p.release(c)
+			return nil, c.err
+		}
+		p.log.V(3).Info("reusing the service client", "location", config.Location, "key", key)
+		return &leasedClient{ServiceClient: c.client, pool: p, pooled: c}, nil
+	}
+
+	start := time.Now()
+	c.client, c.err = p.client.Init(ctx, log, config)
+	close(c.ready)
+	if c.err != nil {
+		// the next analysis tries again
+		p.mutex.Lock()
+		delete(p.clients, key)
+		p.mutex.Unlock()
+		return nil, c.err
+	}
+	p.log.V(3).Info("initialized the service client", "location", config.Location, "key", key, "duration", time.Since(start))
+	return &leasedClient{ServiceClient: c.client, pool: p, pooled: c}, nil
+}
+
+// release gives back a lease of the client, the client is stopped once it
+// is not used for the idle timeout
+func (p *Pool) release(c *pooledClient) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	c.leases--
+	if c.leases > 0 || c.err != nil || p.idleTimeout == 0 {
+		return
+	}
+	c.idle = time.AfterFunc(p.idleTimeout, func() {
+		p.mutex.Lock()
+		if c.leases > 0 || p.clients[c.key] != c {
+			p.mutex.Unlock()
+			return
+		}
+		delete(p.clients, c.key)
+		p.mutex.Unlock()
+		p.log.V(3).Info("stopping the idle service client", "key", c.key)
+		c.client.Stop()
+	})
+}
+
+// Close stops the service clients of the pool, also the ones that are used
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	clients := p.clients
+	p.clients = map[string]*pooledClient{}
+	for _, c := range clients {
+		if c.idle != nil {
+			c.idle.Stop()
+		}
+	}
+	p.mutex.Unlock()
+	for _, c := range clients {
+		<-c.ready
+		if c.err == nil {
+			c.client.Stop()
+		}
+	}
+}
+
+// leasedClient is a service client of the pool used by an analysis
+type leasedClient struct {
+	ServiceClient
+	pool   *Pool
+	pooled *pooledClient
+	once   sync.Once
+}
+
+func (l *leasedClient) Stop() {
+	l.once.Do(func() {
+		l.pool.release(l.pooled)
+	})
+}
+
+func (l *leasedClient) HealthCheck(ctx context.Context) error {
+	if h, ok := l.ServiceClient.(HealthChecker); ok {
+		return h.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// CacheHits returns the cache hits of the leased client, none when it does not
+// count them, so that the statistics of a pooled client keep them
+func (l *leasedClient) CacheHits() map[string]int64 {
+	if c, ok := l.ServiceClient.(CacheHitCounter); ok {
+		return c.CacheHits()
+	}
+	return nil
+}
+
+// InitConfigKey identifies the service clients of a pool, by the location
+// and the settings of their config. The configs read from the provider
+// settings and the ones received by a provider server have the same key,
+// although the maps of the latter have string keys and their numbers are
+// floats.
+func InitConfigKey(config InitConfig) (string, error) {
+	if config.AnalysisMode == "" {
+		config.AnalysisMode = FullAnalysisMode
+	}
+	proxy := Proxy{}
+	if config.Proxy != nil {
+		proxy = *config.Proxy
+	}
+	content, err := json.Marshal(struct {
+		Location               string
+		DependencyPath         string
+		WorkspaceFolders       []string
+		AnalysisMode           AnalysisMode
+		HTTPProxy              string
+		HTTPSProxy             string
+		NoProxy                string
+		ProviderSpecificConfig string
+	}{
+		Location:         config.Location,
+		DependencyPath:   config.DependencyPath,
+		WorkspaceFolders: config.WorkspaceFolders,
+		AnalysisMode:     config.AnalysisMode,
+		HTTPProxy:        proxy.HTTPProxy,
+		HTTPSProxy:       proxy.HTTPSProxy,
+		NoProxy:          proxy.NoProxy,
+		// fmt prints the maps with their keys sorted, whatever the type of
+		// their keys, and the integers and the integral floats alike
+		ProviderSpecificConfig: fmt.Sprintf("%v", config.ProviderSpecificConfig),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PooledProvider is a provider served by a daemon from a Pool, that the
+// analyses with the same settings for the provider attach to instead of
+// starting it
+type PooledProvider struct {
+	Name    string `yaml:"name" json:"name"`
+	Address string `yaml:"address" json:"address"`
+	// SettingsDigest is the SettingsDigest of the config of the provider the
+	// daemon serves
+	SettingsDigest string `yaml:"settingsDigest" json:"settingsDigest"`
+}
+
+// SettingsDigest returns the digest of the settings of the config that
+// change how the provider runs, such as its binary and its language server.
+// The settings of its init configs are the keys of the service clients of
+// the pool, and the timeouts, retries and rate limits are applied by the
+// analysis.
+func SettingsDigest(config Config) string {
+	content, _ := json.Marshal(struct {
+		Name           string
+		BinaryPath     string
+		Address        string
+		LanguageServer *tooling.Spec
+		ResourceLimits *ResourceLimits
+	}{
+		Name:           config.Name,
+		BinaryPath:     config.BinaryPath,
+		Address:        config.Address,
+		LanguageServer: config.LanguageServer,
+		ResourceLimits: config.ResourceLimits,
+	})
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// DefaultPoolFile is the pool file of the daemon when none is given, in the
+// cache directory of the user
+func DefaultPoolFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "konveyor", "provider-pool.json")
+}
+
+// ReadPool reads the providers a daemon serves from its pool file
+func ReadPool(path string) ([]PooledProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pooled := []PooledProvider{}
+	if err := json.Unmarshal(content, &pooled); err != nil {
+		return nil, fmt.Errorf("invalid pool file %s: %w", path, err)
+	}
+	return pooled, nil
+}
+
+// WritePool writes the providers a daemon serves to its pool file
+func WritePool(path string, pooled []PooledProvider) error {
+	content, err := json.MarshalIndent(pooled, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// AttachToPool returns the config of the provider that attaches to the
+// daemon serving it with the same settings, it returns false when no daemon
+// serves it
+func AttachToPool(config Config, pooled []PooledProvider) (Config, bool) {
+	digest := SettingsDigest(config)
+	for _, p := range pooled {
+		if p.Name != config.Name || p.SettingsDigest != digest {
+			continue
+		}
+		config.Address = p.Address
+		config.BinaryPath = ""
+		return config, true
+	}
+	return config, false
+}
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
+)
+
+// countingClient counts the service clients it initializes and stops
+type countingClient struct {
+	mutex sync.Mutex
+	inits int
+	stops int
+}
+
+func (c *countingClient) Capabilities() []Capability {
+	return []Capability{{Name: "referenced"}}
+}
+
+func (c *countingClient) Init(ctx context.Context, log logr.Logger, config InitConfig) (ServiceClient, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.inits++
+	return &countingServiceClient{client: c}, nil
+}
+
+func (c *countingClient) counts() (int, int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.inits, c.stops
+}
+
+type countingServiceClient struct {
+	client *countingClient
+}
+
+func (s *countingServiceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	return ProviderEvaluateResponse{Matched: true}, nil
+}
+
+func (s *countingServiceClient) Stop() {
+	s.client.mutex.Lock()
+	defer s.client.mutex.Unlock()
+	s.client.stops++
+}
+
+func (s *countingServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]*Dep, error) {
+	return nil, nil
+}
+
+func (s *countingServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]DepDAGItem, error) {
+	return nil, nil
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	client := &countingClient{}
+	pool := NewPool(client, 50*time.Millisecond, logr.Discard())
+
+	first, err := pool.Init(ctx, logr.Discard(), InitConfig{Location: "/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Stop()
+	first.Stop()
+	// a config read from yaml settings and the same config received by a
+	// provider server
+	second, err := pool.Init(ctx, logr.Discard(), InitConfig{
+		Location:               "/app",
+		AnalysisMode:           FullAnalysisMode,
+		ProviderSpecificConfig: nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inits, stops := client.counts(); inits != 1 || stops != 0 {
+		t.Errorf("expected the service client to be reused, got %d inits and %d stops", inits, stops)
+	}
+	other, err := pool.Init(ctx, logr.Discard(), InitConfig{Location: "/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Stop()
+	other.Stop()
+	time.Sleep(200 * time.Millisecond)
+	if inits, stops := client.counts(); inits != 2 || stops != 2 {
+		t.Errorf("expected the idle service clients to be stopped, got %d inits and %d stops", inits, stops)
+	}
+
+	used, err := pool.Init(ctx, logr.Discard(), InitConfig{Location: "/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	if inits, stops := client.counts(); inits != 3 || stops != 3 {
+		t.Errorf("expected the pool to stop the used service client, got %d inits and %d stops", inits, stops)
+	}
+	used.Stop()
+}
+
+// hitCountingProvider initializes service clients that count cache hits
+type hitCountingProvider struct {
+	countingClient
+}
+
+func (c *hitCountingProvider) Init(ctx context.Context, log logr.Logger, config InitConfig) (ServiceClient, error) {
+	return &hitCountingClient{fakeClient: &fakeClient{}, hits: map[string]int64{"referenced": 3}}, nil
+}
+
+func TestPoolForwardsOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	pool := NewPool(&hitCountingProvider{}, 0, logr.Discard())
+	defer pool.Close()
+
+	client, err := pool.Init(ctx, logr.Discard(), InitConfig{Location: "/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	counter, ok := client.(CacheHitCounter)
+	if !ok {
+		t.Fatalf("expected the leased client to count the cache hits")
+	}
+	if hits := counter.CacheHits(); hits["referenced"] != 3 {
+		t.Errorf("expected the cache hits of the pooled client, got %v", hits)
+	}
+	if err := client.(HealthChecker).HealthCheck(ctx); err != nil {
+		t.Errorf("expected a client that is not checked to be healthy, got %v", err)
+	}
+
+	// a leased client that does not count its hits has none
+	counting, err := NewPool(&countingClient{}, 0, logr.Discard()).Init(ctx, logr.Discard(), InitConfig{Location: "/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer counting.Stop()
+	if hits := counting.(CacheHitCounter).CacheHits(); len(hits) != 0 {
+		t.Errorf("expected no cache hits, got %v", hits)
+	}
+}
+
+func TestInitConfigKey(t *testing.T) {
+	fromSettings := InitConfig{
+		Location: "/app",
+		ProviderSpecificConfig: map[string]interface{}{
+			"bundles": []interface{}{"/jdtls/bundle.jar"},
+			"options": map[interface{}]interface{}{"depth": 2, "mode": "fast"},
+		},
+	}
+	fromServer := InitConfig{
+		Location:     "/app",
+		AnalysisMode: FullAnalysisMode,
+		Proxy:        &Proxy{},
+		ProviderSpecificConfig: map[string]interface{}{
+			"options": map[string]interface{}{"mode": "fast", "depth": float64(2)},
+			"bundles": []interface{}{"/jdtls/bundle.jar"},
+		},
+	}
+	sourceOnly := fromServer
+	sourceOnly.AnalysisMode = SourceOnlyAnalysisMode
+	keys := []string{}
+	for _, c := range []InitConfig{fromSettings, fromServer, sourceOnly} {
+		key, err := InitConfigKey(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	if keys[0] != keys[1] {
+		t.Errorf("expected the same key for the config from the settings and from the server")
+	}
+	if keys[1] == keys[2] {
+		t.Errorf("expected another key for another analysis mode")
+	}
+}
+
+func TestAttachToPool(t *testing.T) {
+	config := Config{Name: "java", BinaryPath: "/jdtls/bin/jdtls"}
+	path := filepath.Join(t.TempDir(), "pool", "providers.json")
+	err := WritePool(path, []PooledProvider{
+		{Name: "go", Address: "localhost:4000", SettingsDigest: SettingsDigest(Config{Name: "go"})},
+		{Name: "java", Address: "localhost:4001", SettingsDigest: SettingsDigest(config)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pooled, err := ReadPool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attached, ok := AttachToPool(config, pooled)
+	if !ok || attached.Address != "localhost:4001" || attached.BinaryPath != "" {
+		t.Errorf("expected to attach to the pooled java provider, got %#v", attached)
+	}
+	config.ResourceLimits = &ResourceLimits{Memory: "2Gi"}
+	if _, ok := AttachToPool(config, pooled); ok {
+		t.Errorf("expected a provider with other settings not to attach")
+	}
+}
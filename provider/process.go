@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var memoryRegex = regexp.MustCompile(`^([0-9]+)\s*([KMGT]i?)?B?$`)
+
+// ResourceLimits constrain the processes a provider spawns, such as the grpc
+// provider binary or the java language server, so that a misbehaving one can
+// not take down the host.
+type ResourceLimits struct {
+	// Memory is the maximum memory of a process, such as 512Mi or 2G. Without a
+	// cgroup, it limits the address space of the process, which can be much
+	// larger than its resident memory, notably for a JVM.
+	Memory string `yaml:"memory,omitempty" json:"memory,omitempty"`
+	// CPUTime is the maximum CPU time a process can use, such as 30m
+	CPUTime string `yaml:"cpuTime,omitempty" json:"cpuTime,omitempty"`
+	// CPUs is the number of CPUs a process can use at once, such as 1.5. It
+	// requires a cgroup.
+	CPUs string `yaml:"cpus,omitempty" json:"cpus,omitempty"`
+	// WallTime is how long a process can run before it is killed, such as 2h
+	WallTime string `yaml:"wallTime,omitempty" json:"wallTime,omitempty"`
+	// Cgroup is a cgroup v2 directory the analyzer can write to. A cgroup is
+	// created in it for each process to limit its memory and CPUs.
+	Cgroup string `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
+}
+
+// Validate checks that the limits can be parsed
+func (l *ResourceLimits) Validate() error {
+	if _, err := l.memory(); err != nil {
+		return err
+	}
+	if _, err := parseDuration("cpu time", l.CPUTime); err != nil {
+		return err
+	}
+	if _, err := parseDuration("wall time", l.WallTime); err != nil {
+		return err
+	}
+	if l.CPUs != "" {
+		cpus, err := strconv.ParseFloat(l.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus %q", l.CPUs)
+		}
+		if l.Cgroup == "" {
+			return fmt.Errorf("a cgroup is required to limit the cpus")
+		}
+	}
+	return nil
+}
+
+// memory returns the memory limit in bytes, 0 when there is none
+func (l *ResourceLimits) memory() (uint64, error) {
+	if l.Memory == "" {
+		return 0, nil
+	}
+	match := memoryRegex.FindStringSubmatch(strings.TrimSpace(l.Memory))
+	if match == nil {
+		return 0, fmt.Errorf("invalid memory %q", l.Memory)
+	}
+	value, err := strconv.ParseUint(match[1], 10, 64)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("invalid memory %q", l.Memory)
+	}
+	base := uint64(1000)
+	if strings.HasSuffix(match[2], "i") {
+		base = 1024
+	}
+	if match[2] != "" {
+		for i := 0; i <= strings.Index("KMGT", match[2][:1]); i++ {
+			value *= base
+		}
+	}
+	return value, nil
+}
+
+func parseDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return d, nil
+}
+
+// ResourceLimitError is the error of a process that was stopped because it
+// exceeded one of its limits.
+type ResourceLimitError struct {
+	Process string
+	Limit   string
+	Value   string
+}
+
+func (e *ResourceLimitError) Error() string {
+	return fmt.Sprintf("%s exceeded its %s limit of %s", e.Process, e.Limit, e.Value)
+}
+
+// IsResourceLimitError returns whether a process was stopped because of its
+// limits.
+func IsResourceLimitError(err error) bool {
+	var limitErr *ResourceLimitError
+	return errors.As(err, &limitErr)
+}
+
+// Process is a process started with resource limits, it is waited for in the
+// background so that its failure can be reported without waiting for a
+// request to time out.
+type Process struct {
+	cmd    *exec.Cmd
+	name   string
+	limits ResourceLimits
+
+	done chan struct{}
+	// cgroup is the cgroup created for the process, if any
+	cgroup string
+
+	mutex       sync.Mutex
+	err         error
+	wallTimeout bool
+}
+
+// StartProcess starts the command with the limits, which may be nil. The
+// command must not be waited for, use the Wait method of the process instead.
+func StartProcess(name string, cmd *exec.Cmd, limits *ResourceLimits) (*Process, error) {
+	p := &Process{
+		cmd:  cmd,
+		name: name,
+		done: make(chan struct{}),
+	}
+	if limits != nil {
+		p.limits = *limits
+	}
+	if err := p.limits.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	if err := p.applyLimits(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		p.removeCgroup()
+		return nil, fmt.Errorf("unable to limit the resources of %s: %w", name, err)
+	}
+	wallTime, _ := parseDuration("wall time", p.limits.WallTime)
+	var timer *time.Timer
+	if wallTime > 0 {
+		timer = time.AfterFunc(wallTime, func() {
+			p.mutex.Lock()
+			p.wallTimeout = true
+			p.mutex.Unlock()
+			cmd.Process.Kill()
+		})
+	}
+	go func() {
+		err := cmd.Wait()
+		if timer != nil {
+			timer.Stop()
+		}
+		p.mutex.Lock()
+		p.err = p.exitError(err)
+		p.mutex.Unlock()
+		p.removeCgroup()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// exitError explains why the process stopped
+func (p *Process) exitError(err error) error {
+	switch {
+	case p.wallTimeout:
+		return &ResourceLimitError{Process: p.name, Limit: "wall time", Value: p.limits.WallTime}
+	case p.memoryExceeded(err):
+		return &ResourceLimitError{Process: p.name, Limit: "memory", Value: p.limits.Memory}
+	case p.cpuTimeExceeded(err):
+		return &ResourceLimitError{Process: p.name, Limit: "cpu time", Value: p.limits.CPUTime}
+	case err != nil:
+		return fmt.Errorf("%s stopped: %w", p.name, err)
+	}
+	return fmt.Errorf("%s stopped", p.name)
+}
+
+// cpuTimeExceeded is whether the process used all of its cpu time, it is
+// killed by the kernel when it does. The usage the process is killed at can be
+// reported a little under the limit, the signal it got tells it then.
+func (p *Process) cpuTimeExceeded(err error) bool {
+	cpuTime, _ := parseDuration("cpu time", p.limits.CPUTime)
+	state := p.cmd.ProcessState
+	if err == nil || cpuTime == 0 || state == nil {
+		return false
+	}
+	return state.UserTime()+state.SystemTime() >= cpuTime || cpuLimitSignaled(state)
+}
+
+// Exited returns why the process stopped, or nil while it is running or when
+// there is no process.
+func (p *Process) Exited() error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.done:
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		return p.err
+	default:
+		return nil
+	}
+}
+
+// Wait waits for the process to stop and returns why it did
+func (p *Process) Wait() error {
+	if p == nil {
+		return nil
+	}
+	<-p.done
+	return p.Exited()
+}
+
+// Suspend stops the process from running until Continue is called, along with
+// its children when it has a cgroup. A process that is not running is left
+// as it is.
+func (p *Process) Suspend() error {
+	if p == nil || p.Exited() != nil {
+		return nil
+	}
+	return p.freeze(true)
+}
+
+// Continue runs the process again after Suspend
+func (p *Process) Continue() error {
+	if p == nil || p.Exited() != nil {
+		return nil
+	}
+	return p.freeze(false)
+}
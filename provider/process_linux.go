@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuPeriod is the period of the cpu.max of the cgroups, in microseconds
+const cpuPeriod = 100000
+
+var cgroupNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// applyLimits limits the memory and cpus with a cgroup when one is given,
+// otherwise the memory is limited with an rlimit. The cpu time is always
+// limited with an rlimit.
+func (p *Process) applyLimits() error {
+	pid := p.cmd.Process.Pid
+	memory, _ := p.limits.memory()
+	cpuTime, _ := parseDuration("cpu time", p.limits.CPUTime)
+
+	if p.limits.Cgroup != "" {
+		dir := filepath.Join(p.limits.Cgroup, fmt.Sprintf("konveyor-%s-%d", cgroupNameRegex.ReplaceAllString(p.name, "-"), pid))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return err
+		}
+		p.cgroup = dir
+		if memory > 0 {
+			if err := writeCgroupFile(dir, "memory.max", strconv.FormatUint(memory, 10)); err != nil {
+				return err
+			}
+		}
+		if p.limits.CPUs != "" {
+			cpus, _ := strconv.ParseFloat(p.limits.CPUs, 64)
+			quota := int(math.Ceil(cpus * cpuPeriod))
+			if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+				return err
+			}
+		}
+		if err := writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+			return err
+		}
+	} else if memory > 0 {
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, &unix.Rlimit{Cur: memory, Max: memory}, nil); err != nil {
+			return err
+		}
+	}
+
+	if cpuTime > 0 {
+		// the process gets SIGXCPU at the soft limit and is killed at the hard one
+		seconds := uint64(math.Ceil(cpuTime.Seconds()))
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: seconds, Max: seconds + 1}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCgroupFile(dir string, name string, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+}
+
+// memoryExceeded is whether the process was killed by the out of memory
+// killer of its cgroup. Without a cgroup, the allocations of the process fail
+// and it exits on its own, which can not be told apart from other failures.
+func (p *Process) memoryExceeded(err error) bool {
+	if err == nil || p.cgroup == "" || p.limits.Memory == "" {
+		return false
+	}
+	f, openErr := os.Open(filepath.Join(p.cgroup, "memory.events"))
+	if openErr != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, _ := strconv.Atoi(fields[1])
+			return count > 0
+		}
+	}
+	return false
+}
+
+// cpuLimitSignaled is whether the process was stopped by the signal the kernel
+// sends at the soft cpu time limit
+func cpuLimitSignaled(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGXCPU
+}
+
+func (p *Process) removeCgroup() {
+	if p.cgroup != "" {
+		// this fails while the children of the process are still running
+		os.Remove(p.cgroup)
+	}
+}
+
+// freeze freezes the cgroup of the process when it has one, otherwise the
+// process is stopped and continued with signals
+func (p *Process) freeze(frozen bool) error {
+	if p.cgroup != "" {
+		value := "0"
+		if frozen {
+			value = "1"
+		}
+		return writeCgroupFile(p.cgroup, "cgroup.freeze", value)
+	}
+	signal := syscall.SIGCONT
+	if frozen {
+		signal = syscall.SIGSTOP
+	}
+	return p.cmd.Process.Signal(signal)
+}
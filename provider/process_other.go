@@ -0,0 +1,30 @@
+//go:build !linux
+
+package provider
+
+import (
+	"fmt"
+	"os"
+)
+
+// applyLimits only supports the wall time, which does not need the kernel
+func (p *Process) applyLimits() error {
+	if p.limits.Memory != "" || p.limits.CPUTime != "" || p.limits.CPUs != "" || p.limits.Cgroup != "" {
+		return fmt.Errorf("only the wall time can be limited on this platform")
+	}
+	return nil
+}
+
+func (p *Process) memoryExceeded(err error) bool {
+	return false
+}
+
+func cpuLimitSignaled(state *os.ProcessState) bool {
+	return false
+}
+
+func (p *Process) removeCgroup() {}
+
+func (p *Process) freeze(frozen bool) error {
+	return fmt.Errorf("processes can not be suspended on this platform")
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  ResourceLimits
+		memory  uint64
+		wantErr bool
+	}{
+		{
+			name: "no limits",
+		},
+		{
+			name:   "binary memory",
+			limits: ResourceLimits{Memory: "512Mi", CPUTime: "10m", WallTime: "1h"},
+			memory: 512 * 1024 * 1024,
+		},
+		{
+			name:   "decimal memory",
+			limits: ResourceLimits{Memory: "2GB"},
+			memory: 2000000000,
+		},
+		{
+			name:   "bytes",
+			limits: ResourceLimits{Memory: "4096"},
+			memory: 4096,
+		},
+		{
+			name:   "cpus with a cgroup",
+			limits: ResourceLimits{CPUs: "1.5", Cgroup: "/sys/fs/cgroup/analyzer"},
+		},
+		{
+			name:    "cpus without a cgroup",
+			limits:  ResourceLimits{CPUs: "2"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid memory",
+			limits:  ResourceLimits{Memory: "lots"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			limits:  ResourceLimits{WallTime: "forever"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if memory, _ := tt.limits.memory(); memory != tt.memory {
+				t.Errorf("expected memory %d, got %d", tt.memory, memory)
+			}
+		})
+	}
+}
+
+func TestStartProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the processes are limited on linux")
+	}
+	tests := []struct {
+		name      string
+		command   []string
+		limits    *ResourceLimits
+		wantLimit bool
+		wantErr   string
+	}{
+		{
+			name:    "process without limits",
+			command: []string{"sh", "-c", "exit 2"},
+			wantErr: "provider test stopped: exit status 2",
+		},
+		{
+			name:      "wall time",
+			command:   []string{"sleep", "10"},
+			limits:    &ResourceLimits{WallTime: "100ms"},
+			wantLimit: true,
+			wantErr:   "provider test exceeded its wall time limit of 100ms",
+		},
+		{
+			name:      "cpu time",
+			command:   []string{"sh", "-c", "while :; do :; done"},
+			limits:    &ResourceLimits{CPUTime: "1s", WallTime: "10s"},
+			wantLimit: true,
+			wantErr:   "provider test exceeded its cpu time limit of 1s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := StartProcess("provider test", exec.Command(tt.command[0], tt.command[1:]...), tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Exited(); err != nil && tt.wantLimit {
+				t.Errorf("expected the process to be running, got %v", err)
+			}
+			done := make(chan error)
+			go func() { done <- p.Wait() }()
+			select {
+			case err = <-done:
+			case <-time.After(20 * time.Second):
+				t.Fatal("the process was not stopped")
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+			if IsResourceLimitError(err) != tt.wantLimit {
+				t.Errorf("expected the limit error to be %v, got %v", tt.wantLimit, err)
+			}
+		})
+	}
+}
+
+func TestSuspendProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the processes are suspended on linux")
+	}
+	p, err := StartProcess("provider test", exec.Command("sleep", "10"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.cmd.Process.Kill()
+	state := func() string {
+		// the state is the field after the name of the command
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", p.cmd.Process.Pid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))[0]
+	}
+	waitState := func(want string) {
+		for i := 0; i < 50 && state() != want; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := state(); got != want {
+			t.Errorf("expected the process to be in state %s, got %s", want, got)
+		}
+	}
+	if err := p.Suspend(); err != nil {
+		t.Fatal(err)
+	}
+	waitState("T")
+	if err := p.Continue(); err != nil {
+		t.Fatal(err)
+	}
+	waitState("S")
+}
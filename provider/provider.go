@@ -2,8 +2,10 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -14,6 +16,8 @@ import (
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+	"github.com/konveyor/analyzer-lsp/redact"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"go.lsp.dev/uri"
 	"go.opentelemetry.io/otel/attribute"
@@ -50,6 +54,17 @@ func init() {
 	}
 }
 
+// BuiltinLocation returns the location of the builtin provider, the directory
+// the relative filepaths of the conditions are searched in
+func BuiltinLocation(configs []Config) string {
+	for _, c := range configs {
+		if c.Name == builtinConfig.Name && len(c.InitConfig) > 0 {
+			return c.InitConfig[0].Location
+		}
+	}
+	return ""
+}
+
 type UnimplementedDependenciesComponent struct{}
 
 // We don't have dependencies
@@ -65,15 +80,47 @@ func (p *UnimplementedDependenciesComponent) GetDependenciesDAG(ctx context.Cont
 type Capability struct {
 	Name            string
 	TemplateContext openapi3.SchemaRef
+	// IncidentVariables is the schema of the variables of the incidents of
+	// the capability, see NewIncidentVariablesSchema
+	IncidentVariables openapi3.SchemaRef
+	// DefaultConfidence is the confidence of the incidents of the capability
+	// that the provider does not set one for, such as SymbolSearchConfidence
+	// for a capability that looks up symbols by name. The provider server sets
+	// it on the incidents of the external providers, the incidents of the
+	// in-tree providers have the confidence they set.
+	DefaultConfidence *float64
 }
 
 type Config struct {
-	Name         string       `yaml:"name,omitempty" json:"name,omitempty"`
-	BinaryPath   string       `yaml:"binaryPath,omitempty" json:"binaryPath,omitempty"`
-	Address      string       `yaml:"address,omitempty" json:"address,omitempty"`
-	Proxy        *Proxy       `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty"`
-	InitConfig   []InitConfig `yaml:"initConfig,omitempty" json:"initConfig,omitempty"`
+	Name       string       `yaml:"name,omitempty" json:"name,omitempty"`
+	BinaryPath string       `yaml:"binaryPath,omitempty" json:"binaryPath,omitempty"`
+	Address    string       `yaml:"address,omitempty" json:"address,omitempty"`
+	Proxy      *Proxy       `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty"`
+	InitConfig []InitConfig `yaml:"initConfig,omitempty" json:"initConfig,omitempty"`
+	// Labels can be used to select providers using a label selector
+	Labels       []string `yaml:"labels,omitempty" json:"labels,omitempty"`
 	ContextLines int
+	// ResourceLimits constrain the processes the provider spawns
+	ResourceLimits *ResourceLimits `yaml:"resourceLimits,omitempty" json:"resourceLimits,omitempty"`
+	// LanguageServer pins a language server that is installed for the
+	// provider and used as the lspServerPath of its init configs
+	LanguageServer *tooling.Spec `yaml:"languageServer,omitempty" json:"languageServer,omitempty"`
+	// CallTimeouts bound how long each condition query sent to the provider
+	// can take
+	CallTimeouts *CallTimeouts `yaml:"callTimeouts,omitempty" json:"callTimeouts,omitempty"`
+	// RateLimit limits the queries sent to the provider
+	RateLimit *RateLimit `yaml:"rateLimit,omitempty" json:"rateLimit,omitempty"`
+	// Retry sends the queries that fail with a transient error of the
+	// provider again
+	Retry *RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// DependsOn are the providers that are initialized before this one, such
+	// as the java provider for a provider that uses the local repository it
+	// resolves the dependencies to
+	DependsOn []string `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`
+}
+
+func (c *Config) GetLabels() []string {
+	return c.Labels
 }
 
 type Proxy httpproxy.Config
@@ -107,14 +154,14 @@ type InitConfig struct {
 	Location string `yaml:"location,omitempty" json:"location,omitempty"`
 
 	// This is the path to look for the dependencies for the project.
-	// It is relative to the Location
+	// It is relative to the Location, and to each of the WorkspaceFolders
 	// TODO: This only allows for one directory for dependencies. Use DependencyFolders instead
 	DependencyPath string `yaml:"dependencyPath,omitempty" json:"dependencyPath,omitempty"`
 
-	// It would be nice to get workspacefolders working
-
-	// // The folders for the workspace. Maps to workspaceFolders in the LSP spec
-	// WorkspaceFolders []string `yaml:"workspaceFolders,omitempty" json:"workspaceFolders,omitempty"`
+	// The folders for the workspace. Maps to workspaceFolders in the LSP spec
+	// along with the Location, so that a single provider instance analyzes
+	// several independent modules, such as the ones of a go workspace.
+	WorkspaceFolders []string `yaml:"workspaceFolders,omitempty" json:"workspaceFolders,omitempty"`
 
 	// // The folders for the dependencies. Also maps to workspaceFolders in the LSP
 	// // spec. These folders will not be inlcuded in search results for things like
@@ -129,6 +176,93 @@ type InitConfig struct {
 	Proxy *Proxy `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty"`
 }
 
+// Roots returns the roots of the code base, the location followed by the
+// workspace folders, without duplicates.
+func (c InitConfig) Roots() []string {
+	roots := []string{}
+	seen := map[string]bool{}
+	for _, root := range append([]string{c.Location}, c.WorkspaceFolders...) {
+		if root == "" {
+			continue
+		}
+		root = filepath.Clean(root)
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// WalkRoots returns the roots that are not in another root, so that walking
+// them visits each file once.
+func (c InitConfig) WalkRoots() []string {
+	roots := c.Roots()
+	walkRoots := []string{}
+	for _, root := range roots {
+		nested := false
+		for _, other := range roots {
+			if other != root && inRoot(other, root) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			walkRoots = append(walkRoots, root)
+		}
+	}
+	return walkRoots
+}
+
+// Contains returns whether the file is in one of the roots
+func (c InitConfig) Contains(path string) bool {
+	for _, root := range c.Roots() {
+		if inRoot(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RootOf returns the root the file is in, the innermost one when the roots
+// are nested. It is the location for the files in none of the roots, such as
+// the ones of the dependencies.
+func (c InitConfig) RootOf(path string) string {
+	found := c.Location
+	length := -1
+	for _, root := range c.Roots() {
+		if inRoot(root, path) && len(root) > length {
+			found = root
+			length = len(root)
+		}
+	}
+	return found
+}
+
+// RootOfURI returns the root of the file of the URI, it is the location for
+// the URIs that are not files.
+func (c InitConfig) RootOfURI(u uri.URI) string {
+	if !strings.HasPrefix(string(u), uri.FileScheme) {
+		return c.Location
+	}
+	return c.RootOf(u.Filename())
+}
+
+// inRoot returns whether the path is the root or a file in it
+func inRoot(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func GetConfig(filepath string) ([]Config, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
@@ -141,6 +275,29 @@ func GetConfig(filepath string) ([]Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the init configs are validated as they are written, the fields that do
+	// not exist are dropped once they are decoded
+	rawConfigs := []struct {
+		Name       string        `yaml:"name"`
+		InitConfig []interface{} `yaml:"initConfig"`
+	}{}
+	if err := yaml.Unmarshal(content, &rawConfigs); err != nil {
+		return nil, err
+	}
+	for _, c := range rawConfigs {
+		for i, ic := range c.InitConfig {
+			if err := ValidateInitConfig(c.Name, ic); err != nil {
+				return nil, fmt.Errorf("invalid init config %d of provider %s: %w", i, c.Name, err)
+			}
+		}
+	}
+	return PrepareConfigs(configs)
+}
+
+// PrepareConfigs sets the default proxies, adds the builtin provider when it
+// is missing and validates the configs, for the configs that are not read
+// with GetConfig.
+func PrepareConfigs(configs []Config) ([]Config, error) {
 	foundBuiltin := false
 	for idx := range configs {
 		c := &configs[idx]
@@ -158,6 +315,10 @@ func GetConfig(filepath string) ([]Config, error) {
 			if ic.Proxy == nil {
 				ic.Proxy = c.Proxy
 			}
+			// the first workspace folder is the location when there is none
+			if ic.Location == "" && len(ic.WorkspaceFolders) > 0 {
+				ic.Location = ic.WorkspaceFolders[0]
+			}
 		}
 	}
 	if !foundBuiltin {
@@ -168,11 +329,91 @@ func GetConfig(filepath string) ([]Config, error) {
 	if err := validateProviderName(configs); err != nil {
 		return nil, err
 	}
+	if err := validateDependsOn(configs); err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		if c.ResourceLimits == nil {
+			continue
+		}
+		if err := c.ResourceLimits.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid resource limits of provider %s: %w", c.Name, err)
+		}
+	}
+	for _, c := range configs {
+		for i, ic := range c.InitConfig {
+			if err := ValidateInitConfig(c.Name, ic); err != nil {
+				return nil, fmt.Errorf("invalid init config %d of provider %s: %w", i, c.Name, err)
+			}
+			// the secrets are replaced wherever they are logged
+			redact.Register(SensitiveValues(c.Name, ic)...)
+		}
+	}
+	for _, c := range configs {
+		if c.CallTimeouts == nil {
+			continue
+		}
+		if err := c.CallTimeouts.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid call timeouts of provider %s: %w", c.Name, err)
+		}
+	}
+	for _, c := range configs {
+		if c.RateLimit == nil {
+			continue
+		}
+		if err := c.RateLimit.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rate limit of provider %s: %w", c.Name, err)
+		}
+	}
+	for _, c := range configs {
+		if c.Retry == nil {
+			continue
+		}
+		if err := c.Retry.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid retry policy of provider %s: %w", c.Name, err)
+		}
+	}
+	for _, c := range configs {
+		if c.LanguageServer == nil {
+			continue
+		}
+		if err := c.LanguageServer.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid language server of provider %s: %w", c.Name, err)
+		}
+		for _, ic := range c.InitConfig {
+			if path, ok := ic.ProviderSpecificConfig[LspServerPathConfigKey].(string); ok && path != "" {
+				return nil, fmt.Errorf("provider %s has both a language server and an %s", c.Name, LspServerPathConfigKey)
+			}
+		}
+	}
 
 	return configs, nil
 
 }
 
+// InstallLanguageServers installs the language servers the configs pin, when
+// they are not installed yet, and sets them as the lspServerPath of the init
+// configs.
+func InstallLanguageServers(ctx context.Context, configs []Config, manager *tooling.Manager) error {
+	for _, c := range configs {
+		if c.LanguageServer == nil {
+			continue
+		}
+		path, err := manager.Install(ctx, *c.LanguageServer)
+		if err != nil {
+			return fmt.Errorf("unable to install the language server of provider %s: %w", c.Name, err)
+		}
+		for jdx := range c.InitConfig {
+			ic := &c.InitConfig[jdx]
+			if ic.ProviderSpecificConfig == nil {
+				ic.ProviderSpecificConfig = map[string]interface{}{}
+			}
+			ic.ProviderSpecificConfig[LspServerPathConfigKey] = path
+		}
+	}
+	return nil
+}
+
 func validateProviderName(configs []Config) error {
 	providerNames := make(map[string]bool)
 	for _, config := range configs {
@@ -204,8 +445,28 @@ type IncidentContext struct {
 	Links                []ExternalLinks        `yaml:"externalLink,omitempty"`
 	CodeLocation         *Location              `yaml:"location,omitempty"`
 	IsDependencyIncident bool
+	// Confidence is set, between 0 and 1, when the incident was found by a
+	// heuristic rather than by a precise search, see TextSearchConfidence.
+	Confidence *float64 `yaml:"confidence,omitempty"`
 }
 
+const (
+	// TextSearchConfidence is the confidence of the incidents found by
+	// searching the text of the files for a symbol, such as when the
+	// language server can not find it, they can be in comments or refer to
+	// another symbol with the same name.
+	TextSearchConfidence = 0.5
+	// UnresolvedConfidence is the confidence of the incidents whose symbol
+	// matches the query but that the language server could not resolve, such
+	// as a call of which the overload is unknown.
+	UnresolvedConfidence = 0.7
+	// SymbolSearchConfidence is the confidence of the incidents whose symbol
+	// was looked up by name rather than resolved with its types, such as with
+	// the workspace/symbol request of a language server, the name can be the
+	// one of another symbol.
+	SymbolSearchConfidence = 0.8
+)
+
 type Location struct {
 	StartPosition Position
 	EndPosition   Position
@@ -239,6 +500,8 @@ type ExternalLinks struct {
 type ProviderContext struct {
 	Tags     map[string]interface{}          `yaml:"tags"`
 	Template map[string]engine.ChainTemplate `yaml:"template"`
+	// Scope limits the files the provider should search
+	Scope *engine.Scope `yaml:"scope,omitempty"`
 }
 
 func HasCapability(caps []Capability, name string) bool {
@@ -342,6 +605,14 @@ type Startable interface {
 	Start(context.Context) error
 }
 
+// FileExaminer is implemented by the providers that only examine some kinds of
+// files, such as the source files of a language, so that the coverage of an
+// analysis tells which provider examined which file. The providers that do
+// not implement it are considered to examine all the text files.
+type FileExaminer interface {
+	Examines(path string) bool
+}
+
 type CodeSnipProvider struct {
 	Providers []engine.CodeSnip
 }
@@ -359,6 +630,8 @@ func (p CodeSnipProvider) GetCodeSnip(u uri.URI, l engine.Location) (string, err
 }
 
 type ProviderCondition struct {
+	// ProviderName is the name of the provider of the client
+	ProviderName     string
 	Client           ServiceClient
 	Capability       string
 	ConditionInfo    interface{}
@@ -371,6 +644,45 @@ func (p ProviderCondition) Ignorable() bool {
 	return p.Ignore
 }
 
+var _ engine.ProviderQuery = ProviderCondition{}
+
+func (p ProviderCondition) ProviderCapability() (string, string) {
+	return p.ProviderName, p.Capability
+}
+
+var _ engine.Scoped = ProviderCondition{}
+
+// Scope returns the filepaths the condition was asked to search, if the
+// capability takes them as an input.
+func (p ProviderCondition) Scope(condCtx engine.ConditionContext) []uri.URI {
+	info, ok := p.ConditionInfo.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	filepaths := []string{}
+	switch v := info["filepaths"].(type) {
+	case string:
+		filepaths = append(filepaths, strings.Split(v, " ")...)
+	case []interface{}:
+		for _, f := range v {
+			if s, ok := f.(string); ok {
+				filepaths = append(filepaths, s)
+			}
+		}
+	}
+	scope := []uri.URI{}
+	for _, f := range filepaths {
+		// templated filepaths are only known to the provider
+		if f == "" || strings.Contains(f, "{{") {
+			continue
+		}
+		if u, ok := condCtx.FileURI(f); ok {
+			scope = append(scope, u)
+		}
+	}
+	return scope
+}
+
 func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
 	ctx, span := tracing.StartNewSpan(
 		ctx, "provider-condition", attribute.Key("cap").String(p.Capability))
@@ -383,6 +695,7 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 		ProviderContext: ProviderContext{
 			Tags:     condCtx.Tags,
 			Template: condCtx.Template,
+			Scope:    condCtx.Scope,
 		},
 		Capability: map[string]interface{}{
 			p.Capability: p.ConditionInfo,
@@ -400,6 +713,7 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 		panic(err)
 	}
 	span.SetAttributes(attribute.Key("condition").String(string(templatedInfo)))
+	engine.TraceQuery(ctx, p.Capability, string(templatedInfo))
 	resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
 	if err != nil {
 		// If an error always just return the empty
@@ -430,6 +744,7 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 			LineNumber: inc.LineNumber,
 			Variables:  inc.Variables,
 			Links:      p.Rule.Perform.Message.Links,
+			Confidence: inc.Confidence,
 		}
 
 		if inc.CodeLocation != nil {
@@ -507,29 +822,135 @@ func templateCondition(condition []byte, ctx map[string]engine.ChainTemplate) ([
 	s := strings.ReplaceAll(string(condition), `'{{`, "{{")
 	s = strings.ReplaceAll(s, `}}'`, "}}")
 
-	s, err := mustache.Render(s, true, ctx)
+	// the values are YAML, not HTML
+	s, err := mustache.RenderRaw(s, true, templateContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	return []byte(s), nil
 }
 
+// templateContext returns the values the conditions chained with `from` can
+// use in their query, for each condition with `as`:
+//
+//	{{poms.filepaths}}              the files of the incidents
+//	{{beans.variables.class}}       the values of a variable of the incidents
+//	{{beans.regex.class}}           the values as a regex alternation
+//	{{files.extras.<key>}}          the template context of the provider
+//
+// The lists are rendered as YAML flow sequences so that a list templated as
+// the whole value of a field, such as '{{poms.filepaths}}', is a list.
+func templateContext(templates map[string]engine.ChainTemplate) map[string]interface{} {
+	ctx := map[string]interface{}{}
+	for name, t := range templates {
+		variables := map[string]interface{}{}
+		regex := map[string]interface{}{}
+		for k, values := range t.Variables {
+			variables[k] = templateList(values)
+			alternatives := []string{}
+			for _, v := range values {
+				alternatives = append(alternatives, regexp.QuoteMeta(fmt.Sprint(v)))
+			}
+			regex[k] = strings.Join(alternatives, "|")
+		}
+		filepaths := templateList{}
+		for _, f := range t.Filepaths {
+			filepaths = append(filepaths, f)
+		}
+		ctx[name] = map[string]interface{}{
+			"filepaths": filepaths,
+			"extras":    templateValue(t.Extras),
+			"variables": variables,
+			"regex":     regex,
+		}
+	}
+	return ctx
+}
+
+// templateValue converts the lists in the value to templateLists
+func templateValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[key] = templateValue(item)
+		}
+		return m
+	case []interface{}:
+		l := templateList{}
+		for _, item := range v {
+			l = append(l, templateValue(item))
+		}
+		return l
+	case []string:
+		l := templateList{}
+		for _, item := range v {
+			l = append(l, item)
+		}
+		return l
+	}
+	return value
+}
+
+// templateList is a list that is rendered as a YAML flow sequence, it can
+// still be iterated in a section
+type templateList []interface{}
+
+func (l templateList) String() string {
+	// JSON is valid YAML, and quotes the values that YAML would not read as
+	// strings
+	content, err := json.Marshal([]interface{}(l))
+	if err != nil {
+		return fmt.Sprint([]interface{}(l))
+	}
+	return string(content)
+}
+
 // TODO where should this go
 type DependencyCondition struct {
 	Upperbound string
 	Lowerbound string
-	Name       string
+	// Versions is either a maven version range such as [1.0,2.0) or semver
+	// constraints such as ">= 1.0, < 2.0", it is used instead of the bounds.
+	Versions string
+	Name     string
 	// NameRegex will be a valid go regex that will be used to
 	// search the name of a given dependency.
 	// Examples include kubernetes* or jakarta-.*-2.2.
 	NameRegex string
 
-	Client Client
+	// ProviderName is the name of the provider of the client
+	ProviderName string
+	Client       Client
+}
+
+var _ engine.ProviderQuery = DependencyCondition{}
+
+func (dc DependencyCondition) ProviderCapability() (string, string) {
+	return dc.ProviderName, "dependency"
+}
+
+// query describes the condition in the traces
+func (dc DependencyCondition) query() string {
+	fields := []string{}
+	for _, f := range []struct{ name, value string }{
+		{"name", dc.Name},
+		{"nameRegex", dc.NameRegex},
+		{"lowerbound", dc.Lowerbound},
+		{"upperbound", dc.Upperbound},
+		{"versions", dc.Versions},
+	} {
+		if f.value != "" {
+			fields = append(fields, fmt.Sprintf("%s: %s", f.name, f.value))
+		}
+	}
+	return strings.Join(fields, ", ")
 }
 
 func (dc DependencyCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
 	_, span := tracing.StartNewSpan(ctx, "dep-condition")
 	defer span.End()
+	engine.TraceQuery(ctx, "dependency", dc.query())
 
 	resp := engine.ConditionResponse{}
 	deps, err := dc.Client.GetDependencies(ctx)
@@ -562,7 +983,7 @@ func (dc DependencyCondition) Evaluate(ctx context.Context, log logr.Logger, con
 	}
 
 	for _, matchedDep := range matchedDeps {
-		if matchedDep.dep.Version == "" || (dc.Lowerbound == "" && dc.Upperbound == "") {
+		if matchedDep.dep.Version == "" || (dc.Lowerbound == "" && dc.Upperbound == "" && dc.Versions == "") {
 			resp.Matched = true
 			resp.Incidents = append(resp.Incidents, engine.IncidentContext{
 				FileURI: matchedDep.uri,
@@ -585,33 +1006,15 @@ func (dc DependencyCondition) Evaluate(ctx context.Context, log logr.Logger, con
 			return resp, err
 		}
 
-		constraintPieces := []string{}
-		if dc.Lowerbound != "" {
-			var v string
-			lb, err := getVersion(dc.Lowerbound)
-			if err != nil {
-				v = dc.Lowerbound
-			} else {
-				v = lb.Original()
-			}
-			constraintPieces = append(constraintPieces, ">= "+v)
-		}
-		if dc.Upperbound != "" {
-			var v string
-			ub, err := getVersion(dc.Upperbound)
-			if err != nil {
-				v = dc.Upperbound
-			} else {
-				v = ub.Original()
-			}
-			constraintPieces = append(constraintPieces, "<= "+v)
-		}
-		constraints, err := version.NewConstraint(strings.Join(constraintPieces, ", "))
+		versions, err := dc.versionRange()
 		if err != nil {
 			return resp, err
 		}
 
-		resp.Matched = constraints.Check(depVersion)
+		if !versions.Check(depVersion) {
+			continue
+		}
+		resp.Matched = true
 		resp.Incidents = append(resp.Incidents, engine.IncidentContext{
 			FileURI: matchedDep.uri,
 			Variables: map[string]interface{}{
@@ -628,6 +1031,35 @@ func (dc DependencyCondition) Evaluate(ctx context.Context, log logr.Logger, con
 	return resp, nil
 }
 
+// Validate checks that the versions of the condition can be parsed
+func (dc DependencyCondition) Validate() error {
+	if dc.Versions == "" {
+		return nil
+	}
+	_, err := parseVersionRange(dc.Versions)
+	return err
+}
+
+// versionRange returns the range of the versions of the condition, from either
+// the versions or the bounds.
+func (dc DependencyCondition) versionRange() (versionRange, error) {
+	if dc.Versions != "" {
+		return parseVersionRange(dc.Versions)
+	}
+	constraintPieces := []string{}
+	if dc.Lowerbound != "" {
+		constraintPieces = append(constraintPieces, ">= "+dc.Lowerbound)
+	}
+	if dc.Upperbound != "" {
+		constraintPieces = append(constraintPieces, "<= "+dc.Upperbound)
+	}
+	constraints, err := newConstraints(constraintPieces)
+	if err != nil {
+		return nil, err
+	}
+	return versionRange{constraints}, nil
+}
+
 // TODO(fabianvf): We need to strip out the go-version library for a more lenient
 // one, since it breaks on the `.RELEASE` and `.Final` suffixes which are common in Java.
 // This function will extract only a numeric version pattern and strip out those suffixes.
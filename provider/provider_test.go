@@ -2,13 +2,23 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	libgrpc "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
 	"go.lsp.dev/uri"
 )
 
@@ -45,6 +55,7 @@ func Test_dependencyConditionEvaluation(t *testing.T) {
 		name         string
 		upperbound   string
 		lowerbound   string
+		versions     string
 		dependencies []*Dep
 		shouldMatch  bool
 		shouldErr    bool
@@ -99,6 +110,55 @@ func Test_dependencyConditionEvaluation(t *testing.T) {
 			dependencies: []*Dep{{Name: "DE", Version: "72.13.4788"}},
 			shouldMatch:  false,
 		},
+		{
+			title:        "A dependency in a maven version range should match",
+			name:         "DE",
+			versions:     "[4.0,5.0)",
+			dependencies: []*Dep{{Name: "DE", Version: "4.3.2.RELEASE"}},
+			shouldMatch:  true,
+		},
+		{
+			title:        "A dependency at the exclusive bound of a maven version range should not match",
+			name:         "DE",
+			versions:     "[4.0,5.0)",
+			dependencies: []*Dep{{Name: "DE", Version: "5.0"}},
+			shouldMatch:  false,
+		},
+		{
+			title:        "A dependency in any of the maven version ranges should match",
+			name:         "DE",
+			versions:     "(,1.0],[1.2,)",
+			dependencies: []*Dep{{Name: "DE", Version: "1.5.0"}},
+			shouldMatch:  true,
+		},
+		{
+			title:        "A dependency matching semver constraints should match",
+			name:         "DE",
+			versions:     ">= 1.2, < 2.0",
+			dependencies: []*Dep{{Name: "DE", Version: "v1.9.0"}},
+			shouldMatch:  true,
+		},
+		{
+			title:        "A dependency matching an alternative of semver constraints should match",
+			name:         "DE",
+			versions:     "< 1.0 || ~> 3.1",
+			dependencies: []*Dep{{Name: "DE", Version: "3.1.4"}},
+			shouldMatch:  true,
+		},
+		{
+			title:        "A dependency with the exact version of a maven range should match",
+			name:         "DE",
+			versions:     "[2.0]",
+			dependencies: []*Dep{{Name: "DE", Version: "2.0"}},
+			shouldMatch:  true,
+		},
+		{
+			title:        "Invalid version ranges should error",
+			name:         "DE",
+			versions:     "[1.0,2.0",
+			dependencies: []*Dep{{Name: "DE", Version: "1.0"}},
+			shouldErr:    true,
+		},
 		{
 			title:        "Invalid versions should error",
 			name:         "DE",
@@ -123,6 +183,7 @@ func Test_dependencyConditionEvaluation(t *testing.T) {
 				Name:       tt.name,
 				Upperbound: tt.upperbound,
 				Lowerbound: tt.lowerbound,
+				Versions:   tt.versions,
 				Client:     &fakeClient{dependencies: tt.dependencies},
 			}
 
@@ -335,3 +396,379 @@ func Test_deduplication(t *testing.T) {
 	}
 
 }
+
+type fakeHealthProvider struct {
+	fakeClient
+	healthy    bool
+	healthErr  error
+	restartErr error
+	restarts   int
+}
+
+func (p *fakeHealthProvider) ProviderInit(context.Context) error { return nil }
+func (p *fakeHealthProvider) HealthCheck(context.Context) error {
+	if p.healthErr != nil {
+		return p.healthErr
+	}
+	if !p.healthy {
+		return fmt.Errorf("not responding")
+	}
+	return nil
+}
+
+type fakeRestartableProvider struct {
+	*fakeHealthProvider
+}
+
+func (p fakeRestartableProvider) Restart(context.Context) error {
+	p.restarts++
+	if p.restartErr == nil {
+		p.healthy = true
+	}
+	return p.restartErr
+}
+
+func TestHealthMonitor(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     func(*fakeHealthProvider) InternalProviderClient
+		healthy      bool
+		healthErr    error
+		restartErr   error
+		checks       int
+		wantStatus   engine.HealthStatus
+		wantFailures []string
+	}{
+		{
+			name:       "healthy provider",
+			provider:   func(p *fakeHealthProvider) InternalProviderClient { return p },
+			healthy:    true,
+			checks:     5,
+			wantStatus: engine.HealthStatus{Name: "test", Healthy: true},
+		},
+		{
+			name:       "provider below the failure threshold",
+			provider:   func(p *fakeHealthProvider) InternalProviderClient { return p },
+			checks:     2,
+			wantStatus: engine.HealthStatus{Name: "test", ConsecutiveFailures: 2, LastError: "not responding"},
+		},
+		{
+			name:         "provider that can not be restarted fails",
+			provider:     func(p *fakeHealthProvider) InternalProviderClient { return p },
+			checks:       5,
+			wantStatus:   engine.HealthStatus{Name: "test", Failed: true, ConsecutiveFailures: 3, LastError: "provider failed 3 consecutive health checks: not responding"},
+			wantFailures: []string{"test"},
+		},
+		{
+			name:       "provider is restarted",
+			provider:   func(p *fakeHealthProvider) InternalProviderClient { return fakeRestartableProvider{p} },
+			checks:     4,
+			wantStatus: engine.HealthStatus{Name: "test", Healthy: true, Restarts: 1},
+		},
+		{
+			name:         "provider fails when the restart fails",
+			provider:     func(p *fakeHealthProvider) InternalProviderClient { return fakeRestartableProvider{p} },
+			restartErr:   fmt.Errorf("no restart"),
+			checks:       4,
+			wantStatus:   engine.HealthStatus{Name: "test", Failed: true, ConsecutiveFailures: 3, LastError: "unable to restart provider after 3 failed health checks: no restart"},
+			wantFailures: []string{"test"},
+		},
+		{
+			name:         "provider that exceeded its limits fails without restart",
+			provider:     func(p *fakeHealthProvider) InternalProviderClient { return fakeRestartableProvider{p} },
+			healthErr:    &ResourceLimitError{Process: "provider test", Limit: "memory", Value: "1Gi"},
+			checks:       4,
+			wantStatus:   engine.HealthStatus{Name: "test", Failed: true, ConsecutiveFailures: 1, LastError: "provider test exceeded its memory limit of 1Gi"},
+			wantFailures: []string{"test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeHealthProvider{healthy: tt.healthy, healthErr: tt.healthErr, restartErr: tt.restartErr}
+			failures := []string{}
+			m := NewHealthMonitor(map[string]InternalProviderClient{"test": tt.provider(p)}, time.Second, 3, func(name string, err error) {
+				failures = append(failures, name)
+			}, logr.Discard())
+			for i := 0; i < tt.checks; i++ {
+				m.check(context.TODO(), "test")
+			}
+			got := m.Health()
+			if len(got) != 1 {
+				t.Fatalf("expected one status, got %v", got)
+			}
+			got[0].LastCheck = time.Time{}
+			if !reflect.DeepEqual(got[0], tt.wantStatus) {
+				t.Errorf("expected status %+v, got %+v", tt.wantStatus, got[0])
+			}
+			if len(failures) != len(tt.wantFailures) {
+				t.Errorf("expected failures %v, got %v", tt.wantFailures, failures)
+			}
+		})
+	}
+}
+
+type fakeWarningProvider struct {
+	*fakeHealthProvider
+	warnings Warnings
+}
+
+func (p *fakeWarningProvider) Warnings() []konveyor.Warning {
+	return p.warnings.Warnings()
+}
+
+func TestWarnings(t *testing.T) {
+	p := &fakeWarningProvider{fakeHealthProvider: &fakeHealthProvider{}}
+	p.warnings.Warn("xml files failed to parse", "b.xml", "a.xml")
+	p.warnings.Warn("unable to get the dependency tree")
+	p.warnings.Warn("xml files failed to parse", "a.xml")
+
+	r := NewWarningReporter(map[string]InternalProviderClient{
+		"test":    p,
+		"healthy": &fakeHealthProvider{},
+	})
+	want := []konveyor.Warning{
+		{Provider: "test", Message: "xml files failed to parse", Count: 2, Items: []string{"a.xml", "b.xml"}},
+		{Provider: "test", Message: "unable to get the dependency tree", Count: 0, Items: []string{}},
+	}
+	if got := r.Warnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected warnings %+v, got %+v", want, got)
+	}
+}
+
+func TestInstallLanguageServers(t *testing.T) {
+	content := []byte("gopls")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256(content)
+	spec := &tooling.Spec{Name: "gopls-binary", Version: "0.14.2", URL: server.URL + "/gopls", SHA256: hex.EncodeToString(sum[:]), Binary: "gopls"}
+
+	configs, err := PrepareConfigs([]Config{
+		{
+			Name:           "go",
+			LanguageServer: spec,
+			InitConfig:     []InitConfig{{Location: "a"}, {Location: "b", ProviderSpecificConfig: map[string]interface{}{"name": "go"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dir := t.TempDir()
+	if err := InstallLanguageServers(context.Background(), configs, tooling.NewManager(dir, logr.Discard())); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := filepath.Join(dir, "gopls-binary", "0.14.2", "gopls")
+	for _, ic := range configs[0].InitConfig {
+		if got := ic.ProviderSpecificConfig[LspServerPathConfigKey]; got != want {
+			t.Errorf("expected lspServerPath %s, got %v", want, got)
+		}
+	}
+
+	_, err = PrepareConfigs([]Config{
+		{
+			Name:           "go",
+			LanguageServer: spec,
+			InitConfig:     []InitConfig{{ProviderSpecificConfig: map[string]interface{}{LspServerPathConfigKey: "/usr/bin/gopls"}}},
+		},
+	})
+	if err == nil {
+		t.Errorf("expected an error for a language server and an lspServerPath")
+	}
+}
+
+func TestInitConfigRoots(t *testing.T) {
+	config := InitConfig{
+		Location:         "/repo",
+		WorkspaceFolders: []string{"/repo/modules/api", "/other/", "/repo", "/repo/modules/api/"},
+	}
+	if want := []string{"/repo", "/repo/modules/api", "/other"}; !reflect.DeepEqual(config.Roots(), want) {
+		t.Errorf("expected roots %v, got %v", want, config.Roots())
+	}
+	if want := []string{"/repo", "/other"}; !reflect.DeepEqual(config.WalkRoots(), want) {
+		t.Errorf("expected walk roots %v, got %v", want, config.WalkRoots())
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/repo/pom.xml", want: "/repo"},
+		{path: "/repo/modules/api/src/Api.java", want: "/repo/modules/api"},
+		{path: "/repo/modules/api-impl/Impl.java", want: "/repo"},
+		{path: "/other/go.mod", want: "/other"},
+		{path: "/m2/repository/dep.jar", want: "/repo"},
+	}
+	for _, tt := range tests {
+		if got := config.RootOf(tt.path); got != tt.want {
+			t.Errorf("expected root %s of %s, got %s", tt.want, tt.path, got)
+		}
+	}
+	if got := config.RootOfURI(uri.URI("jdt://contents/dep.jar/Dep.class")); got != "/repo" {
+		t.Errorf("expected the location as the root of a dependency, got %s", got)
+	}
+
+	configs, err := PrepareConfigs([]Config{{Name: "go", InitConfig: []InitConfig{{WorkspaceFolders: []string{"/a", "/b"}}}}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if configs[0].InitConfig[0].Location != "/a" {
+		t.Errorf("expected the first workspace folder as the location, got %s", configs[0].InitConfig[0].Location)
+	}
+}
+
+func Test_templateCondition(t *testing.T) {
+	templates := map[string]engine.ChainTemplate{
+		"poms": {
+			Filepaths: []string{"/app/pom.xml", "/app/module a/pom.xml"},
+		},
+		"beans": {
+			Extras: map[string]interface{}{"filepaths": []interface{}{"/app/beans.xml"}},
+			Variables: map[string][]interface{}{
+				"class": {"com.example.Foo", "com.example.Bar$Inner"},
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		condition string
+		want      string
+	}{
+		{
+			name:      "filepaths",
+			condition: "xml:\n  filepaths: '{{poms.filepaths}}'\n",
+			want:      "xml:\n  filepaths: [\"/app/pom.xml\",\"/app/module a/pom.xml\"]\n",
+		},
+		{
+			name:      "variables",
+			condition: "referenced:\n  patterns: '{{beans.variables.class}}'\n",
+			want:      "referenced:\n  patterns: [\"com.example.Foo\",\"com.example.Bar$Inner\"]\n",
+		},
+		{
+			name:      "regex",
+			condition: "filecontent:\n  pattern: new ({{beans.regex.class}})\\(\n",
+			want:      "filecontent:\n  pattern: new (com\\.example\\.Foo|com\\.example\\.Bar\\$Inner)\\(\n",
+		},
+		{
+			name:      "extras",
+			condition: "xml:\n  filepaths: '{{beans.extras.filepaths}}'\n",
+			want:      "xml:\n  filepaths: [\"/app/beans.xml\"]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templateCondition([]byte(tt.condition), templates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, string(got))
+			}
+		})
+	}
+}
+
+func Test_GetFilesChained(t *testing.T) {
+	root := t.TempDir()
+	other := t.TempDir()
+	for _, f := range []string{filepath.Join(root, "pom.xml"), filepath.Join(root, "module a", "pom.xml"), filepath.Join(other, "pom.xml")} {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("<project/>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		filepaths []string
+		want      []string
+	}{
+		{
+			name:      "one file with a space",
+			filepaths: []string{filepath.Join(root, "module a", "pom.xml")},
+			want:      []string{filepath.Join(root, "module a", "pom.xml")},
+		},
+		{
+			name:      "files out of the location",
+			filepaths: []string{filepath.Join(root, "pom.xml"), filepath.Join(other, "pom.xml")},
+			want:      []string{filepath.Join(root, "pom.xml")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetFiles(root, tt.filepaths, "*.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// confidenceClient declares a default confidence for its referenced
+// capability and returns an incident with a confidence and one without
+type confidenceClient struct {
+	*fakeClient
+}
+
+func (c *confidenceClient) Capabilities() []Capability {
+	confidence := SymbolSearchConfidence
+	return []Capability{{Name: "referenced", DefaultConfidence: &confidence}, {Name: "other"}}
+}
+
+func (c *confidenceClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	confidence := TextSearchConfidence
+	return ProviderEvaluateResponse{Matched: true, Incidents: []IncidentContext{
+		{FileURI: "file:///a.py", Confidence: &confidence},
+		{FileURI: "file:///b.py"},
+	}}, nil
+}
+
+func TestServerDefaultConfidence(t *testing.T) {
+	client := &confidenceClient{fakeClient: &fakeClient{}}
+	s := &server{Client: client, clients: map[int64]clientMapItem{
+		1: {ctx: context.Background(), client: client, stats: newEvaluationStats()},
+	}}
+	for cap, want := range map[string][]*float64{
+		"referenced": {floatPtr(TextSearchConfidence), floatPtr(SymbolSearchConfidence)},
+		"other":      {floatPtr(TextSearchConfidence), nil},
+	} {
+		r, err := s.Evaluate(context.Background(), &libgrpc.EvaluateRequest{Id: 1, Cap: cap})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []*float64{}
+		for _, inc := range r.Response.IncidentContexts {
+			got = append(got, inc.Confidence)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected the confidences %v of the incidents of %s, got %v", want, cap, got)
+		}
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestProviderConditionScope(t *testing.T) {
+	cond := ProviderCondition{ConditionInfo: map[interface{}]interface{}{
+		"filepaths": []interface{}{"/etc/app.xml", "conf/app.xml", "{{poms.filepaths}}"},
+	}}
+	tests := []struct {
+		location string
+		want     []uri.URI
+	}{
+		{location: "/app", want: []uri.URI{"file:///etc/app.xml", "file:///app/conf/app.xml"}},
+		{location: "", want: []uri.URI{"file:///etc/app.xml"}},
+	}
+	for _, tt := range tests {
+		got := cond.Scope(engine.ConditionContext{Location: tt.location})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expected the scope %v in %q, got %v", tt.want, tt.location, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.lsp.dev/uri"
+)
+
+// RateLimit limits the queries sent to a provider, for the language servers
+// that throttle or crash under bursts, such as a shared one. The queries over
+// the limit wait until they can be sent.
+type RateLimit struct {
+	// RequestsPerSecond is the rate the queries are sent at, 0 for no limit
+	RequestsPerSecond float64 `yaml:"requestsPerSecond,omitempty" json:"requestsPerSecond,omitempty"`
+	// Burst is the number of queries that can be sent at once after the
+	// provider was idle, 1 by default
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty"`
+	// MaxInFlight is the number of queries the provider works on at the same
+	// time, 0 for no limit
+	MaxInFlight int `yaml:"maxInFlight,omitempty" json:"maxInFlight,omitempty"`
+}
+
+func (r *RateLimit) Validate() error {
+	if r.RequestsPerSecond < 0 {
+		return fmt.Errorf("invalid requestsPerSecond %v, it can not be negative", r.RequestsPerSecond)
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("invalid burst %d, it can not be negative", r.Burst)
+	}
+	if r.MaxInFlight < 0 {
+		return fmt.Errorf("invalid maxInFlight %d, it can not be negative", r.MaxInFlight)
+	}
+	return nil
+}
+
+type rateLimitedClient struct {
+	InternalProviderClient
+	inFlight chan struct{}
+
+	mutex    sync.Mutex
+	interval time.Duration
+	burst    int
+	// next is when the next query can be sent once the burst is used
+	next time.Time
+}
+
+// WithRateLimit returns a client that waits before sending the queries, the
+// conditions and the dependency requests, that are over the rate limit.
+func WithRateLimit(client InternalProviderClient, limit RateLimit) InternalProviderClient {
+	c := &rateLimitedClient{
+		InternalProviderClient: client,
+		burst:                  limit.Burst,
+	}
+	if limit.RequestsPerSecond > 0 {
+		c.interval = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
+	}
+	if c.burst == 0 {
+		c.burst = 1
+	}
+	if limit.MaxInFlight > 0 {
+		c.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return c
+}
+
+// acquire waits until a query can be sent, the returned function releases it
+// once it is answered
+func (c *rateLimitedClient) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if c.inFlight != nil {
+		select {
+		case c.inFlight <- struct{}{}:
+			release = func() { <-c.inFlight }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.interval == 0 {
+		return release, nil
+	}
+
+	c.mutex.Lock()
+	now := time.Now()
+	// the burst is refilled while no query is sent
+	earliest := now.Add(-time.Duration(c.burst-1) * c.interval)
+	if c.next.Before(earliest) {
+		c.next = earliest
+	}
+	at := c.next
+	c.next = c.next.Add(c.interval)
+	c.mutex.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return release, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *rateLimitedClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return ProviderEvaluateResponse{}, err
+	}
+	defer release()
+	return c.InternalProviderClient.Evaluate(ctx, cap, conditionInfo)
+}
+
+func (c *rateLimitedClient) GetDependencies(ctx context.Context) (map[uri.URI][]*Dep, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.InternalProviderClient.GetDependencies(ctx)
+}
+
+func (c *rateLimitedClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]DepDAGItem, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.InternalProviderClient.GetDependenciesDAG(ctx)
+}
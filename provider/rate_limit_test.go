@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider records how many queries it works on at the same time
+type countingProvider struct {
+	*fakeHealthProvider
+	delay    time.Duration
+	inFlight int32
+	maxSeen  int32
+}
+
+func (p *countingProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	n := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&p.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&p.maxSeen, seen, n) {
+			break
+		}
+	}
+	time.Sleep(p.delay)
+	return ProviderEvaluateResponse{Matched: true}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        RateLimit
+		queries      int
+		delay        time.Duration
+		minDuration  time.Duration
+		maxDuration  time.Duration
+		wantInFlight int32
+	}{
+		{
+			name:        "no limit",
+			limit:       RateLimit{},
+			queries:     10,
+			delay:       10 * time.Millisecond,
+			maxDuration: 500 * time.Millisecond,
+		},
+		{
+			name:        "requests per second",
+			limit:       RateLimit{RequestsPerSecond: 50},
+			queries:     6,
+			minDuration: 100 * time.Millisecond,
+		},
+		{
+			name:        "burst",
+			limit:       RateLimit{RequestsPerSecond: 5, Burst: 4},
+			queries:     4,
+			maxDuration: 150 * time.Millisecond,
+		},
+		{
+			name:         "max in flight",
+			limit:        RateLimit{MaxInFlight: 2},
+			queries:      6,
+			delay:        20 * time.Millisecond,
+			minDuration:  60 * time.Millisecond,
+			wantInFlight: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &countingProvider{fakeHealthProvider: &fakeHealthProvider{}, delay: tt.delay}
+			client := WithRateLimit(p, tt.limit)
+			start := time.Now()
+			wg := sync.WaitGroup{}
+			for i := 0; i < tt.queries; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := client.Evaluate(context.TODO(), "file", nil); err != nil {
+						t.Errorf("unexpected error %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			elapsed := time.Since(start)
+			if tt.minDuration != 0 && elapsed < tt.minDuration {
+				t.Errorf("expected the queries to take at least %s, took %s", tt.minDuration, elapsed)
+			}
+			if tt.maxDuration != 0 && elapsed > tt.maxDuration {
+				t.Errorf("expected the queries to take at most %s, took %s", tt.maxDuration, elapsed)
+			}
+			if tt.wantInFlight != 0 && p.maxSeen != tt.wantInFlight {
+				t.Errorf("expected at most %d queries in flight, got %d", tt.wantInFlight, p.maxSeen)
+			}
+		})
+	}
+}
+
+func TestRateLimitCanceled(t *testing.T) {
+	p := &countingProvider{fakeHealthProvider: &fakeHealthProvider{}}
+	client := WithRateLimit(p, RateLimit{RequestsPerSecond: 1})
+	if _, err := client.Evaluate(context.TODO(), "file", nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Evaluate(ctx, "file", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the waiting query to be canceled, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v2"
+)
+
+// RegistriesConfigKey is the provider specific config of the package
+// registries the dependencies are resolved from
+const RegistriesConfigKey = "registries"
+
+// Registries configures the private registries and mirrors the tools that
+// resolve the dependencies use, such as npm, pip and go, instead of the
+// public ones.
+type Registries struct {
+	Npm  *Registry `yaml:"npm,omitempty" json:"npm,omitempty"`
+	PyPI *Registry `yaml:"pypi,omitempty" json:"pypi,omitempty"`
+	Go   *Registry `yaml:"go,omitempty" json:"go,omitempty"`
+}
+
+// Registry is a package registry. The credentials can reference environment
+// variables, such as ${NPM_TOKEN}, so that they are not in the settings.
+type Registry struct {
+	// URL is the npm registry, the pip index or the go module proxy
+	URL string `yaml:"url" json:"url"`
+	// Mirrors are used after the URL, as the extra pip indexes and the go
+	// module proxies that are tried next, such as direct, npm only has one
+	// registry. The credentials are not sent to the mirrors.
+	Mirrors  []string `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
+	Username string   `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string   `yaml:"password,omitempty" json:"password,omitempty"`
+	// Token is used instead of the username and password, as the auth token
+	// of npm, as the password of the __token__ user of pip and of go
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+	// CAFile is the PEM file of the certificate authorities of the registry,
+	// when it is not signed by a public one
+	CAFile string `yaml:"caFile,omitempty" json:"caFile,omitempty"`
+	// Private are the go module path patterns that are not checked against
+	// the public checksum database, as GOPRIVATE
+	Private []string `yaml:"private,omitempty" json:"private,omitempty"`
+}
+
+// RegistriesSchema is the schema of the registries, for the providers that
+// resolve their dependencies from them to add to the schema of their
+// provider specific config. The passwords and tokens are sensitive.
+func RegistriesSchema() *openapi3.Schema {
+	registry := NewConfigSchema(map[string]*openapi3.Schema{
+		"url":      WithDescription(openapi3.NewStringSchema(), "URL of the npm registry, the pip index or the go module proxy"),
+		"mirrors":  WithDescription(openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()), "URLs used after the URL, without the credentials"),
+		"username": WithDescription(openapi3.NewStringSchema(), "User of the registry, can reference environment variables such as ${USER}"),
+		"password": Sensitive(WithDescription(openapi3.NewStringSchema(), "Password of the user, can reference environment variables")),
+		"token":    Sensitive(WithDescription(openapi3.NewStringSchema(), "Token used instead of the username and password, can reference environment variables")),
+		"caFile":   WithDescription(openapi3.NewStringSchema(), "PEM file of the certificate authorities of the registry"),
+		"private":  WithDescription(openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()), "Go module path patterns that are not checked against the public checksum database"),
+	})
+	return WithDescription(NewConfigSchema(map[string]*openapi3.Schema{
+		"npm":  registry,
+		"pypi": registry,
+		"go":   registry,
+	}), "Private registries and mirrors the dependencies are resolved from")
+}
+
+// GetRegistries reads the registries of the provider specific config, nil
+// when there are none
+func GetRegistries(providerSpecificConfig map[string]interface{}) (*Registries, error) {
+	value, ok := providerSpecificConfig[RegistriesConfigKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	registries := &Registries{}
+	if err := yaml.UnmarshalStrict(content, registries); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", RegistriesConfigKey, err)
+	}
+	return registries, registries.Validate()
+}
+
+// Validate checks the URLs of the registries and that their CA files exist
+func (r *Registries) Validate() error {
+	for name, registry := range map[string]*Registry{"npm": r.Npm, "pypi": r.PyPI, "go": r.Go} {
+		if registry == nil {
+			continue
+		}
+		for _, u := range append([]string{registry.URL}, registry.Mirrors...) {
+			if registry == r.Go && (u == "direct" || u == "off") {
+				continue
+			}
+			parsed, err := url.Parse(u)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return fmt.Errorf("invalid url %q of the %s registry", u, name)
+			}
+		}
+		if registry.CAFile != "" {
+			if _, err := os.Stat(registry.CAFile); err != nil {
+				return fmt.Errorf("invalid caFile of the %s registry: %w", name, err)
+			}
+		}
+	}
+	if r.Npm != nil && len(r.Npm.Mirrors) != 0 {
+		return fmt.Errorf("the npm registry can not have mirrors")
+	}
+	return nil
+}
+
+func (r *Registry) credentials() (string, string) {
+	if r.Token != "" {
+		return "__token__", os.ExpandEnv(r.Token)
+	}
+	return os.ExpandEnv(r.Username), os.ExpandEnv(r.Password)
+}
+
+// Environment returns the environment variables that configure the tools to
+// use the registries. The configuration files with the credentials, the
+// .npmrc and the .netrc of go, are written in the directory, which should
+// only be readable by the user and removed once the tools are done.
+func (r *Registries) Environment(dir string) ([]string, error) {
+	env := []string{}
+	if r.Npm != nil {
+		npmrc := []string{"registry=" + r.Npm.URL}
+		// the credentials are scoped to the registry, without the scheme
+		scope := strings.TrimPrefix(strings.TrimPrefix(r.Npm.URL, "https:"), "http:")
+		if !strings.HasSuffix(scope, "/") {
+			scope += "/"
+		}
+		if r.Npm.Token != "" {
+			npmrc = append(npmrc, fmt.Sprintf("%s:_authToken=%s", scope, os.ExpandEnv(r.Npm.Token)))
+		} else if r.Npm.Username != "" {
+			user, password := r.Npm.credentials()
+			npmrc = append(npmrc,
+				fmt.Sprintf("%s:username=%s", scope, user),
+				fmt.Sprintf("%s:_password=%s", scope, base64.StdEncoding.EncodeToString([]byte(password))))
+		}
+		if r.Npm.CAFile != "" {
+			npmrc = append(npmrc, "cafile="+r.Npm.CAFile)
+			env = append(env, "NODE_EXTRA_CA_CERTS="+r.Npm.CAFile)
+		}
+		path := filepath.Join(dir, ".npmrc")
+		if err := os.WriteFile(path, []byte(strings.Join(npmrc, "\n")+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		env = append(env, "NPM_CONFIG_USERCONFIG="+path)
+	}
+	if r.PyPI != nil {
+		// the credentials are only sent to the registry, not to its mirrors
+		env = append(env, "PIP_INDEX_URL="+r.PyPI.withCredentials(r.PyPI.URL))
+		if len(r.PyPI.Mirrors) != 0 {
+			env = append(env, "PIP_EXTRA_INDEX_URL="+strings.Join(r.PyPI.Mirrors, " "))
+		}
+		if r.PyPI.CAFile != "" {
+			env = append(env, "PIP_CERT="+r.PyPI.CAFile)
+		}
+	}
+	if r.Go != nil {
+		env = append(env, "GOPROXY="+strings.Join(append([]string{r.Go.URL}, r.Go.Mirrors...), ","))
+		if len(r.Go.Private) != 0 {
+			env = append(env, "GOPRIVATE="+strings.Join(r.Go.Private, ","))
+		}
+		if user, password := r.Go.credentials(); user != "" {
+			parsed, err := url.Parse(r.Go.URL)
+			if err != nil {
+				return nil, err
+			}
+			netrc := fmt.Sprintf("machine %s login %s password %s\n", parsed.Hostname(), user, password)
+			path := filepath.Join(dir, ".netrc")
+			if err := os.WriteFile(path, []byte(netrc), 0600); err != nil {
+				return nil, err
+			}
+			env = append(env, "NETRC="+path)
+		}
+		if r.Go.CAFile != "" {
+			env = append(env, "SSL_CERT_FILE="+r.Go.CAFile)
+		}
+	}
+	return env, nil
+}
+
+// withCredentials adds the credentials of the registry to the URL
+func (r *Registry) withCredentials(u string) string {
+	user, password := r.credentials()
+	if user == "" {
+		return u
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.User = url.UserPassword(user, password)
+	return parsed.String()
+}
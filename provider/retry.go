@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/jsonrpc2"
+	"go.lsp.dev/uri"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy sends the queries that fail with a transient error of the
+// provider again, such as a language server that is busy or whose content
+// was modified while it answered, instead of failing their condition.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a query is sent, including the first
+	// one, 3 by default
+	MaxAttempts int `yaml:"maxAttempts,omitempty" json:"maxAttempts,omitempty"`
+	// Backoff is the wait before the first retry, it doubles for every
+	// following one, such as 500ms
+	Backoff string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	// MaxBackoff caps the wait between two attempts, such as 10s
+	MaxBackoff string `yaml:"maxBackoff,omitempty" json:"maxBackoff,omitempty"`
+	// RetryableCodes are the json-rpc error codes of the language server that
+	// are retried, the content modified, server cancelled and server
+	// overloaded ones by default
+	RetryableCodes []int64 `yaml:"retryableCodes,omitempty" json:"retryableCodes,omitempty"`
+}
+
+const defaultMaxAttempts = 3
+
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("invalid maxAttempts %d, it can not be negative", r.MaxAttempts)
+	}
+	if _, err := parseDuration("backoff", r.Backoff); err != nil {
+		return err
+	}
+	if _, err := parseDuration("maxBackoff", r.MaxBackoff); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RPCPolicy returns the policy of the calls sent to a language server over
+// json-rpc
+func (r *RetryPolicy) RPCPolicy() jsonrpc2.RetryPolicy {
+	p := jsonrpc2.RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		RetryableCodes: r.RetryableCodes,
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	p.InitialBackoff, _ = parseDuration("backoff", r.Backoff)
+	p.MaxBackoff, _ = parseDuration("maxBackoff", r.MaxBackoff)
+	return p
+}
+
+// retryable tells whether a query that failed with the error is sent again:
+// the gRPC errors of an external provider that is not available or out of
+// resources. The json-rpc requests of the providers are retried one by one by
+// their connection, and the timeouts and cancellations are never retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	// the gRPC errors wrapped by the provider client
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+type retryClient struct {
+	InternalProviderClient
+	name   string
+	log    logr.Logger
+	policy RetryPolicy
+}
+
+// WithRetry returns a client that sends the queries, the conditions and the
+// dependency requests, that fail with a transient error again, with a backoff
+// between the attempts.
+func WithRetry(name string, client InternalProviderClient, policy RetryPolicy, log logr.Logger) InternalProviderClient {
+	return &retryClient{
+		InternalProviderClient: client,
+		name:                   name,
+		log:                    log,
+		policy:                 policy,
+	}
+}
+
+// retry calls f until it succeeds, fails with an error that is not
+// retryable or the attempts are used
+func (c *retryClient) retry(ctx context.Context, query string, f func() error) error {
+	rpc := c.policy.RPCPolicy()
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= rpc.MaxAttempts || !retryable(ctx, err) {
+			return err
+		}
+		backoff := rpc.Backoff(attempt + 1)
+		c.log.V(5).Info("retrying provider query", "provider", c.name, "query", query, "attempt", attempt+1, "backoff", backoff, "error", err.Error())
+		if sleepContext(ctx, backoff) != nil {
+			return err
+		}
+	}
+}
+
+func (c *retryClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	var resp ProviderEvaluateResponse
+	err := c.retry(ctx, cap, func() error {
+		var err error
+		resp, err = c.InternalProviderClient.Evaluate(ctx, cap, conditionInfo)
+		return err
+	})
+	return resp, err
+}
+
+func (c *retryClient) GetDependencies(ctx context.Context) (map[uri.URI][]*Dep, error) {
+	var deps map[uri.URI][]*Dep
+	err := c.retry(ctx, "dependencies", func() error {
+		var err error
+		deps, err = c.InternalProviderClient.GetDependencies(ctx)
+		return err
+	})
+	return deps, err
+}
+
+func (c *retryClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]DepDAGItem, error) {
+	var deps map[uri.URI][]DepDAGItem
+	err := c.retry(ctx, "dependencies", func() error {
+		var err error
+		deps, err = c.InternalProviderClient.GetDependenciesDAG(ctx)
+		return err
+	})
+	return deps, err
+}
+
+// sleepContext waits for the duration, it returns the error of the context
+// when it is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/jsonrpc2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingProvider fails the first queries with its errors
+type failingProvider struct {
+	*fakeHealthProvider
+	errs  []error
+	calls int
+}
+
+func (p *failingProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return ProviderEvaluateResponse{}, p.errs[p.calls-1]
+	}
+	return ProviderEvaluateResponse{Matched: true}, nil
+}
+
+func TestWithRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "provider restarting")
+	tests := []struct {
+		name        string
+		errs        []error
+		wantMatched bool
+		wantCalls   int
+	}{
+		{
+			name:        "unavailable provider",
+			errs:        []error{unavailable, fmt.Errorf("evaluating: %w", status.Error(codes.ResourceExhausted, "too many requests"))},
+			wantMatched: true,
+			wantCalls:   3,
+		},
+		{
+			name:      "attempts used",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls: 3,
+		},
+		{
+			name:      "invalid condition",
+			errs:      []error{status.Error(codes.InvalidArgument, "invalid condition")},
+			wantCalls: 1,
+		},
+		{
+			name:      "json-rpc errors retried by the connection",
+			errs:      []error{jsonrpc2.NewErrorf(jsonrpc2.CodeContentModified, "content modified")},
+			wantCalls: 1,
+		},
+		{
+			name:      "timeout",
+			errs:      []error{&CallTimeoutError{Provider: "java", Capability: "referenced", Timeout: time.Second}},
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &failingProvider{fakeHealthProvider: &fakeHealthProvider{}, errs: tt.errs}
+			client := WithRetry("java", p, RetryPolicy{Backoff: "1ms"}, logr.Discard())
+			resp, err := client.Evaluate(context.Background(), "referenced", nil)
+			if resp.Matched != tt.wantMatched || (err == nil) != tt.wantMatched {
+				t.Errorf("expected matched %v, got %v, %v", tt.wantMatched, resp.Matched, err)
+			}
+			if p.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, p.calls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	for _, policy := range []RetryPolicy{{MaxAttempts: -1}, {Backoff: "soon"}, {MaxBackoff: "-1s"}} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("expected the retry policy %+v to be invalid", policy)
+		}
+	}
+	rpc := (&RetryPolicy{Backoff: "1s", RetryableCodes: []int64{jsonrpc2.CodeRequestFailed}}).RPCPolicy()
+	if rpc.MaxAttempts != defaultMaxAttempts || rpc.InitialBackoff != time.Second || !rpc.Retryable(jsonrpc2.NewErrorf(jsonrpc2.CodeRequestFailed, "failed")) {
+		t.Errorf("unexpected json-rpc policy %+v", rpc)
+	}
+}
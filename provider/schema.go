@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/konveyor/analyzer-lsp/redact"
+)
+
+var (
+	schemaMutex sync.RWMutex
+	// providerSpecificSchemas are the schemas of the providerSpecificConfig of
+	// the init configs, by provider name
+	providerSpecificSchemas = map[string]*openapi3.Schema{}
+)
+
+// RegisterConfigSchema publishes the schema of the providerSpecificConfig of
+// the init configs of a provider, the settings of the provider are validated
+// against it when they are loaded. Registering a provider that already has a
+// schema replaces it.
+func RegisterConfigSchema(name string, schema *openapi3.Schema) {
+	schemaMutex.Lock()
+	defer schemaMutex.Unlock()
+	providerSpecificSchemas[name] = schema
+}
+
+// NewConfigSchema returns the schema of an object that only has the given
+// properties, for the providers to build the schema of their
+// providerSpecificConfig with.
+func NewConfigSchema(properties map[string]*openapi3.Schema) *openapi3.Schema {
+	// additionalProperties is a different field in the versions of
+	// kin-openapi the providers are built with, it is set from its json
+	schema := &openapi3.Schema{}
+	if err := json.Unmarshal([]byte(`{"type": "object", "additionalProperties": false}`), schema); err != nil {
+		panic(err)
+	}
+	return schema.WithProperties(properties)
+}
+
+// WithDescription sets the description of a schema, which a UI can show along
+// with the field
+func WithDescription(schema *openapi3.Schema, description string) *openapi3.Schema {
+	schema.Description = description
+	return schema
+}
+
+// Sensitive marks a schema as the one of a secret, such as a password or a
+// token, with writeOnly. The values of the sensitive fields of the init
+// configs are replaced in the logs, the traces and the error messages once
+// the configs are prepared.
+func Sensitive(schema *openapi3.Schema) *openapi3.Schema {
+	schema.WriteOnly = true
+	return schema
+}
+
+// InitConfigSchema returns the schema of the init configs of a provider, such
+// as for a UI to generate a form from it. The providerSpecificConfig of the
+// providers that did not register a schema can be any object.
+func InitConfigSchema(name string) *openapi3.Schema {
+	schemaMutex.RLock()
+	specific, ok := providerSpecificSchemas[name]
+	schemaMutex.RUnlock()
+	if !ok {
+		specific = openapi3.NewObjectSchema().WithAnyAdditionalProperties()
+	}
+	return NewConfigSchema(map[string]*openapi3.Schema{
+		"location":               WithDescription(openapi3.NewStringSchema(), "Path of the code base or binary to analyze"),
+		"workspaceFolders":       WithDescription(openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()), "Paths of the other roots of the application"),
+		"dependencyPath":         WithDescription(openapi3.NewStringSchema(), "Path of the dependencies, relative to each root"),
+		"analysisMode":           WithDescription(openapi3.NewStringSchema().WithEnum("", string(FullAnalysisMode), string(SourceOnlyAnalysisMode)), "Whether the dependencies are analyzed along with the source code"),
+		"providerSpecificConfig": specific,
+		"proxyConfig":            WithDescription(openapi3.NewObjectSchema().WithAnyAdditionalProperties(), "Proxy of the provider for this init config"),
+	})
+}
+
+// ConfigSchemaNames returns the sorted names of the providers that registered
+// a schema
+func ConfigSchemaNames() []string {
+	schemaMutex.RLock()
+	defer schemaMutex.RUnlock()
+	names := []string{}
+	for name := range providerSpecificSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateInitConfig validates an init config of a provider against its
+// schema. The init config is either an InitConfig or the value decoded from a
+// settings file, so that the fields that do not exist are reported.
+func ValidateInitConfig(name string, initConfig interface{}) error {
+	value, err := jsonValue(initConfig)
+	if err != nil {
+		return err
+	}
+	err = InitConfigSchema(name).VisitJSON(value, openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+	messages := schemaErrorMessages(err)
+	sort.Strings(messages)
+	return fmt.Errorf("%s", strings.Join(messages, ", "))
+}
+
+// SensitiveValues returns the secrets of an init config of a provider: the
+// values of the fields its schema marks as sensitive and of the keys that look
+// like secrets, such as password or token, the credentials of its registries
+// and the passwords of its proxy. The environment variables they reference
+// are expanded.
+func SensitiveValues(name string, initConfig InitConfig) []string {
+	values := []string{}
+	if value, err := jsonValue(initConfig); err == nil {
+		collectSensitive(InitConfigSchema(name), value, false, &values)
+	}
+	if registries, err := GetRegistries(initConfig.ProviderSpecificConfig); err == nil && registries != nil {
+		for _, r := range []*Registry{registries.Npm, registries.PyPI, registries.Go} {
+			if r != nil {
+				_, password := r.credentials()
+				values = append(values, password)
+			}
+		}
+	}
+	if initConfig.Proxy != nil {
+		for _, u := range []string{initConfig.Proxy.HTTPProxy, initConfig.Proxy.HTTPSProxy} {
+			if parsed, err := url.Parse(u); err == nil && parsed.User != nil {
+				if password, ok := parsed.User.Password(); ok {
+					values = append(values, password)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// collectSensitive adds the strings of a value that are sensitive, because
+// of their schema or of their key, to values
+func collectSensitive(schema *openapi3.Schema, value interface{}, sensitive bool, values *[]string) {
+	if schema != nil && schema.WriteOnly {
+		sensitive = true
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			var property *openapi3.Schema
+			if schema != nil {
+				if ref, ok := schema.Properties[key]; ok && ref != nil {
+					property = ref.Value
+				}
+			}
+			collectSensitive(property, item, sensitive || redact.IsSecretKey(key), values)
+		}
+	case []interface{}:
+		var items *openapi3.Schema
+		if schema != nil && schema.Items != nil {
+			items = schema.Items.Value
+		}
+		for _, item := range v {
+			collectSensitive(items, item, sensitive, values)
+		}
+	case string:
+		if sensitive {
+			*values = append(*values, v, os.ExpandEnv(v))
+		}
+	}
+}
+
+// jsonValue converts a value to what it is when it is decoded from json, the
+// schemas can only validate these values
+func jsonValue(value interface{}) (interface{}, error) {
+	if ic, ok := value.(InitConfig); ok {
+		ic.ProviderSpecificConfig, _ = stringKeys(ic.ProviderSpecificConfig).(map[string]interface{})
+		value = ic
+	} else {
+		value = stringKeys(value)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(content, &v)
+	return v, err
+}
+
+// stringKeys converts the maps decoded from yaml, which have interface keys,
+// to maps with string keys
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[fmt.Sprintf("%v", key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range v {
+			m[key] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = stringKeys(item)
+		}
+		return items
+	}
+	return value
+}
+
+// schemaErrorMessages returns the path and the reason of the schema errors,
+// without the schemas that the errors of kin-openapi are printed with
+func schemaErrorMessages(err error) []string {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		messages := []string{}
+		for _, e := range multi {
+			messages = append(messages, schemaErrorMessages(e)...)
+		}
+		return messages
+	}
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return []string{err.Error()}
+	}
+	if schemaErr.Origin != nil {
+		return schemaErrorMessages(schemaErr.Origin)
+	}
+	reason := schemaErr.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("does not match the %s of its schema", schemaErr.SchemaField)
+	}
+	if path := schemaErr.JSONPointer(); len(path) > 0 {
+		return []string{fmt.Sprintf("%s: %s", strings.Join(path, "."), reason)}
+	}
+	return []string{reason}
+}
@@ -11,8 +11,12 @@ import (
 
 	"github.com/go-logr/logr"
 	libgrpc "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+	"github.com/konveyor/analyzer-lsp/redact"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -22,6 +26,13 @@ type Server interface {
 	Start(context.Context) error
 }
 
+// healthServer is the standard gRPC health service of the provider server, it
+// is not serving when a client that is a HealthChecker fails its check
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	server *server
+}
+
 type server struct {
 	Client BaseClient
 	Log    logr.Logger
@@ -31,11 +42,17 @@ type server struct {
 	mutex   sync.RWMutex
 	clients map[int64]clientMapItem
 	rand    rand.Rand
+
+	// capabilities of the client are only asked once, the clients built on
+	// provider/server create a provider to ask them
+	capabilities     []Capability
+	capabilitiesOnce sync.Once
 }
 
 type clientMapItem struct {
 	ctx    context.Context
 	client ServiceClient
+	stats  *evaluationStats
 }
 
 // Provider GRPC Service
@@ -61,22 +78,66 @@ func (s *server) Start(ctx context.Context) error {
 	}
 	gs := grpc.NewServer()
 	libgrpc.RegisterProviderServiceServer(gs, s)
+	healthpb.RegisterHealthServer(gs, &healthServer{server: s})
 	reflection.Register(gs)
+	go func() {
+		<-ctx.Done()
+		gs.GracefulStop()
+	}()
 	log.Printf("server listening at %v", lis.Addr())
 	if err := gs.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
+	s.stopClients()
 	return nil
 }
 
+// stopClients stops the clients that were initialized and not stopped, when
+// the server is stopped before the analyzer stopped them
+func (s *server) stopClients() {
+	s.mutex.Lock()
+	clients := s.clients
+	s.clients = make(map[int64]clientMapItem)
+	s.mutex.Unlock()
+	for _, c := range clients {
+		c.client.Stop()
+	}
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.Service != "" && req.Service != libgrpc.ProviderService_ServiceDesc.ServiceName {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", req.Service)
+	}
+	h.server.mutex.RLock()
+	checkers := []HealthChecker{}
+	for _, c := range h.server.clients {
+		if checker, ok := c.client.(HealthChecker); ok {
+			checkers = append(checkers, checker)
+		}
+	}
+	h.server.mutex.RUnlock()
+	for _, checker := range checkers {
+		if err := checker.HealthCheck(ctx); err != nil {
+			h.server.Log.Error(err, "provider is not healthy")
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+		}
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
 func (s *server) Capabilities(ctx context.Context, _ *emptypb.Empty) (*libgrpc.CapabilitiesResponse, error) {
-	caps := s.Client.Capabilities()
+	caps := s.clientCapabilities()
 
 	var pbCaps []*libgrpc.Capability
 
 	for _, c := range caps {
+		incidentVariables, err := schemaStruct(c.IncidentVariables)
+		if err != nil {
+			return nil, fmt.Errorf("invalid incident variables of capability %s: %w", c.Name, err)
+		}
 		pbCaps = append(pbCaps, &libgrpc.Capability{
-			Name: c.Name,
+			Name:              c.Name,
+			IncidentVariables: incidentVariables,
 		})
 	}
 
@@ -85,6 +146,25 @@ func (s *server) Capabilities(ctx context.Context, _ *emptypb.Empty) (*libgrpc.C
 	}, nil
 }
 
+func (s *server) clientCapabilities() []Capability {
+	s.capabilitiesOnce.Do(func() {
+		s.capabilities = s.Client.Capabilities()
+	})
+	return s.capabilities
+}
+
+// defaultConfidence returns the DefaultConfidence of the capability, nil when
+// it has none
+func (s *server) defaultConfidence(capability string) *float64 {
+	for _, c := range s.clientCapabilities() {
+		if c.Name == capability && c.DefaultConfidence != nil {
+			confidence := *c.DefaultConfidence
+			return &confidence
+		}
+	}
+	return nil
+}
+
 func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.InitResponse, error) {
 	//By default if nothing is set for analysis mode, in the config, we should default to full for external providers
 	var a AnalysisMode = AnalysisMode(config.AnalysisMode)
@@ -95,19 +175,24 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 	}
 
 	c := InitConfig{
-		Location:       config.Location,
-		DependencyPath: config.DependencyPath,
-		AnalysisMode:   a,
+		Location:         config.Location,
+		DependencyPath:   config.DependencyPath,
+		WorkspaceFolders: config.WorkspaceFolders,
+		AnalysisMode:     a,
 		Proxy: &Proxy{
-			HTTPProxy:  config.Proxy.HTTPProxy,
-			HTTPSProxy: config.Proxy.HTTPSProxy,
-			NoProxy:    config.Proxy.NoProxy,
+			HTTPProxy:  config.GetProxy().GetHTTPProxy(),
+			HTTPSProxy: config.GetProxy().GetHTTPSProxy(),
+			NoProxy:    config.GetProxy().GetNoProxy(),
 		},
 	}
 
 	if config.ProviderSpecificConfig != nil {
 		c.ProviderSpecificConfig = config.ProviderSpecificConfig.AsMap()
 	}
+	// the server does not know the schema of the provider, the secrets are
+	// the ones of the keys that look like secrets, of the registries and of
+	// the proxies
+	redact.Register(SensitiveValues("", c)...)
 
 	id := rand.Int63()
 	log := s.Log.WithValues("client", id)
@@ -116,7 +201,7 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 	client, err := s.Client.Init(newCtx, log, c)
 	if err != nil {
 		return &libgrpc.InitResponse{
-			Error:      err.Error(),
+			Error:      redact.String(err.Error()),
 			Successful: false,
 		}, nil
 	}
@@ -124,6 +209,7 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 	s.clients[id] = clientMapItem{
 		client: client,
 		ctx:    ctx,
+		stats:  newEvaluationStats(),
 	}
 	s.mutex.Unlock()
 
@@ -136,10 +222,18 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*libgrpc.EvaluateResponse, error) {
 
 	s.mutex.RLock()
-	client := s.clients[req.Id]
+	client, ok := s.clients[req.Id]
 	s.mutex.RUnlock()
+	if !ok {
+		return &libgrpc.EvaluateResponse{
+			Error:      fmt.Sprintf("unknown client %d", req.Id),
+			Successful: false,
+		}, nil
+	}
 
+	start := time.Now()
 	r, err := client.client.Evaluate(ctx, req.Cap, []byte(req.ConditionInfo))
+	client.stats.record(req.Cap, time.Since(start), err)
 
 	if err != nil {
 		return &libgrpc.EvaluateResponse{
@@ -181,9 +275,13 @@ func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*l
 		}
 
 		inc := &libgrpc.IncidentContext{
-			FileURI:   string(i.FileURI),
-			Variables: variables,
-			Links:     links,
+			FileURI:    string(i.FileURI),
+			Variables:  variables,
+			Links:      links,
+			Confidence: i.Confidence,
+		}
+		if inc.Confidence == nil {
+			inc.Confidence = s.defaultConfidence(req.Cap)
 		}
 		if i.LineNumber != nil {
 			lineNumber := int64(*i.LineNumber)
@@ -218,17 +316,25 @@ func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*l
 
 func (s *server) Stop(ctx context.Context, in *libgrpc.ServiceRequest) (*emptypb.Empty, error) {
 	s.mutex.Lock()
-	client := s.clients[in.Id]
+	client, ok := s.clients[in.Id]
 	delete(s.clients, in.Id)
 	s.mutex.Unlock()
-	client.client.Stop()
+	if ok {
+		client.client.Stop()
+	}
 	return &emptypb.Empty{}, nil
 }
 
 func (s *server) GetDependencies(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyResponse, error) {
 	s.mutex.RLock()
-	client := s.clients[in.Id]
+	client, ok := s.clients[in.Id]
 	s.mutex.RUnlock()
+	if !ok {
+		return &libgrpc.DependencyResponse{
+			Successful: false,
+			Error:      fmt.Sprintf("unknown client %d", in.Id),
+		}, nil
+	}
 	deps, err := client.client.GetDependencies(ctx)
 	if err != nil {
 		return &libgrpc.DependencyResponse{
@@ -255,6 +361,7 @@ func (s *server) GetDependencies(ctx context.Context, in *libgrpc.ServiceRequest
 				Extras:             extras,
 				Indirect:           d.Indirect,
 				Labels:             d.Labels,
+				License:            d.License,
 			})
 		}
 		fd.List = &libgrpc.DependencyList{
@@ -285,6 +392,7 @@ func recreateDAGAddedItems(items []DepDAGItem) []*libgrpc.DependencyDAGItem {
 				ResolvedIdentifier: i.Dep.ResolvedIdentifier,
 				Extras:             extras,
 				Labels:             i.Dep.Labels,
+				License:            i.Dep.License,
 				Indirect:           false,
 			},
 			AddedDeps: recreateDAGAddedItems(i.AddedDeps),
@@ -293,10 +401,16 @@ func recreateDAGAddedItems(items []DepDAGItem) []*libgrpc.DependencyDAGItem {
 	return deps
 }
 
-func (s *server) GetDependenciesLinkedList(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyDAGResponse, error) {
+func (s *server) GetDependenciesDAG(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyDAGResponse, error) {
 	s.mutex.RLock()
-	client := s.clients[in.Id]
+	client, ok := s.clients[in.Id]
 	s.mutex.RUnlock()
+	if !ok {
+		return &libgrpc.DependencyDAGResponse{
+			Successful: false,
+			Error:      fmt.Sprintf("unknown client %d", in.Id),
+		}, nil
+	}
 	deps, err := client.client.GetDependenciesDAG(ctx)
 	if err != nil {
 		return &libgrpc.DependencyDAGResponse{
@@ -319,3 +433,29 @@ func (s *server) GetDependenciesLinkedList(ctx context.Context, in *libgrpc.Serv
 		FileDagDep: fileDagDeps,
 	}, nil
 }
+
+func (s *server) Stats(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.StatsResponse, error) {
+	s.mutex.RLock()
+	client, ok := s.clients[in.Id]
+	s.mutex.RUnlock()
+	if !ok {
+		return &libgrpc.StatsResponse{
+			Successful: false,
+			Error:      fmt.Sprintf("unknown client %d", in.Id),
+		}, nil
+	}
+	stats := []*libgrpc.CapabilityStats{}
+	for _, c := range client.stats.stats(client.client) {
+		stats = append(stats, &libgrpc.CapabilityStats{
+			Capability:         c.Capability,
+			Evaluations:        c.Evaluations,
+			Errors:             c.Errors,
+			MeanLatencySeconds: c.MeanLatency.Seconds(),
+			CacheHits:          c.CacheHits,
+		})
+	}
+	return &libgrpc.StatsResponse{
+		Successful: true,
+		Stats:      stats,
+	}, nil
+}
@@ -0,0 +1,201 @@
+// Package server turns a Provider into an external provider: a gRPC
+// provider server with the flags the analyzer starts it with, logging,
+// health checks and a graceful shutdown, so that an external provider only
+// implements how it evaluates its capabilities.
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/redact"
+	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+// Provider is the analysis of a location by an external provider, a new one
+// is created for each init config of the provider settings.
+//
+// A Provider can also implement Stop() to release what it started, such as a
+// language server, provider.HealthChecker for the health checks of the
+// analyzer, provider.CacheHitCounter when it answers some evaluations from a
+// cache, and DAGProvider when it knows how the dependencies depend on each
+// other.
+type Provider interface {
+	// Capabilities are the conditions the provider can evaluate, it is called
+	// on a provider that is not initialized
+	Capabilities() []provider.Capability
+	Init(ctx context.Context, log logr.Logger, config provider.InitConfig) error
+	// Evaluate is only called with one of the capabilities, see
+	// DecodeCondition to read the condition
+	Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error)
+	GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error)
+}
+
+// DAGProvider is implemented by the providers that return the dependencies
+// with the dependencies they added, the other providers return their
+// dependencies as direct ones
+type DAGProvider interface {
+	GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error)
+}
+
+// Stopper is implemented by the providers that have something to stop once
+// the analysis is done
+type Stopper interface {
+	Stop()
+}
+
+// Main is the main function of an external provider. It parses the flags,
+// --port is given by the analyzer, and serves the provider until it gets
+// SIGINT or SIGTERM.
+func Main(name string, newProvider func() Provider) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	port := flags.Int("port", 0, "port to serve the provider on, the analyzer sets it when it starts the provider")
+	logLevel := flags.Int("log-level", 5, "level for logging output")
+	flags.Parse(os.Args[1:])
+
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(os.Stdout)
+	logrusLog.SetFormatter(&logrus.TextFormatter{})
+	logrusLog.SetLevel(logrus.Level(*logLevel))
+	log := redact.Logger(logrusr.New(logrusLog)).WithName(name)
+
+	if *port == 0 {
+		fmt.Fprintln(os.Stderr, "must pass in the port for the external provider")
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := Run(ctx, newProvider, *port, log); err != nil {
+		log.Error(err, "unable to serve the provider", "port", *port)
+		os.Exit(1)
+	}
+}
+
+// Run serves the provider on the port until the context is canceled, the
+// providers that were not stopped by the analyzer are stopped then.
+func Run(ctx context.Context, newProvider func() Provider, port int, log logr.Logger) error {
+	return provider.NewServer(NewBaseClient(newProvider), port, log).Start(ctx)
+}
+
+// NewBaseClient wraps the providers created by newProvider in a
+// provider.BaseClient, for provider.NewServer or to use them in process
+func NewBaseClient(newProvider func() Provider) provider.BaseClient {
+	return &baseClient{newProvider: newProvider}
+}
+
+type baseClient struct {
+	newProvider func() Provider
+}
+
+func (b *baseClient) Capabilities() []provider.Capability {
+	return b.newProvider().Capabilities()
+}
+
+func (b *baseClient) Init(ctx context.Context, log logr.Logger, config provider.InitConfig) (provider.ServiceClient, error) {
+	p := b.newProvider()
+	if err := p.Init(ctx, log, config); err != nil {
+		return nil, err
+	}
+	return &serviceClient{provider: p, capabilities: p.Capabilities()}, nil
+}
+
+type serviceClient struct {
+	provider     Provider
+	capabilities []provider.Capability
+}
+
+var _ provider.ServiceClient = &serviceClient{}
+var _ provider.HealthChecker = &serviceClient{}
+var _ provider.CacheHitCounter = &serviceClient{}
+
+func (s *serviceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	if !provider.HasCapability(s.capabilities, cap) {
+		names := []string{}
+		for _, c := range s.capabilities {
+			names = append(names, c.Name)
+		}
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("capability must be one of %v, not %s", names, cap)
+	}
+	return s.provider.Evaluate(ctx, cap, conditionInfo)
+}
+
+func (s *serviceClient) Stop() {
+	if stopper, ok := s.provider.(Stopper); ok {
+		stopper.Stop()
+	}
+}
+
+func (s *serviceClient) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	return s.provider.GetDependencies(ctx)
+}
+
+func (s *serviceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	if p, ok := s.provider.(DAGProvider); ok {
+		return p.GetDependenciesDAG(ctx)
+	}
+	deps, err := s.provider.GetDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dag := map[uri.URI][]provider.DepDAGItem{}
+	for u, list := range deps {
+		items := []provider.DepDAGItem{}
+		for _, d := range list {
+			if d != nil {
+				items = append(items, provider.DepDAGItem{Dep: *d})
+			}
+		}
+		dag[u] = items
+	}
+	return dag, nil
+}
+
+func (s *serviceClient) HealthCheck(ctx context.Context) error {
+	if checker, ok := s.provider.(provider.HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+func (s *serviceClient) CacheHits() map[string]int64 {
+	if counter, ok := s.provider.(provider.CacheHitCounter); ok {
+		return counter.CacheHits()
+	}
+	return nil
+}
+
+// DecodeCondition reads the condition of the capability into v, such as the
+// pattern of a `myprovider.referenced` condition for the `referenced`
+// capability, and returns the context the analyzer gave with it: the tags,
+// the templates of the chained conditions and the scope.
+func DecodeCondition(conditionInfo []byte, cap string, v interface{}) (provider.ProviderContext, error) {
+	providerContext := provider.ProviderContext{}
+	if err := yaml.Unmarshal(conditionInfo, &providerContext); err != nil {
+		return providerContext, fmt.Errorf("unable to get query info: %w", err)
+	}
+	conditions := map[string]interface{}{}
+	if err := yaml.Unmarshal(conditionInfo, &conditions); err != nil {
+		return providerContext, fmt.Errorf("unable to get query info: %w", err)
+	}
+	condition, ok := conditions[cap]
+	if !ok {
+		return providerContext, fmt.Errorf("condition has no %s", cap)
+	}
+	content, err := yaml.Marshal(condition)
+	if err != nil {
+		return providerContext, err
+	}
+	if err := yaml.Unmarshal(content, v); err != nil {
+		return providerContext, fmt.Errorf("unable to get the %s condition: %w", cap, err)
+	}
+	return providerContext, nil
+}
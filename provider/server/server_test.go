@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/grpc"
+	"github.com/phayes/freeport"
+	"go.lsp.dev/uri"
+)
+
+// fileProvider matches the files of the location that have a name
+type fileProvider struct {
+	location string
+	healthy  error
+}
+
+func (f *fileProvider) Capabilities() []provider.Capability {
+	return []provider.Capability{{
+		Name: "file",
+		IncidentVariables: provider.NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+			"name": openapi3.NewStringSchema(),
+		}),
+	}}
+}
+
+func (f *fileProvider) Init(ctx context.Context, log logr.Logger, config provider.InitConfig) error {
+	f.location = config.Location
+	return nil
+}
+
+func (f *fileProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	var condition struct {
+		Name string `yaml:"name"`
+	}
+	if _, err := DecodeCondition(conditionInfo, cap, &condition); err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	matches, err := filepath.Glob(filepath.Join(f.location, condition.Name))
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	response := provider.ProviderEvaluateResponse{Matched: len(matches) != 0}
+	for _, m := range matches {
+		response.Incidents = append(response.Incidents, provider.IncidentContext{FileURI: uri.File(m)})
+	}
+	return response, nil
+}
+
+func (f *fileProvider) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	return map[uri.URI][]*provider.Dep{
+		uri.File(filepath.Join(f.location, "deps.txt")): {{Name: "lib", Version: "1.0"}},
+	}, nil
+}
+
+func (f *fileProvider) HealthCheck(ctx context.Context) error {
+	return f.healthy
+}
+
+func TestRun(t *testing.T) {
+	location := t.TempDir()
+	if err := os.WriteFile(filepath.Join(location, "pom.xml"), []byte("<project/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var served *fileProvider
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, func() Provider {
+			served = &fileProvider{}
+			return served
+		}, port, logr.Discard())
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("unexpected error serving the provider: %v", err)
+		}
+	}()
+
+	client := grpc.NewGRPCClient(provider.Config{
+		Name:       "file",
+		Address:    fmt.Sprintf("localhost:%d", port),
+		InitConfig: []provider.InitConfig{{Location: location}},
+	}, logr.Discard())
+	if err := client.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	// the client connects before the server listens
+	var caps []provider.Capability
+	for i := 0; i < 50 && len(caps) == 0; i++ {
+		caps = client.Capabilities()
+	}
+	if len(caps) != 1 || caps[0].Name != "file" {
+		t.Fatalf("expected the file capability, got %+v", caps)
+	}
+	if names, ok := caps[0].IncidentVariableNames(); !ok || !reflect.DeepEqual(names, map[string]bool{"name": true}) {
+		t.Errorf("expected the incident variables of the capability, got %v", names)
+	}
+	if err := client.ProviderInit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Evaluate(ctx, "file", []byte("file:\n  name: '*.xml'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Matched || len(response.Incidents) != 1 || !strings.HasSuffix(string(response.Incidents[0].FileURI), "pom.xml") {
+		t.Errorf("expected pom.xml to match, got %+v", response)
+	}
+	if _, err := client.Evaluate(ctx, "referenced", []byte("referenced:\n  pattern: a\n")); err == nil {
+		t.Errorf("expected an error for an unknown capability")
+	}
+
+	deps, err := client.GetDependencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 {
+		t.Errorf("expected the dependencies of one file, got %+v", deps)
+	}
+	dag, err := client.GetDependenciesDAG(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, items := range dag {
+		if len(items) != 1 || items[0].Dep.Name != "lib" {
+			t.Errorf("expected lib as a direct dependency, got %+v", items)
+		}
+	}
+
+	if err := client.HealthCheck(ctx); err != nil {
+		t.Errorf("expected the provider to be healthy, got %v", err)
+	}
+	served.healthy = fmt.Errorf("language server exited")
+	if err := client.HealthCheck(ctx); err == nil {
+		t.Errorf("expected the provider to be unhealthy")
+	}
+}
+
+func TestDecodeCondition(t *testing.T) {
+	var condition struct {
+		Pattern string `yaml:"pattern"`
+	}
+	conditionInfo := []byte("referenced:\n  pattern: javax.*\ntags:\n  Java: true\n")
+	providerContext, err := DecodeCondition(conditionInfo, "referenced", &condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition.Pattern != "javax.*" || providerContext.Tags["Java"] != true {
+		t.Errorf("unexpected condition %+v and context %+v", condition, providerContext)
+	}
+	if _, err := DecodeCondition(conditionInfo, "dependency", &condition); err == nil {
+		t.Errorf("expected an error for a missing condition")
+	}
+}
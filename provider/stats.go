@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// CapabilityStats are the evaluations of the conditions of a capability by a
+// provider
+type CapabilityStats struct {
+	Capability  string
+	Evaluations int64
+	Errors      int64
+	// MeanLatency is the mean duration of the evaluations, as the provider
+	// measured it
+	MeanLatency time.Duration
+	// CacheHits are the evaluations the provider answered from a cache of
+	// its own
+	CacheHits int64
+}
+
+// StatsReporter is implemented by the providers that report the statistics of
+// the evaluations of their capabilities, such as the external providers.
+type StatsReporter interface {
+	Stats(ctx context.Context) ([]CapabilityStats, error)
+}
+
+// CacheHitCounter is implemented by the service clients that answer some of
+// the evaluations from a cache of their own, it returns the hits by capability.
+type CacheHitCounter interface {
+	CacheHits() map[string]int64
+}
+
+// MergeStats adds up the statistics of the capabilities of several service
+// clients of a provider, the mean latency is weighted by the evaluations. The
+// statistics are sorted by capability.
+func MergeStats(stats ...[]CapabilityStats) []CapabilityStats {
+	merged := map[string]*CapabilityStats{}
+	total := map[string]time.Duration{}
+	for _, list := range stats {
+		for _, s := range list {
+			m, ok := merged[s.Capability]
+			if !ok {
+				m = &CapabilityStats{Capability: s.Capability}
+				merged[s.Capability] = m
+			}
+			m.Evaluations += s.Evaluations
+			m.Errors += s.Errors
+			m.CacheHits += s.CacheHits
+			total[s.Capability] += s.MeanLatency * time.Duration(s.Evaluations)
+		}
+	}
+	result := []CapabilityStats{}
+	for name, m := range merged {
+		if m.Evaluations > 0 {
+			m.MeanLatency = total[name] / time.Duration(m.Evaluations)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Capability < result[j].Capability
+	})
+	return result
+}
+
+// FullStats returns the statistics of the evaluations of the providers that
+// report them, by provider name. It is called once the rules are evaluated
+// and before the providers are stopped, the providers that fail to report
+// their statistics are logged and left out.
+func FullStats(ctx context.Context, log logr.Logger, providers map[string]InternalProviderClient) map[string][]CapabilityStats {
+	stats := map[string][]CapabilityStats{}
+	for name, p := range providers {
+		reporter, ok := p.(StatsReporter)
+		if !ok {
+			continue
+		}
+		s, err := reporter.Stats(ctx)
+		if err != nil {
+			log.Error(err, "unable to get the stats of the provider", "provider", name)
+			continue
+		}
+		for _, c := range s {
+			log.V(3).Info("provider stats", "provider", name, "capability", c.Capability, "evaluations", c.Evaluations,
+				"errors", c.Errors, "meanLatency", c.MeanLatency.String(), "cacheHits", c.CacheHits)
+		}
+		stats[name] = s
+	}
+	return stats
+}
+
+// evaluationStats counts the evaluations of the capabilities of a service
+// client of the provider server
+type evaluationStats struct {
+	mutex        sync.Mutex
+	capabilities map[string]*capabilityCounters
+}
+
+type capabilityCounters struct {
+	evaluations int64
+	errors      int64
+	duration    time.Duration
+}
+
+func newEvaluationStats() *evaluationStats {
+	return &evaluationStats{capabilities: map[string]*capabilityCounters{}}
+}
+
+// record counts an evaluation of the capability that took the duration, it
+// is an error when err is not nil
+func (e *evaluationStats) record(cap string, duration time.Duration, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, ok := e.capabilities[cap]
+	if !ok {
+		c = &capabilityCounters{}
+		e.capabilities[cap] = c
+	}
+	c.evaluations++
+	c.duration += duration
+	if err != nil {
+		c.errors++
+	}
+}
+
+// stats returns the statistics of the evaluations sorted by capability, with
+// the cache hits of the client when it counts them
+func (e *evaluationStats) stats(client ServiceClient) []CapabilityStats {
+	hits := map[string]int64{}
+	if counter, ok := client.(CacheHitCounter); ok {
+		hits = counter.CacheHits()
+	}
+	e.mutex.Lock()
+	stats := []CapabilityStats{}
+	for name, c := range e.capabilities {
+		stats = append(stats, CapabilityStats{
+			Capability:  name,
+			Evaluations: c.evaluations,
+			Errors:      c.errors,
+			MeanLatency: c.duration / time.Duration(c.evaluations),
+			CacheHits:   hits[name],
+		})
+	}
+	e.mutex.Unlock()
+	return MergeStats(stats)
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	libgrpc "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+)
+
+// hitCountingClient fails the evaluations of the failing capability and counts
+// cache hits of its own
+type hitCountingClient struct {
+	*fakeClient
+	hits map[string]int64
+}
+
+func (c *hitCountingClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	if cap == "failing" {
+		return ProviderEvaluateResponse{}, errors.New("failed")
+	}
+	return ProviderEvaluateResponse{Matched: true}, nil
+}
+
+func (c *hitCountingClient) CacheHits() map[string]int64 {
+	return c.hits
+}
+
+func TestMergeStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats [][]CapabilityStats
+		want  []CapabilityStats
+	}{
+		{
+			name: "no stats",
+			want: []CapabilityStats{},
+		},
+		{
+			name: "the stats of the clients are added up by capability",
+			stats: [][]CapabilityStats{
+				{
+					{Capability: "referenced", Evaluations: 1, MeanLatency: 4 * time.Second, CacheHits: 1},
+					{Capability: "dependency", Evaluations: 2, Errors: 1, MeanLatency: time.Second},
+				},
+				{
+					{Capability: "referenced", Evaluations: 3, Errors: 2, MeanLatency: 2 * time.Second, CacheHits: 2},
+				},
+			},
+			want: []CapabilityStats{
+				{Capability: "dependency", Evaluations: 2, Errors: 1, MeanLatency: time.Second},
+				{Capability: "referenced", Evaluations: 4, Errors: 2, MeanLatency: 2500 * time.Millisecond, CacheHits: 3},
+			},
+		},
+		{
+			name: "a capability without evaluations has no latency",
+			stats: [][]CapabilityStats{
+				{{Capability: "referenced"}},
+			},
+			want: []CapabilityStats{{Capability: "referenced"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeStats(tt.stats...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeStats() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	client := &hitCountingClient{fakeClient: &fakeClient{}, hits: map[string]int64{"referenced": 1}}
+	s := &server{clients: map[int64]clientMapItem{
+		1: {ctx: context.Background(), client: client, stats: newEvaluationStats()},
+	}}
+	ctx := context.Background()
+	for _, cap := range []string{"referenced", "referenced", "failing"} {
+		if _, err := s.Evaluate(ctx, &libgrpc.EvaluateRequest{Id: 1, Cap: cap}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := s.Stats(ctx, &libgrpc.ServiceRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Successful {
+		t.Fatalf("unexpected error %s", r.Error)
+	}
+	if len(r.Stats) != 2 {
+		t.Fatalf("expected the stats of 2 capabilities, got %v", r.Stats)
+	}
+	failing, referenced := r.Stats[0], r.Stats[1]
+	if failing.Capability != "failing" || failing.Evaluations != 1 || failing.Errors != 1 || failing.CacheHits != 0 {
+		t.Errorf("unexpected stats of the failing capability %v", failing)
+	}
+	if referenced.Capability != "referenced" || referenced.Evaluations != 2 || referenced.Errors != 0 || referenced.CacheHits != 1 {
+		t.Errorf("unexpected stats of the referenced capability %v", referenced)
+	}
+
+	r, err = s.Stats(ctx, &libgrpc.ServiceRequest{Id: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Successful {
+		t.Errorf("expected the stats of an unknown client to fail")
+	}
+}
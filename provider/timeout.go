@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CallTimeouts bound how long each condition query sent to a provider can
+// take, so that a request that is stuck fails its condition instead of
+// holding the rule until the analysis ends.
+type CallTimeouts struct {
+	// Default is the timeout of the capabilities that do not have their own,
+	// such as 2m
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+	// Capabilities are the timeouts by capability, such as referenced: 5m
+	Capabilities map[string]string `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
+}
+
+func (t *CallTimeouts) Validate() error {
+	if _, err := parseDuration("default timeout", t.Default); err != nil {
+		return err
+	}
+	for cap, timeout := range t.Capabilities {
+		if _, err := parseDuration(fmt.Sprintf("timeout of %s", cap), timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeout returns the timeout of a capability, zero when it has none
+func (t *CallTimeouts) timeout(cap string) time.Duration {
+	if timeout, ok := t.Capabilities[cap]; ok {
+		d, _ := parseDuration("timeout", timeout)
+		return d
+	}
+	d, _ := parseDuration("timeout", t.Default)
+	return d
+}
+
+// CallTimeoutError is the error of a query that the provider did not answer
+// in time. It wraps context.DeadlineExceeded, so that the engine can tell it
+// apart from the other errors of the conditions.
+type CallTimeoutError struct {
+	Provider   string
+	Capability string
+	Timeout    time.Duration
+}
+
+func (e *CallTimeoutError) Error() string {
+	return fmt.Sprintf("provider %s did not answer the %s query within %s", e.Provider, e.Capability, e.Timeout)
+}
+
+func (e *CallTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+type timeoutClient struct {
+	InternalProviderClient
+	name     string
+	timeouts CallTimeouts
+}
+
+// WithCallTimeouts returns a client that stops waiting for the queries that
+// take longer than their timeout. The query is canceled, but the response is
+// not waited for when the provider does not stop on the cancellation.
+func WithCallTimeouts(name string, client InternalProviderClient, timeouts CallTimeouts) InternalProviderClient {
+	return &timeoutClient{
+		InternalProviderClient: client,
+		name:                   name,
+		timeouts:               timeouts,
+	}
+}
+
+func (c *timeoutClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	timeout := c.timeouts.timeout(cap)
+	if timeout == 0 {
+		return c.InternalProviderClient.Evaluate(ctx, cap, conditionInfo)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		resp ProviderEvaluateResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := c.InternalProviderClient.Evaluate(callCtx, cap, conditionInfo)
+		done <- result{resp, err}
+	}()
+
+	timeoutErr := &CallTimeoutError{Provider: c.name, Capability: cap, Timeout: timeout}
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+			return r.resp, timeoutErr
+		}
+		return r.resp, r.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return ProviderEvaluateResponse{}, ctx.Err()
+		}
+		return ProviderEvaluateResponse{}, timeoutErr
+	}
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowProvider answers after a delay, ignoring the cancellation when stuck is
+// set, as a language server that does not answer would
+type slowProvider struct {
+	*fakeHealthProvider
+	delay time.Duration
+	stuck bool
+}
+
+func (p slowProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	if p.stuck {
+		time.Sleep(p.delay)
+		return ProviderEvaluateResponse{Matched: true}, nil
+	}
+	select {
+	case <-time.After(p.delay):
+		return ProviderEvaluateResponse{Matched: true}, nil
+	case <-ctx.Done():
+		return ProviderEvaluateResponse{}, ctx.Err()
+	}
+}
+
+func TestCallTimeouts(t *testing.T) {
+	timeouts := CallTimeouts{
+		Default:      "50ms",
+		Capabilities: map[string]string{"referenced": "1s"},
+	}
+	tests := []struct {
+		name        string
+		cap         string
+		delay       time.Duration
+		stuck       bool
+		wantMatched bool
+		wantTimeout bool
+	}{
+		{
+			name:        "answered in time",
+			cap:         "file",
+			delay:       time.Millisecond,
+			wantMatched: true,
+		},
+		{
+			name:        "default timeout",
+			cap:         "file",
+			delay:       time.Second,
+			wantTimeout: true,
+		},
+		{
+			name:        "provider that does not stop on the cancellation",
+			cap:         "file",
+			delay:       time.Second,
+			stuck:       true,
+			wantTimeout: true,
+		},
+		{
+			name:        "capability timeout",
+			cap:         "referenced",
+			delay:       100 * time.Millisecond,
+			wantMatched: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := slowProvider{fakeHealthProvider: &fakeHealthProvider{}, delay: tt.delay, stuck: tt.stuck}
+			client := WithCallTimeouts("test", p, timeouts)
+			start := time.Now()
+			resp, err := client.Evaluate(context.TODO(), tt.cap, nil)
+			if tt.wantTimeout {
+				var timeoutErr *CallTimeoutError
+				if !errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected a call timeout, got %v", err)
+				}
+				if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+					t.Errorf("expected the call to stop at its timeout, it took %s", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if resp.Matched != tt.wantMatched {
+				t.Errorf("expected matched %v, got %v", tt.wantMatched, resp.Matched)
+			}
+		})
+	}
+}
+
+func TestCallTimeoutsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts CallTimeouts
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			timeouts: CallTimeouts{Default: "2m", Capabilities: map[string]string{"referenced": "5m"}},
+		},
+		{
+			name:     "invalid default",
+			timeouts: CallTimeouts{Default: "soon"},
+			wantErr:  true,
+		},
+		{
+			name:     "negative capability timeout",
+			timeouts: CallTimeouts{Capabilities: map[string]string{"referenced": "-1s"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.timeouts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package tooling
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// installedFile is written in the directory of a tool once it is installed,
+// with the spec it was installed from.
+const installedFile = ".installed"
+
+// installMutex serializes the installs, so that analyses running at the same
+// time do not install the same language server at once.
+var installMutex sync.Mutex
+
+// Spec pins the version of a language server the analyzer installs for a
+// provider.
+type Spec struct {
+	// Name is the language server, one of jdtls, gopls and pylsp, or any other
+	// name for an archive given with a URL
+	Name    string `yaml:"name,omitempty" json:"name,omitempty"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// URL is the archive to download, a .tar.gz, a .zip or the binary itself.
+	// For pylsp, it is an optional wheel to install instead of the version
+	// from the package index.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// SHA256 is the checksum of the file at the URL
+	SHA256 string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	// Binary is the path of the language server in the archive, it defaults
+	// to the one of the known language servers.
+	Binary string `yaml:"binary,omitempty" json:"binary,omitempty"`
+}
+
+type installer int
+
+const (
+	archiveInstaller installer = iota
+	goInstaller
+	pipInstaller
+)
+
+type tool struct {
+	installer installer
+	// pkg is the go module or python package of the tool
+	pkg    string
+	binary string
+}
+
+var tools = map[string]tool{
+	"jdtls": {installer: archiveInstaller, binary: filepath.Join("bin", "jdtls")},
+	"gopls": {installer: goInstaller, pkg: "golang.org/x/tools/gopls", binary: "gopls"},
+	"pylsp": {installer: pipInstaller, pkg: "python-lsp-server", binary: filepath.Join("bin", "pylsp")},
+}
+
+func (s *Spec) tool() tool {
+	t, ok := tools[s.Name]
+	if !ok {
+		t = tool{installer: archiveInstaller}
+	}
+	if s.Binary != "" {
+		t.binary = s.Binary
+	}
+	return t
+}
+
+// Validate checks that the spec pins a version that can be verified
+func (s *Spec) Validate() error {
+	if s.Name == "" || s.Version == "" {
+		return fmt.Errorf("a language server needs a name and a version")
+	}
+	for _, v := range []string{s.Name, s.Version} {
+		if v == "." || v == ".." || strings.ContainsAny(v, `/\`) {
+			return fmt.Errorf("invalid language server %s %s", s.Name, s.Version)
+		}
+	}
+	t := s.tool()
+	if s.SHA256 != "" {
+		if sum, err := hex.DecodeString(s.SHA256); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("invalid sha256 %q of language server %s", s.SHA256, s.Name)
+		}
+	}
+	switch t.installer {
+	case archiveInstaller:
+		if s.URL == "" || s.SHA256 == "" {
+			return fmt.Errorf("language server %s needs a url and its sha256", s.Name)
+		}
+		if t.binary == "" {
+			return fmt.Errorf("language server %s needs the path of its binary in the archive", s.Name)
+		}
+	case goInstaller:
+		// the go command verifies the modules with the checksum database
+		if s.URL != "" || s.SHA256 != "" {
+			return fmt.Errorf("language server %s is installed with go install, it does not take a url", s.Name)
+		}
+	case pipInstaller:
+		if (s.URL == "") != (s.SHA256 == "") {
+			return fmt.Errorf("language server %s needs both a url and its sha256, or neither", s.Name)
+		}
+	}
+	return nil
+}
+
+// Manager installs the language servers in a cache directory, a version is
+// only downloaded the first time it is used.
+type Manager struct {
+	dir    string
+	log    logr.Logger
+	client *http.Client
+	// run runs the go and pip commands
+	run func(ctx context.Context, env []string, name string, args ...string) error
+}
+
+// DefaultDir is the cache directory of the user for the language servers
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "konveyor", "language-servers")
+}
+
+func NewManager(dir string, log logr.Logger) *Manager {
+	return &Manager{
+		dir:    dir,
+		log:    log.WithName("tooling"),
+		client: http.DefaultClient,
+		run:    run,
+	}
+}
+
+func run(ctx context.Context, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Install installs the language server when it is not in the cache yet and
+// returns the path of its binary.
+func (m *Manager) Install(ctx context.Context, spec Spec) (string, error) {
+	if err := spec.Validate(); err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(filepath.Join(m.dir, spec.Name, spec.Version))
+	if err != nil {
+		return "", err
+	}
+	installMutex.Lock()
+	defer installMutex.Unlock()
+	t := spec.tool()
+	binary := filepath.Join(dir, t.binary)
+	marker, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	if installed, err := os.ReadFile(filepath.Join(dir, installedFile)); err == nil && string(installed) == string(marker) {
+		return binary, nil
+	}
+
+	m.log.Info("installing language server", "name", spec.Name, "version", spec.Version, "dir", dir)
+	// a partial or different install is started over, the install is done in
+	// place because virtual environments can not be moved
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	switch t.installer {
+	case archiveInstaller:
+		err = m.installArchive(ctx, spec, dir, t)
+	case goInstaller:
+		err = m.run(ctx, []string{"GOBIN=" + dir}, "go", "install", fmt.Sprintf("%s@%s", t.pkg, goVersion(spec.Version)))
+	case pipInstaller:
+		err = m.installPip(ctx, spec, dir, t)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to install language server %s %s: %w", spec.Name, spec.Version, err)
+	}
+	info, err := os.Stat(binary)
+	if err != nil {
+		return "", fmt.Errorf("language server %s %s has no binary %s", spec.Name, spec.Version, t.binary)
+	}
+	// archives do not always keep the modes of their files
+	if err := os.Chmod(binary, info.Mode().Perm()|0111); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, installedFile), marker, 0644); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+func goVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func (m *Manager) installArchive(ctx context.Context, spec Spec, dir string, t tool) error {
+	file, err := m.download(ctx, spec, dir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	url := strings.ToLower(spec.URL)
+	switch {
+	case strings.HasSuffix(url, ".tar.gz") || strings.HasSuffix(url, ".tgz"):
+		return extractTarGz(file, dir)
+	case strings.HasSuffix(url, ".zip"):
+		return extractZip(file, dir)
+	}
+	binary := filepath.Join(dir, t.binary)
+	if err := os.MkdirAll(filepath.Dir(binary), 0755); err != nil {
+		return err
+	}
+	return os.Rename(file, binary)
+}
+
+func (m *Manager) installPip(ctx context.Context, spec Spec, dir string, t tool) error {
+	if err := m.run(ctx, nil, "python3", "-m", "venv", dir); err != nil {
+		return err
+	}
+	pip := filepath.Join(dir, "bin", "pip")
+	if spec.URL == "" {
+		return m.run(ctx, nil, pip, "install", fmt.Sprintf("%s==%s", t.pkg, spec.Version))
+	}
+	file, err := m.download(ctx, spec, dir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	// pip finds out what the file is from its name
+	named := filepath.Join(dir, filepath.Base(spec.URL))
+	if err := os.Rename(file, named); err != nil {
+		return err
+	}
+	defer os.Remove(named)
+	return m.run(ctx, nil, pip, "install", named)
+}
+
+// download saves the file at the url of the spec in the directory and checks
+// its checksum.
+func (m *Manager) download(ctx context.Context, spec Spec, dir string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download %s: %s", spec.URL, resp.Status)
+	}
+	f, err := os.CreateTemp(dir, ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, spec.SHA256) {
+		os.Remove(f.Name())
+		return "", &ChecksumError{URL: spec.URL, Expected: spec.SHA256, Actual: sum}
+	}
+	return f.Name(), nil
+}
+
+// ChecksumError is the error of a download that does not have the checksum
+// of its spec.
+type ChecksumError struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum of %s is %s, expected %s", e.URL, e.Actual, e.Expected)
+}
+
+// IsChecksumError returns whether a language server was not installed because
+// its download did not match its checksum.
+func IsChecksumError(err error) bool {
+	var checksumErr *ChecksumError
+	return errors.As(err, &checksumErr)
+}
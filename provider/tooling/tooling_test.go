@@ -0,0 +1,233 @@
+package tooling
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipFile(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallArchive(t *testing.T) {
+	files := map[string][]byte{
+		"/jdtls.tar.gz": tarGz(t, map[string]string{"bin/jdtls": "jdtls", "plugins/a.jar": "a"}),
+		"/server.zip":   zipFile(t, map[string]string{"server/run": "run"}),
+		"/server":       []byte("binary"),
+		"/escape.zip":   zipFile(t, map[string]string{"../escape": "escape"}),
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		spec      Spec
+		binary    string
+		content   string
+		shouldErr bool
+		checksum  bool
+	}{
+		{
+			name:    "tar.gz",
+			spec:    Spec{Name: "jdtls", Version: "1.29.0", URL: server.URL + "/jdtls.tar.gz", SHA256: checksum(files["/jdtls.tar.gz"])},
+			binary:  filepath.Join("jdtls", "1.29.0", "bin", "jdtls"),
+			content: "jdtls",
+		},
+		{
+			name:    "zip",
+			spec:    Spec{Name: "server", Version: "1.0", URL: server.URL + "/server.zip", SHA256: checksum(files["/server.zip"]), Binary: "server/run"},
+			binary:  filepath.Join("server", "1.0", "server", "run"),
+			content: "run",
+		},
+		{
+			name:    "binary",
+			spec:    Spec{Name: "server", Version: "2.0", URL: server.URL + "/server", SHA256: checksum(files["/server"]), Binary: "server"},
+			binary:  filepath.Join("server", "2.0", "server"),
+			content: "binary",
+		},
+		{
+			name:      "checksum mismatch",
+			spec:      Spec{Name: "jdtls", Version: "1.30.0", URL: server.URL + "/jdtls.tar.gz", SHA256: checksum([]byte("other"))},
+			shouldErr: true,
+			checksum:  true,
+		},
+		{
+			name:      "entry outside of the directory",
+			spec:      Spec{Name: "escape", Version: "1.0", URL: server.URL + "/escape.zip", SHA256: checksum(files["/escape.zip"]), Binary: "escape"},
+			shouldErr: true,
+		},
+		{
+			name:      "not found",
+			spec:      Spec{Name: "jdtls", Version: "0.1", URL: server.URL + "/missing.tar.gz", SHA256: checksum(nil)},
+			shouldErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := NewManager(dir, logr.Discard())
+			path, err := m.Install(context.Background(), tt.spec)
+			if tt.shouldErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				if IsChecksumError(err) != tt.checksum {
+					t.Errorf("unexpected checksum error %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "escape", "escape")); err == nil {
+					t.Errorf("expected the archive not to write outside of the directory")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if path != filepath.Join(dir, tt.binary) {
+				t.Errorf("expected binary %s, got %s", filepath.Join(dir, tt.binary), path)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil || string(content) != tt.content {
+				t.Errorf("expected binary content %q, got %q: %v", tt.content, content, err)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0100 == 0 {
+				t.Errorf("expected the binary to be executable")
+			}
+
+			// the cached install is used without downloading it again
+			before := requests
+			if again, err := m.Install(context.Background(), tt.spec); err != nil || again != path {
+				t.Errorf("expected the cached install, got %s: %v", again, err)
+			}
+			if requests != before {
+				t.Errorf("expected no download of a cached install")
+			}
+		})
+	}
+}
+
+func TestInstallCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		want []string
+	}{
+		{
+			name: "gopls",
+			spec: Spec{Name: "gopls", Version: "0.14.2"},
+			want: []string{"GOBIN=<dir> go install golang.org/x/tools/gopls@v0.14.2"},
+		},
+		{
+			name: "pylsp",
+			spec: Spec{Name: "pylsp", Version: "1.9.0"},
+			want: []string{
+				"python3 -m venv <dir>",
+				"<dir>/bin/pip install python-lsp-server==1.9.0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := NewManager(dir, logr.Discard())
+			installDir := filepath.Join(dir, tt.spec.Name, tt.spec.Version)
+			got := []string{}
+			m.run = func(ctx context.Context, env []string, name string, args ...string) error {
+				got = append(got, strings.ReplaceAll(strings.Join(append(append(env, name), args...), " "), installDir, "<dir>"))
+				// the commands would install the binary
+				binary := filepath.Join(installDir, tt.spec.tool().binary)
+				os.MkdirAll(filepath.Dir(binary), 0755)
+				return os.WriteFile(binary, nil, 0755)
+			}
+			path, err := m.Install(context.Background(), tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected commands %v, got %v", tt.want, got)
+			}
+			if path != filepath.Join(installDir, tt.spec.tool().binary) {
+				t.Errorf("unexpected binary %s", path)
+			}
+		})
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	sum := checksum(nil)
+	tests := []struct {
+		name      string
+		spec      Spec
+		shouldErr bool
+	}{
+		{name: "gopls", spec: Spec{Name: "gopls", Version: "v0.14.2"}},
+		{name: "gopls with a url", spec: Spec{Name: "gopls", Version: "v0.14.2", URL: "https://example.com/gopls", SHA256: sum}, shouldErr: true},
+		{name: "pylsp wheel", spec: Spec{Name: "pylsp", Version: "1.9.0", URL: "https://example.com/pylsp.whl", SHA256: sum}},
+		{name: "pylsp without checksum", spec: Spec{Name: "pylsp", Version: "1.9.0", URL: "https://example.com/pylsp.whl"}, shouldErr: true},
+		{name: "jdtls without checksum", spec: Spec{Name: "jdtls", Version: "1.29.0", URL: "https://example.com/jdtls.tar.gz"}, shouldErr: true},
+		{name: "invalid checksum", spec: Spec{Name: "jdtls", Version: "1.29.0", URL: "https://example.com/jdtls.tar.gz", SHA256: "abc"}, shouldErr: true},
+		{name: "unknown without binary", spec: Spec{Name: "other", Version: "1", URL: "https://example.com/other.zip", SHA256: sum}, shouldErr: true},
+		{name: "version with a path", spec: Spec{Name: "gopls", Version: "../v1"}, shouldErr: true},
+		{name: "no version", spec: Spec{Name: "gopls"}, shouldErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("expected error %v, got %v", tt.shouldErr, err)
+			}
+		})
+	}
+}
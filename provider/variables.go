@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"encoding/json"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DependencyIncidentVariables are the variables of the incidents of the
+// dependency conditions
+var DependencyIncidentVariables = NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+	"name":    WithDescription(openapi3.NewStringSchema(), "Name of the dependency"),
+	"version": WithDescription(openapi3.NewStringSchema(), "Version of the dependency"),
+	"type":    WithDescription(openapi3.NewStringSchema(), "Type of the dependency, when its version is not checked"),
+})
+
+// NewIncidentVariablesSchema returns the schema of the variables of the
+// incidents of a capability, for the providers to declare them in their
+// capabilities. The incidents only have the given variables, the messages of
+// the rules and the chained conditions that use other ones fail to load.
+func NewIncidentVariablesSchema(variables map[string]*openapi3.Schema) openapi3.SchemaRef {
+	return openapi3.SchemaRef{Value: NewConfigSchema(variables)}
+}
+
+// IncidentVariableNames returns the names of the variables the incidents of
+// the capability have. It returns false when the capability does not declare
+// them, or when its incidents can have other variables, such as the capture
+// groups of a pattern.
+func (c Capability) IncidentVariableNames() (map[string]bool, bool) {
+	return schemaProperties(c.IncidentVariables)
+}
+
+func schemaProperties(ref openapi3.SchemaRef) (map[string]bool, bool) {
+	if ref.Value == nil {
+		return nil, false
+	}
+	// additionalProperties is a different field in the versions of
+	// kin-openapi the providers are built with, it is read from its json
+	content, err := json.Marshal(ref.Value)
+	if err != nil {
+		return nil, false
+	}
+	schema := struct {
+		AdditionalProperties interface{} `json:"additionalProperties"`
+	}{}
+	if err := json.Unmarshal(content, &schema); err != nil || schema.AdditionalProperties != false {
+		return nil, false
+	}
+	names := map[string]bool{}
+	for name := range ref.Value.Properties {
+		names[name] = true
+	}
+	return names, true
+}
+
+// schemaStruct converts a schema to the struct it is sent as over gRPC, nil
+// when there is no schema
+func schemaStruct(ref openapi3.SchemaRef) (*structpb.Struct, error) {
+	if ref.Value == nil {
+		return nil, nil
+	}
+	content, err := json.Marshal(ref.Value)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+// SchemaFromStruct converts the struct a schema is sent as over gRPC back to
+// the schema
+func SchemaFromStruct(s *structpb.Struct) (openapi3.SchemaRef, error) {
+	if s == nil {
+		return openapi3.SchemaRef{}, nil
+	}
+	content, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return openapi3.SchemaRef{}, err
+	}
+	schema := &openapi3.Schema{}
+	if err := json.Unmarshal(content, schema); err != nil {
+		return openapi3.SchemaRef{}, err
+	}
+	return openapi3.SchemaRef{Value: schema}, nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestIncidentVariableNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    openapi3.SchemaRef
+		wantNames map[string]bool
+		wantOk    bool
+	}{
+		{
+			name: "not declared",
+		},
+		{
+			name: "declared",
+			schema: NewIncidentVariablesSchema(map[string]*openapi3.Schema{
+				"name":    openapi3.NewStringSchema(),
+				"version": openapi3.NewStringSchema(),
+			}),
+			wantNames: map[string]bool{"name": true, "version": true},
+			wantOk:    true,
+		},
+		{
+			name:      "no variables",
+			schema:    NewIncidentVariablesSchema(nil),
+			wantNames: map[string]bool{},
+			wantOk:    true,
+		},
+		{
+			name: "other variables",
+			schema: openapi3.SchemaRef{
+				Value: openapi3.NewObjectSchema().WithAnyAdditionalProperties().WithProperty("kind", openapi3.NewStringSchema()),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, schema := range []string{"capability", "grpc"} {
+				ref := tt.schema
+				if schema == "grpc" {
+					s, err := schemaStruct(tt.schema)
+					if err != nil {
+						t.Fatal(err)
+					}
+					ref, err = SchemaFromStruct(s)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				names, ok := Capability{IncidentVariables: ref}.IncidentVariableNames()
+				if ok != tt.wantOk || !reflect.DeepEqual(names, tt.wantNames) {
+					t.Errorf("%s: expected %v %v, got %v %v", schema, tt.wantNames, tt.wantOk, names, ok)
+				}
+			}
+		})
+	}
+}
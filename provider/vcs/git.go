@@ -0,0 +1,46 @@
+package vcs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type git struct{}
+
+func (git) Name() string { return "git" }
+
+// Marker is a directory, or a file in the worktrees and submodules
+func (git) Marker() string { return ".git" }
+
+func (git) Revision(ctx context.Context, root string) (konveyor.Revision, error) {
+	revision := konveyor.Revision{}
+	out, err := run(ctx, root, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return revision, err
+	}
+	revision.Revision = strings.TrimSpace(out)
+	// HEAD is detached when it is not on a branch
+	if out, err := run(ctx, root, "git", "rev-parse", "--abbrev-ref", "HEAD"); err == nil && strings.TrimSpace(out) != "HEAD" {
+		revision.Branch = strings.TrimSpace(out)
+	}
+	// there is no remote in a repository that was not cloned
+	if out, err := run(ctx, root, "git", "config", "--get", "remote.origin.url"); err == nil {
+		revision.Remote = strings.TrimSpace(out)
+	}
+	out, err = run(ctx, root, "git", "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return revision, err
+	}
+	revision.Modified = strings.TrimSpace(out) != ""
+	return revision, nil
+}
+
+func (git) Ignored(ctx context.Context, root string) ([]string, error) {
+	out, err := run(ctx, root, "git", "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
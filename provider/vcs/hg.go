@@ -0,0 +1,53 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type hg struct{}
+
+func (hg) Name() string { return "hg" }
+
+func (hg) Marker() string { return ".hg" }
+
+func (hg) Revision(ctx context.Context, root string) (konveyor.Revision, error) {
+	out, err := run(ctx, root, "hg", "identify", "--debug", "--id", "--branch")
+	if err != nil {
+		return konveyor.Revision{}, err
+	}
+	revision, err := parseHgIdentify(out)
+	if err != nil {
+		return revision, err
+	}
+	// there is no default path in a repository that was not cloned
+	if out, err := run(ctx, root, "hg", "paths", "default"); err == nil {
+		revision.Remote = strings.TrimSpace(out)
+	}
+	return revision, nil
+}
+
+// parseHgIdentify reads the node and branch of hg identify, the node ends
+// with a "+" when the working copy is modified
+func parseHgIdentify(out string) (konveyor.Revision, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return konveyor.Revision{}, fmt.Errorf("unexpected output of hg identify: %q", out)
+	}
+	return konveyor.Revision{
+		Revision: strings.TrimSuffix(fields[0], "+"),
+		Branch:   fields[1],
+		Modified: strings.HasSuffix(fields[0], "+"),
+	}, nil
+}
+
+func (hg) Ignored(ctx context.Context, root string) ([]string, error) {
+	out, err := run(ctx, root, "hg", "status", "--ignored", "--no-status", "--print0")
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
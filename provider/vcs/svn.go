@@ -0,0 +1,75 @@
+package vcs
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type svn struct{}
+
+func (svn) Name() string { return "svn" }
+
+// Marker is only at the root of the working copies since subversion 1.7
+func (svn) Marker() string { return ".svn" }
+
+func (svn) Revision(ctx context.Context, root string) (konveyor.Revision, error) {
+	out, err := run(ctx, root, "svn", "info")
+	if err != nil {
+		return konveyor.Revision{}, err
+	}
+	revision := parseSVNInfo(out)
+	out, err = run(ctx, root, "svn", "status", "--quiet")
+	if err != nil {
+		return revision, err
+	}
+	revision.Modified = strings.TrimSpace(out) != ""
+	return revision, nil
+}
+
+// parseSVNInfo reads the revision of the output of svn info, the relative URL
+// is the branch, such as ^/branches/1.x
+func parseSVNInfo(out string) konveyor.Revision {
+	revision := konveyor.Revision{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Revision":
+			revision.Revision = value
+		case "URL":
+			revision.Remote = value
+		case "Relative URL":
+			revision.Branch = value
+		}
+	}
+	return revision
+}
+
+func (svn) Ignored(ctx context.Context, root string) ([]string, error) {
+	out, err := run(ctx, root, "svn", "status", "--no-ignore")
+	if err != nil {
+		return nil, err
+	}
+	return parseSVNIgnored(out), nil
+}
+
+// parseSVNIgnored returns the paths of svn status that have the ignored
+// status in the first column
+func parseSVNIgnored(out string) []string {
+	ignored := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "I") {
+			continue
+		}
+		if path := strings.TrimSpace(line[1:]); path != "" {
+			ignored = append(ignored, filepath.FromSlash(path))
+		}
+	}
+	return ignored
+}
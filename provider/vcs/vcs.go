@@ -0,0 +1,163 @@
+// Package vcs finds the working copies of version control systems that the
+// analyzed locations are in, to record their revision in the output and to
+// leave the files they ignore out of the analysis.
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// System is a version control system, git, svn and hg are registered by
+// default.
+type System interface {
+	Name() string
+	// Marker is the name of the metadata directory, or file, at the root of
+	// the working copies
+	Marker() string
+	// Revision returns the revision of the working copy at the root
+	Revision(ctx context.Context, root string) (konveyor.Revision, error)
+	// Ignored returns the files and directories that the system ignores in
+	// the working copy, relative to the root
+	Ignored(ctx context.Context, root string) ([]string, error)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]System{}
+)
+
+func init() {
+	Register(git{})
+	Register(svn{})
+	Register(hg{})
+}
+
+// Register makes a version control system available to Detect, registering a
+// system with the name of an existing one replaces it.
+func Register(s System) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[s.Name()] = s
+}
+
+// Systems returns the registered systems sorted by name
+func Systems() []System {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	systems := []System{}
+	for _, s := range registry {
+		systems = append(systems, s)
+	}
+	sort.Slice(systems, func(i, j int) bool {
+		return systems[i].Name() < systems[j].Name()
+	})
+	return systems
+}
+
+// WorkingCopy is a directory under version control
+type WorkingCopy struct {
+	System System
+	Root   string
+}
+
+// Detect returns the working copy the path is in, the closest one when they
+// are nested, or nil when it is not under version control
+func Detect(path string) (*WorkingCopy, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	systems := Systems()
+	for {
+		for _, s := range systems {
+			if _, err := os.Stat(filepath.Join(dir, s.Marker())); err == nil {
+				return &WorkingCopy{System: s, Root: dir}, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Revision returns the revision of the working copy
+func (w *WorkingCopy) Revision(ctx context.Context) (konveyor.Revision, error) {
+	revision, err := w.System.Revision(ctx, w.Root)
+	if err != nil {
+		return revision, err
+	}
+	revision.System = w.System.Name()
+	revision.Root = w.Root
+	return revision, nil
+}
+
+// ExcludeGlobs returns the scope globs, relative to the location, of the
+// files the system ignores in the location and of the metadata directory. The
+// ignored files with a glob character in their path are left out.
+func (w *WorkingCopy) ExcludeGlobs(ctx context.Context, location string) ([]string, error) {
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	ignored, err := w.System.Ignored(ctx, w.Root)
+	if err != nil {
+		return nil, err
+	}
+	globs := []string{w.System.Marker()}
+	for _, path := range ignored {
+		rel, err := filepath.Rel(location, filepath.Join(w.Root, path))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.ContainsAny(rel, "*?") {
+			continue
+		}
+		// only the ignored path itself, not the paths that end like it
+		globs = append(globs, "/"+rel)
+	}
+	return globs, nil
+}
+
+// run runs the command of a system in the working copy and returns its output
+func run(ctx context.Context, dir string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// splitNull splits the output of a command that separates the paths with NUL
+// bytes
+func splitNull(out string) []string {
+	paths := []string{}
+	for _, p := range strings.Split(out, "\x00") {
+		p = strings.TrimSuffix(strings.TrimSpace(p), "/")
+		if p != "" {
+			paths = append(paths, filepath.FromSlash(p))
+		}
+	}
+	return paths
+}
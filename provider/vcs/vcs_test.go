@@ -0,0 +1,222 @@
+package vcs
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"svn/.svn", "svn/trunk/src", "svn/trunk/hg/.hg", "svn/trunk/hg/src", "plain"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		path       string
+		wantSystem string
+		wantRoot   string
+	}{
+		{path: "svn/trunk/src", wantSystem: "svn", wantRoot: "svn"},
+		{path: "svn", wantSystem: "svn", wantRoot: "svn"},
+		{path: "svn/trunk/hg/src", wantSystem: "hg", wantRoot: "svn/trunk/hg"},
+		{path: "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			wc, err := Detect(filepath.Join(dir, tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSystem == "" {
+				// the temporary directory can be in a working copy
+				if wc != nil && strings.HasPrefix(wc.Root, dir) {
+					t.Errorf("expected no working copy, got %s at %s", wc.System.Name(), wc.Root)
+				}
+				return
+			}
+			if wc == nil {
+				t.Fatalf("expected a %s working copy", tt.wantSystem)
+			}
+			if wc.System.Name() != tt.wantSystem || wc.Root != filepath.Join(dir, tt.wantRoot) {
+				t.Errorf("expected %s at %s, got %s at %s", tt.wantSystem, tt.wantRoot, wc.System.Name(), wc.Root)
+			}
+		})
+	}
+}
+
+type testSystem struct {
+	ignored []string
+}
+
+func (t testSystem) Name() string   { return "test" }
+func (t testSystem) Marker() string { return ".test" }
+func (t testSystem) Revision(ctx context.Context, root string) (konveyor.Revision, error) {
+	return konveyor.Revision{Revision: "42"}, nil
+}
+func (t testSystem) Ignored(ctx context.Context, root string) ([]string, error) {
+	return t.ignored, nil
+}
+
+func TestExcludeGlobs(t *testing.T) {
+	root := t.TempDir()
+	wc := &WorkingCopy{
+		System: testSystem{ignored: []string{
+			"target",
+			filepath.Join("app", "build"),
+			filepath.Join("app", "logs", "debug.log"),
+			filepath.Join("app", "weird*name"),
+			filepath.Join("other", "target"),
+		}},
+		Root: root,
+	}
+	globs, err := wc.ExcludeGlobs(context.Background(), filepath.Join(root, "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".test", "/build", "/logs/debug.log"}
+	if !reflect.DeepEqual(globs, want) {
+		t.Errorf("expected globs %v, got %v", want, globs)
+	}
+
+	revision, err := wc.Revision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.System != "test" || revision.Root != root || revision.Revision != "42" {
+		t.Errorf("unexpected revision %+v", revision)
+	}
+}
+
+func TestParseSVNInfo(t *testing.T) {
+	out := `Path: .
+Working Copy Root Path: /home/user/app
+URL: https://svn.example.com/repos/app/branches/1.x
+Relative URL: ^/branches/1.x
+Repository Root: https://svn.example.com/repos/app
+Revision: 1234
+Node Kind: directory
+Last Changed Rev: 1230
+`
+	want := konveyor.Revision{
+		Revision: "1234",
+		Branch:   "^/branches/1.x",
+		Remote:   "https://svn.example.com/repos/app/branches/1.x",
+	}
+	if got := parseSVNInfo(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestParseSVNIgnored(t *testing.T) {
+	out := "I       target\n?       notes.txt\nM       src/Main.java\nI       web/node_modules\n"
+	want := []string{"target", filepath.Join("web", "node_modules")}
+	if got := parseSVNIgnored(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseHgIdentify(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    konveyor.Revision
+		wantErr bool
+	}{
+		{
+			out:  "0123456789abcdef0123456789abcdef01234567 default\n",
+			want: konveyor.Revision{Revision: "0123456789abcdef0123456789abcdef01234567", Branch: "default"},
+		},
+		{
+			out:  "0123456789abcdef0123456789abcdef01234567+ stable\n",
+			want: konveyor.Revision{Revision: "0123456789abcdef0123456789abcdef01234567", Branch: "stable", Modified: true},
+		},
+		{
+			out:     "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseHgIdentify(tt.out)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expected %+v, got %+v", tt.want, got)
+		}
+	}
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	gitCmd := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	files := map[string]string{
+		".gitignore":           "target/\n*.log\n",
+		"src/Main.java":        "class Main {}\n",
+		"target/Main.class":    "",
+		"src/debug.log":        "",
+		"src/target/keep.java": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCmd("init", "--quiet")
+	gitCmd("symbolic-ref", "HEAD", "refs/heads/main")
+	gitCmd("add", ".gitignore", "src/Main.java")
+	gitCmd("commit", "--quiet", "-m", "initial")
+
+	wc, err := Detect(filepath.Join(root, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wc == nil || wc.System.Name() != "git" {
+		t.Fatalf("expected a git working copy, got %+v", wc)
+	}
+	revision, err := wc.Revision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revision.Revision) != 40 || revision.Branch != "main" || revision.Modified {
+		t.Errorf("unexpected revision %+v", revision)
+	}
+	ignored, err := wc.System.Ignored(context.Background(), wc.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("src", "debug.log"), filepath.Join("src", "target"), "target"}
+	if !reflect.DeepEqual(ignored, want) {
+		t.Errorf("expected ignored %v, got %v", want, ignored)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "src", "Main.java"), []byte("class Main { }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	revision, err = wc.Revision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revision.Modified {
+		t.Errorf("expected the working copy to be modified")
+	}
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// versionRange matches a version when any one of its constraints matches.
+type versionRange []version.Constraints
+
+var mavenRangeRegex = regexp.MustCompile(`[\[\(][^\]\)]*[\]\)]`)
+
+// parseVersionRange parses either a maven version range such as "[1.0,2.0)"
+// or "(,1.0],[1.2,)", or semver constraints such as ">= 1.0, < 2.0" where
+// alternatives are separated by "||".
+func parseVersionRange(r string) (versionRange, error) {
+	r = strings.TrimSpace(r)
+	if r == "" {
+		return nil, fmt.Errorf("version range is empty")
+	}
+	if strings.HasPrefix(r, "[") || strings.HasPrefix(r, "(") {
+		return parseMavenVersionRange(r)
+	}
+	vr := versionRange{}
+	for _, alternative := range strings.Split(r, "||") {
+		constraints, err := newConstraints(strings.Split(alternative, ","))
+		if err != nil {
+			return nil, err
+		}
+		vr = append(vr, constraints)
+	}
+	return vr, nil
+}
+
+func parseMavenVersionRange(r string) (versionRange, error) {
+	ranges := mavenRangeRegex.FindAllString(r, -1)
+	// everything but the separators between the ranges has to be part of a range
+	if len(ranges) == 0 || strings.Trim(mavenRangeRegex.ReplaceAllString(r, ""), ", ") != "" {
+		return nil, fmt.Errorf("invalid maven version range %s", r)
+	}
+	vr := versionRange{}
+	for _, rng := range ranges {
+		lowerInclusive := strings.HasPrefix(rng, "[")
+		upperInclusive := strings.HasSuffix(rng, "]")
+		bounds := strings.Split(rng[1:len(rng)-1], ",")
+		pieces := []string{}
+		switch len(bounds) {
+		case 1:
+			// [1.0] is the exact version
+			if !lowerInclusive || !upperInclusive || strings.TrimSpace(bounds[0]) == "" {
+				return nil, fmt.Errorf("invalid maven version range %s", rng)
+			}
+			pieces = append(pieces, "= "+strings.TrimSpace(bounds[0]))
+		case 2:
+			lower, upper := strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])
+			if lower == "" && upper == "" {
+				return nil, fmt.Errorf("invalid maven version range %s", rng)
+			}
+			if lower != "" {
+				op := "> "
+				if lowerInclusive {
+					op = ">= "
+				}
+				pieces = append(pieces, op+lower)
+			}
+			if upper != "" {
+				op := "< "
+				if upperInclusive {
+					op = "<= "
+				}
+				pieces = append(pieces, op+upper)
+			}
+		default:
+			return nil, fmt.Errorf("invalid maven version range %s", rng)
+		}
+		constraints, err := newConstraints(pieces)
+		if err != nil {
+			return nil, err
+		}
+		vr = append(vr, constraints)
+	}
+	return vr, nil
+}
+
+var constraintRegex = regexp.MustCompile(`^\s*(=|!=|>=|<=|>|<|~>)?\s*(.+?)\s*$`)
+
+// newConstraints creates the constraints leniently parsing the versions in
+// them, the same way the versions of the dependencies are parsed.
+func newConstraints(pieces []string) (version.Constraints, error) {
+	normalized := []string{}
+	for _, piece := range pieces {
+		match := constraintRegex.FindStringSubmatch(piece)
+		if match == nil {
+			return nil, fmt.Errorf("invalid version constraint %q", piece)
+		}
+		op, v := match[1], match[2]
+		if op == "" {
+			op = "="
+		}
+		if parsed, err := getVersion(v); err == nil {
+			v = parsed.Original()
+		}
+		normalized = append(normalized, fmt.Sprintf("%s %s", op, v))
+	}
+	return version.NewConstraint(strings.Join(normalized, ", "))
+}
+
+func (vr versionRange) Check(v *version.Version) bool {
+	for _, constraints := range vr {
+		if constraints.Check(v) {
+			return true
+		}
+	}
+	return false
+}
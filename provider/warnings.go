@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// Warnings collects the non fatal problems of a provider, the same message is
+// reported once with the distinct items it was given for, so that a file
+// that is parsed by many rules is only counted once. The zero value is ready
+// to use.
+type Warnings struct {
+	mutex    sync.Mutex
+	messages []string
+	items    map[string][]string
+	seen     map[string]map[string]bool
+}
+
+// Warn records the problem for the items, such as files or dependencies
+func (w *Warnings) Warn(message string, items ...string) {
+	if w == nil {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.items == nil {
+		w.items = map[string][]string{}
+		w.seen = map[string]map[string]bool{}
+	}
+	if _, ok := w.seen[message]; !ok {
+		w.messages = append(w.messages, message)
+		w.seen[message] = map[string]bool{}
+	}
+	for _, item := range items {
+		if !w.seen[message][item] {
+			w.seen[message][item] = true
+			w.items[message] = append(w.items[message], item)
+		}
+	}
+}
+
+// Warnings returns the recorded problems in the order they were first found
+func (w *Warnings) Warnings() []konveyor.Warning {
+	warnings := []konveyor.Warning{}
+	if w == nil {
+		return warnings
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, message := range w.messages {
+		items := append([]string{}, w.items[message]...)
+		sort.Strings(items)
+		warnings = append(warnings, konveyor.Warning{
+			Message: message,
+			Count:   len(items),
+			Items:   items,
+		})
+	}
+	return warnings
+}
+
+type providerWarnings map[string]InternalProviderClient
+
+// NewWarningReporter reports the warnings of the providers that have any,
+// with the name of the provider set on each warning. The map can be filled in
+// until the warnings are requested.
+func NewWarningReporter(providers map[string]InternalProviderClient) engine.WarningReporter {
+	return providerWarnings(providers)
+}
+
+func (p providerWarnings) Warnings() []konveyor.Warning {
+	warnings := []konveyor.Warning{}
+	for name, prov := range p {
+		r, ok := prov.(engine.WarningReporter)
+		if !ok {
+			continue
+		}
+		for _, w := range r.Warnings() {
+			w.Provider = name
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,129 @@
+// Package watch polls the analyzed locations for the files that are created,
+// changed or deleted, so that an analysis that keeps running can tell its
+// providers and evaluate the rules again.
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/vcs"
+	"go.lsp.dev/uri"
+)
+
+// fileState is what a file is compared with, its content is not read so a
+// file that is only touched is changed
+type fileState struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// Watcher finds the files of the locations that changed between two polls.
+// It does not use the notifications of the operating system, so that it works
+// the same way on network file systems and in containers.
+type Watcher struct {
+	locations []string
+	files     map[string]fileState
+}
+
+// NewWatcher records the current state of the files of the locations, the
+// first poll returns the changes since then.
+func NewWatcher(locations []string) (*Watcher, error) {
+	w := &Watcher{}
+	for _, location := range locations {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return nil, err
+		}
+		w.locations = append(w.locations, abs)
+	}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}
+
+// Poll returns the files that were created, changed or deleted since the
+// previous poll, sorted by path.
+func (w *Watcher) Poll() ([]provider.FileChange, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for path := range files {
+		paths = append(paths, path)
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	changes := []provider.FileChange{}
+	for _, path := range paths {
+		previous, existed := w.files[path]
+		current, exists := files[path]
+		switch {
+		case !existed:
+			changes = append(changes, provider.FileChange{URI: uri.File(path), Type: provider.FileCreated})
+		case !exists:
+			changes = append(changes, provider.FileChange{URI: uri.File(path), Type: provider.FileDeleted})
+		case previous.size != current.size || previous.mode != current.mode || !previous.modTime.Equal(current.modTime):
+			changes = append(changes, provider.FileChange{URI: uri.File(path), Type: provider.FileChanged})
+		}
+	}
+	w.files = files
+	return changes, nil
+}
+
+// scan returns the state of the files of the locations, the metadata
+// directories of the version control systems are left out.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	skip := map[string]bool{}
+	for _, s := range vcs.Systems() {
+		skip[s.Marker()] = true
+	}
+	files := map[string]fileState{}
+	for _, location := range w.locations {
+		err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) {
+				// removed while walking
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if skip[d.Name()] && path != location {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			files[path] = fileState{
+				size:    info.Size(),
+				mode:    info.Mode(),
+				modTime: info.ModTime(),
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,78 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"pom.xml":           "<project/>",
+		"src/App.java":      "class App {}",
+		"src/Lib.java":      "class Lib {}",
+		".git/HEAD":         "ref: refs/heads/main",
+		"docs/README.md":    "app",
+		"docs/old/NOTES.md": "notes",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := NewWatcher([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no change, got %v", changes)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "src", "App.java"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "New.java"), []byte("class New {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "docs", "old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []provider.FileChange{
+		{URI: uri.File(filepath.Join(root, "docs", "old", "NOTES.md")), Type: provider.FileDeleted},
+		{URI: uri.File(filepath.Join(root, "src", "App.java")), Type: provider.FileChanged},
+		{URI: uri.File(filepath.Join(root, "src", "New.java")), Type: provider.FileCreated},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected the changes %v, got %v", want, changes)
+	}
+
+	// the changes are only returned once
+	changes, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no change since the last poll, got %v", changes)
+	}
+}
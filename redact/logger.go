@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// Logger returns a logger that replaces the secrets of the messages, the
+// errors and the values before they are passed to the sink of log
+func Logger(log logr.Logger) logr.Logger {
+	if _, ok := log.GetSink().(sink); ok {
+		return log
+	}
+	inner := log.GetSink()
+	if withDepth, ok := inner.(logr.CallDepthLogSink); ok {
+		// the frame of the sink is skipped for the sinks that report the
+		// caller
+		inner = withDepth.WithCallDepth(1)
+	}
+	return log.WithSink(sink{inner})
+}
+
+// sink replaces the secrets of what is logged to the sink it wraps
+type sink struct {
+	logr.LogSink
+}
+
+var _ logr.CallDepthLogSink = sink{}
+
+func (s sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.LogSink.Info(level, String(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (s sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.LogSink.Error(Error(err), String(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (s sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return sink{s.LogSink.WithValues(redactKeysAndValues(keysAndValues)...)}
+}
+
+func (s sink) WithName(name string) logr.LogSink {
+	return sink{s.LogSink.WithName(name)}
+}
+
+func (s sink) WithCallDepth(depth int) logr.LogSink {
+	if withDepth, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return sink{withDepth.WithCallDepth(depth)}
+	}
+	return s
+}
+
+func redactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i += 2 {
+		redacted[i] = keysAndValues[i]
+		if i+1 < len(keysAndValues) {
+			redacted[i+1] = logValue(keysAndValues[i], keysAndValues[i+1])
+		}
+	}
+	return redacted
+}
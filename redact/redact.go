@@ -0,0 +1,183 @@
+// Package redact scrubs the secrets of the provider settings, such as the
+// passwords and tokens of the registries, from the logs, the traces and the
+// error messages.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces the secrets
+const Placeholder = "REDACTED"
+
+// minSecretLength is the length under which a value is not registered as a
+// secret, so that values such as true or 1 are not scrubbed from everything
+const minSecretLength = 4
+
+var (
+	// secretKeyRegex matches the keys of the settings that have secrets
+	secretKeyRegex = regexp.MustCompile(`(?i)(password|passwd|token|secret|credential|apikey|api_key|auth)`)
+	// urlPasswordRegex matches the password of the user info of a URL
+	urlPasswordRegex = regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`)
+	// textSecretRegex matches the secrets written as key=value or key: value
+	textSecretRegex = regexp.MustCompile(`(?i)((?:password|passwd|token|secret|apikey|api_key)["']?\s*[:=]\s*["']?)[^\s"',}]+`)
+
+	mu sync.RWMutex
+	// secrets are the registered secrets
+	secrets = map[string]bool{}
+	// replacer replaces the registered secrets, the longest ones first
+	replacer = strings.NewReplacer()
+)
+
+// Register adds values to the secrets that are replaced wherever they
+// appear, such as the values of the sensitive fields of the provider
+// settings. The values shorter than 4 characters are ignored.
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	added := false
+	for _, v := range values {
+		if len(v) >= minSecretLength && !secrets[v] {
+			secrets[v] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	sorted := make([]string, 0, len(secrets))
+	for s := range secrets {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, s := range sorted {
+		pairs = append(pairs, s, Placeholder)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// IsSecretKey returns whether the values of a key of the settings are
+// secrets by its name, such as password or token
+func IsSecretKey(key string) bool {
+	return secretKeyRegex.MatchString(key)
+}
+
+// String replaces the registered secrets, the passwords of the URLs and the
+// values of the secrets written as key=value or key: value in a text, such as
+// a log message
+func String(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+	s = r.Replace(s)
+	s = urlPasswordRegex.ReplaceAllString(s, "${1}"+Placeholder+"@")
+	return textSecretRegex.ReplaceAllString(s, "${1}"+Placeholder)
+}
+
+// Bytes is String for a content, such as a log file
+func Bytes(content []byte) []byte {
+	return []byte(String(string(content)))
+}
+
+// Value replaces the secrets of a value decoded from yaml or json, the values
+// of the keys that look like secrets and the secrets in the strings
+func Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		redacted := map[interface{}]interface{}{}
+		for key, item := range v {
+			if k, ok := key.(string); ok && IsSecretKey(k) && item != nil && item != "" {
+				redacted[key] = Placeholder
+				continue
+			}
+			redacted[key] = Value(item)
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := map[string]interface{}{}
+		for key, item := range v {
+			if IsSecretKey(key) && item != nil && item != "" {
+				redacted[key] = Placeholder
+				continue
+			}
+			redacted[key] = Value(item)
+		}
+		return redacted
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = Value(item)
+		}
+		return items
+	case string:
+		return String(v)
+	default:
+		return value
+	}
+}
+
+// Error returns an error with the message of err redacted, which still
+// unwraps to err. err is returned when there is nothing to redact.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if redacted := String(message); redacted != message {
+		return &redactedError{err: err, message: redacted}
+	}
+	return err
+}
+
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// logValue replaces the secrets of a value logged with a key
+func logValue(key interface{}, value interface{}) interface{} {
+	if k, ok := key.(string); ok && IsSecretKey(k) && value != nil && value != "" {
+		return Placeholder
+	}
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return value
+	case string:
+		return String(v)
+	case error:
+		return Error(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = String(item)
+		}
+		return items
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return Value(v)
+	}
+	// the other values, such as the settings, are printed when they have a
+	// secret
+	printed := fmt.Sprintf("%+v", value)
+	if redacted := String(printed); redacted != printed {
+		return redacted
+	}
+	return value
+}
@@ -0,0 +1,210 @@
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"gopkg.in/yaml.v2"
+)
+
+const help = `Paste a rule, a list of rules or a single condition, such as
+  builtin.filecontent:
+    pattern: "TODO"
+and end it with an empty line to evaluate it. Commands:
+  :load <file>  evaluate the rules of a file
+  :trace        show how the conditions of the last evaluation were evaluated
+  :help         show this help
+  :quit         exit
+`
+
+// Session evaluates the rules and conditions a rule author types against the
+// providers, which are started once and kept running between evaluations so
+// that each evaluation only takes the time of its queries.
+type Session struct {
+	log       logr.Logger
+	engine    engine.RuleEngine
+	parser    parser.RuleParser
+	providers map[string]provider.InternalProviderClient
+	out       io.Writer
+	// evaluations numbers the rulesets of the evaluations, so that the traces
+	// of the last one can be told apart
+	evaluations int
+}
+
+// NewSession starts and initializes the providers of the configs. The options
+// configure the engine the rules are evaluated with.
+func NewSession(ctx context.Context, log logr.Logger, configs []provider.Config, out io.Writer, options ...engine.Option) (*Session, error) {
+	s := &Session{
+		log:       log,
+		providers: map[string]provider.InternalProviderClient{},
+		out:       out,
+	}
+	for _, config := range configs {
+		prov, err := lib.GetProviderClient(config, log)
+		if err != nil {
+			s.Stop()
+			return nil, fmt.Errorf("unable to create provider client %s: %w", config.Name, err)
+		}
+		if st, ok := prov.(provider.Startable); ok {
+			if err := st.Start(ctx); err != nil {
+				s.Stop()
+				return nil, fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+			}
+		}
+		if config.CallTimeouts != nil {
+			prov = provider.WithCallTimeouts(config.Name, prov, *config.CallTimeouts)
+		}
+		if config.Retry != nil {
+			prov = provider.WithRetry(config.Name, prov, *config.Retry, log)
+		}
+		if config.RateLimit != nil {
+			prov = provider.WithRateLimit(prov, *config.RateLimit)
+		}
+		s.providers[config.Name] = prov
+	}
+	if err := provider.InitProviders(ctx, s.providers, provider.DependsOn(configs)); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	s.engine = engine.CreateRuleEngine(ctx, 10, log, append(options, engine.WithTrace(true))...)
+	s.parser = parser.RuleParser{
+		ProviderNameToClient: s.providers,
+		Log:                  log.WithName("parser"),
+	}
+	return s, nil
+}
+
+// Stop stops the engine and the providers
+func (s *Session) Stop() {
+	if s.engine != nil {
+		s.engine.Stop()
+	}
+	for _, prov := range s.providers {
+		prov.Stop()
+	}
+}
+
+// Run reads the inputs until the end of the input or :quit. The inputs end
+// with an empty line, the errors of an evaluation are printed and do not end
+// the session.
+func (s *Session) Run(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	input := []string{}
+	fmt.Fprint(s.out, help)
+	fmt.Fprint(s.out, "> ")
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch {
+		case len(input) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":"):
+			if quit := s.command(ctx, strings.TrimSpace(line)); quit {
+				return nil
+			}
+		case strings.TrimSpace(line) == "":
+			if len(input) > 0 {
+				s.printError(s.Eval(ctx, strings.Join(input, "\n")))
+				input = []string{}
+			}
+		default:
+			input = append(input, line)
+			fmt.Fprint(s.out, "... ")
+			continue
+		}
+		fmt.Fprint(s.out, "> ")
+	}
+	if len(input) > 0 {
+		s.printError(s.Eval(ctx, strings.Join(input, "\n")))
+	}
+	return scanner.Err()
+}
+
+// command runs a command and returns whether the session ends
+func (s *Session) command(ctx context.Context, line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	switch name {
+	case ":quit", ":q":
+		return true
+	case ":help", ":h":
+		fmt.Fprint(s.out, help)
+	case ":load":
+		path := strings.TrimSpace(arg)
+		if path == "" {
+			s.printError(fmt.Errorf(":load needs a rule file"))
+			return false
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			s.printError(err)
+			return false
+		}
+		s.printError(s.Eval(ctx, string(content)))
+	case ":trace":
+		s.printError(s.printTrace())
+	default:
+		s.printError(fmt.Errorf("unknown command %s, :help lists the commands", name))
+	}
+	return false
+}
+
+// Eval evaluates a rule, a list of rules or a single condition and prints the
+// incidents they match. A single condition is evaluated as a rule with the
+// condition ID.
+func (s *Session) Eval(ctx context.Context, input string) error {
+	parsedRules, _, err := s.parser.ParseInline([]byte(input))
+	if err != nil {
+		return err
+	}
+
+	s.evaluations++
+	ruleSets := s.engine.RunRules(ctx, []engine.RuleSet{{Name: s.ruleSetName(), Rules: parsedRules}})
+	for _, rs := range ruleSets {
+		if len(rs.Tags) > 0 {
+			fmt.Fprintf(s.out, "tags: %s\n", strings.Join(rs.Tags, ", "))
+		}
+	}
+	return encoder.EncodeDocument(encoder.NewConsoleEncoder(s.out), encoder.Document{RuleSets: ruleSets})
+}
+
+// ruleSetName is the name of the ruleset of the last evaluation
+func (s *Session) ruleSetName() string {
+	return fmt.Sprintf("eval-%d", s.evaluations)
+}
+
+// printTrace prints the traces of the rules of the last evaluation
+func (s *Session) printTrace() error {
+	if s.evaluations == 0 {
+		return fmt.Errorf("nothing was evaluated yet")
+	}
+	traces := []konveyor.RuleTrace{}
+	for _, trace := range s.engine.Traces() {
+		if trace.RuleSet == s.ruleSetName() {
+			traces = append(traces, trace)
+		}
+	}
+	content, err := yaml.Marshal(traces)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(content)
+	return err
+}
+
+func (s *Session) printError(err error) {
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+	}
+}
@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// TODO remove\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rulesFile := filepath.Join(dir, "rules.yaml")
+	rules := "- ruleID: tag-go\n  tag: [Go]\n  when:\n    builtin.file:\n      pattern: \"*.go\"\n"
+	if err := os.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	configs := []provider.Config{{Name: "builtin", InitConfig: []provider.InitConfig{{Location: dir}}}}
+	s, err := NewSession(ctx, logr.Discard(), configs, &out)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer s.Stop()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "condition",
+			input: "builtin.filecontent:\n  pattern: TODO\n\n",
+			want:  []string{"eval-1/condition:3", "condition matched", "1 incidents in 1 files"},
+		},
+		{
+			name:  "rule",
+			input: "ruleID: no-match\nmessage: found\nwhen:\n  builtin.filecontent:\n    pattern: FIXME\n\n",
+			want:  []string{"0 incidents in 0 files"},
+		},
+		{
+			name:  "trace of the last evaluation",
+			input: ":trace\n",
+			want:  []string{"ruleset: eval-2", "rule: no-match", "matched: false"},
+		},
+		{
+			name:  "load",
+			input: ":load " + rulesFile + "\n",
+			want:  []string{"tags: Go"},
+		},
+		{
+			name:  "invalid condition",
+			input: "builtin.unknown:\n  pattern: x\n\n",
+			want:  []string{"error: "},
+		},
+		{
+			name:    "quit",
+			input:   ":quit\nbuiltin.filecontent:\n  pattern: TODO\n\n",
+			notWant: []string{"condition matched"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			if err := s.Run(ctx, strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in the output:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("unexpected %q in the output:\n%s", notWant, out.String())
+				}
+			}
+		})
+	}
+}
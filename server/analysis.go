@@ -0,0 +1,472 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/metrics"
+	"github.com/konveyor/analyzer-lsp/output/uris"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/parser/fetch"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/konveyor/analyzer-lsp/provider/tooling"
+)
+
+// AnalysisRequest is the body of POST /analyses, it takes the same settings as
+// the analyzer command line.
+type AnalysisRequest struct {
+	// Rules are the rule files or directories on the server to run, or the
+	// rulesets to fetch as git or oci references
+	Rules []string `json:"rules,omitempty"`
+	// RuleFiles maps file names to the content of rule files, they are loaded
+	// as a rules directory so a ruleset.yaml can be given as well.
+	RuleFiles map[string]string `json:"ruleFiles,omitempty"`
+	// ProviderConfig selects the providers of the provider settings of the
+	// server and the locations they analyze, all of them are used when it
+	// is empty. The builtin provider is added when it is missing.
+	ProviderConfig   []ProviderRequest `json:"providerConfig,omitempty"`
+	LabelSelector    string            `json:"labelSelector,omitempty"`
+	DepLabelSelector string            `json:"depLabelSelector,omitempty"`
+	// Targets only evaluate the rules of these migration targets, the
+	// violations list the targets that selected their rule
+	Targets []string `json:"targets,omitempty"`
+	// IncidentLimit, CodeSnipLimit and ContextLines default to the same
+	// values as on the command line when not set.
+	IncidentLimit     *int `json:"incidentLimit,omitempty"`
+	CodeSnipLimit     *int `json:"codeSnipLimit,omitempty"`
+	ContextLines      *int `json:"contextLines,omitempty"`
+	NoDependencyRules bool `json:"noDependencyRules,omitempty"`
+	// CodeSnipMaxSize caps the size in bytes of the code snip of each
+	// incident, no limit when not set
+	CodeSnipMaxSize int `json:"codeSnipMaxSize,omitempty"`
+	// MinConfidence drops the incidents found with a lower confidence
+	MinConfidence float64 `json:"minConfidence,omitempty"`
+	// RuleFilter only evaluates the rules of these categories, efforts and
+	// labels
+	RuleFilter *engine.RuleFilter `json:"ruleFilter,omitempty"`
+	// Overrides change the category, effort or labels of rules
+	Overrides []engine.RuleOverride `json:"overrides,omitempty"`
+	// Vars are the values of the {{.vars.<name>}} of the condition
+	// parameters of the rules
+	Vars map[string]string `json:"vars,omitempty"`
+	// URIRewrites rewrite the URIs of the incidents of the results, such as
+	// the paths the locations are mounted at on the server
+	URIRewrites []uris.Config `json:"uriRewrites,omitempty"`
+	// Watch keeps the providers running once the rules are evaluated, and
+	// evaluates them again on the files that change until the analysis is
+	// stopped
+	Watch bool `json:"watch,omitempty"`
+	// WatchInterval is how often the files are polled for changes, 2s when
+	// not set
+	WatchInterval string `json:"watchInterval,omitempty"`
+	// Settings are the settings of the server the request is checked
+	// against, they are never read from the body of a request
+	Settings Settings `json:"-"`
+}
+
+// Settings are what the analyses of a server can use, so that the clients
+// of the API cannot run the binaries or read the files of their choice.
+type Settings struct {
+	// ProviderConfig is the provider settings file of the server, the
+	// binaries and the language servers of the providers come from it
+	ProviderConfig []provider.Config
+	// Rules are the rule files or directories on the server, the rules of a
+	// request that are not fetched have to be in one of them
+	Rules []string
+}
+
+// ProviderRequest selects a provider of the provider settings of the server
+// by name, and gives the locations it analyzes. The other settings of the
+// provider are the ones of the server, the first init config of the provider
+// settings has the ones of the locations.
+type ProviderRequest struct {
+	Name       string            `json:"name"`
+	InitConfig []LocationRequest `json:"initConfig,omitempty"`
+}
+
+// LocationRequest is a location a provider analyzes
+type LocationRequest struct {
+	Location         string                `json:"location,omitempty"`
+	WorkspaceFolders []string              `json:"workspaceFolders,omitempty"`
+	AnalysisMode     provider.AnalysisMode `json:"analysisMode,omitempty"`
+}
+
+// Validate checks the request before the analysis is queued, so that mistakes
+// are reported to the client right away.
+func (r *AnalysisRequest) Validate() error {
+	if len(r.Rules) == 0 && len(r.RuleFiles) == 0 {
+		return fmt.Errorf("rules or ruleFiles are required")
+	}
+	for _, f := range r.Rules {
+		if fetch.IsRemote(f) {
+			if _, err := fetch.ParseReference(f); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("unable to find rule path or file %s", f)
+		}
+		if !r.Settings.hasRules(f) {
+			return fmt.Errorf("rule path or file %s is not in the rules of the server", f)
+		}
+	}
+	for name := range r.RuleFiles {
+		if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return fmt.Errorf("invalid rule file name %q, it must be a file name without a directory", name)
+		}
+	}
+	if r.WatchInterval != "" {
+		if _, err := r.watchInterval(); err != nil {
+			return err
+		}
+	}
+	if r.CodeSnipMaxSize < 0 {
+		return fmt.Errorf("code snip max size must not be negative")
+	}
+	if r.LabelSelector != "" {
+		if _, err := labels.NewLabelSelector[*engine.RuleMeta](r.LabelSelector); err != nil {
+			return fmt.Errorf("invalid label selector: %w", err)
+		}
+	}
+	if r.RuleFilter != nil {
+		if err := r.RuleFilter.Validate(); err != nil {
+			return fmt.Errorf("invalid rule filter: %w", err)
+		}
+	}
+	if r.DepLabelSelector != "" {
+		if _, err := labels.NewLabelSelector[*konveyor.Dep](r.DepLabelSelector); err != nil {
+			return fmt.Errorf("invalid dependency label selector: %w", err)
+		}
+	}
+	for _, t := range r.Targets {
+		if _, err := labels.NewTargetSelector[*engine.RuleMeta](t); err != nil {
+			return err
+		}
+	}
+	if err := engine.ValidateConfidence(r.MinConfidence); err != nil {
+		return fmt.Errorf("invalid min confidence: %w", err)
+	}
+	for _, o := range r.Overrides {
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("invalid override: %w", err)
+		}
+	}
+	if _, err := uris.New(r.URIRewrites); err != nil {
+		return fmt.Errorf("invalid uri rewrites: %w", err)
+	}
+	if _, err := r.providerConfigs(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hasRules returns whether the rule path is one of the rules of the settings
+// or is in one of their directories
+func (s Settings) hasRules(path string) bool {
+	path = resolvePath(path)
+	for _, rules := range s.Rules {
+		rel, err := filepath.Rel(resolvePath(rules), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns the absolute path without symbolic links, so that a
+// link cannot point out of the rules of the settings
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// providerConfigs returns the configs of the providers of the request, the
+// ones of the provider settings of the server with the locations of the
+// request
+func (r *AnalysisRequest) providerConfigs() ([]provider.Config, error) {
+	settings, err := provider.PrepareConfigs(append([]provider.Config{}, r.Settings.ProviderConfig...))
+	if err != nil {
+		return nil, err
+	}
+	if len(r.ProviderConfig) == 0 {
+		return settings, nil
+	}
+	byName := map[string]provider.Config{}
+	for _, c := range settings {
+		byName[c.Name] = c
+	}
+	configs := []provider.Config{}
+	builtin := false
+	for _, p := range r.ProviderConfig {
+		c, ok := byName[p.Name]
+		if !ok {
+			return nil, fmt.Errorf("provider %s is not in the provider settings of the server", p.Name)
+		}
+		builtin = builtin || p.Name == "builtin"
+		if len(p.InitConfig) > 0 {
+			base := provider.InitConfig{}
+			if len(c.InitConfig) > 0 {
+				base = c.InitConfig[0]
+			}
+			c.InitConfig = nil
+			for _, l := range p.InitConfig {
+				ic := base
+				ic.Location = l.Location
+				ic.WorkspaceFolders = l.WorkspaceFolders
+				if l.AnalysisMode != "" {
+					ic.AnalysisMode = l.AnalysisMode
+				}
+				// the providers of concurrent analyses do not share it
+				if base.ProviderSpecificConfig != nil {
+					ic.ProviderSpecificConfig = map[string]interface{}{}
+					for k, v := range base.ProviderSpecificConfig {
+						ic.ProviderSpecificConfig[k] = v
+					}
+				}
+				c.InitConfig = append(c.InitConfig, ic)
+			}
+		}
+		configs = append(configs, c)
+	}
+	if !builtin {
+		configs = append(configs, byName["builtin"])
+	}
+	return provider.PrepareConfigs(configs)
+}
+
+func (r *AnalysisRequest) watchInterval() (time.Duration, error) {
+	if r.WatchInterval == "" {
+		return defaultWatchInterval, nil
+	}
+	interval, err := time.ParseDuration(r.WatchInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid watch interval %q, it must be a positive duration such as 5s", r.WatchInterval)
+	}
+	return interval, nil
+}
+
+func intOrDefault(v *int, def int) int {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+// Analyze runs the analysis of the request, creating and stopping its own
+// providers so that analyses can run concurrently.
+func Analyze(ctx context.Context, log logr.Logger, req AnalysisRequest) ([]konveyor.RuleSet, error) {
+	return analyze(ctx, log, req, nil, nil)
+}
+
+// AnalyzeWithControl runs the analysis of the request as Analyze does, the
+// control pauses and resumes it.
+func AnalyzeWithControl(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error) {
+	return analyze(ctx, log, req, control, nil)
+}
+
+// analyze runs the analysis of the request, pausing it with the control and
+// recording its rules and provider queries in the registry when they are not
+// nil
+func analyze(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control, registry *metrics.Registry) ([]konveyor.RuleSet, error) {
+	if req.Watch && control == nil {
+		return nil, fmt.Errorf("a watching analysis needs a control to publish its results")
+	}
+	configs, err := req.providerConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.InstallLanguageServers(ctx, configs, tooling.NewManager(tooling.DefaultDir(), log)); err != nil {
+		return nil, err
+	}
+
+	selectors := []engine.RuleSelector{}
+	if req.LabelSelector != "" {
+		selector, err := labels.NewLabelSelector[*engine.RuleMeta](req.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	var dependencyLabelSelector *labels.LabelSelector[*konveyor.Dep]
+	if req.DepLabelSelector != "" {
+		dependencyLabelSelector, err = labels.NewLabelSelector[*konveyor.Dep](req.DepLabelSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rewriter, err := uris.New(req.URIRewrites)
+	if err != nil {
+		return nil, err
+	}
+
+	rulePaths, err := fetch.NewFetcher(fetch.DefaultDir(), log).Resolve(ctx, req.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.RuleFiles) > 0 {
+		dir, err := os.MkdirTemp("", "analysis-rules-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		for name, content := range req.RuleFiles {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				return nil, err
+			}
+		}
+		rulePaths = append(rulePaths, dir)
+	}
+
+	contextLines := intOrDefault(req.ContextLines, 10)
+	engineOptions := []engine.Option{
+		engine.WithIncidentLimit(intOrDefault(req.IncidentLimit, 1500)),
+		engine.WithCodeSnipLimit(intOrDefault(req.CodeSnipLimit, 20)),
+		engine.WithContextLines(contextLines),
+		engine.WithCodeSnipMaxSize(req.CodeSnipMaxSize),
+		engine.WithMinConfidence(req.MinConfidence),
+		engine.WithRuleOverrides(req.Overrides),
+		engine.WithLocation(provider.BuiltinLocation(configs)),
+	}
+	if req.RuleFilter != nil {
+		engineOptions = append(engineOptions, engine.WithRuleFilter(*req.RuleFilter))
+	}
+	if registry != nil {
+		engineOptions = append(engineOptions, engine.WithRuleMiddleware(metrics.RuleMiddleware(registry)))
+	}
+	if control != nil {
+		engineOptions = append(engineOptions, engine.WithPause(control.pause))
+	}
+	for _, t := range req.Targets {
+		selector, err := labels.NewTargetSelector[*engine.RuleMeta](t)
+		if err != nil {
+			return nil, err
+		}
+		engineOptions = append(engineOptions, engine.WithTargets(engine.Target{Name: t, Selector: selector}))
+	}
+	providers := map[string]provider.InternalProviderClient{}
+	unwrapped := map[string]provider.InternalProviderClient{}
+	for _, config := range configs {
+		config.ContextLines = contextLines
+		prov, err := lib.GetProviderClient(config, log)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create provider client %s: %w", config.Name, err)
+		}
+		if s, ok := prov.(provider.Startable); ok {
+			if err := s.Start(ctx); err != nil {
+				return nil, fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+			}
+		}
+		unwrapped[config.Name] = prov
+		if config.CallTimeouts != nil {
+			prov = provider.WithCallTimeouts(config.Name, prov, *config.CallTimeouts)
+		}
+		if config.Retry != nil {
+			prov = provider.WithRetry(config.Name, prov, *config.Retry, log)
+		}
+		// the queries that time out are recorded as errors, the time they
+		// wait for the rate limit is not recorded
+		if registry != nil {
+			prov = metrics.WithProviderMetrics(config.Name, prov, registry)
+		}
+		if config.RateLimit != nil {
+			prov = provider.WithRateLimit(prov, *config.RateLimit)
+		}
+		providers[config.Name] = prov
+	}
+
+	parser := parser.RuleParser{
+		ProviderNameToClient: providers,
+		Log:                  log.WithName("parser"),
+		NoDependencyRules:    req.NoDependencyRules,
+		DepLabelSelector:     dependencyLabelSelector,
+		Vars:                 req.Vars,
+	}
+	ruleSets := []engine.RuleSet{}
+	needProviders := map[string]provider.InternalProviderClient{}
+	for _, f := range rulePaths {
+		internRuleSet, internNeedProviders, err := parser.LoadRules(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the rules: %w", err)
+		}
+		ruleSets = append(ruleSets, internRuleSet...)
+		for k, v := range internNeedProviders {
+			needProviders[k] = v
+		}
+	}
+	// the providers are initialized with the providers they depend on
+	dependsOn := provider.DependsOn(configs)
+	needProviders = provider.WithDependencies(needProviders, providers, dependsOn)
+	for _, prov := range needProviders {
+		defer prov.Stop()
+	}
+	if err := provider.InitProviders(ctx, needProviders, dependsOn); err != nil {
+		return nil, err
+	}
+	// the providers are suspended and asked for their stats before they are
+	// wrapped, the suspended ones are continued before they are stopped
+	initialized := map[string]provider.InternalProviderClient{}
+	for name := range needProviders {
+		initialized[name] = unwrapped[name]
+	}
+	control.attach(initialized)
+	defer control.detach()
+
+	// evaluate runs the rules with a new engine, the options of a watching
+	// analysis resume it from the previous evaluation
+	evaluate := func(options ...engine.Option) []konveyor.RuleSet {
+		eng := engine.CreateRuleEngine(ctx,
+			10,
+			log,
+			append(append([]engine.Option{}, engineOptions...), options...)...,
+		)
+		defer eng.Stop()
+		rulesets := eng.RunRules(ctx, ruleSets, selectors...)
+		if registry != nil {
+			for name, stats := range provider.FullStats(ctx, log, initialized) {
+				metrics.RecordProviderStats(registry, name, stats)
+			}
+		}
+		sort.SliceStable(rulesets, func(i, j int) bool {
+			return rulesets[i].Name < rulesets[j].Name
+		})
+		uris.Apply(rewriter, rulesets, nil)
+		return rulesets
+	}
+	if req.Watch {
+		return watchFiles(ctx, log, req, control, configLocations(configs), initialized, evaluate)
+	}
+
+	rulesets := evaluate()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return rulesets, nil
+}
+
+// configLocations returns the locations the providers analyze
+func configLocations(configs []provider.Config) []string {
+	locations := []string{}
+	for _, config := range configs {
+		for _, ic := range config.InitConfig {
+			locations = append(locations, ic.Roots()...)
+		}
+	}
+	return locations
+}
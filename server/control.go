@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// Control pauses and resumes a running analysis. While it is paused, the
+// rules being evaluated complete but no other rule is started, and the
+// providers that run processes of their own can be suspended once these
+// rules are done, so that the analysis does not use the resources of the node
+// until it is resumed.
+type Control struct {
+	log   logr.Logger
+	pause *engine.Pause
+
+	mutex     sync.Mutex
+	providers map[string]provider.InternalProviderClient
+	// suspend is whether the providers are suspended while paused, the ones
+	// attached later are suspended right away
+	suspend bool
+	// cancelSuspend stops waiting for the rules being evaluated before
+	// suspending the providers
+	cancelSuspend context.CancelFunc
+	suspended     []string
+
+	// results is given the results of every evaluation of a watching
+	// analysis, stopWatching ends the analysis
+	results      func([]konveyor.RuleSet)
+	stopWatching chan struct{}
+	stopOnce     sync.Once
+}
+
+func NewControl(log logr.Logger) *Control {
+	return &Control{
+		log:          log,
+		pause:        engine.NewPause(),
+		stopWatching: make(chan struct{}),
+	}
+}
+
+// Pause stops starting rules, and suspends the providers once the rules being
+// evaluated are done when suspend is set. It returns false when the analysis
+// was already paused.
+func (c *Control) Pause(suspend bool) bool {
+	if !c.pause.Pause() {
+		return false
+	}
+	c.log.Info("analysis paused", "suspendProviders", suspend)
+	if !suspend {
+		return true
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.mutex.Lock()
+	c.cancelSuspend = cancel
+	c.mutex.Unlock()
+	go func() {
+		if err := c.pause.Idle(ctx); err != nil {
+			return
+		}
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		// the analysis may have been resumed in the meantime
+		if ctx.Err() != nil {
+			return
+		}
+		c.suspend = true
+		c.suspendProviders()
+	}()
+	return true
+}
+
+// Resume continues the suspended providers and starts the rules again, it
+// returns false when the analysis was not paused.
+func (c *Control) Resume() bool {
+	c.mutex.Lock()
+	if c.cancelSuspend != nil {
+		c.cancelSuspend()
+		c.cancelSuspend = nil
+	}
+	c.suspend = false
+	c.continueProviders()
+	c.mutex.Unlock()
+	if !c.pause.Resume() {
+		return false
+	}
+	c.log.Info("analysis resumed")
+	return true
+}
+
+// OnResults calls f with the results of every evaluation of a watching
+// analysis, before the files are watched again
+func (c *Control) OnResults(f func([]konveyor.RuleSet)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.results = f
+}
+
+// StopWatching ends a watching analysis once the rules being evaluated are
+// done, the analysis returns the results of its last evaluation.
+func (c *Control) StopWatching() {
+	c.stopOnce.Do(func() {
+		c.log.Info("stop watching the files")
+		close(c.stopWatching)
+	})
+}
+
+// publish gives the results of an evaluation of a watching analysis
+func (c *Control) publish(rulesets []konveyor.RuleSet) {
+	c.mutex.Lock()
+	f := c.results
+	c.mutex.Unlock()
+	if f != nil {
+		f(rulesets)
+	}
+}
+
+// Suspended returns the names of the suspended providers
+func (c *Control) Suspended() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string{}, c.suspended...)
+}
+
+// attach gives the providers of the analysis once they are initialized
+func (c *Control) attach(providers map[string]provider.InternalProviderClient) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.providers = providers
+	if c.suspend {
+		c.suspendProviders()
+	}
+}
+
+// detach continues the suspended providers so that they can be stopped
+func (c *Control) detach() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.continueProviders()
+	c.providers = nil
+}
+
+// suspendProviders is called with the mutex held
+func (c *Control) suspendProviders() {
+	names := []string{}
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s, ok := c.providers[name].(provider.Suspendable)
+		if !ok || contains(c.suspended, name) {
+			continue
+		}
+		if err := s.Suspend(); err != nil {
+			c.log.Error(err, "unable to suspend provider", "provider", name)
+			continue
+		}
+		c.log.V(3).Info("provider suspended", "provider", name)
+		c.suspended = append(c.suspended, name)
+	}
+}
+
+// continueProviders is called with the mutex held
+func (c *Control) continueProviders() {
+	for _, name := range c.suspended {
+		if err := c.providers[name].(provider.Suspendable).Continue(); err != nil {
+			c.log.Error(err, "unable to continue provider", "provider", name)
+			continue
+		}
+		c.log.V(3).Info("provider continued", "provider", name)
+	}
+	c.suspended = nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/metrics"
+	"github.com/konveyor/analyzer-lsp/output/encoder"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type JobState string
+
+const (
+	JobPending JobState = "pending"
+	JobRunning JobState = "running"
+	JobPaused  JobState = "paused"
+	// JobWatching is a watching analysis with results, which evaluates the
+	// rules again when the analyzed files change
+	JobWatching  JobState = "watching"
+	JobCompleted JobState = "completed"
+	JobFailed    JobState = "failed"
+)
+
+// JobStatus is returned by POST /analyses and GET /analyses/{id}/status
+type JobStatus struct {
+	ID       string     `json:"id"`
+	State    JobState   `json:"state"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	// SuspendedProviders are the providers suspended while the analysis is
+	// paused
+	SuspendedProviders []string `json:"suspendedProviders,omitempty"`
+	// Evaluations counts the evaluations of the rules of a watching
+	// analysis, Updated is when its results last changed
+	Evaluations int        `json:"evaluations,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+}
+
+type job struct {
+	status  JobStatus
+	results []konveyor.RuleSet
+	control *Control
+}
+
+// AnalyzeFunc runs a single analysis, pausing it with the control, it is
+// Analyze outside of tests.
+type AnalyzeFunc func(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error)
+
+// Server exposes the engine over HTTP. Analyses run in the background, at
+// most maxConcurrent of them at a time, and their results are kept in memory
+// until the server stops.
+type Server struct {
+	ctx      context.Context
+	log      logr.Logger
+	analyze  AnalyzeFunc
+	slots    chan struct{}
+	settings Settings
+
+	mutex sync.RWMutex
+	jobs  map[string]*job
+}
+
+// NewServer creates a server running the analyses with the given context,
+// canceling it stops the running analyses.
+func NewServer(ctx context.Context, log logr.Logger, maxConcurrent int) *Server {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Server{
+		ctx:     ctx,
+		log:     log.WithName("server"),
+		analyze: AnalyzeWithControl,
+		slots:   make(chan struct{}, maxConcurrent),
+		jobs:    map[string]*job{},
+	}
+}
+
+// WithSettings sets the providers and the rules on the server the analyses
+// can use, the analyses only have the builtin provider and the fetched rules
+// without them
+func (s *Server) WithSettings(settings Settings) *Server {
+	s.settings = settings
+	return s
+}
+
+// WithMetrics records the rules and the provider queries of the analyses in
+// the registry
+func (s *Server) WithMetrics(registry *metrics.Registry) *Server {
+	s.analyze = func(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error) {
+		return analyze(ctx, log, req, control, registry)
+	}
+	return s
+}
+
+// ServeHTTP routes POST /analyses, GET /analyses/{id}/status,
+// GET /analyses/{id}/results, POST /analyses/{id}/pause,
+// POST /analyses/{id}/resume and POST /analyses/{id}/stop.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "analyses":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+			return
+		}
+		s.createAnalysis(w, r)
+	case len(parts) == 3 && parts[0] == "analyses" && (parts[2] == "status" || parts[2] == "results"):
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+			return
+		}
+		if parts[2] == "status" {
+			s.getStatus(w, parts[1])
+		} else {
+			s.getResults(w, r, parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "analyses" && (parts[2] == "pause" || parts[2] == "resume"):
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+			return
+		}
+		if parts[2] == "pause" {
+			s.pauseAnalysis(w, r, parts[1])
+		} else {
+			s.resumeAnalysis(w, parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "analyses" && parts[2] == "stop":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+			return
+		}
+		s.stopWatching(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+	}
+}
+
+func (s *Server) createAnalysis(w http.ResponseWriter, r *http.Request) {
+	req := AnalysisRequest{}
+	// the settings that are not in the request, such as the binary of a
+	// provider, are rejected rather than ignored
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid analysis request: %w", err))
+		return
+	}
+	req.Settings = s.settings
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	j := &job{
+		status: JobStatus{
+			ID:      id,
+			State:   JobPending,
+			Created: time.Now(),
+		},
+		control: NewControl(s.log.WithValues("analysis", id)),
+	}
+	j.control.OnResults(func(results []konveyor.RuleSet) {
+		s.publish(j, results)
+	})
+	s.mutex.Lock()
+	s.jobs[id] = j
+	status := j.status
+	s.mutex.Unlock()
+
+	go s.run(j, req)
+
+	w.Header().Set("Location", fmt.Sprintf("/analyses/%s/status", id))
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) run(j *job, req AnalysisRequest) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-s.ctx.Done():
+		s.finish(j, nil, s.ctx.Err())
+		return
+	}
+
+	s.mutex.Lock()
+	now := time.Now()
+	j.status.State = JobRunning
+	j.status.Started = &now
+	s.mutex.Unlock()
+
+	log := s.log.WithValues("analysis", j.status.ID)
+	log.Info("running analysis")
+	results, err := s.analyze(s.ctx, log, req, j.control)
+	s.finish(j, results, err)
+}
+
+func (s *Server) finish(j *job, results []konveyor.RuleSet, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	j.status.Finished = &now
+	if err != nil {
+		s.log.Error(err, "analysis failed", "analysis", j.status.ID)
+		j.status.State = JobFailed
+		j.status.Error = err.Error()
+		return
+	}
+	j.status.State = JobCompleted
+	j.results = results
+}
+
+// publish keeps the results of an evaluation of a watching analysis, they
+// are served until the next evaluation is done
+func (s *Server) publish(j *job, results []konveyor.RuleSet) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	j.status.State = JobWatching
+	j.status.Evaluations++
+	j.status.Updated = &now
+	j.results = results
+}
+
+func (s *Server) getJob(id string) (JobStatus, []konveyor.RuleSet, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return JobStatus{}, nil, false
+	}
+	status := j.status
+	if status.State == JobPaused {
+		status.SuspendedProviders = j.control.Suspended()
+	}
+	return status, j.results, true
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, id string) {
+	status, _, ok := s.getJob(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("analysis %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// getResults writes the results in the format of the format query parameter,
+// json by default.
+func (s *Server) getResults(w http.ResponseWriter, r *http.Request, id string) {
+	status, results, ok := s.getJob(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("analysis %s not found", id))
+		return
+	}
+	if status.State != JobCompleted && status.State != JobWatching {
+		writeError(w, http.StatusConflict, fmt.Errorf("analysis %s is %s", id, status.State))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = encoder.JSONFormat
+	}
+	// encode first so that an error can still be returned to the client
+	b := &strings.Builder{}
+	enc, err := encoder.New(format, b)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := enc.Encode(results, nil); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch format {
+	case encoder.JSONFormat:
+		w.Header().Set("Content-Type", "application/json")
+	case encoder.CSVFormat:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case encoder.HTMLFormat:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// pauseAnalysis stops starting the rules of a running analysis, the
+// suspendProviders query parameter suspends its providers once the rules
+// being evaluated are done.
+func (s *Server) pauseAnalysis(w http.ResponseWriter, r *http.Request, id string) {
+	suspend := false
+	if v := r.URL.Query().Get("suspendProviders"); v != "" {
+		var err error
+		suspend, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid suspendProviders %q", v))
+			return
+		}
+	}
+	s.mutex.Lock()
+	j, ok := s.jobs[id]
+	if !ok {
+		s.mutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("analysis %s not found", id))
+		return
+	}
+	if j.status.State != JobRunning {
+		state := j.status.State
+		s.mutex.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("analysis %s is %s", id, state))
+		return
+	}
+	j.control.Pause(suspend)
+	j.status.State = JobPaused
+	status := j.status
+	s.mutex.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// resumeAnalysis continues the providers of a paused analysis and starts its
+// rules again
+func (s *Server) resumeAnalysis(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	j, ok := s.jobs[id]
+	if !ok {
+		s.mutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("analysis %s not found", id))
+		return
+	}
+	if j.status.State != JobPaused {
+		state := j.status.State
+		s.mutex.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("analysis %s is %s", id, state))
+		return
+	}
+	j.control.Resume()
+	j.status.State = JobRunning
+	status := j.status
+	s.mutex.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// stopWatching ends a watching analysis, it completes with the results of its
+// last evaluation
+func (s *Server) stopWatching(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	j, ok := s.jobs[id]
+	if !ok {
+		s.mutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("analysis %s not found", id))
+		return
+	}
+	if j.status.State != JobWatching {
+		state := j.status.State
+		s.mutex.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("analysis %s is %s", id, state))
+		return
+	}
+	status := j.status
+	s.mutex.Unlock()
+	j.control.StopWatching()
+	writeJSON(w, http.StatusOK, status)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,528 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func TestServer(t *testing.T) {
+	release := make(chan struct{})
+	s := NewServer(context.Background(), logr.Discard(), 2)
+	s.analyze = func(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error) {
+		<-release
+		if req.LabelSelector == "konveyor.io/fail" {
+			return nil, fmt.Errorf("analysis failed")
+		}
+		return []konveyor.RuleSet{{Name: "ruleset"}}, nil
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(body string) (int, JobStatus) {
+		resp, err := http.Post(ts.URL+"/analyses", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		status := JobStatus{}
+		json.NewDecoder(resp.Body).Decode(&status)
+		return resp.StatusCode, status
+	}
+	get := func(path string) (int, string) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	waitFor := func(id string, state JobState) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			_, body := get("/analyses/" + id + "/status")
+			status := JobStatus{}
+			json.Unmarshal([]byte(body), &status)
+			if status.State == state {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("analysis %s did not become %s", id, state)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "no rules",
+			body: `{}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "invalid json",
+			body: `{"rules":`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "rule file in a directory",
+			body: `{"ruleFiles": {"../rules.yaml": "[]"}}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "target without name",
+			body: `{"ruleFiles": {"rules.yaml": "[]"}, "targets": [" "]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "missing rule path",
+			body: `{"rules": ["/does/not/exist"]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "rule path that is not in the rules of the server",
+			body: `{"rules": ["/"]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "provider binary",
+			body: `{"ruleFiles": {"rules.yaml": "[]"}, "providerConfig": [{"name": "builtin", "binaryPath": "/bin/sh"}]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "language server",
+			body: `{"ruleFiles": {"rules.yaml": "[]"}, "providerConfig": [{"name": "builtin", "initConfig": [{"location": ".", "providerSpecificConfig": {"lspServerPath": "/bin/sh"}}]}]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "provider that is not in the settings",
+			body: `{"ruleFiles": {"rules.yaml": "[]"}, "providerConfig": [{"name": "java"}]}`,
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := post(tt.body)
+			if code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, code)
+			}
+		})
+	}
+
+	code, ok := post(`{"ruleFiles": {"rules.yaml": "[]"}}`)
+	if code != http.StatusAccepted || ok.State != JobPending || ok.ID == "" {
+		t.Fatalf("expected a pending analysis, got %d %v", code, ok)
+	}
+	_, failed := post(`{"ruleFiles": {"rules.yaml": "[]"}, "labelSelector": "konveyor.io/fail"}`)
+	waitFor(ok.ID, JobRunning)
+	waitFor(failed.ID, JobRunning)
+
+	if code, _ := get("/analyses/" + ok.ID + "/results"); code != http.StatusConflict {
+		t.Errorf("expected results of a running analysis to conflict, got %d", code)
+	}
+	close(release)
+	waitFor(ok.ID, JobCompleted)
+	waitFor(failed.ID, JobFailed)
+
+	code, body := get("/analyses/" + ok.ID + "/results")
+	if code != http.StatusOK {
+		t.Fatalf("expected results, got %d %s", code, body)
+	}
+	results := []konveyor.RuleSet{}
+	if err := json.Unmarshal([]byte(body), &results); err != nil || len(results) != 1 || results[0].Name != "ruleset" {
+		t.Errorf("unexpected results %s: %v", body, err)
+	}
+	if code, body := get("/analyses/" + ok.ID + "/results?format=yaml"); code != http.StatusOK || !strings.Contains(body, "name: ruleset") {
+		t.Errorf("expected yaml results, got %d %s", code, body)
+	}
+	if code, body := get("/analyses/" + failed.ID + "/status"); !strings.Contains(body, "analysis failed") {
+		t.Errorf("expected the error in the status, got %d %s", code, body)
+	}
+	if code, _ := get("/analyses/" + failed.ID + "/results"); code != http.StatusConflict {
+		t.Errorf("expected results of a failed analysis to conflict, got %d", code)
+	}
+	if code, _ := get("/analyses/unknown/status"); code != http.StatusNotFound {
+		t.Errorf("expected an unknown analysis not to be found, got %d", code)
+	}
+}
+
+func TestProviderConfigs(t *testing.T) {
+	settings := []provider.Config{
+		{
+			Name:       "java",
+			BinaryPath: "/jdtls/bin/jdtls",
+			InitConfig: []provider.InitConfig{{
+				Location:               "/apps/default",
+				AnalysisMode:           provider.FullAnalysisMode,
+				ProviderSpecificConfig: map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls"},
+			}},
+		},
+		{Name: "go", Address: "localhost:14651"},
+	}
+	tests := []struct {
+		name    string
+		request []ProviderRequest
+		want    map[string][]provider.InitConfig
+		wantErr bool
+	}{
+		{
+			name: "all the providers of the settings",
+			want: map[string][]provider.InitConfig{
+				"java": {{Location: "/apps/default", AnalysisMode: provider.FullAnalysisMode, ProviderSpecificConfig: map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls"}}},
+				"go":   nil,
+			},
+		},
+		{
+			name: "locations of the request",
+			request: []ProviderRequest{{Name: "java", InitConfig: []LocationRequest{
+				{Location: "/apps/a", AnalysisMode: provider.SourceOnlyAnalysisMode},
+				{Location: "/apps/b", WorkspaceFolders: []string{"/apps/lib"}},
+			}}},
+			want: map[string][]provider.InitConfig{
+				"java": {
+					{Location: "/apps/a", AnalysisMode: provider.SourceOnlyAnalysisMode, ProviderSpecificConfig: map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls"}},
+					{Location: "/apps/b", WorkspaceFolders: []string{"/apps/lib"}, AnalysisMode: provider.FullAnalysisMode, ProviderSpecificConfig: map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls"}},
+				},
+			},
+		},
+		{
+			name:    "provider without init config",
+			request: []ProviderRequest{{Name: "go", InitConfig: []LocationRequest{{Location: "/apps/a"}}}},
+			want: map[string][]provider.InitConfig{
+				"go": {{Location: "/apps/a"}},
+			},
+		},
+		{
+			name:    "unknown provider",
+			request: []ProviderRequest{{Name: "python"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := AnalysisRequest{ProviderConfig: tt.request, Settings: Settings{ProviderConfig: settings}}
+			configs, err := req.providerConfigs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := map[string][]provider.InitConfig{}
+			for _, c := range configs {
+				if c.Name == "builtin" {
+					continue
+				}
+				if c.Name == "java" && c.BinaryPath != "/jdtls/bin/jdtls" {
+					t.Errorf("expected the binary of the settings, got %s", c.BinaryPath)
+				}
+				for i := range c.InitConfig {
+					c.InitConfig[i].Proxy = nil
+				}
+				got[c.Name] = c.InitConfig
+			}
+			if len(configs) != len(tt.want)+1 {
+				t.Errorf("expected the builtin provider to be added, got %d providers", len(configs))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected the init configs %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSettingsHasRules(t *testing.T) {
+	dir := t.TempDir()
+	rules := filepath.Join(dir, "rules")
+	if err := os.MkdirAll(filepath.Join(rules, "eap7"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/", filepath.Join(rules, "root")); err != nil {
+		t.Fatal(err)
+	}
+	settings := Settings{Rules: []string{rules}}
+	for path, want := range map[string]bool{
+		rules:                               true,
+		filepath.Join(rules, "eap7"):        true,
+		filepath.Join(rules, "eap7", ".."):  true,
+		filepath.Join(rules, "..", "other"): false,
+		filepath.Join(rules, "root"):        false,
+		dir + "/rules-other":                false,
+	} {
+		if got := settings.hasRules(path); got != want {
+			t.Errorf("expected %s to be in the rules: %v, got %v", path, want, got)
+		}
+	}
+	if (Settings{}).hasRules(rules) {
+		t.Errorf("expected no rules on a server without rules")
+	}
+}
+
+// suspendableProvider records whether it is suspended
+type suspendableProvider struct {
+	provider.InternalProviderClient
+	mutex     sync.Mutex
+	suspended bool
+}
+
+func (p *suspendableProvider) Suspend() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.suspended = true
+	return nil
+}
+
+func (p *suspendableProvider) Continue() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.suspended = false
+	return nil
+}
+
+func (p *suspendableProvider) isSuspended() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.suspended
+}
+
+func TestServerPause(t *testing.T) {
+	release := make(chan struct{})
+	prov := &suspendableProvider{}
+	s := NewServer(context.Background(), logr.Discard(), 1)
+	s.analyze = func(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error) {
+		control.attach(map[string]provider.InternalProviderClient{"fake": prov})
+		defer control.detach()
+		<-release
+		return []konveyor.RuleSet{{Name: "ruleset"}}, nil
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	do := func(method string, path string) (int, JobStatus) {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		status := JobStatus{}
+		json.NewDecoder(resp.Body).Decode(&status)
+		return resp.StatusCode, status
+	}
+	waitFor := func(id string, check func(JobStatus) bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, status := do(http.MethodGet, "/analyses/"+id+"/status"); check(status) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("analysis %s did not reach the expected status", id)
+	}
+
+	resp, err := http.Post(ts.URL+"/analyses", "application/json", strings.NewReader(`{"ruleFiles": {"rules.yaml": "[]"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := JobStatus{}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	id := created.ID
+	waitFor(id, func(s JobStatus) bool { return s.State == JobRunning })
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		state  JobState
+	}{
+		{name: "resume a running analysis", method: http.MethodPost, path: "/analyses/" + id + "/resume", code: http.StatusConflict},
+		{name: "invalid suspension", method: http.MethodPost, path: "/analyses/" + id + "/pause?suspendProviders=maybe", code: http.StatusBadRequest},
+		{name: "get pause", method: http.MethodGet, path: "/analyses/" + id + "/pause", code: http.StatusMethodNotAllowed},
+		{name: "pause an unknown analysis", method: http.MethodPost, path: "/analyses/unknown/pause", code: http.StatusNotFound},
+		{name: "pause", method: http.MethodPost, path: "/analyses/" + id + "/pause?suspendProviders=true", code: http.StatusOK, state: JobPaused},
+		{name: "pause a paused analysis", method: http.MethodPost, path: "/analyses/" + id + "/pause", code: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, status := do(tt.method, tt.path)
+			if code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, code)
+			}
+			if tt.state != "" && status.State != tt.state {
+				t.Errorf("expected the analysis to be %s, got %s", tt.state, status.State)
+			}
+		})
+	}
+
+	// the provider is suspended once no rule is evaluated
+	waitFor(id, func(s JobStatus) bool { return len(s.SuspendedProviders) == 1 && s.SuspendedProviders[0] == "fake" })
+	if !prov.isSuspended() {
+		t.Errorf("expected the provider to be suspended")
+	}
+	if code, status := do(http.MethodPost, "/analyses/"+id+"/resume"); code != http.StatusOK || status.State != JobRunning {
+		t.Errorf("expected the analysis to be resumed, got %d %v", code, status)
+	}
+	if prov.isSuspended() {
+		t.Errorf("expected the provider to be continued")
+	}
+
+	// a paused analysis is continued before its providers are stopped
+	if code, _ := do(http.MethodPost, "/analyses/"+id+"/pause?suspendProviders=true"); code != http.StatusOK {
+		t.Fatalf("expected the analysis to be paused, got %d", code)
+	}
+	waitFor(id, func(s JobStatus) bool { return len(s.SuspendedProviders) == 1 })
+	close(release)
+	waitFor(id, func(s JobStatus) bool { return s.State == JobCompleted })
+	if prov.isSuspended() {
+		t.Errorf("expected the provider to be continued when the analysis is done")
+	}
+	if code, _ := do(http.MethodPost, "/analyses/"+id+"/pause"); code != http.StatusConflict {
+		t.Errorf("expected pausing a completed analysis to conflict, got %d", code)
+	}
+}
+
+// watchingProvider records the files it is told about
+type watchingProvider struct {
+	provider.InternalProviderClient
+	changes chan []provider.FileChange
+}
+
+func (p *watchingProvider) DidChangeWatchedFiles(ctx context.Context, changes []provider.FileChange) error {
+	p.changes <- changes
+	return nil
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "App.java"), []byte("class App {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	control := NewControl(logr.Discard())
+	published := make(chan []konveyor.RuleSet, 2)
+	control.OnResults(func(r []konveyor.RuleSet) { published <- r })
+	prov := &watchingProvider{changes: make(chan []provider.FileChange, 1)}
+	evaluations := 0
+	evaluate := func(options ...engine.Option) []konveyor.RuleSet {
+		evaluations++
+		return []konveyor.RuleSet{{Name: fmt.Sprintf("evaluation-%d", evaluations)}}
+	}
+
+	done := make(chan []konveyor.RuleSet)
+	go func() {
+		results, err := watchFiles(context.Background(), logr.Discard(), AnalysisRequest{WatchInterval: "10ms"}, control,
+			[]string{dir}, map[string]provider.InternalProviderClient{"fake": prov}, evaluate)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- results
+	}()
+	if r := <-published; r[0].Name != "evaluation-1" {
+		t.Errorf("expected the results of the first evaluation, got %v", r)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "New.java"), []byte("class New {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case changes := <-prov.changes:
+		want := []provider.FileChange{{URI: uri.File(filepath.Join(dir, "New.java")), Type: provider.FileCreated}}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("expected the provider to be told %v, got %v", want, changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the provider was not told about the changed files")
+	}
+	if r := <-published; r[0].Name != "evaluation-2" {
+		t.Errorf("expected the results of the second evaluation, got %v", r)
+	}
+
+	control.StopWatching()
+	if results := <-done; len(results) != 1 || results[0].Name != "evaluation-2" {
+		t.Errorf("expected the results of the last evaluation, got %v", results)
+	}
+}
+
+func TestServerWatch(t *testing.T) {
+	s := NewServer(context.Background(), logr.Discard(), 1)
+	s.analyze = func(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control) ([]konveyor.RuleSet, error) {
+		control.publish([]konveyor.RuleSet{{Name: "ruleset"}})
+		<-control.stopWatching
+		return []konveyor.RuleSet{{Name: "ruleset"}}, nil
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(path string, body string) (int, JobStatus) {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		status := JobStatus{}
+		json.NewDecoder(resp.Body).Decode(&status)
+		return resp.StatusCode, status
+	}
+	waitFor := func(id string, state JobState) JobStatus {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			status, _, _ := s.getJob(id)
+			if status.State == state {
+				return status
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("analysis %s did not become %s", id, state)
+		return JobStatus{}
+	}
+
+	if code, _ := post("/analyses", `{"ruleFiles": {"rules.yaml": "[]"}, "watch": true, "watchInterval": "never"}`); code != http.StatusBadRequest {
+		t.Errorf("expected an invalid watch interval to be rejected, got %d", code)
+	}
+	_, created := post("/analyses", `{"ruleFiles": {"rules.yaml": "[]"}, "watch": true}`)
+	status := waitFor(created.ID, JobWatching)
+	if status.Evaluations != 1 || status.Updated == nil {
+		t.Errorf("expected one evaluation, got %+v", status)
+	}
+	resp, err := http.Get(ts.URL + "/analyses/" + created.ID + "/results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the results of a watching analysis, got %d", resp.StatusCode)
+	}
+	if code, _ := post("/analyses/"+created.ID+"/stop", ""); code != http.StatusOK {
+		t.Errorf("expected the analysis to stop watching, got %d", code)
+	}
+	waitFor(created.ID, JobCompleted)
+	if code, _ := post("/analyses/"+created.ID+"/stop", ""); code != http.StatusConflict {
+		t.Errorf("expected stopping a completed analysis to conflict, got %d", code)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/digest"
+	"github.com/konveyor/analyzer-lsp/provider/watch"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// watchFiles evaluates the rules, then polls the locations and evaluates the
+// rules again each time files change, until the control stops watching. The
+// providers are told about the changed files first, and each evaluation
+// resumes from the checkpoint of the previous one, so that only the rules
+// with incidents in the changed files or that may now match them are
+// evaluated again, on these files only. The results of every evaluation are
+// published to the control.
+func watchFiles(ctx context.Context, log logr.Logger, req AnalysisRequest, control *Control, locations []string,
+	providers map[string]provider.InternalProviderClient, evaluate func(...engine.Option) []konveyor.RuleSet) ([]konveyor.RuleSet, error) {
+	interval, err := req.watchInterval()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "analysis-watch-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	checkpointPath := filepath.Join(dir, "checkpoint.json")
+
+	// the changes made while the rules are evaluated are found by the next
+	// poll
+	watcher, err := watch.NewWatcher(locations)
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch the analyzed files: %w", err)
+	}
+	var rulesets []konveyor.RuleSet
+	run := func(resume bool) error {
+		workspace, err := digest.NewWorkspace(locations)
+		if err != nil {
+			return fmt.Errorf("unable to compute the digest of the analyzed files: %w", err)
+		}
+		results := evaluate(
+			// the checkpoint is only written once the rules are evaluated
+			engine.WithCheckpoint(checkpointPath, time.Duration(math.MaxInt64)),
+			engine.WithWorkspace(workspace),
+			engine.WithResume(resume),
+		)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rulesets = results
+		control.publish(rulesets)
+		return nil
+	}
+	if err := run(false); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-control.stopWatching:
+			return rulesets, nil
+		case <-ticker.C:
+		}
+		changes, err := watcher.Poll()
+		if err != nil {
+			log.Error(err, "unable to find the changed files")
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		log.Info("files changed, evaluating the rules again", "files", len(changes))
+		provider.NotifyFileChanges(ctx, log, providers, changes)
+		if err := run(true); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/redact"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/jaeger"
@@ -62,8 +63,17 @@ func Shutdown(ctx context.Context, log logr.Logger, tp *tracesdk.TracerProvider)
 	}
 }
 
+// StartNewSpan starts a span with the attributes, the secrets of their
+// string values are replaced
 func StartNewSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
 	ctx, span := otel.Tracer("").Start(ctx, name)
-	span.SetAttributes(attrs...)
+	redacted := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = attr
+		if attr.Value.Type() == attribute.STRING {
+			redacted[i] = attr.Key.String(redact.String(attr.Value.AsString()))
+		}
+	}
+	span.SetAttributes(redacted...)
 	return ctx, span
 }